@@ -0,0 +1,1015 @@
+// Command aegisctl is the operator CLI for the gateway. It manages API keys,
+// limits, model rules, organization and team suspension, provider quarantine, and
+// break-glass filter bypass grants, purges and pauses the response cache, inspects
+// organizations, config, and usage
+// through the admin API, and validates config directories and tests Rego
+// policies offline.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/configcheck"
+	"github.com/af-corp/aegis-gateway/internal/filter/policy"
+)
+
+var version = "dev"
+
+const usage = `usage: aegisctl [-url URL] [-key ADMIN_KEY] [-o table|json] <command> ...
+
+Commands:
+  status                                   gateway and provider health
+  keys list [-org ID] [-team ID] [-status S] [-limit N]
+  keys get <key-id>
+  keys create -org ID -team ID -name NAME [-user ID] [-classification C]
+              [-expires 365d] [-env prod] [-models a,b] [-rpm N] [-tpm N] [-daily-spend-cents N]
+              [-priority interactive|batch]
+  keys revoke <key-id> [-reason TEXT]
+  limits get <key-id>
+  limits set <key-id> [-rpm N] [-tpm N] [-daily-spend-cents N] [-priority P]   (0 restores the default)
+  models get <key-id> | -org ID -team ID
+  models set <key-id> | -org ID -team ID [-default MODEL] [-rewrite from=to,...]
+                                           replace a key's or team's model rules
+  models clear <key-id> | -org ID -team ID
+  orgs list
+  orgs suspend <org> [-team ID] [-reason TEXT]   reject every key of the org or team
+  orgs resume <org> [-team ID]
+  providers list
+  providers quarantine <name> [-reason TEXT]
+  providers release <name>
+  bypass list
+  bypass grant -key ID -filter NAME -justification TEXT -expires 2h
+                                           break-glass: skip one filter for one key
+  bypass revoke <grant-id>
+  cache status | enable | disable          pause the response cache on every replica
+  cache purge [-model M] [-org ID] [-key-hash PREFIX] | -all
+  config validate [-dir configs]           offline, no gateway needed
+  config show | versions | reload
+  config rollback <version>
+  policy test [-bundle configs/policies] [-fixtures DIR|FILE] [-v]
+                                           offline, runs Rego fixtures
+  usage -org ID [-from DATE] [-to DATE]
+  version
+
+The admin key is read from -key or AEGIS_ADMIN_KEY; the gateway URL from -url
+or AEGIS_URL (default http://localhost:8080).
+`
+
+// usageError is reported with exit code 2.
+type usageError struct{ msg string }
+
+func (e usageError) Error() string { return e.msg }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// cli holds the state shared by every command.
+type cli struct {
+	client *client
+	out    io.Writer
+	json   bool
+	ctx    context.Context
+}
+
+type command func(cl *cli, args []string) error
+
+var commands = map[string]command{
+	"status":    statusCmd,
+	"keys":      keysCmd,
+	"limits":    limitsCmd,
+	"models":    modelsCmd,
+	"orgs":      orgsCmd,
+	"providers": providersCmd,
+	"bypass":    bypassCmd,
+	"cache":     cacheCmd,
+	"config":    configCmd,
+	"usage":     usageCmd,
+	"policy":    policyCmd,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes the CLI and returns the process exit code: 0 on success, 1 on
+// a failed operation, 2 on a usage error.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	global := flag.NewFlagSet("aegisctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+	baseURL := global.String("url", envOr(getenv, "AEGIS_URL", "http://localhost:8080"), "gateway base URL")
+	key := global.String("key", getenv("AEGIS_ADMIN_KEY"), "admin API key")
+	output := global.String("o", "table", "output format: table or json")
+	timeout := global.Duration("timeout", 30*time.Second, "request timeout")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(stderr, "error: -o must be table or json\n")
+		return 2
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	if rest[0] == "version" {
+		fmt.Fprintln(stdout, version)
+		return 0
+	}
+	cmd, ok := commands[rest[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "error: unknown command %q\n\n%s", rest[0], usage)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	cl := &cli{
+		client: &client{baseURL: *baseURL, key: *key, http: &http.Client{}},
+		out:    stdout,
+		json:   *output == "json",
+		ctx:    ctx,
+	}
+
+	err := cmd(cl, rest[1:])
+	var ue usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &ue):
+		fmt.Fprintf(stderr, "error: %s\n", ue.msg)
+		return 2
+	case errors.Is(err, errValidationFailed):
+		return 1
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// call performs an admin API request, printing the raw body in JSON mode.
+// out is decoded for table rendering; it returns printed=true when the body
+// was already written.
+func (cl *cli) call(method, path string, query map[string]string, body, out any) (printed bool, err error) {
+	if cl.client.key == "" {
+		return false, usagef("admin key required: set AEGIS_ADMIN_KEY or pass -key")
+	}
+	q := map[string][]string{}
+	for k, v := range query {
+		if v != "" {
+			q[k] = []string{v}
+		}
+	}
+	var raw []byte
+	if err := cl.client.do(cl.ctx, method, path, q, body, out, &raw); err != nil {
+		return false, err
+	}
+	if cl.json {
+		return true, cl.printJSON(raw)
+	}
+	return false, nil
+}
+
+func (cl *cli) printJSON(raw []byte) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		_, err = cl.out.Write(raw)
+		return err
+	}
+	enc := json.NewEncoder(cl.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (cl *cli) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(cl.out, 0, 4, 2, ' ', 0)
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments, returning the positionals in order.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usagef("%s: %v", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// subcommand splits "keys list ..." into "list" and its arguments.
+func subcommand(name string, args []string, valid ...string) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, usagef("%s: missing subcommand (%s)", name, strings.Join(valid, ", "))
+	}
+	for _, v := range valid {
+		if args[0] == v {
+			return v, args[1:], nil
+		}
+	}
+	return "", nil, usagef("%s: unknown subcommand %q (%s)", name, args[0], strings.Join(valid, ", "))
+}
+
+func exactlyOne(what string, positional []string) (string, error) {
+	if len(positional) != 1 {
+		return "", usagef("expected exactly one %s", what)
+	}
+	return positional[0], nil
+}
+
+// optionalInt registers an int flag whose absence is distinguishable from 0.
+func optionalInt(fs *flag.FlagSet, name, help string) **int {
+	var p *int
+	fs.Func(name, help, func(s string) error {
+		var n int
+		if _, err := fmt.Sscanf(s, "%d", &n); err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		p = &n
+		return nil
+	})
+	return &p
+}
+
+func statusCmd(cl *cli, args []string) error {
+	var status struct {
+		Version       string `json:"version"`
+		ConfigVersion string `json:"config_version"`
+		Providers     map[string]struct {
+			Healthy   bool    `json:"healthy"`
+			State     string  `json:"state"`
+			ErrorRate float64 `json:"error_rate"`
+		} `json:"providers"`
+	}
+	if printed, err := cl.call("GET", "/aegis/v1/status", nil, nil, &status); err != nil || printed {
+		return err
+	}
+	fmt.Fprintf(cl.out, "version:        %s\nconfig version: %s\n\n", status.Version, status.ConfigVersion)
+	tw := cl.table()
+	fmt.Fprintln(tw, "PROVIDER\tSTATE\tHEALTHY\tERROR RATE")
+	for _, name := range sortedKeys(status.Providers) {
+		p := status.Providers[name]
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%.1f%%\n", name, p.State, p.Healthy, p.ErrorRate*100)
+	}
+	return tw.Flush()
+}
+
+func keysCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("keys", args, "list", "get", "create", "revoke")
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "list":
+		fs := flag.NewFlagSet("keys list", flag.ContinueOnError)
+		org := fs.String("org", "", "organization ID")
+		team := fs.String("team", "", "team ID")
+		status := fs.String("status", "", "active, revoked, or expired")
+		limit := fs.String("limit", "", "maximum keys to list")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+		var resp struct {
+			Keys []auth.KeyInfo `json:"keys"`
+		}
+		printed, err := cl.call("GET", "/aegis/admin/v1/keys",
+			map[string]string{"org": *org, "team": *team, "status": *status, "limit": *limit}, nil, &resp)
+		if err != nil || printed {
+			return err
+		}
+		tw := cl.table()
+		fmt.Fprintln(tw, "ID\tPREFIX\tORG\tTEAM\tNAME\tSTATUS\tEXPIRES")
+		for _, k := range resp.Keys {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.KeyPrefix, k.OrganizationID, k.TeamID, k.Name, k.Status, k.ExpiresAt.Format("2006-01-02"))
+		}
+		return tw.Flush()
+
+	case "get":
+		fs := flag.NewFlagSet("keys get", flag.ContinueOnError)
+		pos, err := parseArgs(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := exactlyOne("key ID", pos)
+		if err != nil {
+			return err
+		}
+		var k auth.KeyInfo
+		if printed, err := cl.call("GET", "/aegis/admin/v1/keys/"+id, nil, nil, &k); err != nil || printed {
+			return err
+		}
+		return cl.printKey(&k)
+
+	case "create":
+		fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		var req struct {
+			auth.NewKey
+			ExpiresIn string `json:"expires_in,omitempty"`
+		}
+		fs.StringVar(&req.OrganizationID, "org", "", "organization ID (required)")
+		fs.StringVar(&req.TeamID, "team", "", "team ID (required)")
+		fs.StringVar(&req.Name, "name", "", "key name (required)")
+		fs.StringVar(&req.UserID, "user", "", "user ID")
+		fs.StringVar(&req.MaxClassification, "classification", "", "max classification (default INTERNAL)")
+		fs.StringVar(&req.Env, "env", "", "environment prefix (default prod)")
+		fs.StringVar(&req.ExpiresIn, "expires", "", "expiry, e.g. 90d or 720h (default 365d)")
+		fs.StringVar(&req.Priority, "priority", "", "scheduling priority: interactive or batch (default interactive)")
+		models := fs.String("models", "", "comma-separated allowed models (default all)")
+		rpm := optionalInt(fs, "rpm", "requests per minute")
+		tpm := optionalInt(fs, "tpm", "tokens per minute")
+		spend := optionalInt(fs, "daily-spend-cents", "daily spend limit in cents")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+		if req.OrganizationID == "" || req.TeamID == "" || req.Name == "" {
+			return usagef("keys create: -org, -team, and -name are required")
+		}
+		if *models != "" {
+			req.AllowedModels = strings.Split(*models, ",")
+		}
+		req.RPMLimit, req.TPMLimit, req.DailySpendLimitCents = *rpm, *tpm, *spend
+
+		var resp struct {
+			Key    string        `json:"key"`
+			APIKey *auth.KeyInfo `json:"api_key"`
+		}
+		if printed, err := cl.call("POST", "/aegis/admin/v1/keys", nil, req, &resp); err != nil || printed {
+			return err
+		}
+		if err := cl.printKey(resp.APIKey); err != nil {
+			return err
+		}
+		fmt.Fprintf(cl.out, "\nAPI key (save this, it will NOT be shown again):\n  %s\n", resp.Key)
+		return nil
+
+	default: // revoke
+		fs := flag.NewFlagSet("keys revoke", flag.ContinueOnError)
+		reason := fs.String("reason", "", "revocation reason")
+		pos, err := parseArgs(fs, args)
+		if err != nil {
+			return err
+		}
+		id, err := exactlyOne("key ID", pos)
+		if err != nil {
+			return err
+		}
+		var k auth.KeyInfo
+		if printed, err := cl.call("DELETE", "/aegis/admin/v1/keys/"+id, map[string]string{"reason": *reason}, nil, &k); err != nil || printed {
+			return err
+		}
+		return cl.printKey(&k)
+	}
+}
+
+func (cl *cli) printKey(k *auth.KeyInfo) error {
+	tw := cl.table()
+	row := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(tw, "%s:\t%s\n", label, value)
+		}
+	}
+	row("ID", k.ID)
+	row("Prefix", k.KeyPrefix)
+	row("Name", k.Name)
+	row("Organization", k.OrganizationID)
+	row("Team", k.TeamID)
+	row("User", k.UserID)
+	row("Status", k.Status)
+	row("Classification", k.MaxClassification)
+	if len(k.AllowedModels) > 0 {
+		row("Models", strings.Join(k.AllowedModels, ", "))
+	}
+	row("RPM limit", limitString(k.RPMLimit))
+	row("TPM limit", limitString(k.TPMLimit))
+	row("Daily spend (cents)", limitString(k.DailySpendLimitCents))
+	row("Priority", k.Priority)
+	if !k.ExpiresAt.IsZero() {
+		row("Expires", k.ExpiresAt.Format(time.RFC3339))
+	}
+	if k.RevokedAt != nil {
+		row("Revoked", k.RevokedAt.Format(time.RFC3339))
+	}
+	row("Revoked reason", k.RevokedReason)
+	return tw.Flush()
+}
+
+func limitString(v *int) string {
+	if v == nil {
+		return "default"
+	}
+	return fmt.Sprint(*v)
+}
+
+func limitsCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("limits", args, "get", "set")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("limits "+sub, flag.ContinueOnError)
+	var limits auth.KeyLimits
+	rpm := optionalInt(fs, "rpm", "requests per minute (0 restores the default)")
+	tpm := optionalInt(fs, "tpm", "tokens per minute (0 restores the default)")
+	spend := optionalInt(fs, "daily-spend-cents", "daily spend limit in cents (0 restores the default)")
+	fs.StringVar(&limits.Priority, "priority", "", "scheduling priority: interactive or batch")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	id, err := exactlyOne("key ID", pos)
+	if err != nil {
+		return err
+	}
+
+	var k auth.KeyInfo
+	var printed bool
+	if sub == "get" {
+		printed, err = cl.call("GET", "/aegis/admin/v1/keys/"+id, nil, nil, &k)
+	} else {
+		limits.RPMLimit, limits.TPMLimit, limits.DailySpendLimitCents = *rpm, *tpm, *spend
+		if limits == (auth.KeyLimits{}) {
+			return usagef("limits set: pass at least one of -rpm, -tpm, -daily-spend-cents, -priority")
+		}
+		printed, err = cl.call("PATCH", "/aegis/admin/v1/keys/"+id+"/limits", nil, limits, &k)
+	}
+	if err != nil || printed {
+		return err
+	}
+	tw := cl.table()
+	fmt.Fprintf(tw, "Key:\t%s (%s)\n", k.ID, k.Name)
+	fmt.Fprintf(tw, "RPM limit:\t%s\n", limitString(k.RPMLimit))
+	fmt.Fprintf(tw, "TPM limit:\t%s\n", limitString(k.TPMLimit))
+	fmt.Fprintf(tw, "Daily spend (cents):\t%s\n", limitString(k.DailySpendLimitCents))
+	fmt.Fprintf(tw, "Priority:\t%s\n", k.Priority)
+	return tw.Flush()
+}
+
+func modelsCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("models", args, "get", "set", "clear")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("models "+sub, flag.ContinueOnError)
+	org := fs.String("org", "", "organization ID, with -team")
+	team := fs.String("team", "", "set the team's rules instead of a key's")
+	var rules auth.ModelRules
+	fs.StringVar(&rules.DefaultModel, "default", "", "model for requests that name none")
+	rewrites := fs.String("rewrite", "", "comma-separated from=to model rewrites")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *rewrites != "" {
+		rules.ModelRewrites = map[string]string{}
+		for _, pair := range strings.Split(*rewrites, ",") {
+			from, to, ok := strings.Cut(pair, "=")
+			if !ok || from == "" || to == "" {
+				return usagef("models set: -rewrite %q is not from=to", pair)
+			}
+			rules.ModelRewrites[from] = to
+		}
+	}
+	if sub != "set" && (rules.DefaultModel != "" || rules.ModelRewrites != nil) {
+		return usagef("models %s: -default and -rewrite only apply to set", sub)
+	}
+
+	// A key's rules are replaced by PUT, empty to clear them; a team's live
+	// in their own resource.
+	if *team == "" {
+		if *org != "" {
+			return usagef("models %s: -org needs -team", sub)
+		}
+		id, err := exactlyOne("key ID", pos)
+		if err != nil {
+			return err
+		}
+		var k auth.KeyInfo
+		var printed bool
+		if sub == "get" {
+			printed, err = cl.call("GET", "/aegis/admin/v1/keys/"+id, nil, nil, &k)
+		} else {
+			printed, err = cl.call("PUT", "/aegis/admin/v1/keys/"+id+"/models", nil, rules, &k)
+		}
+		if err != nil || printed {
+			return err
+		}
+		return cl.printModelRules("Key", k.ID+" ("+k.Name+")", k.ModelRules)
+	}
+
+	if *org == "" || len(pos) > 0 {
+		return usagef("models %s: pass a key ID or -org and -team", sub)
+	}
+	path := "/aegis/admin/v1/orgs/" + *org + "/teams/" + *team + "/models"
+	var t auth.TeamModelRules
+	var printed bool
+	switch sub {
+	case "get":
+		printed, err = cl.call("GET", path, nil, nil, &t)
+	case "set":
+		printed, err = cl.call("PUT", path, nil, rules, &t)
+	default:
+		if printed, err = cl.call("DELETE", path, nil, nil, nil); err == nil && !printed {
+			fmt.Fprintf(cl.out, "model rules of team %s of organization %s cleared\n", *team, *org)
+		}
+		return err
+	}
+	if err != nil || printed {
+		return err
+	}
+	return cl.printModelRules("Team", t.TeamID+" of "+t.OrganizationID, t.ModelRules)
+}
+
+func (cl *cli) printModelRules(kind, name string, rules auth.ModelRules) error {
+	tw := cl.table()
+	fmt.Fprintf(tw, "%s:\t%s\n", kind, name)
+	defaultModel := rules.DefaultModel
+	if defaultModel == "" {
+		defaultModel = "none"
+	}
+	fmt.Fprintf(tw, "Default model:\t%s\n", defaultModel)
+	for _, from := range sortedKeys(rules.ModelRewrites) {
+		fmt.Fprintf(tw, "Rewrite:\t%s -> %s\n", from, rules.ModelRewrites[from])
+	}
+	return tw.Flush()
+}
+
+func orgsCmd(cl *cli, args []string) error {
+	if len(args) > 0 {
+		sub, rest, err := subcommand("orgs", args, "list", "suspend", "resume")
+		if err != nil {
+			return err
+		}
+		if sub != "list" {
+			return orgSuspendCmd(cl, sub, rest)
+		}
+	}
+	var resp struct {
+		Organizations []auth.OrgSummary `json:"organizations"`
+	}
+	if printed, err := cl.call("GET", "/aegis/admin/v1/orgs", nil, nil, &resp); err != nil || printed {
+		return err
+	}
+	tw := cl.table()
+	fmt.Fprintln(tw, "ORG\tTEAMS\tACTIVE KEYS\tTOTAL KEYS\tSUSPENDED\tSUSPENDED TEAMS")
+	for _, o := range resp.Organizations {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%t\t%d\n", o.OrganizationID, o.Teams, o.ActiveKeys, o.TotalKeys, o.Suspended, o.SuspendedTeams)
+	}
+	return tw.Flush()
+}
+
+func orgSuspendCmd(cl *cli, sub string, args []string) error {
+	fs := flag.NewFlagSet("orgs "+sub, flag.ContinueOnError)
+	team := fs.String("team", "", "suspend only this team")
+	reason := fs.String("reason", "", "why the organization or team is suspended")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	org, err := exactlyOne("organization ID", pos)
+	if err != nil {
+		return err
+	}
+	path, target := "/aegis/admin/v1/orgs/"+org, "organization "+org
+	if *team != "" {
+		path, target = path+"/teams/"+*team, "team "+*team+" of "+target
+	}
+	path += "/suspend"
+	if sub == "resume" {
+		if printed, err := cl.call("DELETE", path, nil, nil, nil); err != nil || printed {
+			return err
+		}
+		fmt.Fprintf(cl.out, "%s resumed\n", target)
+		return nil
+	}
+	var s auth.Suspension
+	if printed, err := cl.call("POST", path, nil, map[string]string{"reason": *reason}, &s); err != nil || printed {
+		return err
+	}
+	fmt.Fprintf(cl.out, "%s suspended at %s\n", target, s.SuspendedAt.Format(time.RFC3339))
+	return nil
+}
+
+func providersCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("providers", args, "list", "quarantine", "release")
+	if err != nil {
+		return err
+	}
+	if sub == "list" {
+		return statusCmd(cl, args)
+	}
+
+	fs := flag.NewFlagSet("providers "+sub, flag.ContinueOnError)
+	reason := fs.String("reason", "", "why the provider is quarantined")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name, err := exactlyOne("provider name", pos)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Quarantined map[string]struct {
+			Reason string    `json:"reason"`
+			Since  time.Time `json:"since"`
+		} `json:"quarantined"`
+	}
+	path := "/aegis/admin/v1/providers/" + name + "/quarantine"
+	var printed bool
+	if sub == "quarantine" {
+		printed, err = cl.call("POST", path, nil, map[string]string{"reason": *reason}, &resp)
+	} else {
+		printed, err = cl.call("DELETE", path, nil, nil, &resp)
+	}
+	if err != nil || printed {
+		return err
+	}
+	if len(resp.Quarantined) == 0 {
+		fmt.Fprintln(cl.out, "no providers quarantined")
+		return nil
+	}
+	tw := cl.table()
+	fmt.Fprintln(tw, "QUARANTINED\tSINCE\tREASON")
+	for _, n := range sortedKeys(resp.Quarantined) {
+		q := resp.Quarantined[n]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", n, q.Since.Format(time.RFC3339), q.Reason)
+	}
+	return tw.Flush()
+}
+
+func cacheCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("cache", args, "status", "enable", "disable", "purge")
+	if err != nil {
+		return err
+	}
+	if sub == "purge" {
+		return cachePurgeCmd(cl, args)
+	}
+	if _, err := parseArgs(flag.NewFlagSet("cache "+sub, flag.ContinueOnError), args); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Configured bool `json:"configured"`
+		Enabled    bool `json:"enabled"`
+	}
+	var printed bool
+	if sub == "status" {
+		printed, err = cl.call("GET", "/aegis/admin/v1/cache", nil, nil, &resp)
+	} else {
+		printed, err = cl.call("PUT", "/aegis/admin/v1/cache", nil, map[string]bool{"enabled": sub == "enable"}, &resp)
+	}
+	if err != nil || printed {
+		return err
+	}
+	state := "on"
+	switch {
+	case !resp.Configured:
+		state = "off (response_cache.enabled is false)"
+	case !resp.Enabled:
+		state = "off (switched off at runtime)"
+	}
+	fmt.Fprintf(cl.out, "response cache %s\n", state)
+	return nil
+}
+
+func cachePurgeCmd(cl *cli, args []string) error {
+	fs := flag.NewFlagSet("cache purge", flag.ContinueOnError)
+	model := fs.String("model", "", "purge entries for this model")
+	org := fs.String("org", "", "purge entries for this organization")
+	keyHash := fs.String("key-hash", "", "purge entries filled by keys whose hash starts with this prefix")
+	all := fs.Bool("all", false, "purge every entry")
+	pos, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) > 0 {
+		return usagef("cache purge takes no arguments, got %q", pos)
+	}
+	if (*model != "" || *org != "" || *keyHash != "") == *all {
+		return usagef("cache purge needs -model, -org, or -key-hash, or -all")
+	}
+
+	var resp struct {
+		Purged int `json:"purged"`
+	}
+	body := map[string]any{"model": *model, "org_id": *org, "key_hash_prefix": *keyHash, "all": *all}
+	if printed, err := cl.call("POST", "/aegis/admin/v1/cache/purge", nil, body, &resp); err != nil || printed {
+		return err
+	}
+	fmt.Fprintf(cl.out, "purged %d cached responses\n", resp.Purged)
+	return nil
+}
+
+func bypassCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("bypass", args, "list", "grant", "revoke")
+	if err != nil {
+		return err
+	}
+	var grants []cache.BypassGrant
+	switch sub {
+	case "list":
+		if _, err := parseArgs(flag.NewFlagSet("bypass list", flag.ContinueOnError), args); err != nil {
+			return err
+		}
+		var resp struct {
+			Grants []cache.BypassGrant `json:"grants"`
+		}
+		if printed, err := cl.call("GET", "/aegis/admin/v1/filter-bypasses", nil, nil, &resp); err != nil || printed {
+			return err
+		}
+		if len(resp.Grants) == 0 {
+			fmt.Fprintln(cl.out, "no active bypass grants")
+			return nil
+		}
+		grants = resp.Grants
+
+	case "grant":
+		fs := flag.NewFlagSet("bypass grant", flag.ContinueOnError)
+		var req struct {
+			APIKeyID      string `json:"api_key_id"`
+			Filter        string `json:"filter"`
+			Justification string `json:"justification"`
+			ExpiresIn     string `json:"expires_in"`
+		}
+		fs.StringVar(&req.APIKeyID, "key", "", "API key ID (required)")
+		fs.StringVar(&req.Filter, "filter", "", "filter to skip, e.g. pii (required)")
+		fs.StringVar(&req.Justification, "justification", "", "why the bypass is needed, e.g. an incident ID (required)")
+		fs.StringVar(&req.ExpiresIn, "expires", "", "duration, e.g. 2h (required)")
+		if _, err := parseArgs(fs, args); err != nil {
+			return err
+		}
+		if req.APIKeyID == "" || req.Filter == "" || req.Justification == "" || req.ExpiresIn == "" {
+			return usagef("bypass grant: -key, -filter, -justification, and -expires are required")
+		}
+		var g cache.BypassGrant
+		if printed, err := cl.call("POST", "/aegis/admin/v1/filter-bypasses", nil, req, &g); err != nil || printed {
+			return err
+		}
+		grants = []cache.BypassGrant{g}
+
+	default: // revoke
+		pos, err := parseArgs(flag.NewFlagSet("bypass revoke", flag.ContinueOnError), args)
+		if err != nil {
+			return err
+		}
+		id, err := exactlyOne("grant ID", pos)
+		if err != nil {
+			return err
+		}
+		var g cache.BypassGrant
+		if printed, err := cl.call("DELETE", "/aegis/admin/v1/filter-bypasses/"+id, nil, nil, &g); err != nil || printed {
+			return err
+		}
+		fmt.Fprintf(cl.out, "bypass grant %s revoked\n", g.ID)
+		return nil
+	}
+
+	tw := cl.table()
+	fmt.Fprintln(tw, "GRANT\tKEY\tORG\tFILTER\tEXPIRES\tJUSTIFICATION")
+	for _, g := range grants {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", g.ID, g.APIKeyID, g.OrganizationID, g.Filter, g.ExpiresAt.Format(time.RFC3339), g.Justification)
+	}
+	return tw.Flush()
+}
+
+// errValidationFailed signals that problems were already printed.
+var errValidationFailed = errors.New("configuration invalid")
+
+func configCmd(cl *cli, args []string) error {
+	sub, args, err := subcommand("config", args, "validate", "show", "versions", "reload", "rollback")
+	if err != nil {
+		return err
+	}
+	switch sub {
+	case "validate":
+		fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+		dir := fs.String("dir", "configs", "configuration directory")
+		pos, err := parseArgs(fs, args)
+		if err != nil {
+			return err
+		}
+		if len(pos) == 1 {
+			*dir = pos[0]
+		}
+		if configcheck.Run(*dir, cl.out) != 0 {
+			return errValidationFailed
+		}
+		return nil
+	case "show":
+		// The effective config is nested YAML-shaped data; JSON is the only
+		// sensible rendering.
+		cl.json = true
+		_, err := cl.call("GET", "/aegis/admin/v1/config", nil, nil, nil)
+		return err
+	case "versions":
+		var resp struct {
+			Versions []struct {
+				Version  string    `json:"version"`
+				LoadedAt time.Time `json:"loaded_at"`
+				Current  bool      `json:"current"`
+			} `json:"versions"`
+		}
+		if printed, err := cl.call("GET", "/aegis/admin/v1/config/versions", nil, nil, &resp); err != nil || printed {
+			return err
+		}
+		tw := cl.table()
+		fmt.Fprintln(tw, "VERSION\tLOADED\tCURRENT")
+		for _, v := range resp.Versions {
+			current := ""
+			if v.Current {
+				current = "*"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", v.Version, v.LoadedAt.Format(time.RFC3339), current)
+		}
+		return tw.Flush()
+	case "reload":
+		var resp struct {
+			ConfigVersion string `json:"config_version"`
+		}
+		if printed, err := cl.call("POST", "/aegis/admin/v1/config/reload", nil, nil, &resp); err != nil || printed {
+			return err
+		}
+		fmt.Fprintf(cl.out, "config reloaded (version %s)\n", resp.ConfigVersion)
+		return nil
+	default: // rollback
+		version, err := exactlyOne("config version", args)
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			ConfigVersion string `json:"config_version"`
+		}
+		if printed, err := cl.call("POST", "/aegis/admin/v1/config/rollback", nil, map[string]string{"version": version}, &resp); err != nil || printed {
+			return err
+		}
+		fmt.Fprintf(cl.out, "rolled back to config version %s\n", resp.ConfigVersion)
+		return nil
+	}
+}
+
+// policyCmd runs policy fixtures against a Rego bundle without a gateway, so
+// policy changes can be tested in CI.
+func policyCmd(cl *cli, args []string) error {
+	_, args, err := subcommand("policy", args, "test")
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("policy test", flag.ContinueOnError)
+	bundle := fs.String("bundle", "configs/policies", "directory of .rego files")
+	fixtures := fs.String("fixtures", "", "fixture file or directory (default <bundle>/tests)")
+	verbose := fs.Bool("v", false, "list passing cases too")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *fixtures == "" {
+		*fixtures = filepath.Join(*bundle, "tests")
+	}
+
+	modules, err := policy.LoadRegoFiles(*bundle)
+	if err != nil {
+		return fmt.Errorf("load policies: %w", err)
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("no .rego files in %s", *bundle)
+	}
+	eval := policy.NewEvaluator(func() config.PolicyFilterConfig {
+		return config.PolicyFilterConfig{Enabled: true, BundlePath: *bundle, EvaluationTimeout: time.Second}
+	})
+	if err := eval.LoadFromModules(modules); err != nil {
+		return err
+	}
+	cases, err := policy.LoadTestCases(*fixtures)
+	if err != nil {
+		return fmt.Errorf("load fixtures: %w", err)
+	}
+	if len(cases) == 0 {
+		return fmt.Errorf("no test cases in %s", *fixtures)
+	}
+
+	results := policy.RunTests(cl.ctx, eval, cases)
+	if cl.json {
+		type jsonResult struct {
+			Name    string `json:"name"`
+			File    string `json:"file"`
+			Passed  bool   `json:"passed"`
+			Allowed bool   `json:"allowed"`
+			Reason  string `json:"reason,omitempty"`
+			Failure string `json:"failure,omitempty"`
+		}
+		out := make([]jsonResult, len(results))
+		for i, r := range results {
+			out[i] = jsonResult{Name: r.Case.Name, File: r.Case.File, Passed: r.Passed(), Allowed: r.Allowed, Reason: r.Reason, Failure: r.Failure}
+			if r.Err != nil {
+				out[i].Failure = r.Err.Error()
+			}
+		}
+		enc := json.NewEncoder(cl.out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Passed() {
+			if *verbose && !cl.json {
+				fmt.Fprintf(cl.out, "PASS  %s\n", r.Case.Name)
+			}
+			continue
+		}
+		failed++
+		if cl.json {
+			continue
+		}
+		msg := r.Failure
+		if r.Err != nil {
+			msg = r.Err.Error()
+		}
+		fmt.Fprintf(cl.out, "FAIL  %s (%s)\n      %s\n", r.Case.Name, r.Case.File, msg)
+	}
+	if !cl.json {
+		fmt.Fprintf(cl.out, "%d passed, %d failed\n", len(results)-failed, failed)
+	}
+	if failed > 0 {
+		return errValidationFailed
+	}
+	return nil
+}
+
+func usageCmd(cl *cli, args []string) error {
+	fs := flag.NewFlagSet("usage", flag.ContinueOnError)
+	org := fs.String("org", "", "organization ID (required)")
+	from := fs.String("from", "", "start, YYYY-MM-DD or RFC 3339 (default 30 days ago)")
+	to := fs.String("to", "", "end, YYYY-MM-DD or RFC 3339 (default now)")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *org == "" {
+		return usagef("usage: -org is required")
+	}
+	var resp struct {
+		OrganizationID    string  `json:"organization_id"`
+		From              string  `json:"from"`
+		To                string  `json:"to"`
+		TotalRequests     int     `json:"total_requests"`
+		TotalCostUSD      float64 `json:"total_cost_usd"`
+		TotalTokens       int64   `json:"total_tokens"`
+		PromptTokens      int64   `json:"prompt_tokens"`
+		CompletionTokens  int64   `json:"completion_tokens"`
+		AverageDurationMs float64 `json:"average_duration_ms"`
+	}
+	printed, err := cl.call("GET", "/aegis/admin/v1/usage", map[string]string{"org": *org, "from": *from, "to": *to}, nil, &resp)
+	if err != nil || printed {
+		return err
+	}
+	tw := cl.table()
+	fmt.Fprintf(tw, "Organization:\t%s\n", resp.OrganizationID)
+	fmt.Fprintf(tw, "Period:\t%s to %s\n", resp.From, resp.To)
+	fmt.Fprintf(tw, "Requests:\t%d\n", resp.TotalRequests)
+	fmt.Fprintf(tw, "Cost (USD):\t%.4f\n", resp.TotalCostUSD)
+	fmt.Fprintf(tw, "Tokens:\t%d (prompt %d, completion %d)\n", resp.TotalTokens, resp.PromptTokens, resp.CompletionTokens)
+	fmt.Fprintf(tw, "Avg duration:\t%.0f ms\n", resp.AverageDurationMs)
+	return tw.Flush()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recordedRequest struct {
+	method, path, query, auth string
+	body                      map[string]any
+}
+
+func newFakeGateway(t *testing.T, responses map[string]string) (*httptest.Server, *[]recordedRequest) {
+	t.Helper()
+	var reqs []recordedRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := recordedRequest{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, auth: r.Header.Get("Authorization")}
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			_ = json.Unmarshal(data, &rec.body)
+		}
+		reqs = append(reqs, rec)
+
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"key_not_found","message":"API key not found"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(resp))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func runCLI(t *testing.T, srvURL string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	env := map[string]string{"AEGIS_URL": srvURL, "AEGIS_ADMIN_KEY": "admin-key"}
+	code := run(args, &stdout, &stderr, func(k string) string { return env[k] })
+	return code, stdout.String(), stderr.String()
+}
+
+func TestKeysList(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"GET /aegis/admin/v1/keys": `{"keys":[{"id":"k1","key_prefix":"aegis-prod-abcd1234","organization_id":"org-1","team_id":"team-1","name":"ci","status":"active","expires_at":"2027-01-01T00:00:00Z"}]}`,
+	})
+
+	code, out, errOut := runCLI(t, srv.URL, "keys", "list", "-org", "org-1")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, "aegis-prod-abcd1234") || !strings.Contains(out, "2027-01-01") {
+		t.Errorf("unexpected table:\n%s", out)
+	}
+	got := (*reqs)[0]
+	if got.auth != "Bearer admin-key" || got.query != "org=org-1" {
+		t.Errorf("unexpected request %+v", got)
+	}
+}
+
+func TestKeysCreate(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"POST /aegis/admin/v1/keys": `{"key":"aegis-prod-secret","api_key":{"id":"k2","name":"batch","organization_id":"org-1","team_id":"t","status":"active"}}`,
+	})
+
+	code, out, errOut := runCLI(t, srv.URL, "keys", "create", "-org", "org-1", "-team", "t", "-name", "batch", "-expires", "30d", "-rpm", "0", "-models", "gpt-4o,claude")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if !strings.Contains(out, "aegis-prod-secret") {
+		t.Errorf("expected raw key printed once, got:\n%s", out)
+	}
+	body := (*reqs)[0].body
+	if body["expires_in"] != "30d" || body["rpm_limit"] != float64(0) || body["tpm_limit"] != nil {
+		t.Errorf("unexpected create body %v", body)
+	}
+	if models, _ := body["allowed_models"].([]any); len(models) != 2 {
+		t.Errorf("expected two allowed models, got %v", body["allowed_models"])
+	}
+}
+
+func TestKeysRevoke_FlagAfterID(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"DELETE /aegis/admin/v1/keys/k1": `{"id":"k1","status":"revoked","revoked_reason":"leaked"}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "keys", "revoke", "k1", "-reason", "leaked")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].query != "reason=leaked" || !strings.Contains(out, "revoked") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[0], out)
+	}
+}
+
+func TestLimitsSet(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"PATCH /aegis/admin/v1/keys/k1/limits": `{"id":"k1","name":"ci","tpm_limit":50000}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "limits", "set", "k1", "-tpm", "50000")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].body["tpm_limit"] != float64(50000) || len((*reqs)[0].body) != 1 {
+		t.Errorf("unexpected body %v", (*reqs)[0].body)
+	}
+	if !strings.Contains(out, "50000") || !strings.Contains(out, "default") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	if code, _, _ := runCLI(t, srv.URL, "limits", "set", "k1"); code != 2 {
+		t.Errorf("expected usage error with no limits, got %d", code)
+	}
+}
+
+func TestModelsSet(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"PUT /aegis/admin/v1/keys/k1/models":                 `{"id":"k1","name":"ci","model_rewrites":{"gpt-4":"gpt-4o-mini"}}`,
+		"PUT /aegis/admin/v1/orgs/org-1/teams/search/models": `{"organization_id":"org-1","team_id":"search","default_model":"gpt-4o-mini"}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "models", "set", "k1", "-rewrite", "gpt-4=gpt-4o-mini")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if rewrites, _ := (*reqs)[0].body["model_rewrites"].(map[string]any); rewrites["gpt-4"] != "gpt-4o-mini" {
+		t.Errorf("unexpected body %v", (*reqs)[0].body)
+	}
+	if !strings.Contains(out, "gpt-4 -> gpt-4o-mini") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	code, out, errOut = runCLI(t, srv.URL, "models", "set", "-org", "org-1", "-team", "search", "-default", "gpt-4o-mini")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[1].body["default_model"] != "gpt-4o-mini" || !strings.Contains(out, "search of org-1") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[1], out)
+	}
+
+	if code, _, _ := runCLI(t, srv.URL, "models", "set", "k1", "-rewrite", "gpt-4"); code != 2 {
+		t.Errorf("expected usage error for a malformed rewrite, got %d", code)
+	}
+}
+
+func TestProvidersQuarantine(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"POST /aegis/admin/v1/providers/openai/quarantine": `{"quarantined":{"openai":{"reason":"bad deploy","since":"2026-10-15T10:00:00Z"}}}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "providers", "quarantine", "openai", "-reason", "bad deploy")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].body["reason"] != "bad deploy" || !strings.Contains(out, "openai") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[0], out)
+	}
+}
+
+func TestCachePurge(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"POST /aegis/admin/v1/cache/purge": `{"purged":4}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "cache", "purge", "-model", "smart", "-key-hash", "ab12")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].body["model"] != "smart" || (*reqs)[0].body["key_hash_prefix"] != "ab12" || !strings.Contains(out, "purged 4") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[0], out)
+	}
+
+	if code, _, _ := runCLI(t, srv.URL, "cache", "purge"); code != 2 {
+		t.Errorf("expected usage error for a purge without a filter, got %d", code)
+	}
+}
+
+func TestOrgsSuspend(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"POST /aegis/admin/v1/orgs/org-1/suspend": `{"organization_id":"org-1","reason":"incident","suspended_at":"2026-10-15T10:00:00Z"}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "orgs", "suspend", "org-1", "-reason", "incident")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].body["reason"] != "incident" || !strings.Contains(out, "org-1 suspended") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[0], out)
+	}
+}
+
+func TestOrgsResumeTeam(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"DELETE /aegis/admin/v1/orgs/org-1/teams/search/suspend": `{"organization_id":"org-1","team_id":"search"}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "orgs", "resume", "org-1", "-team", "search")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if len(*reqs) != 1 || !strings.Contains(out, "team search of organization org-1 resumed") {
+		t.Errorf("unexpected requests %+v / output %s", *reqs, out)
+	}
+}
+
+func TestBypassGrant(t *testing.T) {
+	srv, reqs := newFakeGateway(t, map[string]string{
+		"POST /aegis/admin/v1/filter-bypasses": `{"id":"bypass_1","api_key_id":"k1","organization_id":"org-1","filter":"pii","justification":"INC-7","expires_at":"2026-10-15T12:00:00Z"}`,
+	})
+	code, out, errOut := runCLI(t, srv.URL, "bypass", "grant", "-key", "k1", "-filter", "pii", "-justification", "INC-7", "-expires", "2h")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if (*reqs)[0].body["expires_in"] != "2h" || (*reqs)[0].body["justification"] != "INC-7" || !strings.Contains(out, "bypass_1") {
+		t.Errorf("unexpected request %+v / output %s", (*reqs)[0], out)
+	}
+
+	if code, _, _ := runCLI(t, srv.URL, "bypass", "grant", "-key", "k1", "-filter", "pii"); code != 2 {
+		t.Errorf("expected usage error without justification, got %d", code)
+	}
+}
+
+func TestJSONOutput(t *testing.T) {
+	srv, _ := newFakeGateway(t, map[string]string{
+		"GET /aegis/admin/v1/orgs": `{"organizations":[{"organization_id":"org-1","teams":2,"active_keys":3,"total_keys":4}]}`,
+	})
+	code, out, _ := runCLI(t, srv.URL, "-o", "json", "orgs", "list")
+	if code != 0 {
+		t.Fatalf("exit %d", code)
+	}
+	var v map[string]any
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		t.Fatalf("expected JSON output, got %q", out)
+	}
+}
+
+func TestAPIErrorSurfaced(t *testing.T) {
+	srv, _ := newFakeGateway(t, nil)
+	code, _, errOut := runCLI(t, srv.URL, "keys", "get", "missing")
+	if code != 1 || !strings.Contains(errOut, "404 key_not_found: API key not found") {
+		t.Errorf("expected API error, got exit %d: %s", code, errOut)
+	}
+}
+
+func TestMissingAdminKey(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"orgs", "list"}, &stdout, &stderr, func(string) string { return "" })
+	if code != 2 || !strings.Contains(stderr.String(), "AEGIS_ADMIN_KEY") {
+		t.Errorf("expected usage error for missing key, got exit %d: %s", code, stderr.String())
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"bogus"},
+		{"keys"},
+		{"keys", "frobnicate"},
+		{"keys", "get"},
+		{"usage"},
+	} {
+		if code, _, _ := runCLI(t, "http://unused", args...); code != 2 {
+			t.Errorf("args %v: expected exit 2, got %d", args, code)
+		}
+	}
+}
+
+func TestConfigValidate_Offline(t *testing.T) {
+	t.Setenv("OPA_BUNDLE_PATH", "../../configs/policies")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"config", "validate", "-dir", "../../configs"}, &stdout, &stderr, func(string) string { return "" })
+	if code != 0 || !strings.Contains(stdout.String(), "configuration OK") {
+		t.Errorf("expected shipped configs valid without an admin key, exit %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"config", "validate", t.TempDir()}, &stdout, &stderr, func(string) string { return "" }); code != 1 {
+		t.Errorf("expected exit 1 for a missing config, got %d", code)
+	}
+}
+
+func TestPolicyTest_Offline(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	noEnv := func(string) string { return "" }
+	code := run([]string{"policy", "test", "-bundle", "../../configs/policies"}, &stdout, &stderr, noEnv)
+	if code != 0 || !strings.Contains(stdout.String(), "0 failed") {
+		t.Errorf("expected shipped policy fixtures to pass, exit %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	fixture := filepath.Join(t.TempDir(), "wrong.yaml")
+	if err := os.WriteFile(fixture, []byte(`cases:
+  - name: restricted external allowed
+    input:
+      request: {classification: RESTRICTED, provider_type: external}
+    expect: {allow: true}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	code = run([]string{"policy", "test", "-bundle", "../../configs/policies", "-fixtures", fixture}, &stdout, &stderr, noEnv)
+	if code != 1 || !strings.Contains(stdout.String(), "FAIL  restricted external allowed") {
+		t.Errorf("expected exit 1 with a FAIL line, got %d:\n%s", code, stdout.String())
+	}
+}
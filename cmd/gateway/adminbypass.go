@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/go-chi/chi/v5"
+)
+
+// bypassAdmin is the subset of cache.BypassStore the bypass grant API needs.
+type bypassAdmin interface {
+	Create(ctx context.Context, g cache.BypassGrant) (*cache.BypassGrant, error)
+	List(ctx context.Context) ([]cache.BypassGrant, error)
+	Revoke(ctx context.Context, id string) (*cache.BypassGrant, error)
+}
+
+// createBypassRequest is the body of POST /aegis/admin/v1/filter-bypasses.
+type createBypassRequest struct {
+	APIKeyID      string `json:"api_key_id"`
+	Filter        string `json:"filter"`
+	Justification string `json:"justification"`
+	// ExpiresIn is a Go duration such as "2h", at most
+	// filter.bypass_max_duration.
+	ExpiresIn string `json:"expires_in"`
+}
+
+// mountAdminBypass registers the break-glass filter bypass API: a grant
+// exempts one API key from one filter until it expires or is revoked.
+// Grants require a justification, and creating, revoking, and using one are
+// all audited.
+func mountAdminBypass(r chi.Router, keys keyAdmin, grants bypassAdmin, filters []string, maxDuration func() time.Duration, auditor configChangeAuditor, strictTenancy bool) {
+	r.Get("/aegis/admin/v1/filter-bypasses", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		list, err := grants.List(r.Context())
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to list bypass grants")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"grants": list})
+	})
+
+	r.Post("/aegis/admin/v1/filter-bypasses", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+
+		var req createBypassRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8192))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid bypass request: %v", err))
+			return
+		}
+		if req.APIKeyID == "" || req.Filter == "" || strings.TrimSpace(req.Justification) == "" || req.ExpiresIn == "" {
+			httputil.WriteBadRequestError(w, reqID, "api_key_id, filter, justification, and expires_in are required")
+			return
+		}
+		if !slices.Contains(filters, req.Filter) {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Unknown filter %q (%s)", req.Filter, strings.Join(filters, ", ")))
+			return
+		}
+		maxDur := maxDuration()
+		if maxDur <= 0 {
+			httputil.WriteError(w, reqID, http.StatusForbidden, "permission_error", "bypass_disabled",
+				"Filter bypass grants are disabled (filter.bypass_max_duration is 0)")
+			return
+		}
+		dur, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || dur <= 0 || dur > maxDur {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid expires_in %q: must be a duration up to %s", req.ExpiresIn, maxDur))
+			return
+		}
+		k, err := keys.GetKey(r.Context(), req.APIKeyID)
+		if writeKeyError(w, reqID, err) {
+			return
+		}
+		if k.Status != "active" {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("API key %s is %s", k.ID, k.Status))
+			return
+		}
+
+		var createdBy string
+		if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+			createdBy = authInfo.KeyID
+		}
+		g, err := grants.Create(r.Context(), cache.BypassGrant{
+			OrganizationID: k.OrganizationID,
+			APIKeyID:       k.ID,
+			Filter:         req.Filter,
+			Justification:  req.Justification,
+			CreatedBy:      createdBy,
+			ExpiresAt:      time.Now().UTC().Add(dur),
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to create bypass grant")
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "filter_bypass_grant", g.OrganizationID, strictTenancy, map[string]interface{}{
+			"grant_id":      g.ID,
+			"org_id":        g.OrganizationID,
+			"api_key_id":    g.APIKeyID,
+			"filter":        g.Filter,
+			"justification": g.Justification,
+			"expires_at":    g.ExpiresAt.Format(time.RFC3339),
+		})
+		writeJSON(w, http.StatusCreated, g)
+	})
+
+	r.Delete("/aegis/admin/v1/filter-bypasses/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		g, err := grants.Revoke(r.Context(), chi.URLParam(r, "id"))
+		if errors.Is(err, cache.ErrBypassGrantNotFound) {
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "grant_not_found",
+				"Bypass grant not found or already expired")
+			return
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to revoke bypass grant")
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "filter_bypass_revoke", g.OrganizationID, strictTenancy, map[string]interface{}{
+			"grant_id":   g.ID,
+			"api_key_id": g.APIKeyID,
+			"filter":     g.Filter,
+		})
+		writeJSON(w, http.StatusOK, g)
+	})
+}
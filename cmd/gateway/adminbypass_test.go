@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeBypassAdmin struct {
+	grants map[string]cache.BypassGrant
+}
+
+func (f *fakeBypassAdmin) Create(_ context.Context, g cache.BypassGrant) (*cache.BypassGrant, error) {
+	g.ID, g.CreatedAt = "bypass_1", time.Now()
+	f.grants[g.ID] = g
+	return &g, nil
+}
+
+func (f *fakeBypassAdmin) List(context.Context) ([]cache.BypassGrant, error) {
+	var out []cache.BypassGrant
+	for _, g := range f.grants {
+		out = append(out, g)
+	}
+	return out, nil
+}
+
+func (f *fakeBypassAdmin) Revoke(_ context.Context, id string) (*cache.BypassGrant, error) {
+	g, ok := f.grants[id]
+	if !ok {
+		return nil, cache.ErrBypassGrantNotFound
+	}
+	delete(f.grants, id)
+	return &g, nil
+}
+
+func newAdminBypassTestServer(maxDuration time.Duration) (*fakeBypassAdmin, *fakeConfigAuditor, http.Handler) {
+	keys := &fakeKeyAdmin{keys: map[string]*auth.KeyInfo{
+		"key-1": {ID: "key-1", OrganizationID: "org-1", TeamID: "team-1", Status: "active"},
+		"key-2": {ID: "key-2", OrganizationID: "org-1", TeamID: "team-1", Status: "revoked"},
+	}}
+	grants := &fakeBypassAdmin{grants: map[string]cache.BypassGrant{}}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	mountAdminBypass(r, keys, grants, []string{"secrets", "pii"}, func() time.Duration { return maxDuration }, auditor, false)
+	return grants, auditor, r
+}
+
+func TestAdminBypass_GrantAndRevoke(t *testing.T) {
+	grants, auditor, h := newAdminBypassTestServer(24 * time.Hour)
+
+	body := `{"api_key_id":"key-1","filter":"pii","justification":"INC-123 false positives on invoice numbers","expires_in":"2h"}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/filter-bypasses", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var g cache.BypassGrant
+	if err := json.Unmarshal(w.Body.Bytes(), &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.OrganizationID != "org-1" || g.Filter != "pii" || time.Until(g.ExpiresAt) > 2*time.Hour {
+		t.Errorf("unexpected grant %+v", g)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/filter-bypasses/"+g.ID, nil))
+	if w.Code != http.StatusOK || len(grants.grants) != 0 {
+		t.Fatalf("expected revoke, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/filter-bypasses/"+g.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 revoking twice, got %d", w.Code)
+	}
+
+	if len(auditor.changes) != 2 || auditor.changes[0].action != "filter_bypass_grant" || auditor.changes[1].action != "filter_bypass_revoke" {
+		t.Fatalf("unexpected audit records %+v", auditor.changes)
+	}
+	if auditor.changes[0].changes["justification"] != "INC-123 false positives on invoice numbers" {
+		t.Errorf("expected justification in audit record, got %v", auditor.changes[0].changes)
+	}
+}
+
+func TestAdminBypass_Validation(t *testing.T) {
+	_, _, h := newAdminBypassTestServer(4 * time.Hour)
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"api_key_id":"key-1","filter":"pii","expires_in":"1h"}`, http.StatusBadRequest},
+		{`{"api_key_id":"key-1","filter":"policy","justification":"x","expires_in":"1h"}`, http.StatusBadRequest},
+		{`{"api_key_id":"key-1","filter":"pii","justification":"x","expires_in":"5h"}`, http.StatusBadRequest},
+		{`{"api_key_id":"key-9","filter":"pii","justification":"x","expires_in":"1h"}`, http.StatusNotFound},
+		{`{"api_key_id":"key-2","filter":"pii","justification":"x","expires_in":"1h"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/filter-bypasses", strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("body %s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+
+	_, _, disabled := newAdminBypassTestServer(0)
+	w := httptest.NewRecorder()
+	disabled.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/filter-bypasses",
+		strings.NewReader(`{"api_key_id":"key-1","filter":"pii","justification":"x","expires_in":"1h"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403 with grants disabled, got %d", w.Code)
+	}
+}
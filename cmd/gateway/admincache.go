@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/go-chi/chi/v5"
+)
+
+// responseCacheAdmin is the subset of cache.ResponseStore the cache admin
+// API needs.
+type responseCacheAdmin interface {
+	Enabled(ctx context.Context) (bool, error)
+	SetEnabled(ctx context.Context, enabled bool) error
+	Purge(ctx context.Context, f cache.PurgeFilter) (int, error)
+}
+
+// cacheSwitchRequest is the body of PUT /aegis/admin/v1/cache.
+type cacheSwitchRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// cachePurgeRequest is the body of POST /aegis/admin/v1/cache/purge. Set
+// fields must all match; All must be set to purge every entry.
+type cachePurgeRequest struct {
+	Model         string `json:"model"`
+	OrgID         string `json:"org_id"`
+	KeyHashPrefix string `json:"key_hash_prefix"`
+	All           bool   `json:"all"`
+}
+
+// mountAdminCache registers the response cache admin API: purging entries
+// by model, organization, or API key hash prefix after a bad answer was
+// cached or a model changed, and a runtime switch that pauses the cache on
+// every replica. configured reports response_cache.enabled; the cache
+// serves only while both are on. Changes are audited.
+func mountAdminCache(r chi.Router, store responseCacheAdmin, configured func() bool, auditor configChangeAuditor) {
+	writeState := func(w http.ResponseWriter, r *http.Request, reqID string) {
+		enabled, err := store.Enabled(r.Context())
+		if err != nil {
+			slog.Error("failed to read response cache switch", "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeServiceUnavailable, "Failed to read the response cache switch", nil)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"configured": configured(), "enabled": enabled})
+	}
+
+	r.Get("/aegis/admin/v1/cache", func(w http.ResponseWriter, r *http.Request) {
+		writeState(w, r, w.Header().Get("X-Request-ID"))
+	})
+
+	r.Put("/aegis/admin/v1/cache", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		var req cacheSwitchRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil || req.Enabled == nil {
+			httputil.WriteBadRequestError(w, reqID, `Invalid cache switch: send {"enabled": true|false}`)
+			return
+		}
+		if err := store.SetEnabled(r.Context(), *req.Enabled); err != nil {
+			slog.Error("failed to switch response cache", "enabled", *req.Enabled, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeServiceUnavailable, "Failed to switch the response cache", nil)
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "response_cache_switch", map[string]interface{}{
+			"enabled": *req.Enabled,
+		})
+		writeState(w, r, reqID)
+	})
+
+	r.Post("/aegis/admin/v1/cache/purge", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		var req cachePurgeRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid purge request: %v", err))
+			return
+		}
+		filtered := req.Model != "" || req.OrgID != "" || req.KeyHashPrefix != ""
+		if filtered == req.All {
+			httputil.WriteBadRequestError(w, reqID, "Set model, org_id, or key_hash_prefix, or all to purge every entry")
+			return
+		}
+		if strings.Trim(req.KeyHashPrefix, "0123456789abcdef") != "" {
+			httputil.WriteBadRequestError(w, reqID, "key_hash_prefix must be lowercase hex")
+			return
+		}
+		purged, err := store.Purge(r.Context(), cache.PurgeFilter{
+			Model:          req.Model,
+			OrganizationID: req.OrgID,
+			KeyHashPrefix:  req.KeyHashPrefix,
+		})
+		if err != nil {
+			slog.Error("failed to purge response cache", "purged", purged, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeServiceUnavailable,
+				fmt.Sprintf("Failed to purge the response cache after %d entries", purged), nil)
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "response_cache_purge", map[string]interface{}{
+			"model":           req.Model,
+			"org_id":          req.OrgID,
+			"key_hash_prefix": req.KeyHashPrefix,
+			"all":             req.All,
+			"purged":          purged,
+		})
+		writeJSON(w, http.StatusOK, map[string]any{"purged": purged})
+	})
+}
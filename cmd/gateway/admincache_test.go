@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeCacheAdmin struct {
+	enabled bool
+	purges  []cache.PurgeFilter
+}
+
+func (f *fakeCacheAdmin) Enabled(context.Context) (bool, error) { return f.enabled, nil }
+
+func (f *fakeCacheAdmin) SetEnabled(_ context.Context, enabled bool) error {
+	f.enabled = enabled
+	return nil
+}
+
+func (f *fakeCacheAdmin) Purge(_ context.Context, filter cache.PurgeFilter) (int, error) {
+	f.purges = append(f.purges, filter)
+	return 3, nil
+}
+
+func TestAdminCache_SwitchAndPurge(t *testing.T) {
+	store := &fakeCacheAdmin{enabled: true}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	mountAdminCache(r, store, func() bool { return true }, auditor)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := do("PUT", "/aegis/admin/v1/cache", `{"enabled":false}`)
+	if w.Code != http.StatusOK || store.enabled || !strings.Contains(w.Body.String(), `"enabled":false`) {
+		t.Fatalf("switch off: %d %s", w.Code, w.Body.String())
+	}
+	if w = do("PUT", "/aegis/admin/v1/cache", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("a switch without enabled should be rejected, got %d", w.Code)
+	}
+
+	w = do("POST", "/aegis/admin/v1/cache/purge", `{"model":"smart","key_hash_prefix":"ab12"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"purged":3`) {
+		t.Fatalf("purge: %d %s", w.Code, w.Body.String())
+	}
+	if got := store.purges[0]; got.Model != "smart" || got.KeyHashPrefix != "ab12" || got.OrganizationID != "" {
+		t.Errorf("purge filter = %+v", got)
+	}
+
+	for _, body := range []string{`{}`, `{"all":true,"org_id":"org-1"}`, `{"key_hash_prefix":"sk-live"}`} {
+		if w = do("POST", "/aegis/admin/v1/cache/purge", body); w.Code != http.StatusBadRequest {
+			t.Errorf("purge %s: expected 400, got %d", body, w.Code)
+		}
+	}
+	if w = do("POST", "/aegis/admin/v1/cache/purge", `{"all":true}`); w.Code != http.StatusOK || len(store.purges) != 2 {
+		t.Errorf("purging everything needs all, got %d", w.Code)
+	}
+
+	if len(auditor.changes) != 3 || auditor.changes[0].action != "response_cache_switch" || auditor.changes[1].action != "response_cache_purge" {
+		t.Errorf("switches and purges should be audited, got %+v", auditor.changes)
+	}
+}
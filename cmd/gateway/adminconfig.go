@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/go-chi/chi/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// configAdmin is the subset of config.Loader the admin config API needs.
+type configAdmin interface {
+	Config() *config.Config
+	Models() *config.ModelsConfig
+	Providers() *config.ProvidersConfig
+	Version() string
+	Overrides() config.RuntimeOverrides
+	ApplyOverrides(config.RuntimeOverrides) error
+	ClearOverrides() error
+	Reload() error
+	History() []config.VersionInfo
+	Rollback(version string) error
+	RefreshProviderSecrets(name string) (bool, error)
+}
+
+// configChangeAuditor records admin config changes.
+type configChangeAuditor interface {
+	LogConfigChange(requestID, orgID, teamID, keyID, action string, changes map[string]interface{}, ip string)
+}
+
+// effectiveConfigResponse is returned by GET /aegis/admin/v1/config.
+type effectiveConfigResponse struct {
+	ConfigVersion string                  `json:"config_version"`
+	Overrides     config.RuntimeOverrides `json:"overrides"`
+	Gateway       any                     `json:"gateway"`
+	Models        any                     `json:"models"`
+	Providers     any                     `json:"providers"`
+}
+
+// secretRefreshResponse is returned by
+// POST /aegis/admin/v1/providers/{name}/secrets/refresh.
+type secretRefreshResponse struct {
+	Provider      string `json:"provider"`
+	Rotated       bool   `json:"rotated"`
+	ConfigVersion string `json:"config_version"`
+}
+
+// overridesResponse is returned by the mutating config endpoints.
+type overridesResponse struct {
+	ConfigVersion string                  `json:"config_version"`
+	Overrides     config.RuntimeOverrides `json:"overrides"`
+}
+
+// sensitiveKey matches config keys and header names whose values are masked.
+var sensitiveKey = regexp.MustCompile(`(?i)password|secret|api[-_]?key|access[-_]?key|token|authorization|credential`)
+
+const maskedValue = "********"
+
+// mountAdminConfig registers the runtime config API on r. Every change is audited.
+func mountAdminConfig(r chi.Router, loader configAdmin, auditor configChangeAuditor) {
+	r.Get("/aegis/admin/v1/config", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		resp := effectiveConfigResponse{
+			ConfigVersion: loader.Version(),
+			Overrides:     loader.Overrides(),
+		}
+		var err error
+		if resp.Gateway, err = maskedView(loader.Config()); err == nil {
+			if resp.Models, err = maskedView(loader.Models()); err == nil {
+				resp.Providers, err = maskedView(loader.Providers())
+			}
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to render configuration")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	r.Patch("/aegis/admin/v1/config", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+
+		var update config.RuntimeOverrides
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&update); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid config update: %v", err))
+			return
+		}
+		if update.IsZero() {
+			httputil.WriteBadRequestError(w, reqID, "Config update contains no settings")
+			return
+		}
+		if err := loader.ApplyOverrides(update); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Config update rejected: %v", err))
+			return
+		}
+
+		auditConfigChange(auditor, r, reqID, "update", overrideChanges(update))
+		writeOverrides(w, loader)
+	})
+
+	r.Delete("/aegis/admin/v1/config/overrides", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		previous := loader.Overrides()
+		if err := loader.ClearOverrides(); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Reload after clearing overrides failed: %v", err))
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "clear_overrides", overrideChanges(previous))
+		writeOverrides(w, loader)
+	})
+
+	r.Post("/aegis/admin/v1/config/reload", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		previousVersion := loader.Version()
+		if err := loader.Reload(); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Config reload rejected: %v", err))
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "reload", map[string]interface{}{
+			"previous_version": previousVersion,
+			"version":          loader.Version(),
+		})
+		writeOverrides(w, loader)
+	})
+
+	// Re-reads one provider's secret references now, so an upstream key
+	// rotation takes effect without waiting for secrets.refresh_interval.
+	// Only that provider's adapter is rebuilt.
+	r.Post("/aegis/admin/v1/providers/{name}/secrets/refresh", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		name := chi.URLParam(r, "name")
+		rotated, err := loader.RefreshProviderSecrets(name)
+		switch {
+		case errors.Is(err, config.ErrUnknownProvider):
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "provider_not_found", "Provider not found")
+			return
+		case errors.Is(err, config.ErrNoSecretRefs):
+			httputil.WriteBadRequestError(w, reqID, "Provider credentials hold no secret references; reload the config to re-read them")
+			return
+		case err != nil && rotated:
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Config reload rejected: %v", err))
+			return
+		case err != nil:
+			httputil.WriteServiceUnavailableError(w, reqID, fmt.Sprintf("Secret refresh failed: %v", err))
+			return
+		}
+		if rotated {
+			auditConfigChange(auditor, r, reqID, "provider_secret_refresh", map[string]interface{}{"provider": name})
+		}
+		writeJSON(w, http.StatusOK, secretRefreshResponse{Provider: name, Rotated: rotated, ConfigVersion: loader.Version()})
+	})
+
+	r.Get("/aegis/admin/v1/config/versions", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"versions": loader.History()})
+	})
+
+	r.Post("/aegis/admin/v1/config/rollback", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+
+		var body struct {
+			Version string `json:"version"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil || body.Version == "" {
+			httputil.WriteBadRequestError(w, reqID, "Rollback requires a JSON body with a version")
+			return
+		}
+		previousVersion := loader.Version()
+		if err := loader.Rollback(body.Version); err != nil {
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "version_not_found", err.Error())
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "rollback", map[string]interface{}{
+			"previous_version": previousVersion,
+			"version":          body.Version,
+		})
+		writeOverrides(w, loader)
+	})
+}
+
+func writeOverrides(w http.ResponseWriter, loader configAdmin) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(overridesResponse{
+		ConfigVersion: loader.Version(),
+		Overrides:     loader.Overrides(),
+	})
+}
+
+func auditConfigChange(auditor configChangeAuditor, r *http.Request, reqID, action string, changes map[string]interface{}) {
+	if auditor == nil {
+		return
+	}
+	var orgID, teamID, keyID string
+	if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+		orgID, teamID, keyID = authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID
+	}
+	auditor.LogConfigChange(reqID, orgID, teamID, keyID, action, changes, r.RemoteAddr)
+}
+
+// overrideChanges flattens the set fields of o for the audit record.
+func overrideChanges(o config.RuntimeOverrides) map[string]interface{} {
+	changes := map[string]interface{}{}
+	data, _ := json.Marshal(o)
+	_ = json.Unmarshal(data, &changes)
+	return changes
+}
+
+// maskedView renders v using its YAML field names, with credential values masked.
+func maskedView(v any) (any, error) {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic map[string]any
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return maskSecrets(generic), nil
+}
+
+func maskSecrets(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if s, ok := val.(string); ok && s != "" && sensitiveKey.MatchString(k) {
+				t[k] = maskedValue
+				continue
+			}
+			t[k] = maskSecrets(val)
+		}
+		return t
+	case []any:
+		for i, val := range t {
+			t[i] = maskSecrets(val)
+		}
+		return t
+	default:
+		return v
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/go-chi/chi/v5"
+)
+
+type recordedChange struct {
+	org     string
+	action  string
+	changes map[string]interface{}
+}
+
+type fakeConfigAuditor struct {
+	changes []recordedChange
+}
+
+func (f *fakeConfigAuditor) LogConfigChange(requestID, orgID, teamID, keyID, action string, changes map[string]interface{}, ip string) {
+	f.changes = append(f.changes, recordedChange{org: orgID, action: action, changes: changes})
+}
+
+func newAdminConfigTestServer(t *testing.T) (*config.Loader, *fakeConfigAuditor, http.Handler) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"gateway.yaml": "database:\n  password: \"db-secret\"\nfilter:\n  injection:\n    enabled: true\n    block_threshold: 0.9\n    flag_threshold: 0.7\n",
+		"models.yaml":  "models:\n  m:\n    primary:\n      provider: anthropic\n      model: claude\n",
+		"providers.yaml": `providers:
+  anthropic:
+    type: anthropic
+    base_url: https://api.anthropic.com/v1
+    api_key: "sk-ant-secret"
+    headers:
+      x-api-key: "sk-header-secret"
+      anthropic-version: "2023-06-01"
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	loader := config.NewLoader(dir, slog.Default())
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	mountAdminConfig(r, loader, auditor)
+	return loader, auditor, r
+}
+
+func TestAdminConfig_GetMasksSecrets(t *testing.T) {
+	_, _, h := newAdminConfigTestServer(t)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/config", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := w.Body.String()
+	for _, secret := range []string{"db-secret", "sk-ant-secret", "sk-header-secret"} {
+		if strings.Contains(body, secret) {
+			t.Errorf("response leaks secret %q", secret)
+		}
+	}
+	var resp effectiveConfigResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	providers := resp.Providers.(map[string]any)["providers"].(map[string]any)
+	anthropic := providers["anthropic"].(map[string]any)
+	if anthropic["api_key"] != maskedValue {
+		t.Errorf("expected masked api_key, got %v", anthropic["api_key"])
+	}
+	if anthropic["headers"].(map[string]any)["anthropic-version"] != "2023-06-01" {
+		t.Errorf("expected non-secret header to be shown, got %v", anthropic["headers"])
+	}
+	if resp.ConfigVersion == "" {
+		t.Error("expected config version")
+	}
+}
+
+func TestAdminConfig_PatchAppliesAndAudits(t *testing.T) {
+	loader, auditor, h := newAdminConfigTestServer(t)
+
+	req := httptest.NewRequest("PATCH", "/aegis/admin/v1/config", strings.NewReader(`{"injection_block_threshold":0.8,"log_level":"debug","routing_strategy":"cheapest"}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	cfg := loader.Config()
+	if cfg.Filter.Injection.BlockThreshold != 0.8 || cfg.Telemetry.LogLevel != "debug" || cfg.Routing.Strategy != config.RoutingStrategyCheapest {
+		t.Errorf("expected overrides applied, got threshold=%v level=%q strategy=%q", cfg.Filter.Injection.BlockThreshold, cfg.Telemetry.LogLevel, cfg.Routing.Strategy)
+	}
+	if len(auditor.changes) != 1 || auditor.changes[0].action != "update" || auditor.changes[0].changes["injection_block_threshold"] != 0.8 {
+		t.Errorf("expected one audited update, got %+v", auditor.changes)
+	}
+
+	// Overrides survive a reload from the files.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/config/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("reload: expected 200, got %d", w.Code)
+	}
+	if loader.Config().Filter.Injection.BlockThreshold != 0.8 {
+		t.Error("expected override to survive reload")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/config/overrides", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("clear: expected 200, got %d", w.Code)
+	}
+	if loader.Config().Filter.Injection.BlockThreshold != 0.9 {
+		t.Error("expected file value after clearing overrides")
+	}
+	if len(auditor.changes) != 3 || auditor.changes[1].action != "reload" || auditor.changes[2].action != "clear_overrides" {
+		t.Errorf("expected reload and clear to be audited, got %+v", auditor.changes)
+	}
+}
+
+func TestAdminConfig_PatchRejectsInvalid(t *testing.T) {
+	loader, auditor, h := newAdminConfigTestServer(t)
+
+	for _, body := range []string{
+		`{"injection_flag_threshold":0.95}`, // above block threshold
+		`{"routing_strategy":"fastest"}`,    // not a strategy
+		`{"max_in_flight":10}`,              // not a dynamic setting
+		`{}`,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("PATCH", "/aegis/admin/v1/config", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+	if loader.Config().Filter.Injection.FlagThreshold != 0.7 {
+		t.Error("expected rejected update to leave config unchanged")
+	}
+	if !loader.Overrides().IsZero() {
+		t.Errorf("expected rejected update to leave no overrides, got %+v", loader.Overrides())
+	}
+	if len(auditor.changes) != 0 {
+		t.Errorf("expected no audited changes, got %+v", auditor.changes)
+	}
+}
+
+func TestAdminConfig_Rollback(t *testing.T) {
+	loader, auditor, h := newAdminConfigTestServer(t)
+	original := loader.Version()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PATCH", "/aegis/admin/v1/config", strings.NewReader(`{"injection_enabled":false}`)))
+	if w.Code != http.StatusOK || loader.Config().Filter.Injection.Enabled {
+		t.Fatalf("expected injection filter disabled, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/config/versions", nil))
+	var versions struct {
+		Versions []config.VersionInfo `json:"versions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &versions); err != nil || len(versions.Versions) != 2 {
+		t.Fatalf("expected 2 versions, got %s (err %v)", w.Body.String(), err)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/config/rollback", strings.NewReader(`{"version":"`+original+`"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("rollback: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if loader.Version() != original || !loader.Config().Filter.Injection.Enabled {
+		t.Errorf("expected rollback to %s with injection enabled, got %s", original, loader.Version())
+	}
+	last := auditor.changes[len(auditor.changes)-1]
+	if last.action != "rollback" || last.changes["version"] != original {
+		t.Errorf("expected audited rollback, got %+v", last)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/config/rollback", strings.NewReader(`{"version":"nope"}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown version, got %d", w.Code)
+	}
+}
+
+func TestAdminConfig_RefreshProviderSecrets(t *testing.T) {
+	_, auditor, h := newAdminConfigTestServer(t)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/providers/nope/secrets/refresh", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown provider, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/providers/anthropic/secrets/refresh", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for literal credentials, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(auditor.changes) != 0 {
+		t.Errorf("expected nothing audited, got %+v", auditor.changes)
+	}
+}
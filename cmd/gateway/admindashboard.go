@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	dashboardDefaultLimit = 50
+	dashboardMaxLimit     = 1000
+	dashboardDefaultRange = 7 * 24 * time.Hour
+)
+
+// dashboardQuerier is the subset of storage.UsageRecorder the dashboard API
+// needs. It is satisfied by *storage.UsageRecorder.
+type dashboardQuerier interface {
+	TopModelsBySpend(ctx context.Context, q storage.DashboardQuery) ([]storage.ModelSpend, error)
+	BlocksByFilter(ctx context.Context, q storage.DashboardQuery) ([]storage.FilterBlocks, error)
+	ProviderHealthHistory(ctx context.Context, q storage.DashboardQuery, bucket, provider string) ([]storage.ProviderHealthPoint, error)
+	TeamUsageTrend(ctx context.Context, q storage.DashboardQuery, bucket, team string) ([]storage.TeamUsagePoint, error)
+}
+
+// dashboardPage is the body of every dashboard endpoint. NextOffset is set
+// when more rows follow.
+type dashboardPage[T any] struct {
+	OrganizationID string `json:"organization_id,omitempty"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Bucket         string `json:"bucket,omitempty"`
+	Data           []T    `json:"data"`
+	NextOffset     *int   `json:"next_offset,omitempty"`
+}
+
+// mountAdminDashboard registers the read-only aggregates behind the internal
+// dashboard. Every endpoint takes from and to (RFC 3339 or YYYY-MM-DD,
+// default the last 7 days), an optional org, and limit and offset.
+func mountAdminDashboard(r chi.Router, dash dashboardQuerier) {
+	r.Get("/aegis/admin/v1/dashboard/models", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseDashboardQuery(w, r)
+		if !ok {
+			return
+		}
+		rows, err := dash.TopModelsBySpend(r.Context(), q)
+		writeDashboardPage(w, q, "", rows, err)
+	})
+
+	r.Get("/aegis/admin/v1/dashboard/filters", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseDashboardQuery(w, r)
+		if !ok {
+			return
+		}
+		rows, err := dash.BlocksByFilter(r.Context(), q)
+		writeDashboardPage(w, q, "", rows, err)
+	})
+
+	r.Get("/aegis/admin/v1/dashboard/providers", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseDashboardQuery(w, r)
+		if !ok {
+			return
+		}
+		bucket, ok := parseDashboardBucket(w, r, storage.BucketHour)
+		if !ok {
+			return
+		}
+		rows, err := dash.ProviderHealthHistory(r.Context(), q, bucket, r.URL.Query().Get("provider"))
+		writeDashboardPage(w, q, bucket, rows, err)
+	})
+
+	r.Get("/aegis/admin/v1/dashboard/teams", func(w http.ResponseWriter, r *http.Request) {
+		q, ok := parseDashboardQuery(w, r)
+		if !ok {
+			return
+		}
+		bucket, ok := parseDashboardBucket(w, r, storage.BucketDay)
+		if !ok {
+			return
+		}
+		rows, err := dash.TeamUsageTrend(r.Context(), q, bucket, r.URL.Query().Get("team"))
+		writeDashboardPage(w, q, bucket, rows, err)
+	})
+}
+
+// parseDashboardQuery reads the time range, org, and page. The limit asked
+// of the store is one more than the page so a following page can be
+// detected. It writes a 400 and returns false on bad input.
+func parseDashboardQuery(w http.ResponseWriter, r *http.Request) (storage.DashboardQuery, bool) {
+	reqID := w.Header().Get("X-Request-ID")
+	v := r.URL.Query()
+	q := storage.DashboardQuery{OrganizationID: v.Get("org"), To: time.Now().UTC(), Limit: dashboardDefaultLimit}
+	q.From = q.To.Add(-dashboardDefaultRange)
+
+	var err error
+	if s := v.Get("from"); s != "" {
+		if q.From, err = parseUsageTime(s); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid from: %v", err))
+			return q, false
+		}
+	}
+	if s := v.Get("to"); s != "" {
+		if q.To, err = parseUsageTime(s); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid to: %v", err))
+			return q, false
+		}
+	}
+	if !q.From.Before(q.To) {
+		httputil.WriteBadRequestError(w, reqID, "from must be before to")
+		return q, false
+	}
+	if s := v.Get("limit"); s != "" {
+		if q.Limit, err = strconv.Atoi(s); err != nil || q.Limit < 1 || q.Limit > dashboardMaxLimit {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("limit must be between 1 and %d", dashboardMaxLimit))
+			return q, false
+		}
+	}
+	if s := v.Get("offset"); s != "" {
+		if q.Offset, err = strconv.Atoi(s); err != nil || q.Offset < 0 {
+			httputil.WriteBadRequestError(w, reqID, "offset must be a non-negative integer")
+			return q, false
+		}
+	}
+	q.Limit++
+	return q, true
+}
+
+func parseDashboardBucket(w http.ResponseWriter, r *http.Request, fallback string) (string, bool) {
+	bucket := r.URL.Query().Get("bucket")
+	if bucket == "" {
+		return fallback, true
+	}
+	if !storage.ValidBucket(bucket) {
+		httputil.WriteBadRequestError(w, w.Header().Get("X-Request-ID"), "bucket must be hour or day")
+		return "", false
+	}
+	return bucket, true
+}
+
+// writeDashboardPage writes rows fetched with parseDashboardQuery's limit,
+// trimming the extra row into a next_offset.
+func writeDashboardPage[T any](w http.ResponseWriter, q storage.DashboardQuery, bucket string, rows []T, err error) {
+	if err != nil {
+		httputil.WriteInternalError(w, w.Header().Get("X-Request-ID"), "Failed to query dashboard data")
+		return
+	}
+	page := dashboardPage[T]{
+		OrganizationID: q.OrganizationID,
+		From:           q.From.Format(time.RFC3339),
+		To:             q.To.Format(time.RFC3339),
+		Bucket:         bucket,
+		Data:           rows,
+	}
+	if page.Data == nil {
+		page.Data = []T{}
+	}
+	if limit := q.Limit - 1; len(page.Data) > limit {
+		page.Data = page.Data[:limit]
+		next := q.Offset + limit
+		page.NextOffset = &next
+	}
+	writeJSON(w, http.StatusOK, page)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeDashboard struct {
+	models         []storage.ModelSpend
+	err            error
+	query          storage.DashboardQuery
+	bucket, filter string
+}
+
+func (f *fakeDashboard) TopModelsBySpend(_ context.Context, q storage.DashboardQuery) ([]storage.ModelSpend, error) {
+	f.query = q
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.models[min(q.Offset, len(f.models)):min(q.Offset+q.Limit, len(f.models))], nil
+}
+
+func (f *fakeDashboard) BlocksByFilter(_ context.Context, q storage.DashboardQuery) ([]storage.FilterBlocks, error) {
+	f.query = q
+	return []storage.FilterBlocks{{Filter: "secrets", Blocks: 3, Organizations: 1}}, nil
+}
+
+func (f *fakeDashboard) ProviderHealthHistory(_ context.Context, q storage.DashboardQuery, bucket, provider string) ([]storage.ProviderHealthPoint, error) {
+	f.query, f.bucket, f.filter = q, bucket, provider
+	return nil, nil
+}
+
+func (f *fakeDashboard) TeamUsageTrend(_ context.Context, q storage.DashboardQuery, bucket, team string) ([]storage.TeamUsagePoint, error) {
+	f.query, f.bucket, f.filter = q, bucket, team
+	return nil, nil
+}
+
+func newAdminDashboardTestServer() (*fakeDashboard, http.Handler) {
+	dash := &fakeDashboard{models: []storage.ModelSpend{
+		{Model: "aegis-gpt4", CostUSD: 30}, {Model: "aegis-fast", CostUSD: 20}, {Model: "aegis-reasoning", CostUSD: 10},
+	}}
+	r := chi.NewRouter()
+	mountAdminDashboard(r, dash)
+	return dash, r
+}
+
+func getDashboard(t *testing.T, h http.Handler, url string, into any) *httptest.ResponseRecorder {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	if into != nil && w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), into); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w
+}
+
+func TestAdminDashboard_ModelsPaginates(t *testing.T) {
+	dash, h := newAdminDashboardTestServer()
+
+	var page dashboardPage[storage.ModelSpend]
+	w := getDashboard(t, h, "/aegis/admin/v1/dashboard/models?org=org-1&from=2026-01-01&to=2026-02-01&limit=2", &page)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	if dash.query.OrganizationID != "org-1" || !dash.query.From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("query = %+v", dash.query)
+	}
+	if len(page.Data) != 2 || page.Data[0].Model != "aegis-gpt4" || page.NextOffset == nil || *page.NextOffset != 2 {
+		t.Fatalf("page = %+v", page)
+	}
+
+	page = dashboardPage[storage.ModelSpend]{}
+	getDashboard(t, h, "/aegis/admin/v1/dashboard/models?limit=2&offset=2", &page)
+	if len(page.Data) != 1 || page.Data[0].Model != "aegis-reasoning" || page.NextOffset != nil {
+		t.Errorf("last page = %+v", page)
+	}
+}
+
+func TestAdminDashboard_Trends(t *testing.T) {
+	dash, h := newAdminDashboardTestServer()
+
+	var page dashboardPage[storage.ProviderHealthPoint]
+	getDashboard(t, h, "/aegis/admin/v1/dashboard/providers?provider=openai", &page)
+	if dash.bucket != storage.BucketHour || dash.filter != "openai" || page.Bucket != storage.BucketHour || page.Data == nil {
+		t.Errorf("providers: bucket %q, filter %q, page %+v", dash.bucket, dash.filter, page)
+	}
+	if got := dash.query.To.Sub(dash.query.From); got != dashboardDefaultRange {
+		t.Errorf("default range = %v", got)
+	}
+
+	getDashboard(t, h, "/aegis/admin/v1/dashboard/teams?team=team-1&bucket=hour", nil)
+	if dash.bucket != storage.BucketHour || dash.filter != "team-1" {
+		t.Errorf("teams: bucket %q, filter %q", dash.bucket, dash.filter)
+	}
+
+	var blocks dashboardPage[storage.FilterBlocks]
+	getDashboard(t, h, "/aegis/admin/v1/dashboard/filters", &blocks)
+	if len(blocks.Data) != 1 || blocks.Data[0].Filter != "secrets" {
+		t.Errorf("filters = %+v", blocks)
+	}
+}
+
+func TestAdminDashboard_RejectsBadInput(t *testing.T) {
+	_, h := newAdminDashboardTestServer()
+	for _, url := range []string{
+		"/aegis/admin/v1/dashboard/models?from=yesterday",
+		"/aegis/admin/v1/dashboard/models?from=2026-02-01&to=2026-01-01",
+		"/aegis/admin/v1/dashboard/models?limit=0",
+		"/aegis/admin/v1/dashboard/models?limit=5000",
+		"/aegis/admin/v1/dashboard/models?offset=-1",
+		"/aegis/admin/v1/dashboard/teams?bucket=week",
+	} {
+		if w := getDashboard(t, h, url, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", url, w.Code)
+		}
+	}
+
+	dash, h := newAdminDashboardTestServer()
+	dash.err = errors.New("db down")
+	if w := getDashboard(t, h, "/aegis/admin/v1/dashboard/models", nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("store error: status = %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultFeedbackListLimit = 100
+	maxFeedbackListLimit     = 1000
+)
+
+// feedbackAdmin is the subset of storage.FeedbackStore the review API
+// needs. It is satisfied by *storage.FeedbackStore.
+type feedbackAdmin interface {
+	ListFeedback(ctx context.Context, f storage.FeedbackFilter) ([]storage.FilterFeedback, error)
+	ReviewFeedback(ctx context.Context, id, status, note, reviewedBy string) (*storage.FilterFeedback, error)
+}
+
+// reviewFeedbackRequest is the body of PATCH /aegis/admin/v1/feedback/{id}.
+type reviewFeedbackRequest struct {
+	// Status is accepted (a false positive) or rejected (the block stands).
+	Status string `json:"status"`
+	Note   string `json:"note"`
+}
+
+// mountAdminFeedback registers the security team's review queue for
+// false-positive reports on filter blocks. Verdicts are audited.
+func mountAdminFeedback(r chi.Router, feedback feedbackAdmin, auditor configChangeAuditor) {
+	r.Get("/aegis/admin/v1/feedback", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		q := r.URL.Query()
+		f := storage.FeedbackFilter{
+			OrganizationID: q.Get("org"),
+			Filter:         q.Get("filter"),
+			Status:         q.Get("status"),
+			Limit:          defaultFeedbackListLimit,
+		}
+		if v := q.Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				httputil.WriteBadRequestError(w, reqID, "limit must be a positive integer")
+				return
+			}
+			f.Limit = min(n, maxFeedbackListLimit)
+		}
+		list, err := feedback.ListFeedback(r.Context(), f)
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to list feedback")
+			return
+		}
+		if list == nil {
+			list = []storage.FilterFeedback{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"feedback": list})
+	})
+
+	r.Patch("/aegis/admin/v1/feedback/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		id := chi.URLParam(r, "id")
+
+		var req reviewFeedbackRequest
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8192))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid review: %v", err))
+			return
+		}
+		if req.Status != storage.FeedbackAccepted && req.Status != storage.FeedbackRejected {
+			httputil.WriteBadRequestError(w, reqID, "status must be accepted or rejected")
+			return
+		}
+
+		var reviewer string
+		if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+			reviewer = authInfo.KeyID
+		}
+		fb, err := feedback.ReviewFeedback(r.Context(), id, req.Status, req.Note, reviewer)
+		if errors.Is(err, storage.ErrFeedbackNotFound) {
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "feedback_not_found", "Feedback not found")
+			return
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to review feedback")
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "filter_feedback_review", map[string]interface{}{
+			"feedback_id": id,
+			"request_id":  fb.RequestID,
+			"filter":      fb.Filter,
+			"status":      req.Status,
+		})
+		writeJSON(w, http.StatusOK, fb)
+	})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeFeedback struct {
+	items  map[string]*storage.FilterFeedback
+	filter storage.FeedbackFilter
+}
+
+func (f *fakeFeedback) ListFeedback(_ context.Context, filter storage.FeedbackFilter) ([]storage.FilterFeedback, error) {
+	f.filter = filter
+	var out []storage.FilterFeedback
+	for _, fb := range f.items {
+		out = append(out, *fb)
+	}
+	return out, nil
+}
+
+func (f *fakeFeedback) ReviewFeedback(_ context.Context, id, status, note, reviewedBy string) (*storage.FilterFeedback, error) {
+	fb, ok := f.items[id]
+	if !ok {
+		return nil, storage.ErrFeedbackNotFound
+	}
+	fb.Status, fb.ReviewNote, fb.ReviewedBy = status, note, reviewedBy
+	return fb, nil
+}
+
+func newAdminFeedbackTestServer() (*fakeFeedback, *fakeConfigAuditor, http.Handler) {
+	feedback := &fakeFeedback{items: map[string]*storage.FilterFeedback{
+		"fb_1": {ID: "fb_1", RequestID: "req-1", OrganizationID: "org-1", Filter: "pii", Status: storage.FeedbackOpen},
+	}}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	mountAdminFeedback(r, feedback, auditor)
+	return feedback, auditor, r
+}
+
+func TestAdminFeedback_List(t *testing.T) {
+	feedback, _, h := newAdminFeedbackTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/feedback?org=org-1&filter=pii&status=open&limit=5000", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := storage.FeedbackFilter{OrganizationID: "org-1", Filter: "pii", Status: "open", Limit: maxFeedbackListLimit}
+	if feedback.filter != want {
+		t.Errorf("filter = %+v, want %+v", feedback.filter, want)
+	}
+	var body struct {
+		Feedback []storage.FilterFeedback `json:"feedback"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Feedback) != 1 || body.Feedback[0].ID != "fb_1" {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/feedback?limit=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: expected 400, got %d", w.Code)
+	}
+}
+
+func TestAdminFeedback_Review(t *testing.T) {
+	feedback, auditor, h := newAdminFeedbackTestServer()
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/aegis/admin/v1/feedback/"+id, bytes.NewBufferString(body))
+		req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{OrganizationID: "ops", KeyID: "admin-key"}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch("fb_1", `{"status":"accepted","note":"allowlisted the SKU pattern"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if fb := feedback.items["fb_1"]; fb.Status != storage.FeedbackAccepted || fb.ReviewedBy != "admin-key" {
+		t.Errorf("unexpected review %+v", fb)
+	}
+	if len(auditor.changes) != 1 || auditor.changes[0].action != "filter_feedback_review" || auditor.changes[0].changes["filter"] != "pii" {
+		t.Errorf("expected an audited review, got %+v", auditor.changes)
+	}
+
+	for _, tt := range []struct {
+		id, body string
+		want     int
+	}{
+		{"fb_1", `{"status":"open"}`, http.StatusBadRequest},
+		{"fb_1", `{"status":"rejected","extra":1}`, http.StatusBadRequest},
+		{"fb_missing", `{"status":"rejected"}`, http.StatusNotFound},
+	} {
+		if w := patch(tt.id, tt.body); w.Code != tt.want {
+			t.Errorf("PATCH %s %s: expected %d, got %d", tt.id, tt.body, tt.want, w.Code)
+		}
+	}
+	if len(auditor.changes) != 1 {
+		t.Errorf("failed reviews should not be audited, got %+v", auditor.changes)
+	}
+}
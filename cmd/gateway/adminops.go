@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/af-corp/aegis-gateway/internal/types"
+	"github.com/go-chi/chi/v5"
+)
+
+// keyAdmin is the subset of auth.KeyManager the admin key API needs.
+type keyAdmin interface {
+	ListKeys(ctx context.Context, f auth.KeyFilter) ([]auth.KeyInfo, error)
+	GetKey(ctx context.Context, id string) (*auth.KeyInfo, error)
+	CreateKey(ctx context.Context, nk auth.NewKey) (string, *auth.KeyInfo, error)
+	RevokeKey(ctx context.Context, id, reason string) (*auth.KeyInfo, error)
+	SetLimits(ctx context.Context, id string, l auth.KeyLimits) (*auth.KeyInfo, error)
+	ListOrgs(ctx context.Context) ([]auth.OrgSummary, error)
+	SuspendOrg(ctx context.Context, orgID, reason, suspendedBy string) (*auth.Suspension, error)
+	ResumeOrg(ctx context.Context, orgID string) error
+	SuspendTeam(ctx context.Context, orgID, teamID, reason, suspendedBy string) (*auth.Suspension, error)
+	ResumeTeam(ctx context.Context, orgID, teamID string) error
+	SetModelRules(ctx context.Context, id string, rules auth.ModelRules) (*auth.KeyInfo, error)
+	GetTeamModelRules(ctx context.Context, orgID, teamID string) (*auth.TeamModelRules, error)
+	SetTeamModelRules(ctx context.Context, orgID, teamID string, rules auth.ModelRules, updatedBy string) (*auth.TeamModelRules, error)
+	ClearTeamModelRules(ctx context.Context, orgID, teamID string) error
+}
+
+// providerQuarantiner is the subset of router.HealthTracker used to pull
+// providers out of routing.
+type providerQuarantiner interface {
+	Quarantine(provider, reason string) error
+	Release(provider string) (bool, error)
+	Quarantined() map[string]router.Quarantine
+}
+
+// providerLookup reports which providers are configured. It is satisfied by
+// *router.Registry.
+type providerLookup interface {
+	Get(name string) (adapters.ProviderAdapter, bool)
+}
+
+// usageQuerier is the subset of storage.UsageRecorder the usage API needs.
+type usageQuerier interface {
+	GetUsageSummary(ctx context.Context, orgID string, start, end time.Time) (*storage.UsageSummary, error)
+}
+
+// createKeyRequest is the body of POST /aegis/admin/v1/keys.
+type createKeyRequest struct {
+	auth.NewKey
+	// ExpiresIn accepts "365d" or a Go duration; default 365d.
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// createKeyResponse includes the raw key, which is shown only once.
+type createKeyResponse struct {
+	Key    string        `json:"key"`
+	APIKey *auth.KeyInfo `json:"api_key"`
+}
+
+// usageResponse is returned by GET /aegis/admin/v1/usage.
+type usageResponse struct {
+	OrganizationID string `json:"organization_id"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	*storage.UsageSummary
+}
+
+// mountAdminOps registers the day-2 operations API used by aegisctl: API key
+// lifecycle, limits, and model rules, organization inventory and suspension,
+// team model rules, provider quarantine, and usage summaries. Mutations are audited like config changes;
+// under strict tenancy, changes to an organization's keys are audited under
+// that organization. Only providers in configured may be quarantined.
+func mountAdminOps(r chi.Router, keys keyAdmin, providers providerQuarantiner, configured providerLookup, usage usageQuerier, auditor configChangeAuditor, strictTenancy bool) {
+	r.Get("/aegis/admin/v1/keys", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		q := r.URL.Query()
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		list, err := keys.ListKeys(r.Context(), auth.KeyFilter{
+			OrganizationID: q.Get("org"),
+			TeamID:         q.Get("team"),
+			Status:         q.Get("status"),
+			Limit:          limit,
+		})
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to list API keys")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"keys": list})
+	})
+
+	r.Get("/aegis/admin/v1/keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		k, err := keys.GetKey(r.Context(), chi.URLParam(r, "id"))
+		if writeKeyError(w, reqID, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, k)
+	})
+
+	r.Post("/aegis/admin/v1/keys", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+
+		var req createKeyRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid key request: %v", err))
+			return
+		}
+		if req.OrganizationID == "" || req.TeamID == "" || req.Name == "" {
+			httputil.WriteBadRequestError(w, reqID, "organization_id, team_id, and name are required")
+			return
+		}
+		if req.MaxClassification != "" {
+			if _, ok := types.ParseClassification(req.MaxClassification); !ok {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Unknown classification %q", req.MaxClassification))
+				return
+			}
+		}
+		if req.Priority != "" {
+			if _, ok := types.ParsePriority(req.Priority); !ok {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Unknown priority %q", req.Priority))
+				return
+			}
+		}
+		if err := req.ModelRules.Validate(); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid model rules: %v", err))
+			return
+		}
+		expiresIn := "365d"
+		if req.ExpiresIn != "" {
+			expiresIn = req.ExpiresIn
+		}
+		dur, err := auth.ParseDuration(expiresIn)
+		if err != nil || dur <= 0 {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid expires_in %q", expiresIn))
+			return
+		}
+		req.NewKey.ExpiresIn = dur
+
+		rawKey, k, err := keys.CreateKey(r.Context(), req.NewKey)
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to create API key")
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "key_create", k.OrganizationID, strictTenancy, map[string]interface{}{
+			"api_key_id": k.ID,
+			"org_id":     k.OrganizationID,
+			"team_id":    k.TeamID,
+			"name":       k.Name,
+		})
+		writeJSON(w, http.StatusCreated, createKeyResponse{Key: rawKey, APIKey: k})
+	})
+
+	r.Delete("/aegis/admin/v1/keys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		id := chi.URLParam(r, "id")
+		k, err := keys.RevokeKey(r.Context(), id, r.URL.Query().Get("reason"))
+		if writeKeyError(w, reqID, err) {
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "key_revoke", k.OrganizationID, strictTenancy, map[string]interface{}{
+			"api_key_id": id,
+			"reason":     r.URL.Query().Get("reason"),
+		})
+		writeJSON(w, http.StatusOK, k)
+	})
+
+	r.Patch("/aegis/admin/v1/keys/{id}/limits", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		id := chi.URLParam(r, "id")
+
+		var limits auth.KeyLimits
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&limits); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid limits update: %v", err))
+			return
+		}
+		if limits == (auth.KeyLimits{}) {
+			httputil.WriteBadRequestError(w, reqID, "Limits update contains no settings")
+			return
+		}
+		for _, v := range []*int{limits.RPMLimit, limits.TPMLimit, limits.DailySpendLimitCents} {
+			if v != nil && *v < 0 {
+				httputil.WriteBadRequestError(w, reqID, "Limits must not be negative (0 restores the default)")
+				return
+			}
+		}
+		if limits.Priority != "" {
+			if _, ok := types.ParsePriority(limits.Priority); !ok {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Unknown priority %q", limits.Priority))
+				return
+			}
+		}
+		k, err := keys.SetLimits(r.Context(), id, limits)
+		if writeKeyError(w, reqID, err) {
+			return
+		}
+		changes := map[string]interface{}{"api_key_id": id}
+		data, _ := json.Marshal(limits)
+		_ = json.Unmarshal(data, &changes)
+		auditTenantChange(auditor, r, reqID, "key_limits", k.OrganizationID, strictTenancy, changes)
+		writeJSON(w, http.StatusOK, k)
+	})
+
+	// Model rules replace the key's own rules wholesale; an empty body
+	// clears them.
+	r.Put("/aegis/admin/v1/keys/{id}/models", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		id := chi.URLParam(r, "id")
+		rules, ok := decodeModelRules(w, r, reqID)
+		if !ok {
+			return
+		}
+		k, err := keys.SetModelRules(r.Context(), id, rules)
+		if writeKeyError(w, reqID, err) {
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "key_model_rules", k.OrganizationID, strictTenancy, map[string]interface{}{
+			"api_key_id":     id,
+			"default_model":  rules.DefaultModel,
+			"model_rewrites": rules.ModelRewrites,
+		})
+		writeJSON(w, http.StatusOK, k)
+	})
+
+	r.Get("/aegis/admin/v1/orgs", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		orgs, err := keys.ListOrgs(r.Context())
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to list organizations")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"organizations": orgs})
+	})
+
+	// Suspensions apply to a whole organization, or to one team when the
+	// path names it.
+	suspend := func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		org, team := chi.URLParam(r, "org"), chi.URLParam(r, "team")
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid suspend request: %v", err))
+				return
+			}
+		}
+		var actor string
+		if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+			actor = authInfo.KeyID
+		}
+		action := "org_suspend"
+		changes := map[string]interface{}{"org_id": org, "reason": body.Reason}
+		var suspension *auth.Suspension
+		var err error
+		if team == "" {
+			suspension, err = keys.SuspendOrg(r.Context(), org, body.Reason, actor)
+		} else {
+			action, changes["team_id"] = "team_suspend", team
+			suspension, err = keys.SuspendTeam(r.Context(), org, team, body.Reason, actor)
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to suspend "+suspensionTarget(org, team))
+			return
+		}
+		auditTenantChange(auditor, r, reqID, action, org, strictTenancy, changes)
+		writeJSON(w, http.StatusOK, suspension)
+	}
+
+	resume := func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		org, team := chi.URLParam(r, "org"), chi.URLParam(r, "team")
+		action := "org_resume"
+		changes := map[string]interface{}{"org_id": org}
+		var err error
+		if team == "" {
+			err = keys.ResumeOrg(r.Context(), org)
+		} else {
+			action, changes["team_id"] = "team_resume", team
+			err = keys.ResumeTeam(r.Context(), org, team)
+		}
+		if errors.Is(err, auth.ErrNotSuspended) {
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "not_suspended",
+				fmt.Sprintf("The %s is not suspended", suspensionTarget(org, team)))
+			return
+		}
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to resume "+suspensionTarget(org, team))
+			return
+		}
+		auditTenantChange(auditor, r, reqID, action, org, strictTenancy, changes)
+		writeJSON(w, http.StatusOK, auth.Suspension{OrganizationID: org, TeamID: team})
+	}
+
+	r.Post("/aegis/admin/v1/orgs/{org}/suspend", suspend)
+	r.Delete("/aegis/admin/v1/orgs/{org}/suspend", resume)
+	r.Post("/aegis/admin/v1/orgs/{org}/teams/{team}/suspend", suspend)
+	r.Delete("/aegis/admin/v1/orgs/{org}/teams/{team}/suspend", resume)
+
+	r.Get("/aegis/admin/v1/orgs/{org}/teams/{team}/models", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		org, team := chi.URLParam(r, "org"), chi.URLParam(r, "team")
+		rules, err := keys.GetTeamModelRules(r.Context(), org, team)
+		if writeModelRulesError(w, reqID, org, team, err) {
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+	})
+
+	r.Put("/aegis/admin/v1/orgs/{org}/teams/{team}/models", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		org, team := chi.URLParam(r, "org"), chi.URLParam(r, "team")
+		rules, ok := decodeModelRules(w, r, reqID)
+		if !ok {
+			return
+		}
+		var actor string
+		if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+			actor = authInfo.KeyID
+		}
+		saved, err := keys.SetTeamModelRules(r.Context(), org, team, rules, actor)
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to set model rules for "+suspensionTarget(org, team))
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "team_model_rules", org, strictTenancy, map[string]interface{}{
+			"org_id":         org,
+			"team_id":        team,
+			"default_model":  rules.DefaultModel,
+			"model_rewrites": rules.ModelRewrites,
+		})
+		writeJSON(w, http.StatusOK, saved)
+	})
+
+	r.Delete("/aegis/admin/v1/orgs/{org}/teams/{team}/models", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		org, team := chi.URLParam(r, "org"), chi.URLParam(r, "team")
+		err := keys.ClearTeamModelRules(r.Context(), org, team)
+		if writeModelRulesError(w, reqID, org, team, err) {
+			return
+		}
+		auditTenantChange(auditor, r, reqID, "team_model_rules_clear", org, strictTenancy, map[string]interface{}{
+			"org_id":  org,
+			"team_id": team,
+		})
+		writeJSON(w, http.StatusOK, auth.TeamModelRules{OrganizationID: org, TeamID: team})
+	})
+
+	r.Get("/aegis/admin/v1/providers/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"quarantined": providers.Quarantined()})
+	})
+
+	r.Post("/aegis/admin/v1/providers/{name}/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		name := chi.URLParam(r, "name")
+		if _, ok := configured.Get(name); !ok {
+			writeProviderNotFound(w, reqID, name)
+			return
+		}
+		var body struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&body); err != nil {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid quarantine request: %v", err))
+				return
+			}
+		}
+		if err := providers.Quarantine(name, body.Reason); err != nil {
+			slog.Error("failed to quarantine provider", "provider", name, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeServiceUnavailable, "Failed to record the quarantine", nil)
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "provider_quarantine", map[string]interface{}{
+			"provider": name,
+			"reason":   body.Reason,
+		})
+		writeJSON(w, http.StatusOK, map[string]any{"quarantined": providers.Quarantined()})
+	})
+
+	r.Delete("/aegis/admin/v1/providers/{name}/quarantine", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		name := chi.URLParam(r, "name")
+		// A provider removed from config while quarantined can still be
+		// released.
+		if _, quarantined := providers.Quarantined()[name]; !quarantined {
+			if _, ok := configured.Get(name); !ok {
+				writeProviderNotFound(w, reqID, name)
+				return
+			}
+		}
+		released, err := providers.Release(name)
+		if err != nil {
+			slog.Error("failed to release provider", "provider", name, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeServiceUnavailable, "Failed to record the release", nil)
+			return
+		}
+		if !released {
+			httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "not_quarantined",
+				fmt.Sprintf("Provider %q is not quarantined", name))
+			return
+		}
+		auditConfigChange(auditor, r, reqID, "provider_release", map[string]interface{}{"provider": name})
+		writeJSON(w, http.StatusOK, map[string]any{"quarantined": providers.Quarantined()})
+	})
+
+	r.Get("/aegis/admin/v1/usage", func(w http.ResponseWriter, r *http.Request) {
+		reqID := w.Header().Get("X-Request-ID")
+		q := r.URL.Query()
+		org := q.Get("org")
+		if org == "" {
+			httputil.WriteBadRequestError(w, reqID, "org is required")
+			return
+		}
+		to := time.Now().UTC()
+		from := to.AddDate(0, 0, -30)
+		var err error
+		if v := q.Get("from"); v != "" {
+			if from, err = parseUsageTime(v); err != nil {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid from: %v", err))
+				return
+			}
+		}
+		if v := q.Get("to"); v != "" {
+			if to, err = parseUsageTime(v); err != nil {
+				httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid to: %v", err))
+				return
+			}
+		}
+		if !from.Before(to) {
+			httputil.WriteBadRequestError(w, reqID, "from must be before to")
+			return
+		}
+		summary, err := usage.GetUsageSummary(r.Context(), org, from, to)
+		if err != nil {
+			httputil.WriteInternalError(w, reqID, "Failed to query usage")
+			return
+		}
+		writeJSON(w, http.StatusOK, usageResponse{
+			OrganizationID: org,
+			From:           from.Format(time.RFC3339),
+			To:             to.Format(time.RFC3339),
+			UsageSummary:   summary,
+		})
+	})
+}
+
+// auditTenantChange audits an admin change to org's resources. Under strict
+// tenancy the record belongs to org, so each tenant's audit trail is complete
+// on its own, and the acting admin key is kept in the changes; otherwise it
+// is audited like any config change.
+func auditTenantChange(auditor configChangeAuditor, r *http.Request, reqID, action, org string, strictTenancy bool, changes map[string]interface{}) {
+	if !strictTenancy {
+		auditConfigChange(auditor, r, reqID, action, changes)
+		return
+	}
+	if auditor == nil {
+		return
+	}
+	if authInfo, ok := auth.AuthFromContext(r.Context()); ok {
+		changes["actor_org_id"] = authInfo.OrganizationID
+		changes["actor_key_id"] = authInfo.KeyID
+	}
+	auditor.LogConfigChange(reqID, org, "", "", action, changes, r.RemoteAddr)
+}
+
+// parseUsageTime accepts RFC 3339 timestamps or YYYY-MM-DD dates (UTC).
+func parseUsageTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// suspensionTarget names an organization, or a team within it, for messages.
+func suspensionTarget(org, team string) string {
+	if team == "" {
+		return fmt.Sprintf("organization %q", org)
+	}
+	return fmt.Sprintf("team %q of organization %q", team, org)
+}
+
+// decodeModelRules reads and validates a model rules body, writing a 400 and
+// reporting false if it is invalid.
+func decodeModelRules(w http.ResponseWriter, r *http.Request, reqID string) (auth.ModelRules, bool) {
+	var rules auth.ModelRules
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&rules); err != nil {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid model rules: %v", err))
+			return rules, false
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("Invalid model rules: %v", err))
+		return rules, false
+	}
+	return rules, true
+}
+
+// writeModelRulesError writes a 404 when a team has no model rules or a 500
+// otherwise. It reports whether err was non-nil.
+func writeModelRulesError(w http.ResponseWriter, reqID, org, team string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, auth.ErrNoModelRules):
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "no_model_rules",
+			fmt.Sprintf("The %s has no model rules", suspensionTarget(org, team)))
+	default:
+		httputil.WriteInternalError(w, reqID, "Model rules operation failed")
+	}
+	return true
+}
+
+// writeKeyError writes a 404 for unknown keys or a 500 otherwise. It reports
+// whether err was non-nil.
+func writeKeyError(w http.ResponseWriter, reqID string, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, auth.ErrKeyNotFound):
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "key_not_found", "API key not found")
+	default:
+		httputil.WriteInternalError(w, reqID, "API key operation failed")
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeProviderNotFound(w http.ResponseWriter, reqID, name string) {
+	httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "provider_not_found",
+		fmt.Sprintf("Provider %q is not configured", name))
+}
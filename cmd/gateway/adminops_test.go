@@ -0,0 +1,445 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/gateway"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+type fakeKeyAdmin struct {
+	keys      map[string]*auth.KeyInfo
+	created   auth.NewKey
+	limits    auth.KeyLimits
+	suspended map[string]string
+	teamRules map[string]auth.ModelRules
+}
+
+func (f *fakeKeyAdmin) ListKeys(_ context.Context, flt auth.KeyFilter) ([]auth.KeyInfo, error) {
+	var out []auth.KeyInfo
+	for _, k := range f.keys {
+		if flt.OrganizationID == "" || k.OrganizationID == flt.OrganizationID {
+			out = append(out, *k)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeKeyAdmin) GetKey(_ context.Context, id string) (*auth.KeyInfo, error) {
+	if k, ok := f.keys[id]; ok {
+		return k, nil
+	}
+	return nil, auth.ErrKeyNotFound
+}
+
+func (f *fakeKeyAdmin) CreateKey(_ context.Context, nk auth.NewKey) (string, *auth.KeyInfo, error) {
+	f.created = nk
+	k := &auth.KeyInfo{ID: "key-new", OrganizationID: nk.OrganizationID, TeamID: nk.TeamID, Name: nk.Name, Status: "active"}
+	f.keys[k.ID] = k
+	return "aegis-prod-rawsecret", k, nil
+}
+
+func (f *fakeKeyAdmin) RevokeKey(ctx context.Context, id, reason string) (*auth.KeyInfo, error) {
+	k, err := f.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	k.Status, k.RevokedReason = "revoked", reason
+	return k, nil
+}
+
+func (f *fakeKeyAdmin) SetLimits(ctx context.Context, id string, l auth.KeyLimits) (*auth.KeyInfo, error) {
+	f.limits = l
+	return f.GetKey(ctx, id)
+}
+
+func (f *fakeKeyAdmin) ListOrgs(context.Context) ([]auth.OrgSummary, error) {
+	return []auth.OrgSummary{{OrganizationID: "org-1", Teams: 1, ActiveKeys: 1, TotalKeys: 1}}, nil
+}
+
+func (f *fakeKeyAdmin) SuspendOrg(ctx context.Context, org, reason, suspendedBy string) (*auth.Suspension, error) {
+	return f.SuspendTeam(ctx, org, "", reason, suspendedBy)
+}
+
+func (f *fakeKeyAdmin) ResumeOrg(ctx context.Context, org string) error {
+	return f.ResumeTeam(ctx, org, "")
+}
+
+// SuspendTeam records suspensions by "org" or "org/team".
+func (f *fakeKeyAdmin) SuspendTeam(_ context.Context, org, team, reason, suspendedBy string) (*auth.Suspension, error) {
+	f.suspended[strings.TrimSuffix(org+"/"+team, "/")] = reason
+	return &auth.Suspension{OrganizationID: org, TeamID: team, Reason: reason, SuspendedBy: suspendedBy, SuspendedAt: time.Now()}, nil
+}
+
+func (f *fakeKeyAdmin) ResumeTeam(_ context.Context, org, team string) error {
+	target := strings.TrimSuffix(org+"/"+team, "/")
+	if _, ok := f.suspended[target]; !ok {
+		return auth.ErrNotSuspended
+	}
+	delete(f.suspended, target)
+	return nil
+}
+
+func (f *fakeKeyAdmin) SetModelRules(ctx context.Context, id string, rules auth.ModelRules) (*auth.KeyInfo, error) {
+	k, err := f.GetKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	k.ModelRules = rules
+	return k, nil
+}
+
+func (f *fakeKeyAdmin) GetTeamModelRules(_ context.Context, org, team string) (*auth.TeamModelRules, error) {
+	rules, ok := f.teamRules[org+"/"+team]
+	if !ok {
+		return nil, auth.ErrNoModelRules
+	}
+	return &auth.TeamModelRules{OrganizationID: org, TeamID: team, ModelRules: rules}, nil
+}
+
+func (f *fakeKeyAdmin) SetTeamModelRules(ctx context.Context, org, team string, rules auth.ModelRules, updatedBy string) (*auth.TeamModelRules, error) {
+	f.teamRules[org+"/"+team] = rules
+	return f.GetTeamModelRules(ctx, org, team)
+}
+
+func (f *fakeKeyAdmin) ClearTeamModelRules(_ context.Context, org, team string) error {
+	if _, ok := f.teamRules[org+"/"+team]; !ok {
+		return auth.ErrNoModelRules
+	}
+	delete(f.teamRules, org+"/"+team)
+	return nil
+}
+
+type fakeUsage struct {
+	org        string
+	start, end time.Time
+}
+
+func (f *fakeUsage) GetUsageSummary(_ context.Context, org string, start, end time.Time) (*storage.UsageSummary, error) {
+	f.org, f.start, f.end = org, start, end
+	return &storage.UsageSummary{TotalRequests: 42, TotalCostUSD: 1.5}, nil
+}
+
+func newAdminOpsTestServer() (*fakeKeyAdmin, *router.HealthTracker, *fakeUsage, *fakeConfigAuditor, http.Handler) {
+	keys := &fakeKeyAdmin{keys: map[string]*auth.KeyInfo{
+		"key-1": {ID: "key-1", OrganizationID: "org-1", TeamID: "team-1", Name: "ci", Status: "active"},
+	}, suspended: map[string]string{}, teamRules: map[string]auth.ModelRules{}}
+	health := router.NewHealthTracker(3, time.Minute)
+	usage := &fakeUsage{}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	providers := router.NewRegistry()
+	providers.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{}, http.DefaultClient))
+	mountAdminOps(r, keys, health, providers, usage, auditor, false)
+	return keys, health, usage, auditor, r
+}
+
+func TestAdminOps_CreateKey(t *testing.T) {
+	keys, _, _, auditor, h := newAdminOpsTestServer()
+
+	body := `{"organization_id":"org-1","team_id":"team-2","name":"batch","max_classification":"CONFIDENTIAL","expires_in":"30d","rpm_limit":100}`
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/keys", strings.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp createKeyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Key != "aegis-prod-rawsecret" || resp.APIKey.ID != "key-new" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if keys.created.ExpiresIn != 30*24*time.Hour || keys.created.RPMLimit == nil || *keys.created.RPMLimit != 100 {
+		t.Errorf("unexpected create request %+v", keys.created)
+	}
+	if len(auditor.changes) != 1 || auditor.changes[0].action != "key_create" {
+		t.Errorf("expected key_create audit, got %+v", auditor.changes)
+	}
+	if strings.Contains(auditor.changes[0].changes["api_key_id"].(string), "rawsecret") {
+		t.Error("audit record must not contain the raw key")
+	}
+}
+
+func TestAdminOps_CreateKeyValidation(t *testing.T) {
+	_, _, _, _, h := newAdminOpsTestServer()
+	for _, body := range []string{
+		`{"organization_id":"org-1","name":"x"}`,
+		`{"organization_id":"org-1","team_id":"t","name":"x","max_classification":"TOPSECRET"}`,
+		`{"organization_id":"org-1","team_id":"t","name":"x","expires_in":"soon"}`,
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/keys", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAdminOps_RevokeAndLimits(t *testing.T) {
+	keys, _, _, auditor, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/keys/key-1?reason=leaked", nil))
+	if w.Code != http.StatusOK || keys.keys["key-1"].Status != "revoked" {
+		t.Fatalf("expected revoke, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/keys/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown key, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PATCH", "/aegis/admin/v1/keys/key-1/limits", strings.NewReader(`{"tpm_limit":50000,"rpm_limit":0}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if keys.limits.TPMLimit == nil || *keys.limits.TPMLimit != 50000 || keys.limits.RPMLimit == nil || *keys.limits.RPMLimit != 0 || keys.limits.DailySpendLimitCents != nil {
+		t.Errorf("unexpected limits %+v", keys.limits)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PATCH", "/aegis/admin/v1/keys/key-1/limits", strings.NewReader(`{"rpm_limit":-1}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for negative limit, got %d", w.Code)
+	}
+
+	if len(auditor.changes) != 2 || auditor.changes[0].action != "key_revoke" || auditor.changes[1].action != "key_limits" {
+		t.Errorf("unexpected audit records %+v", auditor.changes)
+	}
+}
+
+func TestAdminOps_ProviderQuarantine(t *testing.T) {
+	_, health, _, auditor, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/providers/openai/quarantine", strings.NewReader(`{"reason":"bad deploy"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if health.IsAvailable("openai") {
+		t.Error("expected provider quarantined")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/providers/openai/quarantine", nil))
+	if w.Code != http.StatusOK || !health.IsAvailable("openai") {
+		t.Fatalf("expected release, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/providers/openai/quarantine", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 releasing an unquarantined provider, got %d", w.Code)
+	}
+	if len(auditor.changes) != 2 || auditor.changes[0].changes["reason"] != "bad deploy" {
+		t.Errorf("unexpected audit records %+v", auditor.changes)
+	}
+
+	for _, method := range []string{"POST", "DELETE"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/aegis/admin/v1/providers/opneai/quarantine", nil))
+		if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "provider_not_found") {
+			t.Errorf("%s for an unknown provider: got %d %s, want 404 provider_not_found", method, w.Code, w.Body.String())
+		}
+	}
+	if len(health.Quarantined()) != 0 {
+		t.Errorf("unknown provider was quarantined: %v", health.Quarantined())
+	}
+}
+
+func TestAdminOps_SuspendOrg(t *testing.T) {
+	keys, _, _, auditor, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/orgs/org-1/suspend", strings.NewReader(`{"reason":"leaked key"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if keys.suspended["org-1"] != "leaked key" {
+		t.Errorf("expected org-1 suspended, got %v", keys.suspended)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/suspend", nil))
+	if w.Code != http.StatusOK || len(keys.suspended) != 0 {
+		t.Fatalf("expected resume, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/suspend", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 resuming an active org, got %d", w.Code)
+	}
+	if len(auditor.changes) != 2 || auditor.changes[0].action != "org_suspend" || auditor.changes[1].action != "org_resume" {
+		t.Errorf("unexpected audit records %+v", auditor.changes)
+	}
+}
+
+func TestAdminOps_SuspendTeam(t *testing.T) {
+	keys, _, _, auditor, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/aegis/admin/v1/orgs/org-1/teams/team-1/suspend", strings.NewReader(`{"reason":"audit hold"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var s auth.Suspension
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil || s.TeamID != "team-1" {
+		t.Errorf("unexpected response %s", w.Body.String())
+	}
+	if keys.suspended["org-1/team-1"] != "audit hold" || len(keys.suspended) != 1 {
+		t.Errorf("expected only team-1 suspended, got %v", keys.suspended)
+	}
+	if len(auditor.changes) != 1 || auditor.changes[0].action != "team_suspend" || auditor.changes[0].changes["team_id"] != "team-1" {
+		t.Errorf("unexpected audit records %+v", auditor.changes)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/suspend", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 resuming the org while only a team is suspended, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/teams/team-1/suspend", nil))
+	if w.Code != http.StatusOK || len(keys.suspended) != 0 {
+		t.Fatalf("expected team resume, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAdminOps_ModelRules(t *testing.T) {
+	keys, _, _, auditor, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/aegis/admin/v1/keys/key-1/models",
+		strings.NewReader(`{"default_model":"gpt-4o-mini","model_rewrites":{"gpt-4":"gpt-4o-mini"}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := keys.keys["key-1"].ModelRules; got.DefaultModel != "gpt-4o-mini" || got.ModelRewrites["gpt-4"] != "gpt-4o-mini" {
+		t.Errorf("unexpected key rules %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/aegis/admin/v1/orgs/org-1/teams/team-1/models",
+		strings.NewReader(`{"model_rewrites":{"gpt-4o":"gpt-4o"}}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a rewrite to itself, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("PUT", "/aegis/admin/v1/orgs/org-1/teams/team-1/models",
+		strings.NewReader(`{"model_rewrites":{"gpt-4o":"gpt-4o-mini"}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/orgs/org-1/teams/team-1/models", nil))
+	var team auth.TeamModelRules
+	if err := json.Unmarshal(w.Body.Bytes(), &team); err != nil || team.ModelRewrites["gpt-4o"] != "gpt-4o-mini" {
+		t.Errorf("unexpected team rules %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/teams/team-1/models", nil))
+	if w.Code != http.StatusOK || len(keys.teamRules) != 0 {
+		t.Fatalf("expected rules cleared, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("DELETE", "/aegis/admin/v1/orgs/org-1/teams/team-1/models", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 clearing absent rules, got %d", w.Code)
+	}
+
+	var actions []string
+	for _, c := range auditor.changes {
+		actions = append(actions, c.action)
+	}
+	if strings.Join(actions, ",") != "key_model_rules,team_model_rules,team_model_rules_clear" {
+		t.Errorf("unexpected audit actions %v", actions)
+	}
+}
+
+func TestAdminOps_StrictTenancyAuditsUnderTargetOrg(t *testing.T) {
+	keys := &fakeKeyAdmin{keys: map[string]*auth.KeyInfo{}, suspended: map[string]string{}}
+	auditor := &fakeConfigAuditor{}
+	r := chi.NewRouter()
+	mountAdminOps(r, keys, router.NewHealthTracker(3, time.Minute), router.NewRegistry(), &fakeUsage{}, auditor, true)
+
+	req := httptest.NewRequest("POST", "/aegis/admin/v1/orgs/org-2/suspend", nil)
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{OrganizationID: "ops", KeyID: "admin-key"}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(auditor.changes) != 1 {
+		t.Fatalf("expected one audit record, got %+v", auditor.changes)
+	}
+	rec := auditor.changes[0]
+	if rec.org != "org-2" || rec.changes["actor_org_id"] != "ops" || rec.changes["actor_key_id"] != "admin-key" {
+		t.Errorf("expected record under org-2 naming the acting admin, got %+v", rec)
+	}
+}
+
+func TestAdminOps_Usage(t *testing.T) {
+	_, _, usage, _, h := newAdminOpsTestServer()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/usage?org=org-1&from=2026-09-01&to=2026-10-01", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if usage.org != "org-1" || !usage.start.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)) || !usage.end.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected query org=%s start=%v end=%v", usage.org, usage.start, usage.end)
+	}
+	if !strings.Contains(w.Body.String(), `"total_requests":42`) {
+		t.Errorf("expected summary in body, got %s", w.Body.String())
+	}
+
+	for _, q := range []string{"", "?org=org-1&from=yesterday", "?org=org-1&from=2026-10-02&to=2026-10-01"} {
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/admin/v1/usage"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("query %q: expected 400, got %d", q, w.Code)
+		}
+	}
+}
+
+// TestOpenAPISpec_CoversAdminRoutes keeps the published OpenAPI document in
+// step with the admin API.
+func TestOpenAPISpec_CoversAdminRoutes(t *testing.T) {
+	paths := gateway.OpenAPISpec("test")["paths"].(map[string]any)
+	_, _, _, _, ops := newAdminOpsTestServer()
+	_, _, bypass := newAdminBypassTestServer(time.Hour)
+	_, _, cfg := newAdminConfigTestServer(t)
+	dash := chi.NewRouter()
+	mountAdminDashboard(dash, &fakeDashboard{})
+	_, _, feedback := newAdminFeedbackTestServer()
+	for _, h := range []http.Handler{ops, bypass, cfg, dash, feedback} {
+		err := chi.Walk(h.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			item, _ := paths[route].(map[string]any)
+			if _, ok := item[strings.ToLower(method)]; !ok {
+				t.Errorf("%s %s is not in the OpenAPI document", method, route)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
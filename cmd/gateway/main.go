@@ -8,40 +8,86 @@ import (
 	"flag"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/af-corp/aegis-gateway/internal/archive"
 	"github.com/af-corp/aegis-gateway/internal/audit"
 	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/cost"
+	"github.com/af-corp/aegis-gateway/internal/egress"
+	"github.com/af-corp/aegis-gateway/internal/events"
 	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/filter/classify"
 	"github.com/af-corp/aegis-gateway/internal/filter/injection"
 	"github.com/af-corp/aegis-gateway/internal/filter/pii"
 	"github.com/af-corp/aegis-gateway/internal/filter/policy"
 	"github.com/af-corp/aegis-gateway/internal/filter/secrets"
 	"github.com/af-corp/aegis-gateway/internal/gateway"
+	"github.com/af-corp/aegis-gateway/internal/grpcserver"
+	"github.com/af-corp/aegis-gateway/internal/guardrails"
+	"github.com/af-corp/aegis-gateway/internal/hooks"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/janitor"
 	"github.com/af-corp/aegis-gateway/internal/ratelimit"
 	"github.com/af-corp/aegis-gateway/internal/retry"
 	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/secretstore"
+	"github.com/af-corp/aegis-gateway/internal/siem"
 	"github.com/af-corp/aegis-gateway/internal/storage"
 	"github.com/af-corp/aegis-gateway/internal/telemetry"
 	"github.com/af-corp/aegis-gateway/internal/validation"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 )
 
 var version = "dev"
 
 func main() {
+	// "gateway validate [-config dir]" is equivalent to "gateway --validate".
+	validateCmd := len(os.Args) > 1 && os.Args[1] == "validate"
+	if validateCmd {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	// "gateway migrate <up|down|status|version> [-config dir]" uses the embedded migrations.
+	migrateCmd := len(os.Args) > 1 && os.Args[1] == "migrate"
+	var migrateArg string
+	if migrateCmd {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+			migrateArg = os.Args[1]
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
+	// "gateway smoke [provider ...] [-config dir]" sends test requests to providers.
+	smokeCmd := len(os.Args) > 1 && os.Args[1] == "smoke"
+	var smokeProviders []string
+	if smokeCmd {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		for len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+			smokeProviders = append(smokeProviders, os.Args[1])
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
 	configDir := flag.String("config", "configs", "path to configuration directory")
 	showVersion := flag.Bool("version", false, "print version and exit")
+	validateOnly := flag.Bool("validate", false, "validate configuration and exit non-zero on errors")
+	autoMigrateFlag := flag.Bool("auto-migrate", false, "apply pending embedded database migrations before starting")
 	flag.Parse()
 
 	if *showVersion {
@@ -49,39 +95,78 @@ func main() {
 		return
 	}
 
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	}))
+	if *validateOnly || validateCmd {
+		os.Exit(runValidate(*configDir, os.Stdout))
+	}
+
+	// Bootstrap logger until telemetry config is loaded
+	logLevel := new(slog.LevelVar)
+	logger := telemetry.NewLogger(os.Stdout, "json", logLevel)
 	slog.SetDefault(logger)
 
-	// Load configuration
+	// Load configuration. Credentials may be secretsmanager:// or gcpsm://
+	// references, or vault:// references when VAULT_ADDR is set.
 	loader := config.NewLoader(*configDir, logger)
+	loader.AddSecretResolver(secretstore.NewAWSSecretsManagerResolver(secretstore.AWSConfigFromEnv(), nil))
+	loader.AddSecretResolver(secretstore.NewGCPSecretManagerResolver(secretstore.GCPConfigFromEnv(), nil))
+	if vaultCfg := secretstore.VaultConfigFromEnv(); vaultCfg.Address != "" {
+		vault := secretstore.NewVaultResolver(vaultCfg, nil)
+		vault.StartRenewal(context.Background(), logger)
+		loader.AddSecretResolver(vault)
+		logger.Info("vault secret backend enabled", "addr", vaultCfg.Address)
+	}
 	if err := loader.Load(); err != nil {
 		logger.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
 
-	if err := loader.Watch(); err != nil {
-		logger.Warn("failed to start config watcher", "error", err)
+	cfg := loader.Config()
+
+	if migrateCmd {
+		os.Exit(runMigrate(migrateArg, cfg.Database, os.Stdout))
 	}
 
-	cfg := loader.Config()
+	if smokeCmd {
+		smokeProviders = append(smokeProviders, flag.Args()...)
+		os.Exit(runSmoke(loader.Providers(), loader.Models(), smokeProviders, os.Stdout))
+	}
+
+	// Apply configured log format and level. The level follows hot-reloads;
+	// the format is fixed for the life of the process.
+	logLevel.Set(telemetry.ParseLogLevel(cfg.Telemetry.LogLevel))
+	logger = telemetry.NewLogger(os.Stdout, cfg.Telemetry.LogFormat, logLevel)
+	slog.SetDefault(logger)
+	loader.SetLogger(logger)
+	loader.OnReload(func() {
+		newLevel := telemetry.ParseLogLevel(loader.Config().Telemetry.LogLevel)
+		if newLevel != logLevel.Level() {
+			logLevel.Set(newLevel)
+			logger.Info("log level changed", "level", newLevel.String())
+		}
+	})
 
-	// Connect to PostgreSQL with pool configuration
-	poolConfig, err := pgxpool.ParseConfig(cfg.Database.DSN())
+	if *autoMigrateFlag {
+		if err := autoMigrate(cfg.Database, logger); err != nil {
+			logger.Error("database migration failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Connect to PostgreSQL with pool and TLS settings from config
+	poolConfig, err := storage.NewPoolConfig(cfg.Database)
 	if err != nil {
-		logger.Error("failed to parse database DSN", "error", err)
+		logger.Error("failed to build database pool config", "error", err)
 		os.Exit(1)
 	}
 
-	// Apply pool settings from config
-	poolConfig.MaxConns = int32(cfg.Database.MaxOpenConns)
-	poolConfig.MinConns = int32(cfg.Database.MaxIdleConns)
-	poolConfig.MaxConnLifetime = cfg.Database.ConnMaxLifetime
-	poolConfig.MaxConnIdleTime = 30 * time.Minute
-	poolConfig.HealthCheckPeriod = 1 * time.Minute
+	// Read the password per connection so rotated secrets apply to new connections
+	poolConfig.BeforeConnect = func(_ context.Context, cc *pgx.ConnConfig) error {
+		cc.Password = loader.Config().Database.Password
+		return nil
+	}
 
 	logger.Info("database pool configuration",
+		"tls", poolConfig.ConnConfig.TLSConfig != nil,
 		"max_conns", poolConfig.MaxConns,
 		"min_conns", poolConfig.MinConns,
 		"max_conn_lifetime", poolConfig.MaxConnLifetime,
@@ -111,8 +196,11 @@ func main() {
 	var rdb *redis.Client
 	if len(cfg.Redis.Addresses) > 0 && cfg.Redis.Addresses[0] != "" {
 		rdb = redis.NewClient(&redis.Options{
-			Addr:     cfg.Redis.Addresses[0],
-			Password: cfg.Redis.Password,
+			Addr: cfg.Redis.Addresses[0],
+			// Read per connection so rotated secrets apply to new connections
+			CredentialsProvider: func() (string, string) {
+				return "", loader.Config().Redis.Password
+			},
 			DB:       cfg.Redis.DB,
 			PoolSize: cfg.Redis.PoolSize,
 		})
@@ -124,21 +212,58 @@ func main() {
 		}
 	}
 
-	// Build provider registry
-	providerRegistry := router.BuildFromConfig(loader.Providers())
+	// Initialize metrics
+	metrics := telemetry.NewMetrics()
+	metrics.SetStrictTenancy(cfg.Tenancy.Strict)
+	labelLimits := make(map[string]telemetry.LabelLimit, len(cfg.Telemetry.MetricLabels))
+	for label, l := range cfg.Telemetry.MetricLabels {
+		labelLimits[label] = telemetry.LabelLimit{Mode: l.Mode, Buckets: l.Buckets, TopN: l.TopN}
+	}
+	metrics.SetLabelLimits(labelLimits)
+	loader.SetMetrics(metrics)
+
+	// Build provider registry. The egress allowlist was validated with the
+	// config, so it parses.
+	buildOptions := func() router.BuildOptions {
+		cfg := loader.Config()
+		opts := router.BuildOptions{Metrics: metrics}
+		opts.Allow, _ = egress.Parse(cfg.Egress.Allowlist)
+		if dr := cfg.DryRun; dr.Enabled {
+			logger.Warn("dry-run mode: provider calls are answered with canned responses")
+			opts.DryRun = &dr
+		}
+		if f := cfg.Fixtures; f.Mode != "" {
+			logger.Warn("provider fixtures enabled", "mode", f.Mode, "dir", f.Dir)
+			opts.Fixtures = &f
+		}
+		if c := cfg.Chaos; c.Enabled && len(c.Providers) > 0 {
+			logger.Warn("chaos injection enabled", "providers", len(c.Providers))
+			opts.Chaos = c.Providers
+		}
+		return opts
+	}
+	providerRegistry := router.BuildFromConfig(loader.Providers(), buildOptions())
+	providerRegistry.SetStrategy(cfg.Routing.Strategy)
 	loader.OnReload(func() {
-		newRegistry := router.BuildFromConfig(loader.Providers())
-		providerRegistry.ReplaceFrom(newRegistry)
-		logger.Info("provider registry reloaded")
+		newRegistry := router.BuildFromConfig(loader.Providers(), buildOptions())
+		rebuilt := providerRegistry.ReplaceFrom(newRegistry)
+		logger.Info("provider registry reloaded", "rebuilt", rebuilt)
+		providerRegistry.SetStrategy(loader.Config().Routing.Strategy)
 	})
 
-	// Initialize metrics
-	metrics := telemetry.NewMetrics()
+	if err := loader.Watch(); err != nil {
+		logger.Warn("failed to start config watcher", "error", err)
+	}
+	loader.WatchSecrets(cfg.Secrets.RefreshInterval)
 
 	// Start metrics server
 	metricsAddr := fmt.Sprintf(":%d", cfg.Telemetry.MetricsPort)
 	metricsMux := http.NewServeMux()
 	metricsMux.Handle("/metrics", promhttp.Handler())
+	if cfg.Telemetry.DebugEndpoints {
+		registerDebugHandlers(metricsMux)
+		logger.Info("debug endpoints enabled on metrics listener", "paths", "/debug/pprof/, /debug/vars")
+	}
 	metricsSrv := &http.Server{Addr: metricsAddr, Handler: metricsMux}
 	go func() {
 		logger.Info("metrics server starting", "addr", metricsAddr)
@@ -147,6 +272,10 @@ func main() {
 		}
 	}()
 
+	if err := storage.RegisterPoolMetrics(prometheus.DefaultRegisterer, dbPool); err != nil {
+		logger.Warn("failed to register database pool metrics", "error", err)
+	}
+
 	// Start DB pool metrics collector
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
@@ -165,12 +294,45 @@ func main() {
 	// Build filter chain
 	secretsFilter := secrets.NewFilter(func() bool { return loader.Config().Filter.Secrets.Enabled })
 	injectionScanner := injection.NewScanner(func() config.InjectionFilterConfig { return loader.Config().Filter.Injection })
+	if sim := cfg.Filter.Injection.Similarity; cfg.Filter.Injection.Enabled && sim.Enabled {
+		var index injection.JailbreakIndex
+		switch {
+		case sim.Store == "pgvector":
+			index = injection.NewPGVectorIndex(dbPool, sim.PGTable)
+		case rdb != nil:
+			index = injection.NewRedisIndex(rdb, sim.RedisKey, sim.RefreshInterval)
+		}
+		if index == nil {
+			logger.Warn("jailbreak similarity detection disabled: redis store configured but redis is unavailable")
+		} else {
+			embedder := injection.NewHTTPEmbedder(sim.EmbeddingURL, sim.EmbeddingModel, sim.EmbeddingAPIKey)
+			injectionScanner.SetSimilarity(injection.NewSimilarityDetector(embedder, index,
+				func() config.InjectionSimilarityConfig { return loader.Config().Filter.Injection.Similarity }))
+			if sim.SeedFile != "" {
+				seedCtx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+				n, err := injection.LoadSeedFile(seedCtx, sim.SeedFile, embedder, index)
+				cancel()
+				if err != nil {
+					logger.Warn("failed to seed jailbreak vectors", "error", err, "loaded", n)
+				} else {
+					logger.Info("seeded jailbreak vectors", "count", n)
+				}
+			}
+		}
+	}
 	piiClient := pii.NewClient(func() config.PIIServiceConfig { return loader.Config().Filter.PIIService })
 	if cfg.Filter.PIIService.Enabled {
 		if err := piiClient.Connect(); err != nil {
 			logger.Warn("failed to connect to PII service", "error", err)
 		}
 	}
+	classifier := classify.NewDetector(func() config.ClassificationDetectionConfig { return loader.Config().Filter.Classification })
+	classifier.SetMetrics(metrics)
+	if cfg.Filter.Classification.Enabled {
+		if err := classifier.Connect(); err != nil {
+			logger.Warn("failed to connect to classification service", "error", err)
+		}
+	}
 	policyEvaluator := policy.NewEvaluator(func() config.PolicyFilterConfig { return loader.Config().Filter.Policy })
 	policyEvaluator.SetMetrics(metrics)
 	if cfg.Filter.Policy.Enabled {
@@ -188,20 +350,67 @@ func main() {
 			logger.Info("policies reloaded")
 		}
 	})
-	filterChain := filter.NewChain(secretsFilter, injectionScanner, piiClient)
+	filterChain := filter.NewChain(secretsFilter, injectionScanner, piiClient, classifier)
+	filterChain.SetMetrics(metrics)
+	filterChain.SetFailOpen(func(name, org string) bool { return loader.Config().Filter.FailOpen(name, org) })
+
+	// Break-glass filter bypass grants are shared through Redis; without it
+	// none can be created.
+	var bypassStore *cache.BypassStore
+	if rdb != nil {
+		bypassStore = cache.NewBypassStore(rdb)
+		bypassStore.SetStrictTenancy(cfg.Tenancy.Strict)
+		filterChain.SetBypass(bypassStore)
+	}
 
 	// Rate limiting
 	rateLimiter := ratelimit.NewLimiter(rdb)
+	rateLimiter.SetStrictTenancy(cfg.Tenancy.Strict)
+	rateLimiter.SetTokenEstimator(func(r *http.Request, body []byte) int64 {
+		if r.URL.Path != "/v1/chat/completions" {
+			return 0
+		}
+		return gateway.EstimateBodyTokens(body)
+	}, func() int64 { return loader.Config().Limits.MaxBodyBytes })
 	budgetTracker := ratelimit.NewBudgetTracker(rdb)
+	budgetTracker.SetStrictTenancy(cfg.Tenancy.Strict)
 
 	// Health tracking (circuit breaker)
 	healthTracker := router.NewHealthTracker(
 		cfg.Routing.CircuitBreaker.FailureThreshold,
 		cfg.Routing.CircuitBreaker.RecoveryProbeInterval,
 	)
+	healthTracker.SetMetrics(metrics)
+	healthTracker.SetErrorRateWindow(cfg.Routing.CircuitBreaker.ErrorRateWindow)
+	healthTracker.SetQuotaHeadroom(cfg.Routing.QuotaHeadroom)
+	if cfg.Routing.CircuitBreaker.Shared && rdb == nil {
+		logger.Warn("routing.circuit_breaker.shared needs Redis; circuit breakers stay per replica")
+	} else if cfg.Routing.CircuitBreaker.Shared {
+		healthTracker.ShareCircuits(rdb)
+		circuitCtx, stopCircuitEvents := context.WithCancel(context.Background())
+		defer stopCircuitEvents()
+		go healthTracker.ListenForCircuitEvents(circuitCtx)
+	}
+	// Operator quarantines apply on every replica and survive restarts.
+	if rdb != nil {
+		healthTracker.ShareQuarantine(rdb)
+		quarantineCtx, stopQuarantineEvents := context.WithCancel(context.Background())
+		defer stopQuarantineEvents()
+		go healthTracker.ListenForQuarantines(quarantineCtx)
+	}
 
 	// Build audit logger
 	auditLogger := audit.NewLogger(dbPool)
+	if cfg.SIEM.Enabled {
+		siemOut, err := siem.OpenOutput(cfg.SIEM)
+		if err != nil {
+			logger.Warn("failed to open security event output (SIEM stream disabled)", "error", err)
+		} else {
+			defer func() { _ = siemOut.Close() }()
+			auditLogger.SetSecuritySink(siem.NewLogger(siemOut, cfg.SIEM.Format, version))
+			logger.Info("security event stream enabled", "format", cfg.SIEM.Format, "output", cfg.SIEM.Output)
+		}
+	}
 
 	// Build retry executor
 	retryConfig := retry.Config{
@@ -219,31 +428,201 @@ func main() {
 
 	// Build handler
 	keyStore := auth.NewCachedKeyStore(dbPool, rdb)
+	keyStore.SetLocalCache(cfg.Auth.LocalCacheSize, cfg.Auth.LocalCacheTTL)
+	keyCacheCtx, stopKeyCache := context.WithCancel(context.Background())
+	defer stopKeyCache()
+	go keyStore.ListenForInvalidations(keyCacheCtx)
 	costCalc := cost.NewCalculator(func() *config.ModelsConfig {
 		return loader.Models()
 	})
+	// Usage ledger: request_usage is the durable record for billing, the usage
+	// API, and rebuilding budget counters when Redis loses them.
+	usageLedger := storage.NewUsageLedger(dbPool, cfg.Usage)
+	usageLedger.SetMetrics(metrics)
 	usageRecorder := storage.NewUsageRecorder(dbPool)
+	usageRecorder.SetLedger(usageLedger)
+	budgetTracker.SetSpendSource(usageRecorder)
+
+	// Retention janitor: prunes old usage/audit rows, dead API keys, and
+	// stale Redis buckets. Retention settings follow hot reload.
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	defer stopJanitor()
+	retentionJanitor := janitor.New(storage.NewRetentionStore(dbPool), func() config.RetentionConfig {
+		return loader.Config().Retention
+	}, rateLimiter, budgetTracker)
+	retentionJanitor.SetMetrics(metrics)
+	retentionJanitor.Start(janitorCtx)
+	if cfg.Retention.Enabled {
+		logger.Info("retention janitor enabled",
+			"interval", cfg.Retention.Interval,
+			"usage_retention", cfg.Retention.UsageRetention,
+			"audit_retention", cfg.Retention.AuditRetention,
+		)
+	}
 	handler := gateway.NewHandler(providerRegistry, healthTracker, func() *config.ModelsConfig {
 		return loader.Models()
 	}, func() *config.Config {
 		return loader.Config()
 	}, filterChain, policyEvaluator, metrics, costCalc, usageRecorder, auditLogger, retryExecutor, contextMonitor, validator)
 
+	// Payload archival (redacted, gated per org/classification in config)
+	archiveCfg := func() config.ArchiveConfig { return loader.Config().Archive }
+	handler.SetArchiver(archive.NewArchiver(archive.NewS3Store(archiveCfg, nil), archiveCfg))
+
+	// Request/response transformation hooks, ordered and enabled per org in
+	// config. Compiled-in hooks are registered on the chain by name.
+	handler.SetHooks(hooks.NewChain(func() []config.HookConfig { return loader.Config().Hooks }))
+
+	// Response guardrails (classification banners, external link stripping)
+	handler.SetGuardrails(guardrails.New(func() config.GuardrailsConfig { return loader.Config().Guardrails }))
+
+	// Event export to Kafka/NATS for SIEM and analytics pipelines
+	if cfg.Events.Enabled {
+		publisher, err := events.NewPublisher(cfg.Events)
+		if err != nil {
+			logger.Warn("failed to initialize event publisher (event export disabled)", "error", err)
+		} else {
+			eventExporter := events.NewExporter(publisher, cfg.Events.BufferSize, cfg.Events.PublishTimeout)
+			defer func() { _ = eventExporter.Close() }()
+			handler.SetEventEmitter(eventExporter)
+			logger.Info("event export enabled", "backend", cfg.Events.Backend, "topic", cfg.Events.Topic)
+		}
+	}
+
+	// Idempotency-Key replay needs Redis; without it the header is ignored.
+	if rdb != nil {
+		idempotencyStore := cache.NewIdempotencyStore(rdb, func() time.Duration {
+			return loader.Config().Idempotency.TTL
+		})
+		idempotencyStore.SetStrictTenancy(cfg.Tenancy.Strict)
+		handler.SetIdempotencyStore(idempotencyStore)
+	}
+
+	// The response cache follows response_cache.enabled on reload and the
+	// admin switch; replicas share entries through Redis, and without it
+	// nothing is cached.
+	var responseStore *cache.ResponseStore
+	if rdb != nil {
+		responseStore = cache.NewResponseStore(rdb, func() time.Duration {
+			return loader.Config().ResponseCache.TTL
+		})
+		responseStore.SetStrictTenancy(cfg.Tenancy.Strict)
+		handler.SetResponseCache(responseStore)
+	}
+
+	// Sticky routing follows routing.sticky.enabled on reload but, like
+	// idempotency, needs Redis to share conversations between replicas.
+	if rdb != nil {
+		stickyRoutes := cache.NewStickyRouteStore(rdb, func() time.Duration {
+			return loader.Config().Routing.Sticky.TTL
+		})
+		stickyRoutes.SetStrictTenancy(cfg.Tenancy.Strict)
+		handler.SetStickyRouteStore(stickyRoutes)
+	} else if cfg.Routing.Sticky.Enabled {
+		logger.Warn("routing.sticky needs Redis; requests are routed without provider affinity")
+	}
+
+	// Asynchronous batch API, worked off by a pool sharing the Postgres queue
+	// with other replicas.
+	batchCtx, stopBatches := context.WithCancel(context.Background())
+	defer stopBatches()
+	var batchRunner *gateway.BatchRunner
+	if cfg.Batch.Enabled {
+		handler.SetBatchStore(storage.NewBatchStore(dbPool), budgetTracker)
+		batchRunner = gateway.NewBatchRunner(handler)
+		// Items re-check their key and its rate limits as they run, not
+		// just when the batch was submitted.
+		batchRunner.SetKeyStore(keyStore)
+		batchRunner.SetRateLimit(ratelimit.Middleware(rateLimiter, budgetTracker, metrics, auditLogger))
+		logger.Info("batch API enabled",
+			"workers", cfg.Batch.Workers,
+			"max_concurrent_per_org", cfg.Batch.MaxConcurrentPerOrg,
+			"completion_window", cfg.Batch.CompletionWindow,
+		)
+	}
+
+	// Conversation tracking for X-Aegis-Conversation-ID; the setting follows
+	// hot reload, the store only needs Postgres.
+	handler.SetConversationStore(storage.NewConversationStore(dbPool))
+	feedbackStore := storage.NewFeedbackStore(dbPool)
+	handler.SetFeedbackStore(feedbackStore)
+	handler.SetBudget(budgetTracker)
+	handler.SetSelfService(rateLimiter, usageRecorder)
+
+	streamDrainer := gateway.NewStreamDrainer()
+	handler.SetStreamDrainer(streamDrainer)
+
 	// Router setup
 	r := chi.NewRouter()
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
 	r.Use(requestIDMiddleware)
+	r.Use(dryRunMiddleware(func() bool { return loader.Config().DryRun.Enabled }))
+	r.Use(httputil.CORS(func() config.CORSConfig { return loader.Config().CORS }))
+	loadShedder := ratelimit.NewLoadShedder(
+		func() int { return loader.Config().Server.MaxInFlight },
+		func() time.Duration { return loader.Config().Server.LoadShedRetryAfter },
+		metrics,
+	)
+	loadShedder.SetBatchRatio(func() float64 { return loader.Config().Server.BatchInFlightRatio })
+	if batchRunner != nil {
+		// Batch items count against max_in_flight and yield to interactive
+		// traffic like batch-priority requests do.
+		batchRunner.SetAdmitter(loadShedder)
+		batchRunner.Start(batchCtx)
+	}
+	r.Use(loadShedder.Middleware(notShed))
 
 	// Unauthenticated routes
 	r.Get("/aegis/v1/health", makeHealthHandler(dbPool, rdb, rateLimiter, providerRegistry, healthTracker))
+	r.Get("/aegis/v1/error-codes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"codes": httputil.Codes()})
+	})
+	openAPISpec := gateway.OpenAPISpec(version)
+	r.Get("/aegis/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, openAPISpec)
+	})
 
 	// Authenticated routes
 	r.Group(func(r chi.Router) {
 		r.Use(auth.Middleware(keyStore, auditLogger))
+		r.Use(loadShedder.Prioritize(notShed))
 		r.Use(ratelimit.Middleware(rateLimiter, budgetTracker, metrics, auditLogger))
 		r.Post("/v1/chat/completions", handler.ChatCompletions)
 		r.Get("/v1/models", handler.ListModels)
+		r.Get("/v1/models/{id}", handler.GetModel)
+		r.Post("/aegis/v1/compare", handler.Compare)
+		r.Post("/aegis/v1/tokenize", handler.Tokenize)
+		r.Post("/aegis/v1/estimate", handler.Estimate)
+		r.Get("/aegis/v1/me", handler.Me)
+		r.Post("/aegis/v1/batches", handler.CreateBatch)
+		r.Get("/aegis/v1/batches/{id}", handler.GetBatch)
+		r.Get("/aegis/v1/batches/{id}/results", handler.GetBatchResults)
+		r.Post("/aegis/v1/batches/{id}/cancel", handler.CancelBatch)
+		r.Get("/aegis/v1/conversations", handler.ListConversations)
+		r.Get("/aegis/v1/conversations/{id}", handler.GetConversation)
+		r.Post("/aegis/v1/feedback", handler.SubmitFeedback)
+	})
+
+	// Admin/ops routes (restricted to configured key IDs, not rate limited)
+	r.Group(func(r chi.Router) {
+		r.Use(auth.Middleware(keyStore, auditLogger))
+		r.Use(auth.RequireAdmin(func() []string { return loader.Config().Admin.KeyIDs }))
+		r.Get("/aegis/v1/status", makeStatusHandler(providerRegistry, healthTracker, loader.Models, loader.Version, piiClient, policyEvaluator,
+			func() []config.AccessWindowConfig { return loader.Config().Routing.AccessWindows }))
+		mountAdminConfig(r, loader, auditLogger)
+		keyManager := auth.NewKeyManager(dbPool, rdb)
+		mountAdminOps(r, keyManager, healthTracker, providerRegistry, usageRecorder, auditLogger, cfg.Tenancy.Strict)
+		mountAdminDashboard(r, usageRecorder)
+		mountAdminFeedback(r, feedbackStore, auditLogger)
+		if responseStore != nil {
+			mountAdminCache(r, responseStore, func() bool { return loader.Config().ResponseCache.Enabled }, auditLogger)
+		}
+		if bypassStore != nil {
+			mountAdminBypass(r, keyManager, bypassStore, filterChain.Names(),
+				func() time.Duration { return loader.Config().Filter.BypassMaxDuration },
+				auditLogger, cfg.Tenancy.Strict)
+		}
 	})
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
@@ -262,6 +641,25 @@ func main() {
 		errCh <- srv.ListenAndServe()
 	}()
 
+	// Native gRPC ingress, bridged onto the same router so every middleware applies.
+	var grpcSrv *grpc.Server
+	if cfg.Server.GRPCPort != 0 {
+		grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
+		lis, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			logger.Error("failed to listen for gRPC", "addr", grpcAddr, "error", err)
+			os.Exit(1)
+		}
+		grpcSrv = grpc.NewServer()
+		grpcserver.New(r, logger).Register(grpcSrv)
+		go func() {
+			logger.Info("gRPC ingress starting", "addr", grpcAddr)
+			if err := grpcSrv.Serve(lis); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -275,11 +673,56 @@ func main() {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.GracefulShutdown)
+	// Stop accepting new work on both listeners right away. In-flight streams
+	// get stream_drain_timeout to finish before being cut with a final error
+	// event; everything else then has graceful_shutdown to wrap up.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.StreamDrainTimeout+cfg.Server.GracefulShutdown)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		logger.Error("graceful shutdown failed", "error", err)
+	httpStopped := make(chan error, 1)
+	go func() { httpStopped <- srv.Shutdown(ctx) }()
+	grpcStopped := make(chan struct{})
+	go func() {
+		if grpcSrv != nil {
+			grpcSrv.GracefulStop()
+		}
+		close(grpcStopped)
+	}()
+
+	if n := streamDrainer.Active(); n > 0 {
+		logger.Info("draining active streams", "active", n, "timeout", cfg.Server.StreamDrainTimeout)
+	}
+	drainCtx, drainCancel := context.WithTimeout(ctx, cfg.Server.StreamDrainTimeout)
+	if !streamDrainer.Wait(drainCtx) {
+		logger.Warn("stream drain timeout, cutting remaining streams", "active", streamDrainer.Active())
+		streamDrainer.Cut()
+	}
+	drainCancel()
+
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		if grpcSrv != nil {
+			grpcSrv.Stop()
+		}
+	}
+	httpErr := <-httpStopped
+	stopJanitor()
+	// Batch items cut short here go back to the queue for another replica.
+	stopBatches()
+	if batchRunner != nil {
+		batchRunner.Wait()
+	}
+
+	// Requests are done; flush buffered usage records before the pool closes.
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), cfg.Server.GracefulShutdown)
+	if err := usageLedger.Close(flushCtx); err != nil {
+		logger.Error("usage ledger flush incomplete", "error", err)
+	}
+	flushCancel()
+
+	if httpErr != nil {
+		logger.Error("graceful shutdown failed", "error", httpErr)
 		os.Exit(1)
 	}
 	logger.Info("gateway stopped")
@@ -419,6 +862,22 @@ func makeHealthHandler(pool *pgxpool.Pool, rdb *redis.Client, limiter *ratelimit
 	}
 }
 
+// unshedPaths are the operational endpoints that stay reachable when the
+// gateway is shedding load. Everything else, including tokenize, estimate,
+// and batch submission, is shed like any API call.
+var unshedPaths = map[string]bool{
+	"/aegis/v1/health":       true,
+	"/aegis/v1/status":       true,
+	"/aegis/v1/openapi.json": true,
+	"/aegis/v1/error-codes":  true,
+}
+
+// notShed reports whether r is exempt from load shedding: the health,
+// status, and spec endpoints, and the admin API.
+func notShed(r *http.Request) bool {
+	return unshedPaths[r.URL.Path] || strings.HasPrefix(r.URL.Path, "/aegis/admin/")
+}
+
 func requestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		reqID := r.Header.Get("X-Request-ID")
@@ -431,6 +890,19 @@ func requestIDMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// dryRunMiddleware marks every response X-Aegis-Dry-Run: true while dry-run
+// mode is on, so a simulated completion is never mistaken for a real one.
+func dryRunMiddleware(enabled func() bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if enabled() {
+				w.Header().Set("X-Aegis-Dry-Run", "true")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 type contextKey string
 
 const requestIDKey contextKey = "request_id"
@@ -138,3 +138,23 @@ func TestMakeHealthHandler_ContentType(t *testing.T) {
 		t.Errorf("expected Content-Type application/json, got %s", ct)
 	}
 }
+
+func TestNotShed(t *testing.T) {
+	for path, want := range map[string]bool{
+		"/aegis/v1/health":         true,
+		"/aegis/v1/status":         true,
+		"/aegis/v1/openapi.json":   true,
+		"/aegis/admin/v1/keys":     true,
+		"/v1/chat/completions":     false,
+		"/aegis/v1/compare":        false,
+		"/aegis/v1/tokenize":       false,
+		"/aegis/v1/estimate":       false,
+		"/aegis/v1/batches":        false,
+		"/aegis/v1/conversations":  false,
+		"/aegis/administrator/foo": false,
+	} {
+		if got := notShed(httptest.NewRequest(http.MethodPost, path, nil)); got != want {
+			t.Errorf("notShed(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+const migrateUsage = "usage: gateway migrate <up|down|status|version> [-config dir]"
+
+// runMigrate handles "gateway migrate <cmd>" against the configured database
+// using the migrations embedded in the binary. "down" rolls back only the
+// most recent migration. It returns the process exit code: 0 on success, 1
+// on failure, 2 on a usage error.
+func runMigrate(cmd string, db config.DatabaseConfig, out io.Writer) int {
+	switch cmd {
+	case "up", "down", "status", "version":
+	default:
+		fmt.Fprintln(out, migrateUsage)
+		return 2
+	}
+
+	mg, err := storage.NewMigrator(db.DSN(), "")
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+	defer func() { _ = mg.Close() }()
+
+	switch cmd {
+	case "up":
+		if _, err := mg.Up(); err != nil {
+			fmt.Fprintf(out, "error: migrate up: %v\n", err)
+			return 1
+		}
+		v, dirty, err := mg.Version()
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "migrations applied (version: %d, dirty: %v)\n", v, dirty)
+	case "down":
+		if err := mg.Steps(-1); err != nil {
+			fmt.Fprintf(out, "error: migrate down: %v\n", err)
+			return 1
+		}
+		v, dirty, err := mg.Version()
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "migration rolled back (version: %d, dirty: %v)\n", v, dirty)
+	case "version":
+		v, dirty, err := mg.Version()
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		if dirty {
+			fmt.Fprintf(out, "%d (dirty)\n", v)
+		} else {
+			fmt.Fprintf(out, "%d\n", v)
+		}
+	case "status":
+		status, err := mg.Status()
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			return 1
+		}
+		printMigrationStatus(out, status)
+		if status.Dirty {
+			return 1
+		}
+	}
+	return 0
+}
+
+func printMigrationStatus(out io.Writer, s storage.MigrationStatus) {
+	fmt.Fprintf(out, "current version: %d\n", s.Current)
+	fmt.Fprintf(out, "latest version:  %d\n", s.Latest)
+	switch {
+	case s.Dirty:
+		fmt.Fprintf(out, "state: dirty, migration %d failed part-way and must be repaired by hand\n", s.Current)
+	case s.Current > s.Latest:
+		fmt.Fprintln(out, "state: database is newer than this binary")
+	case len(s.Pending) > 0:
+		fmt.Fprintf(out, "state: %d pending %v\n", len(s.Pending), s.Pending)
+	default:
+		fmt.Fprintln(out, "state: up to date")
+	}
+}
+
+// autoMigrate applies pending embedded migrations before the gateway starts
+// serving, for single-binary deployments without a separate migrate job.
+func autoMigrate(db config.DatabaseConfig, logger *slog.Logger) error {
+	mg, err := storage.NewMigrator(db.DSN(), "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = mg.Close() }()
+
+	changed, err := mg.Up()
+	if err != nil {
+		return fmt.Errorf("migrate up: %w", err)
+	}
+	v, _, err := mg.Version()
+	if err != nil {
+		return err
+	}
+	if changed {
+		logger.Info("database migrations applied", "version", v)
+	} else {
+		logger.Info("database schema up to date", "version", v)
+	}
+	return nil
+}
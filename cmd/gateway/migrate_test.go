@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+func TestRunMigrate_UnknownCommand(t *testing.T) {
+	var out bytes.Buffer
+	if code := runMigrate("sideways", config.DefaultConfig().Database, &out); code != 2 {
+		t.Fatalf("expected exit 2, got %d", code)
+	}
+	if !strings.Contains(out.String(), "usage: gateway migrate") {
+		t.Errorf("expected usage, got %q", out.String())
+	}
+}
+
+func TestPrintMigrationStatus(t *testing.T) {
+	tests := []struct {
+		status storage.MigrationStatus
+		want   string
+	}{
+		{storage.MigrationStatus{Current: 4, Latest: 6, Pending: []uint{5, 6}}, "state: 2 pending [5 6]"},
+		{storage.MigrationStatus{Current: 6, Latest: 6}, "state: up to date"},
+		{storage.MigrationStatus{Current: 5, Latest: 6, Dirty: true, Pending: []uint{6}}, "state: dirty, migration 5"},
+		{storage.MigrationStatus{Current: 7, Latest: 6}, "newer than this binary"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		printMigrationStatus(&out, tt.status)
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("status %+v: expected %q in output:\n%s", tt.status, tt.want, out.String())
+		}
+	}
+}
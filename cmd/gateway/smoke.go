@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const smokeUsage = "usage: gateway smoke [provider ...] [-config dir]"
+
+// smokePrompt is deliberately tiny so a smoke run costs a few tokens per model.
+const smokePrompt = "Reply with the single word: ok"
+
+// smokeTarget is one provider/model pair referenced by a model route.
+type smokeTarget struct {
+	provider string
+	model    string
+}
+
+// smokeResult is the outcome of one request against a target.
+type smokeResult struct {
+	smokeTarget
+	mode    string // "complete" or "stream"
+	outcome string // ok, auth_error, model_unavailable, rate_limited, error
+	latency time.Duration
+	detail  string
+}
+
+// runSmoke sends a minimal completion, and a streaming one when the adapter
+// supports it, to every provider/model pair used by a model route, so
+// credentials and model access can be checked before a config rollout. only
+// restricts the run to the named providers. It returns the process exit code:
+// 0 when every request succeeded, 1 if any failed, 2 on a usage error.
+func runSmoke(providers *config.ProvidersConfig, models *config.ModelsConfig, only []string, out io.Writer) int {
+	for _, name := range only {
+		if _, ok := providers.Providers[name]; !ok {
+			fmt.Fprintf(out, "error: unknown provider %q\n%s\n", name, smokeUsage)
+			return 2
+		}
+	}
+	targets := smokeTargets(providers, models, only)
+	if len(targets) == 0 {
+		fmt.Fprintln(out, "no provider is referenced by a model route; nothing to test")
+		return 1
+	}
+
+	registry := router.BuildFromConfig(providers, router.BuildOptions{})
+	results := make([][]smokeResult, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		adapter, _ := registry.Get(t.provider)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = smokeTest(context.Background(), adapter, t)
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tMODEL\tMODE\tRESULT\tLATENCY\tDETAIL")
+	for _, rs := range results {
+		for _, r := range rs {
+			if r.outcome != "ok" {
+				failed++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				r.provider, r.model, r.mode, r.outcome, r.latency.Round(time.Millisecond), r.detail)
+		}
+	}
+	_ = tw.Flush()
+
+	if failed > 0 {
+		fmt.Fprintf(out, "%d request(s) failed\n", failed)
+		return 1
+	}
+	return 0
+}
+
+// smokeTargets returns the distinct provider/model pairs referenced by model
+// routes (primary and fallbacks), sorted, limited to only when non-empty.
+func smokeTargets(providers *config.ProvidersConfig, models *config.ModelsConfig, only []string) []smokeTarget {
+	wanted := func(name string) bool {
+		if _, ok := providers.Providers[name]; !ok {
+			return false
+		}
+		if len(only) == 0 {
+			return true
+		}
+		for _, o := range only {
+			if o == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	seen := make(map[smokeTarget]bool)
+	var targets []smokeTarget
+	for _, m := range models.Models {
+		for _, route := range append([]config.ProviderRoute{m.Primary}, m.Fallback...) {
+			t := smokeTarget{provider: route.Provider, model: route.Model}
+			if route.Model == "" || !wanted(t.provider) || seen[t] {
+				continue
+			}
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].provider != targets[j].provider {
+			return targets[i].provider < targets[j].provider
+		}
+		return targets[i].model < targets[j].model
+	})
+	return targets
+}
+
+func smokeTest(ctx context.Context, adapter adapters.ProviderAdapter, t smokeTarget) []smokeResult {
+	results := []smokeResult{smokeRequest(ctx, adapter, t, false)}
+	if adapter.SupportsStreaming() {
+		results = append(results, smokeRequest(ctx, adapter, t, true))
+	}
+	return results
+}
+
+// smokeRequest sends one request. For streams the latency is time to the
+// first content chunk.
+func smokeRequest(ctx context.Context, adapter adapters.ProviderAdapter, t smokeTarget, stream bool) smokeResult {
+	res := smokeResult{smokeTarget: t, mode: "complete"}
+	if stream {
+		res.mode = "stream"
+	}
+
+	maxTokens := 5
+	req := &types.AegisRequest{
+		RequestID: "smoke-" + t.provider,
+		Model:     t.model,
+		Messages:  []types.Message{{Role: "user", Content: smokePrompt}},
+		MaxTokens: &maxTokens,
+		Stream:    stream,
+	}
+	httpReq, err := adapter.TransformRequest(ctx, req)
+	if err != nil {
+		res.outcome, res.detail = "error", err.Error()
+		return res
+	}
+
+	start := time.Now()
+	resp, err := adapter.SendRequest(httpReq)
+	if err != nil {
+		res.latency = time.Since(start)
+		res.outcome, res.detail = "error", err.Error()
+		return res
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		res.latency = time.Since(start)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		res.outcome = smokeOutcome(resp.StatusCode)
+		res.detail = fmt.Sprintf("HTTP %d: %s", resp.StatusCode, strings.Join(strings.Fields(string(body)), " "))
+		return res
+	}
+
+	if !stream {
+		aegisResp, err := adapter.TransformResponse(ctx, resp)
+		res.latency = time.Since(start)
+		if err != nil {
+			res.outcome, res.detail = "error", err.Error()
+			return res
+		}
+		res.outcome = "ok"
+		res.detail = fmt.Sprintf("served %s, %d tokens", aegisResp.Model, aegisResp.Usage.TotalTokens)
+		return res
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		chunk, err := adapter.TransformStreamChunk([]byte(data))
+		if err != nil || chunk == nil || string(chunk) == "[DONE]" {
+			continue
+		}
+		res.latency = time.Since(start)
+		res.outcome, res.detail = "ok", "first chunk received"
+		return res
+	}
+	res.latency = time.Since(start)
+	res.outcome = "error"
+	res.detail = "stream ended without a content chunk"
+	if err := scanner.Err(); err != nil {
+		res.detail = err.Error()
+	}
+	return res
+}
+
+// smokeOutcome classifies a non-200 provider status.
+func smokeOutcome(status int) string {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "auth_error"
+	case http.StatusNotFound:
+		return "model_unavailable"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	default:
+		return "error"
+	}
+}
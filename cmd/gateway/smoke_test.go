@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// fakeOpenAI accepts "good-key" and serves only gpt-test.
+func fakeOpenAI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided"}}`))
+			return
+		}
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "gpt-test" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"model not found"}}`))
+			return
+		}
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"gpt-test-0613","choices":[{"message":{"role":"assistant","content":"ok"}}],"usage":{"prompt_tokens":8,"completion_tokens":1,"total_tokens":9}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func smokeConfigs(url string) (*config.ProvidersConfig, *config.ModelsConfig) {
+	providers := &config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"good":    {Type: "openai", BaseURL: url, APIKey: "good-key", Timeout: 5 * time.Second},
+		"badauth": {Type: "openai", BaseURL: url, APIKey: "wrong", Timeout: 5 * time.Second},
+		"unused":  {Type: "openai", BaseURL: url, APIKey: "good-key", Timeout: 5 * time.Second},
+	}}
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"fast": {
+			Primary:  config.ProviderRoute{Provider: "good", Model: "gpt-test"},
+			Fallback: []config.ProviderRoute{{Provider: "badauth", Model: "gpt-test"}},
+		},
+		"smart": {Primary: config.ProviderRoute{Provider: "good", Model: "gpt-missing"}},
+		// Same pair as "fast" primary; tested once.
+		"alias": {Primary: config.ProviderRoute{Provider: "good", Model: "gpt-test"}},
+	}}
+	return providers, models
+}
+
+func TestSmokeTargets(t *testing.T) {
+	providers, models := smokeConfigs("http://unused")
+	got := smokeTargets(providers, models, nil)
+	want := []smokeTarget{{"badauth", "gpt-test"}, {"good", "gpt-missing"}, {"good", "gpt-test"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("smokeTargets = %v, want %v", got, want)
+	}
+	if got := smokeTargets(providers, models, []string{"badauth"}); len(got) != 1 || got[0].provider != "badauth" {
+		t.Errorf("filtered smokeTargets = %v", got)
+	}
+}
+
+func TestRunSmoke(t *testing.T) {
+	providers, models := smokeConfigs(fakeOpenAI(t).URL)
+
+	var out bytes.Buffer
+	if code := runSmoke(providers, models, []string{"good"}, &out); code != 1 {
+		t.Fatalf("expected exit 1 with a missing model, got %d:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"good      gpt-test     complete  ok",
+		"good      gpt-test     stream    ok",
+		"model_unavailable",
+		"served gpt-test-0613, 9 tokens",
+		"2 request(s) failed",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	runSmoke(providers, models, []string{"badauth"}, &out)
+	if !strings.Contains(out.String(), "auth_error") || !strings.Contains(out.String(), "Incorrect API key") {
+		t.Errorf("expected auth_error with provider message:\n%s", out.String())
+	}
+
+	models.Models = map[string]config.ModelMapping{"fast": models.Models["fast"]}
+	out.Reset()
+	if code := runSmoke(providers, models, []string{"good"}, &out); code != 0 {
+		t.Errorf("expected exit 0 when every request succeeds, got %d:\n%s", code, out.String())
+	}
+}
+
+func TestRunSmoke_UnknownProvider(t *testing.T) {
+	providers, models := smokeConfigs("http://unused")
+	var out bytes.Buffer
+	if code := runSmoke(providers, models, []string{"nope"}, &out); code != 2 {
+		t.Errorf("expected exit 2, got %d", code)
+	}
+}
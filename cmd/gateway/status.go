@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter/pii"
+	"github.com/af-corp/aegis-gateway/internal/filter/policy"
+	"github.com/af-corp/aegis-gateway/internal/router"
+)
+
+// statusResponse is the on-call diagnostic returned by /aegis/v1/status.
+type statusResponse struct {
+	Version       string                    `json:"version"`
+	ConfigVersion string                    `json:"config_version"`
+	Timestamp     time.Time                 `json:"timestamp"`
+	Providers     map[string]providerDetail `json:"providers"`
+	Models        []string                  `json:"models"`
+	Filters       filterServices            `json:"filters"`
+	// Schedule lists the configured maintenance windows and office hours.
+	Schedule []accessWindowStatus `json:"schedule"`
+}
+
+type providerDetail struct {
+	Healthy        bool       `json:"healthy"`
+	State          string     `json:"state"`
+	RecentRequests int        `json:"recent_requests"`
+	RecentFailures int        `json:"recent_failures"`
+	ErrorRate      float64    `json:"error_rate"`
+	LastFailure    *time.Time `json:"last_failure,omitempty"`
+}
+
+type filterServices struct {
+	PII    piiServiceStatus    `json:"pii"`
+	Policy policyServiceStatus `json:"policy"`
+}
+
+// accessWindowStatus is a configured access window and whether it is open.
+type accessWindowStatus struct {
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	Organizations []string   `json:"organizations,omitempty"`
+	Models        []string   `json:"models,omitempty"`
+	Timezone      string     `json:"timezone,omitempty"`
+	Days          []string   `json:"days,omitempty"`
+	Start         string     `json:"start,omitempty"`
+	End           string     `json:"end,omitempty"`
+	StartsAt      *time.Time `json:"starts_at,omitempty"`
+	EndsAt        *time.Time `json:"ends_at,omitempty"`
+	Open          bool       `json:"open"`
+	// NextChange is when Open next flips, if within a week.
+	NextChange *time.Time `json:"next_change,omitempty"`
+}
+
+type piiServiceStatus struct {
+	Enabled bool   `json:"enabled"`
+	State   string `json:"state"`
+}
+
+type policyServiceStatus struct {
+	Enabled bool `json:"enabled"`
+	Loaded  bool `json:"loaded"`
+}
+
+func makeStatusHandler(registry *router.Registry, healthTracker *router.HealthTracker, modelsCfg func() *config.ModelsConfig, configVersion func() string, piiClient *pii.Client, policyEvaluator *policy.Evaluator, accessWindows func() []config.AccessWindowConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := statusResponse{
+			Version:       version,
+			ConfigVersion: configVersion(),
+			Timestamp:     time.Now(),
+			Providers:     make(map[string]providerDetail),
+			Models:        []string{},
+			Schedule:      []accessWindowStatus{},
+		}
+
+		if registry != nil && healthTracker != nil {
+			for _, provName := range registry.ListProviders() {
+				detail := providerDetail{
+					Healthy: healthTracker.IsAvailable(provName),
+					State:   healthTracker.GetState(provName),
+				}
+				if stats, ok := healthTracker.GetStats(provName); ok {
+					detail.State = stats.State.String()
+					detail.RecentRequests = stats.RecentRequests
+					detail.RecentFailures = stats.RecentFailures
+					detail.ErrorRate = stats.ErrorRate
+					if !stats.LastFailure.IsZero() {
+						lastFailure := stats.LastFailure
+						detail.LastFailure = &lastFailure
+					}
+				}
+				resp.Providers[provName] = detail
+			}
+		}
+
+		if models := modelsCfg(); models != nil {
+			for name := range models.Models {
+				resp.Models = append(resp.Models, name)
+			}
+			slices.Sort(resp.Models)
+		}
+
+		if piiClient != nil {
+			resp.Filters.PII = piiServiceStatus{Enabled: piiClient.Enabled(), State: piiClient.ConnState()}
+		}
+		if policyEvaluator != nil {
+			resp.Filters.Policy = policyServiceStatus{Enabled: policyEvaluator.Enabled(), Loaded: policyEvaluator.Loaded()}
+		}
+
+		if accessWindows != nil {
+			for _, aw := range accessWindows() {
+				resp.Schedule = append(resp.Schedule, newAccessWindowStatus(aw, resp.Timestamp))
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func newAccessWindowStatus(w config.AccessWindowConfig, now time.Time) accessWindowStatus {
+	st := accessWindowStatus{
+		Name:          w.Name,
+		Type:          w.Type,
+		Organizations: w.Organizations,
+		Models:        w.Models,
+		Timezone:      w.Timezone,
+		Days:          w.Days,
+		Start:         w.Start,
+		End:           w.End,
+	}
+	if !w.StartsAt.IsZero() {
+		st.StartsAt, st.EndsAt = &w.StartsAt, &w.EndsAt
+	}
+	open, change := router.AccessWindowState(w, now)
+	st.Open = open
+	if !change.IsZero() {
+		st.NextChange = &change
+	}
+	return st
+}
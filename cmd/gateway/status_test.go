@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+)
+
+func TestMakeStatusHandler(t *testing.T) {
+	registry := router.NewRegistry()
+	registry.Register("openai", nil)
+	registry.Register("anthropic", nil)
+
+	healthTracker := router.NewHealthTracker(1, time.Minute)
+	healthTracker.RecordSuccess("openai")
+	healthTracker.RecordFailure("anthropic")
+
+	modelsCfg := func() *config.ModelsConfig {
+		return &config.ModelsConfig{Models: map[string]config.ModelMapping{
+			"aegis-gpt4": {},
+			"aegis-fast": {},
+		}}
+	}
+
+	handler := makeStatusHandler(registry, healthTracker, modelsCfg, func() string { return "abc123def456" }, nil, nil, nil)
+	req := httptest.NewRequest("GET", "/aegis/v1/status", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp statusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ConfigVersion != "abc123def456" {
+		t.Errorf("expected config version abc123def456, got %s", resp.ConfigVersion)
+	}
+	if len(resp.Models) != 2 || resp.Models[0] != "aegis-fast" {
+		t.Errorf("expected sorted models [aegis-fast aegis-gpt4], got %v", resp.Models)
+	}
+
+	anthropic := resp.Providers["anthropic"]
+	if anthropic.State != "open" || anthropic.Healthy {
+		t.Errorf("expected anthropic open and unhealthy, got %+v", anthropic)
+	}
+	if anthropic.ErrorRate != 1 || anthropic.LastFailure == nil {
+		t.Errorf("expected anthropic error rate 1 with last failure, got %+v", anthropic)
+	}
+
+	openai := resp.Providers["openai"]
+	if openai.State != "closed" || !openai.Healthy || openai.RecentRequests != 1 {
+		t.Errorf("expected openai closed with 1 recent request, got %+v", openai)
+	}
+}
+
+func TestMakeStatusHandler_Schedule(t *testing.T) {
+	windows := []config.AccessWindowConfig{{
+		Name: "always-open", Type: config.AccessWindowOfficeHours, Organizations: []string{"org-1"},
+		Start: "00:00", End: "23:59",
+	}}
+	handler := makeStatusHandler(nil, nil, func() *config.ModelsConfig { return nil }, func() string { return "" }, nil, nil,
+		func() []config.AccessWindowConfig { return windows })
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/aegis/v1/status", nil))
+
+	var resp statusResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Schedule) != 1 {
+		t.Fatalf("expected 1 schedule entry, got %+v", resp.Schedule)
+	}
+	if got := resp.Schedule[0]; got.Name != "always-open" || got.Organizations[0] != "org-1" || got.NextChange == nil {
+		t.Errorf("unexpected schedule entry %+v", got)
+	}
+}
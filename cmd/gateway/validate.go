@@ -0,0 +1,14 @@
+package main
+
+import (
+	"io"
+
+	"github.com/af-corp/aegis-gateway/internal/configcheck"
+)
+
+// runValidate loads and cross-checks the config directory and prints each
+// problem to out. It returns the process exit code: 0 when the config is
+// deployable, 1 otherwise.
+func runValidate(configDir string, out io.Writer) int {
+	return configcheck.Run(configDir, out)
+}
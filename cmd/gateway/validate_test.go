@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunValidate_RepoConfigs(t *testing.T) {
+	t.Setenv("OPA_BUNDLE_PATH", "../../configs/policies")
+
+	var out bytes.Buffer
+	if code := runValidate("../../configs", &out); code != 0 {
+		t.Fatalf("expected shipped configs to validate, exit %d:\n%s", code, out.String())
+	}
+	if !strings.Contains(out.String(), "configuration OK") {
+		t.Errorf("expected OK summary, got:\n%s", out.String())
+	}
+}
+
+func TestRunValidate_Errors(t *testing.T) {
+	dir := t.TempDir()
+	policies := filepath.Join(dir, "policies")
+	if err := os.Mkdir(policies, 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"gateway.yaml": "filter:\n  policy:\n    enabled: true\n    bundle_path: \"" + policies + "\"\n",
+		"models.yaml": `models:
+  m:
+    primary:
+      provider: missing
+      model: x
+      classification_ceiling: TOPSECRET
+`,
+		"providers.yaml":       "providers:\n  openai:\n    type: openai\n    base_url: \"https://api.openai.com/v1\"\n",
+		"policies/broken.rego": "package aegis.policy\n\nallow := {\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if code := runValidate(dir, &out); code != 1 {
+		t.Fatalf("expected exit 1, got %d:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		`models.m.primary.provider: "missing" is not defined`,
+		`unknown classification "TOPSECRET"`,
+		"prepare rego",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunValidate_MissingDir(t *testing.T) {
+	var out bytes.Buffer
+	if code := runValidate(filepath.Join(t.TempDir(), "nope"), &out); code != 1 {
+		t.Errorf("expected exit 1 for missing config dir, got %d", code)
+	}
+}
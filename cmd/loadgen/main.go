@@ -0,0 +1,301 @@
+// Command loadgen replays synthetic or recorded chat requests against a
+// gateway and reports throughput, latency, time to first token, and gateway
+// overhead percentiles, for benchmarking filter-chain and routing changes.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const usage = `usage: loadgen [flags]
+
+Sends -n requests (or runs for -duration) with -c concurrent workers and
+prints a summary. Requests are synthetic unless -requests names a JSONL file
+of recorded chat completion bodies, which are replayed in order and cycled.
+
+Flags:
+`
+
+// config holds the parsed command line.
+type config struct {
+	url         string
+	key         string
+	model       string
+	requests    string
+	n           int
+	duration    time.Duration
+	concurrency int
+	rate        float64
+	stream      bool
+	maxTokens   int
+	promptWords int
+	timeout     time.Duration
+	json        bool
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv))
+}
+
+// run executes a load test and returns the process exit code: 0 when every
+// request succeeded, 1 if any failed, 2 on a usage error.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string) int {
+	var cfg config
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.url, "url", envOr(getenv, "AEGIS_URL", "http://localhost:8080"), "gateway base URL")
+	fs.StringVar(&cfg.key, "key", getenv("AEGIS_API_KEY"), "API key (default $AEGIS_API_KEY)")
+	fs.StringVar(&cfg.model, "model", "aegis-fast", "model for synthetic requests; forces the model on recorded ones when set explicitly")
+	fs.StringVar(&cfg.requests, "requests", "", "JSONL file of recorded chat completion request bodies")
+	fs.IntVar(&cfg.n, "n", 100, "total requests (ignored with -duration)")
+	fs.DurationVar(&cfg.duration, "duration", 0, "run for this long instead of -n requests")
+	fs.IntVar(&cfg.concurrency, "c", 10, "concurrent workers")
+	fs.Float64Var(&cfg.rate, "rate", 0, "target requests per second across all workers; 0 = as fast as possible")
+	fs.BoolVar(&cfg.stream, "stream", false, "send streaming requests and measure time to first token")
+	fs.IntVar(&cfg.maxTokens, "max-tokens", 64, "max_tokens for requests that do not set it; 0 omits it")
+	fs.IntVar(&cfg.promptWords, "prompt-words", 50, "approximate prompt size of synthetic requests, in words")
+	fs.DurationVar(&cfg.timeout, "timeout", 60*time.Second, "per-request timeout")
+	output := fs.String("o", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	forceModel := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "model" {
+			forceModel = true
+		}
+	})
+
+	switch {
+	case cfg.key == "":
+		fmt.Fprintln(stderr, "error: API key required: set AEGIS_API_KEY or pass -key")
+		return 2
+	case cfg.concurrency < 1:
+		fmt.Fprintln(stderr, "error: -c must be at least 1")
+		return 2
+	case cfg.duration <= 0 && cfg.n < 1:
+		fmt.Fprintln(stderr, "error: -n must be at least 1")
+		return 2
+	case *output != "text" && *output != "json":
+		fmt.Fprintln(stderr, "error: -o must be text or json")
+		return 2
+	}
+	cfg.json = *output == "json"
+
+	var workload []chatRequest
+	if cfg.requests != "" {
+		var err error
+		if workload, err = loadWorkload(cfg.requests); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		workload = syntheticWorkload(max(cfg.n, 100), cfg.promptWords)
+		forceModel = true
+	}
+	bodies := make([][]byte, len(workload))
+	for i, req := range workload {
+		body, err := json.Marshal(req.prepare(cfg.model, forceModel, cfg.stream, cfg.maxTokens))
+		if err != nil {
+			fmt.Fprintf(stderr, "error: encode request %d: %v\n", i, err)
+			return 1
+		}
+		bodies[i] = body
+	}
+
+	results, elapsed := execute(context.Background(), cfg, bodies)
+	rep := buildReport(results, elapsed)
+	var err error
+	if cfg.json {
+		err = rep.writeJSON(stdout)
+	} else {
+		err = rep.writeText(stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+	if rep.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func envOr(getenv func(string) string, key, def string) string {
+	if v := getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// execute feeds request bodies to the workers, cycling through them, until
+// cfg.n requests are sent or cfg.duration elapses.
+func execute(ctx context.Context, cfg config, bodies [][]byte) ([]result, time.Duration) {
+	if cfg.duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.duration)
+		defer cancel()
+	}
+
+	client := &http.Client{
+		Timeout: cfg.timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        cfg.concurrency,
+			MaxIdleConnsPerHost: cfg.concurrency,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+	endpoint := strings.TrimRight(cfg.url, "/") + "/v1/chat/completions"
+
+	jobs := make(chan []byte)
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if cfg.rate > 0 {
+			ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for i := 0; cfg.duration > 0 || i < cfg.n; i++ {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- bodies[i%len(bodies)]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu      sync.Mutex
+		results []result
+		wg      sync.WaitGroup
+	)
+	start := time.Now()
+	for range cfg.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for body := range jobs {
+				// Requests in flight when -duration ends still complete.
+				r := send(context.Background(), client, endpoint, cfg.key, body, cfg.stream)
+				mu.Lock()
+				results = append(results, r)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return results, time.Since(start)
+}
+
+// send issues one chat completion and measures it. On streams the body is
+// read to the end so latency covers the whole response.
+func send(ctx context.Context, client *http.Client, endpoint, key string, body []byte, stream bool) result {
+	var res result
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		res.err = err.Error()
+		return res
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.latency = time.Since(start)
+		res.err = errorKind(err)
+		return res
+	}
+	defer func() { _ = resp.Body.Close() }()
+	res.status = resp.StatusCode
+
+	if resp.StatusCode != http.StatusOK || !stream {
+		_, err = io.Copy(io.Discard, resp.Body)
+		res.latency = time.Since(start)
+		if err != nil {
+			res.err = errorKind(err)
+		}
+		res.completionTokens, _ = strconv.Atoi(resp.Header.Get("X-Aegis-Tokens-Completion"))
+		if v := resp.Header.Get("X-Aegis-Provider-Latency-Ms"); v != "" {
+			if providerMs, err := strconv.ParseFloat(v, 64); err == nil {
+				res.overhead = res.latency - time.Duration(providerMs*float64(time.Millisecond))
+				res.hasOverhead = true
+			}
+		}
+		return res
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	usageNext := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "event: aegis.usage" {
+			usageNext = true
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		switch {
+		case data == "[DONE]":
+		case usageNext:
+			var u struct {
+				CompletionTokens int `json:"completion_tokens"`
+			}
+			if json.Unmarshal([]byte(data), &u) == nil {
+				res.completionTokens = u.CompletionTokens
+			}
+			usageNext = false
+		case res.ttft == 0 && strings.Contains(data, `"content"`):
+			res.ttft = time.Since(start)
+		case strings.HasPrefix(data, `{"error"`):
+			res.err = "stream error event"
+		}
+	}
+	res.latency = time.Since(start)
+	if err := scanner.Err(); err != nil {
+		res.err = errorKind(err)
+	}
+	return res
+}
+
+// errorKind shortens transport errors so they group in the report.
+func errorKind(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "Client.Timeout"), strings.Contains(msg, "deadline exceeded"):
+		return "timeout"
+	case strings.Contains(msg, "connection refused"):
+		return "connection refused"
+	case strings.Contains(msg, "connection reset"):
+		return "connection reset"
+	default:
+		return msg
+	}
+}
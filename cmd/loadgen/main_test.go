@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGateway answers chat completions like the gateway does, failing every
+// request whose model is "broken".
+func fakeGateway(t *testing.T) (*httptest.Server, func() []map[string]any) {
+	t.Helper()
+	var mu sync.Mutex
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		bodies = append(bodies, body)
+		mu.Unlock()
+
+		if body["model"] == "broken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if stream, _ := body["stream"].(bool); stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+			fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"ok\"}}]}\n\n")
+			fmt.Fprint(w, "event: aegis.usage\ndata: {\"completion_tokens\":7}\n\n")
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+		w.Header().Set("X-Aegis-Tokens-Completion", "5")
+		w.Header().Set("X-Aegis-Provider-Latency-Ms", "1.0")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func runLoadgen(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr, func(k string) string {
+		if k == "AEGIS_API_KEY" {
+			return "test-key"
+		}
+		return ""
+	})
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Synthetic(t *testing.T) {
+	srv, bodies := fakeGateway(t)
+	code, out, stderr := runLoadgen(t, "-url", srv.URL, "-n", "20", "-c", "4", "-o", "json")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var rep report
+	if err := json.Unmarshal([]byte(out), &rep); err != nil {
+		t.Fatalf("decode report: %v\n%s", err, out)
+	}
+	if rep.Requests != 20 || rep.Succeeded != 20 || rep.CompletionTokens != 100 {
+		t.Errorf("unexpected counts: %+v", rep)
+	}
+	if rep.Overhead == nil || rep.Overhead.Count != 20 || rep.TTFT != nil {
+		t.Errorf("expected overhead and no TTFT for non-streaming: %+v", rep)
+	}
+	if rep.Overhead.P50 >= rep.Latency.P50 {
+		t.Errorf("overhead p50 %.1f should be below latency p50 %.1f", rep.Overhead.P50, rep.Latency.P50)
+	}
+	if got := bodies()[0]["model"]; got != "aegis-fast" {
+		t.Errorf("synthetic model = %v, want aegis-fast", got)
+	}
+}
+
+func TestRun_StreamingRecorded(t *testing.T) {
+	srv, bodies := fakeGateway(t)
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	recorded := `# captured from staging
+{"model":"aegis-gpt4","messages":[{"role":"user","content":"hi"}],"max_tokens":10}
+
+{"model":"aegis-reasoning","messages":[{"role":"user","content":"why"}],"temperature":0.2}
+`
+	if err := os.WriteFile(path, []byte(recorded), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runLoadgen(t, "-url", srv.URL, "-requests", path, "-stream", "-n", "4", "-c", "2")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	for _, want := range []string{"4 ok, 0 failed", "ttft ms", "latency ms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "overhead ms") {
+		t.Errorf("streams carry no provider latency header; overhead row unexpected:\n%s", out)
+	}
+
+	models := map[any]int{}
+	for _, b := range bodies() {
+		models[b["model"]]++
+		if b["stream"] != true {
+			t.Errorf("expected stream=true, got %v", b["stream"])
+		}
+	}
+	if models["aegis-gpt4"] != 2 || models["aegis-reasoning"] != 2 {
+		t.Errorf("recorded models should be kept and cycled: %v", models)
+	}
+}
+
+func TestRun_FailuresReported(t *testing.T) {
+	srv, _ := fakeGateway(t)
+	code, out, _ := runLoadgen(t, "-url", srv.URL, "-model", "broken", "-n", "3")
+	if code != 1 || !strings.Contains(out, "error HTTP 503: 3") {
+		t.Errorf("expected exit 1 with grouped errors, got %d:\n%s", code, out)
+	}
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-c", "0"},
+		{"-o", "yaml"},
+		{"-bogus"},
+	} {
+		if code, _, _ := runLoadgen(t, args...); code != 2 {
+			t.Errorf("args %v: expected exit 2, got %d", args, code)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 100; i++ {
+		ds = append(ds, time.Duration(i)*time.Millisecond)
+	}
+	p := summarize(ds)
+	if p.Count != 100 || p.Min != 1 || p.P50 != 50 || p.P90 != 90 || p.P99 != 99 || p.Max != 100 || p.Mean != 50.5 {
+		t.Errorf("unexpected percentiles: %+v", p)
+	}
+	if got := summarize(nil); got.Count != 0 {
+		t.Errorf("empty input: %+v", got)
+	}
+}
+
+func TestLoadWorkload_Errors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.jsonl")
+	_ = os.WriteFile(bad, []byte(`{"model":"x"}`+"\n"), 0o644)
+	if _, err := loadWorkload(bad); err == nil || !strings.Contains(err.Error(), "no messages") {
+		t.Errorf("expected missing messages error, got %v", err)
+	}
+	empty := filepath.Join(dir, "empty.jsonl")
+	_ = os.WriteFile(empty, []byte("# nothing\n"), 0o644)
+	if _, err := loadWorkload(empty); err == nil {
+		t.Error("expected error for a file with no requests")
+	}
+}
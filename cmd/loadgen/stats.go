@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+// result is the outcome of one request.
+type result struct {
+	status  int // 0 when the request never got a response
+	err     string
+	latency time.Duration
+	// ttft is the time to the first content chunk on streams.
+	ttft time.Duration
+	// overhead is latency minus the gateway-reported provider latency, on
+	// non-streaming responses that carry X-Aegis-Provider-Latency-Ms.
+	overhead         time.Duration
+	hasOverhead      bool
+	completionTokens int
+}
+
+func (r result) ok() bool { return r.status == 200 && r.err == "" }
+
+// percentiles summarizes a latency distribution in milliseconds.
+type percentiles struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min_ms"`
+	P50   float64 `json:"p50_ms"`
+	P90   float64 `json:"p90_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+	Mean  float64 `json:"mean_ms"`
+}
+
+// summarize computes nearest-rank percentiles.
+func summarize(ds []time.Duration) percentiles {
+	if len(ds) == 0 {
+		return percentiles{}
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	at := func(p float64) float64 {
+		idx := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return ms(sorted[idx])
+	}
+	return percentiles{
+		Count: len(sorted),
+		Min:   ms(sorted[0]),
+		P50:   at(50),
+		P90:   at(90),
+		P95:   at(95),
+		P99:   at(99),
+		Max:   ms(sorted[len(sorted)-1]),
+		Mean:  ms(total / time.Duration(len(sorted))),
+	}
+}
+
+func ms(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*10) / 10
+}
+
+// report is the aggregate of a run.
+type report struct {
+	Requests         int            `json:"requests"`
+	Succeeded        int            `json:"succeeded"`
+	Failed           int            `json:"failed"`
+	Errors           map[string]int `json:"errors,omitempty"`
+	DurationSeconds  float64        `json:"duration_seconds"`
+	RequestsPerSec   float64        `json:"requests_per_second"`
+	CompletionTokens int            `json:"completion_tokens"`
+	TokensPerSec     float64        `json:"completion_tokens_per_second"`
+	Latency          percentiles    `json:"latency"`
+	TTFT             *percentiles   `json:"ttft,omitempty"`
+	Overhead         *percentiles   `json:"gateway_overhead,omitempty"`
+}
+
+func buildReport(results []result, elapsed time.Duration) report {
+	rep := report{Requests: len(results), DurationSeconds: elapsed.Seconds()}
+	var latency, ttft, overhead []time.Duration
+	for _, r := range results {
+		if !r.ok() {
+			rep.Failed++
+			if rep.Errors == nil {
+				rep.Errors = make(map[string]int)
+			}
+			key := r.err
+			if r.status != 0 {
+				key = "HTTP " + strconv.Itoa(r.status)
+			}
+			rep.Errors[key]++
+			continue
+		}
+		rep.Succeeded++
+		rep.CompletionTokens += r.completionTokens
+		latency = append(latency, r.latency)
+		if r.ttft > 0 {
+			ttft = append(ttft, r.ttft)
+		}
+		if r.hasOverhead {
+			overhead = append(overhead, r.overhead)
+		}
+	}
+	if elapsed > 0 {
+		rep.RequestsPerSec = float64(rep.Succeeded) / elapsed.Seconds()
+		rep.TokensPerSec = float64(rep.CompletionTokens) / elapsed.Seconds()
+	}
+	rep.Latency = summarize(latency)
+	if len(ttft) > 0 {
+		p := summarize(ttft)
+		rep.TTFT = &p
+	}
+	if len(overhead) > 0 {
+		p := summarize(overhead)
+		rep.Overhead = &p
+	}
+	return rep
+}
+
+func (rep report) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
+
+func (rep report) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "requests:    %d (%d ok, %d failed) in %.2fs\n",
+		rep.Requests, rep.Succeeded, rep.Failed, rep.DurationSeconds)
+	fmt.Fprintf(w, "throughput:  %.1f req/s, %.1f completion tokens/s\n", rep.RequestsPerSec, rep.TokensPerSec)
+	keys := make([]string, 0, len(rep.Errors))
+	for k := range rep.Errors {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  error %s: %d\n", k, rep.Errors[k])
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "\tn\tmin\tp50\tp90\tp95\tp99\tmax\tmean\t")
+	row := func(name string, p percentiles) {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+			name, p.Count, p.Min, p.P50, p.P90, p.P95, p.P99, p.Max, p.Mean)
+	}
+	row("latency ms", rep.Latency)
+	if rep.TTFT != nil {
+		row("ttft ms", *rep.TTFT)
+	}
+	if rep.Overhead != nil {
+		row("overhead ms", *rep.Overhead)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// chatRequest is the subset of an OpenAI chat completion request loadgen
+// sends. Recorded requests may carry other fields; they are kept verbatim.
+type chatRequest map[string]any
+
+// syntheticPrompts are varied so semantic caches and provider-side prompt
+// caching do not flatten the results.
+var syntheticPrompts = []string{
+	"Summarize the main trade-offs between optimistic and pessimistic locking.",
+	"Write a haiku about a message queue.",
+	"List three ways to reduce tail latency in an HTTP service.",
+	"Explain what a circuit breaker does in one paragraph.",
+	"Translate 'the deployment finished successfully' into French and German.",
+	"Give a short checklist for reviewing a database migration.",
+	"What is the difference between a mutex and a semaphore?",
+	"Suggest a name for an internal AI gateway and explain it in one sentence.",
+}
+
+// syntheticWorkload builds n distinct requests, each padded with filler text
+// to roughly promptWords words so filter-chain cost scales like real prompts.
+func syntheticWorkload(n, promptWords int) []chatRequest {
+	reqs := make([]chatRequest, n)
+	for i := range reqs {
+		prompt := syntheticPrompts[i%len(syntheticPrompts)]
+		if pad := promptWords - len(strings.Fields(prompt)); pad > 0 {
+			prompt = strings.Repeat("context ", pad) + "\n\n" + prompt
+		}
+		reqs[i] = chatRequest{
+			"messages": []map[string]string{
+				{"role": "system", "content": "You are a concise assistant."},
+				{"role": "user", "content": fmt.Sprintf("[%d] %s", i, prompt)},
+			},
+		}
+	}
+	return reqs
+}
+
+// loadWorkload reads recorded chat requests, one JSON request body per line.
+// Blank lines and lines starting with # are skipped.
+func loadWorkload(path string) ([]chatRequest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var reqs []chatRequest
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		var req chatRequest
+		if err := json.Unmarshal([]byte(text), &req); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if _, ok := req["messages"]; !ok {
+			return nil, fmt.Errorf("%s:%d: request has no messages", path, line)
+		}
+		reqs = append(reqs, req)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%s: no requests", path)
+	}
+	return reqs, nil
+}
+
+// prepare fills in the settings that apply to every request. A recorded
+// request keeps its own model unless model is forced.
+func (r chatRequest) prepare(model string, forceModel, stream bool, maxTokens int) chatRequest {
+	out := make(chatRequest, len(r)+3)
+	for k, v := range r {
+		out[k] = v
+	}
+	if _, ok := out["model"]; !ok || forceModel {
+		out["model"] = model
+	}
+	out["stream"] = stream
+	if _, ok := out["max_tokens"]; !ok && maxTokens > 0 {
+		out["max_tokens"] = maxTokens
+	}
+	return out
+}
@@ -1,70 +0,0 @@
-package main
-
-import (
-	"flag"
-	"fmt"
-	"log"
-	"os"
-
-	"github.com/golang-migrate/migrate/v4"
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
-)
-
-func main() {
-	direction := flag.String("direction", "up", "migration direction: up or down")
-	steps := flag.Int("steps", 0, "number of steps (0 = all)")
-	dbURL := flag.String("db-url", "", "database URL (overrides env)")
-	migrationsPath := flag.String("path", "migrations", "path to migrations directory")
-	flag.Parse()
-
-	dsn := *dbURL
-	if dsn == "" {
-		dsn = os.Getenv("DATABASE_URL")
-	}
-	if dsn == "" {
-		host := envOrDefault("DB_HOST", "localhost")
-		port := envOrDefault("DB_PORT", "5432")
-		user := envOrDefault("DB_USER", "aegis")
-		pass := envOrDefault("DB_PASSWORD", "aegis-dev")
-		name := envOrDefault("DB_NAME", "aegis")
-		dsn = fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, pass, host, port, name)
-	}
-
-	m, err := migrate.New("file://"+*migrationsPath, dsn)
-	if err != nil {
-		log.Fatalf("failed to create migrator: %v", err)
-	}
-	defer func() { _, _ = m.Close() }()
-
-	switch *direction {
-	case "up":
-		if *steps > 0 {
-			err = m.Steps(*steps)
-		} else {
-			err = m.Up()
-		}
-	case "down":
-		if *steps > 0 {
-			err = m.Steps(-*steps)
-		} else {
-			err = m.Down()
-		}
-	default:
-		log.Fatalf("invalid direction: %s (use 'up' or 'down')", *direction)
-	}
-
-	if err != nil && err != migrate.ErrNoChange {
-		log.Fatalf("migration failed: %v", err)
-	}
-
-	v, dirty, _ := m.Version()
-	fmt.Printf("migration %s complete (version: %d, dirty: %v)\n", *direction, v, dirty)
-}
-
-func envOrDefault(key, def string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
-	}
-	return def
-}
@@ -2,12 +2,11 @@
 set -euo pipefail
 
 CONFIG_DIR="${AEGIS_CONFIG_DIR:-/etc/aegis/configs}"
-MIGRATIONS_DIR="${AEGIS_MIGRATIONS_DIR:-/etc/aegis/migrations}"
 
 # ── Wait for Postgres ────────────────────────────────────────────
 wait_for_postgres() {
   local retries=30
-  until migrate -path "$MIGRATIONS_DIR" -direction up -db-url "$DATABASE_URL" 2>/dev/null || [ $retries -eq 0 ]; do
+  until gateway migrate version -config "$CONFIG_DIR" >/dev/null 2>&1 || [ $retries -eq 0 ]; do
     echo "waiting for postgres… ($retries attempts left)"
     retries=$((retries - 1))
     sleep 1
@@ -21,7 +20,7 @@ wait_for_postgres() {
 # ── Run migrations ───────────────────────────────────────────────
 run_migrations() {
   echo "running migrations…"
-  migrate -path "$MIGRATIONS_DIR" -direction up
+  gateway migrate up -config "$CONFIG_DIR"
   echo "migrations complete"
 }
 
@@ -66,7 +65,7 @@ case "${1:-gateway}" in
     ;;
   migrate)
     shift
-    exec migrate "$@"
+    exec gateway migrate "$@"
     ;;
   keygen)
     shift
@@ -0,0 +1,69 @@
+"""Heuristic data classification estimate for prompt content."""
+
+import re
+from dataclasses import dataclass
+
+from presidio_analyzer import AnalyzerEngine
+
+# Entity types whose presence implies at least the given classification.
+ENTITY_LEVELS = {
+    "US_SSN": "RESTRICTED",
+    "CREDIT_CARD": "RESTRICTED",
+    "IBAN_CODE": "RESTRICTED",
+    "US_BANK_NUMBER": "RESTRICTED",
+    "US_PASSPORT": "RESTRICTED",
+    "MEDICAL_LICENSE": "CONFIDENTIAL",
+    "US_DRIVER_LICENSE": "CONFIDENTIAL",
+    "PHONE_NUMBER": "CONFIDENTIAL",
+    "EMAIL_ADDRESS": "INTERNAL",
+    "PERSON": "INTERNAL",
+}
+
+# Document markings and vocabulary that suggest a classification.
+KEYWORD_LEVELS = [
+    (re.compile(r"\b(top secret|restricted|attorney[- ]client privilege)\b", re.I), "RESTRICTED"),
+    (re.compile(r"\b(confidential|salary|salaries|payroll|diagnosis|m&a|acquisition target)\b", re.I), "CONFIDENTIAL"),
+    (re.compile(r"\b(internal only|internal use|do not distribute|roadmap)\b", re.I), "INTERNAL"),
+]
+
+LEVELS = ["PUBLIC", "INTERNAL", "CONFIDENTIAL", "RESTRICTED"]
+
+
+@dataclass
+class ClassificationResult:
+    classification: str
+    confidence: float
+    reasons: list[str]
+
+
+class ContentClassifier:
+    """Estimates classification from PII entities and sensitivity markers."""
+
+    def __init__(self, analyzer: AnalyzerEngine):
+        self.analyzer = analyzer
+
+    def classify(self, text: str) -> ClassificationResult:
+        level = 0
+        confidence = 0.6
+        reasons: list[str] = []
+
+        for r in self.analyzer.analyze(text=text, language="en", score_threshold=0.7):
+            entity_level = LEVELS.index(ENTITY_LEVELS.get(r.entity_type, "PUBLIC"))
+            if entity_level > level:
+                level, confidence = entity_level, r.score
+            if entity_level > 0 and r.entity_type not in reasons:
+                reasons.append(r.entity_type)
+
+        for pattern, marked in KEYWORD_LEVELS:
+            match = pattern.search(text)
+            if match and LEVELS.index(marked) >= level:
+                if LEVELS.index(marked) > level:
+                    confidence = 0.75
+                level = LEVELS.index(marked)
+                reasons.append(f"mentions '{match.group(0).lower()}'")
+
+        return ClassificationResult(
+            classification=LEVELS[level],
+            confidence=confidence,
+            reasons=reasons,
+        )
@@ -5,6 +5,7 @@ import logging
 import grpc
 import filter_pb2
 import filter_pb2_grpc
+from content_classifier import ContentClassifier
 from pii_scanner import PIIScanner
 
 logger = logging.getLogger(__name__)
@@ -15,6 +16,7 @@ class FilterService(filter_pb2_grpc.FilterServiceServicer):
 
     def __init__(self):
         self.scanner = PIIScanner()
+        self.classifier = ContentClassifier(self.scanner.analyzer)
         logger.info("PII scanner initialized")
 
     def ScanPII(self, request, context):
@@ -45,3 +47,18 @@ class FilterService(filter_pb2_grpc.FilterServiceServicer):
             context.set_code(grpc.StatusCode.INTERNAL)
             context.set_details(str(e))
             return filter_pb2.ScanPIIResponse()
+
+    def ClassifyContent(self, request, context):
+        """Estimate the data classification of text."""
+        try:
+            result = self.classifier.classify(request.text)
+            return filter_pb2.ClassifyContentResponse(
+                classification=result.classification,
+                confidence=result.confidence,
+                reasons=result.reasons,
+            )
+        except Exception as e:
+            logger.error("classification error: %s", e)
+            context.set_code(grpc.StatusCode.INTERNAL)
+            context.set_details(str(e))
+            return filter_pb2.ClassifyContentResponse()
@@ -30,9 +30,23 @@ type PIIDetection struct {
 	Score      float32 `json:"score"`
 }
 
+// ClassifyContentRequest is the request message for ClassifyContent.
+type ClassifyContentRequest struct {
+	Text                   string `json:"text"`
+	DeclaredClassification string `json:"declared_classification"`
+}
+
+// ClassifyContentResponse is the response message for ClassifyContent.
+type ClassifyContentResponse struct {
+	Classification string   `json:"classification"`
+	Confidence     float32  `json:"confidence"`
+	Reasons        []string `json:"reasons"`
+}
+
 // FilterServiceClient is the client interface for the FilterService.
 type FilterServiceClient interface {
 	ScanPII(ctx context.Context, in *ScanPIIRequest, opts ...grpc.CallOption) (*ScanPIIResponse, error)
+	ClassifyContent(ctx context.Context, in *ClassifyContentRequest, opts ...grpc.CallOption) (*ClassifyContentResponse, error)
 }
 
 type filterServiceClient struct {
@@ -53,9 +67,19 @@ func (c *filterServiceClient) ScanPII(ctx context.Context, in *ScanPIIRequest, o
 	return out, nil
 }
 
+func (c *filterServiceClient) ClassifyContent(ctx context.Context, in *ClassifyContentRequest, opts ...grpc.CallOption) (*ClassifyContentResponse, error) {
+	out := new(ClassifyContentResponse)
+	err := c.cc.Invoke(ctx, "/aegis.filter.v1.FilterService/ClassifyContent", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FilterServiceServer is the server interface for the FilterService.
 type FilterServiceServer interface {
 	ScanPII(context.Context, *ScanPIIRequest) (*ScanPIIResponse, error)
+	ClassifyContent(context.Context, *ClassifyContentRequest) (*ClassifyContentResponse, error)
 }
 
 // UnimplementedFilterServiceServer should be embedded to have forward compatible implementations.
@@ -65,6 +89,10 @@ func (UnimplementedFilterServiceServer) ScanPII(context.Context, *ScanPIIRequest
 	return nil, status.Errorf(codes.Unimplemented, "method ScanPII not implemented")
 }
 
+func (UnimplementedFilterServiceServer) ClassifyContent(context.Context, *ClassifyContentRequest) (*ClassifyContentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClassifyContent not implemented")
+}
+
 // RegisterFilterServiceServer registers the FilterService server.
 func RegisterFilterServiceServer(s *grpc.Server, srv FilterServiceServer) {
 	s.RegisterService(&FilterService_ServiceDesc, srv)
@@ -88,6 +116,24 @@ func _FilterService_ScanPII_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _FilterService_ClassifyContent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClassifyContentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FilterServiceServer).ClassifyContent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/aegis.filter.v1.FilterService/ClassifyContent",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FilterServiceServer).ClassifyContent(ctx, req.(*ClassifyContentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FilterService_ServiceDesc is the grpc.ServiceDesc for FilterService.
 var FilterService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "aegis.filter.v1.FilterService",
@@ -97,6 +143,10 @@ var FilterService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ScanPII",
 			Handler:    _FilterService_ScanPII_Handler,
 		},
+		{
+			MethodName: "ClassifyContent",
+			Handler:    _FilterService_ClassifyContent_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/filter/v1/filter.proto",
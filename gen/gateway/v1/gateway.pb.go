@@ -0,0 +1,618 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: gateway/v1/gateway.proto
+
+package gatewayv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Message struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of "system", "user", "assistant".
+	Role          string `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Name          string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Message) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type ChatCompletionRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Model alias, e.g. "aegis-fast".
+	Model       string     `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Messages    []*Message `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	Temperature *float64   `protobuf:"fixed64,3,opt,name=temperature,proto3,oneof" json:"temperature,omitempty"`
+	TopP        *float64   `protobuf:"fixed64,4,opt,name=top_p,json=topP,proto3,oneof" json:"top_p,omitempty"`
+	MaxTokens   *int32     `protobuf:"varint,5,opt,name=max_tokens,json=maxTokens,proto3,oneof" json:"max_tokens,omitempty"`
+	Stop        []string   `protobuf:"bytes,6,rep,name=stop,proto3" json:"stop,omitempty"`
+	// Project tag used for cost attribution (X-Aegis-Project).
+	Project string `protobuf:"bytes,7,opt,name=project,proto3" json:"project,omitempty"`
+	// Preferred provider within the model's route (X-Aegis-Prefer-Provider).
+	PreferProvider string `protobuf:"bytes,8,opt,name=prefer_provider,json=preferProvider,proto3" json:"prefer_provider,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ChatCompletionRequest) Reset() {
+	*x = ChatCompletionRequest{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatCompletionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatCompletionRequest) ProtoMessage() {}
+
+func (x *ChatCompletionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatCompletionRequest.ProtoReflect.Descriptor instead.
+func (*ChatCompletionRequest) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChatCompletionRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatCompletionRequest) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *ChatCompletionRequest) GetTemperature() float64 {
+	if x != nil && x.Temperature != nil {
+		return *x.Temperature
+	}
+	return 0
+}
+
+func (x *ChatCompletionRequest) GetTopP() float64 {
+	if x != nil && x.TopP != nil {
+		return *x.TopP
+	}
+	return 0
+}
+
+func (x *ChatCompletionRequest) GetMaxTokens() int32 {
+	if x != nil && x.MaxTokens != nil {
+		return *x.MaxTokens
+	}
+	return 0
+}
+
+func (x *ChatCompletionRequest) GetStop() []string {
+	if x != nil {
+		return x.Stop
+	}
+	return nil
+}
+
+func (x *ChatCompletionRequest) GetProject() string {
+	if x != nil {
+		return x.Project
+	}
+	return ""
+}
+
+func (x *ChatCompletionRequest) GetPreferProvider() string {
+	if x != nil {
+		return x.PreferProvider
+	}
+	return ""
+}
+
+type Usage struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens     int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens      int32                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Usage) Reset() {
+	*x = Usage{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Usage) ProtoMessage() {}
+
+func (x *Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Usage.ProtoReflect.Descriptor instead.
+func (*Usage) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Usage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *Usage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+type Choice struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Message       *Message               `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FinishReason  string                 `protobuf:"bytes,3,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Choice) Reset() {
+	*x = Choice{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Choice) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Choice) ProtoMessage() {}
+
+func (x *Choice) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Choice.ProtoReflect.Descriptor instead.
+func (*Choice) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Choice) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Choice) GetMessage() *Message {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+func (x *Choice) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+type ChatCompletionResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// Model that served the request.
+	Model string `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	// Provider that served the request.
+	Provider      string    `protobuf:"bytes,3,opt,name=provider,proto3" json:"provider,omitempty"`
+	Choices       []*Choice `protobuf:"bytes,4,rep,name=choices,proto3" json:"choices,omitempty"`
+	Usage         *Usage    `protobuf:"bytes,5,opt,name=usage,proto3" json:"usage,omitempty"`
+	CostUsd       float64   `protobuf:"fixed64,6,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatCompletionResponse) Reset() {
+	*x = ChatCompletionResponse{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatCompletionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatCompletionResponse) ProtoMessage() {}
+
+func (x *ChatCompletionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatCompletionResponse.ProtoReflect.Descriptor instead.
+func (*ChatCompletionResponse) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ChatCompletionResponse) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *ChatCompletionResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatCompletionResponse) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ChatCompletionResponse) GetChoices() []*Choice {
+	if x != nil {
+		return x.Choices
+	}
+	return nil
+}
+
+func (x *ChatCompletionResponse) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ChatCompletionResponse) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+type ChatCompletionChunk struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Model string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Index int32                  `protobuf:"varint,3,opt,name=index,proto3" json:"index,omitempty"`
+	// Set on the first chunk only.
+	Role  string `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	Delta string `protobuf:"bytes,5,opt,name=delta,proto3" json:"delta,omitempty"`
+	// Set on the last content chunk.
+	FinishReason string `protobuf:"bytes,6,opt,name=finish_reason,json=finishReason,proto3" json:"finish_reason,omitempty"`
+	// Usage, provider, and cost are set on the final chunk only.
+	Usage         *Usage  `protobuf:"bytes,7,opt,name=usage,proto3" json:"usage,omitempty"`
+	Provider      string  `protobuf:"bytes,8,opt,name=provider,proto3" json:"provider,omitempty"`
+	CostUsd       float64 `protobuf:"fixed64,9,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChatCompletionChunk) Reset() {
+	*x = ChatCompletionChunk{}
+	mi := &file_gateway_v1_gateway_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChatCompletionChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChatCompletionChunk) ProtoMessage() {}
+
+func (x *ChatCompletionChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_gateway_v1_gateway_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChatCompletionChunk.ProtoReflect.Descriptor instead.
+func (*ChatCompletionChunk) Descriptor() ([]byte, []int) {
+	return file_gateway_v1_gateway_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ChatCompletionChunk) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *ChatCompletionChunk) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetDelta() string {
+	if x != nil {
+		return x.Delta
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetFinishReason() string {
+	if x != nil {
+		return x.FinishReason
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetUsage() *Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *ChatCompletionChunk) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *ChatCompletionChunk) GetCostUsd() float64 {
+	if x != nil {
+		return x.CostUsd
+	}
+	return 0
+}
+
+var File_gateway_v1_gateway_proto protoreflect.FileDescriptor
+
+const file_gateway_v1_gateway_proto_rawDesc = "" +
+	"\n" +
+	"\x18gateway/v1/gateway.proto\x12\x10aegis.gateway.v1\"K\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"\xc9\x02\n" +
+	"\x15ChatCompletionRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x125\n" +
+	"\bmessages\x18\x02 \x03(\v2\x19.aegis.gateway.v1.MessageR\bmessages\x12%\n" +
+	"\vtemperature\x18\x03 \x01(\x01H\x00R\vtemperature\x88\x01\x01\x12\x18\n" +
+	"\x05top_p\x18\x04 \x01(\x01H\x01R\x04topP\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_tokens\x18\x05 \x01(\x05H\x02R\tmaxTokens\x88\x01\x01\x12\x12\n" +
+	"\x04stop\x18\x06 \x03(\tR\x04stop\x12\x18\n" +
+	"\aproject\x18\a \x01(\tR\aproject\x12'\n" +
+	"\x0fprefer_provider\x18\b \x01(\tR\x0epreferProviderB\x0e\n" +
+	"\f_temperatureB\b\n" +
+	"\x06_top_pB\r\n" +
+	"\v_max_tokens\"|\n" +
+	"\x05Usage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x05R\vtotalTokens\"x\n" +
+	"\x06Choice\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x123\n" +
+	"\amessage\x18\x02 \x01(\v2\x19.aegis.gateway.v1.MessageR\amessage\x12#\n" +
+	"\rfinish_reason\x18\x03 \x01(\tR\ffinishReason\"\xe7\x01\n" +
+	"\x16ChatCompletionResponse\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x1a\n" +
+	"\bprovider\x18\x03 \x01(\tR\bprovider\x122\n" +
+	"\achoices\x18\x04 \x03(\v2\x18.aegis.gateway.v1.ChoiceR\achoices\x12-\n" +
+	"\x05usage\x18\x05 \x01(\v2\x17.aegis.gateway.v1.UsageR\x05usage\x12\x19\n" +
+	"\bcost_usd\x18\x06 \x01(\x01R\acostUsd\"\x86\x02\n" +
+	"\x13ChatCompletionChunk\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x14\n" +
+	"\x05index\x18\x03 \x01(\x05R\x05index\x12\x12\n" +
+	"\x04role\x18\x04 \x01(\tR\x04role\x12\x14\n" +
+	"\x05delta\x18\x05 \x01(\tR\x05delta\x12#\n" +
+	"\rfinish_reason\x18\x06 \x01(\tR\ffinishReason\x12-\n" +
+	"\x05usage\x18\a \x01(\v2\x17.aegis.gateway.v1.UsageR\x05usage\x12\x1a\n" +
+	"\bprovider\x18\b \x01(\tR\bprovider\x12\x19\n" +
+	"\bcost_usd\x18\t \x01(\x01R\acostUsd2\xdf\x01\n" +
+	"\x0eGatewayService\x12c\n" +
+	"\x0eChatCompletion\x12'.aegis.gateway.v1.ChatCompletionRequest\x1a(.aegis.gateway.v1.ChatCompletionResponse\x12h\n" +
+	"\x14StreamChatCompletion\x12'.aegis.gateway.v1.ChatCompletionRequest\x1a%.aegis.gateway.v1.ChatCompletionChunk0\x01B;Z9github.com/af-corp/aegis-gateway/gen/gateway/v1;gatewayv1b\x06proto3"
+
+var (
+	file_gateway_v1_gateway_proto_rawDescOnce sync.Once
+	file_gateway_v1_gateway_proto_rawDescData []byte
+)
+
+func file_gateway_v1_gateway_proto_rawDescGZIP() []byte {
+	file_gateway_v1_gateway_proto_rawDescOnce.Do(func() {
+		file_gateway_v1_gateway_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gateway_v1_gateway_proto_rawDesc), len(file_gateway_v1_gateway_proto_rawDesc)))
+	})
+	return file_gateway_v1_gateway_proto_rawDescData
+}
+
+var file_gateway_v1_gateway_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_gateway_v1_gateway_proto_goTypes = []any{
+	(*Message)(nil),                // 0: aegis.gateway.v1.Message
+	(*ChatCompletionRequest)(nil),  // 1: aegis.gateway.v1.ChatCompletionRequest
+	(*Usage)(nil),                  // 2: aegis.gateway.v1.Usage
+	(*Choice)(nil),                 // 3: aegis.gateway.v1.Choice
+	(*ChatCompletionResponse)(nil), // 4: aegis.gateway.v1.ChatCompletionResponse
+	(*ChatCompletionChunk)(nil),    // 5: aegis.gateway.v1.ChatCompletionChunk
+}
+var file_gateway_v1_gateway_proto_depIdxs = []int32{
+	0, // 0: aegis.gateway.v1.ChatCompletionRequest.messages:type_name -> aegis.gateway.v1.Message
+	0, // 1: aegis.gateway.v1.Choice.message:type_name -> aegis.gateway.v1.Message
+	3, // 2: aegis.gateway.v1.ChatCompletionResponse.choices:type_name -> aegis.gateway.v1.Choice
+	2, // 3: aegis.gateway.v1.ChatCompletionResponse.usage:type_name -> aegis.gateway.v1.Usage
+	2, // 4: aegis.gateway.v1.ChatCompletionChunk.usage:type_name -> aegis.gateway.v1.Usage
+	1, // 5: aegis.gateway.v1.GatewayService.ChatCompletion:input_type -> aegis.gateway.v1.ChatCompletionRequest
+	1, // 6: aegis.gateway.v1.GatewayService.StreamChatCompletion:input_type -> aegis.gateway.v1.ChatCompletionRequest
+	4, // 7: aegis.gateway.v1.GatewayService.ChatCompletion:output_type -> aegis.gateway.v1.ChatCompletionResponse
+	5, // 8: aegis.gateway.v1.GatewayService.StreamChatCompletion:output_type -> aegis.gateway.v1.ChatCompletionChunk
+	7, // [7:9] is the sub-list for method output_type
+	5, // [5:7] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_gateway_v1_gateway_proto_init() }
+func file_gateway_v1_gateway_proto_init() {
+	if File_gateway_v1_gateway_proto != nil {
+		return
+	}
+	file_gateway_v1_gateway_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gateway_v1_gateway_proto_rawDesc), len(file_gateway_v1_gateway_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gateway_v1_gateway_proto_goTypes,
+		DependencyIndexes: file_gateway_v1_gateway_proto_depIdxs,
+		MessageInfos:      file_gateway_v1_gateway_proto_msgTypes,
+	}.Build()
+	File_gateway_v1_gateway_proto = out.File
+	file_gateway_v1_gateway_proto_goTypes = nil
+	file_gateway_v1_gateway_proto_depIdxs = nil
+}
@@ -0,0 +1,196 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// Store persists archived payloads.
+type Store interface {
+	Put(ctx context.Context, key string, body []byte) error
+}
+
+// Record is a completed request/response pair eligible for archival.
+type Record struct {
+	RequestID      string
+	OrganizationID string
+	TeamID         string
+	APIKeyID       string
+	Classification string
+	ModelRequested string
+	RequestHash    string
+	Timestamp      time.Time
+	Request        *types.AegisRequest
+	Response       *types.AegisResponse
+}
+
+// document is the JSON object written to the store.
+type document struct {
+	RequestID      string               `json:"request_id"`
+	Timestamp      time.Time            `json:"timestamp"`
+	OrganizationID string               `json:"organization_id"`
+	TeamID         string               `json:"team_id"`
+	APIKeyID       string               `json:"api_key_id"`
+	Classification string               `json:"classification"`
+	ModelRequested string               `json:"model_requested"`
+	RequestHash    string               `json:"request_hash,omitempty"`
+	Request        *types.AegisRequest  `json:"request"`
+	Response       *types.AegisResponse `json:"response,omitempty"`
+}
+
+// Archiver writes redacted request/response payloads to object storage for
+// incident forensics and model evaluation. Archival is asynchronous and never
+// affects the client response.
+type Archiver struct {
+	store    Store
+	redactor *Redactor
+	cfg      func() config.ArchiveConfig
+}
+
+// NewArchiver creates an archiver backed by the given store.
+func NewArchiver(store Store, cfg func() config.ArchiveConfig) *Archiver {
+	return &Archiver{
+		store:    store,
+		redactor: NewRedactor(),
+		cfg:      cfg,
+	}
+}
+
+// ShouldArchive reports whether payloads for the given org and classification
+// are eligible for archival under the current configuration.
+func (a *Archiver) ShouldArchive(orgID, classification string) bool {
+	cfg := a.cfg()
+	if !cfg.Enabled {
+		return false
+	}
+	if len(cfg.Organizations) > 0 && !contains(cfg.Organizations, orgID) {
+		return false
+	}
+	if len(cfg.Classifications) > 0 && !contains(cfg.Classifications, classification) {
+		return false
+	}
+	return true
+}
+
+// Archive redacts the record if it is eligible and writes it asynchronously.
+// The record is redacted and encoded before Archive returns, so the caller
+// may change its request and response afterwards; only the upload runs in
+// the background.
+func (a *Archiver) Archive(rec Record) {
+	if !a.ShouldArchive(rec.OrganizationID, rec.Classification) {
+		return
+	}
+	key, body, err := a.encode(rec)
+	if err != nil {
+		slog.Error("failed to archive payload", "error", err, "request_id", rec.RequestID, "org_id", rec.OrganizationID)
+		return
+	}
+	go func() {
+		timeout := a.cfg().Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if err := a.store.Put(ctx, key, body); err != nil {
+			slog.Error("failed to archive payload",
+				"error", err,
+				"request_id", rec.RequestID,
+				"org_id", rec.OrganizationID,
+			)
+		}
+	}()
+}
+
+// encode returns the object key and the redacted JSON document for rec.
+func (a *Archiver) encode(rec Record) (string, []byte, error) {
+	body, err := json.Marshal(a.redact(rec))
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal archive document: %w", err)
+	}
+	return a.objectKey(rec), body, nil
+}
+
+// redact builds the archive document with all message content, reasoning,
+// tool call arguments, and tool results redacted. The caller's request and response
+// are never mutated.
+func (a *Archiver) redact(rec Record) document {
+	doc := document{
+		RequestID:      rec.RequestID,
+		Timestamp:      rec.Timestamp.UTC(),
+		OrganizationID: rec.OrganizationID,
+		TeamID:         rec.TeamID,
+		APIKeyID:       rec.APIKeyID,
+		Classification: rec.Classification,
+		ModelRequested: rec.ModelRequested,
+		RequestHash:    rec.RequestHash,
+	}
+
+	if rec.Request != nil {
+		req := *rec.Request
+		req.Messages = make([]types.Message, len(rec.Request.Messages))
+		for i, m := range rec.Request.Messages {
+			req.Messages[i] = a.redactMessage(m)
+		}
+		doc.Request = &req
+	}
+
+	if rec.Response != nil {
+		resp := *rec.Response
+		resp.Choices = make([]types.Choice, len(rec.Response.Choices))
+		for i, c := range rec.Response.Choices {
+			c.Message = a.redactMessage(c.Message)
+			resp.Choices[i] = c
+		}
+		doc.Response = &resp
+	}
+
+	return doc
+}
+
+// redactMessage returns a copy of m with its content, reasoning, and tool
+// call arguments redacted. Tool results and arguments are usually JSON and are
+// redacted value by value.
+func (a *Archiver) redactMessage(m types.Message) types.Message {
+	if m.Role == "tool" || m.Role == "function" {
+		m.Content = a.redactor.RedactJSON(m.Content)
+	} else {
+		m.Content = a.redactor.Redact(m.Content)
+	}
+	m.ReasoningContent = a.redactor.Redact(m.ReasoningContent)
+	if m.ToolCalls != nil {
+		calls := make([]types.ToolCall, len(m.ToolCalls))
+		for i, tc := range m.ToolCalls {
+			tc.Function.Arguments = a.redactor.RedactJSON(tc.Function.Arguments)
+			calls[i] = tc
+		}
+		m.ToolCalls = calls
+	}
+	return m
+}
+
+// objectKey returns {prefix}/{org}/{yyyy}/{mm}/{dd}/{request_id}.json.
+func (a *Archiver) objectKey(rec Record) string {
+	ts := rec.Timestamp.UTC()
+	key := fmt.Sprintf("%s/%04d/%02d/%02d/%s.json", rec.OrganizationID, ts.Year(), ts.Month(), ts.Day(), rec.RequestID)
+	if prefix := a.cfg().Prefix; prefix != "" {
+		key = prefix + "/" + key
+	}
+	return key
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/filter/secrets"
+)
+
+// piiPatterns are lightweight PII detectors applied before payloads leave the
+// gateway. They are intentionally conservative; the NLP PII service remains the
+// authoritative detector for inbound filtering.
+var piiPatterns = []struct {
+	label string
+	regex *regexp.Regexp
+}{
+	{"EMAIL_ADDRESS", regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	{"US_SSN", regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)},
+	{"CREDIT_CARD", regexp.MustCompile(`\b(?:\d[ \-]?){13,16}\b`)},
+	{"PHONE_NUMBER", regexp.MustCompile(`\+?\(?\d{3}\)?[ .\-]\d{3}[ .\-]\d{4}\b`)},
+}
+
+// Redactor replaces detected secrets and PII with typed placeholders.
+type Redactor struct {
+	scanner *secrets.Scanner
+}
+
+// NewRedactor creates a redactor using the built-in secret patterns.
+func NewRedactor() *Redactor {
+	return &Redactor{scanner: secrets.NewScanner()}
+}
+
+type span struct {
+	start int
+	end   int
+	label string
+}
+
+// Redact returns text with every secret and PII match replaced by
+// "[REDACTED:<type>]". Overlapping matches are merged into a single placeholder.
+func (r *Redactor) Redact(text string) string {
+	var spans []span
+	for _, d := range r.scanner.Scan(text) {
+		spans = append(spans, span{start: d.Start, end: d.End, label: "SECRET"})
+	}
+	for _, p := range piiPatterns {
+		for _, loc := range p.regex.FindAllStringIndex(text, -1) {
+			spans = append(spans, span{start: loc[0], end: loc[1], label: p.label})
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	merged := []span{spans[0]}
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start < last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	out := make([]byte, 0, len(text))
+	prev := 0
+	for _, s := range merged {
+		out = append(out, text[prev:s.start]...)
+		out = append(out, "[REDACTED:"+s.label+"]"...)
+		prev = s.end
+	}
+	out = append(out, text[prev:]...)
+	return string(out)
+}
+
+// RedactJSON redacts the string and number values of the JSON document text,
+// such as tool call arguments or a tool result, after decoding them, so
+// escape sequences cannot hide a secret from the patterns. A redacted number
+// becomes its placeholder string. Text that is not JSON is redacted as is.
+func (r *Redactor) RedactJSON(text string) string {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return r.Redact(text)
+	}
+	var walk func(any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, e := range v {
+				v[k] = walk(e)
+			}
+		case []any:
+			for i, e := range v {
+				v[i] = walk(e)
+			}
+		case string:
+			return r.Redact(v)
+		case json.Number:
+			if redacted := r.Redact(v.String()); redacted != v.String() {
+				return redacted
+			}
+		}
+		return v
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(walk(v)); err != nil {
+		return r.Redact(text)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/awssig"
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// S3Store writes objects to an S3-compatible bucket using path-style URLs and
+// AWS Signature Version 4. It works against AWS S3, MinIO, and Ceph RGW.
+type S3Store struct {
+	cfg    func() config.ArchiveConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store creates an S3 store. Configuration is read on every Put so that
+// credential and endpoint changes apply on hot-reload.
+func NewS3Store(cfg func() config.ArchiveConfig, client *http.Client) *S3Store {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &S3Store{cfg: cfg, client: client, now: time.Now}
+}
+
+// Put uploads body under key.
+func (s *S3Store) Put(ctx context.Context, key string, body []byte) error {
+	cfg := s.cfg()
+	if cfg.Bucket == "" {
+		return fmt.Errorf("archive bucket not configured")
+	}
+
+	objectPath := "/" + awssig.URIEncode(cfg.Bucket) + "/" + encodeKey(key)
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+objectPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create s3 request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Amz-Content-Sha256", awssig.SHA256Hex(body))
+	creds := awssig.Credentials{AccessKeyID: cfg.AccessKeyID, SecretAccessKey: cfg.SecretAccessKey}
+	awssig.Sign(req, creds, cfg.Region, "s3", objectPath, body, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3 put: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 put returned status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+// encodeKey URI-encodes each segment of an object key, preserving slashes.
+func encodeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = awssig.URIEncode(seg)
+	}
+	return strings.Join(segments, "/")
+}
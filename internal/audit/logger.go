@@ -14,14 +14,18 @@ import (
 type EventType string
 
 const (
-	EventAuthFailure         EventType = "auth_failure"
-	EventAuthSuccess         EventType = "auth_success"
-	EventRateLimitViolation  EventType = "rate_limit_violation"
-	EventBudgetViolation     EventType = "budget_violation"
-	EventFilterBlock         EventType = "filter_block"
-	EventRedisFailure        EventType = "redis_failure"
-	EventProviderFailure     EventType = "provider_failure"
-	EventRequestComplete     EventType = "request_complete"
+	EventAuthFailure             EventType = "auth_failure"
+	EventAuthSuccess             EventType = "auth_success"
+	EventRateLimitViolation      EventType = "rate_limit_violation"
+	EventBudgetViolation         EventType = "budget_violation"
+	EventFilterBlock             EventType = "filter_block"
+	EventRedisFailure            EventType = "redis_failure"
+	EventProviderFailure         EventType = "provider_failure"
+	EventRequestComplete         EventType = "request_complete"
+	EventPolicyDenial            EventType = "policy_denial"
+	EventClassificationViolation EventType = "classification_violation"
+	EventConfigChange            EventType = "config_change"
+	EventFilterBypass            EventType = "filter_bypass"
 )
 
 // Event represents a security-relevant audit event.
@@ -42,9 +46,16 @@ type Event struct {
 	Metadata        map[string]interface{}
 }
 
+// SecuritySink receives a copy of every audit event for a dedicated security
+// event stream (e.g. SIEM). Implementations must be safe for concurrent use.
+type SecuritySink interface {
+	LogSecurityEvent(event Event)
+}
+
 // Logger writes audit events to the database.
 type Logger struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	sink SecuritySink
 }
 
 // NewLogger creates a new audit logger.
@@ -52,8 +63,17 @@ func NewLogger(db *pgxpool.Pool) *Logger {
 	return &Logger{db: db}
 }
 
+// SetSecuritySink forwards audit events to a security event stream in
+// addition to the database.
+func (l *Logger) SetSecuritySink(sink SecuritySink) {
+	l.sink = sink
+}
+
 // Log records an audit event asynchronously.
 func (l *Logger) Log(event Event) {
+	if l.sink != nil {
+		go l.sink.LogSecurityEvent(event)
+	}
 	go l.writeEvent(event)
 }
 
@@ -167,8 +187,9 @@ func (l *Logger) LogBudgetViolation(requestID, orgID, teamID, keyID string, spen
 	})
 }
 
-// LogFilterBlock logs a content filter block.
-func (l *Logger) LogFilterBlock(requestID, orgID, teamID, keyID, filterType, reason string, ip string) {
+// LogFilterBlock logs a content filter block with what the filter detected,
+// which false-positive feedback on the block is reviewed against.
+func (l *Logger) LogFilterBlock(requestID, orgID, teamID, keyID, filterType, reason string, detections int, score float64, ip string) {
 	l.Log(Event{
 		RequestID:      requestID,
 		Timestamp:      time.Now(),
@@ -182,6 +203,87 @@ func (l *Logger) LogFilterBlock(requestID, orgID, teamID, keyID, filterType, rea
 		Metadata: map[string]interface{}{
 			"filter_type": filterType,
 			"reason":      reason,
+			"detections":  detections,
+			"score":       score,
+		},
+	})
+}
+
+// LogFilterBypass logs a request that skipped a filter under a break-glass
+// bypass grant.
+func (l *Logger) LogFilterBypass(requestID, orgID, teamID, keyID, filterType, grantID string, ip string) {
+	l.Log(Event{
+		RequestID:      requestID,
+		Timestamp:      time.Now(),
+		EventType:      EventFilterBypass,
+		OrganizationID: orgID,
+		TeamID:         teamID,
+		APIKeyID:       &keyID,
+		IPAddress:      ip,
+		StatusCode:     200,
+		ErrorMessage:   fmt.Sprintf("%s filter bypassed by grant %s", filterType, grantID),
+		Metadata: map[string]interface{}{
+			"filter_type": filterType,
+			"grant_id":    grantID,
+		},
+	})
+}
+
+// LogPolicyDenial logs a request denied by OPA policy evaluation.
+func (l *Logger) LogPolicyDenial(requestID, orgID, teamID, keyID, reason string, ip string) {
+	l.Log(Event{
+		RequestID:      requestID,
+		Timestamp:      time.Now(),
+		EventType:      EventPolicyDenial,
+		OrganizationID: orgID,
+		TeamID:         teamID,
+		APIKeyID:       &keyID,
+		IPAddress:      ip,
+		StatusCode:     403,
+		ErrorMessage:   "Request denied by policy",
+		Metadata: map[string]interface{}{
+			"reason": reason,
+		},
+	})
+}
+
+// LogClassificationViolation logs a request whose data classification exceeds
+// every provider route configured for the requested model.
+func (l *Logger) LogClassificationViolation(requestID, orgID, teamID, keyID, model, classification string, ip string) {
+	l.Log(Event{
+		RequestID:      requestID,
+		Timestamp:      time.Now(),
+		EventType:      EventClassificationViolation,
+		OrganizationID: orgID,
+		TeamID:         teamID,
+		APIKeyID:       &keyID,
+		IPAddress:      ip,
+		StatusCode:     503,
+		ErrorMessage:   fmt.Sprintf("Classification %s not permitted for model %s", classification, model),
+		Metadata: map[string]interface{}{
+			"model":          model,
+			"classification": classification,
+		},
+	})
+}
+
+// LogConfigChange logs a runtime configuration change made through the admin
+// API. action names the operation (e.g. "update", "reload", "clear_overrides").
+func (l *Logger) LogConfigChange(requestID, orgID, teamID, keyID, action string, changes map[string]interface{}, ip string) {
+	l.Log(Event{
+		RequestID:      requestID,
+		Timestamp:      time.Now(),
+		EventType:      EventConfigChange,
+		OrganizationID: orgID,
+		TeamID:         teamID,
+		APIKeyID:       &keyID,
+		IPAddress:      ip,
+		Endpoint:       "/aegis/admin/v1/config",
+		StatusCode:     200,
+		ErrorMessage:   fmt.Sprintf("Configuration %s", action),
+		Metadata: map[string]interface{}{
+			"action":  action,
+			"changes": changes,
 		},
 	})
 }
@@ -75,6 +75,9 @@ func TestAuditEventTypes(t *testing.T) {
 		EventRedisFailure,
 		EventProviderFailure,
 		EventRequestComplete,
+		EventPolicyDenial,
+		EventClassificationViolation,
+		EventConfigChange,
 	}
 
 	for _, et := range eventTypes {
@@ -83,3 +86,32 @@ func TestAuditEventTypes(t *testing.T) {
 		}
 	}
 }
+
+// recordingSink captures events forwarded to the security sink.
+type recordingSink struct {
+	events chan Event
+}
+
+func (s *recordingSink) LogSecurityEvent(event Event) {
+	s.events <- event
+}
+
+func TestLogger_ForwardsToSecuritySink(t *testing.T) {
+	sink := &recordingSink{events: make(chan Event, 1)}
+	l := NewLogger(nil)
+	l.SetSecuritySink(sink)
+
+	l.LogClassificationViolation("req_1", "org-1", "team-1", "key-1", "aegis-fast", "RESTRICTED", "10.0.0.1")
+
+	select {
+	case ev := <-sink.events:
+		if ev.EventType != EventClassificationViolation {
+			t.Errorf("expected classification_violation, got %s", ev.EventType)
+		}
+		if ev.Metadata["classification"] != "RESTRICTED" {
+			t.Errorf("expected classification metadata, got %v", ev.Metadata)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("security sink did not receive event")
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+)
+
+// RequireAdmin returns middleware that only admits API keys listed in keyIDs.
+// It must run after Middleware so auth info is in the context. The list is
+// read per request so hot-reloaded config takes effect immediately.
+func RequireAdmin(keyIDs func() []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqID := w.Header().Get("X-Request-ID")
+
+			authInfo, ok := AuthFromContext(r.Context())
+			if !ok {
+				httputil.WriteAuthError(w, reqID, "Missing authentication")
+				return
+			}
+			if !slices.Contains(keyIDs(), authInfo.KeyID) {
+				slog.Warn("admin endpoint access denied",
+					"request_id", reqID,
+					"key_id", authInfo.KeyID,
+					"org_id", authInfo.OrganizationID,
+					"path", r.URL.Path,
+				)
+				httputil.WriteForbiddenError(w, reqID, "This API key is not authorized for admin endpoints")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	mw := RequireAdmin(func() []string { return []string{"ops-key"} })
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		authInfo *AuthInfo
+		want     int
+	}{
+		{"no auth", nil, http.StatusUnauthorized},
+		{"non-admin key", &AuthInfo{KeyID: "app-key"}, http.StatusForbidden},
+		{"admin key", &AuthInfo{KeyID: "ops-key"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/aegis/v1/status", nil)
+			if tt.authInfo != nil {
+				req = req.WithContext(ContextWithAuth(req.Context(), tt.authInfo))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
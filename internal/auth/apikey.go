@@ -77,7 +77,33 @@ type KeyMetadata struct {
 	RPMLimit             *int                `json:"rpm_limit,omitempty"`
 	TPMLimit             *int                `json:"tpm_limit,omitempty"`
 	DailySpendLimitCents *int                `json:"daily_spend_limit_cents,omitempty"`
+	Priority             types.Priority      `json:"priority,omitempty"`
 	ExpiresAt            time.Time           `json:"expires_at"`
+	// ModelRules are the key's own rules merged over its team's.
+	ModelRules           ModelRules          `json:"model_rules"`
+	// OrgSuspended and TeamSuspended are set while the key's organization
+	// or team is suspended; the key is rejected until the suspension is
+	// lifted.
+	OrgSuspended         bool                `json:"org_suspended,omitempty"`
+	TeamSuspended        bool                `json:"team_suspended,omitempty"`
+}
+
+// AuthInfo returns the identity and limits a request made with the key
+// runs under.
+func (km *KeyMetadata) AuthInfo() *AuthInfo {
+	return &AuthInfo{
+		KeyID:                km.ID,
+		OrganizationID:       km.OrganizationID,
+		TeamID:               km.TeamID,
+		UserID:               km.UserID,
+		MaxClassification:    km.MaxClassification,
+		AllowedModels:        km.AllowedModels,
+		RPMLimit:             km.RPMLimit,
+		TPMLimit:             km.TPMLimit,
+		DailySpendLimitCents: km.DailySpendLimitCents,
+		Priority:             km.Priority,
+		ModelRules:           km.ModelRules,
+	}
 }
 
 func (km *KeyMetadata) MarshalJSON() ([]byte, error) {
@@ -21,6 +21,14 @@ type AuthInfo struct {
 	RPMLimit             *int
 	TPMLimit             *int
 	DailySpendLimitCents *int
+	// Priority is the key's scheduling class; empty means interactive.
+	Priority types.Priority
+	// ModelRules pick the model served when a request names none or names
+	// one the key's or team's rules rewrite.
+	ModelRules ModelRules
+	// KeyHash looks the key up again in the KeyStore, for work that runs
+	// after the request that authorized it, such as batch items.
+	KeyHash string
 }
 
 func ContextWithAuth(ctx context.Context, info *AuthInfo) context.Context {
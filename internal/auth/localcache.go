@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyInvalidationChannel carries the hashes of keys evicted from the Redis
+// cache, comma-separated, so every gateway drops them from its local cache.
+const keyInvalidationChannel = "aegis:key:invalidate"
+
+// localKeyCache is a size-bounded LRU of key metadata with a TTL, held in
+// front of Redis for the hottest keys.
+type localKeyCache struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+type localKeyEntry struct {
+	keyHash string
+	meta    KeyMetadata
+	expires time.Time
+}
+
+func newLocalKeyCache(size int, ttl time.Duration) *localKeyCache {
+	return &localKeyCache{size: size, ttl: ttl, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *localKeyCache) get(keyHash string, now time.Time) (*KeyMetadata, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[keyHash]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*localKeyEntry)
+	if !now.Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, keyHash)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	meta := e.meta
+	return &meta, true
+}
+
+func (c *localKeyCache) put(keyHash string, meta KeyMetadata, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[keyHash]; ok {
+		el.Value = &localKeyEntry{keyHash: keyHash, meta: meta, expires: now.Add(c.ttl)}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[keyHash] = c.order.PushFront(&localKeyEntry{keyHash: keyHash, meta: meta, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*localKeyEntry).keyHash)
+	}
+}
+
+func (c *localKeyCache) remove(keyHashes ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, h := range keyHashes {
+		if el, ok := c.entries[h]; ok {
+			c.order.Remove(el)
+			delete(c.entries, h)
+		}
+	}
+}
+
+// SetLocalCache keeps up to size recently used keys in process for ttl, so
+// the hottest keys skip Redis. Evictions made through KeyManager reach every
+// gateway's local cache over Redis pub/sub once ListenForInvalidations runs;
+// ttl bounds how stale a key can be if a message is missed. Call before
+// serving traffic; a size or ttl of zero leaves the local cache off.
+func (s *CachedKeyStore) SetLocalCache(size int, ttl time.Duration) {
+	if size <= 0 || ttl <= 0 {
+		s.local = nil
+		return
+	}
+	s.local = newLocalKeyCache(size, ttl)
+}
+
+// ListenForInvalidations drops keys from the local cache as KeyManager
+// evicts them, until ctx is cancelled. It returns at once when there is no
+// local cache or no Redis.
+func (s *CachedKeyStore) ListenForInvalidations(ctx context.Context) {
+	if s.local == nil || s.redis == nil {
+		return
+	}
+	sub := s.redis.Subscribe(ctx, keyInvalidationChannel)
+	defer func() { _ = sub.Close() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			s.local.remove(strings.Split(msg.Payload, ",")...)
+		}
+	}
+}
+
+// publishInvalidation tells every gateway to drop keyHashes from its local
+// cache. Failures are logged; the local TTL still bounds staleness.
+func publishInvalidation(ctx context.Context, rdb *redis.Client, keyHashes []string) {
+	if err := rdb.Publish(ctx, keyInvalidationChannel, strings.Join(keyHashes, ",")).Err(); err != nil {
+		slog.Warn("failed to publish key cache invalidation", "keys", len(keyHashes), "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalKeyCache(t *testing.T) {
+	now := time.Now()
+	c := newLocalKeyCache(2, time.Minute)
+	c.put("a", KeyMetadata{ID: "key-a"}, now)
+	c.put("b", KeyMetadata{ID: "key-b"}, now)
+
+	if meta, ok := c.get("a", now); !ok || meta.ID != "key-a" {
+		t.Fatalf("expected key-a, got %v %v", meta, ok)
+	}
+	// "b" is now least recently used and goes first.
+	c.put("c", KeyMetadata{ID: "key-c"}, now)
+	if _, ok := c.get("b", now); ok {
+		t.Error("expected b to be evicted")
+	}
+	if _, ok := c.get("a", now); !ok {
+		t.Error("expected a to survive eviction")
+	}
+
+	if _, ok := c.get("c", now.Add(time.Minute)); ok {
+		t.Error("expected c to expire after the TTL")
+	}
+
+	c.remove("a")
+	if _, ok := c.get("a", now); ok {
+		t.Error("expected a to be invalidated")
+	}
+}
+
+func TestCachedKeyStore_LocalCacheHit(t *testing.T) {
+	s := NewCachedKeyStore(nil, nil)
+	s.SetLocalCache(10, time.Minute)
+	s.cacheLocally("hash-1", &KeyMetadata{ID: "key-1", OrganizationID: "org-1"})
+
+	// With no Redis or database, only the local cache can answer.
+	meta, err := s.Lookup(t.Context(), "hash-1")
+	if err != nil || meta == nil || meta.ID != "key-1" {
+		t.Fatalf("expected local hit, got %v, %v", meta, err)
+	}
+}
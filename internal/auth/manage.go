@@ -0,0 +1,427 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrKeyNotFound is returned when an admin operation targets an unknown key.
+var ErrKeyNotFound = errors.New("api key not found")
+
+// ErrNotSuspended is returned when resuming an organization or team that is
+// not suspended.
+var ErrNotSuspended = errors.New("not suspended")
+
+// KeyInfo is the admin view of an API key. It never includes the raw key or
+// its hash.
+type KeyInfo struct {
+	ID                   string     `json:"id"`
+	KeyPrefix            string     `json:"key_prefix"`
+	OrganizationID       string     `json:"organization_id"`
+	TeamID               string     `json:"team_id"`
+	UserID               string     `json:"user_id,omitempty"`
+	Name                 string     `json:"name"`
+	Status               string     `json:"status"`
+	MaxClassification    string     `json:"max_classification"`
+	AllowedModels        []string   `json:"allowed_models"`
+	RPMLimit             *int       `json:"rpm_limit,omitempty"`
+	TPMLimit             *int       `json:"tpm_limit,omitempty"`
+	DailySpendLimitCents *int       `json:"daily_spend_limit_cents,omitempty"`
+	Priority             string     `json:"priority"`
+	CreatedAt            time.Time  `json:"created_at"`
+	ExpiresAt            time.Time  `json:"expires_at"`
+	LastUsedAt           *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt            *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason        string     `json:"revoked_reason,omitempty"`
+	// ModelRules are the key's own; its team's are read separately.
+	ModelRules
+}
+
+// KeyFilter narrows ListKeys. Empty fields match everything.
+type KeyFilter struct {
+	OrganizationID string
+	TeamID         string
+	Status         string
+	Limit          int
+}
+
+// NewKey describes a key to create.
+type NewKey struct {
+	OrganizationID       string        `json:"organization_id"`
+	TeamID               string        `json:"team_id"`
+	UserID               string        `json:"user_id,omitempty"`
+	Name                 string        `json:"name"`
+	Env                  string        `json:"env,omitempty"`
+	MaxClassification    string        `json:"max_classification,omitempty"`
+	AllowedModels        []string      `json:"allowed_models,omitempty"`
+	ExpiresIn            time.Duration `json:"-"`
+	RPMLimit             *int          `json:"rpm_limit,omitempty"`
+	TPMLimit             *int          `json:"tpm_limit,omitempty"`
+	DailySpendLimitCents *int          `json:"daily_spend_limit_cents,omitempty"`
+	Priority             string        `json:"priority,omitempty"`
+	ModelRules
+}
+
+// KeyLimits updates a key's per-key limits. A nil field is left unchanged;
+// zero clears the override so the configured default applies. An empty
+// Priority is left unchanged.
+type KeyLimits struct {
+	RPMLimit             *int   `json:"rpm_limit,omitempty"`
+	TPMLimit             *int   `json:"tpm_limit,omitempty"`
+	DailySpendLimitCents *int   `json:"daily_spend_limit_cents,omitempty"`
+	Priority             string `json:"priority,omitempty"`
+}
+
+// OrgSummary aggregates keys per organization. Organizations exist only as
+// identifiers on keys, so this is the organization inventory.
+type OrgSummary struct {
+	OrganizationID string `json:"organization_id"`
+	Teams          int    `json:"teams"`
+	ActiveKeys     int    `json:"active_keys"`
+	TotalKeys      int    `json:"total_keys"`
+	Suspended      bool   `json:"suspended,omitempty"`
+	SuspendedTeams int    `json:"suspended_teams,omitempty"`
+}
+
+// Suspension records why and by whom an organization, or one of its teams
+// when TeamID is set, was suspended.
+type Suspension struct {
+	OrganizationID string    `json:"organization_id"`
+	TeamID         string    `json:"team_id,omitempty"`
+	Reason         string    `json:"reason,omitempty"`
+	SuspendedBy    string    `json:"suspended_by,omitempty"`
+	SuspendedAt    time.Time `json:"suspended_at"`
+}
+
+// KeyManager performs admin operations on API keys. Changes that affect
+// authentication evict the key from the Redis auth cache so they apply
+// immediately instead of after the cache TTL.
+type KeyManager struct {
+	db    *pgxpool.Pool
+	redis *redis.Client
+}
+
+// NewKeyManager creates a key manager. rdb may be nil.
+func NewKeyManager(db *pgxpool.Pool, rdb *redis.Client) *KeyManager {
+	return &KeyManager{db: db, redis: rdb}
+}
+
+const keyInfoColumns = `id, key_prefix, organization_id, team_id, COALESCE(user_id, ''), name, status,
+	max_classification, allowed_models, rpm_limit, tpm_limit, daily_spend_limit_cents, priority,
+	COALESCE(default_model, ''), model_rewrites,
+	created_at, expires_at, last_used_at, revoked_at, COALESCE(revoked_reason, '')`
+
+func scanKeyInfo(row pgx.Row) (*KeyInfo, error) {
+	var k KeyInfo
+	var allowedModels, modelRewrites []byte
+	err := row.Scan(&k.ID, &k.KeyPrefix, &k.OrganizationID, &k.TeamID, &k.UserID, &k.Name, &k.Status,
+		&k.MaxClassification, &allowedModels, &k.RPMLimit, &k.TPMLimit, &k.DailySpendLimitCents, &k.Priority,
+		&k.DefaultModel, &modelRewrites,
+		&k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.RevokedReason)
+	if err != nil {
+		return nil, err
+	}
+	if len(allowedModels) > 0 {
+		_ = json.Unmarshal(allowedModels, &k.AllowedModels)
+	}
+	if len(modelRewrites) > 0 {
+		_ = json.Unmarshal(modelRewrites, &k.ModelRewrites)
+	}
+	return &k, nil
+}
+
+// ListKeys returns keys matching f, newest first.
+func (m *KeyManager) ListKeys(ctx context.Context, f KeyFilter) ([]KeyInfo, error) {
+	var where []string
+	var args []any
+	add := func(clause, value string) {
+		if value == "" {
+			return
+		}
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	add("organization_id = $%d", f.OrganizationID)
+	add("team_id = $%d", f.TeamID)
+	add("status::text = $%d", f.Status)
+
+	query := "SELECT " + keyInfoColumns + " FROM api_keys"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d", len(args))
+
+	rows, err := m.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list api_keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []KeyInfo{}
+	for rows.Next() {
+		k, err := scanKeyInfo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api_keys: %w", err)
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// GetKey returns a single key by ID.
+func (m *KeyManager) GetKey(ctx context.Context, id string) (*KeyInfo, error) {
+	k, err := scanKeyInfo(m.db.QueryRow(ctx, "SELECT "+keyInfoColumns+" FROM api_keys WHERE id::text = $1", id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+	return k, nil
+}
+
+// CreateKey generates and stores a new key. The raw key is returned once and
+// cannot be recovered later.
+func (m *KeyManager) CreateKey(ctx context.Context, nk NewKey) (string, *KeyInfo, error) {
+	env := nk.Env
+	if env == "" {
+		env = "prod"
+	}
+	rawKey, err := GenerateKey(env)
+	if err != nil {
+		return "", nil, err
+	}
+	classification := nk.MaxClassification
+	if classification == "" {
+		classification = "INTERNAL"
+	}
+	allowedModels, _ := json.Marshal(nk.AllowedModels)
+	if nk.AllowedModels == nil {
+		allowedModels = []byte("[]")
+	}
+	var userID *string
+	if nk.UserID != "" {
+		userID = &nk.UserID
+	}
+	priority := nk.Priority
+	if priority == "" {
+		priority = "interactive"
+	}
+
+	k, err := scanKeyInfo(m.db.QueryRow(ctx, `
+		INSERT INTO api_keys (key_hash, key_prefix, organization_id, team_id, user_id, name,
+			max_classification, allowed_models, rpm_limit, tpm_limit, daily_spend_limit_cents, priority,
+			default_model, model_rewrites, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), $14, $15)
+		RETURNING `+keyInfoColumns,
+		HashKey(rawKey), KeyPrefix(rawKey), nk.OrganizationID, nk.TeamID, userID, nk.Name,
+		classification, allowedModels, nk.RPMLimit, nk.TPMLimit, nk.DailySpendLimitCents, priority,
+		nk.DefaultModel, nk.rewritesJSON(), time.Now().Add(nk.ExpiresIn),
+	))
+	if err != nil {
+		return "", nil, fmt.Errorf("insert api key: %w", err)
+	}
+	return rawKey, k, nil
+}
+
+// RevokeKey revokes an active key and evicts it from the auth cache.
+func (m *KeyManager) RevokeKey(ctx context.Context, id, reason string) (*KeyInfo, error) {
+	var keyHash string
+	err := m.db.QueryRow(ctx, `
+		UPDATE api_keys SET status = 'revoked', revoked_at = NOW(), revoked_reason = NULLIF($2, '')
+		WHERE id::text = $1 AND status <> 'revoked'
+		RETURNING key_hash`, id, reason).Scan(&keyHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Unknown, or already revoked: report the current state if it exists.
+		return m.GetKey(ctx, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("revoke api key: %w", err)
+	}
+	m.evict(ctx, keyHash)
+	return m.GetKey(ctx, id)
+}
+
+// SetLimits updates a key's rate and spend limits and priority and evicts it
+// from the auth cache.
+func (m *KeyManager) SetLimits(ctx context.Context, id string, l KeyLimits) (*KeyInfo, error) {
+	var keyHash string
+	err := m.db.QueryRow(ctx, `
+		UPDATE api_keys SET
+			rpm_limit = CASE WHEN $2::int IS NULL THEN rpm_limit ELSE NULLIF($2::int, 0) END,
+			tpm_limit = CASE WHEN $3::int IS NULL THEN tpm_limit ELSE NULLIF($3::int, 0) END,
+			daily_spend_limit_cents = CASE WHEN $4::int IS NULL THEN daily_spend_limit_cents ELSE NULLIF($4::int, 0) END,
+			priority = COALESCE(NULLIF($5, '')::request_priority, priority)
+		WHERE id::text = $1
+		RETURNING key_hash`, id, l.RPMLimit, l.TPMLimit, l.DailySpendLimitCents, l.Priority).Scan(&keyHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update api key limits: %w", err)
+	}
+	m.evict(ctx, keyHash)
+	return m.GetKey(ctx, id)
+}
+
+// ListOrgs summarises keys per organization.
+func (m *KeyManager) ListOrgs(ctx context.Context) ([]OrgSummary, error) {
+	rows, err := m.db.Query(ctx, `
+		SELECT k.organization_id,
+		       COUNT(DISTINCT k.team_id),
+		       COUNT(*) FILTER (WHERE k.status = 'active' AND k.expires_at > NOW()),
+		       COUNT(*),
+		       BOOL_OR(s.organization_id IS NOT NULL),
+		       (SELECT COUNT(*) FROM team_suspensions ts WHERE ts.organization_id = k.organization_id)
+		FROM api_keys k
+		LEFT JOIN organization_suspensions s ON s.organization_id = k.organization_id
+		GROUP BY k.organization_id
+		ORDER BY k.organization_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []OrgSummary{}
+	for rows.Next() {
+		var o OrgSummary
+		if err := rows.Scan(&o.OrganizationID, &o.Teams, &o.ActiveKeys, &o.TotalKeys, &o.Suspended, &o.SuspendedTeams); err != nil {
+			return nil, fmt.Errorf("scan organizations: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// SuspendOrg suspends an organization so every one of its keys is rejected,
+// and evicts those keys from the auth cache so it applies at once.
+// Suspending an already suspended organization updates the reason.
+func (m *KeyManager) SuspendOrg(ctx context.Context, orgID, reason, suspendedBy string) (*Suspension, error) {
+	row := m.db.QueryRow(ctx, `
+		INSERT INTO organization_suspensions (organization_id, reason, suspended_by)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''))
+		ON CONFLICT (organization_id) DO UPDATE SET reason = EXCLUDED.reason, suspended_by = EXCLUDED.suspended_by
+		RETURNING organization_id, '', reason, suspended_by, suspended_at`, orgID, reason, suspendedBy)
+	s, err := scanSuspension(row)
+	if err != nil {
+		return nil, fmt.Errorf("suspend organization: %w", err)
+	}
+	if err := m.evictKeys(ctx, orgID, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ResumeOrg lifts an organization's suspension and evicts its keys from the
+// auth cache. Suspended teams stay suspended.
+func (m *KeyManager) ResumeOrg(ctx context.Context, orgID string) error {
+	tag, err := m.db.Exec(ctx, `DELETE FROM organization_suspensions WHERE organization_id = $1`, orgID)
+	if err != nil {
+		return fmt.Errorf("resume organization: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotSuspended
+	}
+	return m.evictKeys(ctx, orgID, "")
+}
+
+// SuspendTeam suspends one team of an organization, like SuspendOrg.
+func (m *KeyManager) SuspendTeam(ctx context.Context, orgID, teamID, reason, suspendedBy string) (*Suspension, error) {
+	row := m.db.QueryRow(ctx, `
+		INSERT INTO team_suspensions (organization_id, team_id, reason, suspended_by)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (organization_id, team_id) DO UPDATE SET reason = EXCLUDED.reason, suspended_by = EXCLUDED.suspended_by
+		RETURNING organization_id, team_id, reason, suspended_by, suspended_at`, orgID, teamID, reason, suspendedBy)
+	s, err := scanSuspension(row)
+	if err != nil {
+		return nil, fmt.Errorf("suspend team: %w", err)
+	}
+	if err := m.evictKeys(ctx, orgID, teamID); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ResumeTeam lifts a team's suspension and evicts its keys from the auth
+// cache.
+func (m *KeyManager) ResumeTeam(ctx context.Context, orgID, teamID string) error {
+	tag, err := m.db.Exec(ctx, `DELETE FROM team_suspensions WHERE organization_id = $1 AND team_id = $2`, orgID, teamID)
+	if err != nil {
+		return fmt.Errorf("resume team: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotSuspended
+	}
+	return m.evictKeys(ctx, orgID, teamID)
+}
+
+func scanSuspension(row pgx.Row) (*Suspension, error) {
+	var s Suspension
+	var reason, suspendedBy *string
+	if err := row.Scan(&s.OrganizationID, &s.TeamID, &reason, &suspendedBy, &s.SuspendedAt); err != nil {
+		return nil, err
+	}
+	if reason != nil {
+		s.Reason = *reason
+	}
+	if suspendedBy != nil {
+		s.SuspendedBy = *suspendedBy
+	}
+	return &s, nil
+}
+
+// evictKeys evicts an organization's keys, or only one team's when teamID
+// is set, from the auth cache.
+func (m *KeyManager) evictKeys(ctx context.Context, orgID, teamID string) error {
+	if m.redis == nil {
+		return nil
+	}
+	rows, err := m.db.Query(ctx, `
+		SELECT key_hash FROM api_keys
+		WHERE organization_id = $1 AND ($2 = '' OR team_id = $2)`, orgID, teamID)
+	if err != nil {
+		return fmt.Errorf("list keys to evict: %w", err)
+	}
+	defer rows.Close()
+	var keyHashes, cacheKeys []string
+	for rows.Next() {
+		var keyHash string
+		if err := rows.Scan(&keyHash); err != nil {
+			return fmt.Errorf("scan keys to evict: %w", err)
+		}
+		keyHashes = append(keyHashes, keyHash)
+		cacheKeys = append(cacheKeys, redisKeyPrefix+keyHash)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list keys to evict: %w", err)
+	}
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+	if err := m.redis.Del(ctx, cacheKeys...).Err(); err != nil {
+		return fmt.Errorf("evict keys: %w", err)
+	}
+	publishInvalidation(ctx, m.redis, keyHashes)
+	return nil
+}
+
+func (m *KeyManager) evict(ctx context.Context, keyHash string) {
+	if m.redis == nil {
+		return
+	}
+	_ = m.redis.Del(ctx, redisKeyPrefix+keyHash).Err()
+	publishInvalidation(ctx, m.redis, []string{keyHash})
+}
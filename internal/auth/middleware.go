@@ -64,19 +64,28 @@ func Middleware(store KeyStore, auditLogger AuditLogger) func(http.Handler) http
 				httputil.WriteAuthError(w, reqID, "Invalid API key")
 				return
 			}
+			if meta.OrgSuspended {
+				slog.Warn("auth failed: organization suspended", "org_id", meta.OrganizationID, "key_id", meta.ID)
+				if auditLogger != nil {
+					auditLogger.LogAuthFailure(reqID, r.RemoteAddr, r.UserAgent(), token, "organization suspended")
+				}
+				httputil.WriteError(w, reqID, http.StatusForbidden, "permission_error", "organization_suspended",
+					"Organization is suspended")
+				return
+			}
+			if meta.TeamSuspended {
+				slog.Warn("auth failed: team suspended", "org_id", meta.OrganizationID, "team_id", meta.TeamID, "key_id", meta.ID)
+				if auditLogger != nil {
+					auditLogger.LogAuthFailure(reqID, r.RemoteAddr, r.UserAgent(), token, "team suspended")
+				}
+				httputil.WriteError(w, reqID, http.StatusForbidden, "permission_error", "team_suspended",
+					"Team is suspended")
+				return
+			}
 
 			// Enrich context
-			info := &AuthInfo{
-				KeyID:                meta.ID,
-				OrganizationID:       meta.OrganizationID,
-				TeamID:               meta.TeamID,
-				UserID:               meta.UserID,
-				MaxClassification:    meta.MaxClassification,
-				AllowedModels:        meta.AllowedModels,
-				RPMLimit:             meta.RPMLimit,
-				TPMLimit:             meta.TPMLimit,
-				DailySpendLimitCents: meta.DailySpendLimitCents,
-			}
+			info := meta.AuthInfo()
+			info.KeyHash = keyHash
 
 			ctx := ContextWithAuth(r.Context(), info)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -129,3 +130,49 @@ func TestMiddleware_ValidKey(t *testing.T) {
 		t.Errorf("expected team-1, got %s", gotAuth.TeamID)
 	}
 }
+
+func TestMiddleware_Suspended(t *testing.T) {
+	rawKey := "aegis-prod-testkey12345678901234567890ab"
+	tests := []struct {
+		name          string
+		org, team     bool
+		wantErrorCode string
+	}{
+		{"org", true, false, "organization_suspended"},
+		{"team", false, true, "team_suspended"},
+		{"both", true, true, "organization_suspended"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &mockKeyStore{
+				keys: map[string]*KeyMetadata{
+					HashKey(rawKey): {
+						ID:             "key-uuid-123",
+						OrganizationID: "org-1",
+						TeamID:         "team-1",
+						ExpiresAt:      time.Now().Add(24 * time.Hour),
+						OrgSuspended:   tt.org,
+						TeamSuspended:  tt.team,
+					},
+				},
+			}
+
+			handler := Middleware(store, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler should not be called")
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+rawKey)
+			w := httptest.NewRecorder()
+			w.Header().Set("X-Request-ID", "test-req")
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d", w.Code)
+			}
+			if !strings.Contains(w.Body.String(), `"`+tt.wantErrorCode+`"`) {
+				t.Errorf("expected %s code, got %s", tt.wantErrorCode, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNoModelRules is returned when a team has no model rules to read or
+// clear.
+var ErrNoModelRules = errors.New("no model rules")
+
+// ModelRules steer a key's or team's traffic between models without client
+// changes. Team rules apply to every key of the team; a key's own rules take
+// precedence over them, rewrite by rewrite.
+type ModelRules struct {
+	// DefaultModel is used when a request names no model.
+	DefaultModel string `json:"default_model,omitempty"`
+	// ModelRewrites maps a requested model to the one actually served, e.g.
+	// "gpt-4" to "gpt-4o-mini" for a low-budget team. Rewrites do not
+	// chain: the target is served even if it has a rewrite of its own.
+	ModelRewrites map[string]string `json:"model_rewrites,omitempty"`
+}
+
+// Resolve returns the model to serve for a request naming model: the
+// default if model is empty, rewritten if a rule matches.
+func (r ModelRules) Resolve(model string) string {
+	if model == "" {
+		model = r.DefaultModel
+	}
+	if to, ok := r.ModelRewrites[model]; ok {
+		return to
+	}
+	return model
+}
+
+// Validate reports rules with empty model names or rewrites of a model to
+// itself.
+func (r ModelRules) Validate() error {
+	for from, to := range r.ModelRewrites {
+		if from == "" || to == "" {
+			return fmt.Errorf("model_rewrites: model names must not be empty")
+		}
+		if from == to {
+			return fmt.Errorf("model_rewrites: %q is rewritten to itself", from)
+		}
+	}
+	return nil
+}
+
+// rewritesJSON encodes ModelRewrites for the JSONB columns, which are never
+// NULL.
+func (r ModelRules) rewritesJSON() []byte {
+	if len(r.ModelRewrites) == 0 {
+		return []byte("{}")
+	}
+	data, _ := json.Marshal(r.ModelRewrites)
+	return data
+}
+
+// TeamModelRules are the model rules every key of a team inherits.
+type TeamModelRules struct {
+	OrganizationID string `json:"organization_id"`
+	TeamID         string `json:"team_id"`
+	ModelRules
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetModelRules replaces a key's own model rules and evicts it from the auth
+// cache. Empty rules leave only the team's.
+func (m *KeyManager) SetModelRules(ctx context.Context, id string, rules ModelRules) (*KeyInfo, error) {
+	var keyHash string
+	err := m.db.QueryRow(ctx, `
+		UPDATE api_keys SET default_model = NULLIF($2, ''), model_rewrites = $3
+		WHERE id::text = $1
+		RETURNING key_hash`, id, rules.DefaultModel, rules.rewritesJSON()).Scan(&keyHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update api key model rules: %w", err)
+	}
+	m.evict(ctx, keyHash)
+	return m.GetKey(ctx, id)
+}
+
+// GetTeamModelRules returns a team's model rules, or ErrNoModelRules.
+func (m *KeyManager) GetTeamModelRules(ctx context.Context, orgID, teamID string) (*TeamModelRules, error) {
+	t, err := scanTeamModelRules(m.db.QueryRow(ctx, `
+		SELECT `+teamModelRulesColumns+` FROM team_model_rules
+		WHERE organization_id = $1 AND team_id = $2`, orgID, teamID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoModelRules
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get team model rules: %w", err)
+	}
+	return t, nil
+}
+
+// SetTeamModelRules replaces a team's model rules and evicts the team's keys
+// from the auth cache so they apply at once.
+func (m *KeyManager) SetTeamModelRules(ctx context.Context, orgID, teamID string, rules ModelRules, updatedBy string) (*TeamModelRules, error) {
+	t, err := scanTeamModelRules(m.db.QueryRow(ctx, `
+		INSERT INTO team_model_rules (organization_id, team_id, default_model, model_rewrites, updated_by)
+		VALUES ($1, $2, NULLIF($3, ''), $4, NULLIF($5, ''))
+		ON CONFLICT (organization_id, team_id) DO UPDATE SET
+			default_model = EXCLUDED.default_model, model_rewrites = EXCLUDED.model_rewrites,
+			updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING `+teamModelRulesColumns, orgID, teamID, rules.DefaultModel, rules.rewritesJSON(), updatedBy))
+	if err != nil {
+		return nil, fmt.Errorf("set team model rules: %w", err)
+	}
+	if err := m.evictKeys(ctx, orgID, teamID); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ClearTeamModelRules removes a team's model rules and evicts its keys from
+// the auth cache.
+func (m *KeyManager) ClearTeamModelRules(ctx context.Context, orgID, teamID string) error {
+	tag, err := m.db.Exec(ctx, `DELETE FROM team_model_rules WHERE organization_id = $1 AND team_id = $2`, orgID, teamID)
+	if err != nil {
+		return fmt.Errorf("clear team model rules: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNoModelRules
+	}
+	return m.evictKeys(ctx, orgID, teamID)
+}
+
+const teamModelRulesColumns = `organization_id, team_id, COALESCE(default_model, ''), model_rewrites,
+	COALESCE(updated_by, ''), updated_at`
+
+func scanTeamModelRules(row pgx.Row) (*TeamModelRules, error) {
+	var t TeamModelRules
+	var rewrites []byte
+	if err := row.Scan(&t.OrganizationID, &t.TeamID, &t.DefaultModel, &rewrites, &t.UpdatedBy, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(rewrites, &t.ModelRewrites)
+	return &t, nil
+}
@@ -0,0 +1,35 @@
+package auth
+
+import "testing"
+
+func TestModelRules_Resolve(t *testing.T) {
+	rules := ModelRules{
+		DefaultModel:  "gpt-4",
+		ModelRewrites: map[string]string{"gpt-4": "gpt-4o-mini", "gpt-4o-mini": "gpt-3.5-turbo"},
+	}
+	tests := []struct{ model, want string }{
+		{"", "gpt-4o-mini"}, // default, then one rewrite
+		{"gpt-4", "gpt-4o-mini"},
+		{"gpt-4o-mini", "gpt-3.5-turbo"},
+		{"claude-sonnet", "claude-sonnet"},
+	}
+	for _, tt := range tests {
+		if got := rules.Resolve(tt.model); got != tt.want {
+			t.Errorf("Resolve(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+	if got := (ModelRules{}).Resolve(""); got != "" {
+		t.Errorf("empty rules resolved %q", got)
+	}
+}
+
+func TestModelRules_Validate(t *testing.T) {
+	if err := (ModelRules{ModelRewrites: map[string]string{"a": "b"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, rewrites := range []map[string]string{{"a": "a"}, {"": "b"}, {"a": ""}} {
+		if err := (ModelRules{ModelRewrites: rewrites}).Validate(); err == nil {
+			t.Errorf("expected %v to be rejected", rewrites)
+		}
+	}
+}
@@ -22,6 +22,7 @@ type KeyStore interface {
 type CachedKeyStore struct {
 	db    *pgxpool.Pool
 	redis *redis.Client
+	local *localKeyCache
 }
 
 func NewCachedKeyStore(db *pgxpool.Pool, rdb *redis.Client) *CachedKeyStore {
@@ -29,12 +30,18 @@ func NewCachedKeyStore(db *pgxpool.Pool, rdb *redis.Client) *CachedKeyStore {
 }
 
 func (s *CachedKeyStore) Lookup(ctx context.Context, keyHash string) (*KeyMetadata, error) {
-	// Check Redis cache first
+	// Check the local cache, then Redis
+	if s.local != nil {
+		if meta, ok := s.local.get(keyHash, time.Now()); ok {
+			return meta, nil
+		}
+	}
 	if s.redis != nil {
 		cached, err := s.redis.Get(ctx, redisKeyPrefix+keyHash).Bytes()
 		if err == nil {
 			var meta KeyMetadata
 			if err := json.Unmarshal(cached, &meta); err == nil {
+				s.cacheLocally(keyHash, &meta)
 				return &meta, nil
 			}
 		}
@@ -56,22 +63,34 @@ func (s *CachedKeyStore) Lookup(ctx context.Context, keyHash string) (*KeyMetada
 			s.redis.Set(ctx, redisKeyPrefix+keyHash, data, redisCacheTTL)
 		}
 	}
+	s.cacheLocally(keyHash, meta)
 
 	return meta, nil
 }
 
+func (s *CachedKeyStore) cacheLocally(keyHash string, meta *KeyMetadata) {
+	if s.local != nil {
+		s.local.put(keyHash, *meta, time.Now())
+	}
+}
+
 func (s *CachedKeyStore) lookupDB(ctx context.Context, keyHash string) (*KeyMetadata, error) {
 	var meta KeyMetadata
-	var allowedModelsJSON []byte
+	var allowedModelsJSON, modelRewritesJSON []byte
 	var userID *string
 
 	err := s.db.QueryRow(ctx, `
-		SELECT id, organization_id, team_id, user_id, name, max_classification,
-		       allowed_models, rpm_limit, tpm_limit, daily_spend_limit_cents, expires_at
-		FROM api_keys
-		WHERE key_hash = $1
-		  AND status = 'active'
-		  AND expires_at > NOW()
+		SELECT k.id, k.organization_id, k.team_id, k.user_id, k.name, k.max_classification,
+		       k.allowed_models, k.rpm_limit, k.tpm_limit, k.daily_spend_limit_cents, k.priority, k.expires_at,
+		       s.organization_id IS NOT NULL, ts.team_id IS NOT NULL,
+		       COALESCE(k.default_model, tm.default_model, ''), COALESCE(tm.model_rewrites, '{}') || k.model_rewrites
+		FROM api_keys k
+		LEFT JOIN organization_suspensions s ON s.organization_id = k.organization_id
+		LEFT JOIN team_suspensions ts ON ts.organization_id = k.organization_id AND ts.team_id = k.team_id
+		LEFT JOIN team_model_rules tm ON tm.organization_id = k.organization_id AND tm.team_id = k.team_id
+		WHERE k.key_hash = $1
+		  AND k.status = 'active'
+		  AND k.expires_at > NOW()
 	`, keyHash).Scan(
 		&meta.ID,
 		&meta.OrganizationID,
@@ -83,7 +102,12 @@ func (s *CachedKeyStore) lookupDB(ctx context.Context, keyHash string) (*KeyMeta
 		&meta.RPMLimit,
 		&meta.TPMLimit,
 		&meta.DailySpendLimitCents,
+		&meta.Priority,
 		&meta.ExpiresAt,
+		&meta.OrgSuspended,
+		&meta.TeamSuspended,
+		&meta.ModelRules.DefaultModel,
+		&modelRewritesJSON,
 	)
 	if err != nil {
 		if err.Error() == "no rows in result set" {
@@ -99,6 +123,9 @@ func (s *CachedKeyStore) lookupDB(ctx context.Context, keyHash string) (*KeyMeta
 	if len(allowedModelsJSON) > 0 {
 		_ = json.Unmarshal(allowedModelsJSON, &meta.AllowedModels)
 	}
+	if len(modelRewritesJSON) > 0 {
+		_ = json.Unmarshal(modelRewritesJSON, &meta.ModelRules.ModelRewrites)
+	}
 
 	// Update last_used_at asynchronously (fire-and-forget)
 	go func() {
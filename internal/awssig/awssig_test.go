@@ -0,0 +1,47 @@
+package awssig
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSign_AWSExample signs the IAM ListUsers request from the AWS
+// Signature Version 4 documentation and checks the published signature.
+func TestSign_AWSExample(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	creds := Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	Sign(req, creds, "us-east-1", "iam", "/", nil, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date, " +
+		"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n  %s\nwant\n  %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSign_SessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://secretsmanager.us-east-1.amazonaws.com/", nil)
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	Sign(req, Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, "us-east-1", "secretsmanager", "/", nil, time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "token" {
+		t.Error("temporary credentials should send X-Amz-Security-Token")
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=host;x-amz-date;x-amz-security-token;x-amz-target,") {
+		t.Errorf("the session token and target should be signed, got %q", auth)
+	}
+}
+
+func TestURIEncode(t *testing.T) {
+	if got := URIEncode("org 1/a~b+c"); got != "org%201%2Fa~b%2Bc" {
+		t.Errorf("URIEncode = %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// ErrBypassGrantNotFound is returned when revoking an unknown or expired grant.
+var ErrBypassGrantNotFound = errors.New("bypass grant not found")
+
+// BypassGrant is a break-glass exemption of one API key from one filter
+// until ExpiresAt.
+type BypassGrant struct {
+	ID             string    `json:"id"`
+	OrganizationID string    `json:"organization_id"`
+	APIKeyID       string    `json:"api_key_id"`
+	Filter         string    `json:"filter"`
+	Justification  string    `json:"justification"`
+	CreatedBy      string    `json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// BypassStore keeps filter bypass grants in Redis, each under a key that
+// expires with the grant, so every replica honours a grant as soon as it is
+// created and no replica honours it after it expires or is revoked.
+type BypassStore struct {
+	rdb           *redis.Client
+	strictTenancy bool
+}
+
+// NewBypassStore returns a grant store on rdb.
+func NewBypassStore(rdb *redis.Client) *BypassStore {
+	return &BypassStore{rdb: rdb}
+}
+
+// SetStrictTenancy keeps each organization's grants under its own key
+// prefix. Call before serving traffic.
+func (s *BypassStore) SetStrictTenancy(strict bool) {
+	s.strictTenancy = strict
+}
+
+func (s *BypassStore) redisKey(org, keyID, filter string) string {
+	return tenant.RedisPrefix(s.strictTenancy, org) + "bypass:" + keyID + ":" + filter
+}
+
+// Create stores g with a new ID and CreatedAt, replacing any grant the key
+// already holds for the same filter.
+func (s *BypassStore) Create(ctx context.Context, g BypassGrant) (*BypassGrant, error) {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	g.ID = "bypass_" + hex.EncodeToString(b)
+	g.CreatedAt = time.Now().UTC()
+	ttl := g.ExpiresAt.Sub(g.CreatedAt)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("bypass grant expires in the past")
+	}
+	data, err := json.Marshal(g)
+	if err != nil {
+		return nil, fmt.Errorf("marshal bypass grant: %w", err)
+	}
+	if err := s.rdb.Set(ctx, s.redisKey(g.OrganizationID, g.APIKeyID, g.Filter), data, ttl).Err(); err != nil {
+		return nil, fmt.Errorf("store bypass grant: %w", err)
+	}
+	return &g, nil
+}
+
+// BypassGrants returns the IDs of the grants req's key holds for filters.
+// Lookup errors are logged and treated as no grant, so filters still run.
+func (s *BypassStore) BypassGrants(ctx context.Context, req *types.AegisRequest, filters []string) map[string]string {
+	if req.APIKeyID == "" || len(filters) == 0 {
+		return nil
+	}
+	keys := make([]string, len(filters))
+	for i, f := range filters {
+		keys[i] = s.redisKey(req.OrganizationID, req.APIKeyID, f)
+	}
+	vals, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		slog.Warn("bypass grant lookup failed; running all filters", "request_id", req.RequestID, "error", err)
+		return nil
+	}
+	var grants map[string]string
+	for i, v := range vals {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var g BypassGrant
+		if err := json.Unmarshal([]byte(raw), &g); err != nil || !time.Now().Before(g.ExpiresAt) {
+			continue
+		}
+		if grants == nil {
+			grants = make(map[string]string)
+		}
+		grants[filters[i]] = g.ID
+	}
+	return grants
+}
+
+// List returns the unexpired grants, soonest to expire first.
+func (s *BypassStore) List(ctx context.Context) ([]BypassGrant, error) {
+	grants := []BypassGrant{}
+	err := s.scan(ctx, func(_ string, g BypassGrant) bool {
+		grants = append(grants, g)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(grants, func(i, j int) bool { return grants[i].ExpiresAt.Before(grants[j].ExpiresAt) })
+	return grants, nil
+}
+
+// Revoke deletes the grant with id and returns it.
+func (s *BypassStore) Revoke(ctx context.Context, id string) (*BypassGrant, error) {
+	var found *BypassGrant
+	var foundKey string
+	err := s.scan(ctx, func(key string, g BypassGrant) bool {
+		if g.ID != id {
+			return true
+		}
+		found, foundKey = &g, key
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrBypassGrantNotFound
+	}
+	if err := s.rdb.Del(ctx, foundKey).Err(); err != nil {
+		return nil, fmt.Errorf("revoke bypass grant: %w", err)
+	}
+	return found, nil
+}
+
+// scan calls fn for each stored grant until fn returns false.
+func (s *BypassStore) scan(ctx context.Context, fn func(key string, g BypassGrant) bool) error {
+	iter := s.rdb.Scan(ctx, 0, tenant.RedisPattern(s.strictTenancy, "bypass:"), 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		data, err := s.rdb.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("read bypass grant: %w", err)
+		}
+		var g BypassGrant
+		if err := json.Unmarshal(data, &g); err != nil {
+			continue
+		}
+		if !fn(key, g) {
+			return nil
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("scan bypass grants: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+)
+
+// idempotencyPendingTTL bounds how long a reservation survives a gateway that
+// died mid-request, so a crashed replica cannot lock a key for the full TTL.
+const idempotencyPendingTTL = 5 * time.Minute
+
+var (
+	// ErrIdempotencyInProgress means another request holding the key has not
+	// finished yet.
+	ErrIdempotencyInProgress = errors.New("idempotency key in use by a request still in progress")
+	// ErrIdempotencyMismatch means the key was first used with a different
+	// request body.
+	ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+)
+
+// StoredResponse is the recorded outcome of a request made with an
+// Idempotency-Key. Pending entries mark a request still in flight.
+type StoredResponse struct {
+	Fingerprint string            `json:"fingerprint"`
+	Pending     bool              `json:"pending,omitempty"`
+	StatusCode  int               `json:"status_code,omitempty"`
+	Header      map[string]string `json:"header,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+}
+
+// IdempotencyStore keeps idempotent responses in Redis.
+type IdempotencyStore struct {
+	rdb           *redis.Client
+	ttl           func() time.Duration
+	strictTenancy bool
+}
+
+// NewIdempotencyStore returns a store that keeps completed responses for
+// ttl(), read on every write so the window follows config reloads.
+func NewIdempotencyStore(rdb *redis.Client, ttl func() time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{rdb: rdb, ttl: ttl}
+}
+
+// SetStrictTenancy keeps each organization's entries under its own key
+// prefix. Call before serving traffic.
+func (s *IdempotencyStore) SetStrictTenancy(strict bool) {
+	s.strictTenancy = strict
+}
+
+func (s *IdempotencyStore) redisKey(org, key string) string {
+	return tenant.RedisPrefix(s.strictTenancy, org) + "idem:" + key
+}
+
+// Reserve claims key for a request whose body hashes to fingerprint. It
+// returns (nil, nil) when the caller now owns the key and must Complete or
+// Release it, the stored response when the key already completed, and
+// ErrIdempotencyInProgress or ErrIdempotencyMismatch otherwise.
+func (s *IdempotencyStore) Reserve(ctx context.Context, org, key, fingerprint string) (*StoredResponse, error) {
+	pending, err := json.Marshal(StoredResponse{Fingerprint: fingerprint, Pending: true})
+	if err != nil {
+		return nil, fmt.Errorf("marshal idempotency reservation: %w", err)
+	}
+	ok, err := s.rdb.SetNX(ctx, s.redisKey(org, key), pending, min(s.ttl(), idempotencyPendingTTL)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	if ok {
+		return nil, nil
+	}
+
+	data, err := s.rdb.Get(ctx, s.redisKey(org, key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Expired between SETNX and GET; let the caller try again.
+		return nil, ErrIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read idempotency key: %w", err)
+	}
+	var stored StoredResponse
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("decode idempotency entry: %w", err)
+	}
+	switch {
+	case stored.Fingerprint != fingerprint:
+		return nil, ErrIdempotencyMismatch
+	case stored.Pending:
+		return nil, ErrIdempotencyInProgress
+	}
+	return &stored, nil
+}
+
+// Complete records the response for a reserved key.
+func (s *IdempotencyStore) Complete(ctx context.Context, org, key string, resp StoredResponse) error {
+	resp.Pending = false
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("marshal idempotent response: %w", err)
+	}
+	if err := s.rdb.Set(ctx, s.redisKey(org, key), data, s.ttl()).Err(); err != nil {
+		return fmt.Errorf("store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// Release drops a reservation whose request failed, so a retry with the same
+// key runs again.
+func (s *IdempotencyStore) Release(ctx context.Context, org, key string) error {
+	if err := s.rdb.Del(ctx, s.redisKey(org, key)).Err(); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+	return nil
+}
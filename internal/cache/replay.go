@@ -0,0 +1,131 @@
+// Package cache keeps responses and short-lived request state in Redis.
+// ResponseStore is the response cache, and ReplayStream makes its hits
+// transparent to clients that requested stream=true. IdempotencyStore keeps
+// responses to requests sent with an Idempotency-Key.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// ReplayConfig controls how a cached response is paced when replayed as SSE.
+type ReplayConfig struct {
+	// ChunkSize is the number of runes of content per chunk.
+	// Zero or negative sends each choice's content in a single chunk.
+	ChunkSize int
+	// Interval is the delay between content chunks. Zero sends everything at once.
+	Interval time.Duration
+}
+
+type replayChunk struct {
+	ID      string         `json:"id"`
+	Object  string         `json:"object"`
+	Created int64          `json:"created"`
+	Model   string         `json:"model"`
+	Choices []replayChoice `json:"choices"`
+	Usage   *types.Usage   `json:"usage,omitempty"`
+}
+
+type replayChoice struct {
+	Index        int         `json:"index"`
+	Delta        replayDelta `json:"delta"`
+	FinishReason *string     `json:"finish_reason"`
+}
+
+type replayDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// ReplayStream writes a complete response as OpenAI chat.completion.chunk SSE
+// events: a role chunk, content chunks paced per cfg, a finish_reason chunk per
+// choice, a usage chunk, and the [DONE] terminator. The caller sets response
+// headers. Returns ctx.Err() if the client goes away mid-replay.
+func ReplayStream(ctx context.Context, w http.ResponseWriter, resp *types.AegisResponse, cfg ReplayConfig) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming not supported by response writer")
+	}
+
+	base := replayChunk{
+		ID:      "chatcmpl-" + resp.RequestID,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   resp.Model,
+	}
+	write := func(choices []replayChoice, usage *types.Usage) error {
+		c := base
+		c.Choices = choices
+		c.Usage = usage
+		data, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("marshal replay chunk: %w", err)
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for _, choice := range resp.Choices {
+		if err := write([]replayChoice{{Index: choice.Index, Delta: replayDelta{Role: choice.Message.Role}}}, nil); err != nil {
+			return err
+		}
+
+		for i, piece := range splitContent(choice.Message.Content, cfg.ChunkSize) {
+			if i > 0 && cfg.Interval > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(cfg.Interval):
+				}
+			}
+			if err := write([]replayChoice{{Index: choice.Index, Delta: replayDelta{Content: piece}}}, nil); err != nil {
+				return err
+			}
+		}
+
+		finish := choice.FinishReason
+		if finish == "" {
+			finish = "stop"
+		}
+		if err := write([]replayChoice{{Index: choice.Index, FinishReason: &finish}}, nil); err != nil {
+			return err
+		}
+	}
+
+	usage := resp.Usage
+	if err := write([]replayChoice{}, &usage); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(w, "data: [DONE]\n\n"); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// splitContent breaks content into pieces of at most size runes.
+func splitContent(content string, size int) []string {
+	if content == "" {
+		return nil
+	}
+	runes := []rune(content)
+	if size <= 0 || size >= len(runes) {
+		return []string{content}
+	}
+	pieces := make([]string, 0, (len(runes)+size-1)/size)
+	for start := 0; start < len(runes); start += size {
+		end := min(start+size, len(runes))
+		pieces = append(pieces, string(runes[start:end]))
+	}
+	return pieces
+}
@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func testResponse() *types.AegisResponse {
+	return &types.AegisResponse{
+		RequestID: "req-1",
+		Model:     "gpt-4o",
+		Choices: []types.Choice{{
+			Index:        0,
+			Message:      types.Message{Role: "assistant", Content: "Hello world"},
+			FinishReason: "stop",
+		}},
+		Usage: types.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7},
+	}
+}
+
+func dataLines(body string) []string {
+	var lines []string
+	for _, l := range strings.Split(body, "\n") {
+		if strings.HasPrefix(l, "data: ") {
+			lines = append(lines, strings.TrimPrefix(l, "data: "))
+		}
+	}
+	return lines
+}
+
+func TestReplayStream_ChunksContent(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := ReplayStream(context.Background(), w, testResponse(), ReplayConfig{ChunkSize: 4}); err != nil {
+		t.Fatalf("ReplayStream: %v", err)
+	}
+
+	lines := dataLines(w.Body.String())
+	// role + 3 content ("Hell", "o wo", "rld") + finish + usage + [DONE]
+	if len(lines) != 7 {
+		t.Fatalf("expected 7 events, got %d: %v", len(lines), lines)
+	}
+	if lines[len(lines)-1] != "[DONE]" {
+		t.Errorf("expected [DONE] terminator, got %q", lines[len(lines)-1])
+	}
+
+	var content strings.Builder
+	for _, l := range lines[1:4] {
+		var c replayChunk
+		if err := json.Unmarshal([]byte(l), &c); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if c.Object != "chat.completion.chunk" || c.ID != "chatcmpl-req-1" {
+			t.Errorf("unexpected chunk envelope: %+v", c)
+		}
+		content.WriteString(c.Choices[0].Delta.Content)
+	}
+	if content.String() != "Hello world" {
+		t.Errorf("expected reassembled content %q, got %q", "Hello world", content.String())
+	}
+
+	var usage replayChunk
+	if err := json.Unmarshal([]byte(lines[5]), &usage); err != nil {
+		t.Fatalf("unmarshal usage: %v", err)
+	}
+	if usage.Usage == nil || usage.Usage.TotalTokens != 7 {
+		t.Errorf("expected usage chunk with 7 total tokens, got %+v", usage.Usage)
+	}
+}
+
+func TestReplayStream_AllAtOnce(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := ReplayStream(context.Background(), w, testResponse(), ReplayConfig{}); err != nil {
+		t.Fatalf("ReplayStream: %v", err)
+	}
+	if got := len(dataLines(w.Body.String())); got != 5 {
+		t.Errorf("expected 5 events, got %d", got)
+	}
+}
+
+func TestReplayStream_CancelledMidReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	w := httptest.NewRecorder()
+	err := ReplayStream(ctx, w, testResponse(), ReplayConfig{ChunkSize: 1, Interval: time.Second})
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if strings.Contains(w.Body.String(), "[DONE]") {
+		t.Error("expected no [DONE] after cancellation")
+	}
+}
+
+func TestSplitContent_Multibyte(t *testing.T) {
+	got := splitContent("héllo", 2)
+	want := []string{"hé", "ll", "o"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// responseCacheDisabledKey is set while the response cache is switched off
+// at runtime. It is global: one switch pauses the cache on every replica
+// and for every organization.
+const responseCacheDisabledKey = "aegis:response_cache:disabled"
+
+// ErrResponseCacheDisabled is returned by Get and Put while the cache is
+// switched off with SetEnabled.
+var ErrResponseCacheDisabled = errors.New("response cache disabled")
+
+// CachedResponse is a completion kept for requests with the same content.
+// Model (as the client asked for it, after model rules), OrganizationID,
+// and KeyHash (of the API key whose request filled the entry) record what it
+// was cached for, so entries can be found again to purge them.
+type CachedResponse struct {
+	Model          string               `json:"model"`
+	OrganizationID string               `json:"organization_id"`
+	KeyHash        string               `json:"key_hash,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	Response       *types.AegisResponse `json:"response"`
+}
+
+// ResponseStore keeps completed chat completions in Redis, shared by every
+// replica, so a repeated request is answered without calling the provider.
+type ResponseStore struct {
+	rdb           *redis.Client
+	ttl           func() time.Duration
+	strictTenancy bool
+}
+
+// NewResponseStore returns a store that keeps responses for ttl(), read on
+// every write so the window follows config reloads.
+func NewResponseStore(rdb *redis.Client, ttl func() time.Duration) *ResponseStore {
+	return &ResponseStore{rdb: rdb, ttl: ttl}
+}
+
+// SetStrictTenancy keeps each organization's entries under its own key
+// prefix. Call before serving traffic.
+func (s *ResponseStore) SetStrictTenancy(strict bool) {
+	s.strictTenancy = strict
+}
+
+// redisKey scopes hash to org even without strict tenancy: one
+// organization's answers are never served to another.
+func (s *ResponseStore) redisKey(org, hash string) string {
+	return tenant.RedisPrefix(s.strictTenancy, org) + "respcache:" + org + ":" + hash
+}
+
+// Get returns the response cached for org under hash, or nil if there is
+// none, and ErrResponseCacheDisabled while the cache is switched off.
+func (s *ResponseStore) Get(ctx context.Context, org, hash string) (*CachedResponse, error) {
+	// One round trip reads the switch and the entry.
+	vals, err := s.rdb.MGet(ctx, responseCacheDisabledKey, s.redisKey(org, hash)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("read cached response: %w", err)
+	}
+	if vals[0] != nil {
+		return nil, ErrResponseCacheDisabled
+	}
+	data, ok := vals[1].(string)
+	if !ok {
+		return nil, nil
+	}
+	var entry CachedResponse
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return nil, fmt.Errorf("decode cached response: %w", err)
+	}
+	return &entry, nil
+}
+
+// Put caches entry for org under hash, replacing any entry already there.
+// It returns ErrResponseCacheDisabled while the cache is switched off.
+func (s *ResponseStore) Put(ctx context.Context, org, hash string, entry CachedResponse) error {
+	disabled, err := s.rdb.Exists(ctx, responseCacheDisabledKey).Result()
+	if err != nil {
+		return fmt.Errorf("read response cache switch: %w", err)
+	}
+	if disabled > 0 {
+		return ErrResponseCacheDisabled
+	}
+	entry.OrganizationID = org
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshal cached response: %w", err)
+	}
+	if err := s.rdb.Set(ctx, s.redisKey(org, hash), data, s.ttl()).Err(); err != nil {
+		return fmt.Errorf("store cached response: %w", err)
+	}
+	return nil
+}
+
+// Enabled reports whether the cache is switched on. It says nothing about
+// response_cache.enabled, which each replica reads from its own config.
+func (s *ResponseStore) Enabled(ctx context.Context) (bool, error) {
+	n, err := s.rdb.Exists(ctx, responseCacheDisabledKey).Result()
+	if err != nil {
+		return false, fmt.Errorf("read response cache switch: %w", err)
+	}
+	return n == 0, nil
+}
+
+// SetEnabled switches the cache on or off on every replica at once. The
+// switch outlives restarts and config reloads until it is flipped back.
+// Entries are kept while the cache is off; Purge them to drop them.
+func (s *ResponseStore) SetEnabled(ctx context.Context, enabled bool) error {
+	var err error
+	if enabled {
+		err = s.rdb.Del(ctx, responseCacheDisabledKey).Err()
+	} else {
+		err = s.rdb.Set(ctx, responseCacheDisabledKey, "1", 0).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("set response cache switch: %w", err)
+	}
+	return nil
+}
+
+// PurgeFilter selects cached responses to purge. Set fields must all match;
+// an empty filter matches every entry.
+type PurgeFilter struct {
+	Model          string
+	OrganizationID string
+	KeyHashPrefix  string
+}
+
+func (f PurgeFilter) matches(e CachedResponse) bool {
+	return (f.Model == "" || e.Model == f.Model) &&
+		(f.OrganizationID == "" || e.OrganizationID == f.OrganizationID) &&
+		(f.KeyHashPrefix == "" || strings.HasPrefix(e.KeyHash, f.KeyHashPrefix))
+}
+
+// Purge deletes the cached responses f matches and returns how many it
+// deleted.
+func (s *ResponseStore) Purge(ctx context.Context, f PurgeFilter) (int, error) {
+	purged := 0
+	iter := s.rdb.Scan(ctx, 0, tenant.RedisPattern(s.strictTenancy, "respcache:"), 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		data, err := s.rdb.Get(ctx, key).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return purged, fmt.Errorf("read cached response: %w", err)
+		}
+		var entry CachedResponse
+		if err := json.Unmarshal(data, &entry); err != nil || !f.matches(entry) {
+			continue
+		}
+		n, err := s.rdb.Del(ctx, key).Result()
+		if err != nil {
+			return purged, fmt.Errorf("purge cached response: %w", err)
+		}
+		purged += int(n)
+	}
+	if err := iter.Err(); err != nil {
+		return purged, fmt.Errorf("scan cached responses: %w", err)
+	}
+	return purged, nil
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPurgeFilter_Matches(t *testing.T) {
+	entry := CachedResponse{Model: "smart", OrganizationID: "org-1", KeyHash: "ab12cd"}
+	tests := []struct {
+		filter PurgeFilter
+		want   bool
+	}{
+		{PurgeFilter{}, true},
+		{PurgeFilter{Model: "smart"}, true},
+		{PurgeFilter{Model: "fast"}, false},
+		{PurgeFilter{OrganizationID: "org-1", KeyHashPrefix: "ab1"}, true},
+		{PurgeFilter{OrganizationID: "org-1", KeyHashPrefix: "cd"}, false},
+		{PurgeFilter{Model: "smart", OrganizationID: "org-2"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.matches(entry); got != tt.want {
+			t.Errorf("%+v matches = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestResponseStore_RedisDown(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer func() { _ = rdb.Close() }()
+	s := NewResponseStore(rdb, func() time.Duration { return time.Hour })
+	ctx := context.Background()
+
+	if entry, err := s.Get(ctx, "org-1", "hash"); err == nil || entry != nil {
+		t.Errorf("Get should fail without Redis, got %v, %v", entry, err)
+	}
+	if err := s.Put(ctx, "org-1", "hash", CachedResponse{}); err == nil {
+		t.Error("Put should fail without Redis")
+	}
+	if err := s.SetEnabled(ctx, false); err == nil {
+		t.Error("SetEnabled should fail without Redis")
+	}
+	if _, err := s.Purge(ctx, PurgeFilter{}); err == nil {
+		t.Error("Purge should fail without Redis")
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+)
+
+// StickyRouteStore remembers which provider served a conversation or
+// affinity key, per model, so later turns can be routed to it again. Entries
+// expire ttl() after the last turn that used them.
+type StickyRouteStore struct {
+	rdb           *redis.Client
+	ttl           func() time.Duration
+	strictTenancy bool
+}
+
+// NewStickyRouteStore returns a store on rdb whose entries live for ttl(),
+// read on every write so the window follows config reloads.
+func NewStickyRouteStore(rdb *redis.Client, ttl func() time.Duration) *StickyRouteStore {
+	return &StickyRouteStore{rdb: rdb, ttl: ttl}
+}
+
+// SetStrictTenancy keeps each organization's entries under its own key
+// prefix. Call before serving traffic.
+func (s *StickyRouteStore) SetStrictTenancy(strict bool) {
+	s.strictTenancy = strict
+}
+
+func (s *StickyRouteStore) redisKey(org, model, key string) string {
+	// Conversation IDs are chosen by clients, so the shared prefix needs the
+	// org to keep one organization's keys from steering another's.
+	if !s.strictTenancy {
+		return tenant.RedisPrefix(false, org) + "sticky:" + org + ":" + model + ":" + key
+	}
+	return tenant.RedisPrefix(true, org) + "sticky:" + model + ":" + key
+}
+
+// Provider returns the provider that last served key for model, or "" if
+// there is none.
+func (s *StickyRouteStore) Provider(ctx context.Context, org, model, key string) (string, error) {
+	provider, err := s.rdb.Get(ctx, s.redisKey(org, model, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get sticky route: %w", err)
+	}
+	return provider, nil
+}
+
+// Record notes that provider served key for model.
+func (s *StickyRouteStore) Record(ctx context.Context, org, model, key, provider string) error {
+	if err := s.rdb.Set(ctx, s.redisKey(org, model, key), provider, s.ttl()).Err(); err != nil {
+		return fmt.Errorf("store sticky route: %w", err)
+	}
+	return nil
+}
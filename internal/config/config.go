@@ -1,6 +1,12 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
 
 type Config struct {
 	Server    ServerConfig    `yaml:"server"`
@@ -9,6 +15,41 @@ type Config struct {
 	Telemetry TelemetryConfig `yaml:"telemetry"`
 	Filter    FilterConfig    `yaml:"filter"`
 	Routing   RoutingConfig   `yaml:"routing"`
+	Archive   ArchiveConfig   `yaml:"archive"`
+	Events    EventsConfig    `yaml:"events"`
+	Admin     AdminConfig     `yaml:"admin"`
+	SIEM      SIEMConfig      `yaml:"siem"`
+	Secrets   SecretsConfig   `yaml:"secrets"`
+	Limits    LimitsConfig    `yaml:"limits"`
+	CORS      CORSConfig      `yaml:"cors"`
+	Usage     UsageConfig     `yaml:"usage"`
+	Retention RetentionConfig `yaml:"retention"`
+	// Idempotency controls replay of non-streaming responses for requests
+	// sent with an Idempotency-Key header. It needs Redis.
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	// ResponseCache answers repeated chat completions from Redis instead of
+	// the provider.
+	ResponseCache ResponseCacheConfig `yaml:"response_cache"`
+	// Batch controls the asynchronous batch completion API and its workers.
+	Batch BatchConfig `yaml:"batch"`
+	// Hooks lists request/response transformation hooks, run in order.
+	Hooks []HookConfig `yaml:"hooks"`
+	// Guardrails rewrites or annotates model output per organization.
+	Guardrails GuardrailsConfig `yaml:"guardrails"`
+	// Conversations tracks multi-turn sessions and enforces their budgets.
+	Conversations ConversationsConfig `yaml:"conversations"`
+	// Tenancy controls isolation between organizations.
+	Tenancy TenancyConfig `yaml:"tenancy"`
+	// Auth controls API key lookup caching.
+	Auth AuthConfig `yaml:"auth"`
+	// Egress restricts where provider traffic may go.
+	Egress EgressConfig `yaml:"egress"`
+	// DryRun answers provider calls with canned responses.
+	DryRun DryRunConfig `yaml:"dry_run"`
+	// Fixtures records provider traffic to disk or replays it.
+	Fixtures FixturesConfig `yaml:"fixtures"`
+	// Chaos injects provider faults for resilience testing.
+	Chaos ChaosConfig `yaml:"chaos"`
 }
 
 type ServerConfig struct {
@@ -18,6 +59,25 @@ type ServerConfig struct {
 	WriteTimeout     time.Duration `yaml:"write_timeout"`
 	IdleTimeout      time.Duration `yaml:"idle_timeout"`
 	GracefulShutdown time.Duration `yaml:"graceful_shutdown"`
+	// StreamDrainTimeout is how long shutdown waits for in-flight SSE streams
+	// to complete before cutting them with a final error event.
+	StreamDrainTimeout time.Duration `yaml:"stream_drain_timeout"`
+	// StreamKeepAliveInterval is how long a client stream may sit idle before
+	// the gateway sends an SSE ": ping" comment, so proxies and load balancers
+	// do not drop it during long provider silences. Zero disables pings.
+	StreamKeepAliveInterval time.Duration `yaml:"stream_keepalive_interval"`
+	// MaxInFlight caps concurrent API requests (streams included); beyond it
+	// requests get a fast 503 with Retry-After. Health and admin endpoints are
+	// exempt so they stay responsive under overload. Zero disables the cap.
+	MaxInFlight        int           `yaml:"max_in_flight"`
+	LoadShedRetryAfter time.Duration `yaml:"load_shed_retry_after"`
+	// BatchInFlightRatio is the share of max_in_flight that batch-priority
+	// requests may fill; the rest is kept for interactive traffic, and batch
+	// workers stop claiming work once it is reached.
+	BatchInFlightRatio float64 `yaml:"batch_in_flight_ratio"`
+	// GRPCPort serves the native gRPC ingress (aegis.gateway.v1) on the same
+	// host. Zero disables it.
+	GRPCPort int `yaml:"grpc_port"`
 }
 
 type DatabaseConfig struct {
@@ -29,10 +89,43 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `yaml:"max_open_conns"`
 	MaxIdleConns    int           `yaml:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+	// HealthCheckPeriod is how often idle connections are checked and the
+	// pool is topped back up to max_idle_conns.
+	HealthCheckPeriod time.Duration `yaml:"health_check_period"`
+
+	// SSLMode is a libpq sslmode: disable, allow, prefer, require,
+	// verify-ca, or verify-full. Empty means disable.
+	SSLMode     string `yaml:"sslmode"`
+	SSLRootCert string `yaml:"sslrootcert"` // CA bundle for verify-ca/verify-full
+	SSLCert     string `yaml:"sslcert"`     // client certificate for mutual TLS
+	SSLKey      string `yaml:"sslkey"`
 }
 
 func (d DatabaseConfig) DSN() string {
-	return "postgres://" + d.User + ":" + d.Password + "@" + d.Host + ":" + itoa(d.Port) + "/" + d.Name + "?sslmode=disable"
+	q := url.Values{}
+	sslmode := d.SSLMode
+	if sslmode == "" {
+		sslmode = "disable"
+	}
+	q.Set("sslmode", sslmode)
+	if d.SSLRootCert != "" {
+		q.Set("sslrootcert", d.SSLRootCert)
+	}
+	if d.SSLCert != "" {
+		q.Set("sslcert", d.SSLCert)
+	}
+	if d.SSLKey != "" {
+		q.Set("sslkey", d.SSLKey)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(d.User, d.Password),
+		Host:     d.Host + ":" + itoa(d.Port),
+		Path:     "/" + d.Name,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
 }
 
 func itoa(i int) string {
@@ -59,7 +152,25 @@ type TelemetryConfig struct {
 	LogFormat       string  `yaml:"log_format"`
 	MetricsPort     int     `yaml:"metrics_port"`
 	OTLPEndpoint    string  `yaml:"otlp_endpoint"`
+	// TraceSampleRate is the fraction of requests, 0 to 1, whose verbose
+	// completion logs and payload archives are kept. Metrics and usage
+	// records cover every request regardless.
 	TraceSampleRate float64 `yaml:"trace_sample_rate"`
+	// DebugEndpoints exposes /debug/pprof and /debug/vars on the metrics listener.
+	DebugEndpoints bool `yaml:"debug_endpoints"`
+	// MetricLabels bounds the values of the org, team, and model metric
+	// labels, keyed by label name. Read at startup.
+	MetricLabels map[string]MetricLabelConfig `yaml:"metric_labels"`
+}
+
+// MetricLabelConfig limits the cardinality of one metric label.
+type MetricLabelConfig struct {
+	// Mode is "keep" (the default), "drop" to record the label empty,
+	// "hash" to record one of Buckets hash buckets, or "top_n" to record
+	// the first TopN distinct values seen and the rest as "other".
+	Mode    string `yaml:"mode"`
+	Buckets int    `yaml:"buckets"`
+	TopN    int    `yaml:"top_n"`
 }
 
 type FilterConfig struct {
@@ -67,6 +178,93 @@ type FilterConfig struct {
 	Secrets    SecretsFilterConfig    `yaml:"secrets"`
 	Injection  InjectionFilterConfig  `yaml:"injection"`
 	Policy     PolicyFilterConfig     `yaml:"policy"`
+	// Classification estimates the classification of prompt content and
+	// reports content above the key's declared max classification.
+	Classification ClassificationDetectionConfig `yaml:"classification"`
+	// BypassMaxDuration caps how long a break-glass filter bypass grant may
+	// last. Zero disables grants.
+	BypassMaxDuration time.Duration `yaml:"bypass_max_duration"`
+	// OrgFailOpen overrides each filter's fail_open for an organization,
+	// keyed by org ID and then filter name.
+	OrgFailOpen map[string]map[string]bool `yaml:"org_fail_open"`
+	// BlockDetails adds the redacted findings behind a content filter block
+	// (types, counts, and positions, never matched text) to the 451 error's
+	// details.
+	BlockDetails bool `yaml:"block_details"`
+	// OrgBlockDetails overrides BlockDetails for an organization, e.g. one
+	// that prefers opaque errors.
+	OrgBlockDetails map[string]bool `yaml:"org_block_details"`
+	// BlockResponse is what a blocked request gets back.
+	BlockResponse BlockResponseConfig `yaml:"block_response"`
+	// OrgBlockResponse overrides BlockResponse for an organization.
+	OrgBlockResponse map[string]BlockResponseConfig `yaml:"org_block_response"`
+}
+
+// Block response modes.
+const (
+	// BlockResponseError answers a blocked request with a 451 error.
+	BlockResponseError = "error"
+	// BlockResponseMessage answers with a chat completion whose assistant
+	// message is Message, so chat UIs render the block as a reply.
+	BlockResponseMessage = "message"
+	// BlockResponseRedirect sends a request blocked by a content filter to
+	// Model, an internally hosted model trusted with the content. Policy
+	// denials are decided for the routed provider and still get the error.
+	BlockResponseRedirect = "redirect"
+)
+
+// BlockResponseConfig selects what a blocked request returns.
+type BlockResponseConfig struct {
+	Mode    string `yaml:"mode"`
+	Message string `yaml:"message"`
+	Model   string `yaml:"model"`
+}
+
+// FilterNames lists the filters whose failure handling is configurable.
+var FilterNames = []string{"secrets", "injection", "pii", "classification", "policy"}
+
+// FailOpen reports whether a failed evaluation of the named filter lets
+// org's request through: the org's org_fail_open entry if it has one,
+// otherwise the filter's own fail_open.
+func (c FilterConfig) FailOpen(filter, org string) bool {
+	if v, ok := c.OrgFailOpen[org][filter]; ok {
+		return v
+	}
+	switch filter {
+	case "secrets":
+		return c.Secrets.FailOpen
+	case "injection":
+		return c.Injection.FailOpen
+	case "pii":
+		return c.PIIService.FailOpen
+	case "classification":
+		return c.Classification.FailOpen
+	case "policy":
+		return c.Policy.FailOpen
+	}
+	return false
+}
+
+// ShowBlockDetails reports whether org's 451 errors carry block findings:
+// the org's org_block_details entry if it has one, otherwise block_details.
+func (c FilterConfig) ShowBlockDetails(org string) bool {
+	if v, ok := c.OrgBlockDetails[org]; ok {
+		return v
+	}
+	return c.BlockDetails
+}
+
+// BlockResponseFor returns org's block response: its org_block_response
+// entry if it has one, taking block_response's message when it sets none,
+// otherwise block_response.
+func (c FilterConfig) BlockResponseFor(org string) BlockResponseConfig {
+	if v, ok := c.OrgBlockResponse[org]; ok {
+		if v.Message == "" {
+			v.Message = c.BlockResponse.Message
+		}
+		return v
+	}
+	return c.BlockResponse
 }
 
 type PIIServiceConfig struct {
@@ -77,29 +275,208 @@ type PIIServiceConfig struct {
 	FailOpen   bool          `yaml:"fail_open"`
 }
 
+// ClassificationDetectionConfig configures the advisory classifier, which
+// asks the filter service's ClassifyContent RPC to estimate the
+// classification of each prompt.
+type ClassificationDetectionConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address"` // filter service gRPC address, normally the PII service
+	// Enforce blocks mismatched requests; otherwise they are only flagged.
+	Enforce bool `yaml:"enforce"`
+	// MinConfidence is the classifier confidence below which an estimate is
+	// ignored.
+	MinConfidence float64       `yaml:"min_confidence"`
+	Timeout       time.Duration `yaml:"timeout"`
+	// FailOpen lets requests through when the classifier is unreachable.
+	FailOpen bool `yaml:"fail_open"`
+}
+
 type SecretsFilterConfig struct {
-	Enabled bool `yaml:"enabled"`
+	Enabled  bool `yaml:"enabled"`
+	FailOpen bool `yaml:"fail_open"`
 }
 
 type InjectionFilterConfig struct {
 	Enabled        bool    `yaml:"enabled"`
 	BlockThreshold float64 `yaml:"block_threshold"`
 	FlagThreshold  float64 `yaml:"flag_threshold"`
+	// FailOpen keeps the regex verdict when the similarity check fails;
+	// otherwise such requests are blocked.
+	FailOpen bool `yaml:"fail_open"`
+	// Similarity compares prompt embeddings against known jailbreaks to
+	// catch rephrasings the regex rules miss.
+	Similarity InjectionSimilarityConfig `yaml:"similarity"`
+}
+
+// InjectionSimilarityConfig configures embedding-based jailbreak detection.
+// User messages are embedded through an OpenAI-compatible embeddings
+// endpoint and compared by cosine similarity with a curated vector set.
+type InjectionSimilarityConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Threshold is the cosine similarity at or above which a prompt matches.
+	Threshold float64 `yaml:"threshold"`
+	// Block rejects matching prompts instead of flagging them.
+	Block bool `yaml:"block"`
+
+	EmbeddingURL    string        `yaml:"embedding_url"` // e.g. https://api.openai.com/v1/embeddings
+	EmbeddingModel  string        `yaml:"embedding_model"`
+	EmbeddingAPIKey string        `yaml:"embedding_api_key"`
+	Timeout         time.Duration `yaml:"timeout"`
+
+	// Store is "redis" (vectors in the RedisKey hash, cached in memory for
+	// RefreshInterval) or "pgvector" (nearest neighbour queried in PGTable).
+	Store           string        `yaml:"store"`
+	RedisKey        string        `yaml:"redis_key"`
+	PGTable         string        `yaml:"pg_table"`
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// SeedFile is a JSONL file of {"id", "category", "text"} known
+	// jailbreaks, embedded and upserted into the store at startup.
+	SeedFile string `yaml:"seed_file"`
 }
 
 type PolicyFilterConfig struct {
 	Enabled           bool          `yaml:"enabled"`
 	BundlePath        string        `yaml:"bundle_path"`
 	EvaluationTimeout time.Duration `yaml:"evaluation_timeout"`
+	// DecisionCacheTTL is how long a decision is reused for requests whose
+	// input matches in every field the policies read. Policies that read
+	// message content are never cached. Zero disables the cache.
+	DecisionCacheTTL time.Duration `yaml:"decision_cache_ttl"`
+	// FailOpen lets requests through when no policies are loaded or
+	// evaluation errors or times out.
+	FailOpen bool `yaml:"fail_open"`
 }
 
 type RoutingConfig struct {
+	// Strategy orders each model's routes; see RoutingStrategyPriority and
+	// RoutingStrategyCheapest. It can be changed at runtime.
+	Strategy                string             `yaml:"strategy"`
 	DefaultTimeout          time.Duration      `yaml:"default_timeout"`
 	StreamFirstChunkTimeout time.Duration      `yaml:"stream_first_chunk_timeout"`
 	StreamChunkTimeout      time.Duration      `yaml:"stream_chunk_timeout"`
 	MaxRetries              int                `yaml:"max_retries"`
 	CircuitBreaker          CircuitBreakerConfig `yaml:"circuit_breaker"`
 	HealthCheckInterval     time.Duration      `yaml:"health_check_interval"`
+	// QuotaHeadroom is the fraction of a provider's upstream requests or
+	// tokens, per its rate limit response headers, below which it is tried
+	// after routes with more room. Zero disables.
+	QuotaHeadroom float64 `yaml:"quota_headroom"`
+	// AccessWindows closes models to organizations on a schedule.
+	AccessWindows []AccessWindowConfig `yaml:"access_windows"`
+	// Sticky routes later turns of a conversation to the provider that
+	// served the earlier ones.
+	Sticky StickyRoutingConfig `yaml:"sticky"`
+}
+
+// StickyRoutingConfig controls per-conversation provider affinity. Requests
+// carrying X-Aegis-Conversation-ID or X-Aegis-Affinity-Key go back to the
+// provider that last served the same key and model while it is eligible and
+// healthy, keeping behaviour consistent and provider prompt caches warm.
+// Needs Redis.
+type StickyRoutingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL is how long an affinity lasts after the turn that last used it.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// Routing strategies.
+const (
+	// RoutingStrategyPriority tries a model's primary, then its fallbacks in
+	// the order models.yaml lists them.
+	RoutingStrategyPriority = "priority"
+	// RoutingStrategyCheapest tries a model's routes from lowest to highest
+	// price in models.yaml. Routes without a price come last, in the order
+	// models.yaml lists them.
+	RoutingStrategyCheapest = "cheapest"
+)
+
+// Access window types.
+const (
+	// AccessWindowMaintenance rejects requests while the window is open.
+	AccessWindowMaintenance = "maintenance"
+	// AccessWindowOfficeHours rejects requests while the window is closed.
+	// Where several office-hours windows apply, being inside any one of
+	// them is enough.
+	AccessWindowOfficeHours = "office_hours"
+)
+
+// AccessWindowConfig is a maintenance window or office-hours restriction. It
+// either recurs on Days from Start to End in Timezone, or, for a one-off
+// maintenance window, runs from StartsAt to EndsAt.
+type AccessWindowConfig struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"` // "maintenance" or "office_hours"
+	// Organizations and Models limit the window to the listed org IDs and
+	// model names. Empty means all.
+	Organizations []string `yaml:"organizations"`
+	Models        []string `yaml:"models"`
+	// Timezone is an IANA zone name such as "Europe/Berlin"; default UTC.
+	Timezone string `yaml:"timezone"`
+	// Days lists weekdays ("Mon".."Sun") the window starts on. Empty means
+	// every day.
+	Days []string `yaml:"days"`
+	// Start and End are "HH:MM" local times; an End at or before Start runs
+	// past midnight into the next day.
+	Start string `yaml:"start"`
+	End   string `yaml:"end"`
+	// StartsAt and EndsAt bound a one-off window instead.
+	StartsAt time.Time `yaml:"starts_at"`
+	EndsAt   time.Time `yaml:"ends_at"`
+	// Message replaces the default error returned to rejected requests.
+	Message string `yaml:"message"`
+}
+
+// Applies reports whether the window covers requests from org for model.
+func (w AccessWindowConfig) Applies(org, model string) bool {
+	return (len(w.Organizations) == 0 || slices.Contains(w.Organizations, org)) &&
+		(len(w.Models) == 0 || slices.Contains(w.Models, model))
+}
+
+// Location returns the window's time zone, UTC if unset or unknown.
+func (w AccessWindowConfig) Location() *time.Location {
+	if w.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// OnDay reports whether the window starts on day.
+func (w AccessWindowConfig) OnDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if wd, ok := ParseWeekday(d); ok && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWeekday parses a weekday name, full or abbreviated to three letters,
+// in any case.
+func ParseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// ParseClock parses an "HH:MM" time of day into minutes after midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
 }
 
 type CircuitBreakerConfig struct {
@@ -107,26 +484,378 @@ type CircuitBreakerConfig struct {
 	ErrorRateThreshold    float64       `yaml:"error_rate_threshold"`
 	ErrorRateWindow       time.Duration `yaml:"error_rate_window"`
 	RecoveryProbeInterval time.Duration `yaml:"recovery_probe_interval"`
+	// Shared publishes breaker trips and recoveries over Redis so every
+	// replica opens and closes a provider's breaker together. Read at startup.
+	Shared bool `yaml:"shared"`
+}
+
+// ArchiveConfig controls optional archival of redacted request/response
+// payloads to S3-compatible object storage.
+type ArchiveConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	Endpoint        string        `yaml:"endpoint"`
+	Region          string        `yaml:"region"`
+	Bucket          string        `yaml:"bucket"`
+	Prefix          string        `yaml:"prefix"`
+	AccessKeyID     string        `yaml:"access_key_id"`
+	SecretAccessKey string        `yaml:"secret_access_key"`
+	Timeout         time.Duration `yaml:"timeout"`
+	// Organizations limits archival to the listed org IDs. Empty means all orgs.
+	Organizations []string `yaml:"organizations"`
+	// Classifications limits archival to the listed data classifications.
+	// Empty means all classifications.
+	Classifications []string `yaml:"classifications"`
+}
+
+// EventsConfig controls export of structured gateway events to a message bus.
+type EventsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Backend string `yaml:"backend"` // "kafka", "nats", or "webhook"
+	// Brokers lists Kafka bootstrap addresses (kafka backend).
+	Brokers []string `yaml:"brokers"`
+	// URL is the NATS server URL (nats backend).
+	URL string `yaml:"url"`
+	// Topic is the Kafka topic or NATS subject events are published to.
+	Topic          string        `yaml:"topic"`
+	BufferSize     int           `yaml:"buffer_size"`
+	PublishTimeout time.Duration `yaml:"publish_timeout"`
+	// Webhooks lists the endpoints every event is POSTed to (webhook
+	// backend).
+	Webhooks []WebhookConfig `yaml:"webhooks"`
+}
+
+// WebhookConfig is one webhook endpoint. Deliveries carry a timestamp, a
+// nonce, and an HMAC-SHA256 signature under Secret so the receiver can
+// verify they came from the gateway.
+type WebhookConfig struct {
+	URL    string `yaml:"url"`
+	Secret string `yaml:"secret"`
+}
+
+// AdminConfig controls access to operational endpoints such as /aegis/v1/status.
+type AdminConfig struct {
+	// KeyIDs lists API key IDs allowed to call admin endpoints. Empty denies all.
+	KeyIDs []string `yaml:"key_ids"`
+}
+
+// SIEMConfig controls the dedicated security event stream (filter blocks, auth
+// failures, policy denials, classification violations) fed to SIEM tooling.
+type SIEMConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Format  string `yaml:"format"` // "json" or "cef"
+	// Output is "stdout", "stderr", "syslog", or a file path.
+	Output string `yaml:"output"`
+	// SyslogNetwork and SyslogAddress select a remote syslog server
+	// (e.g. "udp", "siem.internal:514"); empty uses the local daemon.
+	SyslogNetwork string `yaml:"syslog_network"`
+	SyslogAddress string `yaml:"syslog_address"`
+	SyslogTag     string `yaml:"syslog_tag"`
+}
+
+// SecretsConfig controls refresh of secret references (e.g. vault://...) used
+// in place of literal credentials. Backends are configured from the
+// environment (VAULT_ADDR, VAULT_TOKEN) since this file may itself hold refs.
+type SecretsConfig struct {
+	// RefreshInterval is how often references are re-resolved to pick up
+	// rotated values. Zero disables refresh.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// LimitsConfig bounds request size. Limits are enforced before the body is
+// fully buffered or any filter runs; violations return 413. Zero disables a limit.
+type LimitsConfig struct {
+	MaxBodyBytes     int64 `yaml:"max_body_bytes"`
+	MaxMessages      int   `yaml:"max_messages"`
+	MaxMessageLength int   `yaml:"max_message_length"` // characters per message
+	MaxJSONDepth     int   `yaml:"max_json_depth"`
+	// TruncateContextTeams lists team IDs whose requests exceeding a
+	// model's context_window have their oldest non-system messages dropped
+	// instead of being rejected.
+	TruncateContextTeams []string `yaml:"truncate_context_teams"`
+	// MaxCompareModels caps how many models one /aegis/v1/compare request
+	// may fan out to.
+	MaxCompareModels int `yaml:"max_compare_models"`
+	// StrictFields rejects chat completion requests with top-level fields
+	// the gateway does not know, listing them, instead of ignoring them.
+	StrictFields bool `yaml:"strict_fields"`
+	// DisallowUnknownFields also rejects unknown fields nested in the
+	// request, reporting the first one found.
+	DisallowUnknownFields bool `yaml:"disallow_unknown_fields"`
+}
+
+// UsageConfig tunes the async writer for the request_usage ledger. Records
+// are buffered and written in batches; when the buffer is full, callers wait
+// up to EnqueueTimeout before the record is dropped and counted.
+type UsageConfig struct {
+	BufferSize     int           `yaml:"buffer_size"`
+	BatchSize      int           `yaml:"batch_size"`
+	FlushInterval  time.Duration `yaml:"flush_interval"`
+	EnqueueTimeout time.Duration `yaml:"enqueue_timeout"`
+}
+
+// RetentionConfig controls the janitor that prunes old rows and stale Redis
+// buckets. A zero retention keeps that data forever.
+type RetentionConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Interval time.Duration `yaml:"interval"`
+	// UsageRetention applies to request_usage and the legacy usage tables.
+	// Keep it at least as long as the billing period.
+	UsageRetention time.Duration `yaml:"usage_retention"`
+	AuditRetention time.Duration `yaml:"audit_retention"`
+	// ExpiredKeyRetention is how long expired or revoked API keys are kept
+	// before deletion. Keys still referenced by audit_logs are kept.
+	ExpiredKeyRetention time.Duration `yaml:"expired_key_retention"`
+	// BatchRetention is how long finished batch jobs and their results are
+	// kept.
+	BatchRetention time.Duration `yaml:"batch_retention"`
+	// ConversationRetention is how long a conversation is kept after its
+	// last turn.
+	ConversationRetention time.Duration `yaml:"conversation_retention"`
+	// BatchSize bounds rows deleted per statement so pruning never holds
+	// long locks on hot tables.
+	BatchSize int `yaml:"batch_size"`
+}
+
+// BatchConfig tunes asynchronous batch jobs. Workers and MaxConcurrentPerOrg
+// apply per replica and are read at startup; the rest follow hot reload.
+type BatchConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Workers is how many batch requests a replica runs at once. Keep it
+	// small so batches do not compete with interactive traffic.
+	Workers             int `yaml:"workers"`
+	MaxConcurrentPerOrg int `yaml:"max_concurrent_per_org"`
+	// MaxRequests and MaxBodyBytes bound one submitted JSONL file.
+	MaxRequests  int   `yaml:"max_requests"`
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+	// CompletionWindow is how long a job may run; requests still pending
+	// after it fail with batch_expired.
+	CompletionWindow time.Duration `yaml:"completion_window"`
+	PollInterval     time.Duration `yaml:"poll_interval"`
+	// StaleAfter returns requests claimed by a replica that died mid-request
+	// to the queue. Keep it above the longest provider timeout.
+	StaleAfter time.Duration `yaml:"stale_after"`
+}
+
+// Hook types and stages.
+const (
+	HookTypeHTTP = "http" // external service called over HTTP
+	HookTypeGo   = "go"   // compiled-in hook registered under the same name
+
+	HookStageRequest  = "request"
+	HookStageResponse = "response"
+)
+
+// HookConfig configures one transformation hook. Hooks run in ascending
+// Order (ties keep file order); request hooks see the canonical request
+// after validation and the filter chain, before routing, and response hooks
+// see complete responses, streamed or not, before they are returned.
+type HookConfig struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"` // "http" or "go"
+	Enabled bool   `yaml:"enabled"`
+	Order   int    `yaml:"order"`
+	// Stages lists "request" and/or "response". Empty means both.
+	Stages []string `yaml:"stages"`
+	// Organizations limits the hook to the listed org IDs. Empty means all orgs.
+	Organizations []string `yaml:"organizations"`
+	// URL and Timeout apply to http hooks.
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+	// FailOpen passes the request or response through unchanged when the
+	// hook errors; otherwise the request fails with 502.
+	FailOpen bool `yaml:"fail_open"`
+}
+
+// RunsAt reports whether the hook is configured for the given stage.
+func (h HookConfig) RunsAt(stage string) bool {
+	if len(h.Stages) == 0 {
+		return true
+	}
+	for _, s := range h.Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// GuardrailsConfig holds response guardrail rules. Every rule matching a
+// request's org applies, in file order, after any response hooks.
+type GuardrailsConfig struct {
+	Response []GuardrailRule `yaml:"response"`
+}
+
+// GuardrailRule post-processes assistant output for a set of organizations.
+// Streaming responses it applies to are buffered and sent once complete.
+type GuardrailRule struct {
+	Name string `yaml:"name"`
+	// Organizations limits the rule to the listed org IDs. Empty means all orgs.
+	Organizations []string `yaml:"organizations"`
+	// ClassificationBanner adds BannerTemplate, with {classification}
+	// replaced by the request's data classification, at BannerPosition
+	// ("top" or "bottom", default bottom).
+	ClassificationBanner bool   `yaml:"classification_banner"`
+	BannerTemplate       string `yaml:"banner_template"`
+	BannerPosition       string `yaml:"banner_position"`
+	// StripExternalLinks replaces markdown links and images pointing outside
+	// InternalDomains (subdomains included) with their text.
+	StripExternalLinks bool     `yaml:"strip_external_links"`
+	InternalDomains    []string `yaml:"internal_domains"`
+}
+
+// ConversationsConfig controls tracking of multi-turn sessions sent with an
+// X-Aegis-Conversation-ID header. It needs migration 009.
+type ConversationsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxTurns, MaxTokens and MaxCostUSD cap one conversation; further
+	// turns get 402. Zero disables a cap.
+	MaxTurns   int     `yaml:"max_turns"`
+	MaxTokens  int64   `yaml:"max_tokens"`
+	MaxCostUSD float64 `yaml:"max_cost_usd"`
+}
+
+// TenancyConfig controls isolation between organizations. Strict is read at
+// startup; changing it moves per-org Redis state to new keys, so counters
+// restart from the durable ledger.
+type TenancyConfig struct {
+	// Strict keeps every organization's per-tenant state apart: rate limit,
+	// budget, and idempotency keys live under aegis:org:<org>:, request
+	// metrics all carry the org label, and admin changes to a tenant's keys
+	// are audited under that tenant rather than the admin's org.
+	Strict bool `yaml:"strict"`
+}
+
+// AuthConfig controls the in-process cache of API key metadata kept in front
+// of Redis. Evictions reach every gateway over Redis pub/sub; LocalCacheTTL
+// bounds staleness if one is missed. A size of 0 disables the cache.
+type AuthConfig struct {
+	LocalCacheSize int           `yaml:"local_cache_size"`
+	LocalCacheTTL  time.Duration `yaml:"local_cache_ttl"`
+}
+
+// EgressConfig is a safety net against a tampered providers.yaml: with an
+// allowlist set, providers whose base_url is not on it are disabled and no
+// provider connection is made to a destination off it. It lives in
+// gateway.yaml so that changing providers.yaml alone cannot widen it.
+type EgressConfig struct {
+	// Allowlist holds hostnames, "*.domain" wildcards, IPs, and CIDRs.
+	// Empty allows every destination.
+	Allowlist []string `yaml:"allowlist"`
+}
+
+// DryRunConfig replaces every provider call with a canned response in the
+// provider's own format. Requests are still authenticated, filtered, routed,
+// logged, and metered, so staging can be load-tested and config changes
+// checked without spending tokens. Token counts are estimated from the size
+// of the request and of Response.
+type DryRunConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Response is the assistant message content returned.
+	Response string `yaml:"response"`
+	// Latency is waited before answering, to simulate a provider.
+	Latency time.Duration `yaml:"latency"`
+}
+
+// Fixture modes.
+const (
+	FixturesRecord = "record"
+	FixturesReplay = "replay"
+)
+
+// FixturesConfig records provider request/response pairs, sanitized, under
+// Dir, or replays them from there without calling providers, for
+// reproducible regression tests of the adapters. Requests with no recorded
+// fixture fail in replay mode.
+type FixturesConfig struct {
+	// Mode is "record", "replay", or empty for off.
+	Mode string `yaml:"mode"`
+	Dir  string `yaml:"dir"`
+}
+
+// ChaosConfig injects faults into provider calls, so circuit breakers,
+// retries, and failover can be exercised in staging under controlled
+// failure. Nothing is injected unless Enabled is set.
+type ChaosConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Providers maps provider names from providers.yaml to their faults.
+	Providers map[string]ChaosFaultConfig `yaml:"providers"`
+}
+
+// ChaosFaultConfig is the faults injected into one provider's calls. Rates
+// are probabilities from 0 to 1, drawn independently per call or chunk.
+type ChaosFaultConfig struct {
+	// Latency is the most added before a call; each call waits a uniformly
+	// random duration up to it.
+	Latency time.Duration `yaml:"latency"`
+	// ErrorRate is the share of calls answered with ErrorStatus instead of
+	// reaching the provider. ErrorStatus defaults to 503.
+	ErrorRate   float64 `yaml:"error_rate"`
+	ErrorStatus int     `yaml:"error_status"`
+	// MalformedChunkRate is the share of stream chunks replaced with
+	// invalid JSON.
+	MalformedChunkRate float64 `yaml:"malformed_chunk_rate"`
+}
+
+// IdempotencyConfig controls how long responses to requests carrying an
+// Idempotency-Key are kept for replay to retries.
+type IdempotencyConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	TTL     time.Duration `yaml:"ttl"`
+}
+
+// ResponseCacheConfig controls the response cache. Entries are kept per
+// organization for TTL, keyed by the request's content hash, classification,
+// and route, are served only once routing and policy admit a request, and
+// are filled from non-streaming completions; stream=true requests that hit
+// one get it replayed as SSE, ReplayChunkSize runes of content per chunk (0
+// for one chunk per choice) every ReplayInterval (0 for no delay).
+type ResponseCacheConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	TTL             time.Duration `yaml:"ttl"`
+	ReplayChunkSize int           `yaml:"replay_chunk_size"`
+	ReplayInterval  time.Duration `yaml:"replay_interval"`
+}
+
+// CORSConfig controls cross-origin access so browser-based internal UIs can
+// call the gateway directly.
+type CORSConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowedOrigins lists exact origins ("https://chat.internal"), subdomain
+	// wildcards ("https://*.corp.example"), or "*" for any origin.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// AllowedHeaders lists request headers browsers may send; "*" allows any.
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	// ExposedHeaders lists response headers scripts may read.
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Host:             "0.0.0.0",
-			Port:             8080,
-			ReadTimeout:      30 * time.Second,
-			WriteTimeout:     120 * time.Second,
-			IdleTimeout:      120 * time.Second,
-			GracefulShutdown: 30 * time.Second,
+			Host:                    "0.0.0.0",
+			Port:                    8080,
+			ReadTimeout:             30 * time.Second,
+			WriteTimeout:            120 * time.Second,
+			IdleTimeout:             120 * time.Second,
+			GracefulShutdown:        30 * time.Second,
+			StreamDrainTimeout:      60 * time.Second,
+			StreamKeepAliveInterval: 15 * time.Second,
+			LoadShedRetryAfter:      time.Second,
+			BatchInFlightRatio:      0.8,
 		},
 		Database: DatabaseConfig{
-			Host:            "localhost",
-			Port:            5432,
-			Name:            "aegis",
-			User:            "aegis",
-			MaxOpenConns:    25,
-			MaxIdleConns:    10,
-			ConnMaxLifetime: 5 * time.Minute,
+			Host:              "localhost",
+			Port:              5432,
+			Name:              "aegis",
+			User:              "aegis",
+			MaxOpenConns:      25,
+			MaxIdleConns:      10,
+			ConnMaxLifetime:   5 * time.Minute,
+			ConnMaxIdleTime:   30 * time.Minute,
+			HealthCheckPeriod: time.Minute,
+			SSLMode:           "disable",
 		},
 		Redis: RedisConfig{
 			Addresses: []string{"localhost:6379"},
@@ -137,7 +866,7 @@ func DefaultConfig() *Config {
 			LogLevel:        "info",
 			LogFormat:       "json",
 			MetricsPort:     9090,
-			TraceSampleRate: 0.1,
+			TraceSampleRate: 1,
 		},
 		Filter: FilterConfig{
 			PIIService: PIIServiceConfig{
@@ -145,19 +874,42 @@ func DefaultConfig() *Config {
 				Timeout:    5 * time.Second,
 				MaxRetries: 1,
 			},
+			Classification: ClassificationDetectionConfig{
+				Address:       "aegis-filter-nlp:50051",
+				MinConfidence: 0.7,
+				Timeout:       2 * time.Second,
+				FailOpen:      true,
+			},
 			Secrets: SecretsFilterConfig{Enabled: true},
 			Injection: InjectionFilterConfig{
 				Enabled:        true,
 				BlockThreshold: 0.9,
 				FlagThreshold:  0.7,
+				FailOpen:       true,
+				Similarity: InjectionSimilarityConfig{
+					Threshold:       0.88,
+					Timeout:         500 * time.Millisecond,
+					Store:           "redis",
+					RedisKey:        "aegis:injection:jailbreaks",
+					PGTable:         "jailbreak_embeddings",
+					RefreshInterval: 5 * time.Minute,
+				},
 			},
 			Policy: PolicyFilterConfig{
 				Enabled:           true,
 				BundlePath:        "/etc/aegis/policies",
 				EvaluationTimeout: 100 * time.Millisecond,
+				DecisionCacheTTL:  30 * time.Second,
+			},
+			BypassMaxDuration: 24 * time.Hour,
+			BlockDetails:      true,
+			BlockResponse: BlockResponseConfig{
+				Mode:    BlockResponseError,
+				Message: "I can't help with that request because it was blocked by your organization's content policy.",
 			},
 		},
 		Routing: RoutingConfig{
+			Strategy:                RoutingStrategyPriority,
 			DefaultTimeout:          30 * time.Second,
 			StreamFirstChunkTimeout: 60 * time.Second,
 			StreamChunkTimeout:      10 * time.Second,
@@ -169,6 +921,87 @@ func DefaultConfig() *Config {
 				RecoveryProbeInterval: 15 * time.Second,
 			},
 			HealthCheckInterval: 10 * time.Second,
+			QuotaHeadroom:       0.05,
+			Sticky:              StickyRoutingConfig{TTL: time.Hour},
+		},
+		Archive: ArchiveConfig{
+			Region:  "us-east-1",
+			Prefix:  "aegis",
+			Timeout: 10 * time.Second,
+		},
+		Events: EventsConfig{
+			Backend:        "kafka",
+			Topic:          "aegis.gateway.events",
+			BufferSize:     1024,
+			PublishTimeout: 5 * time.Second,
+		},
+		SIEM: SIEMConfig{
+			Format:    "json",
+			Output:    "stdout",
+			SyslogTag: "aegis-gateway",
+		},
+		Secrets: SecretsConfig{
+			RefreshInterval: 5 * time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedHeaders: []string{
+				"Authorization", "Content-Type", "Accept", "Cache-Control", "Last-Event-ID",
+				"X-Request-ID", "X-Aegis-Project", "X-Aegis-Prefer-Provider", "X-Aegis-Trace-Context", "traceparent",
+				"Idempotency-Key", "X-Aegis-Priority", "X-Aegis-Conversation-ID",
+			},
+			ExposedHeaders: []string{
+				"X-Request-ID", "X-Aegis-Cost-USD", "X-Aegis-Tokens-Prompt", "X-Aegis-Tokens-Completion",
+				"X-Aegis-Provider", "X-Aegis-Model-Served", "X-Aegis-Provider-Latency-Ms",
+				"Idempotent-Replayed", "X-Aegis-Context-Truncated", "X-Aegis-Request-Hash",
+				"X-Aegis-Dry-Run", "X-Aegis-Cache",
+			},
+			MaxAge: 10 * time.Minute,
+		},
+		Limits: LimitsConfig{
+			MaxBodyBytes:     10 << 20,
+			MaxMessages:      1000,
+			MaxMessageLength: 100000,
+			MaxJSONDepth:     32,
+			MaxCompareModels: 5,
+		},
+		Usage: UsageConfig{
+			BufferSize:     10000,
+			BatchSize:      500,
+			FlushInterval:  time.Second,
+			EnqueueTimeout: 50 * time.Millisecond,
+		},
+		Retention: RetentionConfig{
+			Interval:              time.Hour,
+			UsageRetention:        400 * 24 * time.Hour,
+			AuditRetention:        90 * 24 * time.Hour,
+			ExpiredKeyRetention:   30 * 24 * time.Hour,
+			BatchRetention:        30 * 24 * time.Hour,
+			ConversationRetention: 90 * 24 * time.Hour,
+			BatchSize:             5000,
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled: true,
+			TTL:     10 * time.Minute,
+		},
+		ResponseCache: ResponseCacheConfig{
+			TTL:             time.Hour,
+			ReplayChunkSize: 16,
+		},
+		DryRun: DryRunConfig{
+			Response: "This is a dry-run response from AEGIS; no provider was called.",
+		},
+		Auth: AuthConfig{
+			LocalCacheSize: 10000,
+			LocalCacheTTL:  30 * time.Second,
+		},
+		Batch: BatchConfig{
+			Workers:             4,
+			MaxConcurrentPerOrg: 2,
+			MaxRequests:         10000,
+			MaxBodyBytes:        100 << 20,
+			CompletionWindow:    24 * time.Hour,
+			PollInterval:        2 * time.Second,
+			StaleAfter:          15 * time.Minute,
 		},
 	}
 }
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// historySize is how many distinct config versions are kept for rollback.
+const historySize = 10
+
+// VersionInfo describes one config version held in memory.
+type VersionInfo struct {
+	Version   string           `json:"version"`
+	LoadedAt  time.Time        `json:"loaded_at"`
+	Current   bool             `json:"current"`
+	Overrides RuntimeOverrides `json:"overrides"`
+}
+
+// recordHistory appends snap to the version history. Reloading an identical
+// version moves it to the end instead of adding a duplicate.
+func (l *Loader) recordHistory(snap *snapshot) {
+	l.historyMu.Lock()
+	defer l.historyMu.Unlock()
+
+	for i, s := range l.history {
+		if s.version == snap.version {
+			l.history = append(l.history[:i], l.history[i+1:]...)
+			break
+		}
+	}
+	l.history = append(l.history, snap)
+	if len(l.history) > historySize {
+		l.history = l.history[len(l.history)-historySize:]
+	}
+}
+
+// History returns the config versions available for rollback, newest first.
+func (l *Loader) History() []VersionInfo {
+	current := l.Version()
+
+	l.historyMu.Lock()
+	defer l.historyMu.Unlock()
+
+	out := make([]VersionInfo, 0, len(l.history))
+	for i := len(l.history) - 1; i >= 0; i-- {
+		s := l.history[i]
+		out = append(out, VersionInfo{
+			Version:   s.version,
+			LoadedAt:  s.loadedAt,
+			Current:   s.version == current,
+			Overrides: s.overrides,
+		})
+	}
+	return out
+}
+
+// Rollback reinstalls a previously loaded config version, including the
+// runtime overrides it was loaded with, and notifies OnReload callbacks. Its
+// secret references are resolved again, so the rollback does not bring back
+// credentials rotated since. The config files are not touched: the version
+// stays in effect through secret rotations until the next file change
+// reloads from disk as usual.
+func (l *Loader) Rollback(version string) error {
+	l.loadMu.Lock()
+
+	l.historyMu.Lock()
+	var target *snapshot
+	for _, s := range l.history {
+		if s.version == version {
+			target = s
+			break
+		}
+	}
+	l.historyMu.Unlock()
+
+	if target == nil {
+		l.loadMu.Unlock()
+		return fmt.Errorf("config version %q is not in history", version)
+	}
+
+	previous := l.Version()
+	if _, err := l.install(target.sources, target.overrides); err != nil {
+		l.loadMu.Unlock()
+		return fmt.Errorf("reinstall config version %s: %w", version, err)
+	}
+	l.overridesMu.Lock()
+	l.overrides = target.overrides
+	l.overridesMu.Unlock()
+	l.loadMu.Unlock()
+
+	l.logger.Warn("configuration rolled back", "from_version", previous, "to_version", version)
+	l.notifyWatchers()
+	return nil
+}
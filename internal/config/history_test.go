@@ -0,0 +1,131 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestLoader_HistoryAndRollback(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "gateway.yaml", "server:\n  port: 1111\n")
+	writeTestFile(t, dir, "models.yaml", minimalModelsYAML)
+	writeTestFile(t, dir, "providers.yaml", minimalProvidersYAML)
+
+	loader := NewLoader(dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	good := loader.Version()
+
+	writeTestFile(t, dir, "gateway.yaml", "server:\n  port: 2222\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	bad := loader.Version()
+	// Reloading unchanged files must not add a duplicate entry.
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+
+	history := loader.History()
+	if len(history) != 2 {
+		t.Fatalf("expected 2 versions, got %+v", history)
+	}
+	if history[0].Version != bad || !history[0].Current || history[1].Version != good {
+		t.Errorf("expected newest-first history with %s current, got %+v", bad, history)
+	}
+
+	notified := false
+	loader.OnReload(func() { notified = true })
+	if err := loader.Rollback(good); err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if loader.Version() != good || loader.Config().Server.Port != 1111 {
+		t.Errorf("expected rollback to %s (port 1111), got %s (port %d)", good, loader.Version(), loader.Config().Server.Port)
+	}
+	if !notified {
+		t.Error("expected OnReload callbacks after rollback")
+	}
+
+	if err := loader.Rollback("000000000000"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestLoader_HistoryIsBounded(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "models.yaml", minimalModelsYAML)
+	writeTestFile(t, dir, "providers.yaml", minimalProvidersYAML)
+
+	loader := NewLoader(dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for port := 1000; port < 1000+historySize+5; port++ {
+		writeTestFile(t, dir, "gateway.yaml", "server:\n  port: "+itoa(port)+"\n")
+		if err := loader.Load(); err != nil {
+			t.Fatalf("Load() failed: %v", err)
+		}
+	}
+	if got := len(loader.History()); got != historySize {
+		t.Errorf("expected %d versions, got %d", historySize, got)
+	}
+}
+
+func TestLoader_OverridesChangeVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "gateway.yaml", "server:\n  port: 1111\n")
+	writeTestFile(t, dir, "models.yaml", minimalModelsYAML)
+	writeTestFile(t, dir, "providers.yaml", minimalProvidersYAML)
+
+	loader := NewLoader(dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	before := loader.Version()
+
+	level := "debug"
+	if err := loader.ApplyOverrides(RuntimeOverrides{LogLevel: &level}); err != nil {
+		t.Fatalf("ApplyOverrides() failed: %v", err)
+	}
+	if loader.Version() == before {
+		t.Error("expected runtime overrides to produce a new version")
+	}
+
+	if err := loader.Rollback(before); err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if !loader.Overrides().IsZero() || loader.Config().Telemetry.LogLevel != "info" {
+		t.Errorf("expected rollback to restore the version's overrides, got %+v", loader.Overrides())
+	}
+}
+
+func TestLoader_OverridesDoNotReadFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "gateway.yaml", "server:\n  port: 1111\n")
+	writeTestFile(t, dir, "models.yaml", minimalModelsYAML)
+	writeTestFile(t, dir, "providers.yaml", minimalProvidersYAML)
+
+	loader := NewLoader(dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	before := loader.Version()
+
+	// An edit not yet reloaded, or rolled back from, must not slip in with
+	// an override.
+	writeTestFile(t, dir, "gateway.yaml", "server:\n  port: 2222\n")
+	level := "debug"
+	if err := loader.ApplyOverrides(RuntimeOverrides{LogLevel: &level}); err != nil {
+		t.Fatalf("ApplyOverrides() failed: %v", err)
+	}
+	if loader.Config().Server.Port != 1111 || loader.Config().Telemetry.LogLevel != "debug" {
+		t.Errorf("expected overrides on the loaded config (port 1111), got port %d, log level %s",
+			loader.Config().Server.Port, loader.Config().Telemetry.LogLevel)
+	}
+
+	if err := loader.ClearOverrides(); err != nil {
+		t.Fatalf("ClearOverrides() failed: %v", err)
+	}
+	if loader.Config().Server.Port != 1111 || loader.Version() != before {
+		t.Errorf("expected the loaded config back as version %s, got %s (port %d)", before, loader.Version(), loader.Config().Server.Port)
+	}
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
 	"regexp"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
@@ -38,6 +43,12 @@ func LoadFile(path string, dest interface{}) error {
 	if err != nil {
 		return fmt.Errorf("read config file %s: %w", path, err)
 	}
+	return parseFile(path, data, dest)
+}
+
+// parseFile expands env vars in data, the content of path, and unmarshals it
+// into dest.
+func parseFile(path string, data []byte, dest interface{}) error {
 	expanded := expandEnvVars(string(data))
 	if err := yaml.Unmarshal([]byte(expanded), dest); err != nil {
 		return fmt.Errorf("parse config file %s: %w", path, err)
@@ -45,15 +56,52 @@ func LoadFile(path string, dest interface{}) error {
 	return nil
 }
 
-// Loader manages configuration loading and hot-reload via fsnotify.
-type Loader struct {
-	configDir string
-	mu        sync.RWMutex
+// reloadDebounce coalesces the burst of fsnotify events an editor or a
+// ConfigMap update produces, so a half-written file is not parsed mid-save.
+const reloadDebounce = 250 * time.Millisecond
+
+// ReloadMetrics is an optional interface for recording config reload outcomes.
+type ReloadMetrics interface {
+	RecordConfigReload(success bool)
+}
+
+// snapshot is one consistent, validated set of config files. Readers always
+// see all three files from the same load.
+type snapshot struct {
 	cfg       *Config
 	models    *ModelsConfig
 	providers *ProvidersConfig
-	watchers  []func()
+	version   string
+	secrets   secretSet
+	overrides RuntimeOverrides
+	loadedAt  time.Time
+	// sources are the file contents cfg, models, and providers were parsed
+	// from, before overrides and secrets, so the snapshot can be rebuilt
+	// without reading the files again.
+	sources sources
+	// providerSecrets lists the references behind each provider's
+	// credentials, for RefreshProviderSecrets.
+	providerSecrets map[string][]string
+}
+
+// Loader manages configuration loading and hot-reload via fsnotify.
+type Loader struct {
+	configDir string
+	current   atomic.Pointer[snapshot]
+	loadMu    sync.Mutex // serializes Load so two reloads cannot interleave
+
+	watchersMu sync.Mutex
+	watchers   []func()
+
+	historyMu sync.Mutex
+	history   []*snapshot // oldest first, at most historySize entries
+
+	overridesMu sync.Mutex
+	overrides   RuntimeOverrides
+
 	logger    *slog.Logger
+	metrics   ReloadMetrics
+	resolvers map[string]SecretResolver
 }
 
 func NewLoader(configDir string, logger *slog.Logger) *Loader {
@@ -63,55 +111,256 @@ func NewLoader(configDir string, logger *slog.Logger) *Loader {
 	}
 }
 
-func (l *Loader) Load() error {
+// sources holds the raw content of gateway.yaml, models.yaml, and
+// providers.yaml from one config directory.
+type sources struct {
+	dir                        string
+	gateway, models, providers []byte
+}
+
+// readSources reads the three config files in dir.
+func readSources(dir string) (sources, error) {
+	src := sources{dir: dir}
+	for _, f := range []struct {
+		kind string
+		dest *[]byte
+	}{
+		{"gateway", &src.gateway},
+		{"models", &src.models},
+		{"providers", &src.providers},
+	} {
+		path := dir + "/" + f.kind + ".yaml"
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sources{}, fmt.Errorf("load %s config: read config file %s: %w", f.kind, path, err)
+		}
+		*f.dest = data
+	}
+	return src, nil
+}
+
+// parse parses the three files without validating them.
+func (src sources) parse() (*Config, *ModelsConfig, *ProvidersConfig, error) {
 	cfg := DefaultConfig()
-	if err := LoadFile(l.configDir+"/gateway.yaml", cfg); err != nil {
-		return fmt.Errorf("load gateway config: %w", err)
+	if err := parseFile(src.dir+"/gateway.yaml", src.gateway, cfg); err != nil {
+		return nil, nil, nil, fmt.Errorf("load gateway config: %w", err)
 	}
 
 	models := &ModelsConfig{}
-	if err := LoadFile(l.configDir+"/models.yaml", models); err != nil {
-		return fmt.Errorf("load models config: %w", err)
+	if err := parseFile(src.dir+"/models.yaml", src.models, models); err != nil {
+		return nil, nil, nil, fmt.Errorf("load models config: %w", err)
 	}
 
 	providers := &ProvidersConfig{}
-	if err := LoadFile(l.configDir+"/providers.yaml", providers); err != nil {
-		return fmt.Errorf("load providers config: %w", err)
+	if err := parseFile(src.dir+"/providers.yaml", src.providers, providers); err != nil {
+		return nil, nil, nil, fmt.Errorf("load providers config: %w", err)
 	}
+	return cfg, models, providers, nil
+}
 
-	l.mu.Lock()
-	l.cfg = cfg
-	l.models = models
-	l.providers = providers
-	l.mu.Unlock()
+// LoadDir parses gateway.yaml, models.yaml, and providers.yaml from dir
+// without validating or installing them.
+func LoadDir(dir string) (*Config, *ModelsConfig, *ProvidersConfig, error) {
+	src, err := readSources(dir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return src.parse()
+}
+
+// Load parses and validates the config directory, then atomically installs
+// the result. On any error the previously loaded config stays in effect.
+func (l *Loader) Load() error {
+	l.loadMu.Lock()
+	defer l.loadMu.Unlock()
+
+	src, err := readSources(l.configDir)
+	if err != nil {
+		return err
+	}
+	snap, err := l.install(src, l.Overrides())
+	if err != nil {
+		return err
+	}
+	l.logger.Info("configuration loaded", "dir", l.configDir, "version", snap.version)
+	return nil
+}
 
-	l.logger.Info("configuration loaded", "dir", l.configDir)
+// rebuild reinstalls the current config from the file contents it was loaded
+// from, with the runtime overrides overrides returns for the current ones and
+// its secret references resolved again, and notifies OnReload callbacks.
+// Unlike Reload it does not read the files, so a rolled back version stays
+// in effect. On error the current config and overrides stay in effect.
+func (l *Loader) rebuild(overrides func(RuntimeOverrides) RuntimeOverrides) error {
+	l.loadMu.Lock()
+	err := l.rebuildLocked(overrides)
+	l.loadMu.Unlock()
+	if err != nil {
+		l.logger.Error("config reload rejected, keeping previous config", "error", err, "version", l.Version())
+		if l.metrics != nil {
+			l.metrics.RecordConfigReload(false)
+		}
+		return err
+	}
+	if l.metrics != nil {
+		l.metrics.RecordConfigReload(true)
+	}
+	l.notifyWatchers()
 	return nil
 }
 
+func (l *Loader) rebuildLocked(overrides func(RuntimeOverrides) RuntimeOverrides) error {
+	var src sources
+	if s := l.current.Load(); s != nil {
+		src = s.sources
+	} else {
+		var err error
+		if src, err = readSources(l.configDir); err != nil {
+			return err
+		}
+	}
+	next := overrides(l.Overrides())
+	snap, err := l.install(src, next)
+	if err != nil {
+		return err
+	}
+	l.overridesMu.Lock()
+	l.overrides = next
+	l.overridesMu.Unlock()
+	l.logger.Info("configuration rebuilt", "version", snap.version)
+	return nil
+}
+
+// install builds a snapshot from src with overrides applied and its secret
+// references resolved and, if it is valid, makes it current. The caller
+// holds loadMu.
+func (l *Loader) install(src sources, overrides RuntimeOverrides) (*snapshot, error) {
+	cfg, models, providers, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+	overrides.apply(cfg)
+
+	report := Validate(cfg, models, providers)
+	if err := report.Err(); err != nil {
+		return nil, err
+	}
+	for _, w := range report.Warnings {
+		l.logger.Warn("configuration warning", "warning", w)
+	}
+
+	secrets, providerSecrets, err := l.resolveSecrets(cfg, providers)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &snapshot{
+		cfg:       cfg,
+		models:    models,
+		providers: providers,
+		version:   configDigest(overrides, src.gateway, src.models, src.providers),
+		secrets:   secrets,
+		overrides: overrides,
+		loadedAt:  time.Now(),
+		sources:   src,
+
+		providerSecrets: providerSecrets,
+	}
+	l.current.Store(snap)
+	l.recordHistory(snap)
+	return snap, nil
+}
+
+// Version returns a short content hash of the currently loaded config files
+// and runtime overrides, so replicas running different configs can be told apart.
+func (l *Loader) Version() string {
+	if s := l.current.Load(); s != nil {
+		return s.version
+	}
+	return ""
+}
+
+// configDigest returns the first 12 hex chars of a SHA-256 over the raw file
+// contents the config was parsed from and any runtime overrides.
+func configDigest(overrides RuntimeOverrides, files ...[]byte) string {
+	h := sha256.New()
+	for _, data := range files {
+		_, _ = h.Write(data)
+	}
+	if !overrides.IsZero() {
+		data, _ := json.Marshal(overrides)
+		_, _ = h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
 func (l *Loader) Config() *Config {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	return l.cfg
+	if s := l.current.Load(); s != nil {
+		return s.cfg
+	}
+	return nil
 }
 
 func (l *Loader) Models() *ModelsConfig {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	return l.models
+	if s := l.current.Load(); s != nil {
+		return s.models
+	}
+	return nil
 }
 
 func (l *Loader) Providers() *ProvidersConfig {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-	return l.providers
+	if s := l.current.Load(); s != nil {
+		return s.providers
+	}
+	return nil
+}
+
+// SetLogger replaces the logger used for load and reload events.
+// Call before Watch.
+func (l *Loader) SetLogger(logger *slog.Logger) {
+	l.logger = logger
+}
+
+// SetMetrics attaches a recorder for hot-reload outcomes. Call before Watch.
+func (l *Loader) SetMetrics(m ReloadMetrics) {
+	l.metrics = m
 }
 
 // OnReload registers a callback that fires after config is reloaded.
 func (l *Loader) OnReload(fn func()) {
+	l.watchersMu.Lock()
+	defer l.watchersMu.Unlock()
 	l.watchers = append(l.watchers, fn)
 }
 
+// Reload re-reads the config directory and, only if the new config is valid,
+// installs it and notifies OnReload callbacks. The previous config stays in
+// effect on error.
+func (l *Loader) Reload() error {
+	if err := l.Load(); err != nil {
+		l.logger.Error("config reload rejected, keeping previous config", "error", err, "version", l.Version())
+		if l.metrics != nil {
+			l.metrics.RecordConfigReload(false)
+		}
+		return err
+	}
+	if l.metrics != nil {
+		l.metrics.RecordConfigReload(true)
+	}
+	l.notifyWatchers()
+	return nil
+}
+
+// notifyWatchers runs the OnReload callbacks.
+func (l *Loader) notifyWatchers() {
+	l.watchersMu.Lock()
+	watchers := append([]func(){}, l.watchers...)
+	l.watchersMu.Unlock()
+	for _, fn := range watchers {
+		fn()
+	}
+}
+
 // Watch starts watching the config directory for changes and reloads on modification.
 func (l *Loader) Watch() error {
 	watcher, err := fsnotify.NewWatcher()
@@ -136,6 +385,7 @@ func (l *Loader) Watch() error {
 
 	go func() {
 		defer func() { _ = watcher.Close() }()
+		var debounce <-chan time.Time
 		for {
 			select {
 			case event, ok := <-watcher.Events:
@@ -143,15 +393,13 @@ func (l *Loader) Watch() error {
 					return
 				}
 				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-					l.logger.Info("config file changed, reloading", "file", event.Name)
-					if err := l.Load(); err != nil {
-						l.logger.Error("failed to reload config", "error", err)
-						continue
-					}
-					for _, fn := range l.watchers {
-						fn()
-					}
+					l.logger.Debug("config file changed", "file", event.Name)
+					debounce = time.After(reloadDebounce)
 				}
+			case <-debounce:
+				debounce = nil
+				l.logger.Info("config changed, reloading", "dir", l.configDir)
+				_ = l.Reload()
 			case err, ok := <-watcher.Errors:
 				if !ok {
 					return
@@ -1,14 +1,39 @@
 package config
 
+import "time"
+
 type ModelsConfig struct {
 	Models  map[string]ModelMapping          `yaml:"models"`
 	Pricing map[string]map[string]PriceEntry `yaml:"pricing"`
+	// Capabilities declares, per provider and provider model, the features
+	// a route supports. Requests are routed past routes lacking a feature
+	// they need; a route with no entry is assumed to support everything.
+	Capabilities map[string]map[string]ModelCapabilities `yaml:"capabilities"`
 }
 
 type ModelMapping struct {
-	DisplayName string           `yaml:"display_name"`
-	Primary     ProviderRoute    `yaml:"primary"`
-	Fallback    []ProviderRoute  `yaml:"fallback"`
+	DisplayName string          `yaml:"display_name"`
+	Primary     ProviderRoute   `yaml:"primary"`
+	Fallback    []ProviderRoute `yaml:"fallback"`
+	// ContextWindow is the smallest context window, in tokens, among the
+	// model's routes. Requests estimated to exceed it are rejected (or
+	// truncated for opted-in teams) before reaching a provider. Zero skips
+	// the check.
+	ContextWindow int `yaml:"context_window,omitempty"`
+	// Created is when the model was released, reported by /v1/models; a
+	// date such as 2024-05-13 is enough.
+	Created time.Time `yaml:"created,omitempty"`
+	// Deprecated marks the model for retirement. Requests for it are still
+	// served, with a warning naming the sunset date and replacement.
+	Deprecated *ModelDeprecation `yaml:"deprecated,omitempty"`
+}
+
+// ModelDeprecation announces a model's retirement.
+type ModelDeprecation struct {
+	// Sunset is the date the model stops being served, e.g. 2026-06-30.
+	Sunset time.Time `yaml:"sunset"`
+	// Replacement is the model clients should move to; optional.
+	Replacement string `yaml:"replacement,omitempty"`
 }
 
 type ProviderRoute struct {
@@ -19,6 +44,19 @@ type ProviderRoute struct {
 	ClassificationCeiling string `yaml:"classification_ceiling"`
 }
 
+// ModelCapabilities describes what one provider model supports. An unset
+// feature is assumed supported.
+type ModelCapabilities struct {
+	Streaming *bool `yaml:"streaming,omitempty"`
+	Tools     *bool `yaml:"tools,omitempty"`
+	// Vision is informational: image content parts are not accepted yet.
+	Vision   *bool `yaml:"vision,omitempty"`
+	JSONMode *bool `yaml:"json_mode,omitempty"`
+	// MaxOutputTokens is the largest completion the model produces; zero if
+	// unknown. Requests asking for more are routed elsewhere.
+	MaxOutputTokens int `yaml:"max_output_tokens,omitempty"`
+}
+
 type PriceEntry struct {
 	Input  float64 `yaml:"input"`
 	Output float64 `yaml:"output"`
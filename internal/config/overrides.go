@@ -0,0 +1,128 @@
+package config
+
+// RuntimeOverrides holds dynamic settings changed at runtime through the admin
+// API. Nil fields leave the file value alone. Overrides are layered on top of
+// the files on every load, so they survive hot-reloads until cleared.
+// Changing them rebuilds the config in memory: files edited on disk since
+// the last load are only picked up by the next reload.
+type RuntimeOverrides struct {
+	LogLevel                *string  `json:"log_level,omitempty"`
+	RoutingStrategy         *string  `json:"routing_strategy,omitempty"`
+	SecretsEnabled          *bool    `json:"secrets_enabled,omitempty"`
+	InjectionEnabled        *bool    `json:"injection_enabled,omitempty"`
+	InjectionBlockThreshold *float64 `json:"injection_block_threshold,omitempty"`
+	InjectionFlagThreshold  *float64 `json:"injection_flag_threshold,omitempty"`
+	PolicyEnabled           *bool    `json:"policy_enabled,omitempty"`
+	PIIFailOpen             *bool    `json:"pii_fail_open,omitempty"`
+	SecretsFailOpen         *bool    `json:"secrets_fail_open,omitempty"`
+	InjectionFailOpen       *bool    `json:"injection_fail_open,omitempty"`
+	ClassificationFailOpen  *bool    `json:"classification_fail_open,omitempty"`
+	PolicyFailOpen          *bool    `json:"policy_fail_open,omitempty"`
+}
+
+// IsZero reports whether no override is set.
+func (o RuntimeOverrides) IsZero() bool {
+	return o == RuntimeOverrides{}
+}
+
+// merge returns o with every field set in other replacing its own.
+func (o RuntimeOverrides) merge(other RuntimeOverrides) RuntimeOverrides {
+	if other.LogLevel != nil {
+		o.LogLevel = other.LogLevel
+	}
+	if other.RoutingStrategy != nil {
+		o.RoutingStrategy = other.RoutingStrategy
+	}
+	if other.SecretsEnabled != nil {
+		o.SecretsEnabled = other.SecretsEnabled
+	}
+	if other.InjectionEnabled != nil {
+		o.InjectionEnabled = other.InjectionEnabled
+	}
+	if other.InjectionBlockThreshold != nil {
+		o.InjectionBlockThreshold = other.InjectionBlockThreshold
+	}
+	if other.InjectionFlagThreshold != nil {
+		o.InjectionFlagThreshold = other.InjectionFlagThreshold
+	}
+	if other.PolicyEnabled != nil {
+		o.PolicyEnabled = other.PolicyEnabled
+	}
+	if other.PIIFailOpen != nil {
+		o.PIIFailOpen = other.PIIFailOpen
+	}
+	if other.SecretsFailOpen != nil {
+		o.SecretsFailOpen = other.SecretsFailOpen
+	}
+	if other.InjectionFailOpen != nil {
+		o.InjectionFailOpen = other.InjectionFailOpen
+	}
+	if other.ClassificationFailOpen != nil {
+		o.ClassificationFailOpen = other.ClassificationFailOpen
+	}
+	if other.PolicyFailOpen != nil {
+		o.PolicyFailOpen = other.PolicyFailOpen
+	}
+	return o
+}
+
+// apply writes the set overrides into cfg.
+func (o RuntimeOverrides) apply(cfg *Config) {
+	if o.LogLevel != nil {
+		cfg.Telemetry.LogLevel = *o.LogLevel
+	}
+	if o.RoutingStrategy != nil {
+		cfg.Routing.Strategy = *o.RoutingStrategy
+	}
+	if o.SecretsEnabled != nil {
+		cfg.Filter.Secrets.Enabled = *o.SecretsEnabled
+	}
+	if o.InjectionEnabled != nil {
+		cfg.Filter.Injection.Enabled = *o.InjectionEnabled
+	}
+	if o.InjectionBlockThreshold != nil {
+		cfg.Filter.Injection.BlockThreshold = *o.InjectionBlockThreshold
+	}
+	if o.InjectionFlagThreshold != nil {
+		cfg.Filter.Injection.FlagThreshold = *o.InjectionFlagThreshold
+	}
+	if o.PolicyEnabled != nil {
+		cfg.Filter.Policy.Enabled = *o.PolicyEnabled
+	}
+	if o.PIIFailOpen != nil {
+		cfg.Filter.PIIService.FailOpen = *o.PIIFailOpen
+	}
+	if o.SecretsFailOpen != nil {
+		cfg.Filter.Secrets.FailOpen = *o.SecretsFailOpen
+	}
+	if o.InjectionFailOpen != nil {
+		cfg.Filter.Injection.FailOpen = *o.InjectionFailOpen
+	}
+	if o.ClassificationFailOpen != nil {
+		cfg.Filter.Classification.FailOpen = *o.ClassificationFailOpen
+	}
+	if o.PolicyFailOpen != nil {
+		cfg.Filter.Policy.FailOpen = *o.PolicyFailOpen
+	}
+}
+
+// Overrides returns the runtime overrides currently in effect.
+func (l *Loader) Overrides() RuntimeOverrides {
+	l.overridesMu.Lock()
+	defer l.overridesMu.Unlock()
+	return l.overrides
+}
+
+// ApplyOverrides merges o into the runtime overrides and rebuilds the current
+// config with them, without reading the files again. If the resulting config
+// fails validation the previous overrides stay in effect and the error is
+// returned.
+func (l *Loader) ApplyOverrides(o RuntimeOverrides) error {
+	return l.rebuild(func(cur RuntimeOverrides) RuntimeOverrides { return cur.merge(o) })
+}
+
+// ClearOverrides drops all runtime overrides and rebuilds the current config
+// from its files as they were loaded.
+func (l *Loader) ClearOverrides() error {
+	return l.rebuild(func(RuntimeOverrides) RuntimeOverrides { return RuntimeOverrides{} })
+}
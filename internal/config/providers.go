@@ -14,4 +14,149 @@ type ProviderConfig struct {
 	MaxConcurrent int               `yaml:"max_concurrent"`
 	Timeout       time.Duration     `yaml:"timeout"`
 	Headers       map[string]string `yaml:"headers,omitempty"`
+	// Organization and Project are sent to OpenAI-compatible providers as
+	// OpenAI-Organization and OpenAI-Project, so usage is billed to the
+	// right upstream account. Anthropic providers send APIVersion as
+	// anthropic-version instead. These take precedence over Headers.
+	Organization string `yaml:"organization,omitempty"`
+	Project      string `yaml:"project,omitempty"`
+	// Teams overrides Organization, Project, and APIVersion for requests
+	// from the listed team IDs.
+	Teams map[string]ProviderTeamConfig `yaml:"teams,omitempty"`
+	// ForwardTraceContext sends the caller's W3C traceparent to the provider.
+	ForwardTraceContext bool `yaml:"forward_trace_context,omitempty"`
+	// Auth obtains bearer tokens from an identity provider in place of the
+	// static api_key. Only OpenAI-compatible providers support it.
+	Auth *ProviderAuthConfig `yaml:"auth,omitempty"`
+	// Proxy is the outbound proxy URL (http, https, or socks5) for this
+	// provider, or "env" to use HTTPS_PROXY/NO_PROXY. Empty connects
+	// directly.
+	Proxy string `yaml:"proxy,omitempty"`
+	// TLS customises verification of the provider and optionally presents a
+	// client certificate.
+	TLS *ProviderTLSConfig `yaml:"tls,omitempty"`
+	// RateLimit throttles outbound traffic to stay under the provider's
+	// quota.
+	RateLimit *ProviderRateLimitConfig `yaml:"rate_limit,omitempty"`
+	// Connections tunes the keep-alive connection pool to the provider.
+	Connections *ProviderConnectionsConfig `yaml:"connections,omitempty"`
+	// Mock configures the canned completions of a "mock" provider.
+	Mock *ProviderMockConfig `yaml:"mock,omitempty"`
+}
+
+// ProviderTypeMock is the provider type answered by the gateway itself with
+// canned OpenAI-format completions, for integration tests and local
+// development without API keys. It needs no base_url or api_key.
+const ProviderTypeMock = "mock"
+
+// ProviderMockConfig shapes a mock provider's completions.
+type ProviderMockConfig struct {
+	// Response is the assistant message content. Defaults to a fixed
+	// sentence.
+	Response string `yaml:"response,omitempty"`
+	// Latency is waited before responding.
+	Latency time.Duration `yaml:"latency,omitempty"`
+	// ChunkSize is how many characters of content each stream chunk
+	// carries; zero streams the content in one chunk. ChunkDelay is waited
+	// between stream chunks.
+	ChunkSize  int           `yaml:"chunk_size,omitempty"`
+	ChunkDelay time.Duration `yaml:"chunk_delay,omitempty"`
+}
+
+// ProviderTeamConfig is the upstream account one team's requests are billed
+// to at a provider. Empty fields keep the provider's own value.
+type ProviderTeamConfig struct {
+	Organization string `yaml:"organization,omitempty"`
+	Project      string `yaml:"project,omitempty"`
+	APIVersion   string `yaml:"api_version,omitempty"`
+}
+
+// Account returns the organization, project, and API version to send for
+// team's requests.
+func (p ProviderConfig) Account(team string) (organization, project, apiVersion string) {
+	organization, project, apiVersion = p.Organization, p.Project, p.APIVersion
+	t, ok := p.Teams[team]
+	if !ok {
+		return organization, project, apiVersion
+	}
+	if t.Organization != "" {
+		organization = t.Organization
+	}
+	if t.Project != "" {
+		project = t.Project
+	}
+	if t.APIVersion != "" {
+		apiVersion = t.APIVersion
+	}
+	return organization, project, apiVersion
+}
+
+// ProviderConnectionsConfig tunes one provider's connection pool. Zero
+// values keep the defaults: max_idle_conns_per_host follows max_concurrent,
+// max_conns_per_host is unlimited, and the timeouts match Go's default
+// transport.
+type ProviderConnectionsConfig struct {
+	// MaxConnsPerHost caps connections to the provider, dialing, active,
+	// and idle together; requests beyond it wait for a free connection.
+	MaxConnsPerHost int `yaml:"max_conns_per_host,omitempty"`
+	// MaxIdleConnsPerHost is how many idle connections are kept for reuse.
+	// Set it near the steady-state concurrency to avoid closing and
+	// redialing connections under load.
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     time.Duration `yaml:"idle_conn_timeout,omitempty"`
+	DialTimeout         time.Duration `yaml:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout,omitempty"`
+	KeepAlive           time.Duration `yaml:"keep_alive,omitempty"`
+}
+
+// ProviderProxyFromEnv selects the proxy from the environment.
+const ProviderProxyFromEnv = "env"
+
+// ProviderTLSConfig configures TLS to a provider. CAFile is a PEM bundle
+// trusted in addition to the system roots (e.g. a TLS-inspecting proxy's or
+// an internal CA).
+type ProviderTLSConfig struct {
+	CAFile     string `yaml:"ca_file,omitempty"`
+	CertFile   string `yaml:"cert_file,omitempty"`
+	KeyFile    string `yaml:"key_file,omitempty"`
+	ServerName string `yaml:"server_name,omitempty"`
+	// MinVersion is "1.2" or "1.3"; empty means Go's default (1.2).
+	MinVersion         string `yaml:"min_version,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// ProviderRateLimitConfig caps the requests and tokens per minute one
+// gateway replica sends to a provider; divide the upstream quota by the
+// replica count. Tokens are counted the way OpenAI counts them against TPM:
+// the estimated prompt plus max_tokens. Zero leaves a dimension unlimited.
+type ProviderRateLimitConfig struct {
+	RPM int `yaml:"rpm,omitempty"`
+	TPM int `yaml:"tpm,omitempty"`
+	// MaxWait is how long a request may queue for capacity when no fallback
+	// route has room before it is rejected with 429. Defaults to 5s.
+	MaxWait time.Duration `yaml:"max_wait,omitempty"`
+}
+
+// Provider auth types.
+const (
+	ProviderAuthAzureClientCredentials = "azure_client_credentials"
+	ProviderAuthAzureWorkloadIdentity  = "azure_workload_identity"
+)
+
+// ProviderAuthConfig configures Azure AD (Entra ID) token auth. With
+// azure_client_credentials the gateway authenticates with a client secret;
+// with azure_workload_identity it exchanges the federated service account
+// token that Kubernetes projects into the pod. Empty tenant_id, client_id,
+// federated_token_file, and authority_host fall back to the AZURE_TENANT_ID,
+// AZURE_CLIENT_ID, AZURE_FEDERATED_TOKEN_FILE, and AZURE_AUTHORITY_HOST
+// variables the workload identity webhook injects.
+type ProviderAuthConfig struct {
+	Type               string `yaml:"type"`
+	TenantID           string `yaml:"tenant_id,omitempty"`
+	ClientID           string `yaml:"client_id,omitempty"`
+	ClientSecret       string `yaml:"client_secret,omitempty"`
+	FederatedTokenFile string `yaml:"federated_token_file,omitempty"`
+	// Scope defaults to https://cognitiveservices.azure.com/.default.
+	Scope         string `yaml:"scope,omitempty"`
+	AuthorityHost string `yaml:"authority_host,omitempty"`
 }
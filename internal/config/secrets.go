@@ -0,0 +1,215 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// secretResolveTimeout bounds resolving every secret reference in one load.
+const secretResolveTimeout = 30 * time.Second
+
+// SecretResolver resolves secret references such as
+// "vault://secret/data/aegis/providers#openai" that appear in place of a
+// literal value in config. Scheme is the URI scheme it handles ("vault").
+type SecretResolver interface {
+	Scheme() string
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// secretSet maps each secret reference seen during a load to its resolved value.
+type secretSet map[string]string
+
+var (
+	// ErrUnknownProvider is returned by RefreshProviderSecrets for a
+	// provider not in providers.yaml.
+	ErrUnknownProvider = errors.New("unknown provider")
+	// ErrNoSecretRefs is returned by RefreshProviderSecrets for a provider
+	// whose credentials are literals or environment values, which only a
+	// config reload re-reads.
+	ErrNoSecretRefs = errors.New("provider credentials hold no secret references")
+)
+
+// AddSecretResolver registers a resolver for its scheme. Call before Load.
+func (l *Loader) AddSecretResolver(r SecretResolver) {
+	if l.resolvers == nil {
+		l.resolvers = make(map[string]SecretResolver)
+	}
+	l.resolvers[r.Scheme()] = r
+}
+
+// resolverFor returns the resolver for ref's scheme, or nil if ref is a literal.
+func (l *Loader) resolverFor(ref string) SecretResolver {
+	scheme, _, ok := strings.Cut(ref, "://")
+	if !ok {
+		return nil
+	}
+	return l.resolvers[scheme]
+}
+
+// resolveSecrets replaces secret references in credential fields with their
+// resolved values. Only provider API keys and headers, database and Redis
+// passwords, archive credentials, and the injection embedding API key may
+// hold references. It also returns the references each provider's
+// credentials use.
+func (l *Loader) resolveSecrets(cfg *Config, providers *ProvidersConfig) (secretSet, map[string][]string, error) {
+	if len(l.resolvers) == 0 {
+		return nil, nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	resolved := secretSet{}
+	var refs []string
+	resolve := func(field string, v *string) error {
+		r := l.resolverFor(*v)
+		if r == nil {
+			return nil
+		}
+		val, err := r.Resolve(ctx, *v)
+		if err != nil {
+			return fmt.Errorf("resolve secret for %s: %w", field, err)
+		}
+		resolved[*v] = val
+		refs = append(refs, *v)
+		*v = val
+		return nil
+	}
+
+	for field, v := range map[string]*string{
+		"database.password":                             &cfg.Database.Password,
+		"redis.password":                                &cfg.Redis.Password,
+		"archive.access_key_id":                         &cfg.Archive.AccessKeyID,
+		"archive.secret_access_key":                     &cfg.Archive.SecretAccessKey,
+		"filter.injection.similarity.embedding_api_key": &cfg.Filter.Injection.Similarity.EmbeddingAPIKey,
+	} {
+		if err := resolve(field, v); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	providerRefs := map[string][]string{}
+	for name, p := range providers.Providers {
+		refs = nil
+		if err := resolve("providers."+name+".api_key", &p.APIKey); err != nil {
+			return nil, nil, err
+		}
+		if len(p.Headers) > 0 {
+			headers := make(map[string]string, len(p.Headers))
+			for k, v := range p.Headers {
+				if err := resolve("providers."+name+".headers."+k, &v); err != nil {
+					return nil, nil, err
+				}
+				headers[k] = v
+			}
+			p.Headers = headers
+		}
+		if p.Auth != nil && p.Auth.ClientSecret != "" {
+			auth := *p.Auth
+			if err := resolve("providers."+name+".auth.client_secret", &auth.ClientSecret); err != nil {
+				return nil, nil, err
+			}
+			p.Auth = &auth
+		}
+		providers.Providers[name] = p
+		if len(refs) > 0 {
+			providerRefs[name] = refs
+		}
+	}
+	return resolved, providerRefs, nil
+}
+
+// secretsChanged re-resolves every reference from the current config and
+// reports whether any value differs from what is loaded.
+func (l *Loader) secretsChanged() (bool, error) {
+	s := l.current.Load()
+	if s == nil || len(s.secrets) == 0 {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	for ref, old := range s.secrets {
+		r := l.resolverFor(ref)
+		if r == nil {
+			continue
+		}
+		val, err := r.Resolve(ctx, ref)
+		if err != nil {
+			return false, err
+		}
+		if val != old {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RefreshProviderSecrets re-resolves one provider's secret references now,
+// rather than at the next WatchSecrets tick, and rebuilds the current config
+// if any was rotated, which rebuilds that provider's adapter. It reports whether a
+// rotation was found.
+func (l *Loader) RefreshProviderSecrets(name string) (bool, error) {
+	s := l.current.Load()
+	if s == nil {
+		return false, ErrUnknownProvider
+	}
+	if _, ok := s.providers.Providers[name]; !ok {
+		return false, ErrUnknownProvider
+	}
+	refs := s.providerSecrets[name]
+	if len(refs) == 0 {
+		return false, ErrNoSecretRefs
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), secretResolveTimeout)
+	defer cancel()
+
+	rotated := false
+	for _, ref := range refs {
+		r := l.resolverFor(ref)
+		if r == nil {
+			continue
+		}
+		val, err := r.Resolve(ctx, ref)
+		if err != nil {
+			return false, fmt.Errorf("resolve secret for providers.%s: %w", name, err)
+		}
+		rotated = rotated || val != s.secrets[ref]
+	}
+	if !rotated {
+		return false, nil
+	}
+	l.logger.Info("provider secret rotated, reloading config", "provider", name)
+	return true, l.rebuild(func(o RuntimeOverrides) RuntimeOverrides { return o })
+}
+
+// WatchSecrets periodically re-resolves secret references and rebuilds the
+// current config when any has been rotated, so OnReload callbacks pick up
+// new credentials. It is a no-op when no resolvers are registered.
+func (l *Loader) WatchSecrets(interval time.Duration) {
+	if len(l.resolvers) == 0 || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			l.refreshSecrets()
+		}
+	}()
+}
+
+// refreshSecrets is one WatchSecrets tick.
+func (l *Loader) refreshSecrets() {
+	changed, err := l.secretsChanged()
+	if err != nil {
+		l.logger.Warn("secret refresh failed, keeping current values", "error", err)
+		return
+	}
+	if changed {
+		l.logger.Info("secret rotated, reloading config")
+		_ = l.rebuild(func(o RuntimeOverrides) RuntimeOverrides { return o })
+	}
+}
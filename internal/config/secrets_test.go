@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeResolver struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (f *fakeResolver) Scheme() string { return "fake" }
+
+func (f *fakeResolver) Resolve(_ context.Context, ref string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.values[ref]
+	if !ok {
+		return "", fmt.Errorf("no secret at %s", ref)
+	}
+	return v, nil
+}
+
+func (f *fakeResolver) set(ref, v string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[ref] = v
+}
+
+func writeSecretRefConfigs(t *testing.T, dir string) {
+	t.Helper()
+	writeTestFile(t, dir, "gateway.yaml", "database:\n  password: \"fake://db#password\"\nredis:\n  password: \"plain\"\n")
+	writeTestFile(t, dir, "models.yaml", "models:\n  m:\n    primary:\n      provider: openai\n      model: gpt-4o\n")
+	writeTestFile(t, dir, "providers.yaml", `providers:
+  openai:
+    type: openai
+    base_url: https://api.openai.com/v1
+    api_key: "fake://providers#openai"
+    headers:
+      Organization: "fake://providers#org"
+`)
+}
+
+func TestLoader_ResolvesSecretRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+
+	resolver := &fakeResolver{values: map[string]string{
+		"fake://db#password":      "db-secret",
+		"fake://providers#openai": "sk-123",
+		"fake://providers#org":    "org-1",
+	}}
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(resolver)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if got := loader.Config().Database.Password; got != "db-secret" {
+		t.Errorf("expected resolved db password, got %q", got)
+	}
+	if got := loader.Config().Redis.Password; got != "plain" {
+		t.Errorf("expected literal redis password untouched, got %q", got)
+	}
+	openai := loader.Providers().Providers["openai"]
+	if openai.APIKey != "sk-123" || openai.Headers["Organization"] != "org-1" {
+		t.Errorf("expected resolved provider credentials, got key=%q org=%q", openai.APIKey, openai.Headers["Organization"])
+	}
+}
+
+func TestLoader_SecretResolveFailureKeepsPreviousConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+
+	resolver := &fakeResolver{values: map[string]string{
+		"fake://db#password":      "db-secret",
+		"fake://providers#openai": "sk-123",
+		"fake://providers#org":    "org-1",
+	}}
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(resolver)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	writeTestFile(t, dir, "gateway.yaml", "database:\n  password: \"fake://db#missing\"\n")
+	err := loader.Load()
+	if err == nil || !strings.Contains(err.Error(), "database.password") {
+		t.Fatalf("expected resolve error naming the field, got %v", err)
+	}
+	if got := loader.Config().Database.Password; got != "db-secret" {
+		t.Errorf("expected previous config to stay active, got %q", got)
+	}
+}
+
+func TestLoader_SecretsChangedDetectsRotation(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+
+	resolver := &fakeResolver{values: map[string]string{
+		"fake://db#password":      "db-secret",
+		"fake://providers#openai": "sk-123",
+		"fake://providers#org":    "org-1",
+	}}
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(resolver)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	if changed, err := loader.secretsChanged(); err != nil || changed {
+		t.Fatalf("expected no change, got changed=%v err=%v", changed, err)
+	}
+
+	resolver.set("fake://providers#openai", "sk-456")
+	changed, err := loader.secretsChanged()
+	if err != nil || !changed {
+		t.Fatalf("expected rotation to be detected, got changed=%v err=%v", changed, err)
+	}
+
+	reloaded := false
+	loader.OnReload(func() { reloaded = true })
+	_ = loader.Reload()
+	if !reloaded {
+		t.Error("expected OnReload callbacks after rotation reload")
+	}
+	if got := loader.Providers().Providers["openai"].APIKey; got != "sk-456" {
+		t.Errorf("expected rotated key, got %q", got)
+	}
+}
+
+func TestLoader_RefreshProviderSecrets(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+
+	resolver := &fakeResolver{values: map[string]string{
+		"fake://db#password":      "db-secret",
+		"fake://providers#openai": "sk-123",
+		"fake://providers#org":    "org-1",
+	}}
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(resolver)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	reloads := 0
+	loader.OnReload(func() { reloads++ })
+
+	if _, err := loader.RefreshProviderSecrets("anthropic"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("unknown provider: got %v, want ErrUnknownProvider", err)
+	}
+	if rotated, err := loader.RefreshProviderSecrets("openai"); err != nil || rotated || reloads != 0 {
+		t.Fatalf("unchanged secret: rotated=%v err=%v reloads=%d", rotated, err, reloads)
+	}
+
+	// A rotated database password is not the provider's to pick up.
+	resolver.set("fake://db#password", "db-rotated")
+	if rotated, _ := loader.RefreshProviderSecrets("openai"); rotated {
+		t.Error("another field's rotation should not count for the provider")
+	}
+
+	resolver.set("fake://providers#org", "org-2")
+	rotated, err := loader.RefreshProviderSecrets("openai")
+	if err != nil || !rotated || reloads != 1 {
+		t.Fatalf("rotated header: rotated=%v err=%v reloads=%d", rotated, err, reloads)
+	}
+	if got := loader.Providers().Providers["openai"].Headers["Organization"]; got != "org-2" {
+		t.Errorf("expected rotated header, got %q", got)
+	}
+}
+
+func TestLoader_RefreshProviderSecrets_NoRefs(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+	writeTestFile(t, dir, "providers.yaml", "providers:\n  openai:\n    type: openai\n    base_url: https://api.openai.com/v1\n    api_key: \"sk-literal\"\n")
+
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(&fakeResolver{values: map[string]string{"fake://db#password": "db-secret"}})
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if _, err := loader.RefreshProviderSecrets("openai"); !errors.Is(err, ErrNoSecretRefs) {
+		t.Errorf("got %v, want ErrNoSecretRefs", err)
+	}
+}
+
+func TestLoader_RollbackSurvivesSecretRefresh(t *testing.T) {
+	dir := t.TempDir()
+	writeSecretRefConfigs(t, dir)
+
+	resolver := &fakeResolver{values: map[string]string{
+		"fake://db#password":      "db-secret",
+		"fake://providers#openai": "sk-123",
+		"fake://providers#org":    "org-1",
+	}}
+	loader := NewLoader(dir, slog.Default())
+	loader.AddSecretResolver(resolver)
+	if err := loader.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	good := loader.Version()
+
+	writeTestFile(t, dir, "gateway.yaml", "database:\n  password: \"fake://db#password\"\nredis:\n  password: \"changed\"\n")
+	if err := loader.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	resolver.set("fake://providers#openai", "sk-456")
+	if err := loader.Rollback(good); err != nil {
+		t.Fatalf("Rollback() failed: %v", err)
+	}
+	if got := loader.Providers().Providers["openai"].APIKey; got != "sk-456" {
+		t.Errorf("rollback should resolve secrets again, got key %q", got)
+	}
+
+	reloads := 0
+	loader.OnReload(func() { reloads++ })
+	loader.refreshSecrets()
+	if reloads != 0 || loader.Version() != good {
+		t.Fatalf("an unchanged secret should not reload, got %d reloads, version %s", reloads, loader.Version())
+	}
+
+	resolver.set("fake://providers#openai", "sk-789")
+	loader.refreshSecrets()
+	if reloads != 1 || loader.Providers().Providers["openai"].APIKey != "sk-789" {
+		t.Fatalf("a rotation should be picked up, got %d reloads, key %q", reloads, loader.Providers().Providers["openai"].APIKey)
+	}
+	if loader.Version() != good || loader.Config().Redis.Password != "plain" {
+		t.Errorf("a rotation should keep the rolled back version %s, got %s (redis password %q)",
+			good, loader.Version(), loader.Config().Redis.Password)
+	}
+}
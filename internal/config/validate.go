@@ -0,0 +1,656 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/egress"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// validTableName matches a plain or schema-qualified SQL table name, which is
+// interpolated into queries and so must not need quoting.
+var validTableName = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z_][a-z0-9_]*)?$`)
+
+// ErrInvalidConfig is wrapped by the error returned from ValidationReport.Err.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ValidationReport lists problems found by Validate. Errors make the
+// configuration unsafe to deploy; warnings are worth fixing but not fatal.
+type ValidationReport struct {
+	Errors   []string
+	Warnings []string
+}
+
+func (r *ValidationReport) errorf(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+func (r *ValidationReport) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Err returns nil when there are no errors, otherwise one error listing them all.
+func (r *ValidationReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w (%d errors):\n  %s", ErrInvalidConfig, len(r.Errors), strings.Join(r.Errors, "\n  "))
+}
+
+// Validate cross-checks the three config files against each other: every model
+// route must name a defined provider and a known classification ceiling, and
+// pricing must exist for each routed model. It does not compile Rego; callers
+// that need that check use the policy package.
+func Validate(cfg *Config, models *ModelsConfig, providers *ProvidersConfig) *ValidationReport {
+	r := &ValidationReport{}
+	validateGateway(r, cfg)
+	validateProviders(r, providers)
+	validateModels(r, models, providers)
+	validateBlockResponses(r, cfg.Filter, models)
+	validateEgress(r, cfg.Egress, providers)
+	validateChaos(r, cfg.Chaos, providers)
+	return r
+}
+
+func validateGateway(r *ValidationReport, cfg *Config) {
+	if cfg.Server.Port <= 0 || cfg.Server.Port > 65535 {
+		r.errorf("gateway.yaml: server.port: %d is not a valid port", cfg.Server.Port)
+	}
+	if cfg.Telemetry.MetricsPort <= 0 || cfg.Telemetry.MetricsPort > 65535 {
+		r.errorf("gateway.yaml: telemetry.metrics_port: %d is not a valid port", cfg.Telemetry.MetricsPort)
+	}
+	if cfg.Telemetry.MetricsPort == cfg.Server.Port {
+		r.errorf("gateway.yaml: telemetry.metrics_port: %d collides with server.port", cfg.Telemetry.MetricsPort)
+	}
+	if g := cfg.Server.GRPCPort; g != 0 {
+		if g < 0 || g > 65535 {
+			r.errorf("gateway.yaml: server.grpc_port: %d is not a valid port", g)
+		} else if g == cfg.Server.Port || g == cfg.Telemetry.MetricsPort {
+			r.errorf("gateway.yaml: server.grpc_port: %d collides with another listener", g)
+		}
+	}
+	switch cfg.Database.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		r.errorf("gateway.yaml: database.sslmode: unknown mode %q", cfg.Database.SSLMode)
+	}
+	if (cfg.Database.SSLCert == "") != (cfg.Database.SSLKey == "") {
+		r.errorf("gateway.yaml: database: sslcert and sslkey must be set together")
+	}
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns && cfg.Database.MaxOpenConns > 0 {
+		r.warnf("gateway.yaml: database.max_idle_conns: %d exceeds max_open_conns %d, pool will be capped", cfg.Database.MaxIdleConns, cfg.Database.MaxOpenConns)
+	}
+	if cfg.Server.MaxInFlight < 0 {
+		r.errorf("gateway.yaml: server.max_in_flight: must not be negative (0 disables the cap)")
+	}
+	if ratio := cfg.Server.BatchInFlightRatio; ratio <= 0 || ratio > 1 {
+		r.errorf("gateway.yaml: server.batch_in_flight_ratio: must be greater than 0 and at most 1")
+	}
+	if l := cfg.Limits; l.MaxBodyBytes < 0 || l.MaxMessages < 0 || l.MaxMessageLength < 0 || l.MaxJSONDepth < 0 || l.MaxCompareModels < 0 {
+		r.errorf("gateway.yaml: limits: values must not be negative (0 disables a limit)")
+	}
+	if u := cfg.Usage; u.BufferSize < 0 || u.BatchSize < 0 || u.FlushInterval < 0 || u.EnqueueTimeout < 0 {
+		r.errorf("gateway.yaml: usage: values must not be negative")
+	} else if u.BatchSize > u.BufferSize && u.BufferSize > 0 {
+		r.warnf("gateway.yaml: usage.batch_size: %d exceeds buffer_size %d, batches will never fill", u.BatchSize, u.BufferSize)
+	}
+	if rt := cfg.Retention; rt.Interval < 0 || rt.UsageRetention < 0 || rt.AuditRetention < 0 || rt.ExpiredKeyRetention < 0 || rt.BatchRetention < 0 || rt.BatchSize < 0 {
+		r.errorf("gateway.yaml: retention: values must not be negative (0 keeps data forever)")
+	} else if rt.Enabled && rt.UsageRetention > 0 && rt.UsageRetention < 31*24*time.Hour {
+		r.warnf("gateway.yaml: retention.usage_retention: %s is shorter than a billing month", rt.UsageRetention)
+	}
+	if cfg.CORS.Enabled {
+		if len(cfg.CORS.AllowedOrigins) == 0 {
+			r.warnf("gateway.yaml: cors.allowed_origins: empty, no cross-origin requests will be allowed")
+		}
+		for _, o := range cfg.CORS.AllowedOrigins {
+			if o == "*" && cfg.CORS.AllowCredentials {
+				r.warnf("gateway.yaml: cors: allow_credentials with origin \"*\" lets any site send credentialed requests")
+			}
+		}
+		if cfg.CORS.MaxAge < 0 {
+			r.errorf("gateway.yaml: cors.max_age: must not be negative")
+		}
+	}
+	switch strings.ToLower(cfg.Telemetry.LogLevel) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		r.errorf("gateway.yaml: telemetry.log_level: unknown level %q", cfg.Telemetry.LogLevel)
+	}
+	switch cfg.Telemetry.LogFormat {
+	case "", "json", "text":
+	default:
+		r.errorf("gateway.yaml: telemetry.log_format: must be json or text, got %q", cfg.Telemetry.LogFormat)
+	}
+	if rate := cfg.Telemetry.TraceSampleRate; rate < 0 || rate > 1 {
+		r.errorf("gateway.yaml: telemetry.trace_sample_rate: must be between 0 and 1, got %g", rate)
+	}
+	if cfg.Routing.Sticky.Enabled && cfg.Routing.Sticky.TTL <= 0 {
+		r.errorf("gateway.yaml: routing.sticky.ttl: must be positive when sticky routing is enabled")
+	}
+	for _, label := range sortedKeys(cfg.Telemetry.MetricLabels) {
+		l := cfg.Telemetry.MetricLabels[label]
+		switch label {
+		case "org", "team", "model":
+		default:
+			r.errorf("gateway.yaml: telemetry.metric_labels: unknown label %q (want org, team, or model)", label)
+			continue
+		}
+		switch l.Mode {
+		case "", "keep", "drop":
+		case "hash":
+			if l.Buckets <= 0 {
+				r.errorf("gateway.yaml: telemetry.metric_labels.%s: hash mode needs buckets > 0", label)
+			}
+		case "top_n":
+			if l.TopN <= 0 {
+				r.errorf("gateway.yaml: telemetry.metric_labels.%s: top_n mode needs top_n > 0", label)
+			}
+		default:
+			r.errorf("gateway.yaml: telemetry.metric_labels.%s: unknown mode %q", label, l.Mode)
+		}
+	}
+
+	inj := cfg.Filter.Injection
+	if inj.Enabled {
+		if inj.BlockThreshold < 0 || inj.BlockThreshold > 1 {
+			r.errorf("gateway.yaml: filter.injection.block_threshold: %v is outside [0, 1]", inj.BlockThreshold)
+		}
+		if inj.FlagThreshold < 0 || inj.FlagThreshold > 1 {
+			r.errorf("gateway.yaml: filter.injection.flag_threshold: %v is outside [0, 1]", inj.FlagThreshold)
+		}
+		if inj.FlagThreshold > inj.BlockThreshold {
+			r.errorf("gateway.yaml: filter.injection.flag_threshold: %v is above block_threshold %v", inj.FlagThreshold, inj.BlockThreshold)
+		}
+	}
+	if sim := inj.Similarity; inj.Enabled && sim.Enabled {
+		if sim.Threshold <= 0 || sim.Threshold > 1 {
+			r.errorf("gateway.yaml: filter.injection.similarity.threshold: %v is outside (0, 1]", sim.Threshold)
+		}
+		if sim.EmbeddingURL == "" || sim.EmbeddingModel == "" {
+			r.errorf("gateway.yaml: filter.injection.similarity: embedding_url and embedding_model are required")
+		}
+		switch sim.Store {
+		case "redis":
+			if sim.RedisKey == "" {
+				r.errorf("gateway.yaml: filter.injection.similarity.redis_key: required for the redis store")
+			}
+		case "pgvector":
+			if !validTableName.MatchString(sim.PGTable) {
+				r.errorf("gateway.yaml: filter.injection.similarity.pg_table: %q is not a valid table name", sim.PGTable)
+			}
+		default:
+			r.errorf("gateway.yaml: filter.injection.similarity.store: must be redis or pgvector, got %q", sim.Store)
+		}
+	}
+	if cls := cfg.Filter.Classification; cls.Enabled {
+		if cls.Address == "" {
+			r.errorf("gateway.yaml: filter.classification.address: required when enabled")
+		}
+		if cls.MinConfidence < 0 || cls.MinConfidence > 1 {
+			r.errorf("gateway.yaml: filter.classification.min_confidence: %v is outside [0, 1]", cls.MinConfidence)
+		}
+	}
+	for org, filters := range cfg.Filter.OrgFailOpen {
+		for name := range filters {
+			if !slices.Contains(FilterNames, name) {
+				r.errorf("gateway.yaml: filter.org_fail_open.%s: unknown filter %q (%s)", org, name, strings.Join(FilterNames, ", "))
+			}
+		}
+	}
+	if cfg.Filter.BypassMaxDuration < 0 {
+		r.errorf("gateway.yaml: filter.bypass_max_duration: must not be negative (0 disables bypass grants)")
+	}
+	if cfg.Filter.Policy.DecisionCacheTTL < 0 {
+		r.errorf("gateway.yaml: filter.policy.decision_cache_ttl: must not be negative (0 disables the cache)")
+	}
+	if cfg.Filter.Policy.Enabled && cfg.Filter.Policy.BundlePath == "" {
+		r.errorf("gateway.yaml: filter.policy.bundle_path: required when policy filter is enabled")
+	}
+
+	cb := cfg.Routing.CircuitBreaker
+	if cb.FailureThreshold <= 0 {
+		r.errorf("gateway.yaml: routing.circuit_breaker.failure_threshold: must be positive, got %d", cb.FailureThreshold)
+	}
+	if cb.ErrorRateThreshold < 0 || cb.ErrorRateThreshold > 1 {
+		r.errorf("gateway.yaml: routing.circuit_breaker.error_rate_threshold: %v is outside [0, 1]", cb.ErrorRateThreshold)
+	}
+	if h := cfg.Routing.QuotaHeadroom; h < 0 || h >= 1 {
+		r.errorf("gateway.yaml: routing.quota_headroom: %v is outside [0, 1)", h)
+	}
+	if cfg.Server.StreamKeepAliveInterval < 0 {
+		r.errorf("gateway.yaml: server.stream_keepalive_interval: must not be negative, got %s", cfg.Server.StreamKeepAliveInterval)
+	}
+	if cfg.Routing.StreamFirstChunkTimeout < 0 {
+		r.errorf("gateway.yaml: routing.stream_first_chunk_timeout: must not be negative, got %s", cfg.Routing.StreamFirstChunkTimeout)
+	}
+	if cfg.Routing.StreamChunkTimeout < 0 {
+		r.errorf("gateway.yaml: routing.stream_chunk_timeout: must not be negative, got %s", cfg.Routing.StreamChunkTimeout)
+	}
+	switch cfg.Routing.Strategy {
+	case "", RoutingStrategyPriority, RoutingStrategyCheapest:
+	default:
+		r.errorf("gateway.yaml: routing.strategy: must be %q or %q, got %q", RoutingStrategyPriority, RoutingStrategyCheapest, cfg.Routing.Strategy)
+	}
+	if cfg.Routing.MaxRetries < 0 {
+		r.errorf("gateway.yaml: routing.max_retries: must not be negative, got %d", cfg.Routing.MaxRetries)
+	}
+
+	if cfg.Auth.LocalCacheSize < 0 || cfg.Auth.LocalCacheTTL < 0 {
+		r.errorf("gateway.yaml: auth: local_cache_size and local_cache_ttl must not be negative")
+	}
+	if cfg.Idempotency.Enabled && cfg.Idempotency.TTL <= 0 {
+		r.errorf("gateway.yaml: idempotency.ttl: must be positive when idempotency is enabled, got %s", cfg.Idempotency.TTL)
+	}
+	if rc := cfg.ResponseCache; rc.ReplayChunkSize < 0 || rc.ReplayInterval < 0 {
+		r.errorf("gateway.yaml: response_cache: replay_chunk_size and replay_interval must not be negative")
+	} else if rc.Enabled && rc.TTL <= 0 {
+		r.errorf("gateway.yaml: response_cache.ttl: must be positive when the response cache is enabled, got %s", rc.TTL)
+	}
+	if b := cfg.Batch; b.Workers < 0 || b.MaxConcurrentPerOrg < 0 || b.MaxRequests < 0 || b.MaxBodyBytes < 0 || b.PollInterval < 0 || b.StaleAfter < 0 {
+		r.errorf("gateway.yaml: batch: values must not be negative")
+	} else if b.Enabled && (b.Workers == 0 || b.CompletionWindow <= 0) {
+		r.errorf("gateway.yaml: batch: workers and completion_window must be positive when batches are enabled")
+	}
+
+	if c := cfg.Conversations; c.MaxTurns < 0 || c.MaxTokens < 0 || c.MaxCostUSD < 0 {
+		r.errorf("gateway.yaml: conversations: limits must not be negative")
+	}
+	validateHooks(r, cfg.Hooks)
+	validateAccessWindows(r, cfg.Routing.AccessWindows)
+	for i, g := range cfg.Guardrails.Response {
+		if g.BannerPosition != "" && g.BannerPosition != "top" && g.BannerPosition != "bottom" {
+			r.errorf("gateway.yaml: guardrails.response[%d].banner_position: must be top or bottom, got %q", i, g.BannerPosition)
+		}
+		if !g.ClassificationBanner && !g.StripExternalLinks {
+			r.warnf("gateway.yaml: guardrails.response[%d]: rule has no actions enabled", i)
+		}
+	}
+
+	if cfg.Events.Enabled && cfg.Events.Backend != "kafka" && cfg.Events.Backend != "nats" && cfg.Events.Backend != "webhook" {
+		r.errorf("gateway.yaml: events.backend: must be kafka, nats, or webhook, got %q", cfg.Events.Backend)
+	}
+	if cfg.Events.Enabled && cfg.Events.Backend == "webhook" {
+		validateWebhooks(r, cfg.Events.Webhooks)
+	}
+	if cfg.SIEM.Enabled && cfg.SIEM.Format != "json" && cfg.SIEM.Format != "cef" {
+		r.errorf("gateway.yaml: siem.format: must be json or cef, got %q", cfg.SIEM.Format)
+	}
+	if cfg.Archive.Enabled && cfg.Archive.Bucket == "" {
+		r.errorf("gateway.yaml: archive.bucket: required when archive is enabled")
+	}
+	for i, c := range cfg.Archive.Classifications {
+		if _, ok := types.ParseClassification(c); !ok {
+			r.errorf("gateway.yaml: archive.classifications[%d]: unknown classification %q", i, c)
+		}
+	}
+	if cfg.DryRun.Latency < 0 {
+		r.errorf("gateway.yaml: dry_run.latency: must not be negative")
+	}
+	if cfg.DryRun.Enabled {
+		r.warnf("gateway.yaml: dry_run.enabled: provider calls are simulated and no real completions are served")
+	}
+	switch cfg.Fixtures.Mode {
+	case "":
+	case FixturesRecord, FixturesReplay:
+		if cfg.Fixtures.Dir == "" {
+			r.errorf("gateway.yaml: fixtures.dir: required when fixtures.mode is set")
+		}
+		if cfg.DryRun.Enabled {
+			r.errorf("gateway.yaml: fixtures.mode: cannot be used with dry_run")
+		}
+		r.warnf("gateway.yaml: fixtures.mode: provider traffic is %sed", cfg.Fixtures.Mode)
+	default:
+		r.errorf("gateway.yaml: fixtures.mode: must be %s or %s, got %q", FixturesRecord, FixturesReplay, cfg.Fixtures.Mode)
+	}
+}
+
+// validateEgress checks the egress allowlist parses and that every
+// provider's base_url and proxy could be reached through it.
+func validateEgress(r *ValidationReport, e EgressConfig, providers *ProvidersConfig) {
+	allow, err := egress.Parse(e.Allowlist)
+	if err != nil {
+		r.errorf("gateway.yaml: egress.allowlist: %v", err)
+		return
+	}
+	if allow == nil || providers == nil {
+		return
+	}
+	for _, name := range sortedKeys(providers.Providers) {
+		p := providers.Providers[name]
+		if p.Type == ProviderTypeMock {
+			continue
+		}
+		if u, err := url.Parse(p.BaseURL); err == nil && !allow.MayAllow(u.Hostname()) {
+			r.errorf("providers.yaml: providers.%s.base_url: host %q is not on egress.allowlist", name, u.Hostname())
+		}
+		if p.Proxy != "" && p.Proxy != ProviderProxyFromEnv {
+			if u, err := url.Parse(p.Proxy); err == nil && !allow.MayAllow(u.Hostname()) {
+				r.errorf("providers.yaml: providers.%s.proxy: host %q is not on egress.allowlist", name, u.Hostname())
+			}
+		}
+	}
+}
+
+// validateChaos checks fault rates are probabilities and that faults name
+// configured providers.
+func validateChaos(r *ValidationReport, c ChaosConfig, providers *ProvidersConfig) {
+	for _, name := range sortedKeys(c.Providers) {
+		f := c.Providers[name]
+		if f.Latency < 0 {
+			r.errorf("gateway.yaml: chaos.providers.%s.latency: must not be negative", name)
+		}
+		if f.ErrorRate < 0 || f.ErrorRate > 1 || f.MalformedChunkRate < 0 || f.MalformedChunkRate > 1 {
+			r.errorf("gateway.yaml: chaos.providers.%s: error_rate and malformed_chunk_rate must be between 0 and 1", name)
+		}
+		if f.ErrorStatus != 0 && (f.ErrorStatus < 400 || f.ErrorStatus > 599) {
+			r.errorf("gateway.yaml: chaos.providers.%s.error_status: must be a 4xx or 5xx status, got %d", name, f.ErrorStatus)
+		}
+		if providers == nil {
+			continue
+		}
+		if _, ok := providers.Providers[name]; !ok {
+			r.warnf("gateway.yaml: chaos.providers.%s: no such provider in providers.yaml", name)
+		}
+	}
+	if c.Enabled && len(c.Providers) > 0 {
+		r.warnf("gateway.yaml: chaos.enabled: faults are injected into provider calls")
+	}
+}
+
+// validateWebhooks checks each webhook endpoint has an http(s) URL and a
+// signing secret.
+func validateWebhooks(r *ValidationReport, hooks []WebhookConfig) {
+	if len(hooks) == 0 {
+		r.errorf("gateway.yaml: events.webhooks: at least one endpoint is required for the webhook backend")
+	}
+	for i, h := range hooks {
+		if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			r.errorf("gateway.yaml: events.webhooks[%d].url: must be an http or https URL, got %q", i, h.URL)
+		}
+		if h.Secret == "" {
+			r.errorf("gateway.yaml: events.webhooks[%d].secret: required to sign deliveries", i)
+		}
+	}
+}
+
+// validateBlockResponses checks each block response mode, and that a
+// redirect names a model defined in models.yaml.
+func validateBlockResponses(r *ValidationReport, f FilterConfig, models *ModelsConfig) {
+	check := func(path string, br BlockResponseConfig) {
+		switch br.Mode {
+		case "", BlockResponseError, BlockResponseMessage:
+		case BlockResponseRedirect:
+			if br.Model == "" {
+				r.errorf("gateway.yaml: %s.model: required for redirect", path)
+			} else if _, ok := models.Models[br.Model]; !ok {
+				r.errorf("gateway.yaml: %s.model: %q is not defined in models.yaml", path, br.Model)
+			}
+		default:
+			r.errorf("gateway.yaml: %s.mode: unknown mode %q (error, message, redirect)", path, br.Mode)
+		}
+	}
+	check("filter.block_response", f.BlockResponse)
+	for _, org := range sortedKeys(f.OrgBlockResponse) {
+		check("filter.org_block_response."+org, f.OrgBlockResponse[org])
+	}
+}
+
+func validateHooks(r *ValidationReport, hooks []HookConfig) {
+	seen := make(map[string]bool, len(hooks))
+	for i, h := range hooks {
+		if h.Name == "" {
+			r.errorf("gateway.yaml: hooks[%d].name: required", i)
+		} else if seen[h.Name] {
+			r.errorf("gateway.yaml: hooks[%d].name: duplicate hook %q", i, h.Name)
+		}
+		seen[h.Name] = true
+
+		switch h.Type {
+		case HookTypeHTTP:
+			u, err := url.Parse(h.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				r.errorf("gateway.yaml: hooks[%d].url: must be an http(s) URL, got %q", i, h.URL)
+			}
+		case HookTypeGo:
+		default:
+			r.errorf("gateway.yaml: hooks[%d].type: must be http or go, got %q", i, h.Type)
+		}
+		for _, s := range h.Stages {
+			if s != HookStageRequest && s != HookStageResponse {
+				r.errorf("gateway.yaml: hooks[%d].stages: unknown stage %q", i, s)
+			}
+		}
+		if h.Timeout < 0 {
+			r.errorf("gateway.yaml: hooks[%d].timeout: must not be negative, got %s", i, h.Timeout)
+		}
+	}
+}
+
+func validateAccessWindows(r *ValidationReport, windows []AccessWindowConfig) {
+	seen := make(map[string]bool, len(windows))
+	for i, w := range windows {
+		field := fmt.Sprintf("gateway.yaml: routing.access_windows[%d]", i)
+		if w.Name == "" {
+			r.errorf("%s.name: required", field)
+		} else if seen[w.Name] {
+			r.errorf("%s.name: duplicate window %q", field, w.Name)
+		}
+		seen[w.Name] = true
+
+		if w.Type != AccessWindowMaintenance && w.Type != AccessWindowOfficeHours {
+			r.errorf("%s.type: must be maintenance or office_hours, got %q", field, w.Type)
+		}
+		if w.Timezone != "" {
+			if _, err := time.LoadLocation(w.Timezone); err != nil {
+				r.errorf("%s.timezone: unknown time zone %q", field, w.Timezone)
+			}
+		}
+		for _, d := range w.Days {
+			if _, ok := ParseWeekday(d); !ok {
+				r.errorf("%s.days: unknown weekday %q", field, d)
+			}
+		}
+
+		oneOff := !w.StartsAt.IsZero() || !w.EndsAt.IsZero()
+		switch {
+		case oneOff && w.Type == AccessWindowOfficeHours:
+			r.errorf("%s: starts_at and ends_at are for one-off maintenance; office hours recur", field)
+		case oneOff && (w.Start != "" || w.End != "" || len(w.Days) > 0):
+			r.errorf("%s: set either starts_at and ends_at or start, end, and days", field)
+		case oneOff:
+			if !w.EndsAt.After(w.StartsAt) {
+				r.errorf("%s.ends_at: must be after starts_at", field)
+			}
+		default:
+			start, startErr := ParseClock(w.Start)
+			if startErr != nil {
+				r.errorf("%s.start: %v", field, startErr)
+			}
+			end, endErr := ParseClock(w.End)
+			if endErr != nil {
+				r.errorf("%s.end: %v", field, endErr)
+			}
+			if startErr == nil && endErr == nil && start == end {
+				r.errorf("%s: start and end are both %s", field, w.Start)
+			}
+		}
+	}
+}
+
+func validateProviders(r *ValidationReport, providers *ProvidersConfig) {
+	if len(providers.Providers) == 0 {
+		r.errorf("providers.yaml: providers: no providers defined")
+	}
+	for _, name := range sortedKeys(providers.Providers) {
+		p := providers.Providers[name]
+		switch p.Type {
+		case "openai", "anthropic", "azure_openai":
+		case ProviderTypeMock:
+			if m := p.Mock; m != nil && (m.Latency < 0 || m.ChunkSize < 0 || m.ChunkDelay < 0) {
+				r.errorf("providers.yaml: providers.%s.mock: latency, chunk_size, and chunk_delay must not be negative", name)
+			}
+		case "":
+			r.errorf("providers.yaml: providers.%s.type: required", name)
+		default:
+			r.warnf("providers.yaml: providers.%s.type: unknown type %q will be treated as openai-compatible", name, p.Type)
+		}
+		if p.BaseURL == "" && p.Type != ProviderTypeMock {
+			r.errorf("providers.yaml: providers.%s.base_url: required", name)
+		}
+		if p.Mock != nil && p.Type != ProviderTypeMock {
+			r.warnf("providers.yaml: providers.%s.mock: ignored unless type is %s", name, ProviderTypeMock)
+		}
+		if p.Timeout < 0 {
+			r.errorf("providers.yaml: providers.%s.timeout: must not be negative", name)
+		}
+		if p.Auth != nil {
+			validateProviderAuth(r, name, p)
+		}
+		if p.Type == "anthropic" {
+			if p.Organization != "" || p.Project != "" {
+				r.warnf("providers.yaml: providers.%s: organization and project are ignored for anthropic providers", name)
+			}
+			for _, team := range sortedKeys(p.Teams) {
+				if t := p.Teams[team]; t.Organization != "" || t.Project != "" {
+					r.warnf("providers.yaml: providers.%s.teams.%s: organization and project are ignored for anthropic providers", name, team)
+				}
+			}
+		}
+		if rl := p.RateLimit; rl != nil && (rl.RPM < 0 || rl.TPM < 0 || rl.MaxWait < 0) {
+			r.errorf("providers.yaml: providers.%s.rate_limit: rpm, tpm, and max_wait must not be negative", name)
+		}
+		if c := p.Connections; c != nil && (c.MaxConnsPerHost < 0 || c.MaxIdleConnsPerHost < 0 ||
+			c.IdleConnTimeout < 0 || c.DialTimeout < 0 || c.TLSHandshakeTimeout < 0 || c.KeepAlive < 0) {
+			r.errorf("providers.yaml: providers.%s.connections: limits and timeouts must not be negative", name)
+		}
+		if p.Proxy != "" && p.Proxy != ProviderProxyFromEnv {
+			if u, err := url.Parse(p.Proxy); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+				r.errorf("providers.yaml: providers.%s.proxy: must be an http, https, or socks5 URL, or %q", name, ProviderProxyFromEnv)
+			}
+		}
+		if t := p.TLS; t != nil {
+			if (t.CertFile == "") != (t.KeyFile == "") {
+				r.errorf("providers.yaml: providers.%s.tls: cert_file and key_file must be set together", name)
+			}
+			switch t.MinVersion {
+			case "", "1.2", "1.3":
+			default:
+				r.errorf("providers.yaml: providers.%s.tls.min_version: must be 1.2 or 1.3", name)
+			}
+			if t.InsecureSkipVerify {
+				r.warnf("providers.yaml: providers.%s.tls.insecure_skip_verify: provider certificates are not verified", name)
+			}
+		}
+	}
+}
+
+func validateProviderAuth(r *ValidationReport, name string, p ProviderConfig) {
+	a := p.Auth
+	if p.Type == "anthropic" {
+		r.errorf("providers.yaml: providers.%s.auth: not supported for anthropic providers", name)
+	}
+	switch a.Type {
+	case ProviderAuthAzureClientCredentials:
+		if a.TenantID == "" || a.ClientID == "" || a.ClientSecret == "" {
+			r.errorf("providers.yaml: providers.%s.auth: tenant_id, client_id, and client_secret are required for %s", name, a.Type)
+		}
+	case ProviderAuthAzureWorkloadIdentity:
+		if a.ClientSecret != "" {
+			r.warnf("providers.yaml: providers.%s.auth.client_secret: ignored for %s", name, a.Type)
+		}
+	default:
+		r.errorf("providers.yaml: providers.%s.auth.type: must be %s or %s", name, ProviderAuthAzureClientCredentials, ProviderAuthAzureWorkloadIdentity)
+	}
+	if p.APIKey != "" {
+		r.warnf("providers.yaml: providers.%s.api_key: ignored, tokens come from auth", name)
+	}
+}
+
+func validateModels(r *ValidationReport, models *ModelsConfig, providers *ProvidersConfig) {
+	if len(models.Models) == 0 {
+		r.errorf("models.yaml: models: no models defined")
+	}
+	for _, name := range sortedKeys(models.Models) {
+		m := models.Models[name]
+		if m.ContextWindow < 0 {
+			r.errorf("models.yaml: models.%s.context_window: must not be negative", name)
+		}
+		if d := m.Deprecated; d != nil {
+			if d.Sunset.IsZero() {
+				r.errorf("models.yaml: models.%s.deprecated.sunset: required", name)
+			}
+			if _, ok := models.Models[d.Replacement]; d.Replacement != "" && !ok {
+				r.errorf("models.yaml: models.%s.deprecated.replacement: model %q is not defined", name, d.Replacement)
+			} else if d.Replacement == name {
+				r.errorf("models.yaml: models.%s.deprecated.replacement: must not be the model itself", name)
+			}
+		}
+		validateRoute(r, models, providers, "models."+name+".primary", m.Primary)
+		for i, fb := range m.Fallback {
+			validateRoute(r, models, providers, fmt.Sprintf("models.%s.fallback[%d]", name, i), fb)
+		}
+	}
+
+	for _, provider := range sortedKeys(models.Pricing) {
+		if _, ok := providers.Providers[provider]; !ok {
+			r.warnf("models.yaml: pricing.%s: provider is not defined in providers.yaml", provider)
+		}
+		for _, model := range sortedKeys(models.Pricing[provider]) {
+			price := models.Pricing[provider][model]
+			if price.Input < 0 || price.Output < 0 {
+				r.errorf("models.yaml: pricing.%s.%s: prices must not be negative", provider, model)
+			}
+		}
+	}
+
+	for _, provider := range sortedKeys(models.Capabilities) {
+		if _, ok := providers.Providers[provider]; !ok {
+			r.warnf("models.yaml: capabilities.%s: provider is not defined in providers.yaml", provider)
+		}
+		for _, model := range sortedKeys(models.Capabilities[provider]) {
+			if models.Capabilities[provider][model].MaxOutputTokens < 0 {
+				r.errorf("models.yaml: capabilities.%s.%s.max_output_tokens: must not be negative", provider, model)
+			}
+		}
+	}
+}
+
+func validateRoute(r *ValidationReport, models *ModelsConfig, providers *ProvidersConfig, path string, route ProviderRoute) {
+	if route.Provider == "" {
+		r.errorf("models.yaml: %s.provider: required", path)
+		return
+	}
+	if _, ok := providers.Providers[route.Provider]; !ok {
+		r.errorf("models.yaml: %s.provider: %q is not defined in providers.yaml", path, route.Provider)
+	}
+	if route.Model == "" {
+		r.errorf("models.yaml: %s.model: required", path)
+	}
+	if route.ClassificationCeiling != "" {
+		if _, ok := types.ParseClassification(route.ClassificationCeiling); !ok {
+			r.errorf("models.yaml: %s.classification_ceiling: unknown classification %q (want PUBLIC, INTERNAL, CONFIDENTIAL, or RESTRICTED)", path, route.ClassificationCeiling)
+		}
+	}
+	if route.Model != "" {
+		if _, ok := models.Pricing[route.Provider][route.Model]; !ok {
+			r.warnf("models.yaml: %s: no pricing for %s/%s, cost will be reported as 0", path, route.Provider, route.Model)
+		}
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
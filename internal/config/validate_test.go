@@ -0,0 +1,448 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func validTestConfigs() (*Config, *ModelsConfig, *ProvidersConfig) {
+	cfg := DefaultConfig()
+	models := &ModelsConfig{
+		Models: map[string]ModelMapping{
+			"aegis-gpt4": {
+				Primary:  ProviderRoute{Provider: "openai", Model: "gpt-4o", ClassificationCeiling: "CONFIDENTIAL"},
+				Fallback: []ProviderRoute{{Provider: "anthropic", Model: "claude-sonnet", ClassificationCeiling: "INTERNAL"}},
+			},
+		},
+		Pricing: map[string]map[string]PriceEntry{
+			"openai":    {"gpt-4o": {Input: 0.0025, Output: 0.01}},
+			"anthropic": {"claude-sonnet": {Input: 0.003, Output: 0.015}},
+		},
+	}
+	providers := &ProvidersConfig{
+		Providers: map[string]ProviderConfig{
+			"openai":    {Type: "openai", BaseURL: "https://api.openai.com/v1"},
+			"anthropic": {Type: "anthropic", BaseURL: "https://api.anthropic.com/v1"},
+		},
+	}
+	return cfg, models, providers
+}
+
+func TestValidate_Valid(t *testing.T) {
+	report := Validate(validTestConfigs())
+	if err := report.Err(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+	if len(report.Warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", report.Warnings)
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config, *ModelsConfig, *ProvidersConfig)
+		want   string
+	}{
+		{
+			name: "unknown provider in fallback",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				mm := m.Models["aegis-gpt4"]
+				mm.Fallback[0].Provider = "bedrock"
+				m.Models["aegis-gpt4"] = mm
+			},
+			want: `models.aegis-gpt4.fallback[0].provider: "bedrock" is not defined in providers.yaml`,
+		},
+		{
+			name: "bad classification ceiling",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				mm := m.Models["aegis-gpt4"]
+				mm.Primary.ClassificationCeiling = "SECRET"
+				m.Models["aegis-gpt4"] = mm
+			},
+			want: `models.aegis-gpt4.primary.classification_ceiling: unknown classification "SECRET"`,
+		},
+		{
+			name: "grpc port collides with http port",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Server.GRPCPort = c.Server.Port
+			},
+			want: "gateway.yaml: server.grpc_port: 8080 collides with another listener",
+		},
+		{
+			name: "negative context window",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				mm := m.Models["aegis-gpt4"]
+				mm.ContextWindow = -1
+				m.Models["aegis-gpt4"] = mm
+			},
+			want: "models.aegis-gpt4.context_window: must not be negative",
+		},
+		{
+			name: "deprecation without sunset",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				mm := m.Models["aegis-gpt4"]
+				mm.Deprecated = &ModelDeprecation{Replacement: "aegis-gpt4"}
+				m.Models["aegis-gpt4"] = mm
+			},
+			want: "models.aegis-gpt4.deprecated.sunset: required",
+		},
+		{
+			name: "unknown deprecation replacement",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				mm := m.Models["aegis-gpt4"]
+				mm.Deprecated = &ModelDeprecation{Sunset: time.Now(), Replacement: "gpt-5"}
+				m.Models["aegis-gpt4"] = mm
+			},
+			want: `models.aegis-gpt4.deprecated.replacement: model "gpt-5" is not defined`,
+		},
+		{
+			name: "unknown block response mode",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Filter.BlockResponse.Mode = "silent"
+			},
+			want: `filter.block_response.mode: unknown mode "silent"`,
+		},
+		{
+			name: "block redirect to undefined model",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Filter.OrgBlockResponse = map[string]BlockResponseConfig{"org-1": {Mode: BlockResponseRedirect, Model: "internal-llm"}}
+			},
+			want: `filter.org_block_response.org-1.model: "internal-llm" is not defined in models.yaml`,
+		},
+		{
+			name: "invalid egress allowlist entry",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Egress.Allowlist = []string{"10.0.0.0/40"}
+			},
+			want: `egress.allowlist: invalid CIDR "10.0.0.0/40"`,
+		},
+		{
+			name: "provider off the egress allowlist",
+			mutate: func(c *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				c.Egress.Allowlist = []string{"api.anthropic.com"}
+				p.Providers["openai"] = ProviderConfig{Type: "openai", BaseURL: "https://collector.example.net/v1"}
+			},
+			want: `providers.openai.base_url: host "collector.example.net" is not on egress.allowlist`,
+		},
+		{
+			name: "negative mock chunk size",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				p.Providers["mock"] = ProviderConfig{Type: ProviderTypeMock, Mock: &ProviderMockConfig{ChunkSize: -1}}
+			},
+			want: "providers.mock.mock: latency, chunk_size, and chunk_delay must not be negative",
+		},
+		{
+			name: "unknown fixtures mode",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Fixtures = FixturesConfig{Mode: "rewind", Dir: "testdata"}
+			},
+			want: `fixtures.mode: must be record or replay, got "rewind"`,
+		},
+		{
+			name: "fixtures without a directory",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Fixtures.Mode = FixturesReplay
+			},
+			want: "fixtures.dir: required when fixtures.mode is set",
+		},
+		{
+			name: "chaos rate above one",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Chaos.Providers = map[string]ChaosFaultConfig{"openai": {ErrorRate: 1.5}}
+			},
+			want: "chaos.providers.openai: error_rate and malformed_chunk_rate must be between 0 and 1",
+		},
+		{
+			name: "chaos error status not an error",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Chaos.Providers = map[string]ChaosFaultConfig{"openai": {ErrorRate: 0.1, ErrorStatus: 200}}
+			},
+			want: "chaos.providers.openai.error_status: must be a 4xx or 5xx status, got 200",
+		},
+		{
+			name: "negative dry-run latency",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.DryRun.Latency = -time.Second
+			},
+			want: "dry_run.latency: must not be negative",
+		},
+		{
+			name: "webhook backend without endpoints",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Events.Enabled, c.Events.Backend = true, "webhook"
+			},
+			want: "events.webhooks: at least one endpoint is required",
+		},
+		{
+			name: "unsigned webhook",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Events.Enabled, c.Events.Backend = true, "webhook"
+				c.Events.Webhooks = []WebhookConfig{{URL: "https://hooks.example.com/aegis"}}
+			},
+			want: "events.webhooks[0].secret: required to sign deliveries",
+		},
+		{
+			name: "negative max output tokens",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				m.Capabilities = map[string]map[string]ModelCapabilities{"openai": {"gpt-4o": {MaxOutputTokens: -1}}}
+			},
+			want: "models.yaml: capabilities.openai.gpt-4o.max_output_tokens: must not be negative",
+		},
+		{
+			name: "negative price",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				m.Pricing["openai"]["gpt-4o"] = PriceEntry{Input: -1}
+			},
+			want: "pricing.openai.gpt-4o: prices must not be negative",
+		},
+		{
+			name: "unknown routing strategy",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Routing.Strategy = "fastest"
+			},
+			want: `routing.strategy: must be "priority" or "cheapest", got "fastest"`,
+		},
+		{
+			name: "no providers",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				p.Providers = nil
+			},
+			want: "providers.yaml: providers: no providers defined",
+		},
+		{
+			name: "no models",
+			mutate: func(_ *Config, m *ModelsConfig, _ *ProvidersConfig) {
+				m.Models = nil
+			},
+			want: "models.yaml: models: no models defined",
+		},
+		{
+			name: "provider without type",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				p.Providers["openai"] = ProviderConfig{BaseURL: "https://api.openai.com/v1"}
+			},
+			want: "providers.openai.type: required",
+		},
+		{
+			name: "provider without base_url",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				p.Providers["openai"] = ProviderConfig{Type: "openai"}
+			},
+			want: "providers.openai.base_url: required",
+		},
+		{
+			name: "client credentials without secret",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				op := p.Providers["openai"]
+				op.Auth = &ProviderAuthConfig{Type: ProviderAuthAzureClientCredentials, TenantID: "t", ClientID: "c"}
+				p.Providers["openai"] = op
+			},
+			want: "providers.openai.auth: tenant_id, client_id, and client_secret are required",
+		},
+		{
+			name: "negative provider dial timeout",
+			mutate: func(_ *Config, _ *ModelsConfig, p *ProvidersConfig) {
+				op := p.Providers["openai"]
+				op.Connections = &ProviderConnectionsConfig{DialTimeout: -1}
+				p.Providers["openai"] = op
+			},
+			want: "providers.openai.connections: limits and timeouts must not be negative",
+		},
+		{
+			name: "trace sample rate above one",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Telemetry.TraceSampleRate = 10
+			},
+			want: "gateway.yaml: telemetry.trace_sample_rate: must be between 0 and 1, got 10",
+		},
+		{
+			name: "metric label hash without buckets",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Telemetry.MetricLabels = map[string]MetricLabelConfig{"model": {Mode: "hash"}}
+			},
+			want: "gateway.yaml: telemetry.metric_labels.model: hash mode needs buckets > 0",
+		},
+		{
+			name: "metric label limit on unknown label",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Telemetry.MetricLabels = map[string]MetricLabelConfig{"provider": {Mode: "drop"}}
+			},
+			want: `gateway.yaml: telemetry.metric_labels: unknown label "provider" (want org, team, or model)`,
+		},
+		{
+			name: "negative local key cache size",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Auth.LocalCacheSize = -1
+			},
+			want: "gateway.yaml: auth: local_cache_size and local_cache_ttl must not be negative",
+		},
+		{
+			name: "http hook without url",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Hooks = []HookConfig{{Name: "disclaimer", Type: HookTypeHTTP, Enabled: true}}
+			},
+			want: `gateway.yaml: hooks[0].url: must be an http(s) URL, got ""`,
+		},
+		{
+			name: "inverted injection thresholds",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Filter.Injection.FlagThreshold = 0.95
+			},
+			want: "filter.injection.flag_threshold: 0.95 is above block_threshold 0.9",
+		},
+		{
+			name: "unquotable pgvector table",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				sim := &c.Filter.Injection.Similarity
+				sim.Enabled, sim.EmbeddingURL, sim.EmbeddingModel = true, "http://embed", "m"
+				sim.Store, sim.PGTable = "pgvector", "jailbreaks; DROP TABLE x"
+			},
+			want: `filter.injection.similarity.pg_table: "jailbreaks; DROP TABLE x" is not a valid table name`,
+		},
+		{
+			name: "access window with unknown time zone",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Routing.AccessWindows = []AccessWindowConfig{{
+					Name: "office", Type: AccessWindowOfficeHours, Timezone: "Mars/Olympus", Start: "09:00", End: "17:00",
+				}}
+			},
+			want: `routing.access_windows[0].timezone: unknown time zone "Mars/Olympus"`,
+		},
+		{
+			name: "recurring office hours without end",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Routing.AccessWindows = []AccessWindowConfig{{Name: "office", Type: AccessWindowOfficeHours, Start: "09:00"}}
+			},
+			want: `routing.access_windows[0].end: invalid time of day ""`,
+		},
+		{
+			name: "sticky routing without ttl",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Routing.Sticky = StickyRoutingConfig{Enabled: true}
+			},
+			want: "routing.sticky.ttl: must be positive when sticky routing is enabled",
+		},
+		{
+			name: "unknown filter in org fail-open override",
+			mutate: func(c *Config, _ *ModelsConfig, _ *ProvidersConfig) {
+				c.Filter.OrgFailOpen = map[string]map[string]bool{"org-1": {"dlp": true}}
+			},
+			want: `filter.org_fail_open.org-1: unknown filter "dlp"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, models, providers := validTestConfigs()
+			tt.mutate(cfg, models, providers)
+
+			err := Validate(cfg, models, providers).Err()
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got:\n%v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFilterConfig_FailOpen(t *testing.T) {
+	c := DefaultConfig().Filter
+	c.OrgFailOpen = map[string]map[string]bool{"org-1": {"pii": true, "injection": false}}
+
+	tests := []struct {
+		filter, org string
+		want        bool
+	}{
+		{"pii", "org-2", false},
+		{"pii", "org-1", true},
+		{"injection", "org-2", true},
+		{"injection", "org-1", false},
+		{"policy", "org-1", false},
+		{"classification", "org-1", true},
+	}
+	for _, tt := range tests {
+		if got := c.FailOpen(tt.filter, tt.org); got != tt.want {
+			t.Errorf("FailOpen(%q, %q) = %v, want %v", tt.filter, tt.org, got, tt.want)
+		}
+	}
+}
+
+func TestFilterConfig_ShowBlockDetails(t *testing.T) {
+	c := DefaultConfig().Filter
+	c.OrgBlockDetails = map[string]bool{"org-opaque": false}
+	if !c.ShowBlockDetails("org-1") {
+		t.Error("block details should default on")
+	}
+	if c.ShowBlockDetails("org-opaque") {
+		t.Error("org_block_details should override block_details")
+	}
+}
+
+func TestFilterConfig_BlockResponseFor(t *testing.T) {
+	c := DefaultConfig().Filter
+	c.OrgBlockResponse = map[string]BlockResponseConfig{"org-1": {Mode: BlockResponseMessage}}
+	if got := c.BlockResponseFor("org-1"); got.Mode != BlockResponseMessage || got.Message != c.BlockResponse.Message {
+		t.Errorf("org entry without a message should take block_response's, got %+v", got)
+	}
+	if got := c.BlockResponseFor("org-2"); got.Mode != BlockResponseError {
+		t.Errorf("default mode = %q, want error", got.Mode)
+	}
+}
+
+func TestValidate_AnthropicAccountIsWarning(t *testing.T) {
+	cfg, models, providers := validTestConfigs()
+	p := providers.Providers["anthropic"]
+	p.Teams = map[string]ProviderTeamConfig{"team-ml": {Project: "proj_ml"}}
+	providers.Providers["anthropic"] = p
+
+	report := Validate(cfg, models, providers)
+	if err := report.Err(); err != nil {
+		t.Fatalf("expected a warning only, got %v", err)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "providers.anthropic.teams.team-ml") {
+		t.Errorf("expected one ignored-project warning, got %v", report.Warnings)
+	}
+}
+
+func TestValidate_MockProviderNeedsNoBaseURL(t *testing.T) {
+	cfg, models, providers := validTestConfigs()
+	cfg.Egress.Allowlist = []string{"api.openai.com", "api.anthropic.com"}
+	providers.Providers["mock"] = ProviderConfig{Type: ProviderTypeMock}
+
+	report := Validate(cfg, models, providers)
+	if err := report.Err(); err != nil || len(report.Warnings) != 0 {
+		t.Errorf("expected a clean report, got %v, %v", err, report.Warnings)
+	}
+}
+
+func TestProviderConfig_Account(t *testing.T) {
+	p := ProviderConfig{Organization: "org-a", Project: "proj-a", APIVersion: "v1",
+		Teams: map[string]ProviderTeamConfig{"team-ml": {Project: "proj-ml"}}}
+	if org, project, version := p.Account("team-ml"); org != "org-a" || project != "proj-ml" || version != "v1" {
+		t.Errorf("team override: got %s, %s, %s", org, project, version)
+	}
+	if _, project, _ := p.Account("team-web"); project != "proj-a" {
+		t.Errorf("other teams should use the provider's project, got %s", project)
+	}
+}
+
+func TestValidate_MissingPricingIsWarning(t *testing.T) {
+	cfg, models, providers := validTestConfigs()
+	delete(models.Pricing, "anthropic")
+
+	report := Validate(cfg, models, providers)
+	if err := report.Err(); err != nil {
+		t.Fatalf("missing pricing should not be an error, got %v", err)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "no pricing for anthropic/claude-sonnet") {
+		t.Errorf("expected one pricing warning, got %v", report.Warnings)
+	}
+}
@@ -0,0 +1,52 @@
+// Package configcheck validates a configuration directory offline. It backs
+// both "gateway validate" and "aegisctl config validate".
+package configcheck
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter/policy"
+)
+
+// Run loads and cross-checks the config directory, compiles the Rego bundle
+// when the policy filter is enabled, and prints each problem to out. It
+// returns the process exit code: 0 when the config is deployable, 1 otherwise.
+func Run(configDir string, out io.Writer) int {
+	cfg, models, providers, err := config.LoadDir(configDir)
+	if err != nil {
+		fmt.Fprintf(out, "error: %v\n", err)
+		return 1
+	}
+
+	report := config.Validate(cfg, models, providers)
+
+	if cfg.Filter.Policy.Enabled && cfg.Filter.Policy.BundlePath != "" {
+		modules, err := policy.LoadRegoFiles(cfg.Filter.Policy.BundlePath)
+		switch {
+		case err != nil:
+			report.Errors = append(report.Errors, fmt.Sprintf("gateway.yaml: filter.policy.bundle_path: %v", err))
+		case len(modules) == 0:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("gateway.yaml: filter.policy.bundle_path: no .rego files in %s, all requests will be denied", cfg.Filter.Policy.BundlePath))
+		default:
+			evaluator := policy.NewEvaluator(func() config.PolicyFilterConfig { return cfg.Filter.Policy })
+			if err := evaluator.LoadFromModules(modules); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", cfg.Filter.Policy.BundlePath, err))
+			}
+		}
+	}
+
+	for _, w := range report.Warnings {
+		fmt.Fprintf(out, "warning: %s\n", w)
+	}
+	for _, e := range report.Errors {
+		fmt.Fprintf(out, "error: %s\n", e)
+	}
+	if len(report.Errors) > 0 {
+		fmt.Fprintf(out, "%s: %d errors, %d warnings\n", configDir, len(report.Errors), len(report.Warnings))
+		return 1
+	}
+	fmt.Fprintf(out, "%s: configuration OK (%d warnings)\n", configDir, len(report.Warnings))
+	return 0
+}
@@ -0,0 +1,150 @@
+// Package egress restricts which destinations the gateway connects to, so a
+// tampered provider config cannot send prompts to an arbitrary endpoint.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// ErrDenied is returned for a connection to a destination not on the
+// allowlist.
+var ErrDenied = errors.New("egress denied")
+
+// validHostname matches a DNS name, optionally with a leading "*." label.
+var validHostname = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)*[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// Allowlist is a set of permitted destinations: hostnames, "*.suffix"
+// wildcards matching any subdomain, IP addresses, and CIDR ranges. A nil
+// Allowlist permits everything.
+type Allowlist struct {
+	hosts    map[string]bool
+	suffixes []string // ".example.com" for "*.example.com"
+	nets     []*net.IPNet
+}
+
+// Parse builds an allowlist from entries. It returns nil, permitting
+// everything, when entries is empty.
+func Parse(entries []string) (*Allowlist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	a := &Allowlist{hosts: map[string]bool{}}
+	for _, e := range entries {
+		e = normalize(e)
+		switch {
+		case strings.Contains(e, "/"):
+			_, n, err := net.ParseCIDR(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", e)
+			}
+			a.nets = append(a.nets, n)
+		case net.ParseIP(e) != nil:
+			ip := net.ParseIP(e)
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			a.nets = append(a.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case validHostname.MatchString(e):
+			if suffix, ok := strings.CutPrefix(e, "*"); ok {
+				a.suffixes = append(a.suffixes, suffix)
+			} else {
+				a.hosts[e] = true
+			}
+		default:
+			return nil, fmt.Errorf("invalid entry %q: want a hostname, *.domain, IP, or CIDR", e)
+		}
+	}
+	return a, nil
+}
+
+func normalize(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// AllowsHost reports whether host, a name or an IP literal, is on the list
+// by itself. A name outside it may still resolve into an allowed network.
+func (a *Allowlist) AllowsHost(host string) bool {
+	if a == nil {
+		return true
+	}
+	host = normalize(host)
+	if ip := net.ParseIP(host); ip != nil {
+		return a.AllowsIP(ip)
+	}
+	if a.hosts[host] {
+		return true
+	}
+	for _, s := range a.suffixes {
+		if strings.HasSuffix(host, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether ip is in an allowed network.
+func (a *Allowlist) AllowsIP(ip net.IP) bool {
+	if a == nil {
+		return true
+	}
+	for _, n := range a.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// MayAllow reports whether a connection to host could be allowed: by name,
+// or, for a name when networks are listed, by the addresses it resolves to,
+// which only a dial can tell.
+func (a *Allowlist) MayAllow(host string) bool {
+	return a.AllowsHost(host) || (len(a.nets) > 0 && net.ParseIP(normalize(host)) == nil)
+}
+
+// DialContext wraps dial to refuse destinations not on the list. A name
+// allowed by itself is dialed as usual; any other name is resolved and
+// only its addresses in an allowed network are dialed, so the check and
+// the connection see the same address.
+func (a *Allowlist) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if a == nil {
+		return dial
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if a.AllowsHost(host) {
+			return dial(ctx, network, addr)
+		}
+		if len(a.nets) == 0 || net.ParseIP(host) != nil {
+			return nil, fmt.Errorf("%w: %s is not on the egress allowlist", ErrDenied, host)
+		}
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range addrs {
+			if !a.AllowsIP(ip.IP) {
+				continue
+			}
+			c, err := dial(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+			if err == nil {
+				return c, nil
+			}
+			lastErr = err
+		}
+		if lastErr != nil {
+			return nil, lastErr
+		}
+		return nil, fmt.Errorf("%w: %s resolves outside the egress allowlist", ErrDenied, host)
+	}
+}
@@ -0,0 +1,83 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	if a, err := Parse(nil); a != nil || err != nil {
+		t.Errorf("empty list should permit everything, got %v, %v", a, err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "https://api.openai.com", "api_openai.com", "*"} {
+		if _, err := Parse([]string{bad}); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestAllowlist_AllowsHost(t *testing.T) {
+	a, err := Parse([]string{"api.openai.com", "*.openai.azure.com", "10.0.0.0/8", "192.168.1.5", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for host, want := range map[string]bool{
+		"api.openai.com":            true,
+		"API.OpenAI.com.":           true,
+		"eastus.openai.azure.com":   true,
+		"openai.azure.com":          false,
+		"api.openai.com.evil.com":   false,
+		"evil.com":                  false,
+		"10.1.2.3":                  true,
+		"11.1.2.3":                  false,
+		"192.168.1.5":               true,
+		"192.168.1.6":               false,
+		"2001:db8::1":               true,
+		"openai.azure.com.evil.com": false,
+	} {
+		if got := a.AllowsHost(host); got != want {
+			t.Errorf("AllowsHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+	var none *Allowlist
+	if !none.AllowsHost("anything.example.com") || !none.MayAllow("anything.example.com") {
+		t.Error("a nil allowlist should permit everything")
+	}
+}
+
+func TestAllowlist_MayAllow(t *testing.T) {
+	names, _ := Parse([]string{"api.openai.com"})
+	if names.MayAllow("evil.com") {
+		t.Error("a name off a names-only list cannot be allowed")
+	}
+	nets, _ := Parse([]string{"10.0.0.0/8"})
+	if !nets.MayAllow("llm.internal") || nets.MayAllow("11.0.0.1") {
+		t.Error("with networks listed, names depend on resolution but IPs do not")
+	}
+}
+
+func TestAllowlist_DialContext(t *testing.T) {
+	var dialed []string
+	dial := func(_ context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return nil, nil
+	}
+	a, _ := Parse([]string{"api.openai.com", "127.0.0.0/8"})
+	d := a.DialContext(dial)
+
+	if _, err := d(context.Background(), "tcp", "api.openai.com:443"); err != nil {
+		t.Errorf("allowed name: %v", err)
+	}
+	if _, err := d(context.Background(), "tcp", "10.0.0.1:443"); !errors.Is(err, ErrDenied) {
+		t.Errorf("IP off the list: got %v, want ErrDenied", err)
+	}
+	// localhost resolves into 127.0.0.0/8 and is dialed by address.
+	if _, err := d(context.Background(), "tcp", "localhost:8080"); err != nil {
+		t.Errorf("name resolving into an allowed network: %v", err)
+	}
+	if len(dialed) != 2 || dialed[0] != "api.openai.com:443" || dialed[1] == "localhost:8080" {
+		t.Errorf("unexpected dials %v", dialed)
+	}
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// Type identifies the kind of gateway event.
+type Type string
+
+const (
+	TypeRequestCompleted Type = "request.completed"
+	TypeFilterBlocked    Type = "filter.blocked"
+)
+
+// Event is a structured gateway activity record published to the event bus.
+type Event struct {
+	Type           Type      `json:"type"`
+	Timestamp      time.Time `json:"timestamp"`
+	RequestID      string    `json:"request_id"`
+	OrganizationID string    `json:"organization_id"`
+	TeamID         string    `json:"team_id"`
+	APIKeyID       string    `json:"api_key_id"`
+	Classification string    `json:"classification,omitempty"`
+	ModelRequested string    `json:"model_requested,omitempty"`
+	ModelServed    string    `json:"model_served,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	Project        string    `json:"project,omitempty"`
+	Stream         bool      `json:"stream,omitempty"`
+	StatusCode     int       `json:"status_code,omitempty"`
+	DurationMs     int64     `json:"duration_ms,omitempty"`
+
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+
+	// Filter block details
+	Filter     string  `json:"filter,omitempty"`
+	Reason     string  `json:"reason,omitempty"`
+	Detections int     `json:"detections,omitempty"`
+	Score      float64 `json:"score,omitempty"`
+	IPAddress  string  `json:"ip_address,omitempty"`
+}
+
+// Publisher delivers serialized events to a message bus.
+type Publisher interface {
+	Publish(ctx context.Context, key string, payload []byte) error
+	Close() error
+}
+
+// Exporter buffers events and publishes them from a background worker so
+// request handling never blocks on the message bus. When the buffer is full,
+// events are dropped and logged rather than applying backpressure.
+type Exporter struct {
+	publisher Publisher
+	queue     chan Event
+	timeout   time.Duration
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewExporter creates an exporter and starts its publishing worker.
+func NewExporter(publisher Publisher, bufferSize int, publishTimeout time.Duration) *Exporter {
+	if bufferSize <= 0 {
+		bufferSize = 1024
+	}
+	if publishTimeout <= 0 {
+		publishTimeout = 5 * time.Second
+	}
+	e := &Exporter{
+		publisher: publisher,
+		queue:     make(chan Event, bufferSize),
+		timeout:   publishTimeout,
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// Emit enqueues an event for publishing. It never blocks.
+func (e *Exporter) Emit(ev Event) {
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+	select {
+	case e.queue <- ev:
+	default:
+		slog.Warn("event export buffer full, dropping event",
+			"type", ev.Type,
+			"request_id", ev.RequestID,
+		)
+	}
+}
+
+// Close drains queued events and closes the publisher.
+func (e *Exporter) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		close(e.queue)
+		e.wg.Wait()
+		err = e.publisher.Close()
+	})
+	return err
+}
+
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	for ev := range e.queue {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			slog.Error("failed to marshal event", "error", err, "type", ev.Type)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+		// Key by organization so partitioned consumers see per-org ordering.
+		if err := e.publisher.Publish(ctx, ev.OrganizationID, payload); err != nil {
+			slog.Error("failed to publish event",
+				"error", err,
+				"type", ev.Type,
+				"request_id", ev.RequestID,
+			)
+		}
+		cancel()
+	}
+}
+
+// NewPublisher builds a publisher for the configured backend ("kafka",
+// "nats", or "webhook").
+func NewPublisher(cfg config.EventsConfig) (Publisher, error) {
+	switch cfg.Backend {
+	case "kafka":
+		return NewKafkaPublisher(cfg.Brokers, cfg.Topic)
+	case "nats":
+		return NewNATSPublisher(cfg.URL, cfg.Topic)
+	case "webhook":
+		return NewWebhookPublisher(cfg.Webhooks, nil)
+	default:
+		return nil, fmt.Errorf("unknown event backend %q (expected kafka, nats, or webhook)", cfg.Backend)
+	}
+}
@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+type fakePublisher struct {
+	mu       sync.Mutex
+	keys     []string
+	payloads [][]byte
+	block    chan struct{}
+	closed   bool
+}
+
+func (f *fakePublisher) Publish(_ context.Context, key string, payload []byte) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys = append(f.keys, key)
+	f.payloads = append(f.payloads, payload)
+	return nil
+}
+
+func (f *fakePublisher) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+func TestExporter_PublishesAndDrainsOnClose(t *testing.T) {
+	pub := &fakePublisher{}
+	e := NewExporter(pub, 10, time.Second)
+
+	e.Emit(Event{Type: TypeRequestCompleted, RequestID: "req-1", OrganizationID: "org-1", PromptTokens: 10})
+	e.Emit(Event{Type: TypeFilterBlocked, RequestID: "req-2", OrganizationID: "org-2", Filter: "secrets"})
+
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if !pub.closed {
+		t.Error("expected publisher to be closed")
+	}
+	if len(pub.payloads) != 2 {
+		t.Fatalf("expected 2 published events, got %d", len(pub.payloads))
+	}
+	if pub.keys[0] != "org-1" || pub.keys[1] != "org-2" {
+		t.Errorf("expected events keyed by org, got %v", pub.keys)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(pub.payloads[1], &ev); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if ev.Type != TypeFilterBlocked || ev.Filter != "secrets" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	if ev.Timestamp.IsZero() {
+		t.Error("expected timestamp to be set on emit")
+	}
+}
+
+func TestExporter_DropsWhenBufferFull(t *testing.T) {
+	pub := &fakePublisher{block: make(chan struct{})}
+	e := NewExporter(pub, 1, time.Second)
+
+	// First event is picked up by the worker (which blocks), second fills the
+	// buffer, and the rest must be dropped without blocking the caller.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			e.Emit(Event{Type: TypeRequestCompleted})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit blocked on a full buffer")
+	}
+
+	close(pub.block)
+	_ = e.Close()
+
+	if len(pub.payloads) > 2 {
+		t.Errorf("expected at most 2 events published, got %d", len(pub.payloads))
+	}
+}
+
+func TestNewPublisher_UnknownBackend(t *testing.T) {
+	if _, err := NewPublisher(config.EventsConfig{Backend: "carrier-pigeon"}); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+func TestNewKafkaPublisher_Validation(t *testing.T) {
+	if _, err := NewKafkaPublisher(nil, "topic"); err == nil {
+		t.Error("expected error when no brokers configured")
+	}
+	if _, err := NewKafkaPublisher([]string{"localhost:9092"}, ""); err == nil {
+		t.Error("expected error when topic is empty")
+	}
+}
@@ -0,0 +1,45 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaPublisher publishes events to a Kafka topic.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher writing to topic on the given brokers.
+func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka: no brokers configured")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("kafka: topic is required")
+	}
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(brokers...),
+			Topic:                  topic,
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireOne,
+			AllowAutoTopicCreation: false,
+		},
+	}, nil
+}
+
+// Publish writes a single message keyed by key.
+func (p *KafkaPublisher) Publish(ctx context.Context, key string, payload []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(key),
+		Value: payload,
+	})
+}
+
+// Close flushes and closes the underlying writer.
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
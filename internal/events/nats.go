@@ -0,0 +1,42 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSPublisher publishes events to a NATS subject.
+type NATSPublisher struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATSPublisher connects to url and returns a publisher for subject.
+func NewNATSPublisher(url, subject string) (*NATSPublisher, error) {
+	if subject == "" {
+		return nil, fmt.Errorf("nats: subject is required")
+	}
+	conn, err := nats.Connect(url, nats.Name("aegis-gateway"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("nats connect: %w", err)
+	}
+	return &NATSPublisher{conn: conn, subject: subject}, nil
+}
+
+// Publish sends payload to the configured subject. The key is ignored;
+// NATS has no partitioning concept.
+func (p *NATSPublisher) Publish(_ context.Context, _ string, payload []byte) error {
+	return p.conn.Publish(p.subject, payload)
+}
+
+// Close flushes pending messages and closes the connection.
+func (p *NATSPublisher) Close() error {
+	if err := p.conn.Flush(); err != nil {
+		p.conn.Close()
+		return err
+	}
+	p.conn.Close()
+	return nil
+}
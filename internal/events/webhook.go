@@ -0,0 +1,149 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// Headers of a webhook delivery. The signature is "v1=" and the hex
+// HMAC-SHA256, under the endpoint's secret, of "<timestamp>.<nonce>.<body>".
+const (
+	HeaderWebhookTimestamp = "X-Aegis-Webhook-Timestamp"
+	HeaderWebhookNonce     = "X-Aegis-Webhook-Nonce"
+	HeaderWebhookSignature = "X-Aegis-Webhook-Signature"
+)
+
+const webhookSignatureVersion = "v1="
+
+var (
+	// ErrWebhookSignature is returned by VerifyWebhook for a delivery whose
+	// signature is missing or does not match.
+	ErrWebhookSignature = errors.New("webhook signature mismatch")
+	// ErrWebhookExpired is returned by VerifyWebhook for a delivery whose
+	// timestamp is outside the tolerance.
+	ErrWebhookExpired = errors.New("webhook timestamp outside tolerance")
+)
+
+type webhookEndpoint struct {
+	url    string
+	secret []byte
+}
+
+// WebhookPublisher POSTs each event to every configured endpoint, signed
+// with that endpoint's secret.
+type WebhookPublisher struct {
+	client    *http.Client
+	endpoints []webhookEndpoint
+}
+
+// NewWebhookPublisher creates a publisher delivering to hooks. Each needs a
+// URL and a secret.
+func NewWebhookPublisher(hooks []config.WebhookConfig, client *http.Client) (*WebhookPublisher, error) {
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("webhook: no endpoints configured")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	p := &WebhookPublisher{client: client}
+	for i, h := range hooks {
+		if h.URL == "" || h.Secret == "" {
+			return nil, fmt.Errorf("webhook: endpoint %d needs a url and a secret", i)
+		}
+		p.endpoints = append(p.endpoints, webhookEndpoint{url: h.URL, secret: []byte(h.Secret)})
+	}
+	return p, nil
+}
+
+// Publish delivers payload to every endpoint, each with a fresh timestamp
+// and nonce. The key is ignored. It returns the joined errors of the
+// endpoints that did not answer 2xx.
+func (p *WebhookPublisher) Publish(ctx context.Context, _ string, payload []byte) error {
+	var errs []error
+	for _, ep := range p.endpoints {
+		if err := p.deliver(ctx, ep, payload); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", ep.url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (p *WebhookPublisher) deliver(ctx context.Context, ep webhookEndpoint, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ep.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	timestamp, nonceHex := strconv.FormatInt(time.Now().Unix(), 10), hex.EncodeToString(nonce)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aegis-gateway")
+	req.Header.Set(HeaderWebhookTimestamp, timestamp)
+	req.Header.Set(HeaderWebhookNonce, nonceHex)
+	req.Header.Set(HeaderWebhookSignature, webhookSignatureVersion+signWebhook(ep.secret, timestamp, nonceHex, payload))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close is a no-op; deliveries hold no connection of their own.
+func (p *WebhookPublisher) Close() error {
+	return nil
+}
+
+func signWebhook(secret []byte, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks a delivery's signature against secret and that its
+// timestamp is within tolerance of now, for receivers written in Go.
+// Receivers should also reject nonces they have already seen within the
+// tolerance, which this does not track.
+func VerifyWebhook(secret string, h http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, nonce := h.Get(HeaderWebhookTimestamp), h.Get(HeaderWebhookNonce)
+	sig, ok := strings.CutPrefix(h.Get(HeaderWebhookSignature), webhookSignatureVersion)
+	if !ok || timestamp == "" || nonce == "" {
+		return ErrWebhookSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	want, _ := hex.DecodeString(signWebhook([]byte(secret), timestamp, nonce, body))
+	if !hmac.Equal(got, want) {
+		return ErrWebhookSignature
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrWebhookSignature
+	}
+	if age := time.Since(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return ErrWebhookExpired
+	}
+	return nil
+}
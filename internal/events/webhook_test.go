@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func TestWebhookPublisher_SignsDeliveries(t *testing.T) {
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	got := make(chan delivery, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- delivery{r.Header.Clone(), body}
+	}))
+	defer srv.Close()
+
+	p, err := NewWebhookPublisher([]config.WebhookConfig{{URL: srv.URL, Secret: "s3cret"}}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte(`{"type":"filter.blocked","request_id":"req-1"}`)
+	for range 2 {
+		if err := p.Publish(context.Background(), "org-1", payload); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+	}
+	first, second := <-got, <-got
+
+	if err := VerifyWebhook("s3cret", first.header, first.body, time.Minute); err != nil {
+		t.Errorf("delivery should verify: %v", err)
+	}
+	if first.header.Get(HeaderWebhookNonce) == second.header.Get(HeaderWebhookNonce) {
+		t.Error("each delivery should carry a fresh nonce")
+	}
+	if err := VerifyWebhook("wrong", first.header, first.body, time.Minute); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("wrong secret: got %v, want ErrWebhookSignature", err)
+	}
+	if err := VerifyWebhook("s3cret", first.header, []byte(`{"type":"tampered"}`), time.Minute); !errors.Is(err, ErrWebhookSignature) {
+		t.Errorf("tampered body: got %v, want ErrWebhookSignature", err)
+	}
+}
+
+func TestVerifyWebhook_Expired(t *testing.T) {
+	body := []byte(`{}`)
+	timestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	h := http.Header{}
+	h.Set(HeaderWebhookTimestamp, timestamp)
+	h.Set(HeaderWebhookNonce, "abc")
+	h.Set(HeaderWebhookSignature, "v1="+signWebhook([]byte("s3cret"), timestamp, "abc", body))
+
+	if err := VerifyWebhook("s3cret", h, body, 5*time.Minute); !errors.Is(err, ErrWebhookExpired) {
+		t.Errorf("got %v, want ErrWebhookExpired", err)
+	}
+	if err := VerifyWebhook("s3cret", h, body, time.Hour); err != nil {
+		t.Errorf("within tolerance: %v", err)
+	}
+}
+
+func TestWebhookPublisher_ReportsFailedEndpoints(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	p, err := NewWebhookPublisher([]config.WebhookConfig{
+		{URL: ok.URL, Secret: "a"},
+		{URL: failing.URL, Secret: "b"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Publish(context.Background(), "", []byte(`{}`)); err == nil {
+		t.Error("expected an error for the failing endpoint")
+	}
+}
+
+func TestNewWebhookPublisher_Validation(t *testing.T) {
+	if _, err := NewWebhookPublisher(nil, nil); err == nil {
+		t.Error("expected error when no endpoints configured")
+	}
+	if _, err := NewWebhookPublisher([]config.WebhookConfig{{URL: "https://hooks.example.com"}}, nil); err == nil {
+		t.Error("expected error when the secret is empty")
+	}
+}
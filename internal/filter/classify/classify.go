@@ -0,0 +1,156 @@
+// Package classify estimates the data classification of prompt content
+// through the filter service and reports prompts that look more sensitive
+// than the API key they were sent with is cleared for.
+package classify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	filterv1 "github.com/af-corp/aegis-gateway/gen/filter/v1"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/types"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// maxClassifyBytes bounds the text sent to the classifier per request.
+const maxClassifyBytes = 64 * 1024
+
+// contentClassifier is the part of filterv1.FilterServiceClient used here.
+type contentClassifier interface {
+	ClassifyContent(ctx context.Context, in *filterv1.ClassifyContentRequest, opts ...grpc.CallOption) (*filterv1.ClassifyContentResponse, error)
+}
+
+// Metrics is an optional interface for recording classification mismatches.
+type Metrics interface {
+	RecordClassificationMismatch(org, declared, detected, action string)
+}
+
+// Detector implements filter.Filter. A mismatch is flagged, or blocked in
+// enforcement mode. The detector is advisory, so classifier errors never
+// block a request.
+type Detector struct {
+	client  contentClassifier
+	conn    *grpc.ClientConn
+	cfg     func() config.ClassificationDetectionConfig
+	metrics Metrics
+}
+
+// NewDetector creates a classification detector. Call Connect() to
+// establish the gRPC connection.
+func NewDetector(cfg func() config.ClassificationDetectionConfig) *Detector {
+	return &Detector{cfg: cfg}
+}
+
+// SetMetrics attaches a recorder for mismatch metrics.
+func (d *Detector) SetMetrics(m Metrics) {
+	d.metrics = m
+}
+
+// Connect establishes the gRPC connection to the filter service.
+func (d *Detector) Connect() error {
+	cfg := d.cfg()
+	conn, err := grpc.NewClient(cfg.Address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		return fmt.Errorf("classification service dial: %w", err)
+	}
+	d.conn = conn
+	d.client = filterv1.NewFilterServiceClient(conn)
+	slog.Info("classification service connected", "address", cfg.Address)
+	return nil
+}
+
+// Close closes the gRPC connection.
+func (d *Detector) Close() error {
+	if d.conn != nil {
+		return d.conn.Close()
+	}
+	return nil
+}
+
+func (d *Detector) Name() string  { return "classification" }
+func (d *Detector) Enabled() bool { return d.cfg().Enabled }
+
+// ScanRequest implements filter.Filter.
+func (d *Detector) ScanRequest(ctx context.Context, req *types.AegisRequest) filter.Result {
+	pass := filter.Result{Action: filter.ActionPass, FilterName: "classification"}
+	if d.client == nil {
+		return pass
+	}
+	text := promptText(req.Messages)
+	if text == "" {
+		return pass
+	}
+
+	cfg := d.cfg()
+	scanCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	resp, err := d.client.ClassifyContent(scanCtx, &filterv1.ClassifyContentRequest{
+		Text:                   text,
+		DeclaredClassification: string(req.Classification),
+	})
+	if err != nil {
+		pass.Err = fmt.Errorf("classification service: %w", err)
+		return pass
+	}
+
+	detected, ok := types.ParseClassification(resp.Classification)
+	confidence := float64(resp.Confidence)
+	if !ok || confidence < cfg.MinConfidence || req.Classification.Allows(detected) {
+		pass.Score = confidence
+		return pass
+	}
+
+	action := filter.ActionFlag
+	if cfg.Enforce {
+		action = filter.ActionBlock
+	}
+	slog.Warn("prompt classification exceeds key classification",
+		"request_id", req.RequestID,
+		"org_id", req.OrganizationID,
+		"declared", req.Classification,
+		"detected", detected,
+		"confidence", confidence,
+		"reasons", resp.Reasons,
+		"action", action,
+	)
+	if d.metrics != nil {
+		d.metrics.RecordClassificationMismatch(req.OrganizationID, string(req.Classification), string(detected), string(action))
+	}
+
+	result := filter.Result{
+		Action:     action,
+		FilterName: "classification",
+		Detections: 1,
+		Score:      confidence,
+	}
+	if action == filter.ActionBlock {
+		result.Message = fmt.Sprintf("Request blocked: content appears to be %s, above this key's %s classification", detected, req.Classification)
+	}
+	return result
+}
+
+// promptText joins the messages' filter text, truncated to maxClassifyBytes.
+func promptText(messages []types.Message) string {
+	var b strings.Builder
+	for _, m := range messages {
+		text := m.FilterText()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(text)
+		if b.Len() >= maxClassifyBytes {
+			return strings.ToValidUTF8(b.String()[:maxClassifyBytes], "")
+		}
+	}
+	return b.String()
+}
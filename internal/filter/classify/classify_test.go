@@ -0,0 +1,84 @@
+package classify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	filterv1 "github.com/af-corp/aegis-gateway/gen/filter/v1"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/types"
+	"google.golang.org/grpc"
+)
+
+type mockClassifier struct {
+	resp *filterv1.ClassifyContentResponse
+	err  error
+}
+
+func (m *mockClassifier) ClassifyContent(context.Context, *filterv1.ClassifyContentRequest, ...grpc.CallOption) (*filterv1.ClassifyContentResponse, error) {
+	return m.resp, m.err
+}
+
+type recordingMetrics struct{ calls []string }
+
+func (r *recordingMetrics) RecordClassificationMismatch(org, declared, detected, action string) {
+	r.calls = append(r.calls, declared+">"+detected+":"+action)
+}
+
+func TestDetector_ScanRequest(t *testing.T) {
+	confidential := &filterv1.ClassifyContentResponse{Classification: "CONFIDENTIAL", Confidence: 0.9, Reasons: []string{"salary data"}}
+
+	tests := []struct {
+		name     string
+		declared types.Classification
+		enforce  bool
+		mock     *mockClassifier
+		want     filter.Action
+		metric   string
+	}{
+		{"mismatch flagged", types.ClassPublic, false, &mockClassifier{resp: confidential}, filter.ActionFlag, "PUBLIC>CONFIDENTIAL:flag"},
+		{"mismatch blocked when enforcing", types.ClassInternal, true, &mockClassifier{resp: confidential}, filter.ActionBlock, "INTERNAL>CONFIDENTIAL:block"},
+		{"key cleared for content", types.ClassRestricted, true, &mockClassifier{resp: confidential}, filter.ActionPass, ""},
+		{"low confidence ignored", types.ClassPublic, true, &mockClassifier{resp: &filterv1.ClassifyContentResponse{Classification: "RESTRICTED", Confidence: 0.4}}, filter.ActionPass, ""},
+		{"unknown classification ignored", types.ClassPublic, true, &mockClassifier{resp: &filterv1.ClassifyContentResponse{Classification: "SECRET", Confidence: 1}}, filter.ActionPass, ""},
+		{"service error passes", types.ClassPublic, true, &mockClassifier{err: errors.New("unavailable")}, filter.ActionPass, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := &recordingMetrics{}
+			d := &Detector{
+				client:  tt.mock,
+				metrics: metrics,
+				cfg: func() config.ClassificationDetectionConfig {
+					return config.ClassificationDetectionConfig{Enabled: true, Enforce: tt.enforce, MinConfidence: 0.7, Timeout: time.Second}
+				},
+			}
+			req := &types.AegisRequest{
+				Messages:       []types.Message{{Role: "user", Content: "Summarise the Q3 salary bands"}},
+				Classification: tt.declared,
+			}
+			got := d.ScanRequest(context.Background(), req)
+			if got.Action != tt.want {
+				t.Errorf("action = %s, want %s", got.Action, tt.want)
+			}
+			if tt.want == filter.ActionBlock && !strings.Contains(got.Message, "CONFIDENTIAL") {
+				t.Errorf("block message should name the detected classification, got %q", got.Message)
+			}
+			if gotMetric := strings.Join(metrics.calls, ","); gotMetric != tt.metric {
+				t.Errorf("metrics = %q, want %q", gotMetric, tt.metric)
+			}
+		})
+	}
+}
+
+func TestPromptText_Truncates(t *testing.T) {
+	long := strings.Repeat("é", maxClassifyBytes)
+	got := promptText([]types.Message{{Content: "a"}, {Content: "  "}, {Content: long}})
+	if len(got) > maxClassifyBytes || !strings.HasPrefix(got, "a\n\né") {
+		t.Errorf("unexpected prompt text of %d bytes", len(got))
+	}
+}
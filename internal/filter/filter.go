@@ -2,6 +2,9 @@ package filter
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
@@ -14,6 +17,8 @@ const (
 	ActionFlag   Action = "flag"
 	ActionRedact Action = "redact"
 	ActionBlock  Action = "block"
+	// ActionBypass marks a filter skipped under a break-glass grant.
+	ActionBypass Action = "bypass"
 )
 
 // Result is returned by each filter.
@@ -23,6 +28,29 @@ type Result struct {
 	Message    string
 	Detections int
 	Score      float64
+	// Findings locates the detections behind the result, where the filter
+	// can say where they are. They carry no matched text.
+	Findings []Finding
+	// GrantID identifies the break-glass grant behind an ActionBypass.
+	GrantID string
+	// Err is set when the filter could not complete its evaluation, e.g.
+	// because a backing service is down; Action is then what it concluded
+	// from what it did evaluate. The chain keeps that Action if the filter
+	// fails open and blocks otherwise.
+	Err error
+	// Degraded marks a result kept despite Err under a fail-open policy.
+	Degraded bool
+}
+
+// Finding is one detection: what kind of content matched and where in the
+// filter text of the request message at Message. Start and End are byte
+// offsets, except that PII findings carry the PII service's character
+// offsets.
+type Finding struct {
+	Type    string `json:"type"`
+	Message int    `json:"message"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
 }
 
 // Filter is the interface all content filters implement.
@@ -32,9 +60,29 @@ type Filter interface {
 	ScanRequest(ctx context.Context, req *types.AegisRequest) Result
 }
 
+// Metrics is an optional interface for recording per-filter evaluation
+// latency and outcome.
+type Metrics interface {
+	RecordFilterEvaluation(filter, org, action string, duration time.Duration)
+	// RecordFilterDegraded counts an evaluation that failed and was passed
+	// under a fail-open policy.
+	RecordFilterDegraded(filter, org string)
+}
+
+// Bypass looks up break-glass grants that exempt a request's API key from
+// named filters. It is satisfied by *cache.BypassStore.
+type Bypass interface {
+	// BypassGrants returns the grant ID for each of filters the request's
+	// key currently holds a grant for.
+	BypassGrants(ctx context.Context, req *types.AegisRequest, filters []string) map[string]string
+}
+
 // Chain runs filters in order, stopping on the first Block.
 type Chain struct {
-	filters []Filter
+	filters  []Filter
+	metrics  Metrics
+	bypass   Bypass
+	failOpen func(filter, org string) bool
 }
 
 // NewChain creates a filter chain from the given filters.
@@ -42,15 +90,80 @@ func NewChain(filters ...Filter) *Chain {
 	return &Chain{filters: filters}
 }
 
+// SetMetrics attaches a recorder for per-filter latency and outcome metrics.
+func (c *Chain) SetMetrics(m Metrics) {
+	c.metrics = m
+}
+
+// SetBypass attaches the break-glass grant lookup consulted before each run.
+func (c *Chain) SetBypass(b Bypass) {
+	c.bypass = b
+}
+
+// SetFailOpen sets the policy for evaluations that fail: when failOpen
+// reports true for the filter and the request's org the filter's own result
+// stands, otherwise the request is blocked. Without a policy every filter
+// fails closed.
+func (c *Chain) SetFailOpen(failOpen func(filter, org string) bool) {
+	c.failOpen = failOpen
+}
+
+// Names returns the names of the chain's filters in order.
+func (c *Chain) Names() []string {
+	names := make([]string, len(c.filters))
+	for i, f := range c.filters {
+		names[i] = f.Name()
+	}
+	return names
+}
+
 // Run executes all enabled filters in order. Returns all results and a pointer
-// to the first blocking result (nil if no filter blocked).
+// to the first blocking result (nil if no filter blocked). A filter the
+// request's key holds a bypass grant for is skipped with an ActionBypass
+// result.
 func (c *Chain) Run(ctx context.Context, req *types.AegisRequest) ([]Result, *Result) {
+	return c.run(ctx, req, c.filters)
+}
+
+// RunAfter runs the filters that follow the one named name as Run does, or
+// none if the chain has no such filter. It finishes the chain for a request
+// a filter blocked but that is still served, such as one redirected to an
+// internal model.
+func (c *Chain) RunAfter(ctx context.Context, req *types.AegisRequest, name string) ([]Result, *Result) {
+	for i, f := range c.filters {
+		if f.Name() == name {
+			return c.run(ctx, req, c.filters[i+1:])
+		}
+	}
+	return nil, nil
+}
+
+func (c *Chain) run(ctx context.Context, req *types.AegisRequest, filters []Filter) ([]Result, *Result) {
+	var enabled []Filter
+	for _, f := range filters {
+		if f.Enabled() {
+			enabled = append(enabled, f)
+		}
+	}
+	var grants map[string]string
+	if c.bypass != nil && len(enabled) > 0 {
+		names := make([]string, len(enabled))
+		for i, f := range enabled {
+			names[i] = f.Name()
+		}
+		grants = c.bypass.BypassGrants(ctx, req, names)
+	}
+
 	var results []Result
-	for _, f := range c.filters {
-		if !f.Enabled() {
+	for _, f := range enabled {
+		if grantID, ok := grants[f.Name()]; ok {
+			if c.metrics != nil {
+				c.metrics.RecordFilterEvaluation(f.Name(), req.OrganizationID, string(ActionBypass), 0)
+			}
+			results = append(results, Result{Action: ActionBypass, FilterName: f.Name(), GrantID: grantID})
 			continue
 		}
-		r := f.ScanRequest(ctx, req)
+		r := c.Evaluate(ctx, f, req)
 		results = append(results, r)
 		if r.Action == ActionBlock {
 			return results, &r
@@ -58,3 +171,57 @@ func (c *Chain) Run(ctx context.Context, req *types.AegisRequest) ([]Result, *Re
 	}
 	return results, nil
 }
+
+// Evaluate runs a single filter, recording its metrics and resolving an
+// evaluation failure (a Result with Err, or a panic) with the fail-open
+// policy. Run uses it for each filter; it is exported for filters that run
+// outside the chain, such as the policy evaluator after routing.
+func (c *Chain) Evaluate(ctx context.Context, f Filter, req *types.AegisRequest) Result {
+	start := time.Now()
+	r := scan(ctx, f, req)
+	if r.Err != nil {
+		r = c.resolveFailure(f.Name(), req, r)
+	}
+	if c.metrics != nil {
+		c.metrics.RecordFilterEvaluation(f.Name(), req.OrganizationID, string(r.Action), time.Since(start))
+	}
+	return r
+}
+
+// scan calls f.ScanRequest, turning a panic into an evaluation failure.
+func scan(ctx context.Context, f Filter, req *types.AegisRequest) (r Result) {
+	defer func() {
+		if p := recover(); p != nil {
+			r = Result{Action: ActionPass, FilterName: f.Name(), Err: fmt.Errorf("filter panicked: %v", p)}
+		}
+	}()
+	return f.ScanRequest(ctx, req)
+}
+
+func (c *Chain) resolveFailure(name string, req *types.AegisRequest, r Result) Result {
+	if c.failOpen != nil && c.failOpen(name, req.OrganizationID) {
+		slog.Warn("filter evaluation failed, failing open",
+			"request_id", req.RequestID,
+			"filter", name,
+			"org_id", req.OrganizationID,
+			"error", r.Err,
+		)
+		if c.metrics != nil {
+			c.metrics.RecordFilterDegraded(name, req.OrganizationID)
+		}
+		r.Degraded = true
+		return r
+	}
+	slog.Error("filter evaluation failed, failing closed",
+		"request_id", req.RequestID,
+		"filter", name,
+		"org_id", req.OrganizationID,
+		"error", r.Err,
+	)
+	return Result{
+		Action:     ActionBlock,
+		FilterName: name,
+		Message:    fmt.Sprintf("Request blocked: %s filter is unavailable", name),
+		Err:        r.Err,
+	}
+}
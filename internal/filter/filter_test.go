@@ -2,7 +2,10 @@ package filter
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
@@ -185,6 +188,26 @@ func TestChain_Run_FlagAndRedactDoNotStop(t *testing.T) {
 	}
 }
 
+func TestChain_RunAfter(t *testing.T) {
+	chain := NewChain(
+		&mockFilter{name: "secrets", enabled: true, result: Result{Action: ActionBlock, FilterName: "secrets"}},
+		&mockFilter{name: "injection", enabled: true, result: Result{Action: ActionFlag, FilterName: "injection"}},
+		&mockFilter{name: "pii", enabled: true, result: Result{Action: ActionBlock, FilterName: "pii"}},
+	)
+	req := &types.AegisRequest{Model: "gpt-4"}
+
+	results, blocked := chain.RunAfter(context.Background(), req, "secrets")
+	if len(results) != 2 || results[0].FilterName != "injection" || blocked == nil || blocked.FilterName != "pii" {
+		t.Errorf("expected the filters after secrets to run, got %+v, blocked %+v", results, blocked)
+	}
+	if results, blocked = chain.RunAfter(context.Background(), req, "pii"); len(results) != 0 || blocked != nil {
+		t.Errorf("nothing follows the last filter, got %+v", results)
+	}
+	if results, _ = chain.RunAfter(context.Background(), req, "policy"); len(results) != 0 {
+		t.Errorf("an unknown filter runs nothing, got %+v", results)
+	}
+}
+
 func TestActionConstants(t *testing.T) {
 	if ActionPass != "pass" {
 		t.Errorf("ActionPass = %q", ActionPass)
@@ -210,3 +233,119 @@ func (ct *callTracker) ScanRequest(ctx context.Context, req *types.AegisRequest)
 	*ct.called = true
 	return ct.Filter.ScanRequest(ctx, req)
 }
+
+// recordingMetrics captures per-filter evaluations for assertions.
+type recordingMetrics struct {
+	evals    []string
+	degraded []string
+}
+
+func (r *recordingMetrics) RecordFilterEvaluation(filter, org, action string, _ time.Duration) {
+	r.evals = append(r.evals, filter+"/"+org+"/"+action)
+}
+
+func (r *recordingMetrics) RecordFilterDegraded(filter, org string) {
+	r.degraded = append(r.degraded, filter+"/"+org)
+}
+
+func TestChain_Run_RecordsMetrics(t *testing.T) {
+	chain := NewChain(
+		&mockFilter{name: "secrets", enabled: true, result: Result{Action: ActionPass, FilterName: "secrets"}},
+		&mockFilter{name: "disabled", enabled: false},
+		&mockFilter{name: "pii", enabled: true, result: Result{Action: ActionBlock, FilterName: "pii"}},
+	)
+	rec := &recordingMetrics{}
+	chain.SetMetrics(rec)
+
+	req := &types.AegisRequest{OrganizationID: "org-1"}
+	chain.Run(context.Background(), req)
+
+	want := []string{"secrets/org-1/pass", "pii/org-1/block"}
+	if len(rec.evals) != len(want) {
+		t.Fatalf("expected %d evaluations, got %v", len(want), rec.evals)
+	}
+	for i := range want {
+		if rec.evals[i] != want[i] {
+			t.Errorf("evaluation %d = %q, want %q", i, rec.evals[i], want[i])
+		}
+	}
+}
+
+type fakeBypass struct {
+	grants map[string]string
+	asked  []string
+}
+
+func (f *fakeBypass) BypassGrants(_ context.Context, _ *types.AegisRequest, filters []string) map[string]string {
+	f.asked = filters
+	return f.grants
+}
+
+func TestChain_Run_BypassGrantSkipsFilter(t *testing.T) {
+	chain := NewChain(
+		&mockFilter{name: "secrets", enabled: true, result: Result{Action: ActionPass, FilterName: "secrets"}},
+		&mockFilter{name: "pii", enabled: true, result: Result{Action: ActionBlock, FilterName: "pii", Message: "ssn"}},
+		&mockFilter{name: "injection", enabled: false},
+	)
+	bypass := &fakeBypass{grants: map[string]string{"pii": "grant-1"}}
+	chain.SetBypass(bypass)
+
+	results, blocked := chain.Run(context.Background(), &types.AegisRequest{APIKeyID: "key-1"})
+	if blocked != nil {
+		t.Fatalf("expected bypassed pii filter not to block, got %+v", blocked)
+	}
+	if len(results) != 2 || results[1].Action != ActionBypass || results[1].GrantID != "grant-1" {
+		t.Errorf("unexpected results %+v", results)
+	}
+	if len(bypass.asked) != 2 {
+		t.Errorf("expected grants looked up for enabled filters only, got %v", bypass.asked)
+	}
+}
+
+type panicFilter struct{}
+
+func (panicFilter) Name() string  { return "secrets" }
+func (panicFilter) Enabled() bool { return true }
+func (panicFilter) ScanRequest(context.Context, *types.AegisRequest) Result {
+	panic("bad pattern")
+}
+
+func TestChain_Run_FailedEvaluation(t *testing.T) {
+	failing := &mockFilter{name: "pii", enabled: true, result: Result{Action: ActionPass, FilterName: "pii", Err: errors.New("connection refused")}}
+	tests := []struct {
+		name         string
+		filter       Filter
+		failOpen     func(filter, org string) bool
+		wantBlocked  bool
+		wantDegraded []string
+	}{
+		{"no policy fails closed", failing, nil, true, nil},
+		{"fail closed", failing, func(string, string) bool { return false }, true, nil},
+		{"fail open", failing, func(string, string) bool { return true }, false, []string{"pii/org-1"}},
+		{"per org", failing, func(_, org string) bool { return org == "org-2" }, true, nil},
+		{"panic fails closed", panicFilter{}, nil, true, nil},
+		{"panic fails open", panicFilter{}, func(string, string) bool { return true }, false, []string{"secrets/org-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := NewChain(tt.filter)
+			rec := &recordingMetrics{}
+			chain.SetMetrics(rec)
+			chain.SetFailOpen(tt.failOpen)
+
+			results, blocked := chain.Run(context.Background(), &types.AegisRequest{OrganizationID: "org-1"})
+			if (blocked != nil) != tt.wantBlocked {
+				t.Fatalf("blocked = %v, want %v", blocked, tt.wantBlocked)
+			}
+			if blocked != nil && blocked.Err == nil {
+				t.Error("expected the evaluation error on the blocking result")
+			}
+			if !tt.wantBlocked && (!results[0].Degraded || results[0].Action != ActionPass) {
+				t.Errorf("expected a degraded pass, got %+v", results[0])
+			}
+			if fmt.Sprint(rec.degraded) != fmt.Sprint(tt.wantDegraded) {
+				t.Errorf("degraded = %v, want %v", rec.degraded, tt.wantDegraded)
+			}
+		})
+	}
+}
@@ -3,9 +3,12 @@ package injection
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"regexp"
 
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/filter/regexset"
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
 
@@ -14,19 +17,33 @@ type Detection struct {
 	RuleName string
 	Severity float64
 	Category string
+	Message  int // index of the message, set by ScanMessages
 	Start    int
 	End      int
 }
 
 // Scanner scans text for prompt injection patterns.
 type Scanner struct {
-	rules []Rule
-	cfg   func() config.InjectionFilterConfig
+	rules      []Rule
+	set        *regexset.Set
+	cfg        func() config.InjectionFilterConfig
+	similarity *SimilarityDetector
 }
 
 // NewScanner creates a prompt injection scanner.
 func NewScanner(cfg func() config.InjectionFilterConfig) *Scanner {
-	return &Scanner{rules: DefaultRules(), cfg: cfg}
+	rules := DefaultRules()
+	res := make([]*regexp.Regexp, len(rules))
+	for i, r := range rules {
+		res[i] = r.Regex
+	}
+	return &Scanner{rules: rules, set: regexset.New(res), cfg: cfg}
+}
+
+// SetSimilarity attaches embedding-based jailbreak detection, consulted when
+// filter.injection.similarity is enabled.
+func (s *Scanner) SetSimilarity(d *SimilarityDetector) {
+	s.similarity = d
 }
 
 func (s *Scanner) Name() string  { return "injection" }
@@ -35,8 +52,8 @@ func (s *Scanner) Enabled() bool { return s.cfg().Enabled }
 // Scan checks a single text string and returns all detections.
 func (s *Scanner) Scan(text string) []Detection {
 	var detections []Detection
-	for _, r := range s.rules {
-		locs := r.Regex.FindAllStringIndex(text, -1)
+	for i, locs := range s.set.FindAll(text) {
+		r := s.rules[i]
 		for _, loc := range locs {
 			detections = append(detections, Detection{
 				RuleName: r.Name,
@@ -54,10 +71,10 @@ func (s *Scanner) Scan(text string) []Detection {
 func (s *Scanner) ScanMessages(messages []types.Message) ([]Detection, float64) {
 	var allDetections []Detection
 	maxScore := 0.0
-	for _, m := range messages {
-		detections := s.Scan(m.Content)
-		allDetections = append(allDetections, detections...)
-		for _, d := range detections {
+	for i, m := range messages {
+		for _, d := range s.Scan(m.FilterText()) {
+			d.Message = i
+			allDetections = append(allDetections, d)
 			if d.Severity > maxScore {
 				maxScore = d.Severity
 			}
@@ -67,28 +84,62 @@ func (s *Scanner) ScanMessages(messages []types.Message) ([]Detection, float64)
 }
 
 // ScanRequest implements filter.Filter.
-func (s *Scanner) ScanRequest(_ context.Context, req *types.AegisRequest) filter.Result {
+func (s *Scanner) ScanRequest(ctx context.Context, req *types.AegisRequest) filter.Result {
 	detections, score := s.ScanMessages(req.Messages)
 	cfg := s.cfg()
+	n := len(detections)
+
+	var simErr error
+	if score < cfg.BlockThreshold && s.similarity != nil && cfg.Similarity.Enabled {
+		m, ok, err := s.similarity.Check(ctx, req.Messages)
+		if err != nil {
+			simErr = fmt.Errorf("jailbreak similarity check: %w", err)
+		}
+		if ok {
+			n++
+			floor := cfg.FlagThreshold
+			if cfg.Similarity.Block {
+				floor = cfg.BlockThreshold
+			}
+			score = max(score, floor)
+			slog.Warn("prompt similar to known jailbreak",
+				"request_id", req.RequestID,
+				"jailbreak_id", m.ID,
+				"category", m.Category,
+				"similarity", m.Similarity,
+			)
+		}
+	}
 
 	if score >= cfg.BlockThreshold {
 		return filter.Result{
 			Action:     filter.ActionBlock,
 			FilterName: "injection",
 			Message:    fmt.Sprintf("Request blocked: prompt injection detected (score %.2f)", score),
-			Detections: len(detections),
+			Detections: n,
 			Score:      score,
+			Findings:   injectionFindings(detections),
 		}
 	}
 	if score >= cfg.FlagThreshold {
 		return filter.Result{
 			Action:     filter.ActionFlag,
 			FilterName: "injection",
-			Detections: len(detections),
+			Detections: n,
 			Score:      score,
+			Err:        simErr,
 		}
 	}
-	return filter.Result{Action: filter.ActionPass, FilterName: "injection", Score: score}
+	return filter.Result{Action: filter.ActionPass, FilterName: "injection", Score: score, Err: simErr}
+}
+
+// injectionFindings reports each detection by its rule's category.
+func injectionFindings(detections []Detection) []filter.Finding {
+	findings := make([]filter.Finding, len(detections))
+	for i, d := range detections {
+		findings[i] = filter.Finding{Type: d.Category, Message: d.Message, Start: d.Start, End: d.End}
+	}
+	return findings
 }
 
 // InjectionClassifier is the ML classifier interface for Phase 2.
@@ -0,0 +1,172 @@
+package injection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisIndex keeps the jailbreak vectors in a Redis hash (field = ID,
+// value = JSON entry) and searches an in-memory copy that is reloaded
+// every refresh interval. The curated set is small, so a linear scan is
+// cheaper than a round trip per lookup.
+type RedisIndex struct {
+	rdb     *redis.Client
+	key     string
+	refresh time.Duration
+
+	mu       sync.Mutex
+	entries  []Jailbreak
+	loadedAt time.Time
+}
+
+// NewRedisIndex creates an index over the hash at key.
+func NewRedisIndex(rdb *redis.Client, key string, refresh time.Duration) *RedisIndex {
+	return &RedisIndex{rdb: rdb, key: key, refresh: refresh}
+}
+
+// Nearest implements JailbreakIndex.
+func (x *RedisIndex) Nearest(ctx context.Context, vec []float32) (Match, bool, error) {
+	entries, err := x.load(ctx)
+	if err != nil {
+		return Match{}, false, err
+	}
+	var best Match
+	found := false
+	for _, e := range entries {
+		if sim := cosine(vec, e.Embedding); !found || sim > best.Similarity {
+			best, found = Match{ID: e.ID, Category: e.Category, Similarity: sim}, true
+		}
+	}
+	return best, found, nil
+}
+
+// load returns the cached entries, reloading them when stale. A failed
+// reload keeps serving the previous set if there is one.
+func (x *RedisIndex) load(ctx context.Context) ([]Jailbreak, error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.loadedAt.IsZero() && time.Since(x.loadedAt) < x.refresh {
+		return x.entries, nil
+	}
+	raw, err := x.rdb.HGetAll(ctx, x.key).Result()
+	if err != nil {
+		if x.entries != nil {
+			return x.entries, nil
+		}
+		return nil, fmt.Errorf("load jailbreak vectors: %w", err)
+	}
+	entries := make([]Jailbreak, 0, len(raw))
+	for id, v := range raw {
+		var j Jailbreak
+		if err := json.Unmarshal([]byte(v), &j); err != nil {
+			continue
+		}
+		j.ID = id
+		entries = append(entries, j)
+	}
+	x.entries, x.loadedAt = entries, time.Now()
+	return entries, nil
+}
+
+// Upsert implements JailbreakIndex.
+func (x *RedisIndex) Upsert(ctx context.Context, entries []Jailbreak) error {
+	values := make([]any, 0, 2*len(entries))
+	for _, e := range entries {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		values = append(values, e.ID, string(b))
+	}
+	if err := x.rdb.HSet(ctx, x.key, values...).Err(); err != nil {
+		return fmt.Errorf("store jailbreak vectors: %w", err)
+	}
+	x.mu.Lock()
+	x.loadedAt = time.Time{}
+	x.mu.Unlock()
+	return nil
+}
+
+// PGVectorIndex queries a pgvector table for the nearest jailbreak. The
+// table is managed by the operator, since the vector extension and the
+// embedding dimension are deployment choices:
+//
+//	CREATE TABLE jailbreak_embeddings (
+//	    id        TEXT PRIMARY KEY,
+//	    category  TEXT NOT NULL DEFAULT '',
+//	    text      TEXT NOT NULL,
+//	    embedding vector(1536) NOT NULL
+//	);
+type PGVectorIndex struct {
+	pool  *pgxpool.Pool
+	table string
+}
+
+// NewPGVectorIndex creates an index over table, which must already have
+// been checked to be a plain SQL identifier.
+func NewPGVectorIndex(pool *pgxpool.Pool, table string) *PGVectorIndex {
+	return &PGVectorIndex{pool: pool, table: table}
+}
+
+// Nearest implements JailbreakIndex using the cosine distance operator.
+func (x *PGVectorIndex) Nearest(ctx context.Context, vec []float32) (Match, bool, error) {
+	var m Match
+	var distance float64
+	err := x.pool.QueryRow(ctx, `
+		SELECT id, category, embedding <=> $1::vector AS distance
+		FROM `+x.table+`
+		ORDER BY distance
+		LIMIT 1`, vectorLiteral(vec)).Scan(&m.ID, &m.Category, &distance)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Match{}, false, nil
+	}
+	if err != nil {
+		return Match{}, false, err
+	}
+	m.Similarity = 1 - distance
+	return m, true, nil
+}
+
+// Upsert implements JailbreakIndex.
+func (x *PGVectorIndex) Upsert(ctx context.Context, entries []Jailbreak) error {
+	batch := &pgx.Batch{}
+	for _, e := range entries {
+		batch.Queue(`
+			INSERT INTO `+x.table+` (id, category, text, embedding)
+			VALUES ($1, $2, $3, $4::vector)
+			ON CONFLICT (id) DO UPDATE SET
+				category = EXCLUDED.category,
+				text = EXCLUDED.text,
+				embedding = EXCLUDED.embedding`,
+			e.ID, e.Category, e.Text, vectorLiteral(e.Embedding))
+	}
+	if err := x.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("store jailbreak vectors: %w", err)
+	}
+	return nil
+}
+
+// vectorLiteral formats a vector in pgvector's text form, "[1,2,3]", so no
+// pgvector type registration is needed.
+func vectorLiteral(vec []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, f := range vec {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
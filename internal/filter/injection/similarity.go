@@ -0,0 +1,249 @@
+package injection
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const (
+	// maxEmbedChars bounds each message sent for embedding; jailbreak
+	// phrasing is nearly always near the start of a prompt.
+	maxEmbedChars = 8000
+
+	maxEmbeddingResponseBytes = 32 << 20
+	defaultEmbeddingTimeout   = 500 * time.Millisecond
+	seedBatchSize             = 64
+)
+
+// Jailbreak is one entry of the known-jailbreak vector set.
+type Jailbreak struct {
+	ID        string    `json:"id"`
+	Category  string    `json:"category"`
+	Text      string    `json:"text"`
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Match is the nearest known jailbreak to a prompt.
+type Match struct {
+	ID         string
+	Category   string
+	Similarity float64
+}
+
+// Embedder turns texts into embedding vectors, one per text, in order.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// JailbreakIndex stores the known-jailbreak vectors. It is satisfied by
+// *RedisIndex and *PGVectorIndex.
+type JailbreakIndex interface {
+	// Nearest returns the most similar entry by cosine similarity; ok is
+	// false when the index is empty.
+	Nearest(ctx context.Context, vec []float32) (m Match, ok bool, err error)
+	Upsert(ctx context.Context, entries []Jailbreak) error
+}
+
+// SimilarityDetector flags prompts whose embedding is close to a known
+// jailbreak, catching rephrasings that evade the regex rules.
+type SimilarityDetector struct {
+	embedder Embedder
+	index    JailbreakIndex
+	cfg      func() config.InjectionSimilarityConfig
+}
+
+// NewSimilarityDetector creates a detector over the given index.
+func NewSimilarityDetector(embedder Embedder, index JailbreakIndex, cfg func() config.InjectionSimilarityConfig) *SimilarityDetector {
+	return &SimilarityDetector{embedder: embedder, index: index, cfg: cfg}
+}
+
+// Check embeds the user messages and returns the closest known jailbreak
+// when it reaches the configured threshold.
+func (d *SimilarityDetector) Check(ctx context.Context, messages []types.Message) (Match, bool, error) {
+	cfg := d.cfg()
+	var texts []string
+	for _, m := range messages {
+		if m.Role != "user" || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		text := m.Content
+		if len(text) > maxEmbedChars {
+			text = text[:maxEmbedChars]
+		}
+		texts = append(texts, text)
+	}
+	if len(texts) == 0 {
+		return Match{}, false, nil
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultEmbeddingTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	vecs, err := d.embedder.Embed(ctx, texts)
+	if err != nil {
+		return Match{}, false, fmt.Errorf("embed prompt: %w", err)
+	}
+	var best Match
+	found := false
+	for _, vec := range vecs {
+		m, ok, err := d.index.Nearest(ctx, vec)
+		if err != nil {
+			return Match{}, false, fmt.Errorf("query jailbreak index: %w", err)
+		}
+		if ok && m.Similarity >= cfg.Threshold && (!found || m.Similarity > best.Similarity) {
+			best, found = m, true
+		}
+	}
+	return best, found, nil
+}
+
+// LoadSeedFile embeds the JSONL jailbreaks in path and upserts them into
+// the index. It returns the number of entries loaded.
+func LoadSeedFile(ctx context.Context, path string, embedder Embedder, index JailbreakIndex) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("open seed file: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Jailbreak
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		var j Jailbreak
+		if err := json.Unmarshal(sc.Bytes(), &j); err != nil {
+			return 0, fmt.Errorf("seed file line %d: %w", line, err)
+		}
+		if j.ID == "" || j.Text == "" {
+			return 0, fmt.Errorf("seed file line %d: id and text are required", line)
+		}
+		entries = append(entries, j)
+	}
+	if err := sc.Err(); err != nil {
+		return 0, fmt.Errorf("read seed file: %w", err)
+	}
+
+	for start := 0; start < len(entries); start += seedBatchSize {
+		batch := entries[start:min(start+seedBatchSize, len(entries))]
+		texts := make([]string, len(batch))
+		for i, j := range batch {
+			texts[i] = j.Text
+		}
+		vecs, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return start, fmt.Errorf("embed seed entries: %w", err)
+		}
+		for i := range batch {
+			batch[i].Embedding = vecs[i]
+		}
+		if err := index.Upsert(ctx, batch); err != nil {
+			return start, err
+		}
+	}
+	return len(entries), nil
+}
+
+// cosine returns the cosine similarity of two vectors, or 0 when their
+// dimensions differ or either is zero.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// HTTPEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type HTTPEmbedder struct {
+	url    string
+	model  string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTPEmbedder creates an embedder. Per-call deadlines come from the
+// request context.
+func NewHTTPEmbedder(url, model, apiKey string) *HTTPEmbedder {
+	return &HTTPEmbedder{url: url, model: model, apiKey: apiKey, client: &http.Client{}}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements Embedder.
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if e.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings endpoint returned %d", resp.StatusCode)
+	}
+
+	var out embeddingResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxEmbeddingResponseBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode embeddings: %w", err)
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, v := range vecs {
+		if len(v) == 0 {
+			return nil, fmt.Errorf("missing embedding for input %d", i)
+		}
+	}
+	return vecs, nil
+}
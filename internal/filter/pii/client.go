@@ -2,6 +2,7 @@ package pii
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -13,6 +14,9 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// errNotConnected reports a scan attempted before Connect succeeded.
+var errNotConnected = errors.New("pii service not connected")
+
 // Client wraps the gRPC FilterServiceClient and implements filter.Filter.
 type Client struct {
 	grpcClient filterv1.FilterServiceClient
@@ -48,20 +52,22 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// ConnState reports the gRPC connection state (e.g. "READY", "TRANSIENT_FAILURE"),
+// or "NOT_CONNECTED" if Connect has not succeeded.
+func (c *Client) ConnState() string {
+	if c.conn == nil {
+		return "NOT_CONNECTED"
+	}
+	return c.conn.GetState().String()
+}
+
 func (c *Client) Name() string  { return "pii" }
 func (c *Client) Enabled() bool { return c.cfg().Enabled }
 
 // ScanRequest implements filter.Filter.
 func (c *Client) ScanRequest(ctx context.Context, req *types.AegisRequest) filter.Result {
 	if c.grpcClient == nil {
-		if c.cfg().FailOpen {
-			return filter.Result{Action: filter.ActionPass, FilterName: "pii"}
-		}
-		return filter.Result{
-			Action:     filter.ActionBlock,
-			FilterName: "pii",
-			Message:    "PII service not connected",
-		}
+		return filter.Result{Action: filter.ActionPass, FilterName: "pii", Err: errNotConnected}
 	}
 
 	cfg := c.cfg()
@@ -70,21 +76,13 @@ func (c *Client) ScanRequest(ctx context.Context, req *types.AegisRequest) filte
 
 	classification := string(req.Classification)
 
-	for _, msg := range req.Messages {
+	for i, msg := range req.Messages {
 		resp, err := c.grpcClient.ScanPII(scanCtx, &filterv1.ScanPIIRequest{
-			Text:           msg.Content,
+			Text:           msg.FilterText(),
 			Classification: classification,
 		})
 		if err != nil {
-			slog.Error("pii service error", "error", err)
-			if cfg.FailOpen {
-				return filter.Result{Action: filter.ActionPass, FilterName: "pii"}
-			}
-			return filter.Result{
-				Action:     filter.ActionBlock,
-				FilterName: "pii",
-				Message:    "PII service unavailable",
-			}
+			return filter.Result{Action: filter.ActionPass, FilterName: "pii", Err: fmt.Errorf("pii service: %w", err)}
 		}
 
 		if resp.Detected {
@@ -95,6 +93,7 @@ func (c *Client) ScanRequest(ctx context.Context, req *types.AegisRequest) filte
 					FilterName: "pii",
 					Message:    fmt.Sprintf("PII detected: %d entities found", len(resp.Detections)),
 					Detections: len(resp.Detections),
+					Findings:   piiFindings(i, resp.Detections),
 				}
 			}
 			if action == filter.ActionFlag {
@@ -110,6 +109,15 @@ func (c *Client) ScanRequest(ctx context.Context, req *types.AegisRequest) filte
 	return filter.Result{Action: filter.ActionPass, FilterName: "pii"}
 }
 
+// piiFindings reports each entity the service found in message i by type.
+func piiFindings(i int, detections []*filterv1.PIIDetection) []filter.Finding {
+	findings := make([]filter.Finding, len(detections))
+	for j, d := range detections {
+		findings[j] = filter.Finding{Type: d.EntityType, Message: i, Start: int(d.Start), End: int(d.End)}
+	}
+	return findings
+}
+
 // classificationAction determines the action based on classification level.
 func classificationAction(classification string, detections int) filter.Action {
 	if detections == 0 {
@@ -26,14 +26,17 @@ func (m *mockFilterClient) ScanPII(ctx context.Context, in *filterv1.ScanPIIRequ
 	return &filterv1.ScanPIIResponse{Detected: false}, nil
 }
 
-func clientWithMock(mock *mockFilterClient, failOpen bool) *Client {
+func (m *mockFilterClient) ClassifyContent(context.Context, *filterv1.ClassifyContentRequest, ...grpc.CallOption) (*filterv1.ClassifyContentResponse, error) {
+	return &filterv1.ClassifyContentResponse{}, nil
+}
+
+func clientWithMock(mock *mockFilterClient) *Client {
 	return &Client{
 		grpcClient: mock,
 		cfg: func() config.PIIServiceConfig {
 			return config.PIIServiceConfig{
-				Enabled:  true,
-				Timeout:  5 * time.Second,
-				FailOpen: failOpen,
+				Enabled: true,
+				Timeout: 5 * time.Second,
 			}
 		},
 	}
@@ -45,7 +48,7 @@ func TestClient_NoPII_Pass(t *testing.T) {
 			return &filterv1.ScanPIIResponse{Detected: false}, nil
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages:       []types.Message{{Role: "user", Content: "Hello world"}},
 		Classification: "INTERNAL",
@@ -67,7 +70,7 @@ func TestClient_PIIDetected_Confidential_Block(t *testing.T) {
 			}, nil
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages:       []types.Message{{Role: "user", Content: "John Doe lives at 123 Main St"}},
 		Classification: "CONFIDENTIAL",
@@ -92,7 +95,7 @@ func TestClient_PIIDetected_Restricted_Block(t *testing.T) {
 			}, nil
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages:       []types.Message{{Role: "user", Content: "Email: john@example.com"}},
 		Classification: "RESTRICTED",
@@ -114,7 +117,7 @@ func TestClient_PIIDetected_Internal_Flag(t *testing.T) {
 			}, nil
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages:       []types.Message{{Role: "user", Content: "Contact me at john@example.com"}},
 		Classification: "INTERNAL",
@@ -125,69 +128,36 @@ func TestClient_PIIDetected_Internal_Flag(t *testing.T) {
 	}
 }
 
-func TestClient_GRPCError_FailClosed(t *testing.T) {
+func TestClient_GRPCError_ReportsFailure(t *testing.T) {
 	mock := &mockFilterClient{
 		scanFunc: func(_ context.Context, _ *filterv1.ScanPIIRequest) (*filterv1.ScanPIIResponse, error) {
 			return nil, fmt.Errorf("connection refused")
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages:       []types.Message{{Role: "user", Content: "test"}},
 		Classification: "INTERNAL",
 	}
 	result := c.ScanRequest(context.Background(), req)
-	if result.Action != filter.ActionBlock {
-		t.Errorf("expected ActionBlock on error (fail closed), got %s", result.Action)
-	}
-}
-
-func TestClient_GRPCError_FailOpen(t *testing.T) {
-	mock := &mockFilterClient{
-		scanFunc: func(_ context.Context, _ *filterv1.ScanPIIRequest) (*filterv1.ScanPIIResponse, error) {
-			return nil, fmt.Errorf("connection refused")
-		},
-	}
-	c := clientWithMock(mock, true)
-	req := &types.AegisRequest{
-		Messages:       []types.Message{{Role: "user", Content: "test"}},
-		Classification: "INTERNAL",
+	if result.Err == nil {
+		t.Fatal("expected the service error to be reported for the chain's fail-open policy")
 	}
-	result := c.ScanRequest(context.Background(), req)
 	if result.Action != filter.ActionPass {
-		t.Errorf("expected ActionPass on error (fail open), got %s", result.Action)
-	}
-}
-
-func TestClient_NotConnected_FailClosed(t *testing.T) {
-	c := NewClient(func() config.PIIServiceConfig {
-		return config.PIIServiceConfig{
-			Enabled:  true,
-			FailOpen: false,
-		}
-	})
-	req := &types.AegisRequest{
-		Messages: []types.Message{{Role: "user", Content: "test"}},
-	}
-	result := c.ScanRequest(context.Background(), req)
-	if result.Action != filter.ActionBlock {
-		t.Errorf("expected ActionBlock when not connected (fail closed), got %s", result.Action)
+		t.Errorf("expected ActionPass alongside the error, got %s", result.Action)
 	}
 }
 
-func TestClient_NotConnected_FailOpen(t *testing.T) {
+func TestClient_NotConnected_ReportsFailure(t *testing.T) {
 	c := NewClient(func() config.PIIServiceConfig {
-		return config.PIIServiceConfig{
-			Enabled:  true,
-			FailOpen: true,
-		}
+		return config.PIIServiceConfig{Enabled: true}
 	})
 	req := &types.AegisRequest{
 		Messages: []types.Message{{Role: "user", Content: "test"}},
 	}
 	result := c.ScanRequest(context.Background(), req)
-	if result.Action != filter.ActionPass {
-		t.Errorf("expected ActionPass when not connected (fail open), got %s", result.Action)
+	if result.Err == nil {
+		t.Error("expected a not-connected error when Connect has not run")
 	}
 }
 
@@ -236,7 +206,7 @@ func TestClient_MultipleMessages_FirstPIIBlocks(t *testing.T) {
 			return &filterv1.ScanPIIResponse{Detected: false}, nil
 		},
 	}
-	c := clientWithMock(mock, false)
+	c := clientWithMock(mock)
 	req := &types.AegisRequest{
 		Messages: []types.Message{
 			{Role: "user", Content: "Hello there"},
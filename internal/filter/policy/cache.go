@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/v1/ast"
+)
+
+// maxCachedDecisions bounds the decision cache; when it fills, it is
+// emptied rather than evicted entry by entry.
+const maxCachedDecisions = 10000
+
+// inputFields records which PolicyInput fields the loaded policies read, as
+// "user", "user.org", and so on. A decision depends only on those fields, so
+// they alone make up its cache key.
+type inputFields map[string]bool
+
+// policyInputFields returns the input fields modules read, or ok false when
+// a decision cannot be cached: a module reads the messages, uses input as a
+// value or indexes it with a computed key, or does not parse.
+func policyInputFields(modules map[string]string) (fields inputFields, ok bool) {
+	fields = inputFields{}
+	ok = true
+	for name, src := range modules {
+		mod, err := ast.ParseModule(name, src)
+		if err != nil {
+			return nil, false
+		}
+		ast.WalkTerms(mod, func(t *ast.Term) bool {
+			if t.Equal(ast.InputRootDocument) {
+				ok = false // input used as a value
+				return true
+			}
+			ref, isRef := t.Value.(ast.Ref)
+			if !isRef || !ref[0].Equal(ast.InputRootDocument) {
+				return false
+			}
+			if len(ref) < 2 {
+				ok = false
+				return true
+			}
+			top, isStr := ref[1].Value.(ast.String)
+			if !isStr || top == "messages" {
+				ok = false
+				return true
+			}
+			if len(ref) < 3 {
+				fields[string(top)] = true
+				return true
+			}
+			field, isStr := ref[2].Value.(ast.String)
+			if !isStr {
+				ok = false
+				return true
+			}
+			fields[string(top)+"."+string(field)] = true
+			return true
+		})
+		if !ok {
+			return nil, false
+		}
+	}
+	return fields, true
+}
+
+func (f inputFields) has(top, field string) bool {
+	return f[top] || f[top+"."+field]
+}
+
+// decisionKey is a PolicyInput without its messages and with every field
+// the policies do not read left zero.
+type decisionKey struct {
+	User    PolicyUser
+	Request PolicyReq
+	Time    PolicyTime
+}
+
+func (f inputFields) key(in PolicyInput) decisionKey {
+	var k decisionKey
+	if f.has("user", "id") {
+		k.User.ID = in.User.ID
+	}
+	if f.has("user", "org") {
+		k.User.Org = in.User.Org
+	}
+	if f.has("user", "team") {
+		k.User.Team = in.User.Team
+	}
+	if f.has("request", "model") {
+		k.Request.Model = in.Request.Model
+	}
+	if f.has("request", "classification") {
+		k.Request.Classification = in.Request.Classification
+	}
+	if f.has("request", "provider_type") {
+		k.Request.ProviderType = in.Request.ProviderType
+	}
+	if f.has("time", "hour") {
+		k.Time.Hour = in.Time.Hour
+	}
+	if f.has("time", "day") {
+		k.Time.Day = in.Time.Day
+	}
+	return k
+}
+
+type decision struct {
+	allowed bool
+	reason  string
+	expires time.Time
+}
+
+// decisionCache holds recent decisions of one compiled policy set. Each
+// Load replaces it, so a bundle reload invalidates every cached decision.
+type decisionCache struct {
+	fields inputFields // nil when decisions cannot be cached
+
+	mu      sync.Mutex
+	entries map[decisionKey]decision
+}
+
+func newDecisionCache(modules map[string]string) *decisionCache {
+	fields, ok := policyInputFields(modules)
+	if !ok {
+		return &decisionCache{}
+	}
+	return &decisionCache{fields: fields, entries: make(map[decisionKey]decision)}
+}
+
+func (c *decisionCache) get(k decisionKey, now time.Time) (decision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.entries[k]
+	if !ok || !now.Before(d.expires) {
+		return decision{}, false
+	}
+	return d, true
+}
+
+func (c *decisionCache) put(k decisionKey, d decision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedDecisions {
+		clear(c.entries)
+	}
+	c.entries[k] = d
+}
@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestPolicyInputFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   []string // nil means uncacheable
+	}{
+		{"default policy", defaultPolicy, []string{"request.classification", "request.provider_type"}},
+		{"whole object", `package aegis.policy
+import rego.v1
+allow if input.user.org in {"org-1"}
+allow if input.time`, []string{"user.org", "time"}},
+		{"reads messages", `package aegis.policy
+import rego.v1
+allow if not contains(input.messages[_].content, "secret")`, nil},
+		{"input as a value", `package aegis.policy
+import rego.v1
+x := input
+allow if x.request.model == "gpt-4o"`, nil},
+		{"computed key", `package aegis.policy
+import rego.v1
+allow if input[k] == "gpt-4o"`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, ok := policyInputFields(map[string]string{"test.rego": tt.policy})
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("expected uncacheable, got fields %v", fields)
+				}
+				return
+			}
+			if !ok || len(fields) != len(tt.want) {
+				t.Fatalf("fields = %v (ok %v), want %v", fields, ok, tt.want)
+			}
+			for _, f := range tt.want {
+				if !fields[f] {
+					t.Errorf("missing field %q in %v", f, fields)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluator_DecisionCache(t *testing.T) {
+	e := NewEvaluator(func() config.PolicyFilterConfig {
+		return config.PolicyFilterConfig{Enabled: true, EvaluationTimeout: 100 * time.Millisecond, DecisionCacheTTL: time.Minute}
+	})
+	fm := &fakeMetrics{}
+	e.SetMetrics(fm)
+	if err := e.LoadFromModules(map[string]string{"test.rego": defaultPolicy}); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(user string, class types.Classification) filter.Action {
+		return e.ScanRequest(context.Background(), &types.AegisRequest{
+			UserID: user, OrganizationID: "org-1", Model: "gpt-4o",
+			Classification: class, ProviderType: "external",
+			Messages: []types.Message{{Role: "user", Content: "hello " + user}},
+		}).Action
+	}
+
+	if scan("user-1", types.ClassRestricted) != filter.ActionBlock {
+		t.Fatal("expected RESTRICTED to external to be blocked")
+	}
+	// The default policy reads neither the user nor the messages, so a
+	// different user with the same request shares the decision.
+	if scan("user-2", types.ClassRestricted) != filter.ActionBlock {
+		t.Fatal("expected cached block")
+	}
+	if scan("user-1", types.ClassInternal) != filter.ActionPass {
+		t.Fatal("expected INTERNAL to pass")
+	}
+	if fm.cacheHits != 1 || fm.cacheMisses != 2 {
+		t.Errorf("hits/misses = %d/%d, want 1/2", fm.cacheHits, fm.cacheMisses)
+	}
+
+	// Reloading drops decisions made under the old policies.
+	allowAll := "package aegis.policy\nimport rego.v1\ndefault allow := true\ndefault reason := \"\"\n"
+	if err := e.LoadFromModules(map[string]string{"test.rego": allowAll}); err != nil {
+		t.Fatal(err)
+	}
+	if scan("user-1", types.ClassRestricted) != filter.ActionPass {
+		t.Error("expected the reloaded policy to decide, not the cached block")
+	}
+}
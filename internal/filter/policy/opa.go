@@ -2,6 +2,7 @@ package policy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -15,46 +16,50 @@ import (
 
 // PolicyMessage represents a single message for policy evaluation.
 type PolicyMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string `json:"role" yaml:"role"`
+	Content string `json:"content" yaml:"content"`
 }
 
 // PolicyInput is the data sent to OPA for evaluation.
 type PolicyInput struct {
-	User     PolicyUser      `json:"user"`
-	Request  PolicyReq       `json:"request"`
-	Messages []PolicyMessage `json:"messages"`
-	Time     PolicyTime      `json:"time"`
+	User     PolicyUser      `json:"user" yaml:"user"`
+	Request  PolicyReq       `json:"request" yaml:"request"`
+	Messages []PolicyMessage `json:"messages" yaml:"messages"`
+	Time     PolicyTime      `json:"time" yaml:"time"`
 }
 
 type PolicyUser struct {
-	ID   string `json:"id"`
-	Org  string `json:"org"`
-	Team string `json:"team"`
+	ID   string `json:"id" yaml:"id"`
+	Org  string `json:"org" yaml:"org"`
+	Team string `json:"team" yaml:"team"`
 }
 
 type PolicyReq struct {
-	Model          string `json:"model"`
-	Classification string `json:"classification"`
-	ProviderType   string `json:"provider_type"`
+	Model          string `json:"model" yaml:"model"`
+	Classification string `json:"classification" yaml:"classification"`
+	ProviderType   string `json:"provider_type" yaml:"provider_type"`
 }
 
 type PolicyTime struct {
-	Hour int    `json:"hour"`
-	Day  string `json:"day"`
+	Hour int    `json:"hour" yaml:"hour"`
+	Day  string `json:"day" yaml:"day"`
 }
 
-// ReloadMetrics is an optional interface for recording policy reload outcomes.
-type ReloadMetrics interface {
+// Metrics is an optional interface for recording policy reload and decision
+// cache outcomes.
+type Metrics interface {
 	RecordPolicyReload(success bool)
+	RecordPolicyCacheLookup(hit bool)
 }
 
 // Evaluator implements filter.Filter using OPA.
 type Evaluator struct {
 	mu       sync.RWMutex
 	prepared *rego.PreparedEvalQuery
-	cfg      func() config.PolicyFilterConfig
-	metrics  ReloadMetrics
+	// decisions caches decisions of prepared; nil when no policy is loaded.
+	decisions *decisionCache
+	cfg       func() config.PolicyFilterConfig
+	metrics   Metrics
 }
 
 // NewEvaluator creates a policy evaluator. Call Load() to compile policies.
@@ -62,11 +67,19 @@ func NewEvaluator(cfg func() config.PolicyFilterConfig) *Evaluator {
 	return &Evaluator{cfg: cfg}
 }
 
-// SetMetrics attaches a metrics recorder for policy reload events.
-func (e *Evaluator) SetMetrics(m ReloadMetrics) {
+// SetMetrics attaches a metrics recorder for policy reload and decision
+// cache events.
+func (e *Evaluator) SetMetrics(m Metrics) {
 	e.metrics = m
 }
 
+// Loaded reports whether a compiled policy is available for evaluation.
+func (e *Evaluator) Loaded() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.prepared != nil
+}
+
 func (e *Evaluator) Name() string  { return "policy" }
 func (e *Evaluator) Enabled() bool { return e.cfg().Enabled }
 
@@ -85,6 +98,7 @@ func (e *Evaluator) Load() error {
 		slog.Warn("no rego files found, clearing policies", "path", cfg.BundlePath)
 		e.mu.Lock()
 		e.prepared = nil
+		e.decisions = nil
 		e.mu.Unlock()
 		e.recordReload(true)
 		return nil
@@ -115,9 +129,11 @@ func (e *Evaluator) Load() error {
 		return fmt.Errorf("prepare rego: %w", err)
 	}
 
-	// Swap only after successful compilation.
+	// Swap only after successful compilation. Decisions cached under the
+	// previous policies go with them.
 	e.mu.Lock()
 	e.prepared = &prepared
+	e.decisions = newDecisionCache(modules)
 	e.mu.Unlock()
 
 	slog.Info("opa policies loaded", "modules", len(modules))
@@ -131,6 +147,37 @@ func (e *Evaluator) recordReload(success bool) {
 	}
 }
 
+// decide evaluates input, answering from the decision cache when
+// filter.policy.decision_cache_ttl is set and the loaded policies read no
+// message content.
+func (e *Evaluator) decide(ctx context.Context, input PolicyInput, now time.Time) (bool, string, error) {
+	ttl := e.cfg().DecisionCacheTTL
+	e.mu.RLock()
+	cache := e.decisions
+	e.mu.RUnlock()
+	if ttl <= 0 || cache == nil || cache.fields == nil {
+		return e.Evaluate(ctx, input)
+	}
+
+	k := cache.fields.key(input)
+	if d, ok := cache.get(k, now); ok {
+		e.recordCacheLookup(true)
+		return d.allowed, d.reason, nil
+	}
+	e.recordCacheLookup(false)
+	allowed, reason, err := e.Evaluate(ctx, input)
+	if err == nil {
+		cache.put(k, decision{allowed: allowed, reason: reason, expires: now.Add(ttl)})
+	}
+	return allowed, reason, err
+}
+
+func (e *Evaluator) recordCacheLookup(hit bool) {
+	if e.metrics != nil {
+		e.metrics.RecordPolicyCacheLookup(hit)
+	}
+}
+
 // LoadFromModules compiles policies from provided module sources (useful for testing).
 func (e *Evaluator) LoadFromModules(modules map[string]string) error {
 	r := rego.New(
@@ -155,6 +202,7 @@ func (e *Evaluator) LoadFromModules(modules map[string]string) error {
 
 	e.mu.Lock()
 	e.prepared = &prepared
+	e.decisions = newDecisionCache(modules)
 	e.mu.Unlock()
 	return nil
 }
@@ -227,15 +275,12 @@ func (e *Evaluator) ScanRequest(ctx context.Context, req *types.AegisRequest) fi
 		},
 	}
 
-	allowed, reason, err := e.Evaluate(ctx, input)
+	if !e.Loaded() {
+		return filter.Result{Action: filter.ActionPass, FilterName: "policy", Err: errors.New("no policies loaded")}
+	}
+	allowed, reason, err := e.decide(ctx, input, now)
 	if err != nil {
-		slog.Error("policy evaluation failed", "error", err)
-		// Fail closed
-		return filter.Result{
-			Action:     filter.ActionBlock,
-			FilterName: "policy",
-			Message:    "Policy evaluation failed: " + err.Error(),
-		}
+		return filter.Result{Action: filter.ActionPass, FilterName: "policy", Err: fmt.Errorf("policy evaluation: %w", err)}
 	}
 
 	if !allowed {
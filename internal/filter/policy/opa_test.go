@@ -16,6 +16,16 @@ import (
 type fakeMetrics struct {
 	reloadSuccess int
 	reloadError   int
+	cacheHits     int
+	cacheMisses   int
+}
+
+func (f *fakeMetrics) RecordPolicyCacheLookup(hit bool) {
+	if hit {
+		f.cacheHits++
+	} else {
+		f.cacheMisses++
+	}
 }
 
 func (f *fakeMetrics) RecordPolicyReload(success bool) {
@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestCase is one policy fixture: an input and the decision it must produce.
+//
+// Time may be given directly as input.time {hour, day} or as an RFC 3339
+// timestamp in At, which is converted the same way ScanRequest does (UTC).
+type TestCase struct {
+	Name   string      `yaml:"name"`
+	Input  PolicyInput `yaml:"input"`
+	At     string      `yaml:"at"`
+	Expect struct {
+		Allow *bool `yaml:"allow"`
+		// Reason, when set, must appear in the policy's reason string.
+		Reason string `yaml:"reason"`
+	} `yaml:"expect"`
+
+	// File is the fixture file the case was read from.
+	File string `yaml:"-"`
+}
+
+// TestResult is the outcome of running a single TestCase.
+type TestResult struct {
+	Case    TestCase
+	Allowed bool
+	Reason  string
+	Err     error
+	Failure string // empty when the case passed
+}
+
+// Passed reports whether the case produced the expected decision.
+func (r TestResult) Passed() bool { return r.Err == nil && r.Failure == "" }
+
+type fixtureFile struct {
+	Cases []TestCase `yaml:"cases"`
+}
+
+// LoadTestCases reads policy fixtures from a YAML file, or from every .yaml
+// and .yml file in a directory (sorted by name). Each file holds a top-level
+// "cases" list.
+func LoadTestCases(path string) ([]TestCase, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+		sort.Strings(files)
+	}
+
+	var cases []TestCase
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var f fixtureFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for i, tc := range f.Cases {
+			tc.File = file
+			if tc.Name == "" {
+				tc.Name = fmt.Sprintf("case %d", i+1)
+			}
+			if tc.Expect.Allow == nil {
+				return nil, fmt.Errorf("%s: %s: expect.allow is required", file, tc.Name)
+			}
+			if tc.At != "" {
+				at, err := time.Parse(time.RFC3339, tc.At)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s: invalid at: %w", file, tc.Name, err)
+				}
+				at = at.UTC()
+				tc.Input.Time = PolicyTime{Hour: at.Hour(), Day: at.Weekday().String()}
+			}
+			cases = append(cases, tc)
+		}
+	}
+	return cases, nil
+}
+
+// RunTests evaluates every case against the loaded policy.
+func RunTests(ctx context.Context, e *Evaluator, cases []TestCase) []TestResult {
+	results := make([]TestResult, 0, len(cases))
+	for _, tc := range cases {
+		res := TestResult{Case: tc}
+		res.Allowed, res.Reason, res.Err = e.Evaluate(ctx, tc.Input)
+		switch {
+		case res.Err != nil:
+		case res.Allowed != *tc.Expect.Allow:
+			res.Failure = fmt.Sprintf("expected allow=%t, got allow=%t", *tc.Expect.Allow, res.Allowed)
+			if res.Reason != "" {
+				res.Failure += fmt.Sprintf(" (reason: %s)", res.Reason)
+			}
+		case tc.Expect.Reason != "" && !strings.Contains(res.Reason, tc.Expect.Reason):
+			res.Failure = fmt.Sprintf("expected reason containing %q, got %q", tc.Expect.Reason, res.Reason)
+		}
+		results = append(results, res)
+	}
+	return results
+}
@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFixture(t *testing.T, dir, name, body string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTestCases_Dir(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "b.yaml", `cases:
+  - name: weekend
+    input:
+      request: {classification: PUBLIC, provider_type: external}
+    at: "2026-10-18T22:30:00+02:00"
+    expect: {allow: true}
+`)
+	writeFixture(t, dir, "a.yml", `cases:
+  - input:
+      user: {id: u-1, org: acme, team: search}
+      request: {model: m, classification: RESTRICTED, provider_type: external}
+      messages: [{role: user, content: hi}]
+      time: {hour: 9, day: Monday}
+    expect: {allow: false, reason: RESTRICTED}
+`)
+	writeFixture(t, dir, "notes.txt", "ignored")
+
+	cases, err := LoadTestCases(dir)
+	if err != nil {
+		t.Fatalf("LoadTestCases: %v", err)
+	}
+	if len(cases) != 2 {
+		t.Fatalf("got %d cases, want 2", len(cases))
+	}
+	first := cases[0]
+	if first.Name != "case 1" || first.Input.Request.ProviderType != "external" || first.Input.User.Org != "acme" ||
+		len(first.Input.Messages) != 1 || first.Input.Time.Day != "Monday" {
+		t.Errorf("unexpected first case (a.yml should sort first): %+v", first)
+	}
+	// "at" is converted to UTC like ScanRequest does.
+	if tm := cases[1].Input.Time; tm.Hour != 20 || tm.Day != "Sunday" {
+		t.Errorf("time from at = %+v, want 20/Sunday", tm)
+	}
+}
+
+func TestLoadTestCases_RequiresExpectAllow(t *testing.T) {
+	dir := t.TempDir()
+	writeFixture(t, dir, "bad.yaml", "cases:\n  - name: no expectation\n    input: {}\n")
+	if _, err := LoadTestCases(filepath.Join(dir, "bad.yaml")); err == nil {
+		t.Error("expected error when expect.allow is missing")
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	e := NewEvaluator(testCfg())
+	if err := e.LoadFromModules(map[string]string{"default.rego": defaultPolicy}); err != nil {
+		t.Fatal(err)
+	}
+	allow, deny := true, false
+	cases := []TestCase{{Name: "denied"}, {Name: "wrong reason"}, {Name: "wrong decision"}}
+	for i := range cases {
+		cases[i].Input.Request = PolicyReq{Classification: "RESTRICTED", ProviderType: "external"}
+	}
+	cases[0].Expect.Allow, cases[0].Expect.Reason = &deny, "external providers"
+	cases[1].Expect.Allow, cases[1].Expect.Reason = &deny, "business hours"
+	cases[2].Expect.Allow = &allow
+
+	results := RunTests(context.Background(), e, cases)
+	if !results[0].Passed() {
+		t.Errorf("expected %q to pass: %s", cases[0].Name, results[0].Failure)
+	}
+	for _, r := range results[1:] {
+		if r.Passed() || r.Failure == "" {
+			t.Errorf("expected %q to fail", r.Case.Name)
+		}
+	}
+}
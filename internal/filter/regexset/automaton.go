@@ -0,0 +1,107 @@
+package regexset
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// otherSymbol stands for every input rune that no literal contains.
+const otherSymbol = 0
+
+// foldedRunes maps the non-ASCII runes that case-fold to an ASCII letter
+// (the Kelvin sign to k, the long s to s) onto that letter, so (?i) literals
+// cannot be dodged with them.
+var foldedRunes = func() map[rune]byte {
+	m := map[rune]byte{}
+	for c := 'a'; c <= 'z'; c++ {
+		for r := unicode.SimpleFold(c); r != c; r = unicode.SimpleFold(r) {
+			if r >= utf8.RuneSelf {
+				m[r] = byte(c)
+			}
+		}
+	}
+	return m
+}()
+
+// automaton is an Aho-Corasick automaton over ASCII-lowercased input, with
+// its goto and failure functions folded into one transition table.
+type automaton struct {
+	next    [][128]int32
+	outputs [][]output
+	maxLen  int
+}
+
+type output struct {
+	length  int // in input symbols
+	pattern int
+}
+
+func newAutomaton(lits []string, owners []int) *automaton {
+	a := &automaton{next: make([][128]int32, 1), outputs: make([][]output, 1)}
+	for i, lit := range lits {
+		state := int32(0)
+		for j := 0; j < len(lit); j++ {
+			c := lit[j]
+			if a.next[state][c] == 0 {
+				a.next = append(a.next, [128]int32{})
+				a.outputs = append(a.outputs, nil)
+				a.next[state][c] = int32(len(a.next) - 1)
+			}
+			state = a.next[state][c]
+		}
+		a.outputs[state] = append(a.outputs[state], output{length: len(lit), pattern: owners[i]})
+		a.maxLen = max(a.maxLen, len(lit))
+	}
+
+	// Breadth-first, point each missing transition where the failure link's
+	// would go, and inherit the failure link's outputs.
+	fail := make([]int32, len(a.next))
+	var queue []int32
+	for c := range 128 {
+		if s := a.next[0][c]; s != 0 {
+			queue = append(queue, s)
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		a.outputs[state] = append(a.outputs[state], a.outputs[fail[state]]...)
+		for c := range 128 {
+			s := a.next[state][c]
+			if s == 0 {
+				a.next[state][c] = a.next[fail[state]][c]
+				continue
+			}
+			fail[s] = a.next[fail[state]][c]
+			queue = append(queue, s)
+		}
+	}
+	return a
+}
+
+// scan returns, per pattern, the byte offsets in text at which one of its
+// literals starts, unsorted and possibly repeated.
+func (a *automaton) scan(text string, patterns int) [][]int {
+	out := make([][]int, patterns)
+	// starts holds the byte offsets of the last maxLen symbols, so a match
+	// ending here can be traced back to where it began.
+	starts := make([]int, a.maxLen)
+	state := int32(0)
+	for i, n := 0, 0; i < len(text); n++ {
+		c, size := text[i], 1
+		if c >= utf8.RuneSelf {
+			var r rune
+			r, size = utf8.DecodeRuneInString(text[i:])
+			c = foldedRunes[r] // otherSymbol when absent
+		} else if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		starts[n%a.maxLen] = i
+		state = a.next[state][c]
+		for _, o := range a.outputs[state] {
+			out[o.pattern] = append(out[o.pattern], starts[(n-o.length+1)%a.maxLen])
+		}
+		i += size
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+// Package regexset finds every match of a set of regular expressions with a
+// single pass over the text. The literal prefixes the patterns must start
+// with are found together by an Aho-Corasick automaton, and each pattern's
+// regexp is then run only where one of its prefixes occurs, anchored there.
+// Patterns without a usable prefix are run over the whole text as before.
+package regexset
+
+import (
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// minPrefixLen is the shortest literal worth prefiltering on; shorter
+	// ones hit so often that the anchored confirms cost more than a scan.
+	minPrefixLen = 2
+	// maxPrefixes bounds the literals expanded from one pattern.
+	maxPrefixes = 64
+)
+
+// Set is a compiled set of patterns. It is safe for concurrent use.
+type Set struct {
+	patterns []*regexp.Regexp
+	// anchored[i] matches pattern i at the start of the text; contextual[i]
+	// does the same after consuming the one rune before it, so leading
+	// assertions such as \b see the real preceding character.
+	anchored   []*regexp.Regexp
+	contextual []*regexp.Regexp
+	// always lists the patterns with no literal prefix.
+	always []int
+	ac     *automaton
+}
+
+// New compiles patterns into a Set. Matches are reported per pattern, in
+// the order given.
+func New(patterns []*regexp.Regexp) *Set {
+	s := &Set{
+		patterns:   patterns,
+		anchored:   make([]*regexp.Regexp, len(patterns)),
+		contextual: make([]*regexp.Regexp, len(patterns)),
+	}
+	var lits []string
+	var owners []int
+	for i, re := range patterns {
+		prefixes, ok := literalPrefixes(re.String())
+		if !ok {
+			s.always = append(s.always, i)
+			continue
+		}
+		s.anchored[i] = regexp.MustCompile(`^(?:` + re.String() + `)`)
+		s.contextual[i] = regexp.MustCompile(`^(?s:.)(?:` + re.String() + `)`)
+		for _, p := range prefixes {
+			lits = append(lits, p)
+			owners = append(owners, i)
+		}
+	}
+	if len(lits) > 0 {
+		s.ac = newAutomaton(lits, owners)
+	}
+	return s
+}
+
+// FindAll returns, for each pattern, the same locations as
+// FindAllStringIndex(text, -1) would.
+func (s *Set) FindAll(text string) [][][]int {
+	out := make([][][]int, len(s.patterns))
+	for _, i := range s.always {
+		out[i] = s.patterns[i].FindAllStringIndex(text, -1)
+	}
+	if s.ac == nil {
+		return out
+	}
+
+	candidates := s.ac.scan(text, len(s.patterns))
+	for i, starts := range candidates {
+		if len(starts) == 0 {
+			continue
+		}
+		slices.Sort(starts)
+		end := 0
+		for j, pos := range starts {
+			if pos < end || (j > 0 && pos == starts[j-1]) {
+				continue
+			}
+			if e := s.matchAt(i, text, pos); e > pos {
+				out[i] = append(out[i], []int{pos, e})
+				end = e
+			}
+		}
+	}
+	return out
+}
+
+// matchAt returns the end of pattern i's leftmost-first match starting at
+// pos, or -1 if there is none.
+func (s *Set) matchAt(i int, text string, pos int) int {
+	if pos == 0 {
+		if loc := s.anchored[i].FindStringIndex(text); loc != nil {
+			return loc[1]
+		}
+		return -1
+	}
+	_, n := utf8.DecodeLastRuneInString(text[:pos])
+	if loc := s.contextual[i].FindStringIndex(text[pos-n:]); loc != nil {
+		return pos - n + loc[1]
+	}
+	return -1
+}
+
+// literalPrefixes returns lower-cased ASCII literals one of which every
+// match of expr starts with, matched case-insensitively. ok is false when
+// no such set of at least minPrefixLen bytes each can be found.
+func literalPrefixes(expr string) (prefixes []string, ok bool) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	prefixes, _, ok = prefixesOf(re.Simplify())
+	if !ok || len(prefixes) == 0 {
+		return nil, false
+	}
+	for _, p := range prefixes {
+		if len(p) < minPrefixLen {
+			return nil, false
+		}
+	}
+	return prefixes, true
+}
+
+// prefixesOf returns literals every match of re starts with. exact reports
+// that re matches those literals and nothing else, so a following
+// concatenation element may extend them.
+func prefixesOf(re *syntax.Regexp) (prefixes []string, exact, ok bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		var b strings.Builder
+		for _, r := range re.Rune {
+			if r >= utf8.RuneSelf {
+				return nil, false, false
+			}
+			b.WriteRune(unicode.ToLower(r))
+		}
+		return []string{b.String()}, true, true
+	case syntax.OpCapture:
+		return prefixesOf(re.Sub[0])
+	case syntax.OpPlus:
+		p, _, ok := prefixesOf(re.Sub[0])
+		return p, false, ok
+	case syntax.OpRepeat:
+		if re.Min < 1 {
+			return nil, false, false
+		}
+		p, _, ok := prefixesOf(re.Sub[0])
+		return p, false, ok
+	case syntax.OpAlternate:
+		exact = true
+		for _, sub := range re.Sub {
+			p, e, ok := prefixesOf(sub)
+			if !ok {
+				return nil, false, false
+			}
+			prefixes = append(prefixes, p...)
+			exact = exact && e
+		}
+		return prefixes, exact, len(prefixes) <= maxPrefixes
+	case syntax.OpConcat:
+		subs := re.Sub
+		// Zero-width assertions at the start do not move where a match
+		// begins; the contextual confirm checks them.
+		for len(subs) > 0 && isAssertion(subs[0].Op) {
+			subs = subs[1:]
+		}
+		if len(subs) == 0 {
+			return nil, false, false
+		}
+		prefixes, exact, ok = prefixesOf(subs[0])
+		if !ok {
+			return nil, false, false
+		}
+		for _, sub := range subs[1:] {
+			if !exact {
+				break
+			}
+			next, e, ok := prefixesOf(sub)
+			if !ok || len(prefixes)*len(next) > maxPrefixes {
+				return prefixes, false, true
+			}
+			var joined []string
+			for _, p := range prefixes {
+				for _, n := range next {
+					joined = append(joined, p+n)
+				}
+			}
+			prefixes, exact = joined, e
+		}
+		return prefixes, exact, true
+	}
+	return nil, false, false
+}
+
+func isAssertion(op syntax.Op) bool {
+	switch op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary, syntax.OpEmptyMatch:
+		return true
+	}
+	return false
+}
@@ -4,32 +4,43 @@ import (
 	"context"
 	"fmt"
 
+	"regexp"
+
 	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/filter/regexset"
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
 
 // Detection represents a detected secret in text.
 type Detection struct {
 	PatternName string // e.g. "AWS Access Key"
+	Message     int    // index of the message, set by ScanMessages
 	Start       int    // byte offset
 	End         int    // byte offset
 }
 
-// Scanner scans text for secrets using pre-compiled regex patterns.
+// Scanner scans text for secrets using pre-compiled regex patterns, matched
+// together in one pass by a regexset.Set.
 type Scanner struct {
 	patterns []Pattern
+	set      *regexset.Set
 }
 
 // NewScanner creates a scanner with the default secret patterns.
 func NewScanner() *Scanner {
-	return &Scanner{patterns: DefaultPatterns()}
+	patterns := DefaultPatterns()
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		res[i] = p.Regex
+	}
+	return &Scanner{patterns: patterns, set: regexset.New(res)}
 }
 
 // Scan checks a single text string for secrets and returns all detections.
 func (s *Scanner) Scan(text string) []Detection {
 	var detections []Detection
-	for _, p := range s.patterns {
-		locs := p.Regex.FindAllStringIndex(text, -1)
+	for i, locs := range s.set.FindAll(text) {
+		p := s.patterns[i]
 		for _, loc := range locs {
 			detections = append(detections, Detection{
 				PatternName: p.Name,
@@ -41,11 +52,15 @@ func (s *Scanner) Scan(text string) []Detection {
 	return detections
 }
 
-// ScanMessages scans all messages for secrets. Returns all detections found.
+// ScanMessages scans all messages, tool calls and tool results included, for
+// secrets. Returns all detections found.
 func (s *Scanner) ScanMessages(messages []types.Message) []Detection {
 	var detections []Detection
-	for _, m := range messages {
-		detections = append(detections, s.Scan(m.Content)...)
+	for i, m := range messages {
+		for _, d := range s.Scan(m.FilterText()) {
+			d.Message = i
+			detections = append(detections, d)
+		}
 	}
 	return detections
 }
@@ -70,8 +85,10 @@ func (f *SecretsFilter) ScanRequest(_ context.Context, req *types.AegisRequest)
 		return filter.Result{Action: filter.ActionPass, FilterName: "secrets"}
 	}
 	seen := map[string]bool{}
-	for _, d := range detections {
+	findings := make([]filter.Finding, len(detections))
+	for i, d := range detections {
 		seen[d.PatternName] = true
+		findings[i] = filter.Finding{Type: d.PatternName, Message: d.Message, Start: d.Start, End: d.End}
 	}
 	secretTypes := ""
 	for name := range seen {
@@ -85,5 +102,6 @@ func (f *SecretsFilter) ScanRequest(_ context.Context, req *types.AegisRequest)
 		FilterName: "secrets",
 		Message:    fmt.Sprintf("Request blocked: detected %d secret(s) of type: %s", len(detections), secretTypes),
 		Detections: len(detections),
+		Findings:   findings,
 	}
 }
@@ -187,6 +187,26 @@ func TestScanMessages(t *testing.T) {
 	if detections[0].PatternName != "AWS Access Key" {
 		t.Errorf("expected AWS Access Key, got %s", detections[0].PatternName)
 	}
+	if d := detections[0]; d.Message != 1 || d.Start != 16 || d.End != 36 {
+		t.Errorf("expected message 1 at [16, 36), got message %d at [%d, %d)", d.Message, d.Start, d.End)
+	}
+}
+
+func TestScanMessages_ToolPayloads(t *testing.T) {
+	s := NewScanner()
+
+	messages := []types.Message{
+		{Role: "assistant", ToolCalls: []types.ToolCall{{
+			ID: "call_1", Type: "function",
+			Function: types.ToolCallFunction{Name: "deploy", Arguments: `{"aws_key":"AKIA\u0049OSFODNN7EXAMPLE"}`},
+		}}},
+		{Role: "tool", ToolCallID: "call_1", Content: `{"dsn":"postgres:\/\/user:pass@host\/db"}`},
+	}
+
+	detections := s.ScanMessages(messages)
+	if len(detections) != 2 {
+		t.Fatalf("expected secrets in the tool call and the tool result, got %+v", detections)
+	}
 }
 
 func TestScanMessages_Clean(t *testing.T) {
@@ -0,0 +1,235 @@
+// Package fixture records provider request/response pairs to disk and
+// replays them, so adapter transformations can be regression-tested against
+// real provider payload shapes without calling the provider again.
+//
+// Fixtures are sanitized before they are written: credentials never reach
+// the file because only the request body is kept, secrets and PII in both
+// bodies are redacted, and only response headers the adapters read are
+// kept. A fixture is found again by a key over the request method, path, and
+// sanitized body, so replaying the same request is deterministic.
+package fixture
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/af-corp/aegis-gateway/internal/archive"
+)
+
+// ErrNotRecorded is returned when replaying a request no fixture matches.
+var ErrNotRecorded = errors.New("no fixture recorded for request")
+
+// Fixture is one recorded provider exchange, as stored on disk.
+type Fixture struct {
+	Provider string   `json:"provider"`
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is the sanitized provider request.
+type Request struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body"`
+}
+
+// Response is the sanitized provider response. Body holds the JSON document
+// or, for streams, the raw SSE text.
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// keptHeaders are response headers adapters read; all others are dropped.
+var keptHeaders = []string{"Content-Type", "X-Request-Id", "Request-Id", "Apim-Request-Id", "Retry-After"}
+
+// keptHeaderPrefixes keep the provider rate limit headers.
+var keptHeaderPrefixes = []string{"X-Ratelimit-", "Anthropic-Ratelimit-"}
+
+var redactor = archive.NewRedactor()
+
+// Key identifies the fixture for a request with the given sanitized body.
+func Key(method, path, body string) string {
+	sum := sha256.Sum256([]byte(method + " " + path + "\n" + body))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Path returns where the fixture for provider and key is stored under dir.
+func Path(dir, provider, key string) string {
+	return filepath.Join(dir, provider, key+".json")
+}
+
+// Load reads the fixture stored at path.
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// HTTPResponse rebuilds the recorded response for req.
+func (f *Fixture) HTTPResponse(req *http.Request) *http.Response {
+	h := http.Header{}
+	for k, v := range f.Response.Headers {
+		h.Set(k, v)
+	}
+	return &http.Response{
+		StatusCode:    f.Response.StatusCode,
+		Status:        fmt.Sprintf("%d %s", f.Response.StatusCode, http.StatusText(f.Response.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(strings.NewReader(f.Response.Body)),
+		ContentLength: int64(len(f.Response.Body)),
+		Request:       req,
+	}
+}
+
+// readRequest reads and restores req's body, returning the sanitized
+// request and its key.
+func readRequest(req *http.Request) (Request, string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return Request{}, "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	r := Request{Method: req.Method, Path: req.URL.Path, Body: redactor.Redact(string(body))}
+	return r, Key(r.Method, r.Path, r.Body), nil
+}
+
+// Replayer answers provider requests from the fixtures recorded for one
+// provider. It never makes a connection.
+type Replayer struct {
+	dir      string
+	provider string
+}
+
+// NewReplayer replays the fixtures for provider under dir.
+func NewReplayer(dir, provider string) *Replayer {
+	return &Replayer{dir: dir, provider: provider}
+}
+
+func (p *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, key, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Load(Path(p.dir, p.provider, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s %s (key %s)", ErrNotRecorded, p.provider, r.Method, r.Path, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f.HTTPResponse(req), nil
+}
+
+// Recorder passes provider requests to base and writes each exchange to a
+// fixture once its response body has been read to the end. Streams reach
+// the caller as they arrive; a response abandoned part way is not recorded.
+type Recorder struct {
+	dir      string
+	provider string
+	base     http.RoundTripper
+	onError  func(error)
+}
+
+// NewRecorder records provider's traffic through base under dir. onError,
+// if non-nil, is told about fixtures that could not be written.
+func NewRecorder(dir, provider string, base http.RoundTripper, onError func(error)) *Recorder {
+	return &Recorder{dir: dir, provider: provider, base: base, onError: onError}
+}
+
+func (c *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r, key, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{Provider: c.provider, Request: r, Response: Response{StatusCode: resp.StatusCode, Headers: keepHeaders(resp.Header)}}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(body []byte) {
+		f.Response.Body = redactor.Redact(string(body))
+		if err := write(Path(c.dir, c.provider, key), f); err != nil && c.onError != nil {
+			c.onError(err)
+		}
+	}}
+	return resp, nil
+}
+
+func keepHeaders(h http.Header) map[string]string {
+	kept := map[string]string{}
+	for k := range h {
+		name := http.CanonicalHeaderKey(k)
+		keep := false
+		for _, want := range keptHeaders {
+			keep = keep || name == want
+		}
+		for _, prefix := range keptHeaderPrefixes {
+			keep = keep || strings.HasPrefix(name, prefix)
+		}
+		if keep {
+			kept[name] = h.Get(k)
+		}
+	}
+	return kept
+}
+
+// write stores f at path, replacing any earlier recording of the same
+// request.
+func write(path string, f *Fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordingBody copies what is read through it and calls done with the
+// whole body once the end is reached.
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.once.Do(func() { b.done(b.buf.Bytes()) })
+	}
+	return n, err
+}
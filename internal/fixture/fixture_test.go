@@ -0,0 +1,101 @@
+package fixture
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const providerBody = `{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Mail me at jane@example.com"},"finish_reason":"stop"}],"usage":{"prompt_tokens":9,"completion_tokens":6,"total_tokens":15}}`
+
+func TestRecordThenReplay(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ratelimit-Remaining-Requests", "99")
+		w.Header().Set("Set-Cookie", "session=secret")
+		_, _ = io.WriteString(w, providerBody)
+	}))
+	defer provider.Close()
+	dir := t.TempDir()
+	req := &types.AegisRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "I am bob@example.com"}}}
+	cfg := config.ProviderConfig{BaseURL: provider.URL, APIKey: "sk-live-credential"}
+
+	recorder := NewRecorder(dir, "openai", http.DefaultTransport, func(err error) { t.Error(err) })
+	recorded := roundTrip(t, adapters.NewOpenAIAdapter(cfg, &http.Client{Transport: recorder}), req)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "openai", "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("expected one fixture, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	for _, leaked := range []string{"sk-live-credential", "bob@example.com", "jane@example.com", "session=secret"} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("fixture should not contain %q:\n%s", leaked, data)
+		}
+	}
+	if !strings.Contains(string(data), "X-Ratelimit-Remaining-Requests") {
+		t.Error("rate limit headers should be kept")
+	}
+
+	provider.Close()
+	replayed := roundTrip(t, adapters.NewOpenAIAdapter(cfg, &http.Client{Transport: NewReplayer(dir, "openai")}), req)
+	if replayed.Usage != recorded.Usage || replayed.Model != recorded.Model {
+		t.Errorf("replay = %+v, want %+v", replayed, recorded)
+	}
+	if got := replayed.Choices[0].Message.Content; got != "Mail me at [REDACTED:EMAIL_ADDRESS]" {
+		t.Errorf("replayed content = %q, want the redacted recording", got)
+	}
+
+	other := &types.AegisRequest{Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "something else"}}}
+	httpReq, _ := adapters.NewOpenAIAdapter(cfg, nil).TransformRequest(context.Background(), other)
+	if _, err := NewReplayer(dir, "openai").RoundTrip(httpReq); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("unrecorded request: got %v, want ErrNotRecorded", err)
+	}
+}
+
+func TestRecorder_SkipsAbandonedResponse(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, providerBody)
+	}))
+	defer provider.Close()
+	dir := t.TempDir()
+
+	httpReq, _ := http.NewRequest(http.MethodPost, provider.URL+"/chat/completions", strings.NewReader(`{}`))
+	resp, err := NewRecorder(dir, "openai", http.DefaultTransport, nil).RoundTrip(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = resp.Body.Read(make([]byte, 4))
+	_ = resp.Body.Close()
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "openai", "*.json")); len(files) != 0 {
+		t.Errorf("a partly read response should not be recorded, got %v", files)
+	}
+}
+
+func roundTrip(t *testing.T, a *adapters.OpenAIAdapter, req *types.AegisRequest) *types.AegisResponse {
+	t.Helper()
+	httpReq, err := a.TransformRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := a.SendRequest(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := a.TransformResponse(context.Background(), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
@@ -0,0 +1,363 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/af-corp/aegis-gateway/internal/types"
+	"github.com/af-corp/aegis-gateway/internal/validation"
+)
+
+// BatchStore persists batch jobs. It is satisfied by *storage.BatchStore.
+type BatchStore interface {
+	CreateBatch(ctx context.Context, job storage.BatchJob, items []storage.BatchItem) error
+	GetBatch(ctx context.Context, id string) (*storage.BatchJob, error)
+	BatchResults(ctx context.Context, id string) ([]storage.BatchItem, error)
+	CancelBatch(ctx context.Context, id string) (*storage.BatchJob, error)
+	ClaimItem(ctx context.Context, skipOrgs []string) (*storage.BatchItem, error)
+	ReleaseItem(ctx context.Context, batchID string, line int) error
+	CompleteItem(ctx context.Context, item storage.BatchItem) error
+	ResetStaleItems(ctx context.Context, cutoff time.Time) (int64, error)
+	ExpireBatches(ctx context.Context, now time.Time) (int64, error)
+}
+
+// BudgetChecker reports a team's daily spend. It is satisfied by
+// *ratelimit.BudgetTracker.
+type BudgetChecker interface {
+	CheckDailySpend(ctx context.Context, orgID, teamID string, limitCents int64) (ratelimit.BudgetResult, error)
+}
+
+// SetBatchStore enables the batch API. budget may be nil to skip the
+// aggregate budget check on submission.
+func (h *Handler) SetBatchStore(s BatchStore, budget BudgetChecker) {
+	h.batches = s
+	h.budget = budget
+}
+
+// batchLine is one line of a submitted JSONL file.
+type batchLine struct {
+	CustomID string          `json:"custom_id"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// batchResultLine is one line of a job's JSONL results.
+type batchResultLine struct {
+	CustomID   string          `json:"custom_id"`
+	StatusCode int             `json:"status_code"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      json.RawMessage `json:"error,omitempty"`
+	CostUSD    float64         `json:"cost_usd"`
+}
+
+// batchPrincipal is the snapshot of the submitting key that batch requests
+// run as. When the runner has a key store, KeyHash looks the key up again
+// before every item and the snapshot only identifies it.
+type batchPrincipal struct {
+	KeyID                string               `json:"key_id"`
+	KeyHash              string               `json:"key_hash,omitempty"`
+	OrganizationID       string               `json:"organization_id"`
+	TeamID               string               `json:"team_id"`
+	UserID               string               `json:"user_id,omitempty"`
+	MaxClassification    types.Classification `json:"max_classification"`
+	AllowedModels        []string             `json:"allowed_models,omitempty"`
+	DailySpendLimitCents *int                 `json:"daily_spend_limit_cents,omitempty"`
+	ModelRules           auth.ModelRules      `json:"model_rules,omitzero"`
+}
+
+func (p batchPrincipal) authInfo() *auth.AuthInfo {
+	return &auth.AuthInfo{
+		KeyID:                p.KeyID,
+		OrganizationID:       p.OrganizationID,
+		TeamID:               p.TeamID,
+		UserID:               p.UserID,
+		MaxClassification:    p.MaxClassification,
+		AllowedModels:        p.AllowedModels,
+		DailySpendLimitCents: p.DailySpendLimitCents,
+		ModelRules:           p.ModelRules,
+	}
+}
+
+// batchesEnabled writes a 404 and reports false when the batch API is off.
+func (h *Handler) batchesEnabled(w http.ResponseWriter, reqID string) bool {
+	if h.batches == nil || h.cfg == nil || !h.cfg().Batch.Enabled {
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "not_found", "Batch API is not enabled")
+		return false
+	}
+	return true
+}
+
+// CreateBatch handles POST /aegis/v1/batches. The body is JSONL, one
+// {"custom_id": ..., "body": <chat completion request>} per line. Every line
+// is validated up front so a bad file is rejected whole, and the estimated
+// cost of the batch must fit in what is left of the team's daily budget.
+func (h *Handler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	if !h.batchesEnabled(w, reqID) {
+		return
+	}
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+	batchCfg := h.cfg().Batch
+
+	limits := h.sizeLimits()
+	limits.MaxBodyBytes = batchCfg.MaxBodyBytes
+	body, err := validation.ReadBody(w, r, limits)
+	if err != nil {
+		var tooLarge *validation.TooLargeError
+		if errors.As(err, &tooLarge) {
+			httputil.WritePayloadTooLargeError(w, reqID, tooLarge.Message)
+			return
+		}
+		httputil.WriteBadRequestError(w, reqID, "Failed to read request body")
+		return
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	items, estimatedUSD, err := h.parseBatch(body, authInfo, batchCfg.MaxRequests)
+	if err != nil {
+		httputil.WriteBadRequestError(w, reqID, err.Error())
+		return
+	}
+
+	if authInfo.DailySpendLimitCents != nil && h.budget != nil {
+		res, err := h.budget.CheckDailySpend(r.Context(), authInfo.OrganizationID, authInfo.TeamID, int64(*authInfo.DailySpendLimitCents))
+		if err != nil {
+			httputil.WriteRetryableCode(w, reqID, httputil.CodeServiceUnavailable, "Budget tracking service temporarily unavailable", nil, res.ResetAt)
+			return
+		}
+		remaining := res.LimitCents - res.SpentCents
+		if estimated := int64(math.Ceil(estimatedUSD * 100)); estimated > remaining {
+			httputil.WriteRetryableCode(w, reqID, httputil.CodeBudgetExceeded,
+				fmt.Sprintf("Batch is estimated at %d cents but only %d of the %d cent daily budget remain", estimated, max(remaining, 0), res.LimitCents),
+				nil, res.ResetAt)
+			return
+		}
+	}
+
+	principal, err := json.Marshal(batchPrincipal{
+		KeyID:                authInfo.KeyID,
+		KeyHash:              authInfo.KeyHash,
+		OrganizationID:       authInfo.OrganizationID,
+		TeamID:               authInfo.TeamID,
+		UserID:               authInfo.UserID,
+		MaxClassification:    authInfo.MaxClassification,
+		AllowedModels:        authInfo.AllowedModels,
+		DailySpendLimitCents: authInfo.DailySpendLimitCents,
+		ModelRules:           authInfo.ModelRules,
+	})
+	if err != nil {
+		httputil.WriteInternalError(w, reqID, "Failed to create batch")
+		return
+	}
+	now := time.Now()
+	job := storage.BatchJob{
+		ID:             newBatchID(),
+		OrganizationID: authInfo.OrganizationID,
+		TeamID:         authInfo.TeamID,
+		APIKeyID:       authInfo.KeyID,
+		Principal:      principal,
+		Status:         storage.BatchQueued,
+		TotalRequests:  len(items),
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(batchCfg.CompletionWindow),
+	}
+	if err := h.batches.CreateBatch(r.Context(), job, items); err != nil {
+		slog.Error("failed to create batch", "request_id", reqID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to create batch")
+		return
+	}
+	slog.Info("batch created",
+		"request_id", reqID,
+		"batch_id", job.ID,
+		"org_id", authInfo.OrganizationID,
+		"team_id", authInfo.TeamID,
+		"requests", len(items),
+		"estimated_cost_usd", estimatedUSD,
+	)
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// parseBatch validates a JSONL batch and returns its items and a rough cost
+// estimate priced at each model's primary route.
+func (h *Handler) parseBatch(body []byte, authInfo *auth.AuthInfo, maxRequests int) ([]storage.BatchItem, float64, error) {
+	var items []storage.BatchItem
+	var estimatedUSD float64
+	seen := make(map[string]bool)
+	modelsCfg := h.modelsCfg()
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, len(body)+1)
+	for n := 1; scanner.Scan(); n++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if maxRequests > 0 && len(items) == maxRequests {
+			return nil, 0, fmt.Errorf("batch has more than %d requests", maxRequests)
+		}
+
+		var line batchLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, 0, fmt.Errorf("line %d: invalid JSON: %v", n, err)
+		}
+		if line.CustomID == "" || len(line.CustomID) > 255 {
+			return nil, 0, fmt.Errorf("line %d: custom_id is required and at most 255 characters", n)
+		}
+		if seen[line.CustomID] {
+			return nil, 0, fmt.Errorf("line %d: custom_id %q is not unique", n, line.CustomID)
+		}
+		seen[line.CustomID] = true
+
+		var req types.AegisRequest
+		if err := json.Unmarshal(line.Body, &req); err != nil {
+			return nil, 0, fmt.Errorf("line %d: invalid body: %v", n, err)
+		}
+		if req.Stream {
+			return nil, 0, fmt.Errorf("line %d: stream is not supported in batches", n)
+		}
+		// The stored body keeps the requested model; rules are applied again,
+		// as of submission, when the line runs.
+		req.Model = authInfo.ModelRules.Resolve(req.Model)
+		if h.validator != nil {
+			if err := h.validator.Validate(&req); err != nil {
+				return nil, 0, fmt.Errorf("line %d: %v", n, err)
+			}
+		}
+		if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, req.Model) {
+			return nil, 0, fmt.Errorf("line %d: API key is not allowed to use model %q", n, req.Model)
+		}
+
+		if mapping, ok := modelsCfg.Models[req.Model]; ok && h.costCalc != nil {
+			completion := 0
+			if limit := req.CompletionTokenLimit(); limit != nil {
+				completion = *limit
+			}
+			usd, _ := h.costCalc.Calculate(mapping.Primary.Provider, mapping.Primary.Model, estimatePromptTokens(req.Messages), completion)
+			estimatedUSD += usd
+		}
+		items = append(items, storage.BatchItem{Line: len(items), CustomID: line.CustomID, Body: line.Body})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("read batch: %v", err)
+	}
+	if len(items) == 0 {
+		return nil, 0, errors.New("batch has no requests")
+	}
+	return items, estimatedUSD, nil
+}
+
+// ownBatch loads a job for the caller, answering 404 for jobs of other
+// organizations so their IDs cannot be probed.
+func (h *Handler) ownBatch(w http.ResponseWriter, r *http.Request, reqID string) (*storage.BatchJob, bool) {
+	if !h.batchesEnabled(w, reqID) {
+		return nil, false
+	}
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return nil, false
+	}
+	job, err := h.batches.GetBatch(r.Context(), chi.URLParam(r, "id"))
+	if errors.Is(err, storage.ErrBatchNotFound) || (err == nil && job.OrganizationID != authInfo.OrganizationID) {
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "batch_not_found", "Batch not found")
+		return nil, false
+	}
+	if err != nil {
+		slog.Error("failed to load batch", "request_id", reqID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to load batch")
+		return nil, false
+	}
+	return job, true
+}
+
+// GetBatch handles GET /aegis/v1/batches/{id}.
+func (h *Handler) GetBatch(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	job, ok := h.ownBatch(w, r, reqID)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// GetBatchResults handles GET /aegis/v1/batches/{id}/results: the finished
+// requests as JSONL in submission order. It may be polled while the job runs.
+func (h *Handler) GetBatchResults(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	job, ok := h.ownBatch(w, r, reqID)
+	if !ok {
+		return
+	}
+	items, err := h.batches.BatchResults(r.Context(), job.ID)
+	if err != nil {
+		slog.Error("failed to load batch results", "request_id", reqID, "batch_id", job.ID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to load batch results")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, it := range items {
+		line := batchResultLine{CustomID: it.CustomID, StatusCode: it.StatusCode, CostUSD: it.CostUSD}
+		if it.Status == storage.BatchItemSucceeded {
+			line.Response = it.Response
+		} else {
+			line.Error = it.Response
+		}
+		_ = enc.Encode(line)
+	}
+}
+
+// CancelBatch handles POST /aegis/v1/batches/{id}/cancel. Requests already
+// running finish; the rest never start.
+func (h *Handler) CancelBatch(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	job, ok := h.ownBatch(w, r, reqID)
+	if !ok {
+		return
+	}
+	cancelled, err := h.batches.CancelBatch(r.Context(), job.ID)
+	if errors.Is(err, storage.ErrBatchNotFound) {
+		httputil.WriteError(w, reqID, http.StatusConflict, "invalid_request_error", "batch_finished",
+			fmt.Sprintf("Batch is already %s", job.Status))
+		return
+	}
+	if err != nil {
+		slog.Error("failed to cancel batch", "request_id", reqID, "batch_id", job.ID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to cancel batch")
+		return
+	}
+	writeJSON(w, http.StatusOK, cancelled)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newBatchID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "batch_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,339 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// batchBudgetBackoff is how long an organization's batch items are left
+// alone after its team was found over budget.
+const batchBudgetBackoff = time.Minute
+
+// BatchAdmitter gates batch work on gateway load. It is satisfied by
+// *ratelimit.LoadShedder.
+type BatchAdmitter interface {
+	Admit(p types.Priority) (release func(), ok bool)
+}
+
+// BatchRunner works through queued batch items with a pool of workers. Each
+// item runs through the full chat completion pipeline as the key that
+// submitted it, so filters, policy, routing, and usage apply as for an
+// interactive request but at batch priority. The per-organization cap is
+// enforced per replica.
+type BatchRunner struct {
+	h        *Handler
+	admitter BatchAdmitter
+	keys     auth.KeyStore
+	limit    func(http.Handler) http.Handler
+
+	mu        sync.Mutex
+	running   map[string]int       // org -> items in flight
+	throttled map[string]time.Time // org -> skip until
+	wg        sync.WaitGroup
+}
+
+// NewBatchRunner creates a runner for the handler's batch store.
+func NewBatchRunner(h *Handler) *BatchRunner {
+	return &BatchRunner{
+		h:         h,
+		running:   make(map[string]int),
+		throttled: make(map[string]time.Time),
+	}
+}
+
+// SetAdmitter makes workers count against the gateway's in-flight limit and
+// pause while batch capacity is used up, leaving room for interactive
+// traffic.
+func (br *BatchRunner) SetAdmitter(a BatchAdmitter) {
+	br.admitter = a
+}
+
+// SetKeyStore makes every item look its key up again before it runs, so
+// items of a key that has since been revoked, expired, or had its
+// organization or team suspended fail instead of running, and limit or
+// permission changes apply to the items still queued.
+func (br *BatchRunner) SetKeyStore(ks auth.KeyStore) {
+	br.keys = ks
+}
+
+// SetRateLimit runs items through mw, normally ratelimit.Middleware, so they
+// count against the key's RPM and TPM limits. Items over a limit go back to
+// the queue and the organization's items wait for the limit to reset.
+func (br *BatchRunner) SetRateLimit(mw func(http.Handler) http.Handler) {
+	br.limit = mw
+}
+
+// Start launches the workers and the expiry loop. They stop when ctx is
+// cancelled; Wait blocks until they have. Items interrupted by shutdown go
+// back to the queue.
+func (br *BatchRunner) Start(ctx context.Context) {
+	cfg := br.h.cfg().Batch
+	for range cfg.Workers {
+		br.wg.Add(1)
+		go func() {
+			defer br.wg.Done()
+			for {
+				if !br.RunOnce(ctx) && !sleepCtx(ctx, br.h.cfg().Batch.PollInterval) {
+					return
+				}
+				if ctx.Err() != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	br.wg.Add(1)
+	go func() {
+		defer br.wg.Done()
+		for sleepCtx(ctx, time.Minute) {
+			br.maintain(ctx)
+		}
+	}()
+}
+
+// Wait blocks until the workers started by Start have returned.
+func (br *BatchRunner) Wait() {
+	br.wg.Wait()
+}
+
+// maintain expires jobs past their window and requeues items orphaned by a
+// replica that died mid-request.
+func (br *BatchRunner) maintain(ctx context.Context) {
+	now := time.Now()
+	if n, err := br.h.batches.ExpireBatches(ctx, now); err != nil {
+		slog.Error("failed to expire batches", "error", err)
+	} else if n > 0 {
+		slog.Info("batches expired", "count", n)
+	}
+	if staleAfter := br.h.cfg().Batch.StaleAfter; staleAfter > 0 {
+		if n, err := br.h.batches.ResetStaleItems(ctx, now.Add(-staleAfter)); err != nil {
+			slog.Error("failed to reset stale batch items", "error", err)
+		} else if n > 0 {
+			slog.Warn("stale batch items requeued", "count", n)
+		}
+	}
+}
+
+// RunOnce claims and runs one item. It reports false when there was nothing
+// to claim or no capacity to run it, so the caller should wait before polling
+// again.
+func (br *BatchRunner) RunOnce(ctx context.Context) bool {
+	if br.admitter != nil {
+		release, ok := br.admitter.Admit(types.PriorityBatch)
+		if !ok {
+			return false
+		}
+		defer release()
+	}
+
+	item, job, ok := br.claim(ctx)
+	if !ok {
+		return false
+	}
+	defer br.done(job.OrganizationID)
+
+	var principal batchPrincipal
+	if err := json.Unmarshal(job.Principal, &principal); err != nil {
+		br.complete(ctx, item, http.StatusInternalServerError, batchError("Batch principal is unreadable"), 0)
+		return true
+	}
+	authInfo := principal.authInfo()
+	if br.keys != nil {
+		meta, err := br.keys.Lookup(ctx, principal.KeyHash)
+		if err != nil {
+			slog.Error("failed to look up batch key", "batch_id", item.BatchID, "key_id", principal.KeyID, "error", err)
+			br.release(ctx, item)
+			return false
+		}
+		switch {
+		case meta == nil || meta.ID != principal.KeyID:
+			br.complete(ctx, item, http.StatusUnauthorized, itemError(httputil.TypeAuthentication, httputil.CodeInvalidAPIKey, "The API key that submitted the batch is no longer active"), 0)
+			return true
+		case meta.OrgSuspended:
+			br.complete(ctx, item, http.StatusForbidden, itemError(httputil.TypePermission, "organization_suspended", "Organization is suspended"), 0)
+			return true
+		case meta.TeamSuspended:
+			br.complete(ctx, item, http.StatusForbidden, itemError(httputil.TypePermission, "team_suspended", "Team is suspended"), 0)
+			return true
+		}
+		authInfo = meta.AuthInfo()
+		authInfo.KeyHash = principal.KeyHash
+	}
+	authInfo.Priority = types.PriorityBatch
+
+	if authInfo.DailySpendLimitCents != nil && br.h.budget != nil {
+		res, err := br.h.budget.CheckDailySpend(ctx, authInfo.OrganizationID, authInfo.TeamID, int64(*authInfo.DailySpendLimitCents))
+		if err != nil || !res.Allowed {
+			// Not a failure of the request: hold the organization's items
+			// until the budget resets or is raised.
+			br.throttle(job.OrganizationID, batchBudgetBackoff)
+			br.release(ctx, item)
+			return true
+		}
+	}
+
+	reqID := fmt.Sprintf("%s-%d", item.BatchID, item.Line)
+	req, err := http.NewRequestWithContext(auth.ContextWithAuth(ctx, authInfo), http.MethodPost, "/v1/chat/completions", bytes.NewReader(item.Body))
+	if err != nil {
+		br.complete(ctx, item, http.StatusInternalServerError, batchError("Failed to build request"), 0)
+		return true
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	cw := newCaptureWriter(reqID)
+	var serve http.Handler = http.HandlerFunc(br.h.ChatCompletions)
+	if br.limit != nil {
+		serve = br.limit(serve)
+	}
+	serve.ServeHTTP(cw, req)
+
+	if ctx.Err() != nil {
+		// Shutting down: the request was cut short, so let another worker
+		// run it rather than recording a failure.
+		br.release(ctx, item)
+		return true
+	}
+
+	status := cw.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	costUSD, _ := strconv.ParseFloat(cw.header.Get(headerCostUSD), 64)
+	body := bytes.TrimSpace(cw.body.Bytes())
+	if status != http.StatusOK {
+		var errBody struct {
+			Error json.RawMessage `json:"error"`
+		}
+		if json.Unmarshal(body, &errBody) == nil && len(errBody.Error) > 0 {
+			body = errBody.Error
+		}
+		var apiErr httputil.APIErrorBody
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Code == httputil.CodeRateLimitExceeded {
+			// Over the key's limit is not a failure of the item: run it
+			// once the window frees up.
+			wait, _ := strconv.Atoi(cw.header.Get("Retry-After"))
+			br.throttle(job.OrganizationID, time.Duration(max(wait, 1))*time.Second)
+			br.release(ctx, item)
+			return true
+		}
+	}
+	br.complete(ctx, item, status, body, costUSD)
+	return true
+}
+
+// claim takes the next item whose organization is under its concurrency cap
+// and not throttled, counting it as running.
+func (br *BatchRunner) claim(ctx context.Context) (*storage.BatchItem, *storage.BatchJob, bool) {
+	capPerOrg := br.h.cfg().Batch.MaxConcurrentPerOrg
+
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	now := time.Now()
+	var skip []string
+	for org, until := range br.throttled {
+		if now.Before(until) {
+			skip = append(skip, org)
+		} else {
+			delete(br.throttled, org)
+		}
+	}
+	for org, n := range br.running {
+		if capPerOrg > 0 && n >= capPerOrg {
+			skip = append(skip, org)
+		}
+	}
+
+	item, err := br.h.batches.ClaimItem(ctx, skip)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to claim batch item", "error", err)
+		}
+		return nil, nil, false
+	}
+	if item == nil {
+		return nil, nil, false
+	}
+	job, err := br.h.batches.GetBatch(ctx, item.BatchID)
+	if err != nil {
+		slog.Error("failed to load batch for item", "batch_id", item.BatchID, "error", err)
+		_ = br.h.batches.ReleaseItem(context.WithoutCancel(ctx), item.BatchID, item.Line)
+		return nil, nil, false
+	}
+	br.running[job.OrganizationID]++
+	return item, job, true
+}
+
+func (br *BatchRunner) done(org string) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	if br.running[org]--; br.running[org] <= 0 {
+		delete(br.running, org)
+	}
+}
+
+// throttle holds back org's items for d. Keys over their rate limit hold
+// back the whole organization, like a team over budget does.
+func (br *BatchRunner) throttle(org string, d time.Duration) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	br.throttled[org] = time.Now().Add(d)
+}
+
+// release puts item back in the queue for a later attempt.
+func (br *BatchRunner) release(ctx context.Context, item *storage.BatchItem) {
+	if err := br.h.batches.ReleaseItem(context.WithoutCancel(ctx), item.BatchID, item.Line); err != nil {
+		slog.Error("failed to release batch item", "batch_id", item.BatchID, "line", item.Line, "error", err)
+	}
+}
+
+func (br *BatchRunner) complete(ctx context.Context, item *storage.BatchItem, status int, body []byte, costUSD float64) {
+	item.Status = storage.BatchItemSucceeded
+	if status != http.StatusOK {
+		item.Status = storage.BatchItemFailed
+	}
+	item.StatusCode = status
+	item.Response = json.RawMessage(body)
+	item.CostUSD = costUSD
+	if err := br.h.batches.CompleteItem(context.WithoutCancel(ctx), *item); err != nil {
+		slog.Error("failed to record batch item result", "batch_id", item.BatchID, "line", item.Line, "error", err)
+	}
+}
+
+func batchError(message string) []byte {
+	return itemError("batch_error", "internal_error", message)
+}
+
+// itemError is the error object recorded as a failed item's response.
+func itemError(errType, code, message string) []byte {
+	b, _ := json.Marshal(map[string]string{"message": message, "type": errType, "code": code})
+	return b
+}
+
+// sleepCtx waits for d and reports whether ctx is still live.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		d = time.Second
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
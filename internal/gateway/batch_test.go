@@ -0,0 +1,367 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+// memBatchStore mirrors storage.BatchStore in memory.
+type memBatchStore struct {
+	mu    sync.Mutex
+	jobs  map[string]*storage.BatchJob
+	items map[string][]storage.BatchItem
+}
+
+func newMemBatchStore() *memBatchStore {
+	return &memBatchStore{jobs: map[string]*storage.BatchJob{}, items: map[string][]storage.BatchItem{}}
+}
+
+func (m *memBatchStore) CreateBatch(_ context.Context, job storage.BatchJob, items []storage.BatchItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.ID] = &job
+	for i := range items {
+		items[i].BatchID = job.ID
+		items[i].Status = storage.BatchItemPending
+	}
+	m.items[job.ID] = items
+	return nil
+}
+
+func (m *memBatchStore) GetBatch(_ context.Context, id string) (*storage.BatchJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, storage.ErrBatchNotFound
+	}
+	cp := *job
+	return &cp, nil
+}
+
+func (m *memBatchStore) BatchResults(_ context.Context, id string) ([]storage.BatchItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []storage.BatchItem
+	for _, it := range m.items[id] {
+		if it.Status == storage.BatchItemSucceeded || it.Status == storage.BatchItemFailed {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (m *memBatchStore) CancelBatch(_ context.Context, id string) (*storage.BatchJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := m.jobs[id]
+	if job.Status != storage.BatchQueued && job.Status != storage.BatchInProgress {
+		return nil, storage.ErrBatchNotFound
+	}
+	job.Status = storage.BatchCancelled
+	cp := *job
+	return &cp, nil
+}
+
+func (m *memBatchStore) ClaimItem(_ context.Context, skipOrgs []string) (*storage.BatchItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, items := range m.items {
+		job := m.jobs[id]
+		if job.Status != storage.BatchQueued && job.Status != storage.BatchInProgress {
+			continue
+		}
+		skipped := false
+		for _, org := range skipOrgs {
+			skipped = skipped || org == job.OrganizationID
+		}
+		if skipped {
+			continue
+		}
+		for i := range items {
+			if items[i].Status == storage.BatchItemPending {
+				items[i].Status = storage.BatchItemRunning
+				job.Status = storage.BatchInProgress
+				cp := items[i]
+				return &cp, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *memBatchStore) ReleaseItem(_ context.Context, batchID string, line int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[batchID][line].Status = storage.BatchItemPending
+	return nil
+}
+
+func (m *memBatchStore) CompleteItem(_ context.Context, item storage.BatchItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[item.BatchID][item.Line] = item
+	job := m.jobs[item.BatchID]
+	if item.Status == storage.BatchItemSucceeded {
+		job.CompletedRequests++
+	} else {
+		job.FailedRequests++
+	}
+	job.CostUSD += item.CostUSD
+	if job.CompletedRequests+job.FailedRequests == job.TotalRequests {
+		job.Status = storage.BatchCompleted
+	}
+	return nil
+}
+
+func (m *memBatchStore) ResetStaleItems(context.Context, time.Time) (int64, error) { return 0, nil }
+func (m *memBatchStore) ExpireBatches(context.Context, time.Time) (int64, error)   { return 0, nil }
+
+type fakeBudget struct{ result ratelimit.BudgetResult }
+
+func (f fakeBudget) CheckDailySpend(context.Context, string, string, int64) (ratelimit.BudgetResult, error) {
+	return f.result, nil
+}
+
+func newBatchTestHandler(t *testing.T) (*Handler, *memBatchStore, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"gpt-4o": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	}}
+	cfg := config.DefaultConfig()
+	cfg.Batch.Enabled = true
+	cfg.Batch.MaxRequests = 3
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	store := newMemBatchStore()
+	h.SetBatchStore(store, nil)
+	return h, store, &calls
+}
+
+func batchRequest(method, path, id, body, org string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	return req.WithContext(auth.ContextWithAuth(ctx, &auth.AuthInfo{OrganizationID: org, TeamID: "team-1", KeyID: "key-1"}))
+}
+
+const batchTestBody = `{"custom_id":"a","body":{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}}
+{"custom_id":"b","body":{"model":"gpt-4o","messages":[{"role":"user","content":"Bonjour"}]}}
+`
+
+func TestCreateBatch_RunsAndReturnsResults(t *testing.T) {
+	h, store, calls := newBatchTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.CreateBatch(w, batchRequest("POST", "/aegis/v1/batches", "", batchTestBody, "org-1"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var job storage.BatchJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	if job.TotalRequests != 2 || job.Status != storage.BatchQueued || !strings.HasPrefix(job.ID, "batch_") {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	runner := NewBatchRunner(h)
+	for runner.RunOnce(context.Background()) {
+	}
+	if calls.Load() != 2 {
+		t.Errorf("provider called %d times, want 2", calls.Load())
+	}
+	if got, _ := store.GetBatch(context.Background(), job.ID); got.Status != storage.BatchCompleted || got.CompletedRequests != 2 {
+		t.Errorf("job after run: %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	h.GetBatchResults(w, batchRequest("GET", "/aegis/v1/batches/x/results", job.ID, "", "org-1"))
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Code != http.StatusOK || len(lines) != 2 {
+		t.Fatalf("results = %d: %s", w.Code, w.Body.String())
+	}
+	var first batchResultLine
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.CustomID != "a" || first.StatusCode != http.StatusOK || !strings.Contains(string(first.Response), `"content":"hi"`) {
+		t.Errorf("unexpected first result: %+v", first)
+	}
+
+	// Another organization cannot see the job.
+	w = httptest.NewRecorder()
+	h.GetBatch(w, batchRequest("GET", "/aegis/v1/batches/x", job.ID, "", "org-2"))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("foreign org got %d, want 404", w.Code)
+	}
+}
+
+func TestCreateBatch_RejectsInvalidFiles(t *testing.T) {
+	h, _, _ := newBatchTestHandler(t)
+	line := `{"custom_id":"%s","body":{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}}`
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"empty", "\n", "no requests"},
+		{"missing custom_id", strings.Replace(line, "%s", "", 1), "custom_id is required"},
+		{"duplicate custom_id", strings.Replace(line, "%s", "x", 1) + "\n" + strings.Replace(line, "%s", "x", 1), "not unique"},
+		{"stream", `{"custom_id":"s","body":{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Hi"}]}}`, "stream is not supported"},
+		{"too many", strings.Join([]string{strings.Replace(line, "%s", "1", 1), strings.Replace(line, "%s", "2", 1), strings.Replace(line, "%s", "3", 1), strings.Replace(line, "%s", "4", 1)}, "\n"), "more than 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.CreateBatch(w, batchRequest("POST", "/aegis/v1/batches", "", tt.body, "org-1"))
+			if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("got %d %s, want 400 containing %q", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBatchRunner_ReleasesItemsOverBudget(t *testing.T) {
+	h, store, calls := newBatchTestHandler(t)
+	limit := 100
+	principal, _ := json.Marshal(batchPrincipal{OrganizationID: "org-1", TeamID: "team-1", DailySpendLimitCents: &limit})
+	_ = store.CreateBatch(context.Background(), storage.BatchJob{
+		ID: "batch_1", OrganizationID: "org-1", Principal: principal, Status: storage.BatchQueued, TotalRequests: 1,
+	}, []storage.BatchItem{{Line: 0, CustomID: "a", Body: json.RawMessage(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`)}})
+	h.SetBatchStore(store, fakeBudget{ratelimit.BudgetResult{Allowed: false, SpentCents: 100, LimitCents: 100}})
+
+	runner := NewBatchRunner(h)
+	if !runner.RunOnce(context.Background()) {
+		t.Fatal("expected an item to be claimed")
+	}
+	if calls.Load() != 0 {
+		t.Error("provider must not be called over budget")
+	}
+	if store.items["batch_1"][0].Status != storage.BatchItemPending {
+		t.Errorf("item should be back in the queue, got %s", store.items["batch_1"][0].Status)
+	}
+	// The organization is throttled, so the item is not claimed again.
+	if runner.RunOnce(context.Background()) {
+		t.Error("throttled organization's item was claimed again")
+	}
+}
+
+// fakeKeyStore looks keys up by hash.
+type fakeKeyStore map[string]*auth.KeyMetadata
+
+func (f fakeKeyStore) Lookup(_ context.Context, keyHash string) (*auth.KeyMetadata, error) {
+	return f[keyHash], nil
+}
+
+// queueBatchItem queues a one-item batch submitted by key-1 of org-1.
+func queueBatchItem(t *testing.T, store *memBatchStore) {
+	t.Helper()
+	principal, _ := json.Marshal(batchPrincipal{KeyID: "key-1", KeyHash: "hash-1", OrganizationID: "org-1", TeamID: "team-1"})
+	_ = store.CreateBatch(context.Background(), storage.BatchJob{
+		ID: "batch_1", OrganizationID: "org-1", Principal: principal, Status: storage.BatchQueued, TotalRequests: 1,
+	}, []storage.BatchItem{{Line: 0, CustomID: "a", Body: json.RawMessage(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hi"}]}`)}})
+}
+
+func TestBatchRunner_RechecksKey(t *testing.T) {
+	active := auth.KeyMetadata{ID: "key-1", OrganizationID: "org-1", TeamID: "team-1"}
+	orgSuspended, teamSuspended, narrowed := active, active, active
+	orgSuspended.OrgSuspended = true
+	teamSuspended.TeamSuspended = true
+	narrowed.AllowedModels = []string{"gpt-4o-mini"}
+
+	tests := []struct {
+		name     string
+		keys     fakeKeyStore
+		status   int
+		wantCode string
+	}{
+		{"revoked", fakeKeyStore{}, http.StatusUnauthorized, httputil.CodeInvalidAPIKey},
+		{"org suspended", fakeKeyStore{"hash-1": &orgSuspended}, http.StatusForbidden, "organization_suspended"},
+		{"team suspended", fakeKeyStore{"hash-1": &teamSuspended}, http.StatusForbidden, "team_suspended"},
+		{"models narrowed", fakeKeyStore{"hash-1": &narrowed}, http.StatusForbidden, httputil.CodeModelNotAllowed},
+		{"active", fakeKeyStore{"hash-1": &active}, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, store, calls := newBatchTestHandler(t)
+			queueBatchItem(t, store)
+			runner := NewBatchRunner(h)
+			runner.SetKeyStore(tt.keys)
+
+			if !runner.RunOnce(context.Background()) {
+				t.Fatal("expected an item to be claimed")
+			}
+			item := store.items["batch_1"][0]
+			if item.StatusCode != tt.status || (tt.wantCode != "" && !strings.Contains(string(item.Response), `"code":"`+tt.wantCode+`"`)) {
+				t.Errorf("item = %d %s, want %d %s", item.StatusCode, item.Response, tt.status, tt.wantCode)
+			}
+			wantCalls := int32(0)
+			if tt.status == http.StatusOK {
+				wantCalls = 1
+			}
+			if calls.Load() != wantCalls {
+				t.Errorf("provider called %d times, want %d", calls.Load(), wantCalls)
+			}
+		})
+	}
+}
+
+func TestBatchRunner_AppliesKeyRateLimits(t *testing.T) {
+	h, store, calls := newBatchTestHandler(t)
+	queueBatchItem(t, store)
+	rpm := 5
+	runner := NewBatchRunner(h)
+	runner.SetKeyStore(fakeKeyStore{"hash-1": {ID: "key-1", OrganizationID: "org-1", TeamID: "team-1", RPMLimit: &rpm}})
+	var seenRPM *int
+	runner.SetRateLimit(func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info, _ := auth.AuthFromContext(r.Context())
+			seenRPM = info.RPMLimit
+			httputil.WriteRetryableCode(w, w.Header().Get("X-Request-ID"), httputil.CodeRateLimitExceeded, "Rate limit exceeded", nil, time.Now().Add(30*time.Second))
+		})
+	})
+
+	if !runner.RunOnce(context.Background()) {
+		t.Fatal("expected an item to be claimed")
+	}
+	if seenRPM == nil || *seenRPM != rpm {
+		t.Errorf("rate limits should come from the key store, got %v", seenRPM)
+	}
+	if calls.Load() != 0 {
+		t.Error("provider must not be called over the rate limit")
+	}
+	if store.items["batch_1"][0].Status != storage.BatchItemPending {
+		t.Errorf("item should be back in the queue, got %s", store.items["batch_1"][0].Status)
+	}
+	if runner.RunOnce(context.Background()) {
+		t.Error("rate limited organization's item was claimed again")
+	}
+}
@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// headerBlockedBy names, on a response that is not a block error, the filter
+// that blocked the request: a synthesized message or a redirected answer.
+const headerBlockedBy = "X-Aegis-Blocked-By"
+
+// answerBlock answers a request the filter chain or policy blocked, as the
+// org's block response says: a 451 error, a synthesized assistant message,
+// or a redirect of req to an internal model. It returns false only for a
+// redirect, after which the request carries on with the new model and the
+// rest of the filter chain. A redirect to a model the caller's key may not
+// use is answered with the 451 instead.
+func (h *Handler) answerBlock(w http.ResponseWriter, r *http.Request, reqID string, authInfo *auth.AuthInfo, req *types.AegisRequest, blocked filter.Result) bool {
+	cfg := h.cfg().Filter
+	br := cfg.BlockResponseFor(authInfo.OrganizationID)
+	if br.Mode == config.BlockResponseRedirect && !redirectAllowed(authInfo, br.Model) {
+		slog.Warn("block redirect target not allowed for key, blocking instead",
+			"request_id", reqID,
+			"filter", blocked.FilterName,
+			"org_id", authInfo.OrganizationID,
+			"key_id", authInfo.KeyID,
+			"model", br.Model,
+		)
+		br.Mode = config.BlockResponseError
+	}
+	switch {
+	case br.Mode == config.BlockResponseRedirect && blocked.FilterName != "policy":
+		slog.Info("blocked request redirected",
+			"request_id", reqID,
+			"filter", blocked.FilterName,
+			"org_id", authInfo.OrganizationID,
+			"model", br.Model,
+		)
+		w.Header().Set(headerBlockedBy, blocked.FilterName)
+		if w.Header().Get(headerRequestedModel) == "" {
+			w.Header().Set(headerRequestedModel, req.Model)
+		}
+		req.Model = br.Model
+		return false
+	case br.Mode == config.BlockResponseMessage:
+		w.Header().Set(headerBlockedBy, blocked.FilterName)
+		writeBlockMessage(w, r, reqID, req, blocked, br.Message)
+	default:
+		httputil.WriteHTTPError(w, reqID, filterBlockError(blocked, cfg.ShowBlockDetails(authInfo.OrganizationID)))
+	}
+	return true
+}
+
+// redirectAllowed reports whether the key may be served model: it is among
+// the key's allowed models, if the key restricts them, and the key's model
+// rules would not rewrite it to another one.
+func redirectAllowed(authInfo *auth.AuthInfo, model string) bool {
+	if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, model) {
+		return false
+	}
+	return authInfo.ModelRules.Resolve(model) == model
+}
+
+// writeBlockMessage answers with a chat completion whose only choice is
+// message, finished for content_filter, streamed if req asked for a stream.
+func writeBlockMessage(w http.ResponseWriter, r *http.Request, reqID string, req *types.AegisRequest, blocked filter.Result, message string) {
+	resp := &types.AegisResponse{
+		RequestID: reqID,
+		Model:     req.Model,
+		Provider:  "aegis",
+		Choices: []types.Choice{{
+			Message:      types.Message{Role: "assistant", Content: message},
+			FinishReason: "content_filter",
+		}},
+		FilterActions: blockedSummary(blocked),
+	}
+	if req.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		if err := cache.ReplayStream(r.Context(), w, resp, cache.ReplayConfig{}); err != nil {
+			slog.Warn("failed to stream block message", "request_id", reqID, "error", err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// blockedSummary reports the blocking filter's action in filter_actions.
+func blockedSummary(blocked filter.Result) types.FilterSummary {
+	action := types.FilterAction{Action: string(filter.ActionBlock), Detections: blocked.Detections, Score: blocked.Score}
+	var s types.FilterSummary
+	switch blocked.FilterName {
+	case "pii":
+		s.PIIInbound = action
+	case "secrets":
+		s.Secrets = action
+	case "injection":
+		s.Injection = action
+	case "policy":
+		s.Policy = action
+	}
+	return s
+}
@@ -0,0 +1,31 @@
+package gateway
+
+import (
+	"log/slog"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/filter"
+)
+
+// recordFilterBypasses logs, audits, and counts each filter the request
+// skipped under a break-glass grant, so every use of a grant is on record.
+func (h *Handler) recordFilterBypasses(reqID string, authInfo *auth.AuthInfo, results []filter.Result, ip string) {
+	for _, fr := range results {
+		if fr.Action != filter.ActionBypass {
+			continue
+		}
+		slog.Warn("filter bypassed by break-glass grant",
+			"request_id", reqID,
+			"filter", fr.FilterName,
+			"grant_id", fr.GrantID,
+			"org_id", authInfo.OrganizationID,
+			"key_id", authInfo.KeyID,
+		)
+		if h.auditLogger != nil {
+			h.auditLogger.LogFilterBypass(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, fr.FilterName, fr.GrantID, ip)
+		}
+		if h.metrics != nil {
+			h.metrics.RecordFilterAction(fr.FilterName, authInfo.OrganizationID, string(filter.ActionBypass))
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/validation"
+)
+
+// compareResult is one model's outcome in a comparison. Response holds the
+// chat completion on success and Error the gateway's error body otherwise.
+type compareResult struct {
+	Model      string          `json:"model"`
+	StatusCode int             `json:"status_code"`
+	Provider   string          `json:"provider,omitempty"`
+	LatencyMs  int64           `json:"latency_ms"`
+	CostUSD    float64         `json:"cost_usd"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      json.RawMessage `json:"error,omitempty"`
+}
+
+type compareResponse struct {
+	Object       string          `json:"object"`
+	RequestID    string          `json:"request_id"`
+	Results      []compareResult `json:"results"`
+	TotalCostUSD float64         `json:"total_cost_usd"`
+}
+
+// Compare handles POST /aegis/v1/compare: a chat completion request whose
+// "models" list replaces "model". The prompt is sent to every model at once
+// and the responses come back side by side in the order given. Each call goes
+// through the full chat completions pipeline, so filters, classification
+// ceilings, context windows, and usage accounting apply per model; the rate
+// limit and budget checks in front of the endpoint apply once.
+func (h *Handler) Compare(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+
+	limits := h.sizeLimits()
+	body, err := validation.ReadBody(w, r, limits)
+	if err != nil {
+		var tooLarge *validation.TooLargeError
+		if errors.As(err, &tooLarge) {
+			httputil.WritePayloadTooLargeError(w, reqID, tooLarge.Message)
+			return
+		}
+		httputil.WriteBadRequestError(w, reqID, "Failed to read request body")
+		return
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		httputil.WriteBadRequestError(w, reqID, "Invalid JSON: "+err.Error())
+		return
+	}
+	var models []string
+	if err := json.Unmarshal(fields["models"], &models); err != nil || len(models) < 2 {
+		httputil.WriteBadRequestError(w, reqID, "models must list at least two models to compare")
+		return
+	}
+	if maxModels := h.maxCompareModels(); maxModels > 0 && len(models) > maxModels {
+		httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("models lists %d models; at most %d can be compared", len(models), maxModels))
+		return
+	}
+	for i, m := range models {
+		if slices.Contains(models[:i], m) {
+			httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("model %q is listed twice", m))
+			return
+		}
+		if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, m) {
+			httputil.WriteHTTPError(w, reqID, modelNotAllowed(m))
+			return
+		}
+	}
+	if stream, ok := fields["stream"]; ok && string(stream) == "true" {
+		httputil.WriteBadRequestError(w, reqID, "stream is not supported for comparisons")
+		return
+	}
+	delete(fields, "models")
+
+	results := make([]compareResult, len(models))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.compareOne(r, fmt.Sprintf("%s.%d", reqID, i), fields, model)
+		}()
+	}
+	wg.Wait()
+
+	resp := compareResponse{Object: "aegis.comparison", RequestID: reqID, Results: results}
+	for _, res := range results {
+		resp.TotalCostUSD += res.CostUSD
+	}
+	slog.Info("comparison completed",
+		"request_id", reqID,
+		"org_id", authInfo.OrganizationID,
+		"models", models,
+		"total_cost_usd", resp.TotalCostUSD,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// compareOne runs the chat completion for one model of a comparison under
+// its own request ID.
+func (h *Handler) compareOne(r *http.Request, reqID string, fields map[string]json.RawMessage, model string) compareResult {
+	res := compareResult{Model: model}
+
+	sub := make(map[string]json.RawMessage, len(fields)+1)
+	for k, v := range fields {
+		sub[k] = v
+	}
+	sub["model"], _ = json.Marshal(model)
+	body, err := json.Marshal(sub)
+	if err != nil {
+		res.StatusCode = http.StatusInternalServerError
+		return res
+	}
+
+	subReq := r.Clone(r.Context())
+	subReq.Body = io.NopCloser(bytes.NewReader(body))
+	subReq.ContentLength = int64(len(body))
+	// Replaying one model's answer for the whole comparison would be wrong.
+	subReq.Header.Del(headerIdempotencyKey)
+
+	cw := newCaptureWriter(reqID)
+	start := time.Now()
+	h.ChatCompletions(cw, subReq)
+	res.LatencyMs = time.Since(start).Milliseconds()
+
+	res.StatusCode = cw.status
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+	res.Provider = cw.header.Get(headerProvider)
+	res.CostUSD, _ = strconv.ParseFloat(cw.header.Get(headerCostUSD), 64)
+
+	var errBody struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if res.StatusCode == http.StatusOK {
+		res.Response = json.RawMessage(bytes.TrimSpace(cw.body.Bytes()))
+	} else if json.Unmarshal(cw.body.Bytes(), &errBody) == nil && len(errBody.Error) > 0 {
+		res.Error = errBody.Error
+	}
+	return res
+}
+
+// maxCompareModels returns the configured fan-out cap; zero means no cap.
+func (h *Handler) maxCompareModels() int {
+	if h.cfg == nil {
+		return 0
+	}
+	return h.cfg().Limits.MaxCompareModels
+}
+
+// captureWriter buffers a response in memory for internally dispatched
+// requests (comparisons and batch items).
+type captureWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// newCaptureWriter returns a writer carrying reqID the way
+// requestIDMiddleware would set it.
+func newCaptureWriter(reqID string) *captureWriter {
+	cw := &captureWriter{header: http.Header{}}
+	cw.header.Set("X-Request-ID", reqID)
+	return cw
+}
+
+func (c *captureWriter) Header() http.Header { return c.header }
+
+func (c *captureWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	return c.body.Write(p)
+}
@@ -0,0 +1,101 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+// newCompareTestHandler routes fast and smart to a fake OpenAI that echoes
+// the provider model it was asked for, and broken to a model it rejects.
+func newCompareTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"model"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &req)
+		w.Header().Set("Content-Type", "application/json")
+		if req.Model == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"message":"model not found","type":"invalid_request_error"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"` + req.Model + `","choices":[{"index":0,"message":{"role":"assistant","content":"from ` + req.Model + `"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"fast":   {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o-mini"}},
+		"smart":  {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+		"broken": {Primary: config.ProviderRoute{Provider: "openai", Model: "missing"}},
+	}}
+	cfg := config.DefaultConfig()
+	cfg.Limits.MaxCompareModels = 3
+	return NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+func TestCompare_FansOutInOrder(t *testing.T) {
+	h := newCompareTestHandler(t)
+	w := postAs(h.Compare, "/aegis/v1/compare", &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1"},
+		`{"models":["smart","broken","fast"],"messages":[{"role":"user","content":"Hi"}]}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp compareResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(resp.Results))
+	}
+	smart, broken, fast := resp.Results[0], resp.Results[1], resp.Results[2]
+	if smart.Model != "smart" || smart.StatusCode != http.StatusOK || !strings.Contains(string(smart.Response), "from gpt-4o") {
+		t.Errorf("unexpected smart result: %+v", smart)
+	}
+	if fast.Model != "fast" || fast.Provider != "openai" || !strings.Contains(string(fast.Response), "from gpt-4o-mini") {
+		t.Errorf("unexpected fast result: %+v", fast)
+	}
+	if broken.StatusCode != http.StatusNotFound || broken.Response != nil || !strings.Contains(string(broken.Error), "model not found") {
+		t.Errorf("a failing model should be reported, not fail the comparison: %+v", broken)
+	}
+}
+
+func TestCompare_RejectsInvalidRequests(t *testing.T) {
+	h := newCompareTestHandler(t)
+	info := &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1"}
+	messages := `"messages":[{"role":"user","content":"Hi"}]`
+
+	tests := []struct {
+		name   string
+		info   *auth.AuthInfo
+		body   string
+		status int
+	}{
+		{"single model", info, `{"models":["fast"],` + messages + `}`, http.StatusBadRequest},
+		{"too many models", info, `{"models":["fast","smart","broken","other"],` + messages + `}`, http.StatusBadRequest},
+		{"duplicate model", info, `{"models":["fast","fast"],` + messages + `}`, http.StatusBadRequest},
+		{"stream", info, `{"models":["fast","smart"],"stream":true,` + messages + `}`, http.StatusBadRequest},
+		{"model not allowed", &auth.AuthInfo{KeyID: "key-2", AllowedModels: []string{"fast"}}, `{"models":["fast","smart"],` + messages + `}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postAs(h.Compare, "/aegis/v1/compare", tt.info, tt.body, nil); w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// headerContextTruncated reports how many messages were dropped to fit the
+// model's context window.
+const headerContextTruncated = "X-Aegis-Context-Truncated"
+
+// fitContextWindow checks the request against the model's context_window,
+// counting the prompt estimate plus any requested completion limit. Requests
+// that do not fit are truncated for teams that opted in and rejected with
+// context_length_exceeded otherwise; handled reports that the error was
+// written. The estimate is rough, so windows should be set conservatively.
+func (h *Handler) fitContextWindow(w http.ResponseWriter, reqID string, authInfo *auth.AuthInfo, modelsCfg *config.ModelsConfig, aegisReq *types.AegisRequest) (handled bool) {
+	aegisReq.EstimatedTokens = estimatePromptTokens(aegisReq.Messages)
+	if modelsCfg == nil {
+		return false
+	}
+	window := modelsCfg.Models[aegisReq.Model].ContextWindow
+	if window == 0 {
+		return false
+	}
+	reserve := 0
+	if limit := aegisReq.CompletionTokenLimit(); limit != nil {
+		reserve = *limit
+	}
+	if aegisReq.EstimatedTokens+reserve <= window {
+		return false
+	}
+
+	if h.cfg != nil && slices.Contains(h.cfg().Limits.TruncateContextTeams, authInfo.TeamID) {
+		msgs, dropped := truncateMessages(aegisReq.Messages, window-reserve)
+		if estimate := estimatePromptTokens(msgs); estimate+reserve <= window {
+			slog.Info("truncated request to fit context window",
+				"request_id", reqID,
+				"team_id", authInfo.TeamID,
+				"model", aegisReq.Model,
+				"dropped_messages", dropped,
+				"estimated_tokens", estimate,
+				"context_window", window,
+			)
+			aegisReq.Messages = msgs
+			aegisReq.EstimatedTokens = estimate
+			w.Header().Set(headerContextTruncated, strconv.Itoa(dropped))
+			return false
+		}
+	}
+
+	httputil.WriteCode(w, reqID, httputil.CodeContextLengthExceeded,
+		fmt.Sprintf("This model's maximum context length is %d tokens; the request needs about %d (%d in messages, %d for the completion)",
+			window, aegisReq.EstimatedTokens+reserve, aegisReq.EstimatedTokens, reserve),
+		map[string]any{"context_window": window, "prompt_tokens": aegisReq.EstimatedTokens, "completion_tokens": reserve})
+	return true
+}
+
+// truncateMessages drops the oldest non-system messages until the estimate
+// fits budget, always keeping system messages and the final message. The
+// conversation is cut so that it still opens with a user turn: providers
+// reject a leading assistant turn or tool results for calls they cannot see.
+func truncateMessages(msgs []types.Message, budget int) ([]types.Message, int) {
+	out := slices.Clone(msgs)
+	dropped := 0
+	for estimatePromptTokens(out) > budget {
+		i := slices.IndexFunc(out, func(m types.Message) bool { return !isSystemRole(m.Role) })
+		if i < 0 || i == len(out)-1 {
+			break
+		}
+		out = slices.Delete(out, i, i+1)
+		dropped++
+		for i < len(out)-1 && out[i].Role != "user" && !isSystemRole(out[i].Role) {
+			out = slices.Delete(out, i, i+1)
+			dropped++
+		}
+	}
+	return out, dropped
+}
+
+func isSystemRole(role string) bool {
+	return role == "system" || role == "developer"
+}
@@ -0,0 +1,109 @@
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestTruncateMessages_DropsOldestNonSystem(t *testing.T) {
+	long := strings.Repeat("x", 400) // about 100 tokens
+	msgs := []types.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: long},
+		{Role: "assistant", ToolCalls: []types.ToolCall{{ID: "call_1"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: "ok"},
+		{Role: "user", Content: "And now?"},
+	}
+
+	got, dropped := truncateMessages(msgs, 250)
+	if dropped != 3 {
+		t.Fatalf("dropped %d messages, want 3: %+v", dropped, got)
+	}
+	if got[0].Role != "system" || got[1].Role != "user" || got[1].Content != long {
+		t.Errorf("system message and the newest turns should survive, got %+v", got)
+	}
+	if len(msgs) != 7 {
+		t.Error("input slice must not be modified")
+	}
+
+	// The final message is never dropped, even if it alone is too large.
+	got, _ = truncateMessages(msgs, 1)
+	if len(got) != 2 || got[1].Content != "And now?" {
+		t.Errorf("expected only system and last message, got %+v", got)
+	}
+}
+
+// newContextWindowTestHandler routes small-model to a fake OpenAI with a
+// 300-token window and returns the handler and the last body it received.
+func newContextWindowTestHandler(t *testing.T, truncateTeams ...string) (*Handler, *[]byte) {
+	t.Helper()
+	var received []byte
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"small-model": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}, ContextWindow: 300},
+	}}
+	cfg := config.DefaultConfig()
+	cfg.Limits.TruncateContextTeams = truncateTeams
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	return h, &received
+}
+
+func postContextWindowRequest(h *Handler, messages []types.Message) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]any{"model": "small-model", "messages": messages})
+	return postAs(h.ChatCompletions, "/v1/chat/completions", &auth.AuthInfo{OrganizationID: "org-1", TeamID: "team-1", KeyID: "key-1"}, string(body), nil)
+}
+
+func TestChatCompletions_ContextWindow(t *testing.T) {
+	long := strings.Repeat("x", 800) // about 200 tokens
+	messages := []types.Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: "noted"},
+		{Role: "user", Content: long},
+	}
+
+	h, received := newContextWindowTestHandler(t)
+	w := postContextWindowRequest(h, messages)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "context_length_exceeded") {
+		t.Fatalf("expected 400 context_length_exceeded, got %d: %s", w.Code, w.Body.String())
+	}
+	if *received != nil {
+		t.Error("an oversized request must not reach the provider")
+	}
+
+	h, received = newContextWindowTestHandler(t, "team-1")
+	w = postContextWindowRequest(h, messages)
+	if w.Code != http.StatusOK {
+		t.Fatalf("opted-in team should be truncated, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get(headerContextTruncated) != "2" {
+		t.Errorf("%s = %q, want 2", headerContextTruncated, w.Header().Get(headerContextTruncated))
+	}
+	var sent struct {
+		Messages []types.Message `json:"messages"`
+	}
+	_ = json.Unmarshal(*received, &sent)
+	if len(sent.Messages) != 1 {
+		t.Errorf("provider should get only the last message, got %d", len(sent.Messages))
+	}
+}
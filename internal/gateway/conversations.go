@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const (
+	headerConversationID = "X-Aegis-Conversation-ID"
+
+	defaultConversationListLimit = 50
+	maxConversationListLimit     = 500
+	conversationRecordTimeout    = 5 * time.Second
+)
+
+// validConversationID bounds client-chosen IDs to something safe to store
+// and log.
+var validConversationID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// ConversationStore persists per-conversation metadata. It is satisfied by
+// *storage.ConversationStore.
+type ConversationStore interface {
+	RecordTurn(ctx context.Context, t storage.ConversationTurn) error
+	GetConversation(ctx context.Context, orgID, id string) (*storage.Conversation, error)
+	ListConversations(ctx context.Context, orgID string, limit int) ([]storage.Conversation, error)
+}
+
+// SetConversationStore enables conversation tracking and the conversations API.
+func (h *Handler) SetConversationStore(s ConversationStore) {
+	h.conversations = s
+}
+
+// conversationsEnabled reports whether tracking is configured and on.
+func (h *Handler) conversationsEnabled() bool {
+	return h.conversations != nil && h.cfg != nil && h.cfg().Conversations.Enabled
+}
+
+// checkConversationBudget rejects a turn of a conversation that has reached
+// one of its caps. It reports whether a response was written. Store errors
+// let the turn through, so an outage never blocks traffic.
+func (h *Handler) checkConversationBudget(w http.ResponseWriter, r *http.Request, reqID string, req *types.AegisRequest) bool {
+	if req.ConversationID == "" || !h.conversationsEnabled() {
+		return false
+	}
+	limits := h.cfg().Conversations
+	if limits.MaxTurns == 0 && limits.MaxTokens == 0 && limits.MaxCostUSD == 0 {
+		return false
+	}
+	conv, err := h.conversations.GetConversation(r.Context(), req.OrganizationID, req.ConversationID)
+	if errors.Is(err, storage.ErrConversationNotFound) {
+		return false
+	}
+	if err != nil {
+		slog.Warn("conversation budget check failed, allowing request",
+			"request_id", reqID,
+			"conversation_id", req.ConversationID,
+			"error", err,
+		)
+		return false
+	}
+
+	var reason string
+	switch {
+	case limits.MaxTurns > 0 && conv.Turns >= limits.MaxTurns:
+		reason = fmt.Sprintf("turn limit of %d reached", limits.MaxTurns)
+	case limits.MaxTokens > 0 && conv.TotalTokens() >= limits.MaxTokens:
+		reason = fmt.Sprintf("token limit of %d reached", limits.MaxTokens)
+	case limits.MaxCostUSD > 0 && conv.CostUSD >= limits.MaxCostUSD:
+		reason = fmt.Sprintf("cost limit of $%.2f reached", limits.MaxCostUSD)
+	default:
+		return false
+	}
+	slog.Warn("conversation budget exceeded",
+		"request_id", reqID,
+		"org_id", req.OrganizationID,
+		"conversation_id", req.ConversationID,
+		"reason", reason,
+	)
+	httputil.WriteBudgetExceededError(w, reqID, "Conversation budget exceeded: "+reason)
+	return true
+}
+
+// recordConversationTurn adds a completed request to its conversation
+// asynchronously; it never affects the client response.
+func (h *Handler) recordConversationTurn(req *types.AegisRequest, modelServed string, promptTokens, completionTokens int, costUSD float64) {
+	if req.ConversationID == "" || !h.conversationsEnabled() {
+		return
+	}
+	turn := storage.ConversationTurn{
+		ConversationID:   req.ConversationID,
+		OrganizationID:   req.OrganizationID,
+		TeamID:           req.TeamID,
+		APIKeyID:         req.APIKeyID,
+		Model:            modelServed,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		CostUSD:          costUSD,
+	}
+	reqID := req.RequestID
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), conversationRecordTimeout)
+		defer cancel()
+		if err := h.conversations.RecordTurn(ctx, turn); err != nil {
+			slog.Error("failed to record conversation turn",
+				"error", err,
+				"request_id", reqID,
+				"conversation_id", turn.ConversationID,
+			)
+		}
+	}()
+}
+
+// conversationList is the response of GET /aegis/v1/conversations.
+type conversationList struct {
+	Object string                 `json:"object"`
+	Data   []storage.Conversation `json:"data"`
+}
+
+// ListConversations handles GET /aegis/v1/conversations: the caller's
+// organization's most recently active conversations.
+func (h *Handler) ListConversations(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	authInfo, ok := h.conversationsCaller(w, r, reqID)
+	if !ok {
+		return
+	}
+	limit := defaultConversationListLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			httputil.WriteBadRequestError(w, reqID, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxConversationListLimit)
+	}
+	convs, err := h.conversations.ListConversations(r.Context(), authInfo.OrganizationID, limit)
+	if err != nil {
+		slog.Error("failed to list conversations", "request_id", reqID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to list conversations")
+		return
+	}
+	if convs == nil {
+		convs = []storage.Conversation{}
+	}
+	writeJSON(w, http.StatusOK, conversationList{Object: "list", Data: convs})
+}
+
+// GetConversation handles GET /aegis/v1/conversations/{id}.
+func (h *Handler) GetConversation(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+	authInfo, ok := h.conversationsCaller(w, r, reqID)
+	if !ok {
+		return
+	}
+	conv, err := h.conversations.GetConversation(r.Context(), authInfo.OrganizationID, chi.URLParam(r, "id"))
+	if errors.Is(err, storage.ErrConversationNotFound) {
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "conversation_not_found", "Conversation not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load conversation", "request_id", reqID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to load conversation")
+		return
+	}
+	writeJSON(w, http.StatusOK, conv)
+}
+
+// conversationsCaller checks that the API is enabled and returns the caller.
+func (h *Handler) conversationsCaller(w http.ResponseWriter, r *http.Request, reqID string) (*auth.AuthInfo, bool) {
+	if !h.conversationsEnabled() {
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "not_found", "Conversation tracking is not enabled")
+		return nil, false
+	}
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return nil, false
+	}
+	return authInfo, true
+}
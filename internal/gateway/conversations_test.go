@@ -0,0 +1,164 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+// memConversationStore aggregates turns in memory like the Postgres upsert.
+type memConversationStore struct {
+	mu    sync.Mutex
+	convs map[string]*storage.Conversation
+}
+
+func newMemConversationStore() *memConversationStore {
+	return &memConversationStore{convs: make(map[string]*storage.Conversation)}
+}
+
+func (s *memConversationStore) RecordTurn(_ context.Context, t storage.ConversationTurn) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := t.OrganizationID + "/" + t.ConversationID
+	c, ok := s.convs[key]
+	if !ok {
+		c = &storage.Conversation{ID: t.ConversationID, OrganizationID: t.OrganizationID, TeamID: t.TeamID, APIKeyID: t.APIKeyID}
+		s.convs[key] = c
+	}
+	c.Turns++
+	c.PromptTokens += int64(t.PromptTokens)
+	c.CompletionTokens += int64(t.CompletionTokens)
+	c.CostUSD += t.CostUSD
+	if !slices.Contains(c.Models, t.Model) {
+		c.Models = append(c.Models, t.Model)
+	}
+	return nil
+}
+
+func (s *memConversationStore) GetConversation(_ context.Context, orgID, id string) (*storage.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.convs[orgID+"/"+id]
+	if !ok {
+		return nil, storage.ErrConversationNotFound
+	}
+	cp := *c
+	return &cp, nil
+}
+
+func (s *memConversationStore) ListConversations(_ context.Context, orgID string, limit int) ([]storage.Conversation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []storage.Conversation
+	for _, c := range s.convs {
+		if c.OrganizationID == orgID && len(out) < limit {
+			out = append(out, *c)
+		}
+	}
+	return out, nil
+}
+
+func (s *memConversationStore) turns(orgID, id string) int {
+	c, err := s.GetConversation(context.Background(), orgID, id)
+	if err != nil {
+		return 0
+	}
+	return c.Turns
+}
+
+func postConversationTurn(h *Handler, info *auth.AuthInfo, conversationID string) *httptest.ResponseRecorder {
+	return postAs(h.ChatCompletions, "/v1/chat/completions", info, `{"model":"fast","messages":[{"role":"user","content":"Hi"}]}`,
+		http.Header{headerConversationID: {conversationID}})
+}
+
+func TestConversations_TracksTurnsAndEnforcesBudget(t *testing.T) {
+	h := newCompareTestHandler(t)
+	store := newMemConversationStore()
+	h.SetConversationStore(store)
+	h.cfg().Conversations.Enabled = true
+	h.cfg().Conversations.MaxTurns = 2
+	info := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "team-1", KeyID: "key-1"}
+
+	for i := 1; i <= 2; i++ {
+		if w := postConversationTurn(h, info, "conv-1"); w.Code != http.StatusOK {
+			t.Fatalf("turn %d: status = %d: %s", i, w.Code, w.Body.String())
+		}
+		// Turns are recorded asynchronously.
+		deadline := time.Now().Add(time.Second)
+		for store.turns("org-1", "conv-1") < i && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	conv, err := store.GetConversation(context.Background(), "org-1", "conv-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.Turns != 2 || conv.TotalTokens() != 10 || !slices.Equal(conv.Models, []string{"gpt-4o-mini"}) {
+		t.Errorf("unexpected conversation %+v", conv)
+	}
+
+	if w := postConversationTurn(h, info, "conv-1"); w.Code != http.StatusPaymentRequired {
+		t.Errorf("third turn: status = %d, want 402", w.Code)
+	}
+	if w := postConversationTurn(h, info, "conv-2"); w.Code != http.StatusOK {
+		t.Errorf("another conversation should not be limited, got %d", w.Code)
+	}
+	if w := postConversationTurn(h, info, "bad id!"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid conversation ID: status = %d, want 400", w.Code)
+	}
+}
+
+func TestConversations_API(t *testing.T) {
+	h := newCompareTestHandler(t)
+	store := newMemConversationStore()
+	h.SetConversationStore(store)
+	h.cfg().Conversations.Enabled = true
+	_ = store.RecordTurn(context.Background(), storage.ConversationTurn{ConversationID: "conv-1", OrganizationID: "org-1", Model: "gpt-4o"})
+
+	get := func(orgID, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/aegis/v1/conversations/"+id, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+		req = req.WithContext(auth.ContextWithAuth(ctx, &auth.AuthInfo{OrganizationID: orgID}))
+		w := httptest.NewRecorder()
+		h.GetConversation(w, req)
+		return w
+	}
+
+	w := get("org-1", "conv-1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var conv storage.Conversation
+	if err := json.Unmarshal(w.Body.Bytes(), &conv); err != nil || conv.Turns != 1 {
+		t.Errorf("unexpected body %s", w.Body.String())
+	}
+	if w := get("org-2", "conv-1"); w.Code != http.StatusNotFound {
+		t.Errorf("other org: status = %d, want 404", w.Code)
+	}
+
+	req := httptest.NewRequest("GET", "/aegis/v1/conversations?limit=10", nil)
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{OrganizationID: "org-1"}))
+	lw := httptest.NewRecorder()
+	h.ListConversations(lw, req)
+	var list conversationList
+	if err := json.Unmarshal(lw.Body.Bytes(), &list); err != nil || len(list.Data) != 1 {
+		t.Errorf("unexpected list %s", lw.Body.String())
+	}
+
+	h.cfg().Conversations.Enabled = false
+	if w := get("org-1", "conv-1"); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// warnDeprecatedModel warns the client, with a Warning header, that model is
+// deprecated and counts the request. It returns the deprecation notice for
+// the response body, or nil if the model is not deprecated. Streams only get
+// the header.
+func (h *Handler) warnDeprecatedModel(w http.ResponseWriter, authInfo *auth.AuthInfo, modelsCfg *config.ModelsConfig, model string) *types.ModelDeprecation {
+	if modelsCfg == nil {
+		return nil
+	}
+	d := modelsCfg.Models[model].Deprecated
+	if d == nil {
+		return nil
+	}
+	notice := &types.ModelDeprecation{
+		Model:       model,
+		SunsetDate:  d.Sunset.Format(time.DateOnly),
+		Replacement: d.Replacement,
+	}
+
+	text := fmt.Sprintf("Model %s is deprecated and will be retired on %s", model, notice.SunsetDate)
+	if d.Replacement != "" {
+		text += "; use " + d.Replacement + " instead"
+	}
+	// 299 is the "miscellaneous persistent warning" code of RFC 9111.
+	w.Header().Add("Warning", fmt.Sprintf("299 - %q", text))
+
+	slog.Debug("deprecated model requested", "org_id", authInfo.OrganizationID, "key_id", authInfo.KeyID, "model", model)
+	if h.metrics != nil {
+		h.metrics.RecordDeprecatedModel(authInfo.OrganizationID, model)
+	}
+	return notice
+}
@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestChatCompletions_DeprecatedModel(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	defer provider.Close()
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"old": {
+			Primary:    config.ProviderRoute{Provider: "openai", Model: "gpt-4"},
+			Deprecated: &config.ModelDeprecation{Sunset: time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC), Replacement: "new"},
+		},
+		"new": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	}}
+	cfg := config.DefaultConfig()
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	post := func(model string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]any{"model": model, "messages": []types.Message{{Role: "user", Content: "Hello"}}})
+		req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewReader(body))
+		req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{OrganizationID: "org-1", TeamID: "team-1", KeyID: "key-1"}))
+		w := httptest.NewRecorder()
+		h.ChatCompletions(w, req)
+		return w
+	}
+
+	w := post("old")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	warning := w.Header().Get("Warning")
+	if !strings.HasPrefix(warning, "299 - ") || !strings.Contains(warning, "2026-06-30") || !strings.Contains(warning, "use new") {
+		t.Errorf("Warning = %q", warning)
+	}
+	var resp types.AegisResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	want := types.ModelDeprecation{Model: "old", SunsetDate: "2026-06-30", Replacement: "new"}
+	if resp.Deprecation == nil || *resp.Deprecation != want {
+		t.Errorf("deprecation = %+v, want %+v", resp.Deprecation, want)
+	}
+
+	w = post("new")
+	if w.Header().Get("Warning") != "" || strings.Contains(w.Body.String(), `"deprecation"`) {
+		t.Errorf("current model should carry no deprecation: %v %s", w.Header(), w.Body.String())
+	}
+}
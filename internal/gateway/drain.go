@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+)
+
+// StreamDrainer tracks in-flight SSE streams so shutdown can let them finish
+// after the listener closes, then cut any that outlive the drain timeout with
+// a final error event instead of dropping the connection mid-chunk.
+type StreamDrainer struct {
+	mu     sync.Mutex
+	active int
+	idle   chan struct{} // closed when active drops to zero; replaced on 0→1
+
+	cut     chan struct{}
+	cutOnce sync.Once
+}
+
+// NewStreamDrainer creates a drainer with no active streams.
+func NewStreamDrainer() *StreamDrainer {
+	idle := make(chan struct{})
+	close(idle)
+	return &StreamDrainer{idle: idle, cut: make(chan struct{})}
+}
+
+// track registers a stream and returns the func that releases it.
+func (d *StreamDrainer) track() func() {
+	d.mu.Lock()
+	if d.active == 0 {
+		d.idle = make(chan struct{})
+	}
+	d.active++
+	d.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			d.active--
+			if d.active == 0 {
+				close(d.idle)
+			}
+			d.mu.Unlock()
+		})
+	}
+}
+
+// Active returns the number of streams in flight.
+func (d *StreamDrainer) Active() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.active
+}
+
+// Wait blocks until no streams are active or ctx is done. It reports whether
+// all streams finished.
+func (d *StreamDrainer) Wait(ctx context.Context) bool {
+	for {
+		d.mu.Lock()
+		idle := d.idle
+		d.mu.Unlock()
+		select {
+		case <-idle:
+			if d.Active() == 0 {
+				return true
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// Cut signals every active and future stream to send a final error event and
+// end. It is safe to call more than once.
+func (d *StreamDrainer) Cut() {
+	d.cutOnce.Do(func() { close(d.cut) })
+}
+
+// cutC returns the channel closed by Cut.
+func (d *StreamDrainer) cutC() <-chan struct{} {
+	return d.cut
+}
@@ -0,0 +1,105 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestStreamDrainer_WaitForStreams(t *testing.T) {
+	d := NewStreamDrainer()
+	if !d.Wait(context.Background()) {
+		t.Fatal("Wait with no streams should return immediately")
+	}
+
+	release1 := d.track()
+	release2 := d.track()
+	if d.Active() != 2 {
+		t.Fatalf("Active() = %d, want 2", d.Active())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if d.Wait(ctx) {
+		t.Fatal("Wait should time out while streams are active")
+	}
+
+	release1()
+	release1() // releasing twice must not double-count
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release2()
+	}()
+	if !d.Wait(context.Background()) {
+		t.Fatal("Wait should return once all streams finish")
+	}
+	if d.Active() != 0 {
+		t.Errorf("Active() = %d, want 0", d.Active())
+	}
+}
+
+func TestHandleStream_CutOnShutdown(t *testing.T) {
+	// The provider sends one chunk and then hangs, like a long generation.
+	pr, pw := io.Pipe()
+	defer func() { _ = pw.Close() }()
+	go func() {
+		_, _ = io.WriteString(pw, `data: {"model":"gpt-4","choices":[{"delta":{"content":"Hi"}}]}`+"\n\n")
+	}()
+
+	adapter := &mockStreamAdapter{
+		name:     "openai",
+		response: &http.Response{StatusCode: http.StatusOK, Body: pr, Header: make(http.Header)},
+	}
+	drainer := NewStreamDrainer()
+	h := &Handler{}
+	h.SetStreamDrainer(drainer)
+	sh := NewStreamingHandler(h, StreamingConfig{
+		PerChunkTimeout: 10 * time.Second,
+		TotalTimeout:    30 * time.Second,
+		BufferSize:      64 * 1024,
+		MaxBufferSize:   1024 * 1024,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	providerReq, _ := http.NewRequest(http.MethodPost, "http://mock-provider.com", nil)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		sh.HandleStream(w, req, "req-drain", providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org-1"}, &types.AegisRequest{Model: "gpt-4", Stream: true})
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for drainer.Active() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if drainer.Active() != 1 {
+		t.Fatalf("expected 1 active stream, got %d", drainer.Active())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if drainer.Wait(ctx) {
+		t.Fatal("hung stream should not drain on its own")
+	}
+	drainer.Cut()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream did not end after Cut")
+	}
+	if drainer.Active() != 0 {
+		t.Errorf("Active() = %d after cut, want 0", drainer.Active())
+	}
+	if body := w.Body.String(); !strings.Contains(body, "event: error\n") || !strings.Contains(body, `"code":"gateway_shutdown"`) {
+		t.Errorf("expected final shutdown error event, got %q", body)
+	}
+}
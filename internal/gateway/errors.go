@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/validation"
+)
+
+// routeError classifies a ResolveRoute failure: a model that is not
+// configured, a classification no route may carry, features no route
+// supports, or no healthy provider, in which case the next circuit probe is
+// the reset time.
+func routeError(model string, err error) *httputil.HTTPError {
+	details := map[string]any{"model": model}
+	var unsupported *router.CapabilityError
+	switch {
+	case errors.Is(err, router.ErrUnknownModel):
+		return httputil.NewCodedError(httputil.CodeModelNotFound, fmt.Sprintf("Model %q does not exist", model), details)
+	case errors.Is(err, router.ErrClassificationNotPermitted):
+		return httputil.NewCodedError(httputil.CodeClassificationExceeded,
+			fmt.Sprintf("No route for model %q may handle this request's data classification", model), details)
+	case errors.As(err, &unsupported):
+		details["missing"] = unsupported.Missing
+		return httputil.NewCodedError(httputil.CodeUnsupportedFeature,
+			fmt.Sprintf("No route for model %q supports %s", model, strings.Join(unsupported.Missing, ", ")), details)
+	default:
+		e := httputil.NewCodedError(httputil.CodeNoProviderAvailable, "No provider available: "+err.Error(), details)
+		var noProvider *router.NoProviderError
+		if errors.As(err, &noProvider) {
+			e.ResetAt = noProvider.ProbeAt
+		}
+		return e
+	}
+}
+
+// providerFailure classifies a provider call that failed without an answer
+// the client should see, telling timeouts apart from other failures.
+func providerFailure(provider string, err error) *httputil.HTTPError {
+	details := map[string]any{"provider": provider}
+	if isTimeout(err) {
+		return httputil.NewCodedError(httputil.CodeProviderTimeout, "Provider did not respond in time", details)
+	}
+	return httputil.NewCodedError(httputil.CodeProviderUnavailable, "Provider request failed", details)
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// validationError reports the fields that failed validation in details.
+func validationError(err error) *httputil.HTTPError {
+	var details map[string]any
+	var verrs validation.ValidationErrors
+	if errors.As(err, &verrs) {
+		details = map[string]any{"fields": verrs.Fields()}
+	}
+	return httputil.NewCodedError(httputil.CodeInvalidRequest, err.Error(), details)
+}
+
+// decodeError reports a request body that could not be decoded, naming the
+// unknown fields strict mode rejected.
+func decodeError(err error) *httputil.HTTPError {
+	var unknown *validation.UnknownFieldsError
+	if errors.As(err, &unknown) {
+		return httputil.NewCodedError(httputil.CodeInvalidRequest, err.Error(), map[string]any{"fields": unknown.Fields})
+	}
+	return httputil.NewCodedError(httputil.CodeInvalidRequest, "Invalid JSON: "+err.Error(), nil)
+}
+
+// modelNotAllowed is returned when the API key's allowed_models excludes
+// model.
+func modelNotAllowed(model string) *httputil.HTTPError {
+	return httputil.NewCodedError(httputil.CodeModelNotAllowed,
+		fmt.Sprintf("API key is not allowed to use model %q", model), map[string]any{"model": model})
+}
+
+// maxBlockFindings caps the findings listed in a block's details.
+const maxBlockFindings = 20
+
+// filterBlockError reports a blocking filter result: a policy denial, a
+// content filter block, or a fail-closed filter that could not run. With
+// detailed set a content block also lists its findings, by type and
+// position only, so the caller can find what tripped the filter.
+func filterBlockError(r filter.Result, detailed bool) *httputil.HTTPError {
+	details := map[string]any{"filter": r.FilterName}
+	switch {
+	case r.Err != nil:
+		return httputil.NewCodedError(httputil.CodeFilterUnavailable, r.Message, details)
+	case r.FilterName == "policy":
+		return httputil.NewCodedError(httputil.CodePolicyDenied, r.Message, details)
+	default:
+		if detailed {
+			addBlockFindings(details, r)
+		}
+		return httputil.NewCodedError(httputil.CodeContentBlocked, r.Message, details)
+	}
+}
+
+// addBlockFindings adds the detection count, a count per finding type, and
+// up to maxBlockFindings positions to details.
+func addBlockFindings(details map[string]any, r filter.Result) {
+	details["detections"] = r.Detections
+	if len(r.Findings) == 0 {
+		return
+	}
+	types := make(map[string]int)
+	for _, f := range r.Findings {
+		types[f.Type]++
+	}
+	details["types"] = types
+	details["findings"] = r.Findings[:min(len(r.Findings), maxBlockFindings)]
+	if len(r.Findings) > maxBlockFindings {
+		details["findings_truncated"] = true
+	}
+}
@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/router"
+)
+
+// Where an estimate's completion bound comes from.
+const (
+	completionBoundRequest = "request"        // the request's max_tokens
+	completionBoundModel   = "model"          // the routed model's max output tokens
+	completionBoundContext = "context_window" // what the context window leaves
+)
+
+// estimateResponse is the response of POST /aegis/v1/estimate. Cost fields
+// are omitted when the served model has no pricing, and MaxCostUSD also when
+// nothing bounds the completion.
+type estimateResponse struct {
+	Object        string `json:"object"`
+	Model         string `json:"model"`
+	Provider      string `json:"provider"`
+	ProviderModel string `json:"provider_model"`
+	PromptTokens  int    `json:"prompt_tokens"`
+
+	// MaxCompletionTokens is the most the completion can use, and
+	// CompletionBound what sets it.
+	MaxCompletionTokens int    `json:"max_completion_tokens,omitempty"`
+	CompletionBound     string `json:"completion_bound,omitempty"`
+
+	EstimatedPromptCostUSD *float64 `json:"estimated_prompt_cost_usd,omitempty"`
+	MaxCostUSD             *float64 `json:"max_cost_usd,omitempty"`
+
+	Limits estimateLimits `json:"limits"`
+}
+
+// estimateLimits are the limits the request would be held to. Remaining
+// requests and tokens in the current window are in the X-RateLimit headers.
+type estimateLimits struct {
+	RequestsPerMinute int   `json:"requests_per_minute"`
+	TokensPerMinute   int   `json:"tokens_per_minute,omitempty"`
+	ContextWindow     int   `json:"context_window,omitempty"`
+	FitsContextWindow *bool `json:"fits_context_window,omitempty"`
+
+	// Daily budget of the key's team, when the key has one.
+	DailySpendLimitUSD *float64   `json:"daily_spend_limit_usd,omitempty"`
+	DailySpendUSD      *float64   `json:"daily_spend_usd,omitempty"`
+	BudgetResetsAt     *time.Time `json:"budget_resets_at,omitempty"`
+	// WithinBudget reports whether today's spend plus MaxCostUSD, or the
+	// prompt cost when the completion is unbounded, stays under the limit.
+	WithinBudget *bool `json:"within_budget,omitempty"`
+}
+
+// SetBudget attaches the daily spend lookup the estimate endpoint reports.
+func (h *Handler) SetBudget(budget BudgetChecker) {
+	h.budget = budget
+}
+
+// Estimate handles POST /aegis/v1/estimate: it routes a chat completion
+// request as the gateway would right now and prices it without contacting
+// the provider, so a client can check a request against its budget before
+// sending it. The prompt is sized with the gateway's local estimate.
+func (h *Handler) Estimate(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+	req, ok := h.readPreviewRequest(w, r, reqID, authInfo)
+	if !ok {
+		return
+	}
+	if id := r.Header.Get(headerConversationID); id != "" {
+		if !validConversationID.MatchString(id) {
+			httputil.WriteBadRequestError(w, reqID, headerConversationID+" must be 1-128 letters, digits, '.', '_', ':' or '-'")
+			return
+		}
+		req.ConversationID = id
+	}
+	if key := r.Header.Get(headerAffinityKey); key != "" {
+		if !validConversationID.MatchString(key) {
+			httputil.WriteBadRequestError(w, reqID, headerAffinityKey+" must be 1-128 letters, digits, '.', '_', ':' or '-'")
+			return
+		}
+		req.AffinityKey = key
+	}
+
+	modelsCfg := h.modelsCfg()
+	adapter, providerModel, err := router.ResolvePreferredRoute(modelsCfg, h.registry, h.healthTracker, req.Model, string(req.Classification),
+		router.RequirementsOf(&req), h.stickyProvider(r.Context(), &req))
+	if err != nil {
+		httputil.WriteHTTPError(w, reqID, routeError(req.Model, err))
+		return
+	}
+	provider := adapter.Name()
+
+	resp := estimateResponse{
+		Object:        "aegis.estimate",
+		Model:         req.Model,
+		Provider:      provider,
+		ProviderModel: providerModel,
+		PromptTokens:  estimateRequestTokens(&req),
+		Limits: estimateLimits{
+			RequestsPerMinute: ratelimit.DefaultRPM,
+		},
+	}
+	if authInfo.RPMLimit != nil {
+		resp.Limits.RequestsPerMinute = *authInfo.RPMLimit
+	}
+	if authInfo.TPMLimit != nil {
+		resp.Limits.TokensPerMinute = *authInfo.TPMLimit
+	}
+
+	window := modelsCfg.Models[req.Model].ContextWindow
+	switch maxOutput := modelsCfg.Capabilities[provider][providerModel].MaxOutputTokens; {
+	case req.CompletionTokenLimit() != nil:
+		resp.MaxCompletionTokens, resp.CompletionBound = *req.CompletionTokenLimit(), completionBoundRequest
+	case maxOutput > 0:
+		resp.MaxCompletionTokens, resp.CompletionBound = maxOutput, completionBoundModel
+	case window > resp.PromptTokens:
+		resp.MaxCompletionTokens, resp.CompletionBound = window-resp.PromptTokens, completionBoundContext
+	}
+	if window > 0 {
+		// Only a completion limit the request sets is reserved, as in the
+		// context window check.
+		reserve := 0
+		if resp.CompletionBound == completionBoundRequest {
+			reserve = resp.MaxCompletionTokens
+		}
+		fits := resp.PromptTokens+reserve <= window
+		resp.Limits.ContextWindow, resp.Limits.FitsContextWindow = window, &fits
+	}
+
+	if h.costCalc != nil {
+		if c, found := h.costCalc.Calculate(provider, providerModel, resp.PromptTokens, 0); found {
+			resp.EstimatedPromptCostUSD = &c
+			if resp.CompletionBound != "" {
+				if maxCost, found := h.costCalc.Calculate(provider, providerModel, resp.PromptTokens, resp.MaxCompletionTokens); found {
+					resp.MaxCostUSD = &maxCost
+				}
+			}
+		}
+	}
+
+	if authInfo.DailySpendLimitCents != nil && h.budget != nil {
+		res, err := h.budget.CheckDailySpend(r.Context(), authInfo.OrganizationID, authInfo.TeamID, int64(*authInfo.DailySpendLimitCents))
+		if err != nil {
+			slog.Warn("budget lookup failed for estimate", "request_id", reqID, "org_id", authInfo.OrganizationID, "error", err)
+		} else {
+			limit, spent := float64(res.LimitCents)/100, float64(res.SpentCents)/100
+			resp.Limits.DailySpendLimitUSD, resp.Limits.DailySpendUSD, resp.Limits.BudgetResetsAt = &limit, &spent, &res.ResetAt
+			cost := resp.MaxCostUSD
+			if cost == nil {
+				cost = resp.EstimatedPromptCostUSD
+			}
+			if cost != nil {
+				within := spent+*cost <= limit
+				resp.Limits.WithinBudget = &within
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/cost"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+func TestEstimate(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("estimate contacted the provider: %s %s", r.Method, r.URL.Path)
+	}))
+	defer provider.Close()
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	registry.Register("anthropic", adapters.NewAnthropicAdapter(config.ProviderConfig{BaseURL: provider.URL, APIKey: "sk-ant"}, provider.Client()))
+	models := &config.ModelsConfig{
+		Models: map[string]config.ModelMapping{
+			"fast":  {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o-mini"}, ContextWindow: 4000},
+			"smart": {Primary: config.ProviderRoute{Provider: "anthropic", Model: "claude-sonnet"}, ContextWindow: 2000},
+		},
+		Pricing: map[string]map[string]config.PriceEntry{
+			"anthropic": {"claude-sonnet": {Input: 0.003, Output: 0.015}},
+		},
+		Capabilities: map[string]map[string]config.ModelCapabilities{
+			"anthropic": {"claude-sonnet": {MaxOutputTokens: 500}},
+		},
+	}
+	modelsFn := func() *config.ModelsConfig { return models }
+	cfg := config.DefaultConfig()
+	h := NewHandler(registry, nil, modelsFn, func() *config.Config { return cfg },
+		nil, nil, nil, cost.NewCalculator(modelsFn), nil, nil, nil, nil, nil)
+	h.SetBudget(fakeBudget{ratelimit.BudgetResult{Allowed: true, SpentCents: 100, LimitCents: 1000}})
+
+	post := func(info *auth.AuthInfo, body string) (*httptest.ResponseRecorder, estimateResponse) {
+		req := httptest.NewRequest("POST", "/aegis/v1/estimate", bytes.NewBufferString(body))
+		req = req.WithContext(auth.ContextWithAuth(req.Context(), info))
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request-ID", "req-1")
+		h.Estimate(w, req)
+		var resp estimateResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	rpm, spendCents := 10, 1000
+	info := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "team-1", RPMLimit: &rpm, DailySpendLimitCents: &spendCents}
+	smart := `{"model":"smart","messages":[{"role":"user","content":"Summarize the release notes."}]}`
+
+	w, resp := post(info, smart)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if resp.Provider != "anthropic" || resp.ProviderModel != "claude-sonnet" || resp.PromptTokens == 0 {
+		t.Errorf("unexpected route %+v", resp)
+	}
+	if resp.MaxCompletionTokens != 500 || resp.CompletionBound != completionBoundModel {
+		t.Errorf("completion should be bounded by the model's max output, got %d (%s)", resp.MaxCompletionTokens, resp.CompletionBound)
+	}
+	wantMax := (float64(resp.PromptTokens)*0.003 + 500*0.015) / 1000
+	if resp.MaxCostUSD == nil || math.Abs(*resp.MaxCostUSD-wantMax) > 1e-9 {
+		t.Errorf("max cost = %v, want %v", resp.MaxCostUSD, wantMax)
+	}
+	l := resp.Limits
+	if l.RequestsPerMinute != 10 || l.TokensPerMinute != 0 || l.ContextWindow != 2000 || l.FitsContextWindow == nil || !*l.FitsContextWindow {
+		t.Errorf("unexpected limits %+v", l)
+	}
+	if l.DailySpendLimitUSD == nil || *l.DailySpendLimitUSD != 10 || *l.DailySpendUSD != 1 || l.WithinBudget == nil || !*l.WithinBudget {
+		t.Errorf("unexpected budget %s", w.Body.String())
+	}
+
+	h.SetBudget(fakeBudget{ratelimit.BudgetResult{SpentCents: 1000, LimitCents: 1000}})
+	if _, resp = post(info, smart); resp.Limits.WithinBudget == nil || *resp.Limits.WithinBudget {
+		t.Error("a spent budget should not fit the request")
+	}
+
+	_, resp = post(info, `{"model":"smart","max_tokens":100,"messages":[{"role":"user","content":"Hi"}]}`)
+	if resp.MaxCompletionTokens != 100 || resp.CompletionBound != completionBoundRequest {
+		t.Errorf("completion should be bounded by max_tokens, got %d (%s)", resp.MaxCompletionTokens, resp.CompletionBound)
+	}
+
+	_, resp = post(info, `{"model":"fast","messages":[{"role":"user","content":"Hi"}]}`)
+	if resp.CompletionBound != completionBoundContext || resp.MaxCompletionTokens != 4000-resp.PromptTokens {
+		t.Errorf("completion should be bounded by the context window, got %d (%s)", resp.MaxCompletionTokens, resp.CompletionBound)
+	}
+	if resp.EstimatedPromptCostUSD != nil || resp.MaxCostUSD != nil || resp.Limits.WithinBudget != nil {
+		t.Errorf("an unpriced model has no costs, got %s", w.Body.String())
+	}
+
+	if w, _ := post(info, `{"model":"gpt-9","messages":[{"role":"user","content":"Hi"}]}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown model: status = %d, want 404", w.Code)
+	}
+}
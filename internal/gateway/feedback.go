@@ -0,0 +1,114 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+const (
+	maxFeedbackBodyBytes = 16 << 10
+	maxFeedbackComment   = 2000
+	maxFeedbackRequestID = 100
+)
+
+// FeedbackStore records false-positive reports on filter blocks. It is
+// satisfied by *storage.FeedbackStore.
+type FeedbackStore interface {
+	Submit(ctx context.Context, sub storage.FeedbackSubmission) (*storage.FilterFeedback, error)
+}
+
+// SetFeedbackStore enables the filter block feedback API.
+func (h *Handler) SetFeedbackStore(s FeedbackStore) {
+	h.feedback = s
+}
+
+// feedbackRequest is the body of POST /aegis/v1/feedback.
+type feedbackRequest struct {
+	RequestID string `json:"request_id"`
+	Comment   string `json:"comment"`
+}
+
+// SubmitFeedback handles POST /aegis/v1/feedback: the caller reports that
+// the filter block of one of its organization's requests, named by the
+// aegis_request_id of the block's error, was a false positive. The report is
+// stored with the block's detection metadata for security review; it does
+// not lift the block.
+func (h *Handler) SubmitFeedback(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+	if h.feedback == nil {
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "not_found", "Filter feedback is not enabled")
+		return
+	}
+
+	var req feedbackRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxFeedbackBodyBytes)).Decode(&req); err != nil {
+		httputil.WriteBadRequestError(w, reqID, "Invalid JSON: "+err.Error())
+		return
+	}
+	switch {
+	case req.RequestID == "":
+		httputil.WriteBadRequestError(w, reqID, "request_id is required")
+		return
+	case len(req.RequestID) > maxFeedbackRequestID:
+		httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("request_id must be at most %d characters", maxFeedbackRequestID))
+		return
+	case utf8.RuneCountInString(req.Comment) > maxFeedbackComment:
+		httputil.WriteBadRequestError(w, reqID, fmt.Sprintf("comment must be at most %d characters", maxFeedbackComment))
+		return
+	}
+
+	fb, err := h.feedback.Submit(r.Context(), storage.FeedbackSubmission{
+		ID:             newFeedbackID(),
+		RequestID:      req.RequestID,
+		OrganizationID: authInfo.OrganizationID,
+		TeamID:         authInfo.TeamID,
+		APIKeyID:       authInfo.KeyID,
+		Comment:        req.Comment,
+	})
+	switch {
+	case errors.Is(err, storage.ErrBlockNotFound):
+		httputil.WriteError(w, reqID, http.StatusNotFound, "invalid_request_error", "block_not_found",
+			"No filter block was recorded for this request in your organization")
+		return
+	case errors.Is(err, storage.ErrFeedbackExists):
+		httputil.WriteError(w, reqID, http.StatusConflict, "invalid_request_error", "feedback_exists",
+			"Feedback was already submitted for this request")
+		return
+	case err != nil:
+		slog.Error("failed to store filter feedback", "request_id", reqID, "blocked_request_id", req.RequestID, "error", err)
+		httputil.WriteInternalError(w, reqID, "Failed to store feedback")
+		return
+	}
+
+	slog.Info("filter block reported as false positive",
+		"request_id", reqID,
+		"blocked_request_id", fb.RequestID,
+		"filter", fb.Filter,
+		"org_id", authInfo.OrganizationID,
+		"feedback_id", fb.ID,
+	)
+	writeJSON(w, http.StatusCreated, fb)
+}
+
+func newFeedbackID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return "fb_" + hex.EncodeToString(b)
+}
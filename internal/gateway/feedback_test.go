@@ -0,0 +1,85 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+// memFeedbackStore knows one blocked request per organization.
+type memFeedbackStore struct {
+	blocks map[string]string // org/request ID -> filter
+	seen   map[string]bool
+	last   storage.FeedbackSubmission
+}
+
+func (s *memFeedbackStore) Submit(_ context.Context, sub storage.FeedbackSubmission) (*storage.FilterFeedback, error) {
+	key := sub.OrganizationID + "/" + sub.RequestID
+	filter, ok := s.blocks[key]
+	if !ok {
+		return nil, storage.ErrBlockNotFound
+	}
+	if s.seen[key] {
+		return nil, storage.ErrFeedbackExists
+	}
+	s.seen[key] = true
+	s.last = sub
+	return &storage.FilterFeedback{
+		ID: sub.ID, RequestID: sub.RequestID, OrganizationID: sub.OrganizationID,
+		Filter: filter, Comment: sub.Comment, Status: storage.FeedbackOpen,
+	}, nil
+}
+
+func TestSubmitFeedback(t *testing.T) {
+	h := &Handler{}
+	info := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "team-1", KeyID: "key-1"}
+
+	if w := postAs(h.SubmitFeedback, "/aegis/v1/feedback", info, `{"request_id":"req-blocked"}`, nil); w.Code != http.StatusNotFound {
+		t.Errorf("without a store: status = %d, want 404", w.Code)
+	}
+
+	store := &memFeedbackStore{blocks: map[string]string{"org-1/req-blocked": "pii"}, seen: map[string]bool{}}
+	h.SetFeedbackStore(store)
+
+	w := postAs(h.SubmitFeedback, "/aegis/v1/feedback", info, `{"request_id":"req-blocked","comment":"that was a product SKU"}`, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var fb storage.FilterFeedback
+	if err := json.Unmarshal(w.Body.Bytes(), &fb); err != nil {
+		t.Fatal(err)
+	}
+	if fb.Filter != "pii" || fb.Status != storage.FeedbackOpen || !strings.HasPrefix(fb.ID, "fb_") {
+		t.Errorf("unexpected feedback %+v", fb)
+	}
+	if store.last.TeamID != "team-1" || store.last.APIKeyID != "key-1" {
+		t.Errorf("submission should carry the caller's identity, got %+v", store.last)
+	}
+
+	tests := []struct {
+		name string
+		info *auth.AuthInfo
+		body string
+		want int
+	}{
+		{"duplicate", info, `{"request_id":"req-blocked"}`, http.StatusConflict},
+		{"other organization", &auth.AuthInfo{OrganizationID: "org-2"}, `{"request_id":"req-blocked"}`, http.StatusNotFound},
+		{"not blocked", info, `{"request_id":"req-ok"}`, http.StatusNotFound},
+		{"missing request_id", info, `{"comment":"x"}`, http.StatusBadRequest},
+		{"long comment", info, `{"request_id":"req-x","comment":"` + strings.Repeat("a", maxFeedbackComment+1) + `"}`, http.StatusBadRequest},
+		{"bad json", info, `{`, http.StatusBadRequest},
+		{"unauthenticated", nil, `{"request_id":"req-blocked"}`, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postAs(h.SubmitFeedback, "/aegis/v1/feedback", tt.info, tt.body, nil); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
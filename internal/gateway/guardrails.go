@@ -0,0 +1,254 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/guardrails"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// SetGuardrails attaches per-org response guardrails.
+func (h *Handler) SetGuardrails(g *guardrails.Guard) {
+	h.guardrails = g
+}
+
+// responseTransform returns what rewrites req's response before the client
+// sees it: response hooks, then guardrails. It returns nil when nothing
+// applies to req's org, so streams are only held back when they must be.
+func (h *Handler) responseTransform(ctx context.Context, req *types.AegisRequest) func(*types.AegisResponse) error {
+	var rw *guardrails.Rewriter
+	if h.guardrails != nil {
+		rw = h.guardrails.For(req.OrganizationID, req.Classification)
+	}
+	runHooks := h.hooks != nil && h.hooks.HasResponseHooks(req.OrganizationID)
+	if rw == nil && !runHooks {
+		return nil
+	}
+	return func(resp *types.AegisResponse) error {
+		if runHooks {
+			if err := h.hooks.RunResponse(ctx, req, resp); err != nil {
+				return err
+			}
+		}
+		if rw != nil {
+			rw.ApplyResponse(resp)
+		}
+		return nil
+	}
+}
+
+// guardedStreamWriter holds back a stream's SSE events so response hooks
+// and guardrails can rewrite the complete assistant text before any of it
+// reaches the client. Comments such as keep-alive pings still pass straight
+// through. Held events are released, rewritten, at [DONE] or by Close when
+// the stream ends early.
+type guardedStreamWriter struct {
+	http.ResponseWriter
+	reqID     string
+	transform func(*types.AegisResponse) error
+	partial   []byte
+	events    []string
+	released  bool
+}
+
+func newGuardedStreamWriter(w http.ResponseWriter, reqID string, transform func(*types.AegisResponse) error) *guardedStreamWriter {
+	return &guardedStreamWriter{ResponseWriter: w, reqID: reqID, transform: transform}
+}
+
+func (g *guardedStreamWriter) Write(p []byte) (int, error) {
+	if g.released {
+		return g.ResponseWriter.Write(p)
+	}
+	g.partial = append(g.partial, p...)
+	for {
+		i := bytes.Index(g.partial, []byte("\n\n"))
+		if i < 0 {
+			return len(p), nil
+		}
+		event := strings.TrimLeft(string(g.partial[:i]), "\n")
+		g.partial = g.partial[i+2:]
+		switch {
+		case event == "":
+		case strings.HasPrefix(event, ":"):
+			_, _ = io.WriteString(g.ResponseWriter, event+"\n\n")
+			g.Flush()
+		default:
+			g.events = append(g.events, event)
+			if event == "data: [DONE]" {
+				g.release()
+			}
+		}
+	}
+}
+
+// Flush pushes headers and pings; held events wait for release.
+func (g *guardedStreamWriter) Flush() {
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close releases whatever is still held, for streams cut short by a
+// timeout, shutdown, or provider error.
+func (g *guardedStreamWriter) Close() {
+	if rest := strings.Trim(string(g.partial), "\n"); rest != "" && !g.released {
+		g.events = append(g.events, rest)
+	}
+	g.partial = nil
+	g.release()
+}
+
+// release rewrites the held content and writes the held events in order.
+// Each choice's rewritten text goes in its first content delta; later
+// content deltas are emptied and dropped when nothing else is left in them.
+// When the rewrite fails none of the held content is sent; the stream ends
+// with an error event instead.
+func (g *guardedStreamWriter) release() {
+	if g.released {
+		return
+	}
+	g.released = true
+
+	chunks := make([]map[string]any, len(g.events))
+	text := map[float64]*strings.Builder{}
+	first := map[float64]int{}
+	var order []float64
+	for i, event := range g.events {
+		chunks[i] = parseChunkEvent(event)
+		for _, c := range streamChoices(chunks[i]) {
+			content, ok := c.delta["content"].(string)
+			if !ok {
+				continue
+			}
+			if _, seen := first[c.index]; !seen {
+				first[c.index] = i
+				text[c.index] = &strings.Builder{}
+				order = append(order, c.index)
+			}
+			text[c.index].WriteString(content)
+		}
+	}
+
+	resp := &types.AegisResponse{RequestID: g.reqID}
+	for _, index := range order {
+		resp.Choices = append(resp.Choices, types.Choice{
+			Index:   int(index),
+			Message: types.Message{Role: "assistant", Content: text[index].String()},
+		})
+	}
+	if err := g.transform(resp); err != nil {
+		slog.Error("response transformation failed", "request_id", g.reqID, "error", err)
+		g.events = nil
+		writeStreamError(g.ResponseWriter, g, g.reqID, httputil.CodeHookFailed, "Response transformation failed")
+		return
+	}
+	rewritten := make(map[float64]string, len(resp.Choices))
+	for _, c := range resp.Choices {
+		rewritten[float64(c.Index)] = c.Message.Content
+	}
+
+	for i, event := range g.events {
+		if chunk := chunks[i]; chunk != nil {
+			changed := false
+			for _, c := range streamChoices(chunk) {
+				if _, ok := c.delta["content"].(string); !ok {
+					continue
+				}
+				changed = true
+				if first[c.index] == i {
+					c.delta["content"] = rewritten[c.index]
+				} else {
+					delete(c.delta, "content")
+				}
+			}
+			if changed {
+				if emptyChunk(chunk) {
+					continue
+				}
+				event = replaceChunkData(event, chunk)
+			}
+		}
+		if _, err := io.WriteString(g.ResponseWriter, event+"\n\n"); err != nil {
+			break
+		}
+	}
+	g.events = nil
+	g.Flush()
+}
+
+// streamChoice is one choice of an OpenAI-format chunk.
+type streamChoice struct {
+	index  float64
+	delta  map[string]any
+	choice map[string]any
+}
+
+// parseChunkEvent decodes the data line of an SSE event, or returns nil for
+// events that are not JSON objects.
+func parseChunkEvent(event string) map[string]any {
+	for _, line := range strings.Split(event, "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			var chunk map[string]any
+			if json.Unmarshal([]byte(data), &chunk) != nil {
+				return nil
+			}
+			return chunk
+		}
+	}
+	return nil
+}
+
+func streamChoices(chunk map[string]any) []streamChoice {
+	raw, _ := chunk["choices"].([]any)
+	var out []streamChoice
+	for _, item := range raw {
+		choice, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		delta, ok := choice["delta"].(map[string]any)
+		if !ok {
+			continue
+		}
+		index, _ := choice["index"].(float64)
+		out = append(out, streamChoice{index: index, delta: delta, choice: choice})
+	}
+	return out
+}
+
+// emptyChunk reports whether a chunk carries nothing once its content is
+// gone: no other delta fields, no finish reason, and no usage.
+func emptyChunk(chunk map[string]any) bool {
+	if chunk["usage"] != nil {
+		return false
+	}
+	for _, c := range streamChoices(chunk) {
+		if len(c.delta) > 0 || c.choice["finish_reason"] != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceChunkData re-encodes chunk into the event's data line.
+func replaceChunkData(event string, chunk map[string]any) string {
+	b, err := json.Marshal(chunk)
+	if err != nil {
+		return event
+	}
+	lines := strings.Split(event, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "data: ") {
+			lines[i] = "data: " + string(b)
+			break
+		}
+	}
+	return strings.Join(lines, "\n")
+}
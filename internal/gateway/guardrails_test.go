@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/archive"
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/guardrails"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// guardrailTransform is the response transform a handler with only g
+// attached uses for an org-1 request of the given classification.
+func guardrailTransform(g *guardrails.Guard, class types.Classification) func(*types.AegisResponse) error {
+	h := &Handler{guardrails: g}
+	return h.responseTransform(context.Background(), &types.AegisRequest{OrganizationID: "org-1", Classification: class})
+}
+
+func TestGuardedStreamWriter_RewritesBufferedContent(t *testing.T) {
+	g := guardrails.New(func() config.GuardrailsConfig {
+		return config.GuardrailsConfig{Response: []config.GuardrailRule{{ClassificationBanner: true, StripExternalLinks: true}}}
+	})
+	rec := httptest.NewRecorder()
+	w := newGuardedStreamWriter(rec, "req-1", guardrailTransform(g, types.ClassRestricted))
+
+	// The link is split across chunks, so only buffering can strip it.
+	for _, event := range []string{
+		`data: {"id":"c1","created":1700000000,"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+		`: ping`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"See [x](https://ev"},"finish_reason":null}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"il.example)."},"finish_reason":null}]}`,
+		`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+	} {
+		_, _ = io.WriteString(w, event+"\n\n")
+	}
+	if got := rec.Body.String(); got != ": ping\n\n" {
+		t.Fatalf("only pings may pass before release, got %q", got)
+	}
+	_, _ = io.WriteString(w, "data: [DONE]\n\n")
+
+	want := `data: {"choices":[{"delta":{"content":"See x.\n\n[Classification: RESTRICTED]","role":"assistant"},"finish_reason":null,"index":0}],"created":1700000000,"id":"c1"}` + "\n\n" +
+		`data: {"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+	if got := strings.TrimPrefix(rec.Body.String(), ": ping\n\n"); got != want {
+		t.Errorf("released stream:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGuardedStreamWriter_CloseReleasesCutStreams(t *testing.T) {
+	g := guardrails.New(func() config.GuardrailsConfig {
+		return config.GuardrailsConfig{Response: []config.GuardrailRule{{ClassificationBanner: true}}}
+	})
+	rec := httptest.NewRecorder()
+	w := newGuardedStreamWriter(rec, "req-1", guardrailTransform(g, types.ClassInternal))
+	_, _ = io.WriteString(w, `data: {"choices":[{"index":0,"delta":{"content":"partial"}}]}`+"\n\n")
+	_, _ = io.WriteString(w, `data: {"error": "chunk timeout"}`+"\n\n")
+	w.Close()
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `partial\n\n[Classification: INTERNAL]`) || !strings.HasSuffix(body, `data: {"error": "chunk timeout"}`+"\n\n") {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+// archiveStore collects archived objects; done is closed after the first.
+type archiveStore struct {
+	body []byte
+	done chan struct{}
+}
+
+func (s *archiveStore) Put(_ context.Context, _ string, body []byte) error {
+	s.body = body
+	close(s.done)
+	return nil
+}
+
+func TestChatCompletions_ArchiveKeepsProviderResponse(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"See [docs](https://external.example/docs)."},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8}}`))
+	}))
+	defer provider.Close()
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"smart": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	}}
+	cfg := config.DefaultConfig()
+	cfg.Archive.Enabled = true
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	store := &archiveStore{done: make(chan struct{})}
+	h.SetArchiver(archive.NewArchiver(store, func() config.ArchiveConfig { return cfg.Archive }))
+	h.SetGuardrails(guardrails.New(func() config.GuardrailsConfig {
+		return config.GuardrailsConfig{Response: []config.GuardrailRule{{StripExternalLinks: true}}}
+	}))
+
+	w := postAs(h.ChatCompletions, "/v1/chat/completions", &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1"},
+		`{"model":"smart","messages":[{"role":"user","content":"Hi"}]}`, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "external.example") {
+		t.Errorf("client response should have the link stripped: %s", w.Body.String())
+	}
+	<-store.done
+	if !strings.Contains(string(store.body), "https://external.example/docs") {
+		t.Errorf("archive should keep the provider's response: %s", store.body)
+	}
+}
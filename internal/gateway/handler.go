@@ -3,19 +3,27 @@ package gateway
 import (
 	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
 	"time"
 
+	"github.com/af-corp/aegis-gateway/internal/archive"
 	"github.com/af-corp/aegis-gateway/internal/auth"
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/cost"
+	"github.com/af-corp/aegis-gateway/internal/events"
 	"github.com/af-corp/aegis-gateway/internal/filter"
 	"github.com/af-corp/aegis-gateway/internal/filter/policy"
+	"github.com/af-corp/aegis-gateway/internal/guardrails"
+	"github.com/af-corp/aegis-gateway/internal/hooks"
 	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
 	"github.com/af-corp/aegis-gateway/internal/retry"
 	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
 	"github.com/af-corp/aegis-gateway/internal/storage"
 	"github.com/af-corp/aegis-gateway/internal/telemetry"
 	"github.com/af-corp/aegis-gateway/internal/types"
@@ -24,7 +32,15 @@ import (
 
 // AuditLogger defines the interface for audit logging (to avoid circular dependency).
 type AuditLogger interface {
-	LogFilterBlock(requestID, orgID, teamID, keyID, filterType, reason string, ip string)
+	LogFilterBlock(requestID, orgID, teamID, keyID, filterType, reason string, detections int, score float64, ip string)
+	LogFilterBypass(requestID, orgID, teamID, keyID, filterType, grantID string, ip string)
+	LogPolicyDenial(requestID, orgID, teamID, keyID, reason string, ip string)
+	LogClassificationViolation(requestID, orgID, teamID, keyID, model, classification string, ip string)
+}
+
+// EventEmitter publishes structured gateway events to downstream consumers.
+type EventEmitter interface {
+	Emit(ev events.Event)
 }
 
 // Handler holds dependencies for the gateway HTTP handlers.
@@ -43,6 +59,20 @@ type Handler struct {
 	contextMonitor   *retry.ContextMonitor
 	validator        *validation.Validator
 	streamingHandler *StreamingHandler
+	archiver         *archive.Archiver
+	events           EventEmitter
+	drainer          *StreamDrainer
+	idempotency      IdempotencyStore
+	responseCache    ResponseCache
+	batches          BatchStore
+	budget           BudgetChecker
+	hooks            *hooks.Chain
+	guardrails       *guardrails.Guard
+	conversations    ConversationStore
+	stickyRoutes     StickyRouteStore
+	feedback         FeedbackStore
+	rateLimitUsage   RateLimitUsage
+	keyUsage         KeyUsageReporter
 }
 
 func NewHandler(registry *router.Registry, healthTracker *router.HealthTracker, modelsCfg func() *config.ModelsConfig, cfg func() *config.Config, filterChain *filter.Chain, policyEvaluator *policy.Evaluator, metrics *telemetry.Metrics, costCalc *cost.Calculator, usageRecorder *storage.UsageRecorder, auditLogger AuditLogger, retryExecutor *retry.Executor, contextMonitor *retry.ContextMonitor, validator *validation.Validator) *Handler {
@@ -68,6 +98,70 @@ func NewHandler(registry *router.Registry, healthTracker *router.HealthTracker,
 	return h
 }
 
+// SetArchiver attaches an optional payload archiver for redacted request/response capture.
+func (h *Handler) SetArchiver(a *archive.Archiver) {
+	h.archiver = a
+}
+
+// SetEventEmitter attaches an optional event exporter (Kafka/NATS).
+func (h *Handler) SetEventEmitter(e EventEmitter) {
+	h.events = e
+}
+
+// SetHooks attaches request/response transformation hooks.
+func (h *Handler) SetHooks(c *hooks.Chain) {
+	h.hooks = c
+}
+
+// SetStreamDrainer attaches a drainer that tracks SSE streams for graceful shutdown.
+func (h *Handler) SetStreamDrainer(d *StreamDrainer) {
+	h.drainer = d
+}
+
+// emitFilterBlocked publishes a filter.blocked event if an emitter is configured.
+func (h *Handler) emitFilterBlocked(reqID string, authInfo *auth.AuthInfo, res filter.Result, ip string) {
+	if h.events == nil {
+		return
+	}
+	h.events.Emit(events.Event{
+		Type:           events.TypeFilterBlocked,
+		RequestID:      reqID,
+		OrganizationID: authInfo.OrganizationID,
+		TeamID:         authInfo.TeamID,
+		APIKeyID:       authInfo.KeyID,
+		Classification: string(authInfo.MaxClassification),
+		StatusCode:     451,
+		Filter:         res.FilterName,
+		Reason:         res.Message,
+		Detections:     res.Detections,
+		Score:          res.Score,
+		IPAddress:      ip,
+	})
+}
+
+// sizeLimits returns the request size limits from the current config.
+func (h *Handler) sizeLimits() validation.SizeLimits {
+	if h.cfg == nil {
+		return validation.SizeLimits{}
+	}
+	l := h.cfg().Limits
+	return validation.SizeLimits{
+		MaxBodyBytes:     l.MaxBodyBytes,
+		MaxMessages:      l.MaxMessages,
+		MaxMessageLength: l.MaxMessageLength,
+		MaxJSONDepth:     l.MaxJSONDepth,
+	}
+}
+
+// decodeOptions returns how strictly request bodies are decoded.
+func (h *Handler) decodeOptions() validation.DecodeOptions {
+	if h.cfg == nil {
+		return validation.DecodeOptions{}
+	}
+	l := h.cfg().Limits
+	return validation.DecodeOptions{Strict: l.StrictFields, DisallowUnknownFields: l.DisallowUnknownFields}
+}
+
 // ChatCompletions handles POST /v1/chat/completions
 func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	reqID := w.Header().Get("X-Request-ID")
@@ -79,19 +173,39 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
+	// Parse request body, bounded by the configured size limits
+	limits := h.sizeLimits()
+	body, err := validation.ReadBody(w, r, limits)
 	if err != nil {
+		var tooLarge *validation.TooLargeError
+		if errors.As(err, &tooLarge) {
+			httputil.WritePayloadTooLargeError(w, reqID, tooLarge.Message)
+			return
+		}
 		httputil.WriteBadRequestError(w, reqID, "Failed to read request body")
 		return
 	}
 	defer func() { _ = r.Body.Close() }()
 
 	var aegisReq types.AegisRequest
-	if err := json.Unmarshal(body, &aegisReq); err != nil {
-		httputil.WriteBadRequestError(w, reqID, "Invalid JSON: "+err.Error())
+	if err := validation.DecodeJSON(body, &aegisReq, h.decodeOptions()); err != nil {
+		httputil.WriteHTTPError(w, reqID, decodeError(err))
+		return
+	}
+	if err := validation.CheckMessageSizes(aegisReq.Messages, limits); err != nil {
+		httputil.WritePayloadTooLargeError(w, reqID, err.Error())
+		return
+	}
+
+	// Replay or claim an Idempotency-Key before anything is sent upstream.
+	iw, handled := h.beginIdempotent(w, r, reqID, authInfo, body, aegisReq.Stream)
+	if handled {
 		return
 	}
+	if iw != nil {
+		w = iw
+		defer h.finishIdempotent(r.Context(), reqID, iw)
+	}
 
 	// Enrich with auth context
 	aegisReq.RequestID = reqID
@@ -106,34 +220,39 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	aegisReq.Project = r.Header.Get("X-Aegis-Project")
 	aegisReq.PreferProvider = r.Header.Get("X-Aegis-Prefer-Provider")
 	aegisReq.TraceContext = r.Header.Get("X-Aegis-Trace-Context")
-
-	// Validate request
-	if h.validator != nil {
-		if err := h.validator.Validate(&aegisReq); err != nil {
-			slog.Warn("request validation failed",
-				"request_id", reqID,
-				"org_id", authInfo.OrganizationID,
-				"error", err.Error(),
-			)
-			httputil.WriteBadRequestError(w, reqID, err.Error())
-			return
-		}
-	} else {
-		// Fallback to basic validation if validator not configured
-		if aegisReq.Model == "" {
-			httputil.WriteBadRequestError(w, reqID, "model is required")
+	if aegisReq.TraceContext == "" {
+		aegisReq.TraceContext = r.Header.Get("traceparent")
+	}
+	aegisReq.RequestHash = aegisReq.ContentHash()
+	w.Header().Set(headerRequestHash, aegisReq.RequestHash)
+	if id := r.Header.Get(headerConversationID); id != "" {
+		if !validConversationID.MatchString(id) {
+			httputil.WriteBadRequestError(w, reqID, headerConversationID+" must be 1-128 letters, digits, '.', '_', ':' or '-'")
 			return
 		}
-		if len(aegisReq.Messages) == 0 {
-			httputil.WriteBadRequestError(w, reqID, "messages is required")
+		aegisReq.ConversationID = id
+	}
+	if key := r.Header.Get(headerAffinityKey); key != "" {
+		if !validConversationID.MatchString(key) {
+			httputil.WriteBadRequestError(w, reqID, headerAffinityKey+" must be 1-128 letters, digits, '.', '_', ':' or '-'")
 			return
 		}
+		aegisReq.AffinityKey = key
+	}
+	if h.checkConversationBudget(w, r, reqID, &aegisReq) {
+		return
+	}
+
+	applyModelRules(w, authInfo, &aegisReq)
+
+	if !h.validateRequest(w, reqID, authInfo, &aegisReq) {
+		return
 	}
 
 	// Run content filter chain (secrets, injection, PII, policy)
 	if h.filterChain != nil {
 		results, blocked := h.filterChain.Run(r.Context(), &aegisReq)
-		if blocked != nil {
+		for blocked != nil {
 			slog.Warn("request blocked by filter",
 				"request_id", reqID,
 				"filter", blocked.FilterName,
@@ -142,36 +261,80 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 				"org_id", authInfo.OrganizationID,
 			)
 			if h.auditLogger != nil {
-				h.auditLogger.LogFilterBlock(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, blocked.FilterName, blocked.Message, r.RemoteAddr)
+				h.auditLogger.LogFilterBlock(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, blocked.FilterName, blocked.Message, blocked.Detections, blocked.Score, r.RemoteAddr)
 			}
+			h.emitFilterBlocked(reqID, authInfo, *blocked, r.RemoteAddr)
 			if h.metrics != nil {
-				h.metrics.RecordFilterAction(blocked.FilterName, string(blocked.Action))
+				h.metrics.RecordFilterAction(blocked.FilterName, authInfo.OrganizationID, string(blocked.Action))
 			}
-			httputil.WriteContentBlockedError(w, reqID, blocked.Message)
-			return
+			if h.answerBlock(w, r, reqID, authInfo, &aegisReq, *blocked) {
+				return
+			}
+			// Redirected: the filters after the blocking one still scan
+			// the request before it goes to the new model.
+			more, next := h.filterChain.RunAfter(r.Context(), &aegisReq, blocked.FilterName)
+			results, blocked = append(results, more...), next
 		}
 		// Record flagged filters
 		for _, fr := range results {
 			if fr.Action == filter.ActionFlag && h.metrics != nil {
-				h.metrics.RecordFilterAction(fr.FilterName, "flag")
+				h.metrics.RecordFilterAction(fr.FilterName, authInfo.OrganizationID, "flag")
 			}
 		}
+		h.recordFilterBypasses(reqID, authInfo, results, r.RemoteAddr)
+	}
+
+	// Hooks run after the filter chain so external hooks only ever see
+	// filtered, redacted content. What they send back is validated again.
+	if h.hooks != nil {
+		if err := h.hooks.RunRequest(r.Context(), &aegisReq); err != nil {
+			slog.Error("request hook failed", "request_id", reqID, "org_id", authInfo.OrganizationID, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeHookFailed, "Request transformation failed", nil)
+			return
+		}
+		if !h.validateRequest(w, reqID, authInfo, &aegisReq) {
+			return
+		}
 	}
 
-	// Route to provider
+	// Reject or truncate requests too large for the model
 	modelsCfg := h.modelsCfg()
-	adapter, providerModel, err := router.ResolveRoute(modelsCfg, h.registry, h.healthTracker, aegisReq.Model, string(aegisReq.Classification))
+	if h.fitContextWindow(w, reqID, authInfo, modelsCfg, &aegisReq) {
+		return
+	}
+
+	// Maintenance windows and office hours close models before routing
+	if h.checkAccessWindows(w, reqID, authInfo, aegisReq.Model) {
+		return
+	}
+
+	// Route to a provider able to serve everything the request asks for,
+	// back to the one that served earlier turns if it can
+	adapter, providerModel, err := router.ResolvePreferredRoute(modelsCfg, h.registry, h.healthTracker, aegisReq.Model, string(aegisReq.Classification),
+		router.RequirementsOf(&aegisReq), h.stickyProvider(r.Context(), &aegisReq))
 	if err != nil {
-		httputil.WriteServiceUnavailableError(w, reqID, "No provider available: "+err.Error())
+		if errors.Is(err, router.ErrClassificationNotPermitted) {
+			slog.Warn("request classification exceeds model routes",
+				"request_id", reqID,
+				"model", aegisReq.Model,
+				"classification", string(aegisReq.Classification),
+				"org_id", authInfo.OrganizationID,
+			)
+			if h.auditLogger != nil {
+				h.auditLogger.LogClassificationViolation(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, aegisReq.Model, string(aegisReq.Classification), r.RemoteAddr)
+			}
+		}
+		httputil.WriteHTTPError(w, reqID, routeError(aegisReq.Model, err))
 		return
 	}
+	deprecation := h.warnDeprecatedModel(w, authInfo, modelsCfg, aegisReq.Model)
 
 	// Set provider type for policy evaluation (adapter.Name() returns "openai", "anthropic", etc.)
 	aegisReq.ProviderType = adapter.Name()
 
 	// Run OPA policy evaluation after routing (needs provider type)
 	if h.policyEvaluator != nil && h.policyEvaluator.Enabled() {
-		result := h.policyEvaluator.ScanRequest(r.Context(), &aegisReq)
+		result := h.evaluatePolicy(r.Context(), &aegisReq)
 		if result.Action == filter.ActionBlock {
 			slog.Warn("request blocked by policy",
 				"request_id", reqID,
@@ -179,20 +342,33 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 				"org_id", authInfo.OrganizationID,
 			)
 			if h.auditLogger != nil {
-				h.auditLogger.LogFilterBlock(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, result.FilterName, result.Message, r.RemoteAddr)
+				h.auditLogger.LogPolicyDenial(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, result.Message, r.RemoteAddr)
 			}
+			h.emitFilterBlocked(reqID, authInfo, result, r.RemoteAddr)
 			if h.metrics != nil {
-				h.metrics.RecordFilterAction(result.FilterName, string(result.Action))
+				h.metrics.RecordFilterAction(result.FilterName, authInfo.OrganizationID, string(result.Action))
 			}
-			httputil.WriteContentBlockedError(w, reqID, result.Message)
+			h.answerBlock(w, r, reqID, authInfo, &aegisReq, result)
 			return
 		}
 	}
 
+	// Repeated requests are answered once routing and policy have admitted
+	// them, before any provider quota is spent
+	route := adapter.Name() + "/" + providerModel
+	if h.serveCached(w, r, reqID, authInfo, route, &aegisReq) {
+		return
+	}
+
 	// Override model with the provider-specific model name
 	originalModel := aegisReq.Model
 	aegisReq.Model = providerModel
 
+	// Stay under the provider's quota before spending any of it
+	if h.waitForProvider(w, r, reqID, adapter.Name(), &aegisReq) {
+		return
+	}
+
 	// Start monitoring context for cancellation
 	var cleanupMonitor func()
 	if h.contextMonitor != nil {
@@ -204,7 +380,8 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	providerReq, err := adapter.TransformRequest(r.Context(), &aegisReq)
 	if err != nil {
 		slog.Error("failed to transform request", "error", err, "provider", adapter.Name())
-		httputil.WriteInternalError(w, reqID, "Failed to prepare provider request")
+		httputil.WriteCode(w, reqID, httputil.CodeInvalidRequest,
+			fmt.Sprintf("Request cannot be sent to provider %s: %v", adapter.Name(), err), map[string]any{"provider": adapter.Name()})
 		return
 	}
 
@@ -214,7 +391,9 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send request with retry logic
+	// Send request with retry logic. Provider latency spans send through body
+	// read so it can be subtracted from total duration to isolate gateway overhead.
+	providerStart := time.Now()
 	var providerResp *http.Response
 	if h.retryExecutor != nil {
 		providerResp, err = h.retryExecutor.Execute(r.Context(), adapter.Name(), func(ctx context.Context, attempt int) (*http.Response, error) {
@@ -229,20 +408,48 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 		// Fallback to direct send if no retry executor
 		providerResp, err = adapter.SendRequest(providerReq)
 	}
+	h.observeRateLimits(adapter.Name(), providerResp)
 
 	if err != nil {
+		if clientGone(r.Context()) {
+			// The cancelled context already aborted the provider call; the
+			// provider did nothing wrong and there is no one to answer.
+			slog.Info("client disconnected before provider responded",
+				"request_id", reqID,
+				"provider", adapter.Name(),
+			)
+			return
+		}
 		slog.Error("provider request failed", "error", err, "provider", adapter.Name())
 		if h.healthTracker != nil {
 			h.healthTracker.RecordFailure(adapter.Name())
 		}
-		httputil.WriteServiceUnavailableError(w, reqID, "Provider request failed")
+		// Retries exhausted on a 4xx such as 429 still carry the provider's answer.
+		if providerResp != nil {
+			pe := adapters.ReadProviderError(adapter.Name(), providerResp)
+			_ = providerResp.Body.Close()
+			if writeProviderError(w, reqID, pe) {
+				return
+			}
+		}
+		httputil.WriteHTTPError(w, reqID, providerFailure(adapter.Name(), err))
 		return
 	}
 
+	providerRequestID := adapters.ProviderRequestID(providerResp)
 	aegisResp, err := adapter.TransformResponse(r.Context(), providerResp)
+	providerLatency := time.Since(providerStart)
 	if err != nil {
-		slog.Error("failed to transform response", "error", err, "provider", adapter.Name())
-		httputil.WriteInternalError(w, reqID, "Failed to process provider response")
+		slog.Error("failed to transform response",
+			"error", err,
+			"provider", adapter.Name(),
+			"request_id", reqID,
+			"provider_request_id", providerRequestID,
+		)
+		if writeProviderError(w, reqID, err) {
+			return
+		}
+		httputil.WriteCode(w, reqID, httputil.CodeProviderError, "Failed to process provider response", map[string]any{"provider": adapter.Name()})
 		return
 	}
 
@@ -271,23 +478,30 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	totalDuration := time.Since(receivedAt)
-
-	slog.Info("request completed",
-		"request_id", reqID,
-		"model_requested", originalModel,
-		"model_served", aegisResp.Model,
-		"provider", aegisResp.Provider,
-		"prompt_tokens", aegisResp.Usage.PromptTokens,
-		"completion_tokens", aegisResp.Usage.CompletionTokens,
-		"total_tokens", aegisResp.Usage.TotalTokens,
-		"estimated_cost_usd", aegisResp.EstimatedCostUSD,
-		"duration_ms", totalDuration.Milliseconds(),
-		"status_code", http.StatusOK,
-		"stream", false,
-		"classification", string(authInfo.MaxClassification),
-		"org_id", authInfo.OrganizationID,
-		"team_id", authInfo.TeamID,
-	)
+	gatewayOverhead := totalDuration - providerLatency
+
+	sampled := h.sampled(reqID)
+	if sampled {
+		slog.Info("request completed",
+			"request_id", reqID,
+			"model_requested", originalModel,
+			"model_served", aegisResp.Model,
+			"provider", aegisResp.Provider,
+			"provider_request_id", providerRequestID,
+			"prompt_tokens", aegisResp.Usage.PromptTokens,
+			"completion_tokens", aegisResp.Usage.CompletionTokens,
+			"total_tokens", aegisResp.Usage.TotalTokens,
+			"estimated_cost_usd", aegisResp.EstimatedCostUSD,
+			"duration_ms", totalDuration.Milliseconds(),
+			"provider_latency_ms", providerLatency.Milliseconds(),
+			"gateway_overhead_ms", gatewayOverhead.Milliseconds(),
+			"status_code", http.StatusOK,
+			"stream", false,
+			"classification", string(authInfo.MaxClassification),
+			"org_id", authInfo.OrganizationID,
+			"team_id", authInfo.TeamID,
+		)
+	}
 
 	if h.metrics != nil {
 		h.metrics.RecordRequest(telemetry.RequestLabels{
@@ -297,11 +511,12 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			Provider:         aegisResp.Provider,
 			Status:           "200",
 			Classification:   string(authInfo.MaxClassification),
-			DurationMs:       float64(totalDuration.Milliseconds()),
-			OverheadMs:       float64(totalDuration.Milliseconds()), // approximation; provider latency subtracted in future
-			PromptTokens:     aegisResp.Usage.PromptTokens,
-			CompletionTokens: aegisResp.Usage.CompletionTokens,
-			CostUSD:          aegisResp.EstimatedCostUSD,
+			DurationMs:        float64(totalDuration.Milliseconds()),
+			OverheadMs:        durationMs(gatewayOverhead),
+			ProviderLatencyMs: durationMs(providerLatency),
+			PromptTokens:      aegisResp.Usage.PromptTokens,
+			CompletionTokens:  aegisResp.Usage.CompletionTokens,
+			CostUSD:           aegisResp.EstimatedCostUSD,
 		})
 	}
 
@@ -325,65 +540,119 @@ func (h *Handler) ChatCompletions(w http.ResponseWriter, r *http.Request) {
 			StatusCode:       http.StatusOK,
 			Project:          aegisReq.Project,
 			Stream:           false,
+			RequestHash:      aegisReq.RequestHash,
 		})
 	}
 
-	// Return OpenAI-compatible response
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(aegisResp)
-}
+	h.recordConversationTurn(&aegisReq, aegisResp.Model, aegisResp.Usage.PromptTokens, aegisResp.Usage.CompletionTokens, aegisResp.EstimatedCostUSD)
+	h.recordStickyRoute(&aegisReq, adapter.Name())
+	h.settleTokens(r.Context(), reqID, aegisResp.Usage.TotalTokens)
 
-// ListModels handles GET /v1/models
-func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
-	reqID := w.Header().Get("X-Request-ID")
+	if h.events != nil {
+		h.events.Emit(events.Event{
+			Type:             events.TypeRequestCompleted,
+			RequestID:        reqID,
+			OrganizationID:   authInfo.OrganizationID,
+			TeamID:           authInfo.TeamID,
+			APIKeyID:         authInfo.KeyID,
+			Classification:   string(authInfo.MaxClassification),
+			ModelRequested:   originalModel,
+			ModelServed:      aegisResp.Model,
+			Provider:         aegisResp.Provider,
+			Project:          aegisReq.Project,
+			StatusCode:       http.StatusOK,
+			DurationMs:       totalDuration.Milliseconds(),
+			PromptTokens:     aegisResp.Usage.PromptTokens,
+			CompletionTokens: aegisResp.Usage.CompletionTokens,
+			EstimatedCostUSD: aegisResp.EstimatedCostUSD,
+		})
+	}
 
-	authInfo, ok := auth.AuthFromContext(r.Context())
-	if !ok {
-		httputil.WriteAuthError(w, reqID, "Not authenticated")
-		return
+	// Archive redacted payloads asynchronously (gated per org and
+	// classification, and sampled with the request log)
+	if h.archiver != nil && sampled {
+		h.archiver.Archive(archive.Record{
+			RequestID:      reqID,
+			OrganizationID: authInfo.OrganizationID,
+			TeamID:         authInfo.TeamID,
+			APIKeyID:       authInfo.KeyID,
+			Classification: string(authInfo.MaxClassification),
+			ModelRequested: originalModel,
+			RequestHash:    aegisReq.RequestHash,
+			Timestamp:      receivedAt,
+			Request:        &aegisReq,
+			Response:       aegisResp,
+		})
 	}
 
-	modelsCfg := h.modelsCfg()
-	var models []modelObject
-	for name, mapping := range modelsCfg.Models {
-		// Filter by allowed models if set
-		if len(authInfo.AllowedModels) > 0 {
-			allowed := false
-			for _, m := range authInfo.AllowedModels {
-				if m == name {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				continue
-			}
+	// The cache, like the archive, keeps the provider's response; hits get
+	// hooks and guardrails applied when they are served.
+	h.storeCached(r.Context(), reqID, authInfo, originalModel, route, &aegisReq, aegisResp)
+
+	// Hooks and then guardrails run last: usage is already recorded if a
+	// hook fails, and the archive, encoded by Archive above, keeps what the
+	// provider actually returned.
+	if transform := h.responseTransform(r.Context(), &aegisReq); transform != nil {
+		if err := transform(aegisResp); err != nil {
+			slog.Error("response hook failed", "request_id", reqID, "org_id", authInfo.OrganizationID, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeHookFailed, "Response transformation failed", nil)
+			return
 		}
-
-		_ = mapping
-		models = append(models, modelObject{
-			ID:      name,
-			Object:  "model",
-			Created: 0,
-			OwnedBy: "aegis",
-		})
 	}
 
+	// Return OpenAI-compatible response
+	aegisResp.Deprecation = deprecation
+	setUsageHeaders(w, aegisResp)
+	setProviderLatencyHeader(w, providerLatency)
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(modelListResponse{
-		Object: "list",
-		Data:   models,
-	})
+	_ = json.NewEncoder(w).Encode(aegisResp)
+}
+
+// validateRequest checks req and that the caller's key may use its model,
+// writing the error response when either fails.
+func (h *Handler) validateRequest(w http.ResponseWriter, reqID string, authInfo *auth.AuthInfo, req *types.AegisRequest) bool {
+	if h.validator != nil {
+		if err := h.validator.Validate(req); err != nil {
+			slog.Warn("request validation failed",
+				"request_id", reqID,
+				"org_id", authInfo.OrganizationID,
+				"error", err.Error(),
+			)
+			httputil.WriteHTTPError(w, reqID, validationError(err))
+			return false
+		}
+	} else {
+		// Fallback to basic validation if validator not configured
+		if req.Model == "" {
+			httputil.WriteBadRequestError(w, reqID, "model is required")
+			return false
+		}
+		if len(req.Messages) == 0 {
+			httputil.WriteBadRequestError(w, reqID, "messages is required")
+			return false
+		}
+	}
+	if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, req.Model) {
+		httputil.WriteHTTPError(w, reqID, modelNotAllowed(req.Model))
+		return false
+	}
+	return true
+}
+
+// clientGone reports whether the caller has disconnected, which cancels the
+// request context and with it any provider call made under it.
+func clientGone(ctx context.Context) bool {
+	return errors.Is(ctx.Err(), context.Canceled)
 }
 
-type modelObject struct {
-	ID      string `json:"id"`
-	Object  string `json:"object"`
-	Created int64  `json:"created"`
-	OwnedBy string `json:"owned_by"`
+// durationMs converts a duration to fractional milliseconds so sub-millisecond
+// gateway overhead is not truncated to zero.
+func durationMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
 }
 
-type modelListResponse struct {
-	Object string        `json:"object"`
-	Data   []modelObject `json:"data"`
+// settleTokens replaces the tokens the rate limiter charged the request on
+// admission with the total the provider reported, in the background.
+func (h *Handler) settleTokens(ctx context.Context, reqID string, totalTokens int) {
+	ratelimit.SettleTokensInBackground(ctx, reqID, int64(totalTokens))
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/af-corp/aegis-gateway/internal/auth"
 	"github.com/af-corp/aegis-gateway/internal/filter"
 	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
 
@@ -61,7 +62,9 @@ func (h *Handler) ChatCompletionsRefactored(w http.ResponseWriter, r *http.Reque
 	}
 
 	// Execute provider request
+	providerStart := time.Now()
 	aegisResp, err := h.executeNonStreamingRequest(r.Context(), routeResult, parsedReq)
+	providerLatency := time.Since(providerStart)
 	if err != nil {
 		h.writeHTTPError(w, reqID, err)
 		return
@@ -71,9 +74,11 @@ func (h *Handler) ChatCompletionsRefactored(w http.ResponseWriter, r *http.Reque
 	h.buildResponse(aegisResp, reqID)
 
 	// Log and record metrics
-	h.logAndRecordMetrics(reqID, parsedReq.OriginalModel, aegisResp, authInfo, parsedReq.AegisRequest.Project, false, time.Since(receivedAt))
+	h.logAndRecordMetrics(reqID, parsedReq.OriginalModel, aegisResp, authInfo, parsedReq.AegisRequest.Project, false, time.Since(receivedAt), providerLatency)
 
 	// Return OpenAI-compatible response
+	setUsageHeaders(w, aegisResp)
+	setProviderLatencyHeader(w, providerLatency)
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(aegisResp)
 }
@@ -84,6 +89,8 @@ func (h *Handler) parseAndValidate(r *http.Request, reqID string, authInfo *auth
 		validator:   h.validator,
 		auditLogger: h.auditLogger,
 		metrics:     h.metrics,
+		limits:      h.sizeLimits(),
+		decode:      h.decodeOptions(),
 	}
 	
 	parsed, err := processor.ParseAndValidateRequest(r, reqID, authInfo)
@@ -100,9 +107,10 @@ func (h *Handler) parseAndValidate(r *http.Request, reqID string, authInfo *auth
 // runContentFilters executes content filtering.
 func (h *Handler) runContentFilters(r *http.Request, parsedReq *ParsedRequestWithModel, authInfo *auth.AuthInfo) error {
 	processor := &FilterProcessor{
-		filterChain: h.filterChain,
-		auditLogger: h.auditLogger,
-		metrics:     h.metrics,
+		filterChain:  h.filterChain,
+		auditLogger:  h.auditLogger,
+		metrics:      h.metrics,
+		blockDetails: h.cfg().Filter.ShowBlockDetails(authInfo.OrganizationID),
 	}
 	
 	_, err := processor.RunFilters(r, parsedReq.AegisRequest, authInfo)
@@ -123,24 +131,29 @@ func (h *Handler) runPolicyCheck(r *http.Request, reqID string, parsedReq *Parse
 	parsedReq.AegisRequest.Model = parsedReq.OriginalModel
 	parsedReq.AegisRequest.ProviderType = routeResult.Adapter.Name()
 
-	result := h.policyEvaluator.ScanRequest(r.Context(), parsedReq.AegisRequest)
+	result := h.evaluatePolicy(r.Context(), parsedReq.AegisRequest)
 
 	parsedReq.AegisRequest.Model = providerModel
 
 	if result.Action == filter.ActionBlock {
 		if h.auditLogger != nil {
-			h.auditLogger.LogFilterBlock(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, result.FilterName, result.Message, r.RemoteAddr)
+			h.auditLogger.LogPolicyDenial(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, result.Message, r.RemoteAddr)
 		}
 		if h.metrics != nil {
-			h.metrics.RecordFilterAction(result.FilterName, string(result.Action))
+			h.metrics.RecordFilterAction(result.FilterName, authInfo.OrganizationID, string(result.Action))
 		}
-		return &httputil.HTTPError{StatusCode: http.StatusForbidden, Message: result.Message}
+		return filterBlockError(result, false)
 	}
 	return nil
 }
 
 // routeRequest handles provider routing.
 func (h *Handler) routeRequest(ctx interface{ Done() <-chan struct{} }, parsedReq *ParsedRequestWithModel) (*RouteResultWithModel, error) {
+	req := parsedReq.AegisRequest
+	if err := router.CheckAccessWindows(h.cfg().Routing.AccessWindows, req.OrganizationID, req.Model, time.Now()); err != nil {
+		return nil, httputil.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
 	processor := &RouterProcessor{
 		registry:      h.registry,
 		healthTracker: h.healthTracker,
@@ -255,17 +268,26 @@ func (h *Handler) logAndRecordMetrics(
 	project string,
 	stream bool,
 	duration time.Duration,
+	providerLatency time.Duration,
 ) {
 	logger := &TelemetryLogger{
 		metrics:       h.metrics,
 		usageRecorder: h.usageRecorder,
+		sampled:       h.sampled(reqID),
 	}
-	logger.LogCompletedRequest(reqID, originalModel, aegisResp, authInfo, project, stream, duration)
+	logger.LogCompletedRequest(reqID, originalModel, aegisResp, authInfo, project, stream, duration, providerLatency)
 }
 
 // writeHTTPError writes an HTTP error response.
 func (h *Handler) writeHTTPError(w http.ResponseWriter, reqID string, err error) {
+	if writeProviderError(w, reqID, err) {
+		return
+	}
 	if httpErr, ok := err.(*httputil.HTTPError); ok {
+		if httpErr.Code != "" {
+			httputil.WriteHTTPError(w, reqID, httpErr)
+			return
+		}
 		switch httpErr.StatusCode {
 		case http.StatusBadRequest:
 			httputil.WriteBadRequestError(w, reqID, httpErr.Message)
@@ -273,6 +295,8 @@ func (h *Handler) writeHTTPError(w http.ResponseWriter, reqID string, err error)
 			httputil.WriteAuthError(w, reqID, httpErr.Message)
 		case http.StatusForbidden:
 			httputil.WriteContentBlockedError(w, reqID, httpErr.Message)
+		case http.StatusRequestEntityTooLarge:
+			httputil.WritePayloadTooLargeError(w, reqID, httpErr.Message)
 		case http.StatusServiceUnavailable:
 			httputil.WriteServiceUnavailableError(w, reqID, httpErr.Message)
 		default:
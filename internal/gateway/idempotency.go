@@ -0,0 +1,155 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+)
+
+const (
+	headerIdempotencyKey      = "Idempotency-Key"
+	headerIdempotentReplayed  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	idempotencyScopeSeparator = "\x00"
+)
+
+// IdempotencyStore records responses to requests carrying an Idempotency-Key.
+// It is satisfied by *cache.IdempotencyStore.
+type IdempotencyStore interface {
+	Reserve(ctx context.Context, org, key, fingerprint string) (*cache.StoredResponse, error)
+	Complete(ctx context.Context, org, key string, resp cache.StoredResponse) error
+	Release(ctx context.Context, org, key string) error
+}
+
+// SetIdempotencyStore enables Idempotency-Key handling for non-streaming
+// chat completions.
+func (h *Handler) SetIdempotencyStore(s IdempotencyStore) {
+	h.idempotency = s
+}
+
+// beginIdempotent applies the request's Idempotency-Key, if any. It reports
+// handled=true when the response has already been written: a replay of the
+// original result or an error for a key that is busy or reused. Otherwise a
+// non-nil writer must be used for the rest of the request and finished with
+// finishIdempotent. Streams are never replayed and ignore the header.
+func (h *Handler) beginIdempotent(w http.ResponseWriter, r *http.Request, reqID string, authInfo *auth.AuthInfo, body []byte, stream bool) (iw *idempotentWriter, handled bool) {
+	key := r.Header.Get(headerIdempotencyKey)
+	if key == "" || stream || h.idempotency == nil || (h.cfg != nil && !h.cfg().Idempotency.Enabled) {
+		return nil, false
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		httputil.WriteError(w, reqID, http.StatusBadRequest, "invalid_request_error", "invalid_idempotency_key",
+			"Idempotency-Key must be at most 255 characters")
+		return nil, true
+	}
+
+	// Keys are scoped to the API key so tenants cannot read each other's
+	// responses by guessing keys.
+	scope := sha256.Sum256([]byte(authInfo.KeyID + idempotencyScopeSeparator + key))
+	storeKey := hex.EncodeToString(scope[:])
+	sum := sha256.Sum256(body)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	stored, err := h.idempotency.Reserve(r.Context(), authInfo.OrganizationID, storeKey, fingerprint)
+	switch {
+	case errors.Is(err, cache.ErrIdempotencyInProgress):
+		httputil.WriteError(w, reqID, http.StatusConflict, "invalid_request_error", "idempotency_key_in_use",
+			"A request with this Idempotency-Key is still in progress")
+		return nil, true
+	case errors.Is(err, cache.ErrIdempotencyMismatch):
+		httputil.WriteError(w, reqID, http.StatusUnprocessableEntity, "invalid_request_error", "idempotency_key_reused",
+			"Idempotency-Key was already used with a different request body")
+		return nil, true
+	case err != nil:
+		// Idempotency is a convenience; an unreachable store must not fail
+		// the request.
+		slog.Warn("idempotency store unavailable, serving request without it",
+			"request_id", reqID,
+			"error", err,
+		)
+		return nil, false
+	case stored != nil:
+		slog.Info("replaying idempotent response",
+			"request_id", reqID,
+			"org_id", authInfo.OrganizationID,
+			"status", stored.StatusCode,
+		)
+		for k, v := range stored.Header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set(headerIdempotentReplayed, "true")
+		w.WriteHeader(stored.StatusCode)
+		_, _ = w.Write(stored.Body)
+		return nil, true
+	}
+	return &idempotentWriter{ResponseWriter: w, org: authInfo.OrganizationID, key: storeKey, fingerprint: fingerprint}, false
+}
+
+// finishIdempotent stores a successful response for replay or releases the
+// key so a retry of a failed request runs again. It runs detached from the
+// client's context: a response finished after the client gave up is exactly
+// the one its retry should get.
+func (h *Handler) finishIdempotent(ctx context.Context, reqID string, iw *idempotentWriter) {
+	ctx = context.WithoutCancel(ctx)
+	if iw.status != http.StatusOK {
+		if err := h.idempotency.Release(ctx, iw.org, iw.key); err != nil {
+			slog.Warn("failed to release idempotency key", "request_id", reqID, "error", err)
+		}
+		return
+	}
+
+	header := make(map[string]string)
+	for k, v := range iw.Header() {
+		if len(v) > 0 && (k == "Content-Type" || strings.HasPrefix(k, "X-Aegis-")) {
+			header[k] = v[0]
+		}
+	}
+	err := h.idempotency.Complete(ctx, iw.org, iw.key, cache.StoredResponse{
+		Fingerprint: iw.fingerprint,
+		StatusCode:  iw.status,
+		Header:      header,
+		Body:        iw.body,
+	})
+	if err != nil {
+		slog.Warn("failed to store idempotent response", "request_id", reqID, "error", err)
+	}
+}
+
+// idempotentWriter passes a response through while keeping a copy for
+// replay.
+type idempotentWriter struct {
+	http.ResponseWriter
+	org         string
+	key         string
+	fingerprint string
+	status      int
+	body        []byte
+}
+
+func (iw *idempotentWriter) WriteHeader(status int) {
+	if iw.status == 0 {
+		iw.status = status
+	}
+	iw.ResponseWriter.WriteHeader(status)
+}
+
+func (iw *idempotentWriter) Write(p []byte) (int, error) {
+	if iw.status == 0 {
+		iw.status = http.StatusOK
+	}
+	iw.body = append(iw.body, p...)
+	return iw.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (iw *idempotentWriter) Unwrap() http.ResponseWriter {
+	return iw.ResponseWriter
+}
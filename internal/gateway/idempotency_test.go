@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+// memIdempotencyStore mirrors cache.IdempotencyStore in memory.
+type memIdempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]cache.StoredResponse
+}
+
+func (m *memIdempotencyStore) Reserve(_ context.Context, _, key, fingerprint string) (*cache.StoredResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.entries[key]
+	switch {
+	case !ok:
+		m.entries[key] = cache.StoredResponse{Fingerprint: fingerprint, Pending: true}
+		return nil, nil
+	case stored.Fingerprint != fingerprint:
+		return nil, cache.ErrIdempotencyMismatch
+	case stored.Pending:
+		return nil, cache.ErrIdempotencyInProgress
+	}
+	return &stored, nil
+}
+
+func (m *memIdempotencyStore) Complete(_ context.Context, _, key string, resp cache.StoredResponse) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = resp
+	return nil
+}
+
+func (m *memIdempotencyStore) Release(_ context.Context, _, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// newIdempotencyTestHandler returns a handler routing gpt-4o to a fake OpenAI
+// that answers with status and counts its calls.
+func newIdempotencyTestHandler(t *testing.T, status *atomic.Int32) (*Handler, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte(`{"id":"chatcmpl-1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"gpt-4o": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	}}
+	cfg := config.DefaultConfig()
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h.SetIdempotencyStore(&memIdempotencyStore{entries: map[string]cache.StoredResponse{}})
+	return h, &calls
+}
+
+func postWithIdempotencyKey(h *Handler, keyID, idemKey, body string) *httptest.ResponseRecorder {
+	return postAs(h.ChatCompletions, "/v1/chat/completions", &auth.AuthInfo{OrganizationID: "org-1", KeyID: keyID}, body,
+		http.Header{headerIdempotencyKey: {idemKey}})
+}
+
+const idempotencyTestBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"Hello"}]}`
+
+func TestChatCompletions_IdempotencyKeyReplays(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	h, calls := newIdempotencyTestHandler(t, &status)
+
+	first := postWithIdempotencyKey(h, "key-1", "retry-me", idempotencyTestBody)
+	second := postWithIdempotencyKey(h, "key-1", "retry-me", idempotencyTestBody)
+
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("status = %d then %d", first.Code, second.Code)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("provider called %d times, want 1", calls.Load())
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("replayed body differs:\n%s\n%s", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get(headerIdempotentReplayed) != "true" || first.Header().Get(headerIdempotentReplayed) != "" {
+		t.Error("only the replay should carry Idempotent-Replayed")
+	}
+	if second.Header().Get(headerTokensCompletion) != "1" {
+		t.Errorf("usage headers should be replayed, got %v", second.Header())
+	}
+
+	// Another API key using the same Idempotency-Key is a different request.
+	if w := postWithIdempotencyKey(h, "key-2", "retry-me", idempotencyTestBody); w.Code != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("keys must be scoped per API key: status %d, calls %d", w.Code, calls.Load())
+	}
+}
+
+func TestChatCompletions_IdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	h, _ := newIdempotencyTestHandler(t, &status)
+
+	postWithIdempotencyKey(h, "key-1", "k", idempotencyTestBody)
+	w := postWithIdempotencyKey(h, "key-1", "k", strings.Replace(idempotencyTestBody, "Hello", "Goodbye", 1))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "idempotency_key_reused") {
+		t.Errorf("expected 422 idempotency_key_reused, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestChatCompletions_IdempotencyKeyReleasedOnFailure(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusBadRequest)
+	h, calls := newIdempotencyTestHandler(t, &status)
+
+	if w := postWithIdempotencyKey(h, "key-1", "k", idempotencyTestBody); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the provider's 400, got %d", w.Code)
+	}
+	status.Store(http.StatusOK)
+	if w := postWithIdempotencyKey(h, "key-1", "k", idempotencyTestBody); w.Code != http.StatusOK {
+		t.Fatalf("retry after a failure should run again, got %d", w.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("provider called %d times, want 2", calls.Load())
+	}
+}
+
+func TestChatCompletions_IdempotencyKeyInProgress(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	h, _ := newIdempotencyTestHandler(t, &status)
+	store := h.idempotency.(*memIdempotencyStore)
+
+	// Simulate the first request still running by holding its reservation.
+	postWithIdempotencyKey(h, "key-1", "k", idempotencyTestBody)
+	for k, v := range store.entries {
+		v.Pending = true
+		store.entries[k] = v
+	}
+	w := postWithIdempotencyKey(h, "key-1", "k", idempotencyTestBody)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "idempotency_key_in_use") {
+		t.Errorf("expected 409 idempotency_key_in_use, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+// RateLimitUsage reports what a key has used of its per-minute limits. It is
+// satisfied by *ratelimit.Limiter.
+type RateLimitUsage interface {
+	Usage(ctx context.Context, org, keyID string) (ratelimit.KeyUsage, error)
+}
+
+// KeyUsageReporter totals a key's recorded usage. It is satisfied by
+// *storage.UsageRecorder.
+type KeyUsageReporter interface {
+	KeyUsageSince(ctx context.Context, keyID string, since time.Time) (storage.KeyUsage, error)
+}
+
+// SetSelfService attaches the rate limit and usage lookups GET /aegis/v1/me
+// reports. Either may be nil to leave its section out.
+func (h *Handler) SetSelfService(limits RateLimitUsage, usage KeyUsageReporter) {
+	h.rateLimitUsage = limits
+	h.keyUsage = usage
+}
+
+// meResponse is the response of GET /aegis/v1/me. Sections whose lookup is
+// unavailable are omitted.
+type meResponse struct {
+	Object            string             `json:"object"`
+	KeyID             string             `json:"key_id"`
+	OrganizationID    string             `json:"organization_id"`
+	TeamID            string             `json:"team_id"`
+	UserID            string             `json:"user_id,omitempty"`
+	MaxClassification string             `json:"max_classification"`
+	AllowedModels     []string           `json:"allowed_models"`
+	Priority          string             `json:"priority,omitempty"`
+	ModelRules        auth.ModelRules    `json:"model_rules,omitzero"`
+	Limits            meLimits           `json:"limits"`
+	RateLimits        *meRateLimits      `json:"rate_limits,omitempty"`
+	Budget            *meBudget          `json:"budget,omitempty"`
+	Usage             map[string]meUsage `json:"usage,omitempty"`
+}
+
+type meLimits struct {
+	RequestsPerMinute  int      `json:"requests_per_minute"`
+	TokensPerMinute    int      `json:"tokens_per_minute,omitempty"`
+	DailySpendLimitUSD *float64 `json:"daily_spend_limit_usd,omitempty"`
+}
+
+// meRateLimits is the key's consumption of its per-minute windows, this
+// request included; a 429 follows when either remaining reaches zero.
+type meRateLimits struct {
+	RequestsUsed      int64  `json:"requests_used"`
+	RequestsRemaining int64  `json:"requests_remaining"`
+	TokensUsed        int64  `json:"tokens_used"`
+	TokensRemaining   *int64 `json:"tokens_remaining,omitempty"`
+}
+
+// meBudget is the team's daily spend against the key's limit; a 402 follows
+// once it is exhausted.
+type meBudget struct {
+	SpentUSD  float64   `json:"spent_usd"`
+	LimitUSD  float64   `json:"limit_usd"`
+	Exhausted bool      `json:"exhausted"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// meUsage is the key's recorded usage since Since.
+type meUsage struct {
+	Since time.Time `json:"since"`
+	storage.KeyUsage
+}
+
+// Me handles GET /aegis/v1/me: the calling key's identity and limits, what
+// it has used of its rate limits this minute, its team's daily budget, and
+// its spend today and this month (UTC), so a team can tell why it got a 429
+// or a 402. Failed lookups are logged and their sections left out.
+func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+	ctx := r.Context()
+
+	resp := meResponse{
+		Object:            "aegis.key",
+		KeyID:             authInfo.KeyID,
+		OrganizationID:    authInfo.OrganizationID,
+		TeamID:            authInfo.TeamID,
+		UserID:            authInfo.UserID,
+		MaxClassification: string(authInfo.MaxClassification),
+		AllowedModels:     authInfo.AllowedModels,
+		Priority:          string(authInfo.Priority),
+		ModelRules:        authInfo.ModelRules,
+		Limits:            meLimits{RequestsPerMinute: ratelimit.DefaultRPM},
+	}
+	if resp.AllowedModels == nil {
+		resp.AllowedModels = []string{}
+	}
+	if authInfo.RPMLimit != nil {
+		resp.Limits.RequestsPerMinute = *authInfo.RPMLimit
+	}
+	if authInfo.TPMLimit != nil {
+		resp.Limits.TokensPerMinute = *authInfo.TPMLimit
+	}
+	if authInfo.DailySpendLimitCents != nil {
+		limit := float64(*authInfo.DailySpendLimitCents) / 100
+		resp.Limits.DailySpendLimitUSD = &limit
+	}
+
+	if h.rateLimitUsage != nil {
+		if u, err := h.rateLimitUsage.Usage(ctx, authInfo.OrganizationID, authInfo.KeyID); err != nil {
+			slog.Warn("rate limit usage lookup failed", "request_id", reqID, "key_id", authInfo.KeyID, "error", err)
+		} else {
+			resp.RateLimits = &meRateLimits{
+				RequestsUsed:      u.Requests,
+				RequestsRemaining: max(int64(resp.Limits.RequestsPerMinute)-u.Requests, 0),
+				TokensUsed:        u.Tokens,
+			}
+			if resp.Limits.TokensPerMinute > 0 {
+				remaining := max(int64(resp.Limits.TokensPerMinute)-u.Tokens, 0)
+				resp.RateLimits.TokensRemaining = &remaining
+			}
+		}
+	}
+
+	if authInfo.DailySpendLimitCents != nil && h.budget != nil {
+		if res, err := h.budget.CheckDailySpend(ctx, authInfo.OrganizationID, authInfo.TeamID, int64(*authInfo.DailySpendLimitCents)); err != nil {
+			slog.Warn("budget lookup failed", "request_id", reqID, "key_id", authInfo.KeyID, "error", err)
+		} else {
+			resp.Budget = &meBudget{
+				SpentUSD:  float64(res.SpentCents) / 100,
+				LimitUSD:  float64(res.LimitCents) / 100,
+				Exhausted: !res.Allowed,
+				ResetsAt:  res.ResetAt,
+			}
+		}
+	}
+
+	if h.keyUsage != nil {
+		now := time.Now().UTC()
+		periods := map[string]time.Time{
+			"today":      time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+			"this_month": time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		}
+		resp.Usage = make(map[string]meUsage, len(periods))
+		for name, since := range periods {
+			u, err := h.keyUsage.KeyUsageSince(ctx, authInfo.KeyID, since)
+			if err != nil {
+				slog.Warn("key usage lookup failed", "request_id", reqID, "key_id", authInfo.KeyID, "error", err)
+				resp.Usage = nil
+				break
+			}
+			resp.Usage[name] = meUsage{Since: since, KeyUsage: u}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
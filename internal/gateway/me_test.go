@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+type fakeRateLimitUsage struct {
+	usage ratelimit.KeyUsage
+	err   error
+}
+
+func (f fakeRateLimitUsage) Usage(context.Context, string, string) (ratelimit.KeyUsage, error) {
+	return f.usage, f.err
+}
+
+// fakeKeyUsage reports more usage for earlier starts, as a month covers a day.
+type fakeKeyUsage struct{}
+
+func (fakeKeyUsage) KeyUsageSince(_ context.Context, _ string, since time.Time) (storage.KeyUsage, error) {
+	if since.Day() == 1 && since.Before(time.Now().UTC().Truncate(24*time.Hour)) {
+		return storage.KeyUsage{Requests: 40, TotalTokens: 4000, CostUSD: 2.5}, nil
+	}
+	return storage.KeyUsage{Requests: 4, TotalTokens: 400, CostUSD: 0.25}, nil
+}
+
+func getMe(t *testing.T, h *Handler, info *auth.AuthInfo) map[string]any {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/aegis/v1/me", nil)
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), info))
+	w := httptest.NewRecorder()
+	h.Me(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMe(t *testing.T) {
+	cfg := config.DefaultConfig()
+	h := NewHandler(nil, nil, nil, func() *config.Config { return cfg }, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h.SetBudget(fakeBudget{ratelimit.BudgetResult{Allowed: false, SpentCents: 1200, LimitCents: 1000}})
+	h.SetSelfService(fakeRateLimitUsage{usage: ratelimit.KeyUsage{Requests: 12, Tokens: 3000}}, fakeKeyUsage{})
+
+	rpm, tpm, spendCents := 10, 5000, 1000
+	resp := getMe(t, h, &auth.AuthInfo{
+		KeyID: "key-1", OrganizationID: "org-1", TeamID: "team-1",
+		MaxClassification: "INTERNAL", AllowedModels: []string{"fast"},
+		RPMLimit: &rpm, TPMLimit: &tpm, DailySpendLimitCents: &spendCents,
+	})
+
+	if resp["key_id"] != "key-1" || resp["organization_id"] != "org-1" || resp["max_classification"] != "INTERNAL" {
+		t.Errorf("unexpected key metadata %v", resp)
+	}
+	limits := resp["limits"].(map[string]any)
+	if limits["requests_per_minute"] != 10.0 || limits["tokens_per_minute"] != 5000.0 || limits["daily_spend_limit_usd"] != 10.0 {
+		t.Errorf("unexpected limits %v", limits)
+	}
+	rl := resp["rate_limits"].(map[string]any)
+	if rl["requests_used"] != 12.0 || rl["requests_remaining"] != 0.0 || rl["tokens_remaining"] != 2000.0 {
+		t.Errorf("unexpected rate limits %v", rl)
+	}
+	budget := resp["budget"].(map[string]any)
+	if budget["exhausted"] != true || budget["spent_usd"] != 12.0 {
+		t.Errorf("unexpected budget %v", budget)
+	}
+	usage := resp["usage"].(map[string]any)
+	if usage["today"].(map[string]any)["cost_usd"] != 0.25 {
+		t.Errorf("unexpected usage %v", usage)
+	}
+	if _, ok := usage["this_month"]; !ok {
+		t.Errorf("usage missing this_month: %v", usage)
+	}
+}
+
+func TestMe_DegradesWithoutLookups(t *testing.T) {
+	cfg := config.DefaultConfig()
+	h := NewHandler(nil, nil, nil, func() *config.Config { return cfg }, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h.SetSelfService(fakeRateLimitUsage{err: errors.New("redis down")}, nil)
+
+	resp := getMe(t, h, &auth.AuthInfo{KeyID: "key-1", OrganizationID: "org-1"})
+	for _, section := range []string{"rate_limits", "budget", "usage"} {
+		if _, ok := resp[section]; ok {
+			t.Errorf("%s should be omitted when its lookup is unavailable: %v", section, resp)
+		}
+	}
+	if models, ok := resp["allowed_models"].([]any); !ok || len(models) != 0 {
+		t.Errorf("allowed_models = %v, want []", resp["allowed_models"])
+	}
+	limits := resp["limits"].(map[string]any)
+	if limits["requests_per_minute"] != float64(ratelimit.DefaultRPM) {
+		t.Errorf("unexpected limits %v", limits)
+	}
+	if _, ok := limits["tokens_per_minute"]; ok {
+		t.Errorf("a key without tpm_limit has no token limit: %v", limits)
+	}
+}
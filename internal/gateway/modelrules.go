@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// headerRequestedModel carries, on responses, the model a client asked for
+// when the key's or team's model rules served another.
+const headerRequestedModel = "X-Aegis-Requested-Model"
+
+// applyModelRules switches req to the model the key's and team's rules pick,
+// before validation and routing see it.
+func applyModelRules(w http.ResponseWriter, authInfo *auth.AuthInfo, req *types.AegisRequest) {
+	model := authInfo.ModelRules.Resolve(req.Model)
+	if model == req.Model {
+		return
+	}
+	if req.Model != "" {
+		w.Header().Set(headerRequestedModel, req.Model)
+	}
+	slog.Debug("model rules applied",
+		"request_id", req.RequestID,
+		"org_id", authInfo.OrganizationID,
+		"team_id", authInfo.TeamID,
+		"requested_model", req.Model,
+		"model", model,
+	)
+	req.Model = model
+}
@@ -0,0 +1,195 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// modelObject is an OpenAI model object. The fields after OwnedBy are
+// gateway extensions for model pickers, which OpenAI clients ignore.
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+
+	DisplayName   string `json:"display_name,omitempty"`
+	ContextWindow int    `json:"context_window,omitempty"`
+	// ClassificationCeiling is the highest classification any route
+	// carries, or empty if some route has no ceiling.
+	ClassificationCeiling string `json:"classification_ceiling,omitempty"`
+	// Capabilities are what the model serves through some route, since
+	// requests fail over to a route that has the feature they need.
+	Capabilities *modelCapabilities `json:"capabilities,omitempty"`
+	// Pricing is the primary route's.
+	Pricing     *modelPricing           `json:"pricing,omitempty"`
+	Deprecation *types.ModelDeprecation `json:"deprecation,omitempty"`
+	Providers   []modelRoute            `json:"providers"`
+}
+
+// modelRoute is one provider route of a model, primary first.
+type modelRoute struct {
+	Provider              string             `json:"provider"`
+	Model                 string             `json:"model"`
+	Role                  string             `json:"role"` // "primary" or "fallback"
+	ClassificationCeiling string             `json:"classification_ceiling,omitempty"`
+	Available             bool               `json:"available"`
+	Capabilities          *modelCapabilities `json:"capabilities,omitempty"`
+	Pricing               *modelPricing      `json:"pricing,omitempty"`
+}
+
+type modelCapabilities struct {
+	Streaming       bool `json:"streaming"`
+	Tools           bool `json:"tools"`
+	Vision          bool `json:"vision"`
+	JSONMode        bool `json:"json_mode"`
+	MaxOutputTokens int  `json:"max_output_tokens,omitempty"`
+}
+
+// modelPricing is in USD per 1,000 tokens, as in models.yaml.
+type modelPricing struct {
+	Input  float64 `json:"input_per_1k_tokens"`
+	Output float64 `json:"output_per_1k_tokens"`
+}
+
+type modelListResponse struct {
+	Object string        `json:"object"`
+	Data   []modelObject `json:"data"`
+}
+
+// ListModels handles GET /v1/models, listing the models the key may use.
+func (h *Handler) ListModels(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+
+	modelsCfg := h.modelsCfg()
+	models := []modelObject{}
+	for name, mapping := range modelsCfg.Models {
+		if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, name) {
+			continue
+		}
+		models = append(models, h.describeModel(modelsCfg, name, mapping))
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+
+	writeJSON(w, http.StatusOK, modelListResponse{Object: "list", Data: models})
+}
+
+// GetModel handles GET /v1/models/{id}. Models the key may not use are
+// reported as not found, as OpenAI does.
+func (h *Handler) GetModel(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+
+	modelsCfg := h.modelsCfg()
+	name := chi.URLParam(r, "id")
+	mapping, ok := modelsCfg.Models[name]
+	if !ok || (len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, name)) {
+		httputil.WriteHTTPError(w, reqID, httputil.NewCodedError(httputil.CodeModelNotFound,
+			fmt.Sprintf("Model %q does not exist", name), map[string]any{"model": name}))
+		return
+	}
+	writeJSON(w, http.StatusOK, h.describeModel(modelsCfg, name, mapping))
+}
+
+// describeModel builds the model object for name from models.yaml and the
+// current provider health.
+func (h *Handler) describeModel(modelsCfg *config.ModelsConfig, name string, mapping config.ModelMapping) modelObject {
+	m := modelObject{
+		ID:            name,
+		Object:        "model",
+		OwnedBy:       "aegis",
+		DisplayName:   mapping.DisplayName,
+		ContextWindow: mapping.ContextWindow,
+		Providers:     []modelRoute{},
+	}
+	if !mapping.Created.IsZero() {
+		m.Created = mapping.Created.Unix()
+	}
+	if d := mapping.Deprecated; d != nil {
+		m.Deprecation = &types.ModelDeprecation{Model: name, SunsetDate: d.Sunset.Format(time.DateOnly), Replacement: d.Replacement}
+	}
+
+	ceiling, unlimited := types.Classification(""), false
+	for i, route := range append([]config.ProviderRoute{mapping.Primary}, mapping.Fallback...) {
+		mr := modelRoute{
+			Provider:              route.Provider,
+			Model:                 route.Model,
+			Role:                  "fallback",
+			ClassificationCeiling: route.ClassificationCeiling,
+			Available:             h.healthTracker == nil || h.healthTracker.IsAvailable(route.Provider),
+			Capabilities:          routeCapabilities(modelsCfg, route),
+			Pricing:               routePricing(modelsCfg, route),
+		}
+		if i == 0 {
+			mr.Role = "primary"
+			m.Pricing = mr.Pricing
+		}
+		m.Providers = append(m.Providers, mr)
+
+		if route.ClassificationCeiling == "" {
+			unlimited = true
+		} else if c := types.Classification(route.ClassificationCeiling); c.Level() > ceiling.Level() {
+			ceiling = c
+		}
+		if mr.Capabilities != nil {
+			if m.Capabilities == nil {
+				m.Capabilities = &modelCapabilities{}
+			}
+			m.Capabilities.Streaming = m.Capabilities.Streaming || mr.Capabilities.Streaming
+			m.Capabilities.Tools = m.Capabilities.Tools || mr.Capabilities.Tools
+			m.Capabilities.Vision = m.Capabilities.Vision || mr.Capabilities.Vision
+			m.Capabilities.JSONMode = m.Capabilities.JSONMode || mr.Capabilities.JSONMode
+			m.Capabilities.MaxOutputTokens = max(m.Capabilities.MaxOutputTokens, mr.Capabilities.MaxOutputTokens)
+		}
+	}
+	if !unlimited {
+		m.ClassificationCeiling = string(ceiling)
+	}
+	return m
+}
+
+// routeCapabilities returns route's declared capabilities, unset features
+// counting as supported, or nil if models.yaml declares none for it.
+func routeCapabilities(modelsCfg *config.ModelsConfig, route config.ProviderRoute) *modelCapabilities {
+	caps, ok := modelsCfg.Capabilities[route.Provider][route.Model]
+	if !ok {
+		return nil
+	}
+	supported := func(feature *bool) bool { return feature == nil || *feature }
+	return &modelCapabilities{
+		Streaming:       supported(caps.Streaming),
+		Tools:           supported(caps.Tools),
+		Vision:          supported(caps.Vision),
+		JSONMode:        supported(caps.JSONMode),
+		MaxOutputTokens: caps.MaxOutputTokens,
+	}
+}
+
+func routePricing(modelsCfg *config.ModelsConfig, route config.ProviderRoute) *modelPricing {
+	price, ok := modelsCfg.Pricing[route.Provider][route.Model]
+	if !ok {
+		return nil
+	}
+	return &modelPricing{Input: price.Input, Output: price.Output}
+}
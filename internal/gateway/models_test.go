@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+)
+
+func newModelsTestHandler(t *testing.T) *Handler {
+	t.Helper()
+	noJSON := false
+	models := &config.ModelsConfig{
+		Models: map[string]config.ModelMapping{
+			"aegis-gpt4": {
+				DisplayName:   "AEGIS GPT-4",
+				ContextWindow: 128000,
+				Created:       time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC),
+				Primary:       config.ProviderRoute{Provider: "anthropic", Model: "claude", ClassificationCeiling: "INTERNAL"},
+				Fallback: []config.ProviderRoute{
+					{Provider: "openai", Model: "gpt-4o", ClassificationCeiling: "CONFIDENTIAL"},
+				},
+			},
+			"local": {
+				Primary: config.ProviderRoute{Provider: "vllm", Model: "llama"},
+			},
+		},
+		Pricing: map[string]map[string]config.PriceEntry{
+			"anthropic": {"claude": {Input: 0.003, Output: 0.015}},
+			"openai":    {"gpt-4o": {Input: 0.0025, Output: 0.01}},
+		},
+		Capabilities: map[string]map[string]config.ModelCapabilities{
+			"anthropic": {"claude": {JSONMode: &noJSON, MaxOutputTokens: 8192}},
+			"openai":    {"gpt-4o": {MaxOutputTokens: 16384}},
+		},
+	}
+	health := router.NewHealthTracker(5, time.Minute)
+	health.Quarantine("openai", "test")
+	return NewHandler(nil, health, func() *config.ModelsConfig { return models }, func() *config.Config { return &config.Config{} },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+}
+
+func modelsRequest(path, id string, allowed []string) *http.Request {
+	req := httptest.NewRequest("GET", path, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", id)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	return req.WithContext(auth.ContextWithAuth(ctx, &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1", AllowedModels: allowed}))
+}
+
+func TestListModels_Enriched(t *testing.T) {
+	h := newModelsTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.ListModels(w, modelsRequest("/v1/models", "", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var resp modelListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Data) != 2 || resp.Data[0].ID != "aegis-gpt4" || resp.Data[1].ID != "local" {
+		t.Fatalf("data = %+v, want aegis-gpt4 then local", resp.Data)
+	}
+
+	m := resp.Data[0]
+	if m.Created != time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("created = %d", m.Created)
+	}
+	if m.DisplayName != "AEGIS GPT-4" || m.ContextWindow != 128000 {
+		t.Errorf("display_name = %q, context_window = %d", m.DisplayName, m.ContextWindow)
+	}
+	if m.ClassificationCeiling != "CONFIDENTIAL" {
+		t.Errorf("classification_ceiling = %q, want the highest route's", m.ClassificationCeiling)
+	}
+	if m.Pricing == nil || m.Pricing.Input != 0.003 || m.Pricing.Output != 0.015 {
+		t.Errorf("pricing = %+v, want the primary's", m.Pricing)
+	}
+	// JSON mode is served by the fallback, so the model offers it.
+	if m.Capabilities == nil || !m.Capabilities.JSONMode || !m.Capabilities.Tools || m.Capabilities.MaxOutputTokens != 16384 {
+		t.Errorf("capabilities = %+v", m.Capabilities)
+	}
+
+	if len(m.Providers) != 2 {
+		t.Fatalf("providers = %+v", m.Providers)
+	}
+	primary, fallback := m.Providers[0], m.Providers[1]
+	if primary.Provider != "anthropic" || primary.Role != "primary" || !primary.Available {
+		t.Errorf("primary = %+v", primary)
+	}
+	if primary.Capabilities == nil || primary.Capabilities.JSONMode {
+		t.Errorf("primary capabilities = %+v, want no json_mode", primary.Capabilities)
+	}
+	if fallback.Provider != "openai" || fallback.Role != "fallback" || fallback.Available {
+		t.Errorf("fallback = %+v, want unavailable openai fallback", fallback)
+	}
+
+	local := resp.Data[1]
+	if local.Created != 0 || local.ClassificationCeiling != "" || local.Pricing != nil || local.Capabilities != nil {
+		t.Errorf("local = %+v, want no extensions beyond its route", local)
+	}
+}
+
+func TestGetModel(t *testing.T) {
+	h := newModelsTestHandler(t)
+
+	w := httptest.NewRecorder()
+	h.GetModel(w, modelsRequest("/v1/models/aegis-gpt4", "aegis-gpt4", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var m modelObject
+	if err := json.Unmarshal(w.Body.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "aegis-gpt4" || m.Object != "model" || len(m.Providers) != 2 {
+		t.Errorf("model = %+v", m)
+	}
+
+	for name, tc := range map[string]struct {
+		id      string
+		allowed []string
+	}{
+		"unknown":    {id: "nope"},
+		"disallowed": {id: "aegis-gpt4", allowed: []string{"local"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.GetModel(w, modelsRequest("/v1/models/"+tc.id, tc.id, tc.allowed))
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want 404", w.Code)
+			}
+		})
+	}
+}
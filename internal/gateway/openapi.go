@@ -0,0 +1,395 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/ratelimit"
+)
+
+// endpointAccess is who may call an endpoint.
+type endpointAccess int
+
+const (
+	accessPublic endpointAccess = iota // no API key
+	accessKey                          // any active API key
+	accessAdmin                        // a key listed in admin.key_ids
+)
+
+// apiEndpoint describes one route for the OpenAPI document. Request and
+// Response name a schema in components.schemas; empty means no body or a
+// free-form object.
+type apiEndpoint struct {
+	Method   string
+	Path     string
+	Summary  string
+	Access   endpointAccess
+	Request  string
+	Response string
+	// RequestType and ContentType are the request and successful response
+	// media types, if not application/json.
+	RequestType string
+	ContentType string
+	// Chat marks endpoints that run the chat completions pipeline and so take
+	// the AEGIS request headers and return the usage headers.
+	Chat bool
+}
+
+// apiEndpoints lists every route the gateway serves. Keep it in step with
+// the router in cmd/gateway and the README's endpoint table.
+var apiEndpoints = []apiEndpoint{
+	{Method: "GET", Path: "/aegis/v1/health", Summary: "Health check", Access: accessPublic},
+	{Method: "GET", Path: "/aegis/v1/error-codes", Summary: "Error code catalog", Access: accessPublic, Response: "ErrorCodeList"},
+	{Method: "GET", Path: "/aegis/v1/openapi.json", Summary: "This document", Access: accessPublic},
+
+	{Method: "POST", Path: "/v1/chat/completions", Summary: "Chat completion (OpenAI-compatible); text/event-stream when stream is true", Access: accessKey,
+		Request: "ChatCompletionRequest", Response: "ChatCompletionResponse", Chat: true},
+	{Method: "GET", Path: "/v1/models", Summary: "Models the key may use, with pricing, capabilities, and routes", Access: accessKey, Response: "ModelList"},
+	{Method: "GET", Path: "/v1/models/{id}", Summary: "One model", Access: accessKey, Response: "Model"},
+	{Method: "POST", Path: "/aegis/v1/compare", Summary: "Send one chat request to several models concurrently", Access: accessKey, Chat: true},
+	{Method: "POST", Path: "/aegis/v1/tokenize", Summary: "Prompt token count, context window fit, and predicted cost of a chat request", Access: accessKey,
+		Request: "ChatCompletionRequest"},
+	{Method: "POST", Path: "/aegis/v1/estimate", Summary: "Route and price a chat request, with the limits it would be held to, without calling the provider", Access: accessKey,
+		Request: "ChatCompletionRequest"},
+	{Method: "GET", Path: "/aegis/v1/me", Summary: "The calling key's limits, current rate limit use, daily budget, and spend today and this month", Access: accessKey},
+	{Method: "POST", Path: "/aegis/v1/batches", Summary: "Submit a JSONL batch of chat requests", Access: accessKey, RequestType: "application/x-ndjson"},
+	{Method: "GET", Path: "/aegis/v1/batches/{id}", Summary: "Batch status and progress", Access: accessKey},
+	{Method: "GET", Path: "/aegis/v1/batches/{id}/results", Summary: "Batch results as JSONL", Access: accessKey, ContentType: "application/x-ndjson"},
+	{Method: "POST", Path: "/aegis/v1/batches/{id}/cancel", Summary: "Cancel a batch", Access: accessKey},
+	{Method: "GET", Path: "/aegis/v1/conversations", Summary: "Most recently active conversations", Access: accessKey},
+	{Method: "GET", Path: "/aegis/v1/conversations/{id}", Summary: "Totals for one conversation", Access: accessKey},
+	{Method: "POST", Path: "/aegis/v1/feedback", Summary: "Report a filter block as a false positive", Access: accessKey},
+
+	{Method: "GET", Path: "/aegis/v1/status", Summary: "Provider, model, config, and filter service status", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/config", Summary: "Effective config and runtime overrides", Access: accessAdmin},
+	{Method: "PATCH", Path: "/aegis/admin/v1/config", Summary: "Override dynamic settings", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/config/overrides", Summary: "Drop runtime overrides", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/config/reload", Summary: "Re-read config files", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/config/versions", Summary: "Recent config versions", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/config/rollback", Summary: "Reinstall a previous config version", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/providers/{name}/secrets/refresh", Summary: "Re-read a provider's secret references and rebuild its adapter if rotated", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/keys", Summary: "API keys, filtered by org, team, and status", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/keys", Summary: "Create an API key; the secret is returned once", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/keys/{id}", Summary: "One API key", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/keys/{id}", Summary: "Revoke an API key", Access: accessAdmin},
+	{Method: "PATCH", Path: "/aegis/admin/v1/keys/{id}/limits", Summary: "Change a key's rate limits", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/orgs", Summary: "Organizations with keys", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/orgs/{org}/suspend", Summary: "Suspend an organization", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/orgs/{org}/suspend", Summary: "Lift an organization's suspension", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/orgs/{org}/teams/{team}/suspend", Summary: "Suspend a team", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/orgs/{org}/teams/{team}/suspend", Summary: "Lift a team's suspension", Access: accessAdmin},
+	{Method: "PUT", Path: "/aegis/admin/v1/keys/{id}/models", Summary: "Replace a key's model rules", Access: accessAdmin, Request: "ModelRules"},
+	{Method: "GET", Path: "/aegis/admin/v1/orgs/{org}/teams/{team}/models", Summary: "A team's model rules", Access: accessAdmin, Response: "ModelRules"},
+	{Method: "PUT", Path: "/aegis/admin/v1/orgs/{org}/teams/{team}/models", Summary: "Replace a team's model rules", Access: accessAdmin,
+		Request: "ModelRules", Response: "ModelRules"},
+	{Method: "DELETE", Path: "/aegis/admin/v1/orgs/{org}/teams/{team}/models", Summary: "Clear a team's model rules", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/providers/quarantine", Summary: "Quarantined providers", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/providers/{name}/quarantine", Summary: "Take a provider out of routing", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/providers/{name}/quarantine", Summary: "Return a provider to routing", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/usage", Summary: "An organization's usage summary", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/dashboard/models", Summary: "Top models by spend", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/dashboard/filters", Summary: "Blocked requests by filter", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/dashboard/providers", Summary: "Provider request outcomes and latency per hour or day", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/dashboard/teams", Summary: "Team usage per hour or day", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/filter-bypasses", Summary: "Active filter bypass grants", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/filter-bypasses", Summary: "Exempt one key from one filter", Access: accessAdmin},
+	{Method: "DELETE", Path: "/aegis/admin/v1/filter-bypasses/{id}", Summary: "Revoke a filter bypass grant", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/cache", Summary: "Response cache state", Access: accessAdmin},
+	{Method: "PUT", Path: "/aegis/admin/v1/cache", Summary: "Switch the response cache on or off on every replica", Access: accessAdmin},
+	{Method: "POST", Path: "/aegis/admin/v1/cache/purge", Summary: "Purge cached responses by model, org, or key hash prefix", Access: accessAdmin},
+	{Method: "GET", Path: "/aegis/admin/v1/feedback", Summary: "Filter block feedback, filtered by org, filter, and status", Access: accessAdmin},
+	{Method: "PATCH", Path: "/aegis/admin/v1/feedback/{id}", Summary: "Accept or reject filter block feedback", Access: accessAdmin},
+}
+
+// chatRequestHeaders are the AEGIS request headers the chat pipeline reads.
+var chatRequestHeaders = []struct{ name, description string }{
+	{"X-Aegis-Project", "Project the request's usage is attributed to."},
+	{"X-Aegis-Prefer-Provider", "Provider to try first when the model has several routes."},
+	{"X-Aegis-Trace-Context", "Trace context to propagate; traceparent is used if absent."},
+	{"traceparent", "W3C trace context."},
+	{ratelimit.HeaderPriority, "interactive (default) or batch; batch traffic is shed first."},
+	{headerConversationID, "Groups the turns of a conversation for totals, budgets, and sticky routing."},
+	{headerAffinityKey, "Keeps requests sharing it on the provider that served the first of them."},
+	{headerIdempotencyKey, "Replays the stored response to a retried non-streaming request."},
+}
+
+// chatResponseHeaders are the headers the chat pipeline sets on success.
+var chatResponseHeaders = []struct{ name, description string }{
+	{"X-Request-ID", "Gateway request ID, also in error bodies as aegis_request_id."},
+	{headerCostUSD, "Estimated cost of the call in USD."},
+	{headerTokensPrompt, "Prompt tokens billed."},
+	{headerTokensCompletion, "Completion tokens billed."},
+	{headerProvider, "Provider that served the request."},
+	{headerModelServed, "Provider model that served the request."},
+	{headerProviderLatencyMs, "Time spent waiting on the provider, in milliseconds."},
+	{headerIdempotentReplayed, "true when the response is a replay for an Idempotency-Key."},
+	{headerCache, "hit when the response came from the response cache, miss otherwise; unset while the cache is off."},
+	{headerContextTruncated, "Number of messages dropped to fit the context window."},
+	{headerRequestedModel, "Model the client asked for when model rules or a block redirect served another."},
+	{headerBlockedBy, "Filter that blocked the request when the org's block response is a message or a redirect."},
+	{"Warning", "Set when the model is deprecated; the body's deprecation field has the details."},
+	{"X-RateLimit-Remaining-Requests", "Requests left in the key's current window."},
+	{"X-RateLimit-Remaining-Tokens", "Tokens left in the key's current window."},
+}
+
+var pathParam = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// OpenAPISpec returns an OpenAPI 3 document describing every gateway
+// endpoint, its AEGIS headers, and the error code catalog, for generating
+// typed clients.
+func OpenAPISpec(version string) map[string]any {
+	paths := map[string]any{}
+	for _, e := range apiEndpoints {
+		item, _ := paths[e.Path].(map[string]any)
+		if item == nil {
+			item = map[string]any{}
+			paths[e.Path] = item
+		}
+		item[strings.ToLower(e.Method)] = e.operation()
+	}
+
+	codes := httputil.Codes()
+	codeNames := make([]string, len(codes))
+	for i, c := range codes {
+		codeNames[i] = c.Code
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "AEGIS AI Gateway",
+			"version":     version,
+			"description": "OpenAI-compatible AI gateway. Errors use OpenAI's envelope; branch on error.code, listed with its HTTP status in x-aegis-error-codes and at /aegis/v1/error-codes.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "http", "scheme": "bearer", "description": "AEGIS API key"},
+			},
+			"schemas": openAPISchemas(codeNames),
+		},
+		"x-aegis-error-codes": codes,
+	}
+}
+
+func (e apiEndpoint) operation() map[string]any {
+	op := map[string]any{
+		"summary":     e.Summary,
+		"operationId": operationID(e.Method, e.Path),
+	}
+	switch e.Access {
+	case accessPublic:
+		op["security"] = []any{}
+	case accessKey:
+		op["security"] = []any{map[string]any{"apiKey": []string{}}}
+	case accessAdmin:
+		op["security"] = []any{map[string]any{"apiKey": []string{}}}
+		op["x-aegis-admin"] = true
+	}
+
+	var params []any
+	for _, m := range pathParam.FindAllStringSubmatch(e.Path, -1) {
+		params = append(params, map[string]any{"name": m[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"}})
+	}
+	if e.Chat {
+		for _, h := range chatRequestHeaders {
+			params = append(params, map[string]any{"name": h.name, "in": "header", "description": h.description, "schema": map[string]any{"type": "string"}})
+		}
+	}
+	if params != nil {
+		op["parameters"] = params
+	}
+
+	requestType := e.RequestType
+	if requestType == "" {
+		requestType = "application/json"
+	}
+	if e.Request != "" {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{requestType: map[string]any{"schema": schemaRef(e.Request)}},
+		}
+	} else if e.RequestType != "" {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{requestType: map[string]any{"schema": map[string]any{"type": "string"}}},
+		}
+	} else if e.Method == "POST" || e.Method == "PUT" || e.Method == "PATCH" {
+		op["requestBody"] = map[string]any{
+			"content": map[string]any{requestType: map[string]any{"schema": map[string]any{"type": "object"}}},
+		}
+	}
+
+	okSchema := map[string]any{"type": "object"}
+	if e.Response != "" {
+		okSchema = schemaRef(e.Response)
+	}
+	contentType := e.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	ok := map[string]any{
+		"description": "OK",
+		"content":     map[string]any{contentType: map[string]any{"schema": okSchema}},
+	}
+	if e.Chat {
+		headers := map[string]any{}
+		for _, h := range chatResponseHeaders {
+			headers[h.name] = map[string]any{"description": h.description, "schema": map[string]any{"type": "string"}}
+		}
+		ok["headers"] = headers
+	}
+	responses := map[string]any{"200": ok}
+	if e.Access != accessPublic {
+		responses["default"] = map[string]any{
+			"description": "Error; see error.code",
+			"content":     map[string]any{"application/json": map[string]any{"schema": schemaRef("Error")}},
+		}
+	}
+	if e.Chat {
+		for status, codes := range errorCodesByStatus() {
+			responses[status] = map[string]any{
+				"description": "Error codes: " + strings.Join(codes, ", "),
+				"content":     map[string]any{"application/json": map[string]any{"schema": schemaRef("Error")}},
+			}
+		}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// errorCodesByStatus groups the catalog's HTTP error codes by status. Stream
+// event codes, sent after a 200, are left out.
+func errorCodesByStatus() map[string][]string {
+	byStatus := map[string][]string{}
+	for _, c := range httputil.Codes() {
+		if c.Status == http.StatusOK {
+			continue
+		}
+		status := fmt.Sprint(c.Status)
+		byStatus[status] = append(byStatus[status], c.Code)
+	}
+	return byStatus
+}
+
+// operationID derives a stable operationId, e.g. "getAegisV1BatchesIdResults".
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return slices.Contains([]rune("/{}-."), r) }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// openAPISchemas describes the request and response bodies. Chat bodies
+// follow OpenAI's; only the fields AEGIS reads or adds are spelled out.
+func openAPISchemas(codes []string) map[string]any {
+	str := map[string]any{"type": "string"}
+	integer := map[string]any{"type": "integer"}
+	number := map[string]any{"type": "number"}
+	boolean := map[string]any{"type": "boolean"}
+	object := map[string]any{"type": "object"}
+	arrayOf := func(items any) map[string]any { return map[string]any{"type": "array", "items": items} }
+	obj := func(required []string, props map[string]any) map[string]any {
+		s := map[string]any{"type": "object", "properties": props}
+		if required != nil {
+			s["required"] = required
+		}
+		return s
+	}
+
+	capabilities := obj(nil, map[string]any{
+		"streaming": boolean, "tools": boolean, "vision": boolean, "json_mode": boolean, "max_output_tokens": integer,
+	})
+	pricing := obj(nil, map[string]any{"input_per_1k_tokens": number, "output_per_1k_tokens": number})
+	deprecation := obj([]string{"model", "sunset_date"}, map[string]any{
+		"model": str, "sunset_date": map[string]any{"type": "string", "format": "date"}, "replacement": str,
+	})
+
+	return map[string]any{
+		"Error": obj([]string{"error"}, map[string]any{
+			"error": obj([]string{"message", "type", "code"}, map[string]any{
+				"message":          str,
+				"type":             str,
+				"code":             map[string]any{"type": "string", "enum": codes},
+				"aegis_request_id": str,
+				"details":          object,
+				"reset_at":         map[string]any{"type": "string", "format": "date-time"},
+			}),
+		}),
+		"ErrorCodeList": obj(nil, map[string]any{
+			"codes": arrayOf(obj(nil, map[string]any{"code": str, "type": str, "status": integer, "description": str})),
+		}),
+		"Message": obj([]string{"role"}, map[string]any{
+			"role":         map[string]any{"type": "string", "enum": []string{"system", "developer", "user", "assistant", "tool", "function"}},
+			"content":      map[string]any{"oneOf": []any{str, arrayOf(object)}, "nullable": true},
+			"name":         str,
+			"tool_calls":   arrayOf(object),
+			"tool_call_id": str,
+		}),
+		"ChatCompletionRequest": obj([]string{"messages"}, map[string]any{
+			"model":                 map[string]any{"type": "string", "description": "Optional when the key or team has a default model."},
+			"messages":              arrayOf(schemaRef("Message")),
+			"stream":                boolean,
+			"stream_options":        object,
+			"temperature":           number,
+			"top_p":                 number,
+			"max_tokens":            integer,
+			"max_completion_tokens": integer,
+			"stop":                  arrayOf(str),
+			"reasoning_effort":      str,
+			"tools":                 arrayOf(object),
+			"tool_choice":           map[string]any{"oneOf": []any{str, object}},
+			"response_format":       object,
+		}),
+		"ChatCompletionResponse": obj(nil, map[string]any{
+			"request_id": str,
+			"model":      str,
+			"provider":   str,
+			"choices": arrayOf(obj(nil, map[string]any{
+				"index": integer, "message": schemaRef("Message"), "finish_reason": str,
+			})),
+			"usage": obj(nil, map[string]any{
+				"prompt_tokens": integer, "completion_tokens": integer, "total_tokens": integer,
+			}),
+			"estimated_cost_usd": number,
+			"filter_actions":     object,
+			"deprecation":        deprecation,
+		}),
+		"Model": obj([]string{"id", "object", "created", "owned_by"}, map[string]any{
+			"id":                     str,
+			"object":                 str,
+			"created":                integer,
+			"owned_by":               str,
+			"display_name":           str,
+			"context_window":         integer,
+			"classification_ceiling": str,
+			"capabilities":           capabilities,
+			"pricing":                pricing,
+			"deprecation":            deprecation,
+			"providers": arrayOf(obj(nil, map[string]any{
+				"provider":               str,
+				"model":                  str,
+				"role":                   map[string]any{"type": "string", "enum": []string{"primary", "fallback"}},
+				"classification_ceiling": str,
+				"available":              boolean,
+				"capabilities":           capabilities,
+				"pricing":                pricing,
+			})),
+		}),
+		"ModelList": obj(nil, map[string]any{"object": str, "data": arrayOf(schemaRef("Model"))}),
+		"ModelRules": obj(nil, map[string]any{
+			"default_model":  str,
+			"model_rewrites": map[string]any{"type": "object", "additionalProperties": str},
+		}),
+	}
+}
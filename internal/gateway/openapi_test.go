@@ -0,0 +1,94 @@
+package gateway
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	data, err := json.Marshal(OpenAPISpec("1.2.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name string `json:"name"`
+				In   string `json:"in"`
+			} `json:"parameters"`
+			Responses map[string]struct {
+				Headers map[string]any `json:"headers"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+		ErrorCodes []httputil.ErrorCode `json:"x-aegis-error-codes"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") || doc.Info.Version != "1.2.3" {
+		t.Errorf("openapi = %q, version = %q", doc.OpenAPI, doc.Info.Version)
+	}
+	if len(doc.ErrorCodes) != len(httputil.Codes()) {
+		t.Errorf("x-aegis-error-codes has %d codes, catalog %d", len(doc.ErrorCodes), len(httputil.Codes()))
+	}
+
+	// Every $ref resolves.
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.IndexByte(ref, '"')]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("unresolved schema reference %q", name)
+		}
+	}
+
+	ids := map[string]bool{}
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			if ids[op.OperationID] {
+				t.Errorf("duplicate operationId %q", op.OperationID)
+			}
+			ids[op.OperationID] = true
+			for _, seg := range strings.Split(path, "/") {
+				if !strings.HasPrefix(seg, "{") {
+					continue
+				}
+				name := strings.Trim(seg, "{}")
+				found := false
+				for _, p := range op.Parameters {
+					found = found || (p.In == "path" && p.Name == name)
+				}
+				if !found {
+					t.Errorf("%s %s: path parameter %q not declared", method, path, name)
+				}
+			}
+		}
+	}
+
+	chat := doc.Paths["/v1/chat/completions"]["post"]
+	if _, ok := chat.Responses["200"].Headers[headerCostUSD]; !ok {
+		t.Errorf("chat completions should document %s", headerCostUSD)
+	}
+	if _, ok := chat.Responses["451"]; !ok {
+		t.Error("chat completions should document the content filter status")
+	}
+	var headers []string
+	for _, p := range chat.Parameters {
+		headers = append(headers, p.Name)
+	}
+	if !strings.Contains(strings.Join(headers, ","), headerConversationID) {
+		t.Errorf("chat completions headers = %v, want %s", headers, headerConversationID)
+	}
+	if _, ok := doc.Paths["/v1/models/{id}"]["get"]; !ok {
+		t.Error("GET /v1/models/{id} missing")
+	}
+}
@@ -0,0 +1,18 @@
+package gateway
+
+import (
+	"context"
+
+	"github.com/af-corp/aegis-gateway/internal/filter"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// evaluatePolicy runs the OPA policy through the filter chain, so a failed
+// evaluation fails open or closed like any other filter's.
+func (h *Handler) evaluatePolicy(ctx context.Context, req *types.AegisRequest) filter.Result {
+	chain := h.filterChain
+	if chain == nil {
+		chain = filter.NewChain()
+	}
+	return chain.Evaluate(ctx, h.policyEvaluator, req)
+}
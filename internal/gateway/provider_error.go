@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+// writeProviderError maps a provider 4xx onto the OpenAI error format so
+// clients can tell a bad prompt from an outage. A provider 429 passes its
+// retry time on as Retry-After. It reports false, writing
+// nothing, when err is not a provider error or the provider returned a 5xx,
+// which callers keep reporting as the gateway's own failure.
+func writeProviderError(w http.ResponseWriter, reqID string, err error) bool {
+	var pe *adapters.ProviderError
+	if !errors.As(err, &pe) || pe.StatusCode < 400 || pe.StatusCode >= 500 {
+		return false
+	}
+
+	slog.Warn("provider rejected request",
+		"request_id", reqID,
+		"provider", pe.Provider,
+		"status", pe.StatusCode,
+		"type", pe.Type,
+		"code", pe.Code,
+		"message", pe.Message,
+	)
+
+	status, errType, code, message := mapProviderError(pe)
+	if status == http.StatusTooManyRequests && !pe.RetryAt.IsZero() {
+		httputil.WriteRetryableCode(w, reqID, httputil.CodeProviderRateLimited, message,
+			map[string]any{"provider": pe.Provider}, pe.RetryAt)
+		return true
+	}
+	httputil.WriteError(w, reqID, status, errType, code, message)
+	return true
+}
+
+// mapProviderError returns the status, error type, code, and message sent to
+// the client for a provider 4xx.
+func mapProviderError(pe *adapters.ProviderError) (int, string, string, string) {
+	message := pe.Message
+	if message == "" {
+		message = fmt.Sprintf("Provider returned status %d", pe.StatusCode)
+	}
+
+	switch pe.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		// The provider rejected the gateway's credentials, not the caller's;
+		// passing 401 through would send clients chasing their own API key.
+		return http.StatusBadGateway, "server_error", "provider_auth_error",
+			"Provider rejected the gateway's credentials"
+	case http.StatusTooManyRequests:
+		return http.StatusTooManyRequests, "rate_limit_error", "provider_rate_limited", message
+	}
+
+	code := pe.Code
+	if code == "" {
+		code = inferProviderErrorCode(pe)
+	}
+	return pe.StatusCode, "invalid_request_error", code, message
+}
+
+// inferProviderErrorCode derives an OpenAI-style code for providers, such as
+// Anthropic, whose errors carry only a type and message.
+func inferProviderErrorCode(pe *adapters.ProviderError) string {
+	msg := strings.ToLower(pe.Message)
+	switch {
+	case strings.Contains(msg, "context length"), strings.Contains(msg, "context window"),
+		strings.Contains(msg, "prompt is too long"), strings.Contains(msg, "maximum context"):
+		return "context_length_exceeded"
+	case strings.Contains(msg, "content policy"), strings.Contains(msg, "content management policy"),
+		strings.Contains(msg, "safety system"):
+		return "content_policy_violation"
+	}
+	switch pe.StatusCode {
+	case http.StatusNotFound:
+		return "model_not_found"
+	case http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	default:
+		return "invalid_request"
+	}
+}
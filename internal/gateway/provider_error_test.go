@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestMapProviderError(t *testing.T) {
+	tests := []struct {
+		name       string
+		pe         *adapters.ProviderError
+		wantStatus int
+		wantType   string
+		wantCode   string
+		wantMsg    string
+	}{
+		{
+			name:       "openai context length keeps provider code",
+			pe:         &adapters.ProviderError{StatusCode: 400, Code: "context_length_exceeded", Message: "This model's maximum context length is 8192 tokens."},
+			wantStatus: 400, wantType: "invalid_request_error", wantCode: "context_length_exceeded",
+			wantMsg: "This model's maximum context length is 8192 tokens.",
+		},
+		{
+			name:       "anthropic context length inferred",
+			pe:         &adapters.ProviderError{StatusCode: 400, Type: "invalid_request_error", Message: "prompt is too long: 210000 tokens > 200000 maximum"},
+			wantStatus: 400, wantType: "invalid_request_error", wantCode: "context_length_exceeded",
+			wantMsg: "prompt is too long: 210000 tokens > 200000 maximum",
+		},
+		{
+			name:       "azure content policy inferred",
+			pe:         &adapters.ProviderError{StatusCode: 400, Message: "The response was filtered due to the prompt triggering Azure OpenAI's content management policy."},
+			wantStatus: 400, wantType: "invalid_request_error", wantCode: "content_policy_violation",
+		},
+		{
+			name:       "unknown model",
+			pe:         &adapters.ProviderError{StatusCode: 404, Message: "model not found"},
+			wantStatus: 404, wantType: "invalid_request_error", wantCode: "model_not_found", wantMsg: "model not found",
+		},
+		{
+			name:       "provider credentials are not the caller's",
+			pe:         &adapters.ProviderError{StatusCode: 401, Message: "Incorrect API key provided: sk-abc"},
+			wantStatus: 502, wantType: "server_error", wantCode: "provider_auth_error",
+			wantMsg: "Provider rejected the gateway's credentials",
+		},
+		{
+			name:       "rate limited",
+			pe:         &adapters.ProviderError{StatusCode: 429, Message: "Rate limit reached"},
+			wantStatus: 429, wantType: "rate_limit_error", wantCode: "provider_rate_limited", wantMsg: "Rate limit reached",
+		},
+		{
+			name:       "empty body",
+			pe:         &adapters.ProviderError{StatusCode: 422},
+			wantStatus: 422, wantType: "invalid_request_error", wantCode: "invalid_request",
+			wantMsg: "Provider returned status 422",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errType, code, msg := mapProviderError(tt.pe)
+			if status != tt.wantStatus || errType != tt.wantType || code != tt.wantCode {
+				t.Errorf("got %d/%s/%s, want %d/%s/%s", status, errType, code, tt.wantStatus, tt.wantType, tt.wantCode)
+			}
+			if tt.wantMsg != "" && msg != tt.wantMsg {
+				t.Errorf("message = %q, want %q", msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestWriteProviderError_IgnoresServerErrors(t *testing.T) {
+	w := httptest.NewRecorder()
+	if writeProviderError(w, "req-1", &adapters.ProviderError{StatusCode: 503}) {
+		t.Error("5xx should be left to the caller")
+	}
+	if writeProviderError(w, "req-1", errors.New("dial tcp: connection refused")) {
+		t.Error("non-provider errors should be left to the caller")
+	}
+	wrapped := fmt.Errorf("transform: %w", &adapters.ProviderError{StatusCode: 400, Message: "bad"})
+	if !writeProviderError(w, "req-1", wrapped) || w.Code != http.StatusBadRequest {
+		t.Errorf("expected wrapped 400 to be written, got %d", w.Code)
+	}
+}
+
+func TestHandleStream_PassesThroughProviderBadRequest(t *testing.T) {
+	adapter := &mockStreamAdapter{
+		name: "openai",
+		response: &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body: io.NopCloser(bytes.NewBufferString(
+				`{"error":{"message":"This model's maximum context length is 8192 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`)),
+			Header: make(http.Header),
+		},
+	}
+	sh := NewStreamingHandler(&Handler{metrics: getTestMetrics()}, StreamingConfig{
+		PerChunkTimeout: 5 * time.Second,
+		TotalTimeout:    30 * time.Second,
+		BufferSize:      64 * 1024,
+		MaxBufferSize:   1024 * 1024,
+	})
+	w := httptest.NewRecorder()
+	providerReq, _ := http.NewRequest("POST", "http://mock-provider.com", nil)
+	sh.HandleStream(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), "req-1", providerReq, adapter,
+		"gpt-4", &auth.AuthInfo{OrganizationID: "org"}, &types.AegisRequest{Model: "gpt-4", Stream: true})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	var body httputil.APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Code != "context_length_exceeded" || body.Error.Type != "invalid_request_error" {
+		t.Errorf("unexpected error body: %+v", body.Error)
+	}
+}
@@ -1,8 +1,7 @@
 package gateway
 
 import (
-	"encoding/json"
-	"io"
+	"errors"
 	"log/slog"
 	"net/http"
 	"time"
@@ -11,13 +10,16 @@ import (
 	"github.com/af-corp/aegis-gateway/internal/filter"
 	"github.com/af-corp/aegis-gateway/internal/httputil"
 	"github.com/af-corp/aegis-gateway/internal/types"
+	"github.com/af-corp/aegis-gateway/internal/validation"
 )
 
 // RequestProcessor handles request parsing, validation, and enrichment.
 type RequestProcessor struct {
 	validator   interface{ Validate(*types.AegisRequest) error }
 	auditLogger AuditLogger
-	metrics     interface{ RecordFilterAction(string, string) }
+	metrics     interface{ RecordFilterAction(string, string, string) }
+	limits      validation.SizeLimits
+	decode      validation.DecodeOptions
 }
 
 // ParsedRequest contains a parsed and validated request.
@@ -36,16 +38,23 @@ func (rp *RequestProcessor) ParseAndValidateRequest(
 ) (*ParsedRequest, error) {
 	receivedAt := time.Now()
 
-	// Parse request body
-	body, err := io.ReadAll(r.Body)
+	// Parse request body, bounded by the configured size limits
+	body, err := validation.ReadBody(nil, r, rp.limits)
 	if err != nil {
+		var tooLarge *validation.TooLargeError
+		if errors.As(err, &tooLarge) {
+			return nil, httputil.NewHTTPError(http.StatusRequestEntityTooLarge, tooLarge.Message)
+		}
 		return nil, httputil.NewHTTPError(http.StatusBadRequest, "Failed to read request body")
 	}
 	defer func() { _ = r.Body.Close() }()
 
 	var aegisReq types.AegisRequest
-	if err := json.Unmarshal(body, &aegisReq); err != nil {
-		return nil, httputil.NewHTTPError(http.StatusBadRequest, "Invalid JSON: "+err.Error())
+	if err := validation.DecodeJSON(body, &aegisReq, rp.decode); err != nil {
+		return nil, decodeError(err)
+	}
+	if err := validation.CheckMessageSizes(aegisReq.Messages, rp.limits); err != nil {
+		return nil, httputil.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
 	}
 
 	// Enrich with auth context
@@ -61,6 +70,11 @@ func (rp *RequestProcessor) ParseAndValidateRequest(
 	aegisReq.Project = r.Header.Get("X-Aegis-Project")
 	aegisReq.PreferProvider = r.Header.Get("X-Aegis-Prefer-Provider")
 	aegisReq.TraceContext = r.Header.Get("X-Aegis-Trace-Context")
+	if aegisReq.TraceContext == "" {
+		aegisReq.TraceContext = r.Header.Get("traceparent")
+	}
+
+	aegisReq.Model = authInfo.ModelRules.Resolve(aegisReq.Model)
 
 	// Validate request
 	if err := rp.validateRequest(&aegisReq); err != nil {
@@ -84,7 +98,7 @@ func (rp *RequestProcessor) validateRequest(req *types.AegisRequest) error {
 				"org_id", req.OrganizationID,
 				"error", err.Error(),
 			)
-			return httputil.NewHTTPError(http.StatusBadRequest, err.Error())
+			return validationError(err)
 		}
 	} else {
 		// Fallback to basic validation if validator not configured
@@ -102,7 +116,9 @@ func (rp *RequestProcessor) validateRequest(req *types.AegisRequest) error {
 type FilterProcessor struct {
 	filterChain *filter.Chain
 	auditLogger AuditLogger
-	metrics     interface{ RecordFilterAction(string, string) }
+	metrics     interface{ RecordFilterAction(string, string, string) }
+	// blockDetails lists a block's findings in its error.
+	blockDetails bool
 }
 
 // FilterResult contains the result of content filtering.
@@ -140,21 +156,31 @@ func (fp *FilterProcessor) RunFilters(
 				authInfo.KeyID,
 				blocked.FilterName,
 				blocked.Message,
+				blocked.Detections,
+				blocked.Score,
 				r.RemoteAddr,
 			)
 		}
 		
 		if fp.metrics != nil {
-			fp.metrics.RecordFilterAction(blocked.FilterName, string(blocked.Action))
+			fp.metrics.RecordFilterAction(blocked.FilterName, authInfo.OrganizationID, string(blocked.Action))
 		}
 		
-		return nil, httputil.NewHTTPError(http.StatusForbidden, blocked.Message)
+		return nil, filterBlockError(*blocked, fp.blockDetails)
 	}
 
-	// Record flagged filters
+	// Record flagged and bypassed filters
 	for _, fr := range results {
 		if fr.Action == filter.ActionFlag && fp.metrics != nil {
-			fp.metrics.RecordFilterAction(fr.FilterName, "flag")
+			fp.metrics.RecordFilterAction(fr.FilterName, authInfo.OrganizationID, "flag")
+		}
+		if fr.Action == filter.ActionBypass {
+			if fp.auditLogger != nil {
+				fp.auditLogger.LogFilterBypass(aegisReq.RequestID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, fr.FilterName, fr.GrantID, r.RemoteAddr)
+			}
+			if fp.metrics != nil {
+				fp.metrics.RecordFilterAction(fr.FilterName, authInfo.OrganizationID, string(filter.ActionBypass))
+			}
 		}
 	}
 
@@ -0,0 +1,135 @@
+package gateway
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// headerCache reports whether a response came from the response cache
+// ("hit") or the provider ("miss"). It is only set while the cache is on,
+// both in config and at runtime.
+const headerCache = "X-Aegis-Cache"
+
+// responseCacheTimeout bounds storing a response, which the client waits on.
+const responseCacheTimeout = 2 * time.Second
+
+// ResponseCache keeps chat completions for requests with the same content.
+// It is satisfied by *cache.ResponseStore.
+type ResponseCache interface {
+	Get(ctx context.Context, org, hash string) (*cache.CachedResponse, error)
+	Put(ctx context.Context, org, hash string, entry cache.CachedResponse) error
+}
+
+// SetResponseCache enables answering repeated chat completions from c while
+// response_cache.enabled is set.
+func (h *Handler) SetResponseCache(c ResponseCache) {
+	h.responseCache = c
+}
+
+func (h *Handler) responseCacheEnabled() bool {
+	return h.responseCache != nil && h.cfg != nil && h.cfg().ResponseCache.Enabled
+}
+
+// responseCacheHash keys a cache entry on the request's content, the
+// classification it was sent at, and route, the provider and provider model
+// it was routed to, so a request is only answered with what the same route
+// would have produced for it.
+func responseCacheHash(req *types.AegisRequest, route string) string {
+	sum := sha256.Sum256([]byte(req.RequestHash + "\x00" + string(req.Classification) + "\x00" + route))
+	return hex.EncodeToString(sum[:])
+}
+
+// serveCached answers req, already admitted by routing and policy for the
+// caller's key and sent to route, from the response cache, replayed as SSE
+// when the client asked for a stream, and reports whether it did. Hits
+// still go through response hooks and guardrails, since entries hold what
+// the provider returned. Nothing is spent on a hit, so it reports no cost.
+func (h *Handler) serveCached(w http.ResponseWriter, r *http.Request, reqID string, authInfo *auth.AuthInfo, route string, req *types.AegisRequest) bool {
+	if !h.responseCacheEnabled() {
+		return false
+	}
+	entry, err := h.responseCache.Get(r.Context(), authInfo.OrganizationID, responseCacheHash(req, route))
+	if errors.Is(err, cache.ErrResponseCacheDisabled) {
+		// Switched off through the admin API.
+		return false
+	}
+	if err != nil {
+		// Like idempotency, the cache is a convenience; an unreachable
+		// store must not fail the request.
+		slog.Warn("response cache unavailable, serving request without it",
+			"request_id", reqID,
+			"error", err,
+		)
+	}
+	// Model rules can send the same content to different models.
+	if entry == nil || entry.Response == nil || entry.Model != req.Model {
+		w.Header().Set(headerCache, "miss")
+		return false
+	}
+
+	resp := entry.Response
+	resp.RequestID = reqID
+	resp.EstimatedCostUSD = 0
+	if transform := h.responseTransform(r.Context(), req); transform != nil {
+		if err := transform(resp); err != nil {
+			slog.Error("response hook failed", "request_id", reqID, "org_id", authInfo.OrganizationID, "error", err)
+			httputil.WriteCode(w, reqID, httputil.CodeHookFailed, "Response transformation failed", nil)
+			return true
+		}
+	}
+	slog.Info("serving cached response",
+		"request_id", reqID,
+		"org_id", authInfo.OrganizationID,
+		"model", req.Model,
+		"cached_at", entry.CreatedAt,
+		"stream", req.Stream,
+	)
+	h.settleTokens(r.Context(), reqID, 0)
+
+	w.Header().Set(headerCache, "hit")
+	if req.Stream {
+		h.streamingHandler.ReplayCached(w, r, reqID, resp)
+		return true
+	}
+	setUsageHeaders(w, resp)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+	return true
+}
+
+// storeCached caches resp for later requests with req's content sent for
+// model, the model the client asked for before routing. Only complete text
+// answers are kept: tool calls and truncated or filtered output are left
+// for the provider to produce again.
+func (h *Handler) storeCached(ctx context.Context, reqID string, authInfo *auth.AuthInfo, model, route string, req *types.AegisRequest, resp *types.AegisResponse) {
+	if !h.responseCacheEnabled() || len(resp.Choices) == 0 {
+		return
+	}
+	for _, c := range resp.Choices {
+		if c.FinishReason != "stop" || len(c.Message.ToolCalls) > 0 {
+			return
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), responseCacheTimeout)
+	defer cancel()
+	err := h.responseCache.Put(ctx, authInfo.OrganizationID, responseCacheHash(req, route), cache.CachedResponse{
+		Model:     model,
+		KeyHash:   authInfo.KeyHash,
+		CreatedAt: time.Now().UTC(),
+		Response:  resp,
+	})
+	if err != nil && !errors.Is(err, cache.ErrResponseCacheDisabled) {
+		slog.Warn("failed to cache response", "request_id", reqID, "error", err)
+	}
+}
@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/filter/policy"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+// memoryResponseCache is an in-process ResponseCache.
+type memoryResponseCache struct {
+	entries  map[string]cache.CachedResponse
+	disabled bool
+}
+
+func (c *memoryResponseCache) Get(_ context.Context, org, hash string) (*cache.CachedResponse, error) {
+	if c.disabled {
+		return nil, cache.ErrResponseCacheDisabled
+	}
+	entry, ok := c.entries[org+"/"+hash]
+	if !ok {
+		return nil, nil
+	}
+	// Copy the response as a store that decodes JSON would.
+	resp := *entry.Response
+	resp.Choices = append(resp.Choices[:0:0], resp.Choices...)
+	entry.Response = &resp
+	return &entry, nil
+}
+
+func (c *memoryResponseCache) Put(_ context.Context, org, hash string, entry cache.CachedResponse) error {
+	if c.disabled {
+		return cache.ErrResponseCacheDisabled
+	}
+	entry.OrganizationID = org
+	c.entries[org+"/"+hash] = entry
+	return nil
+}
+
+// newCachingHandler returns a handler with the response cache on in front of
+// a provider that answers "Hello world" and counts its calls.
+func newCachingHandler(t *testing.T, evaluator *policy.Evaluator) (*Handler, *memoryResponseCache, *config.Config, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"c1","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hello world"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`))
+	}))
+	t.Cleanup(provider.Close)
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client()))
+	models := &config.ModelsConfig{Models: map[string]config.ModelMapping{
+		"smart": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	}}
+	cfg := config.DefaultConfig()
+	cfg.ResponseCache.Enabled = true
+	cfg.ResponseCache.ReplayChunkSize = 5
+	h := NewHandler(registry, nil, func() *config.ModelsConfig { return models }, func() *config.Config { return cfg },
+		nil, evaluator, nil, nil, nil, nil, nil, nil, nil)
+	store := &memoryResponseCache{entries: map[string]cache.CachedResponse{}}
+	h.SetResponseCache(store)
+	return h, store, cfg, calls
+}
+
+func TestChatCompletions_ResponseCache(t *testing.T) {
+	h, store, cfg, calls := newCachingHandler(t, nil)
+
+	info := &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1", KeyHash: "abc123"}
+	const body = `{"model":"smart","messages":[{"role":"user","content":"Hi"}]}`
+
+	w := postAs(h.ChatCompletions, "/v1/chat/completions", info, body, nil)
+	if w.Code != http.StatusOK || w.Header().Get(headerCache) != "miss" {
+		t.Fatalf("first request: status = %d, %s = %q: %s", w.Code, headerCache, w.Header().Get(headerCache), w.Body.String())
+	}
+	if len(store.entries) != 1 {
+		t.Fatalf("the completion should be cached, have %d entries", len(store.entries))
+	}
+	for _, entry := range store.entries {
+		if entry.Model != "smart" || entry.KeyHash != "abc123" || entry.OrganizationID != "org-1" {
+			t.Errorf("entry should record the requested model, key hash, and org for purging: %+v", entry)
+		}
+	}
+
+	w = postAs(h.ChatCompletions, "/v1/chat/completions", info, body, nil)
+	if w.Code != http.StatusOK || w.Header().Get(headerCache) != "hit" || !strings.Contains(w.Body.String(), "Hello world") {
+		t.Fatalf("repeat: status = %d, %s = %q: %s", w.Code, headerCache, w.Header().Get(headerCache), w.Body.String())
+	}
+	if w.Header().Get(headerCostUSD) != "0.000000" {
+		t.Errorf("a hit spends nothing, got cost %s", w.Header().Get(headerCostUSD))
+	}
+
+	w = postAs(h.ChatCompletions, "/v1/chat/completions", info,
+		`{"model":"smart","stream":true,"messages":[{"role":"user","content":"Hi"}]}`, nil)
+	stream := w.Body.String()
+	if w.Header().Get(headerCache) != "hit" || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream: %s = %q, Content-Type = %q", headerCache, w.Header().Get(headerCache), w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(stream, `"content":"Hello"`) || !strings.Contains(stream, `"content":" worl"`) ||
+		!strings.HasSuffix(stream, "data: [DONE]\n\n") {
+		t.Errorf("the cached answer should be replayed as chunks of 5 runes: %s", stream)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("hits must not call the provider, got %d calls", calls.Load())
+	}
+
+	other := &auth.AuthInfo{OrganizationID: "org-2", KeyID: "key-2"}
+	if w = postAs(h.ChatCompletions, "/v1/chat/completions", other, body, nil); w.Header().Get(headerCache) != "miss" {
+		t.Errorf("entries must not be shared between organizations, got %q", w.Header().Get(headerCache))
+	}
+
+	store.disabled = true
+	if w = postAs(h.ChatCompletions, "/v1/chat/completions", info, body, nil); w.Header().Get(headerCache) != "" || calls.Load() != 3 {
+		t.Errorf("a cache switched off at runtime should be bypassed, got %s = %q after %d calls", headerCache, w.Header().Get(headerCache), calls.Load())
+	}
+
+	store.disabled = false
+	cfg.ResponseCache.Enabled = false
+	if w = postAs(h.ChatCompletions, "/v1/chat/completions", info, body, nil); w.Header().Get(headerCache) != "" || calls.Load() != 4 {
+		t.Errorf("a disabled cache should be bypassed, got %s = %q after %d calls", headerCache, w.Header().Get(headerCache), calls.Load())
+	}
+}
+
+func TestChatCompletions_ResponseCacheRespectsAccessControl(t *testing.T) {
+	evaluator := policy.NewEvaluator(func() config.PolicyFilterConfig { return config.PolicyFilterConfig{Enabled: true} })
+	err := evaluator.LoadFromModules(map[string]string{"test.rego": `package aegis.policy
+import rego.v1
+default allow := true
+default reason := ""
+allow := false if input.user.team == "contractors"
+reason := "contractors may not use smart" if input.user.team == "contractors"
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, _, _, calls := newCachingHandler(t, evaluator)
+	const body = `{"model":"smart","messages":[{"role":"user","content":"Hi"}]}`
+
+	staff := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "search", KeyID: "key-1", MaxClassification: "INTERNAL"}
+	if w := postAs(h.ChatCompletions, "/v1/chat/completions", staff, body, nil); w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+
+	contractor := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "contractors", KeyID: "key-2", MaxClassification: "INTERNAL"}
+	w := postAs(h.ChatCompletions, "/v1/chat/completions", contractor, body, nil)
+	if w.Code == http.StatusOK || w.Header().Get(headerCache) == "hit" || strings.Contains(w.Body.String(), "Hello world") {
+		t.Fatalf("a key the policy denies must not get another key's cached answer: %d %s", w.Code, w.Body.String())
+	}
+
+	restricted := &auth.AuthInfo{OrganizationID: "org-1", TeamID: "search", KeyID: "key-3", MaxClassification: "RESTRICTED"}
+	if w := postAs(h.ChatCompletions, "/v1/chat/completions", restricted, body, nil); w.Header().Get(headerCache) != "miss" {
+		t.Errorf("entries must not be shared across classifications, got %q", w.Header().Get(headerCache))
+	}
+	if calls.Load() != 2 {
+		t.Errorf("provider calls = %d, want 2", calls.Load())
+	}
+}
@@ -2,6 +2,8 @@ package gateway
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -43,10 +45,7 @@ func (rp *RouterProcessor) RouteToProvider(
 		string(aegisReq.Classification),
 	)
 	if err != nil {
-		return nil, httputil.NewHTTPError(
-			http.StatusServiceUnavailable,
-			"No provider available: "+err.Error(),
-		)
+		return nil, routeError(aegisReq.Model, err)
 	}
 
 	// Override model with the provider-specific model name
@@ -62,9 +61,9 @@ func (rp *RouterProcessor) RouteToProvider(
 			"error", err,
 			"provider", adapter.Name(),
 		)
-		return nil, httputil.NewHTTPError(
-			http.StatusInternalServerError,
-			"Failed to prepare provider request",
+		return nil, httputil.NewCodedError(httputil.CodeInvalidRequest,
+			fmt.Sprintf("Request cannot be sent to provider %s: %v", adapter.Name(), err),
+			map[string]any{"provider": adapter.Name()},
 		)
 	}
 
@@ -113,6 +112,10 @@ func (pe *ProviderExecutor) ExecuteProviderRequest(
 	}
 
 	if err != nil {
+		if clientGone(ctx) {
+			// The caller is gone; this is not the provider's failure.
+			return nil, ctx.Err()
+		}
 		slog.Error("provider request failed",
 			"error", err,
 			"provider", adapter.Name(),
@@ -120,10 +123,12 @@ func (pe *ProviderExecutor) ExecuteProviderRequest(
 		if pe.healthTracker != nil {
 			pe.healthTracker.RecordFailure(adapter.Name())
 		}
-		return nil, httputil.NewHTTPError(
-			http.StatusServiceUnavailable,
-			"Provider request failed",
-		)
+		// Retries exhausted on a 4xx such as 429 still carry the provider's answer.
+		if providerResp != nil && providerResp.StatusCode < 500 {
+			defer func() { _ = providerResp.Body.Close() }()
+			return nil, adapters.ReadProviderError(adapter.Name(), providerResp)
+		}
+		return nil, providerFailure(adapter.Name(), err)
 	}
 
 	if pe.healthTracker != nil {
@@ -144,10 +149,15 @@ func (pe *ProviderExecutor) TransformProviderResponse(
 		slog.Error("failed to transform response",
 			"error", err,
 			"provider", adapter.Name(),
+			"provider_request_id", adapters.ProviderRequestID(providerResp),
 		)
-		return nil, httputil.NewHTTPError(
-			http.StatusInternalServerError,
+		var providerErr *adapters.ProviderError
+		if errors.As(err, &providerErr) && providerErr.StatusCode < 500 {
+			return nil, providerErr
+		}
+		return nil, httputil.NewCodedError(httputil.CodeProviderError,
 			"Failed to process provider response",
+			map[string]any{"provider": adapter.Name()},
 		)
 	}
 	return aegisResp, nil
@@ -0,0 +1,33 @@
+package gateway
+
+import "hash/fnv"
+
+// sampled reports whether reqID falls within telemetry.trace_sample_rate,
+// which thins the verbose per-request logs and payload archival of busy
+// deployments. Metrics, usage records, and warnings are never sampled.
+func (h *Handler) sampled(reqID string) bool {
+	if h.cfg == nil {
+		return true
+	}
+	return sampleRequest(reqID, h.cfg().Telemetry.TraceSampleRate)
+}
+
+// sampleRequest hashes reqID rather than drawing at random, so a request's
+// log lines and archive are kept or dropped together.
+func sampleRequest(reqID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(reqID))
+	// FNV alone leaves sequential IDs clustered; mix the bits before
+	// scaling the hash to [0, 1).
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return float64(h>>11)/(1<<53) < rate
+}
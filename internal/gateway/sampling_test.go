@@ -0,0 +1,26 @@
+package gateway
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSampleRequest(t *testing.T) {
+	if !sampleRequest("req-1", 1) || sampleRequest("req-1", 0) {
+		t.Fatal("rates 1 and 0 should keep and drop every request")
+	}
+
+	kept := 0
+	for i := range 10000 {
+		id := fmt.Sprintf("req-%d", i)
+		if sampleRequest(id, 0.1) {
+			kept++
+		}
+		if sampleRequest(id, 0.1) != sampleRequest(id, 0.1) {
+			t.Fatalf("sampling %s is not deterministic", id)
+		}
+	}
+	if kept < 800 || kept > 1200 {
+		t.Errorf("expected about 1000 of 10000 requests kept at 0.1, got %d", kept)
+	}
+}
@@ -0,0 +1,37 @@
+package gateway
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+)
+
+// checkAccessWindows rejects the request with 503 when a maintenance window
+// or office hours close model to the caller's organization, with Retry-After
+// set to when it reopens. It reports whether the response has been written.
+func (h *Handler) checkAccessWindows(w http.ResponseWriter, reqID string, authInfo *auth.AuthInfo, model string) bool {
+	now := time.Now()
+	var closed *router.AccessWindowError
+	if !errors.As(router.CheckAccessWindows(h.cfg().Routing.AccessWindows, authInfo.OrganizationID, model, now), &closed) {
+		return false
+	}
+	slog.Info("request rejected by access window",
+		"request_id", reqID,
+		"window", closed.Window,
+		"type", closed.Type,
+		"model", model,
+		"org_id", authInfo.OrganizationID,
+	)
+	code := httputil.CodeMaintenanceWindow
+	if closed.Type == config.AccessWindowOfficeHours {
+		code = httputil.CodeOutsideOfficeHours
+	}
+	httputil.WriteRetryableCode(w, reqID, code, closed.Message, nil, closed.Until)
+	return true
+}
@@ -0,0 +1,47 @@
+package gateway
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func TestChatCompletions_MaintenanceWindow(t *testing.T) {
+	cfg := &config.Config{Routing: config.RoutingConfig{AccessWindows: []config.AccessWindowConfig{{
+		Name:     "db-upgrade",
+		Type:     config.AccessWindowMaintenance,
+		Models:   []string{"gpt-4o"},
+		StartsAt: time.Now().Add(-time.Hour),
+		EndsAt:   time.Now().Add(time.Hour),
+	}}}}
+	h := NewHandler(nil, nil, func() *config.ModelsConfig { return &config.ModelsConfig{} }, func() *config.Config { return cfg },
+		nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	body := `{"model": "gpt-4o", "messages": [{"role": "user", "content": "Hello"}]}`
+	req := httptest.NewRequest("POST", "/v1/chat/completions", bytes.NewBufferString(body))
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{OrganizationID: "org-1", KeyID: "key-1"}))
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "test-123")
+	h.ChatCompletions(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "maintenance_window") || !strings.Contains(w.Body.String(), "db-upgrade") {
+		t.Errorf("expected maintenance_window error naming the window, got %s", w.Body.String())
+	}
+	// Retry-After is jittered by up to a tenth past the reopening.
+	if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || secs < 3500 || secs > 3960 {
+		t.Errorf("expected Retry-After of about an hour, got %q", w.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(w.Body.String(), `"reset_at"`) {
+		t.Errorf("expected reset_at in error body, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const (
+	headerAffinityKey = "X-Aegis-Affinity-Key"
+
+	stickyLookupTimeout = 50 * time.Millisecond
+	stickyRecordTimeout = 2 * time.Second
+)
+
+// StickyRouteStore remembers which provider served each conversation or
+// affinity key. It is satisfied by *cache.StickyRouteStore.
+type StickyRouteStore interface {
+	Provider(ctx context.Context, org, model, key string) (string, error)
+	Record(ctx context.Context, org, model, key, provider string) error
+}
+
+// SetStickyRouteStore enables sticky routing, applied while
+// routing.sticky.enabled is set.
+func (h *Handler) SetStickyRouteStore(s StickyRouteStore) {
+	h.stickyRoutes = s
+}
+
+// stickyKey returns the key req's provider affinity is kept under: its
+// affinity key, else its conversation ID, else "" when sticky routing is off
+// or the request carries neither.
+func (h *Handler) stickyKey(req *types.AegisRequest) string {
+	if h.stickyRoutes == nil || h.cfg == nil || !h.cfg().Routing.Sticky.Enabled {
+		return ""
+	}
+	if req.AffinityKey != "" {
+		return "a:" + req.AffinityKey
+	}
+	if req.ConversationID != "" {
+		return "c:" + req.ConversationID
+	}
+	return ""
+}
+
+// stickyProvider returns the provider that served req's earlier turns, or ""
+// if there is none. A slow or failed lookup routes the request as usual.
+func (h *Handler) stickyProvider(ctx context.Context, req *types.AegisRequest) string {
+	key := h.stickyKey(req)
+	if key == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, stickyLookupTimeout)
+	defer cancel()
+	provider, err := h.stickyRoutes.Provider(ctx, req.OrganizationID, req.Model, key)
+	if err != nil {
+		slog.Warn("sticky route lookup failed", "error", err, "request_id", req.RequestID)
+		return ""
+	}
+	return provider
+}
+
+// recordStickyRoute notes, asynchronously, that provider served req, so its
+// conversation's next turn prefers it.
+func (h *Handler) recordStickyRoute(req *types.AegisRequest, provider string) {
+	key := h.stickyKey(req)
+	if key == "" {
+		return
+	}
+	org, model, reqID := req.OrganizationID, req.Model, req.RequestID
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), stickyRecordTimeout)
+		defer cancel()
+		if err := h.stickyRoutes.Record(ctx, org, model, key, provider); err != nil {
+			slog.Warn("failed to record sticky route", "error", err, "request_id", reqID)
+		}
+	}()
+}
@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+)
+
+// streamErrorEventName is the SSE event sent in place of [DONE] when a
+// stream fails after its headers were flushed, so clients can tell a
+// truncated stream from a complete one.
+const streamErrorEventName = "error"
+
+// Codes carried by the stream error event. They are part of the error
+// catalog alongside the JSON error codes of non-streaming responses.
+const (
+	streamErrFirstChunkTimeout   = httputil.CodeFirstChunkTimeout
+	streamErrChunkTimeout        = httputil.CodeChunkTimeout
+	streamErrStreamTimeout       = httputil.CodeStreamTimeout
+	streamErrProviderDisconnect  = httputil.CodeProviderDisconnect
+	streamErrProviderStreamError = httputil.CodeProviderStreamError
+	streamErrInvalidChunk        = httputil.CodeInvalidChunk
+	streamErrShutdown            = httputil.CodeGatewayShutdown
+)
+
+// writeStreamError writes the stream error event:
+//
+//	event: error
+//	data: {"error":{"message":"...","type":"server_error","code":"chunk_timeout","aegis_request_id":"..."}}
+//
+// Streams end after it, without [DONE].
+func writeStreamError(w http.ResponseWriter, flusher http.Flusher, reqID, code, message string) {
+	payload, err := json.Marshal(httputil.APIError{Error: httputil.APIErrorBody{
+		Message:    message,
+		Type:       httputil.TypeServer,
+		Code:       code,
+		AegisReqID: reqID,
+	}})
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", streamErrorEventName, payload)
+	flusher.Flush()
+}
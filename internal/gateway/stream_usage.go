@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// Stream usage comes from, in order of preference: the provider's usage chunk
+// (OpenAI-compatible providers, which are always asked for one), the usage in
+// native stream events (Anthropic message_start/message_delta), and finally
+// an estimate from the prompt and the streamed text.
+
+// perMessageTokens approximates the role and framing tokens each chat message
+// adds on top of its content.
+const perMessageTokens = 4
+
+// estimateTokens approximates a token count at four characters per token,
+// close enough for English text to keep unreported streams on the books.
+func estimateTokens(runes int) int {
+	return (runes + 3) / 4
+}
+
+// estimatePromptTokens approximates the prompt tokens of a request.
+func estimatePromptTokens(messages []types.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += perMessageTokens + estimateTokens(utf8.RuneCountInString(m.Content))
+	}
+	return n
+}
+
+// mergeStreamUsage folds usage from a native stream event into metrics.
+func mergeStreamUsage(metrics *StreamMetrics, reader adapters.StreamUsageReader, data []byte) {
+	usage, model, ok := reader.StreamUsage(data)
+	if !ok {
+		return
+	}
+	if usage.PromptTokens > 0 {
+		metrics.PromptTokens = usage.PromptTokens
+	}
+	if usage.CompletionTokens > 0 {
+		metrics.CompletionTokens = usage.CompletionTokens
+	}
+	metrics.TotalTokens = metrics.PromptTokens + metrics.CompletionTokens
+	if model != "" && metrics.Model == "" {
+		metrics.Model = model
+	}
+}
+
+// finalizeUsage fills in token counts the provider did not report with
+// estimates. It is safe to call more than once.
+func finalizeUsage(metrics *StreamMetrics) {
+	if metrics.PromptTokens == 0 && metrics.PromptTokensEstimate > 0 {
+		metrics.PromptTokens = metrics.PromptTokensEstimate
+		metrics.UsageEstimated = true
+	}
+	if metrics.CompletionTokens == 0 && metrics.CompletionRunes > 0 {
+		metrics.CompletionTokens = estimateTokens(metrics.CompletionRunes)
+		metrics.UsageEstimated = true
+	}
+	if sum := metrics.PromptTokens + metrics.CompletionTokens; metrics.TotalTokens < sum {
+		metrics.TotalTokens = sum
+	}
+}
+
+// isUsageOnlyChunk reports whether an OpenAI-format chunk is the final
+// stream_options.include_usage chunk: usage present and no choices.
+func isUsageOnlyChunk(chunk []byte) bool {
+	var c struct {
+		Choices []json.RawMessage `json:"choices"`
+		Usage   json.RawMessage   `json:"usage"`
+	}
+	if err := json.Unmarshal(chunk, &c); err != nil {
+		return false
+	}
+	return len(c.Choices) == 0 && len(c.Usage) > 0 && string(c.Usage) != "null"
+}
+
+// usageChunk is the OpenAI-format usage chunk written for clients that set
+// stream_options.include_usage when the provider did not send one itself.
+type usageChunk struct {
+	ID      string      `json:"id"`
+	Object  string      `json:"object"`
+	Created int64       `json:"created"`
+	Model   string      `json:"model"`
+	Choices []struct{}  `json:"choices"`
+	Usage   types.Usage `json:"usage"`
+}
+
+// writeUsageChunk writes the usage chunk built from metrics.
+func writeUsageChunk(w http.ResponseWriter, metrics *StreamMetrics) error {
+	created := metrics.Created
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	payload, err := json.Marshal(usageChunk{
+		ID:      metrics.ChunkID,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   metrics.Model,
+		Choices: []struct{}{},
+		Usage: types.Usage{
+			PromptTokens:     metrics.PromptTokens,
+			CompletionTokens: metrics.CompletionTokens,
+			TotalTokens:      metrics.TotalTokens,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal usage chunk: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const openAIUsageChunk = `data: {"model":"gpt-4","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":20,"total_tokens":30}}`
+
+func TestProcessChunk_UsageChunkOnlyForwardedWhenRequested(t *testing.T) {
+	sh := NewStreamingHandler(&Handler{}, DefaultStreamingConfig())
+	adapter := &mockStreamAdapter{name: "openai"}
+
+	for _, include := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		metrics := &StreamMetrics{StartTime: time.Now(), IncludeUsage: include}
+		if err := sh.processChunk(w, w, []byte(openAIUsageChunk), adapter, metrics); err != nil {
+			t.Fatalf("processChunk failed: %v", err)
+		}
+		if metrics.TotalTokens != 30 || metrics.ChunkCount != 0 {
+			t.Errorf("include=%v: usage chunk should be counted as usage, not content: %+v", include, metrics)
+		}
+		if forwarded := strings.Contains(w.Body.String(), `"usage"`); forwarded != include {
+			t.Errorf("include=%v: forwarded=%v\n%s", include, forwarded, w.Body.String())
+		}
+	}
+}
+
+func TestProcessChunk_AnthropicUsageChunkSynthesized(t *testing.T) {
+	sh := NewStreamingHandler(&Handler{}, DefaultStreamingConfig())
+	adapter := adapters.NewAnthropicAdapter(config.ProviderConfig{}, http.DefaultClient)
+	w := httptest.NewRecorder()
+	metrics := &StreamMetrics{StartTime: time.Now(), IncludeUsage: true}
+
+	for _, event := range []string{
+		`data: {"type":"message_start","message":{"id":"msg_01","model":"claude-sonnet-4-20250514","usage":{"input_tokens":12,"output_tokens":1}}}`,
+		`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hello"}}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+		`data: {"type":"message_stop"}`,
+	} {
+		if err := sh.processChunk(w, w, []byte(event), adapter, metrics); err != nil {
+			t.Fatalf("processChunk failed: %v", err)
+		}
+	}
+
+	var chunk usageChunk
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if ok && strings.Contains(data, `"choices":[]`) {
+			_ = json.Unmarshal([]byte(data), &chunk)
+		}
+	}
+	if chunk.Usage.PromptTokens != 12 || chunk.Usage.CompletionTokens != 7 || chunk.Usage.TotalTokens != 19 {
+		t.Errorf("unexpected usage chunk: %+v\n%s", chunk, w.Body.String())
+	}
+	if chunk.ID != "msg_01" || chunk.Object != "chat.completion.chunk" || chunk.Created == 0 {
+		t.Errorf("usage chunk should share the stream's envelope: %+v", chunk)
+	}
+	if metrics.UsageEstimated {
+		t.Error("provider-reported usage should not be marked estimated")
+	}
+	if !strings.HasSuffix(w.Body.String(), "data: [DONE]\n\n") {
+		t.Errorf("usage chunk must precede [DONE]:\n%s", w.Body.String())
+	}
+}
+
+func TestFinalizeUsage_EstimatesWhenUnreported(t *testing.T) {
+	metrics := &StreamMetrics{
+		PromptTokensEstimate: estimatePromptTokens([]types.Message{{Role: "user", Content: "What is the capital of France?"}}),
+	}
+	sh := NewStreamingHandler(&Handler{}, DefaultStreamingConfig())
+	if err := sh.extractTokensFromChunk([]byte(`{"choices":[{"delta":{"content":"Paris is the capital."}}]}`), metrics); err != nil {
+		t.Fatal(err)
+	}
+	finalizeUsage(metrics)
+
+	if metrics.PromptTokens != 4+8 || metrics.CompletionTokens != 6 || metrics.TotalTokens != 18 {
+		t.Errorf("unexpected estimate: prompt=%d completion=%d total=%d",
+			metrics.PromptTokens, metrics.CompletionTokens, metrics.TotalTokens)
+	}
+	if !metrics.UsageEstimated {
+		t.Error("expected usage to be marked estimated")
+	}
+}
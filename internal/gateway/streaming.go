@@ -2,17 +2,59 @@ package gateway
 
 import (
 	"bufio"
-	"fmt"
+	"bytes"
+	"io"
 	"log/slog"
 	"net/http"
-	"strings"
+	"sync"
 
 	"github.com/af-corp/aegis-gateway/internal/httputil"
 	"github.com/af-corp/aegis-gateway/internal/router/adapters"
 )
 
+var (
+	sseDataPrefix  = []byte("data: ")
+	sseEventPrefix = []byte("event: ")
+	sseDone        = []byte("[DONE]")
+)
+
+// sseBuffers recycles the buffers SSE frames are assembled in, so forwarding
+// a chunk is a single Write with no allocation.
+var sseBuffers = sync.Pool{New: func() any {
+	b := make([]byte, 0, 4*1024)
+	return &b
+}}
+
+// writeSSEData writes payload as one "data: <payload>\n\n" frame.
+func writeSSEData(w io.Writer, payload []byte) {
+	bp := sseBuffers.Get().(*[]byte)
+	b := append((*bp)[:0], sseDataPrefix...)
+	b = append(b, payload...)
+	b = append(b, '\n', '\n')
+	_, _ = w.Write(b)
+	*bp = b
+	sseBuffers.Put(bp)
+}
+
+// writeSSELine writes line followed by a newline.
+func writeSSELine(w io.Writer, line []byte) {
+	bp := sseBuffers.Get().(*[]byte)
+	b := append(append((*bp)[:0], line...), '\n')
+	_, _ = w.Write(b)
+	*bp = b
+	sseBuffers.Put(bp)
+}
+
+// passThroughStream reports whether adapter's chunks are forwarded as the
+// provider sent them, without TransformStreamChunk.
+func passThroughStream(adapter adapters.ProviderAdapter) bool {
+	p, ok := adapter.(adapters.StreamPassThrough)
+	return ok && p.PassThroughStream()
+}
+
 // streamSSE reads SSE events from the provider response and forwards them to the client,
-// transforming each chunk through the adapter's TransformStreamChunk.
+// transforming each chunk through the adapter's TransformStreamChunk unless
+// the adapter passes its stream through.
 func streamSSE(w http.ResponseWriter, reqID string, providerResp *http.Response, adapter adapters.ProviderAdapter) {
 	defer func() { _ = providerResp.Body.Close() }()
 
@@ -32,34 +74,40 @@ func streamSSE(w http.ResponseWriter, reqID string, providerResp *http.Response,
 	scanner := bufio.NewScanner(providerResp.Body)
 	// Increase scanner buffer for large chunks
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	passThrough := passThroughStream(adapter)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		// The line aliases the scanner's buffer and is only valid until
+		// the next Scan.
+		line := scanner.Bytes()
 
 		// SSE format: lines starting with "data: "
-		if !strings.HasPrefix(line, "data: ") {
+		data, ok := bytes.CutPrefix(line, sseDataPrefix)
+		if !ok {
 			// Forward event: lines or empty lines as-is for keep-alive
-			if strings.HasPrefix(line, "event: ") || line == "" {
-				_, _ = fmt.Fprintf(w, "%s\n", line)
+			if bytes.HasPrefix(line, sseEventPrefix) || len(line) == 0 {
+				writeSSELine(w, line)
 				flusher.Flush()
 			}
 			continue
 		}
 
-		data := strings.TrimPrefix(line, "data: ")
-
 		// End of stream
-		if data == "[DONE]" {
-			_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
+		if bytes.Equal(data, sseDone) {
+			writeSSEData(w, sseDone)
 			flusher.Flush()
 			return
 		}
 
 		// Transform chunk through the adapter
-		transformed, err := adapter.TransformStreamChunk([]byte(data))
-		if err != nil {
-			slog.Error("failed to transform stream chunk", "error", err, "provider", adapter.Name())
-			continue
+		transformed := data
+		if !passThrough {
+			var err error
+			transformed, err = adapter.TransformStreamChunk(data)
+			if err != nil {
+				slog.Error("failed to transform stream chunk", "error", err, "provider", adapter.Name())
+				continue
+			}
 		}
 
 		// nil means skip this chunk (e.g., Anthropic non-content events)
@@ -68,13 +116,13 @@ func streamSSE(w http.ResponseWriter, reqID string, providerResp *http.Response,
 		}
 
 		// Check if the adapter signaled end of stream (Anthropic message_stop → [DONE])
-		if string(transformed) == "[DONE]" {
-			_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
+		if bytes.Equal(transformed, sseDone) {
+			writeSSEData(w, sseDone)
 			flusher.Flush()
 			return
 		}
 
-		_, _ = fmt.Fprintf(w, "data: %s\n\n", transformed)
+		writeSSEData(w, transformed)
 		flusher.Flush()
 	}
 
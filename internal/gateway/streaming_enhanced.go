@@ -2,16 +2,19 @@ package gateway
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
-	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/cache"
+	"github.com/af-corp/aegis-gateway/internal/events"
 	"github.com/af-corp/aegis-gateway/internal/httputil"
 	"github.com/af-corp/aegis-gateway/internal/router/adapters"
 	"github.com/af-corp/aegis-gateway/internal/storage"
@@ -21,19 +24,22 @@ import (
 
 // StreamingConfig holds configuration for streaming behavior.
 type StreamingConfig struct {
-	PerChunkTimeout time.Duration // Timeout for each individual chunk
-	TotalTimeout    time.Duration // Total stream timeout
-	BufferSize      int           // Scanner buffer size
-	MaxBufferSize   int           // Maximum scanner buffer size
+	FirstChunkTimeout time.Duration // Timeout until the provider's first chunk
+	PerChunkTimeout   time.Duration // Timeout for each individual chunk
+	TotalTimeout      time.Duration // Total stream timeout
+	KeepAliveInterval time.Duration // Idle time before a ": ping" comment; 0 disables
+	BufferSize        int           // Scanner buffer size
+	MaxBufferSize     int           // Maximum scanner buffer size
 }
 
 // DefaultStreamingConfig returns sensible defaults for streaming.
 func DefaultStreamingConfig() StreamingConfig {
 	return StreamingConfig{
-		PerChunkTimeout: 30 * time.Second,  // 30s per chunk
-		TotalTimeout:    5 * time.Minute,    // 5 min total
-		BufferSize:      64 * 1024,          // 64KB initial
-		MaxBufferSize:   1024 * 1024,        // 1MB max
+		FirstChunkTimeout: 60 * time.Second, // 60s to first chunk
+		PerChunkTimeout:   30 * time.Second, // 30s per chunk
+		TotalTimeout:      5 * time.Minute,  // 5 min total
+		BufferSize:        64 * 1024,        // 64KB initial
+		MaxBufferSize:     1024 * 1024,      // 1MB max
 	}
 }
 
@@ -41,13 +47,41 @@ func DefaultStreamingConfig() StreamingConfig {
 type StreamMetrics struct {
 	StartTime         time.Time
 	FirstChunkTime    time.Time
+	LastChunkTime     time.Time
 	ChunkCount        int
+	InterChunkGapsMs  []float64
 	PromptTokens      int
 	CompletionTokens  int
 	TotalTokens       int
 	EstimatedCostUSD  float64
 	Provider          string
 	Model             string
+
+	// IncludeUsage mirrors the client's stream_options.include_usage;
+	// UsageSent records that a usage chunk has already been forwarded.
+	IncludeUsage bool
+	UsageSent    bool
+	// PromptTokensEstimate and CompletionRunes back the estimate used when
+	// the provider reports no usage; UsageEstimated marks that it was used.
+	PromptTokensEstimate int
+	CompletionRunes      int
+	UsageEstimated       bool
+	// ChunkID and Created are the envelope of the provider's chunks, reused
+	// for chunks the gateway writes itself.
+	ChunkID string
+	Created int64
+	// Completed is set once [DONE] has been written; a stream that ends
+	// without it was cut short.
+	Completed bool
+}
+
+// TimeToFirstToken returns the latency until the first content chunk, or zero
+// if the stream produced no chunks.
+func (m StreamMetrics) TimeToFirstToken() time.Duration {
+	if m.ChunkCount == 0 || m.FirstChunkTime.IsZero() {
+		return 0
+	}
+	return m.FirstChunkTime.Sub(m.StartTime)
 }
 
 // StreamingHandler manages enhanced streaming with metrics, timeouts, and cost tracking.
@@ -64,6 +98,35 @@ func NewStreamingHandler(handler *Handler, config StreamingConfig) *StreamingHan
 	}
 }
 
+// chunkTimeouts returns the first-chunk and inter-chunk deadlines, taking
+// routing.stream_first_chunk_timeout and routing.stream_chunk_timeout from
+// the current config when set.
+func (sh *StreamingHandler) chunkTimeouts() (first, next time.Duration) {
+	first, next = sh.config.FirstChunkTimeout, sh.config.PerChunkTimeout
+	if sh.handler.cfg != nil {
+		rc := sh.handler.cfg().Routing
+		if rc.StreamFirstChunkTimeout > 0 {
+			first = rc.StreamFirstChunkTimeout
+		}
+		if rc.StreamChunkTimeout > 0 {
+			next = rc.StreamChunkTimeout
+		}
+	}
+	if first <= 0 {
+		first = next
+	}
+	return first, next
+}
+
+// keepAliveInterval returns how long a client stream may sit idle before a
+// ping, from server.stream_keepalive_interval when a config is attached.
+func (sh *StreamingHandler) keepAliveInterval() time.Duration {
+	if sh.handler.cfg != nil {
+		return sh.handler.cfg().Server.StreamKeepAliveInterval
+	}
+	return sh.config.KeepAliveInterval
+}
+
 // HandleStream sends the request to the provider and forwards SSE chunks with full monitoring.
 func (sh *StreamingHandler) HandleStream(
 	w http.ResponseWriter,
@@ -75,8 +138,16 @@ func (sh *StreamingHandler) HandleStream(
 	authInfo *auth.AuthInfo,
 	aegisReq *types.AegisRequest,
 ) {
-	receivedAt := time.Now()
-	
+	receivedAt := aegisReq.ReceivedAt
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+
+	if sh.handler.drainer != nil {
+		release := sh.handler.drainer.track()
+		defer release()
+	}
+
 	// Create context with total timeout
 	ctx, cancel := context.WithTimeout(r.Context(), sh.config.TotalTimeout)
 	defer cancel()
@@ -84,9 +155,21 @@ func (sh *StreamingHandler) HandleStream(
 	// Update provider request with timeout context
 	providerReq = providerReq.WithContext(ctx)
 
-	// Send request to provider
+	// Send request to provider. Everything before this point is gateway overhead;
+	// everything after is spent waiting on or relaying the provider stream.
+	providerStart := time.Now()
 	providerResp, err := adapter.SendRequest(providerReq)
 	if err != nil {
+		if clientGone(r.Context()) {
+			slog.Info("client disconnected before stream started",
+				"request_id", reqID,
+				"provider", adapter.Name(),
+			)
+			if sh.handler.metrics != nil {
+				sh.handler.metrics.RecordStreamingError(adapter.Name(), authInfo.OrganizationID, "client_disconnect")
+			}
+			return
+		}
 		slog.Error("streaming provider request failed", "error", err, "provider", adapter.Name())
 		
 		// Record failure metrics
@@ -94,42 +177,78 @@ func (sh *StreamingHandler) HandleStream(
 			sh.handler.healthTracker.RecordFailure(adapter.Name())
 		}
 		if sh.handler.metrics != nil {
-			sh.handler.metrics.RecordStreamingError(adapter.Name(), "request_failed")
+			sh.handler.metrics.RecordStreamingError(adapter.Name(), authInfo.OrganizationID, "request_failed")
 		}
 		
-		httputil.WriteServiceUnavailableError(w, reqID, "Provider request failed")
+		httputil.WriteHTTPError(w, reqID, providerFailure(adapter.Name(), err))
 		return
 	}
 
+	providerRequestID := adapters.ProviderRequestID(providerResp)
+	sh.handler.observeRateLimits(adapter.Name(), providerResp)
+
 	if providerResp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(providerResp.Body)
 		_ = providerResp.Body.Close()
 		slog.Error("streaming provider returned error",
 			"status", providerResp.StatusCode,
 			"provider", adapter.Name(),
+			"request_id", reqID,
+			"provider_request_id", providerRequestID,
 			"body", string(body),
 		)
 		
 		if sh.handler.metrics != nil {
-			sh.handler.metrics.RecordStreamingError(adapter.Name(), fmt.Sprintf("http_%d", providerResp.StatusCode))
+			sh.handler.metrics.RecordStreamingError(adapter.Name(), authInfo.OrganizationID, fmt.Sprintf("http_%d", providerResp.StatusCode))
 		}
 		
-		httputil.WriteInternalError(w, reqID, "Provider returned error")
+		if writeProviderError(w, reqID, adapters.ResponseProviderError(adapter.Name(), providerResp, body)) {
+			return
+		}
+		httputil.WriteCode(w, reqID, httputil.CodeProviderError, "Provider returned error",
+			map[string]any{"provider": adapter.Name(), "provider_status": providerResp.StatusCode})
 		return
 	}
 
+	sampled := sh.handler.sampled(reqID)
+	if sampled {
 	slog.Info("streaming started",
-		"request_id", reqID,
-		"model_requested", originalModel,
-		"provider", adapter.Name(),
-		"org_id", authInfo.OrganizationID,
-	)
+			"request_id", reqID,
+			"model_requested", originalModel,
+			"provider", adapter.Name(),
+			"provider_request_id", providerRequestID,
+			"org_id", authInfo.OrganizationID,
+		)
+	}
+
+	// Orgs with response hooks or guardrails get the stream buffered and
+	// rewritten as a whole, since hooks expect the complete text and rules
+	// such as link stripping span chunks.
+	out := w
+	var guarded *guardedStreamWriter
+	if transform := sh.handler.responseTransform(r.Context(), aegisReq); transform != nil {
+		guarded = newGuardedStreamWriter(w, reqID, transform)
+		out = guarded
+	}
 
 	// Execute streaming with full monitoring
-	metrics := sh.streamWithMonitoring(ctx, w, reqID, providerResp, adapter, authInfo)
-	
+	metrics := sh.streamWithMonitoring(ctx, out, reqID, providerResp, adapter, aegisReq)
+	if guarded != nil {
+		guarded.Close()
+	}
+	// Streams cut short never reach writeDone; account for what was sent.
+	finalizeUsage(&metrics)
+	if metrics.Model == "" {
+		metrics.Model = aegisReq.Model
+	}
+	// Measure TTFT from when the gateway received the request, not from when
+	// the provider returned headers, so it reflects what the client experiences.
+	metrics.StartTime = receivedAt
+
 	totalDuration := time.Since(receivedAt)
-	
+	providerLatency := time.Since(providerStart)
+	gatewayOverhead := totalDuration - providerLatency
+
 	// Calculate final cost if we have token counts
 	if sh.handler.costCalc != nil && metrics.TotalTokens > 0 {
 		if cost, found := sh.handler.costCalc.Calculate(
@@ -148,20 +267,25 @@ func (sh *StreamingHandler) HandleStream(
 		}
 	}
 
+	if sampled {
 	slog.Info("streaming completed",
-		"request_id", reqID,
-		"model_requested", originalModel,
-		"model_served", metrics.Model,
-		"provider", metrics.Provider,
-		"chunks", metrics.ChunkCount,
-		"prompt_tokens", metrics.PromptTokens,
-		"completion_tokens", metrics.CompletionTokens,
-		"total_tokens", metrics.TotalTokens,
-		"estimated_cost_usd", metrics.EstimatedCostUSD,
-		"duration_ms", totalDuration.Milliseconds(),
-		"time_to_first_token_ms", metrics.FirstChunkTime.Sub(metrics.StartTime).Milliseconds(),
-		"org_id", authInfo.OrganizationID,
-	)
+			"request_id", reqID,
+			"model_requested", originalModel,
+			"model_served", metrics.Model,
+			"provider", metrics.Provider,
+			"provider_request_id", providerRequestID,
+			"chunks", metrics.ChunkCount,
+			"prompt_tokens", metrics.PromptTokens,
+			"completion_tokens", metrics.CompletionTokens,
+			"total_tokens", metrics.TotalTokens,
+			"usage_estimated", metrics.UsageEstimated,
+			"estimated_cost_usd", metrics.EstimatedCostUSD,
+			"duration_ms", totalDuration.Milliseconds(),
+			"gateway_overhead_ms", gatewayOverhead.Milliseconds(),
+			"time_to_first_token_ms", metrics.TimeToFirstToken().Milliseconds(),
+			"org_id", authInfo.OrganizationID,
+		)
+	}
 
 	// Record Prometheus metrics
 	if sh.handler.metrics != nil {
@@ -172,22 +296,24 @@ func (sh *StreamingHandler) HandleStream(
 			Provider:         metrics.Provider,
 			Status:           "200",
 			Classification:   string(authInfo.MaxClassification),
-			DurationMs:       float64(totalDuration.Milliseconds()),
-			OverheadMs:       float64(totalDuration.Milliseconds()),
-			PromptTokens:     metrics.PromptTokens,
-			CompletionTokens: metrics.CompletionTokens,
-			CostUSD:          metrics.EstimatedCostUSD,
+			DurationMs:        float64(totalDuration.Milliseconds()),
+			OverheadMs:        durationMs(gatewayOverhead),
+			ProviderLatencyMs: durationMs(providerLatency),
+			PromptTokens:      metrics.PromptTokens,
+			CompletionTokens:  metrics.CompletionTokens,
+			CostUSD:           metrics.EstimatedCostUSD,
 		})
 		
 		// Record streaming-specific metrics
-		timeToFirstToken := metrics.FirstChunkTime.Sub(metrics.StartTime)
 		sh.handler.metrics.RecordStreamingMetrics(telemetry.StreamingLabels{
-			Provider:             metrics.Provider,
-			Model:                originalModel,
-			ChunkCount:           metrics.ChunkCount,
-			TimeToFirstTokenMs:   float64(timeToFirstToken.Milliseconds()),
-			TokensPerSecond:      sh.calculateTokensPerSecond(metrics.CompletionTokens, totalDuration),
-			StreamDurationMs:     float64(totalDuration.Milliseconds()),
+			Org:                   authInfo.OrganizationID,
+			Provider:              metrics.Provider,
+			Model:                 originalModel,
+			ChunkCount:            metrics.ChunkCount,
+			TimeToFirstTokenMs:    float64(metrics.TimeToFirstToken().Milliseconds()),
+			TokensPerSecond:       sh.calculateTokensPerSecond(metrics.CompletionTokens, totalDuration),
+			StreamDurationMs:      float64(totalDuration.Milliseconds()),
+			InterChunkLatenciesMs: metrics.InterChunkGapsMs,
 		})
 	}
 
@@ -211,8 +337,65 @@ func (sh *StreamingHandler) HandleStream(
 			StatusCode:       http.StatusOK,
 			Project:          aegisReq.Project,
 			Stream:           true,
+			RequestHash:      aegisReq.RequestHash,
 		})
 	}
+
+	sh.handler.recordConversationTurn(aegisReq, metrics.Model, metrics.PromptTokens, metrics.CompletionTokens, metrics.EstimatedCostUSD)
+	sh.handler.settleTokens(r.Context(), reqID, metrics.TotalTokens)
+	if metrics.Completed {
+		sh.handler.recordStickyRoute(aegisReq, adapter.Name())
+	}
+
+	if sh.handler.events != nil {
+		sh.handler.events.Emit(events.Event{
+			Type:             events.TypeRequestCompleted,
+			RequestID:        reqID,
+			OrganizationID:   authInfo.OrganizationID,
+			TeamID:           authInfo.TeamID,
+			APIKeyID:         authInfo.KeyID,
+			Classification:   string(authInfo.MaxClassification),
+			ModelRequested:   originalModel,
+			ModelServed:      metrics.Model,
+			Provider:         metrics.Provider,
+			Project:          aegisReq.Project,
+			Stream:           true,
+			StatusCode:       http.StatusOK,
+			DurationMs:       totalDuration.Milliseconds(),
+			PromptTokens:     metrics.PromptTokens,
+			CompletionTokens: metrics.CompletionTokens,
+			EstimatedCostUSD: metrics.EstimatedCostUSD,
+		})
+	}
+}
+
+// ReplayCached answers a stream=true request from the response cache,
+// paced by response_cache.replay_chunk_size and replay_interval.
+func (sh *StreamingHandler) ReplayCached(w http.ResponseWriter, r *http.Request, reqID string, resp *types.AegisResponse) {
+	if _, ok := w.(http.Flusher); !ok {
+		httputil.WriteInternalError(w, reqID, "Streaming not supported")
+		return
+	}
+	if sh.handler.drainer != nil {
+		release := sh.handler.drainer.track()
+		defer release()
+	}
+
+	var replay cache.ReplayConfig
+	if sh.handler.cfg != nil {
+		rc := sh.handler.cfg().ResponseCache
+		replay = cache.ReplayConfig{ChunkSize: rc.ReplayChunkSize, Interval: rc.ReplayInterval}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Request-ID", reqID)
+	w.Header().Set(headerProvider, resp.Provider)
+	w.WriteHeader(http.StatusOK)
+	if err := cache.ReplayStream(r.Context(), w, resp, replay); err != nil {
+		slog.Info("cached stream replay ended early", "request_id", reqID, "error", err)
+	}
 }
 
 // streamWithMonitoring handles the actual streaming with timeouts and monitoring.
@@ -222,7 +405,7 @@ func (sh *StreamingHandler) streamWithMonitoring(
 	reqID string,
 	providerResp *http.Response,
 	adapter adapters.ProviderAdapter,
-	authInfo *auth.AuthInfo,
+	aegisReq *types.AegisRequest,
 ) StreamMetrics {
 	defer func() { _ = providerResp.Body.Close() }()
 
@@ -236,37 +419,67 @@ func (sh *StreamingHandler) streamWithMonitoring(
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Request-ID", reqID)
+	w.Header().Set(headerProvider, adapter.Name())
 	w.WriteHeader(http.StatusOK)
 	flusher.Flush()
 
 	metrics := StreamMetrics{
-		StartTime: time.Now(),
-		Provider:  adapter.Name(),
+		StartTime:            time.Now(),
+		Provider:             adapter.Name(),
+		IncludeUsage:         aegisReq.StreamOptions != nil && aegisReq.StreamOptions.IncludeUsage,
+		PromptTokensEstimate: estimatePromptTokens(aegisReq.Messages),
+	}
+	if f, ok := adapter.(adapters.StreamTransformerFactory); ok {
+		adapter = streamSession{ProviderAdapter: adapter, transformer: f.NewStreamTransformer()}
 	}
 
 	scanner := bufio.NewScanner(providerResp.Body)
 	scanner.Buffer(make([]byte, 0, sh.config.BufferSize), sh.config.MaxBufferSize)
 
-	// Channel to detect client disconnect via context cancellation
-	clientDisconnected := make(chan bool, 1)
-	go func() {
-		<-ctx.Done()
-		clientDisconnected <- true
-	}()
-	
-	// Channel for per-chunk timeout
-	chunkTimer := time.NewTimer(sh.config.PerChunkTimeout)
+	// Closed when shutdown gives up waiting for in-flight streams; nil blocks forever.
+	var shutdownCut <-chan struct{}
+	if sh.handler.drainer != nil {
+		shutdownCut = sh.handler.drainer.cutC()
+	}
+
+	// The provider gets longer for its first chunk, which waits on prompt
+	// processing, than for each one after it.
+	firstChunkTimeout, chunkTimeout := sh.chunkTimeouts()
+	received := false
+	chunkTimer := time.NewTimer(firstChunkTimeout)
 	defer chunkTimer.Stop()
 
+	// SSE comments during provider silences keep intermediaries from closing
+	// an idle connection; clients ignore them.
+	pingInterval := sh.keepAliveInterval()
+	var pingTicker *time.Ticker
+	var pingC <-chan time.Time
+	if pingInterval > 0 {
+		pingTicker = time.NewTicker(pingInterval)
+		defer pingTicker.Stop()
+		pingC = pingTicker.C
+	}
+
 	scanChan := make(chan bool)
-	lineChan := make(chan string)
-	
+	lineChan := make(chan []byte)
+	// Lines alias the scanner's buffer, so the scanner waits on resume until
+	// each one is handled before reading the next; stop releases it when the
+	// stream ends first.
+	resume := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+
 	// Scanner goroutine
 	go func() {
 		for scanner.Scan() {
 			select {
-			case lineChan <- scanner.Text():
-			case <-ctx.Done():
+			case lineChan <- scanner.Bytes():
+			case <-stop:
+				return
+			}
+			select {
+			case <-resume:
+			case <-stop:
 				return
 			}
 		}
@@ -274,103 +487,183 @@ func (sh *StreamingHandler) streamWithMonitoring(
 	}()
 
 	for {
-		// Reset chunk timer for each iteration
-		chunkTimer.Reset(sh.config.PerChunkTimeout)
-		
 		select {
+		case <-pingC:
+			_, _ = io.WriteString(w, ": ping\n\n")
+			flusher.Flush()
+
 		case <-ctx.Done():
+			// ctx derives from the client's request, so a disconnect cancels it
+			// too; returning closes the provider body and, with the request
+			// context already cancelled, aborts the upstream call.
+			if clientGone(ctx) {
+				slog.Info("client disconnected during streaming",
+					"request_id", reqID,
+					"chunks_sent", metrics.ChunkCount,
+				)
+				if sh.handler.metrics != nil {
+					sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "client_disconnect")
+				}
+				return metrics
+			}
 			slog.Warn("stream total timeout exceeded",
 				"request_id", reqID,
 				"chunks_sent", metrics.ChunkCount,
 			)
 			if sh.handler.metrics != nil {
-				sh.handler.metrics.RecordStreamingError(adapter.Name(), "total_timeout")
+				sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "total_timeout")
 			}
-			_, _ = fmt.Fprintf(w, "data: {\"error\": \"timeout\"}\n\n")
-			flusher.Flush()
+			writeStreamError(w, flusher, reqID, streamErrStreamTimeout, "Stream exceeded the request timeout")
 			return metrics
 			
 		case <-chunkTimer.C:
+			// A stalled stream is the provider's failure, counted against
+			// its circuit like a failed request.
+			errType, message, timeout := streamErrChunkTimeout, "Provider stalled: chunk timeout", chunkTimeout
+			if !received {
+				errType, message, timeout = streamErrFirstChunkTimeout, "Provider sent no data: first chunk timeout", firstChunkTimeout
+			}
 			slog.Warn("stream chunk timeout",
 				"request_id", reqID,
+				"provider", adapter.Name(),
+				"first_chunk", !received,
+				"timeout", timeout,
 				"chunks_sent", metrics.ChunkCount,
 			)
+			if sh.handler.healthTracker != nil {
+				sh.handler.healthTracker.RecordFailure(adapter.Name())
+			}
 			if sh.handler.metrics != nil {
-				sh.handler.metrics.RecordStreamingError(adapter.Name(), "chunk_timeout")
+				sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, errType)
 			}
-			_, _ = fmt.Fprintf(w, "data: {\"error\": \"chunk timeout\"}\n\n")
-			flusher.Flush()
+			writeStreamError(w, flusher, reqID, errType, message)
 			return metrics
 			
-		case <-clientDisconnected:
-			slog.Info("client disconnected during streaming",
+		case <-shutdownCut:
+			slog.Warn("stream cut by gateway shutdown",
 				"request_id", reqID,
 				"chunks_sent", metrics.ChunkCount,
 			)
 			if sh.handler.metrics != nil {
-				sh.handler.metrics.RecordStreamingError(adapter.Name(), "client_disconnect")
+				sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "shutdown")
 			}
+			writeStreamError(w, flusher, reqID, streamErrShutdown, "Gateway shutting down")
 			return metrics
-			
+
 		case <-scanChan:
-			// Scanner finished
+			// Scanner finished; without [DONE] the provider went away mid-stream.
 			if err := scanner.Err(); err != nil {
 				slog.Error("error reading stream", "error", err, "provider", adapter.Name())
 				if sh.handler.metrics != nil {
-					sh.handler.metrics.RecordStreamingError(adapter.Name(), "scanner_error")
+					sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "scanner_error")
+				}
+				writeStreamError(w, flusher, reqID, streamErrProviderStreamError, "Error reading provider stream")
+			} else if !metrics.Completed {
+				slog.Warn("provider closed stream before completion",
+					"request_id", reqID,
+					"provider", adapter.Name(),
+					"chunks_sent", metrics.ChunkCount,
+				)
+				if sh.handler.metrics != nil {
+					sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "provider_disconnect")
 				}
+				writeStreamError(w, flusher, reqID, streamErrProviderDisconnect, "Provider closed the stream before it completed")
 			}
 			return metrics
 			
 		case line := <-lineChan:
+			// Only provider output restarts the chunk deadline and the ping
+			// interval; pings themselves must not hide a stalled provider.
+			received = true
+			chunkTimer.Reset(chunkTimeout)
+			if pingTicker != nil {
+				pingTicker.Reset(pingInterval)
+			}
 			// Process chunk
 			if err := sh.processChunk(w, flusher, line, adapter, &metrics); err != nil {
-				slog.Error("error processing chunk", "error", err)
+				slog.Error("error processing chunk", "error", err, "provider", adapter.Name())
+				// A malformed chunk is the provider's failure, like a stall.
+				if sh.handler.healthTracker != nil {
+					sh.handler.healthTracker.RecordFailure(adapter.Name())
+				}
 				if sh.handler.metrics != nil {
-					sh.handler.metrics.RecordStreamingError(adapter.Name(), "chunk_processing_error")
+					sh.handler.metrics.RecordStreamingError(adapter.Name(), aegisReq.OrganizationID, "chunk_processing_error")
 				}
+				writeStreamError(w, flusher, reqID, streamErrInvalidChunk, "Provider sent an invalid stream chunk")
 				return metrics
 			}
 			
 			// Check if stream ended
-			if strings.Contains(line, "[DONE]") {
+			if bytes.Contains(line, sseDone) {
 				return metrics
 			}
+			resume <- struct{}{}
 		}
 	}
 }
 
+// streamSession is an adapter bound to the transformer it issued for one
+// stream, so stateful conversions see every chunk of that stream in order.
+type streamSession struct {
+	adapters.ProviderAdapter
+	transformer adapters.StreamTransformer
+}
+
+func (s streamSession) TransformStreamChunk(chunk []byte) ([]byte, error) {
+	return s.transformer.TransformStreamChunk(chunk)
+}
+
+// StreamUsage forwards to the wrapped adapter, which embedding alone would
+// hide from the StreamUsageReader check.
+func (s streamSession) StreamUsage(chunk []byte) (types.Usage, string, bool) {
+	if r, ok := s.ProviderAdapter.(adapters.StreamUsageReader); ok {
+		return r.StreamUsage(chunk)
+	}
+	return types.Usage{}, "", false
+}
+
 // processChunk handles a single SSE chunk with token counting.
 func (sh *StreamingHandler) processChunk(
 	w http.ResponseWriter,
 	flusher http.Flusher,
-	line string,
+	line []byte,
 	adapter adapters.ProviderAdapter,
 	metrics *StreamMetrics,
 ) error {
 	// SSE format: lines starting with "data: "
-	if !strings.HasPrefix(line, "data: ") {
+	data, ok := bytes.CutPrefix(line, sseDataPrefix)
+	if !ok {
 		// Forward event lines or empty lines as-is for keep-alive
-		if strings.HasPrefix(line, "event: ") || line == "" {
-			_, _ = fmt.Fprintf(w, "%s\n", line)
+		if bytes.HasPrefix(line, sseEventPrefix) || len(line) == 0 {
+			writeSSELine(w, line)
 			flusher.Flush()
 		}
 		return nil
 	}
 
-	data := strings.TrimPrefix(line, "data: ")
-
 	// End of stream
-	if data == "[DONE]" {
-		_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
-		flusher.Flush()
+	if bytes.Equal(data, sseDone) {
+		sh.writeDone(w, flusher, metrics)
 		return nil
 	}
 
-	// Transform chunk through the adapter
-	transformed, err := adapter.TransformStreamChunk([]byte(data))
-	if err != nil {
-		return fmt.Errorf("transform chunk failed: %w", err)
+	// Native events may carry usage the OpenAI-format chunks drop
+	if reader, ok := adapter.(adapters.StreamUsageReader); ok {
+		mergeStreamUsage(metrics, reader, data)
+	}
+
+	// Transform chunk through the adapter; pass-through providers already
+	// send OpenAI-format chunks, which are only checked to be JSON so a
+	// malformed one fails the stream rather than reaching the client.
+	transformed := data
+	if !passThroughStream(adapter) {
+		var err error
+		transformed, err = adapter.TransformStreamChunk(data)
+		if err != nil {
+			return fmt.Errorf("transform chunk failed: %w", err)
+		}
+	} else if !json.Valid(data) {
+		return fmt.Errorf("chunk is not valid JSON: %.64q", data)
 	}
 
 	// nil means skip this chunk (e.g., Anthropic non-content events)
@@ -379,17 +672,34 @@ func (sh *StreamingHandler) processChunk(
 	}
 
 	// Check if the adapter signaled end of stream
-	if string(transformed) == "[DONE]" {
-		_, _ = fmt.Fprintf(w, "data: [DONE]\n\n")
-		flusher.Flush()
+	if bytes.Equal(transformed, sseDone) {
+		sh.writeDone(w, flusher, metrics)
 		return nil
 	}
 
-	// Track time to first chunk
+	// The usage chunk is always read for accounting but only forwarded to
+	// clients that asked for it.
+	if isUsageOnlyChunk(transformed) {
+		if err := sh.extractTokensFromChunk(transformed, metrics); err != nil {
+			slog.Debug("failed to extract tokens from usage chunk", "error", err)
+		}
+		if metrics.IncludeUsage {
+			writeSSEData(w, transformed)
+			flusher.Flush()
+			metrics.UsageSent = true
+		}
+		return nil
+	}
+
+	// Track time to first chunk and gaps between chunks
+	now := time.Now()
 	if metrics.ChunkCount == 0 {
-		metrics.FirstChunkTime = time.Now()
+		metrics.FirstChunkTime = now
+	} else {
+		metrics.InterChunkGapsMs = append(metrics.InterChunkGapsMs, float64(now.Sub(metrics.LastChunkTime).Microseconds())/1000)
 	}
-	
+	metrics.LastChunkTime = now
+
 	metrics.ChunkCount++
 
 	// Extract token counts and model info from chunk (OpenAI format)
@@ -399,16 +709,45 @@ func (sh *StreamingHandler) processChunk(
 	}
 
 	// Forward to client
-	_, _ = fmt.Fprintf(w, "data: %s\n\n", transformed)
+	writeSSEData(w, transformed)
 	flusher.Flush()
 
 	return nil
 }
 
+// writeDone sends the usage chunk if the client asked for one and the
+// provider sent none, then the aegis.usage event and the [DONE] terminator.
+func (sh *StreamingHandler) writeDone(w http.ResponseWriter, flusher http.Flusher, metrics *StreamMetrics) {
+	finalizeUsage(metrics)
+	if metrics.IncludeUsage && !metrics.UsageSent {
+		if err := writeUsageChunk(w, metrics); err != nil {
+			slog.Debug("failed to write usage chunk", "error", err)
+		}
+		metrics.UsageSent = true
+	}
+	var costUSD float64
+	if sh.handler.costCalc != nil && metrics.TotalTokens > 0 {
+		costUSD, _ = sh.handler.costCalc.Calculate(metrics.Provider, metrics.Model, metrics.PromptTokens, metrics.CompletionTokens)
+	}
+	if err := writeUsageEvent(w, metrics, costUSD); err != nil {
+		slog.Debug("failed to write usage event", "error", err)
+	}
+	writeSSEData(w, sseDone)
+	flusher.Flush()
+	metrics.Completed = true
+}
+
 // extractTokensFromChunk attempts to parse token usage from a streaming chunk.
 func (sh *StreamingHandler) extractTokensFromChunk(chunk []byte, metrics *StreamMetrics) error {
 	var chunkData struct {
-		Model string `json:"model"`
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Delta struct {
+				Content string `json:"content"`
+			} `json:"delta"`
+		} `json:"choices"`
 		Usage *struct {
 			PromptTokens     int `json:"prompt_tokens"`
 			CompletionTokens int `json:"completion_tokens"`
@@ -424,6 +763,14 @@ func (sh *StreamingHandler) extractTokensFromChunk(chunk []byte, metrics *Stream
 	if chunkData.Model != "" && metrics.Model == "" {
 		metrics.Model = chunkData.Model
 	}
+	if chunkData.ID != "" && metrics.ChunkID == "" {
+		metrics.ChunkID = chunkData.ID
+		metrics.Created = chunkData.Created
+	}
+
+	for _, c := range chunkData.Choices {
+		metrics.CompletionRunes += utf8.RuneCountInString(c.Delta.Content)
+	}
 
 	// Update token counts if present
 	if chunkData.Usage != nil {
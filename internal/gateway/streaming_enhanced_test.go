@@ -3,14 +3,21 @@ package gateway
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
 	"github.com/af-corp/aegis-gateway/internal/telemetry"
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
@@ -367,3 +374,271 @@ func (s *slowReader) Read(p []byte) (n int, err error) {
 func (s *slowReader) Close() error {
 	return nil
 }
+
+// TestProcessChunkTracksInterChunkGaps tests TTFT and inter-chunk latency tracking.
+func TestProcessChunkTracksInterChunkGaps(t *testing.T) {
+	sh := NewStreamingHandler(&Handler{}, DefaultStreamingConfig())
+	adapter := &mockStreamAdapter{name: "openai"}
+	w := httptest.NewRecorder()
+
+	metrics := &StreamMetrics{StartTime: time.Now()}
+	if got := metrics.TimeToFirstToken(); got != 0 {
+		t.Errorf("expected zero TTFT before any chunk, got %v", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := sh.processChunk(w, w, []byte(`data: {"choices":[{"delta":{"content":"x"}}]}`), adapter, metrics); err != nil {
+			t.Fatalf("processChunk failed: %v", err)
+		}
+	}
+
+	if metrics.ChunkCount != 3 {
+		t.Errorf("expected 3 chunks, got %d", metrics.ChunkCount)
+	}
+	if len(metrics.InterChunkGapsMs) != 2 {
+		t.Errorf("expected 2 inter-chunk gaps, got %d", len(metrics.InterChunkGapsMs))
+	}
+	if metrics.TimeToFirstToken() < 0 {
+		t.Errorf("expected non-negative TTFT, got %v", metrics.TimeToFirstToken())
+	}
+}
+
+func TestHandleStream_ClientDisconnectCancelsProvider(t *testing.T) {
+	upstreamCancelled := make(chan struct{})
+	firstChunk := make(chan struct{})
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		close(firstChunk)
+		<-r.Context().Done()
+		close(upstreamCancelled)
+	}))
+	defer provider.Close()
+
+	adapter := adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client())
+	aegisReq := &types.AegisRequest{Model: "gpt-4", Stream: true}
+	providerReq, err := adapter.TransformRequest(context.Background(), aegisReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	go func() {
+		<-firstChunk
+		disconnect()
+	}()
+	req := httptest.NewRequest("POST", "/v1/chat/completions", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	sh := NewStreamingHandler(&Handler{metrics: getTestMetrics()}, DefaultStreamingConfig())
+	sh.HandleStream(w, req, "req-1", providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org"}, aegisReq)
+
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("provider request was not cancelled after the client disconnected")
+	}
+	if strings.Contains(w.Body.String(), "timeout") {
+		t.Errorf("disconnect must not be reported as a timeout:\n%s", w.Body.String())
+	}
+}
+
+func TestHandleStream_FirstChunkTimeoutFromRoutingConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     time.Duration
+		wantError string
+	}{
+		{name: "provider never starts", delay: time.Second, wantError: "first chunk timeout"},
+		{name: "slow start within first chunk deadline", delay: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.(http.Flusher).Flush()
+				select {
+				case <-time.After(tt.delay):
+				case <-r.Context().Done():
+					return
+				}
+				_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+			}))
+			defer provider.Close()
+
+			cfg := &config.Config{Routing: config.RoutingConfig{
+				StreamFirstChunkTimeout: 300 * time.Millisecond,
+				StreamChunkTimeout:      50 * time.Millisecond,
+			}}
+			health := router.NewHealthTracker(5, time.Minute)
+			h := &Handler{metrics: getTestMetrics(), healthTracker: health, cfg: func() *config.Config { return cfg }}
+			sh := NewStreamingHandler(h, DefaultStreamingConfig())
+
+			adapter := adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client())
+			aegisReq := &types.AegisRequest{Model: "gpt-4", Stream: true}
+			providerReq, _ := adapter.TransformRequest(context.Background(), aegisReq)
+			w := httptest.NewRecorder()
+			sh.HandleStream(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), "req-1",
+				providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org"}, aegisReq)
+
+			stats, _ := health.GetStats("openai")
+			if tt.wantError == "" {
+				if !strings.Contains(w.Body.String(), "data: [DONE]") || stats.Failures != 0 {
+					t.Errorf("expected a complete stream and no failure, got failures=%d:\n%s", stats.Failures, w.Body.String())
+				}
+				return
+			}
+			if !strings.Contains(w.Body.String(), tt.wantError) {
+				t.Errorf("expected %q error event:\n%s", tt.wantError, w.Body.String())
+			}
+			if stats.Failures != 1 {
+				t.Errorf("expected the timeout recorded as a provider failure, got %d", stats.Failures)
+			}
+		})
+	}
+}
+
+func TestHandleStream_MalformedPassThroughChunkFailsProvider(t *testing.T) {
+	// A chunk cut short, as chaos malformed_chunk_rate produces.
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n"+
+			"data: {\"choices\":[{\"delta\":{\"con\n\n"+
+			"data: [DONE]\n\n")
+	}))
+	defer provider.Close()
+
+	cfg := config.DefaultConfig()
+	health := router.NewHealthTracker(5, time.Minute)
+	h := &Handler{metrics: getTestMetrics(), healthTracker: health, cfg: func() *config.Config { return cfg }}
+	sh := NewStreamingHandler(h, DefaultStreamingConfig())
+
+	adapter := adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client())
+	if !passThroughStream(adapter) {
+		t.Fatal("the OpenAI adapter should pass its stream through")
+	}
+	aegisReq := &types.AegisRequest{Model: "gpt-4", Stream: true}
+	providerReq, _ := adapter.TransformRequest(context.Background(), aegisReq)
+	w := httptest.NewRecorder()
+	sh.HandleStream(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), "req-1",
+		providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org"}, aegisReq)
+
+	body := w.Body.String()
+	if strings.Contains(body, `"con`+"\n") || strings.Contains(body, "data: [DONE]") {
+		t.Errorf("the malformed chunk must not reach the client:\n%s", body)
+	}
+	if !strings.Contains(body, "invalid stream chunk") {
+		t.Errorf("expected an invalid chunk error event:\n%s", body)
+	}
+	if stats, _ := health.GetStats("openai"); stats.Failures != 1 {
+		t.Errorf("expected the malformed chunk recorded as a provider failure, got %d", stats.Failures)
+	}
+}
+
+func TestHandleStream_KeepAlivePingsDuringProviderSilence(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		_, _ = io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer provider.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Server.StreamKeepAliveInterval = 30 * time.Millisecond
+	sh := NewStreamingHandler(&Handler{metrics: getTestMetrics(), cfg: func() *config.Config { return cfg }}, DefaultStreamingConfig())
+
+	adapter := adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client())
+	aegisReq := &types.AegisRequest{Model: "gpt-4", Stream: true}
+	providerReq, _ := adapter.TransformRequest(context.Background(), aegisReq)
+	w := httptest.NewRecorder()
+	sh.HandleStream(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), "req-1",
+		providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org"}, aegisReq)
+
+	body := w.Body.String()
+	ping := strings.Index(body, ": ping\n\n")
+	if ping < 0 || ping > strings.Index(body, `"hi"`) {
+		t.Errorf("expected pings before the first chunk:\n%s", body)
+	}
+	if !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("pings must not disturb the stream:\n%s", body)
+	}
+}
+
+func TestHandleStream_ErrorEventOnProviderDisconnect(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+	}{
+		{name: "complete stream", body: "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\ndata: [DONE]\n\n"},
+		{name: "provider closes early", body: "data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n", wantCode: "provider_disconnected"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			defer provider.Close()
+
+			sh := NewStreamingHandler(&Handler{metrics: getTestMetrics()}, DefaultStreamingConfig())
+			adapter := adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: provider.URL}, provider.Client())
+			aegisReq := &types.AegisRequest{Model: "gpt-4", Stream: true}
+			providerReq, _ := adapter.TransformRequest(context.Background(), aegisReq)
+			w := httptest.NewRecorder()
+			sh.HandleStream(w, httptest.NewRequest("POST", "/v1/chat/completions", nil), "req-1",
+				providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org"}, aegisReq)
+
+			body := w.Body.String()
+			if tt.wantCode == "" {
+				if strings.Contains(body, "event: error") || !strings.Contains(body, "data: [DONE]") {
+					t.Errorf("expected a clean completion:\n%s", body)
+				}
+				return
+			}
+			i := strings.Index(body, "event: error\ndata: ")
+			if i < 0 || strings.Contains(body, "[DONE]") {
+				t.Fatalf("expected an error event and no [DONE]:\n%s", body)
+			}
+			line, _, _ := strings.Cut(body[i+len("event: error\ndata: "):], "\n")
+			var ev httputil.APIError
+			if err := json.Unmarshal([]byte(line), &ev); err != nil {
+				t.Fatalf("error event is not JSON: %v", err)
+			}
+			if ev.Error.Code != tt.wantCode || ev.Error.AegisReqID != "req-1" {
+				t.Errorf("error event = %+v, want code %q", ev.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+// passThroughAdapter fails every TransformStreamChunk call, so any chunk it
+// forwards must have bypassed the transform.
+type passThroughAdapter struct {
+	mockStreamAdapter
+}
+
+func (a *passThroughAdapter) TransformStreamChunk([]byte) ([]byte, error) {
+	return nil, errors.New("transform called for a pass-through stream")
+}
+
+func (a *passThroughAdapter) PassThroughStream() bool { return true }
+
+func TestProcessChunk_PassThroughSkipsTransform(t *testing.T) {
+	sh := NewStreamingHandler(&Handler{}, DefaultStreamingConfig())
+	adapter := &passThroughAdapter{mockStreamAdapter{name: "openai"}}
+	w := httptest.NewRecorder()
+	metrics := &StreamMetrics{StartTime: time.Now()}
+
+	line := `data: {"model":"gpt-4","choices":[{"delta":{"content":"hi"}}]}`
+	if err := sh.processChunk(w, w, []byte(line), adapter, metrics); err != nil {
+		t.Fatalf("processChunk failed: %v", err)
+	}
+	if got := w.Body.String(); got != line+"\n\n" {
+		t.Errorf("expected chunk forwarded unchanged, got %q", got)
+	}
+	if metrics.ChunkCount != 1 || metrics.Model != "gpt-4" {
+		t.Errorf("expected the chunk to be counted, got %+v", metrics)
+	}
+}
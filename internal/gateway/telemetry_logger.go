@@ -14,6 +14,9 @@ import (
 type TelemetryLogger struct {
 	metrics       *telemetry.Metrics
 	usageRecorder *storage.UsageRecorder
+	// sampled is whether the request's log line is kept; metrics and usage
+	// are always recorded.
+	sampled bool
 }
 
 // LogCompletedRequest logs a completed request with all metrics.
@@ -25,38 +28,46 @@ func (tl *TelemetryLogger) LogCompletedRequest(
 	project string,
 	stream bool,
 	totalDuration time.Duration,
+	providerLatency time.Duration,
 ) {
-	slog.Info("request completed",
-		"request_id", reqID,
-		"model_requested", originalModel,
-		"model_served", aegisResp.Model,
-		"provider", aegisResp.Provider,
-		"prompt_tokens", aegisResp.Usage.PromptTokens,
-		"completion_tokens", aegisResp.Usage.CompletionTokens,
-		"total_tokens", aegisResp.Usage.TotalTokens,
-		"estimated_cost_usd", aegisResp.EstimatedCostUSD,
-		"duration_ms", totalDuration.Milliseconds(),
-		"status_code", 200,
-		"stream", stream,
-		"classification", string(authInfo.MaxClassification),
-		"org_id", authInfo.OrganizationID,
-		"team_id", authInfo.TeamID,
-	)
+	gatewayOverhead := totalDuration - providerLatency
+
+	if tl.sampled {
+		slog.Info("request completed",
+			"request_id", reqID,
+			"model_requested", originalModel,
+			"model_served", aegisResp.Model,
+			"provider", aegisResp.Provider,
+			"prompt_tokens", aegisResp.Usage.PromptTokens,
+			"completion_tokens", aegisResp.Usage.CompletionTokens,
+			"total_tokens", aegisResp.Usage.TotalTokens,
+			"estimated_cost_usd", aegisResp.EstimatedCostUSD,
+			"duration_ms", totalDuration.Milliseconds(),
+			"provider_latency_ms", providerLatency.Milliseconds(),
+			"gateway_overhead_ms", gatewayOverhead.Milliseconds(),
+			"status_code", 200,
+			"stream", stream,
+			"classification", string(authInfo.MaxClassification),
+			"org_id", authInfo.OrganizationID,
+			"team_id", authInfo.TeamID,
+		)
+	}
 
 	// Record Prometheus metrics
 	if tl.metrics != nil {
 		tl.metrics.RecordRequest(telemetry.RequestLabels{
-			Org:              authInfo.OrganizationID,
-			Team:             authInfo.TeamID,
-			Model:            originalModel,
-			Provider:         aegisResp.Provider,
-			Status:           "200",
-			Classification:   string(authInfo.MaxClassification),
-			DurationMs:       float64(totalDuration.Milliseconds()),
-			OverheadMs:       float64(totalDuration.Milliseconds()),
-			PromptTokens:     aegisResp.Usage.PromptTokens,
-			CompletionTokens: aegisResp.Usage.CompletionTokens,
-			CostUSD:          aegisResp.EstimatedCostUSD,
+			Org:               authInfo.OrganizationID,
+			Team:              authInfo.TeamID,
+			Model:             originalModel,
+			Provider:          aegisResp.Provider,
+			Status:            "200",
+			Classification:    string(authInfo.MaxClassification),
+			DurationMs:        float64(totalDuration.Milliseconds()),
+			OverheadMs:        durationMs(gatewayOverhead),
+			ProviderLatencyMs: durationMs(providerLatency),
+			PromptTokens:      aegisResp.Usage.PromptTokens,
+			CompletionTokens:  aegisResp.Usage.CompletionTokens,
+			CostUSD:           aegisResp.EstimatedCostUSD,
 		})
 	}
 
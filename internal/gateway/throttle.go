@@ -0,0 +1,79 @@
+package gateway
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// waitForProvider queues req on the provider's outbound rate limit, if it
+// has one. It reports true when the request must not be sent: a 429 with
+// Retry-After has been written, or the client went away while queued.
+func (h *Handler) waitForProvider(w http.ResponseWriter, r *http.Request, reqID, provider string, req *types.AegisRequest) bool {
+	throttle := h.registry.Throttle(provider)
+	if throttle == nil {
+		return false
+	}
+	tokens := estimateRequestTokens(req)
+	if limit := req.CompletionTokenLimit(); limit != nil {
+		tokens += *limit
+	}
+
+	waited, err := throttle.Wait(r.Context(), tokens)
+	if err == nil {
+		if waited > 0 && h.metrics != nil {
+			h.metrics.RecordProviderThrottle(provider, "queued", waited)
+		}
+		return false
+	}
+
+	var throttled *router.ThrottledError
+	if !errors.As(err, &throttled) {
+		slog.Info("client disconnected while queued for provider rate limit",
+			"request_id", reqID,
+			"provider", provider,
+		)
+		return true
+	}
+	slog.Warn("provider outbound rate limit reached",
+		"request_id", reqID,
+		"provider", provider,
+		"retry_after", throttled.RetryAfter,
+	)
+	if h.metrics != nil {
+		h.metrics.RecordProviderThrottle(provider, "rejected", 0)
+	}
+	httputil.WriteRetryableCode(w, reqID, httputil.CodeProviderRateLimited, "Provider is at its outbound rate limit; retry later",
+		map[string]any{"provider": provider}, time.Now().Add(throttled.RetryAfter))
+	return true
+}
+
+// observeRateLimits reads the provider's rate limit headers off resp into
+// metrics and the health tracker, which routes around providers that are
+// nearly out of quota. Error responses count too: a 429 reports zero left.
+func (h *Handler) observeRateLimits(provider string, resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	status, ok := adapters.ParseRateLimitHeaders(resp.Header, time.Now())
+	if !ok {
+		return
+	}
+	if h.healthTracker != nil {
+		h.healthTracker.ObserveRateLimit(provider, status)
+	}
+	if h.metrics != nil {
+		if w := status.Requests; w != nil {
+			h.metrics.RecordProviderRateLimit(provider, "requests", w.Limit, w.Remaining)
+		}
+		if w := status.Tokens; w != nil {
+			h.metrics.RecordProviderRateLimit(provider, "tokens", w.Limit, w.Remaining)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestWaitForProvider_RejectsWithRetryAfter(t *testing.T) {
+	h := newCompareTestHandler(t)
+	h.registry.SetThrottle("openai", router.NewThrottle("openai", &config.ProviderRateLimitConfig{TPM: 600, MaxWait: 100 * time.Millisecond}))
+	req := &types.AegisRequest{Messages: []types.Message{{Role: "user", Content: "Hi"}}}
+
+	r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+	w := httptest.NewRecorder()
+	if h.waitForProvider(w, r, "req-1", "openai", req) {
+		t.Fatalf("first request should pass: %d %s", w.Code, w.Body.String())
+	}
+
+	// 600 TPM has a 100-token burst; a request that needs the whole of it
+	// again must wait about 10s, well past max_wait.
+	limit := 100
+	req.MaxTokens = &limit
+	w = httptest.NewRecorder()
+	if !h.waitForProvider(w, r, "req-2", "openai", req) {
+		t.Fatal("expected the request to be rejected")
+	}
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("status = %d, Retry-After = %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
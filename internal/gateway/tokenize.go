@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+	"github.com/af-corp/aegis-gateway/internal/validation"
+)
+
+// tokenCountTimeout bounds a provider token counting call.
+const tokenCountTimeout = 10 * time.Second
+
+// Token count methods reported by the tokenize endpoint.
+const (
+	tokenMethodProvider = "provider" // counted by the provider's API
+	tokenMethodEstimate = "estimate" // the gateway's local estimate
+)
+
+// tokenizeResponse is the response of POST /aegis/v1/tokenize. Cost fields
+// are omitted when the served model has no pricing.
+type tokenizeResponse struct {
+	Object        string `json:"object"`
+	Model         string `json:"model"`
+	Provider      string `json:"provider"`
+	ProviderModel string `json:"provider_model"`
+	PromptTokens  int    `json:"prompt_tokens"`
+	Method        string `json:"method"`
+
+	MaxCompletionTokens *int  `json:"max_completion_tokens,omitempty"`
+	ContextWindow       int   `json:"context_window,omitempty"`
+	FitsContextWindow   *bool `json:"fits_context_window,omitempty"`
+
+	// EstimatedPromptCostUSD prices the prompt alone; EstimatedMaxCostUSD
+	// adds max_completion_tokens of output at the completion price.
+	EstimatedPromptCostUSD *float64 `json:"estimated_prompt_cost_usd,omitempty"`
+	EstimatedMaxCostUSD    *float64 `json:"estimated_max_cost_usd,omitempty"`
+}
+
+// Tokenize handles POST /aegis/v1/tokenize: it takes a chat completion
+// request and reports its prompt size and predicted cost for the provider
+// and model AEGIS would route it to right now. Providers with a token
+// counting API (Anthropic count_tokens) count exactly; others get the same
+// local estimate the gateway uses for context window checks. Nothing is
+// generated and no usage is recorded.
+func (h *Handler) Tokenize(w http.ResponseWriter, r *http.Request) {
+	reqID := w.Header().Get("X-Request-ID")
+
+	authInfo, ok := auth.AuthFromContext(r.Context())
+	if !ok {
+		httputil.WriteAuthError(w, reqID, "Not authenticated")
+		return
+	}
+	req, ok := h.readPreviewRequest(w, r, reqID, authInfo)
+	if !ok {
+		return
+	}
+
+	modelsCfg := h.modelsCfg()
+	adapter, providerModel, err := router.ResolveRoute(modelsCfg, h.registry, h.healthTracker, req.Model, string(req.Classification))
+	if err != nil {
+		httputil.WriteHTTPError(w, reqID, routeError(req.Model, err))
+		return
+	}
+
+	resp := tokenizeResponse{
+		Object:              "aegis.tokenize",
+		Model:               req.Model,
+		Provider:            adapter.Name(),
+		ProviderModel:       providerModel,
+		PromptTokens:        estimateRequestTokens(&req),
+		Method:              tokenMethodEstimate,
+		MaxCompletionTokens: req.CompletionTokenLimit(),
+	}
+	if counter, ok := adapter.(adapters.TokenCounter); ok {
+		upstream := req
+		upstream.Model = providerModel
+		ctx, cancel := context.WithTimeout(r.Context(), tokenCountTimeout)
+		n, err := counter.CountTokens(ctx, &upstream)
+		cancel()
+		if err != nil {
+			slog.Warn("provider token count failed, using estimate",
+				"request_id", reqID,
+				"provider", adapter.Name(),
+				"error", err,
+			)
+		} else {
+			resp.PromptTokens, resp.Method = n, tokenMethodProvider
+		}
+	}
+
+	reserve := 0
+	if resp.MaxCompletionTokens != nil {
+		reserve = *resp.MaxCompletionTokens
+	}
+	if window := modelsCfg.Models[req.Model].ContextWindow; window > 0 {
+		fits := resp.PromptTokens+reserve <= window
+		resp.ContextWindow, resp.FitsContextWindow = window, &fits
+	}
+	if h.costCalc != nil {
+		if c, found := h.costCalc.Calculate(adapter.Name(), providerModel, resp.PromptTokens, 0); found {
+			resp.EstimatedPromptCostUSD = &c
+			if resp.MaxCompletionTokens != nil {
+				if maxCost, found := h.costCalc.Calculate(adapter.Name(), providerModel, resp.PromptTokens, reserve); found {
+					resp.EstimatedMaxCostUSD = &maxCost
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// readPreviewRequest reads and checks the chat completion request a preview
+// endpoint sizes up, as far as the gateway would before routing it. It
+// writes an error and returns false if the request would be rejected.
+func (h *Handler) readPreviewRequest(w http.ResponseWriter, r *http.Request, reqID string, authInfo *auth.AuthInfo) (types.AegisRequest, bool) {
+	var req types.AegisRequest
+	limits := h.sizeLimits()
+	body, err := validation.ReadBody(w, r, limits)
+	if err != nil {
+		var tooLarge *validation.TooLargeError
+		if errors.As(err, &tooLarge) {
+			httputil.WritePayloadTooLargeError(w, reqID, tooLarge.Message)
+			return req, false
+		}
+		httputil.WriteBadRequestError(w, reqID, "Failed to read request body")
+		return req, false
+	}
+	defer func() { _ = r.Body.Close() }()
+
+	if err := validation.DecodeJSON(body, &req, h.decodeOptions()); err != nil {
+		httputil.WriteHTTPError(w, reqID, decodeError(err))
+		return req, false
+	}
+	applyModelRules(w, authInfo, &req)
+	if req.Model == "" || len(req.Messages) == 0 {
+		httputil.WriteBadRequestError(w, reqID, "model and messages are required")
+		return req, false
+	}
+	if len(authInfo.AllowedModels) > 0 && !slices.Contains(authInfo.AllowedModels, req.Model) {
+		httputil.WriteHTTPError(w, reqID, modelNotAllowed(req.Model))
+		return req, false
+	}
+	req.RequestID = reqID
+	req.OrganizationID = authInfo.OrganizationID
+	req.TeamID = authInfo.TeamID
+	req.APIKeyID = authInfo.KeyID
+	req.Classification = authInfo.MaxClassification
+	return req, true
+}
+
+// EstimateBodyTokens estimates the tokens a chat completion request body will
+// use against a key's TPM limit: its prompt and tool definitions plus the
+// completion limit, if set. A body that does not parse counts as zero; the
+// handler rejects it anyway.
+func EstimateBodyTokens(body []byte) int64 {
+	var req types.AegisRequest
+	if json.Unmarshal(body, &req) != nil {
+		return 0
+	}
+	n := estimateRequestTokens(&req)
+	if limit := req.CompletionTokenLimit(); limit != nil {
+		n += *limit
+	}
+	return int64(n)
+}
+
+// estimateRequestTokens is estimatePromptTokens plus the tool definitions,
+// which providers bill as prompt tokens too.
+func estimateRequestTokens(req *types.AegisRequest) int {
+	n := estimatePromptTokens(req.Messages)
+	if len(req.Tools) > 0 {
+		if b, err := json.Marshal(req.Tools); err == nil {
+			n += estimateTokens(utf8.RuneCount(b))
+		}
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/cost"
+	"github.com/af-corp/aegis-gateway/internal/router"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+func TestTokenize(t *testing.T) {
+	anthropic := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages/count_tokens" || r.Header.Get("x-api-key") != "sk-ant" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body struct {
+			Model  string `json:"model"`
+			System string `json:"system"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "claude-sonnet" || body.System != "Be brief." {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"input_tokens":1200}`))
+	}))
+	defer anthropic.Close()
+
+	registry := router.NewRegistry()
+	registry.Register("openai", adapters.NewOpenAIAdapter(config.ProviderConfig{BaseURL: "http://unused"}, http.DefaultClient))
+	registry.Register("anthropic", adapters.NewAnthropicAdapter(config.ProviderConfig{BaseURL: anthropic.URL, APIKey: "sk-ant"}, anthropic.Client()))
+	models := &config.ModelsConfig{
+		Models: map[string]config.ModelMapping{
+			"fast":  {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o-mini"}},
+			"smart": {Primary: config.ProviderRoute{Provider: "anthropic", Model: "claude-sonnet"}, ContextWindow: 2000},
+		},
+		Pricing: map[string]map[string]config.PriceEntry{
+			"anthropic": {"claude-sonnet": {Input: 0.003, Output: 0.015}},
+		},
+	}
+	modelsFn := func() *config.ModelsConfig { return models }
+	cfg := config.DefaultConfig()
+	h := NewHandler(registry, nil, modelsFn, func() *config.Config { return cfg },
+		nil, nil, nil, cost.NewCalculator(modelsFn), nil, nil, nil, nil, nil)
+
+	post := func(info *auth.AuthInfo, body string) (*httptest.ResponseRecorder, tokenizeResponse) {
+		req := httptest.NewRequest("POST", "/aegis/v1/tokenize", bytes.NewBufferString(body))
+		req = req.WithContext(auth.ContextWithAuth(req.Context(), info))
+		w := httptest.NewRecorder()
+		w.Header().Set("X-Request-ID", "req-1")
+		h.Tokenize(w, req)
+		var resp tokenizeResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+	info := &auth.AuthInfo{OrganizationID: "org-1"}
+
+	w, resp := post(info, `{"model":"smart","max_tokens":1000,"messages":[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if resp.PromptTokens != 1200 || resp.Method != tokenMethodProvider || resp.Provider != "anthropic" || resp.ProviderModel != "claude-sonnet" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.FitsContextWindow == nil || *resp.FitsContextWindow {
+		t.Error("1200 prompt + 1000 completion tokens should not fit a 2000-token window")
+	}
+	if resp.EstimatedPromptCostUSD == nil || *resp.EstimatedPromptCostUSD != 0.0036 ||
+		resp.EstimatedMaxCostUSD == nil || *resp.EstimatedMaxCostUSD != 0.0186 {
+		t.Errorf("unexpected costs: %s", w.Body.String())
+	}
+
+	w, resp = post(info, `{"model":"fast","messages":[{"role":"user","content":"Hello there, how are you?"}]}`)
+	if w.Code != http.StatusOK || resp.Method != tokenMethodEstimate || resp.PromptTokens != 11 {
+		t.Errorf("openai estimate: status %d, %+v", w.Code, resp)
+	}
+	if resp.EstimatedPromptCostUSD != nil || resp.FitsContextWindow != nil {
+		t.Errorf("unpriced model without a window should omit cost and fit: %s", w.Body.String())
+	}
+
+	if w, _ := post(&auth.AuthInfo{OrganizationID: "org-1", AllowedModels: []string{"fast"}}, `{"model":"smart","messages":[{"role":"user","content":"Hi"}]}`); w.Code != http.StatusForbidden {
+		t.Errorf("disallowed model: status = %d, want 403", w.Code)
+	}
+
+	// Model rules apply before the allowed-models check.
+	steered := &auth.AuthInfo{OrganizationID: "org-1", AllowedModels: []string{"fast"},
+		ModelRules: auth.ModelRules{DefaultModel: "fast", ModelRewrites: map[string]string{"smart": "fast"}}}
+	w, resp = post(steered, `{"model":"smart","messages":[{"role":"user","content":"Hi"}]}`)
+	if w.Code != http.StatusOK || resp.Model != "fast" || w.Header().Get(headerRequestedModel) != "smart" {
+		t.Errorf("rewritten model: status %d, %+v, requested %q", w.Code, resp, w.Header().Get(headerRequestedModel))
+	}
+	if w, resp := post(steered, `{"messages":[{"role":"user","content":"Hi"}]}`); w.Code != http.StatusOK || resp.Model != "fast" {
+		t.Errorf("default model: status %d, %+v", w.Code, resp)
+	}
+}
+
+func TestEstimateBodyTokens(t *testing.T) {
+	prompt := EstimateBodyTokens([]byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"Hello there, how are you?"}]}`))
+	if prompt <= 0 {
+		t.Fatalf("expected a positive estimate, got %d", prompt)
+	}
+	withLimit := EstimateBodyTokens([]byte(`{"model":"gpt-4o","max_tokens":500,"messages":[{"role":"user","content":"Hello there, how are you?"}]}`))
+	if withLimit != prompt+500 {
+		t.Errorf("expected the completion limit on top of the prompt, got %d (prompt %d)", withLimit, prompt)
+	}
+	if got := EstimateBodyTokens([]byte(`not json`)); got != 0 {
+		t.Errorf("expected 0 for an unparseable body, got %d", got)
+	}
+}
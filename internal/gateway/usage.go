@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// Response headers exposing per-call usage so clients can display cost
+// without scraping gateway logs.
+const (
+	headerCostUSD          = "X-Aegis-Cost-USD"
+	headerTokensPrompt     = "X-Aegis-Tokens-Prompt"
+	headerTokensCompletion = "X-Aegis-Tokens-Completion"
+	headerProvider         = "X-Aegis-Provider"
+	headerModelServed      = "X-Aegis-Model-Served"
+
+	// headerProviderLatencyMs lets load tests separate gateway overhead from
+	// provider time on non-streaming responses.
+	headerProviderLatencyMs = "X-Aegis-Provider-Latency-Ms"
+
+	// headerRequestHash carries the request's content hash, the same value
+	// the usage ledger and payload archive record, so clients can key
+	// caches and dedup on it.
+	headerRequestHash = "X-Aegis-Request-Hash"
+
+	// usageEventName is the SSE event sent just before [DONE] on streams,
+	// since headers are already flushed by the time usage is known.
+	usageEventName = "aegis.usage"
+)
+
+// setUsageHeaders sets the X-Aegis usage headers for a non-streaming response.
+// Must be called before the response body is written.
+func setUsageHeaders(w http.ResponseWriter, resp *types.AegisResponse) {
+	h := w.Header()
+	h.Set(headerCostUSD, formatCostUSD(resp.EstimatedCostUSD))
+	h.Set(headerTokensPrompt, strconv.Itoa(resp.Usage.PromptTokens))
+	h.Set(headerTokensCompletion, strconv.Itoa(resp.Usage.CompletionTokens))
+	h.Set(headerProvider, resp.Provider)
+	h.Set(headerModelServed, resp.Model)
+}
+
+// setProviderLatencyHeader sets the provider latency header for a
+// non-streaming response. Must be called before the response body is written.
+func setProviderLatencyHeader(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set(headerProviderLatencyMs, strconv.FormatFloat(durationMs(d), 'f', 1, 64))
+}
+
+func formatCostUSD(cost float64) string {
+	return strconv.FormatFloat(cost, 'f', 6, 64)
+}
+
+// usageEvent is the payload of the final aegis.usage SSE event.
+type usageEvent struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+	// Estimated is set when the provider reported no usage and the token
+	// counts were approximated from the text.
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// writeUsageEvent writes the aegis.usage SSE event for a stream.
+func writeUsageEvent(w http.ResponseWriter, metrics *StreamMetrics, costUSD float64) error {
+	payload, err := json.Marshal(usageEvent{
+		Provider:         metrics.Provider,
+		Model:            metrics.Model,
+		PromptTokens:     metrics.PromptTokens,
+		CompletionTokens: metrics.CompletionTokens,
+		TotalTokens:      metrics.TotalTokens,
+		EstimatedCostUSD: costUSD,
+		Estimated:        metrics.UsageEstimated,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal usage event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", usageEventName, payload)
+	return err
+}
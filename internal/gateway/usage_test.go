@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestSetUsageHeaders(t *testing.T) {
+	w := httptest.NewRecorder()
+	setUsageHeaders(w, &types.AegisResponse{
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		Usage:            types.Usage{PromptTokens: 12, CompletionTokens: 34, TotalTokens: 46},
+		EstimatedCostUSD: 0.00123,
+	})
+
+	want := map[string]string{
+		headerCostUSD:          "0.001230",
+		headerTokensPrompt:     "12",
+		headerTokensCompletion: "34",
+		headerProvider:         "openai",
+		headerModelServed:      "gpt-4o",
+	}
+	for header, value := range want {
+		if got := w.Header().Get(header); got != value {
+			t.Errorf("%s = %q, want %q", header, got, value)
+		}
+	}
+}
+
+func TestSetProviderLatencyHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	setProviderLatencyHeader(w, 1234567*time.Microsecond)
+	if got := w.Header().Get(headerProviderLatencyMs); got != "1234.6" {
+		t.Errorf("%s = %q, want 1234.6", headerProviderLatencyMs, got)
+	}
+}
+
+func TestHandleStream_WritesUsageEventBeforeDone(t *testing.T) {
+	streamData := `data: {"model":"gpt-4","choices":[{"delta":{"content":"Hi"}}]}
+
+data: {"model":"gpt-4","usage":{"prompt_tokens":10,"completion_tokens":8,"total_tokens":18}}
+
+data: [DONE]
+
+`
+	adapter := &mockStreamAdapter{
+		name: "openai",
+		response: &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(streamData)),
+			Header:     make(http.Header),
+		},
+	}
+	sh := NewStreamingHandler(&Handler{}, StreamingConfig{
+		PerChunkTimeout: 5 * time.Second,
+		TotalTimeout:    30 * time.Second,
+		BufferSize:      64 * 1024,
+		MaxBufferSize:   1024 * 1024,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	providerReq, _ := http.NewRequest(http.MethodPost, "http://mock-provider.com", nil)
+	w := httptest.NewRecorder()
+	sh.HandleStream(w, req, "req-usage", providerReq, adapter, "gpt-4", &auth.AuthInfo{OrganizationID: "org-1"}, &types.AegisRequest{Model: "gpt-4", Stream: true})
+
+	if got := w.Header().Get(headerProvider); got != "openai" {
+		t.Errorf("%s = %q, want openai", headerProvider, got)
+	}
+
+	body := w.Body.String()
+	usageAt := strings.Index(body, "event: "+usageEventName+"\n")
+	doneAt := strings.Index(body, "data: [DONE]")
+	if usageAt < 0 {
+		t.Fatalf("expected usage event in stream, got %q", body)
+	}
+	if doneAt < usageAt {
+		t.Errorf("expected usage event before [DONE], got %q", body)
+	}
+	if !strings.Contains(body[usageAt:], `"total_tokens":18`) {
+		t.Errorf("expected usage event to carry total_tokens, got %q", body[usageAt:])
+	}
+}
+
+func TestChatCompletions_RequestHashHeader(t *testing.T) {
+	h, _ := newContextWindowTestHandler(t)
+	messages := []types.Message{{Role: "user", Content: "hello"}}
+	w := postContextWindowRequest(h, messages)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	want := (&types.AegisRequest{Model: "small-model", Messages: messages}).ContentHash()
+	if got := w.Header().Get(headerRequestHash); got != want {
+		t.Errorf("%s = %q, want %q", headerRequestHash, got, want)
+	}
+}
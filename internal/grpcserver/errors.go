@@ -0,0 +1,99 @@
+package grpcserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+)
+
+// errorDomain identifies the gateway in google.rpc.ErrorInfo details.
+const errorDomain = "aegis-gateway"
+
+// codeForHTTP maps a gateway HTTP status to the closest gRPC code.
+func codeForHTTP(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusTooManyRequests, http.StatusPaymentRequired:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable, http.StatusBadGateway:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout, http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	default:
+		return codes.Internal
+	}
+}
+
+// statusFromHTTP converts an OpenAI-style JSON error response into a gRPC
+// status. The gateway error code, type, and request ID are attached as an
+// ErrorInfo detail so clients can branch on them without string matching.
+func statusFromHTTP(statusCode int, body []byte, requestID string) error {
+	var apiErr httputil.APIError
+	msg := http.StatusText(statusCode)
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error.Message != "" {
+		msg = apiErr.Error.Message
+		if apiErr.Error.AegisReqID != "" {
+			requestID = apiErr.Error.AegisReqID
+		}
+	}
+
+	st := status.New(codeForHTTP(statusCode), msg)
+	info := &errdetails.ErrorInfo{
+		Reason: apiErr.Error.Code,
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			"type":        apiErr.Error.Type,
+			"http_status": strconv.Itoa(statusCode),
+			"request_id":  requestID,
+		},
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// statusFromStreamError converts an in-band stream error event into a gRPC
+// status. Timeouts map to DeadlineExceeded and everything else to
+// Unavailable; the event's code, when present, is attached as ErrorInfo.
+func statusFromStreamError(data []byte, msg string) error {
+	var apiErr httputil.APIError
+	_ = json.Unmarshal(data, &apiErr)
+
+	code := codes.Unavailable
+	if strings.HasSuffix(apiErr.Error.Code, "timeout") || strings.Contains(msg, "timeout") {
+		code = codes.DeadlineExceeded
+	}
+	st := status.New(code, msg)
+	if apiErr.Error.Code == "" {
+		return st.Err()
+	}
+	info := &errdetails.ErrorInfo{
+		Reason: apiErr.Error.Code,
+		Domain: errorDomain,
+		Metadata: map[string]string{
+			"type":       apiErr.Error.Type,
+			"request_id": apiErr.Error.AegisReqID,
+		},
+	}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
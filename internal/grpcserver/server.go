@@ -0,0 +1,202 @@
+// Package grpcserver exposes the chat completions API as a native gRPC
+// service (aegis.gateway.v1.GatewayService).
+//
+// Each RPC is bridged onto the gateway's HTTP handler chain, so auth, rate
+// limiting, filters, routing, audit, and usage accounting behave exactly as
+// they do for POST /v1/chat/completions. Callers avoid SSE parsing and get
+// typed status codes instead of JSON error bodies.
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	gatewayv1 "github.com/af-corp/aegis-gateway/gen/gateway/v1"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// chatCompletionsPath is the HTTP route every RPC is dispatched to.
+const chatCompletionsPath = "/v1/chat/completions"
+
+// Server implements gatewayv1.GatewayServiceServer on top of an http.Handler.
+type Server struct {
+	gatewayv1.UnimplementedGatewayServiceServer
+	handler http.Handler
+	logger  *slog.Logger
+}
+
+// New creates a Server that dispatches to handler, normally the gateway's
+// root chi router.
+func New(handler http.Handler, logger *slog.Logger) *Server {
+	return &Server{handler: handler, logger: logger}
+}
+
+// Register installs the service on g.
+func (s *Server) Register(g *grpc.Server) {
+	gatewayv1.RegisterGatewayServiceServer(g, s)
+}
+
+// ChatCompletion handles a unary completion.
+func (s *Server) ChatCompletion(ctx context.Context, req *gatewayv1.ChatCompletionRequest) (*gatewayv1.ChatCompletionResponse, error) {
+	httpReq, err := newHTTPRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newResponseBuffer()
+	s.handler.ServeHTTP(rec, httpReq)
+
+	reqID := rec.header.Get("X-Request-ID")
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", reqID))
+	if rec.status != http.StatusOK {
+		return nil, statusFromHTTP(rec.status, rec.body.Bytes(), reqID)
+	}
+
+	var resp types.AegisResponse
+	if err := json.Unmarshal(rec.body.Bytes(), &resp); err != nil {
+		s.logger.Error("grpc: decode gateway response", "error", err, "request_id", reqID)
+		return nil, status.Error(codes.Internal, "malformed gateway response")
+	}
+	if resp.RequestID == "" {
+		resp.RequestID = reqID
+	}
+	return toProtoResponse(&resp), nil
+}
+
+// StreamChatCompletion handles a server-streaming completion.
+func (s *Server) StreamChatCompletion(req *gatewayv1.ChatCompletionRequest, stream gatewayv1.GatewayService_StreamChatCompletionServer) error {
+	httpReq, err := newHTTPRequest(stream.Context(), req, true)
+	if err != nil {
+		return err
+	}
+
+	w := newStreamWriter(stream)
+	s.handler.ServeHTTP(w, httpReq)
+	return w.finish()
+}
+
+// newHTTPRequest converts an RPC into the equivalent OpenAI-style HTTP
+// request, carrying incoming metadata over as headers.
+func newHTTPRequest(ctx context.Context, req *gatewayv1.ChatCompletionRequest, stream bool) (*http.Request, error) {
+	body := chatRequestBody{
+		Model:       req.GetModel(),
+		Messages:    make([]types.Message, 0, len(req.GetMessages())),
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		MaxTokens:   req.MaxTokens,
+		Stop:        req.GetStop(),
+		Stream:      stream,
+	}
+	for _, m := range req.GetMessages() {
+		body.Messages = append(body.Messages, types.Message{Role: m.GetRole(), Content: m.GetContent(), Name: m.GetName()})
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "encode request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, chatCompletionsPath, bytes.NewReader(payload))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "build request: %v", err)
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") || strings.HasPrefix(key, "grpc-") || key == "content-type" {
+				continue
+			}
+			for _, v := range values {
+				httpReq.Header.Add(key, v)
+			}
+		}
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if req.GetProject() != "" {
+		httpReq.Header.Set("X-Aegis-Project", req.GetProject())
+	}
+	if req.GetPreferProvider() != "" {
+		httpReq.Header.Set("X-Aegis-Prefer-Provider", req.GetPreferProvider())
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		httpReq.RemoteAddr = p.Addr.String()
+	}
+	return httpReq, nil
+}
+
+// chatRequestBody is the JSON body sent to the chat completions handler.
+type chatRequestBody struct {
+	Model       string          `json:"model"`
+	Messages    []types.Message `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
+	MaxTokens   *int32          `json:"max_tokens,omitempty"`
+	Stop        []string        `json:"stop,omitempty"`
+	Stream      bool            `json:"stream"`
+}
+
+func toProtoResponse(resp *types.AegisResponse) *gatewayv1.ChatCompletionResponse {
+	out := &gatewayv1.ChatCompletionResponse{
+		RequestId: resp.RequestID,
+		Model:     resp.Model,
+		Provider:  resp.Provider,
+		Usage:     toProtoUsage(resp.Usage),
+		CostUsd:   resp.EstimatedCostUSD,
+	}
+	for _, c := range resp.Choices {
+		out.Choices = append(out.Choices, &gatewayv1.Choice{
+			Index: int32(c.Index),
+			Message: &gatewayv1.Message{
+				Role:    c.Message.Role,
+				Content: c.Message.Content,
+				Name:    c.Message.Name,
+			},
+			FinishReason: c.FinishReason,
+		})
+	}
+	return out
+}
+
+func toProtoUsage(u types.Usage) *gatewayv1.Usage {
+	return &gatewayv1.Usage{
+		PromptTokens:     int32(u.PromptTokens),
+		CompletionTokens: int32(u.CompletionTokens),
+		TotalTokens:      int32(u.TotalTokens),
+	}
+}
+
+// responseBuffer is a minimal http.ResponseWriter that captures a
+// non-streaming response.
+type responseBuffer struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *responseBuffer) Header() http.Header { return b.header }
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.wroteHeader {
+		return
+	}
+	b.wroteHeader = true
+	b.status = code
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
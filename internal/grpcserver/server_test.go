@@ -0,0 +1,262 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	gatewayv1 "github.com/af-corp/aegis-gateway/gen/gateway/v1"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// dial starts a Server backed by handler on an in-memory listener.
+func dial(t *testing.T, handler http.Handler) gatewayv1.GatewayServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	New(handler, slog.New(slog.NewTextHandler(io.Discard, nil))).Register(g)
+	go func() { _ = g.Serve(lis) }()
+	t.Cleanup(g.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return gatewayv1.NewGatewayServiceClient(conn)
+}
+
+func withKey(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer aegis-test")
+}
+
+func TestChatCompletion_BridgesToHTTP(t *testing.T) {
+	var gotBody chatRequestBody
+	var gotAuth, gotProject string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != chatCompletionsPath {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		gotAuth = r.Header.Get("Authorization")
+		gotProject = r.Header.Get("X-Aegis-Project")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+
+		w.Header().Set("X-Request-ID", "req_1")
+		_ = json.NewEncoder(w).Encode(types.AegisResponse{
+			RequestID:        "req_1",
+			Model:            "gpt-4o-mini",
+			Provider:         "openai",
+			Choices:          []types.Choice{{Message: types.Message{Role: "assistant", Content: "hi"}, FinishReason: "stop"}},
+			Usage:            types.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+			EstimatedCostUSD: 0.0002,
+		})
+	})
+	client := dial(t, handler)
+
+	maxTokens := int32(16)
+	resp, err := client.ChatCompletion(withKey(context.Background()), &gatewayv1.ChatCompletionRequest{
+		Model:     "aegis-fast",
+		Messages:  []*gatewayv1.Message{{Role: "user", Content: "hello"}},
+		MaxTokens: &maxTokens,
+		Project:   "search",
+	})
+	if err != nil {
+		t.Fatalf("ChatCompletion: %v", err)
+	}
+
+	if gotAuth != "Bearer aegis-test" {
+		t.Errorf("authorization not forwarded, got %q", gotAuth)
+	}
+	if gotProject != "search" {
+		t.Errorf("project header = %q, want search", gotProject)
+	}
+	if gotBody.Model != "aegis-fast" || gotBody.Stream || len(gotBody.Messages) != 1 || *gotBody.MaxTokens != 16 {
+		t.Errorf("unexpected HTTP body: %+v", gotBody)
+	}
+	if resp.GetRequestId() != "req_1" || resp.GetProvider() != "openai" || resp.GetCostUsd() != 0.0002 {
+		t.Errorf("unexpected response: %v", resp)
+	}
+	if len(resp.GetChoices()) != 1 || resp.GetChoices()[0].GetMessage().GetContent() != "hi" {
+		t.Errorf("unexpected choices: %v", resp.GetChoices())
+	}
+	if resp.GetUsage().GetTotalTokens() != 4 {
+		t.Errorf("total tokens = %d, want 4", resp.GetUsage().GetTotalTokens())
+	}
+}
+
+func TestChatCompletion_TypedErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		code   codes.Code
+		reason string
+	}{
+		{http.StatusUnauthorized, codes.Unauthenticated, "invalid_api_key"},
+		{http.StatusForbidden, codes.PermissionDenied, "classification_denied"},
+		{http.StatusTooManyRequests, codes.ResourceExhausted, "rate_limit_exceeded"},
+		{http.StatusBadRequest, codes.InvalidArgument, "content_blocked"},
+		{http.StatusServiceUnavailable, codes.Unavailable, "no_provider"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				httputil.WriteError(w, "req_err", tt.status, "gateway_error", tt.reason, "nope")
+			})
+			_, err := dial(t, handler).ChatCompletion(context.Background(), &gatewayv1.ChatCompletionRequest{Model: "aegis-fast"})
+
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Fatalf("code = %s, want %s", st.Code(), tt.code)
+			}
+			if st.Message() != "nope" {
+				t.Errorf("message = %q, want nope", st.Message())
+			}
+			if info := errorInfo(t, err); info.GetReason() != tt.reason || info.GetMetadata()["request_id"] != "req_err" {
+				t.Errorf("unexpected ErrorInfo: %v", info)
+			}
+		})
+	}
+}
+
+// sseHandler writes the given raw SSE lines as a 200 event stream.
+func sseHandler(lines ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("X-Request-ID", "req_stream")
+		w.WriteHeader(http.StatusOK)
+		for _, l := range lines {
+			_, _ = fmt.Fprint(w, l)
+			w.(http.Flusher).Flush()
+		}
+	})
+}
+
+func recvAll(t *testing.T, stream gatewayv1.GatewayService_StreamChatCompletionClient) ([]*gatewayv1.ChatCompletionChunk, error) {
+	t.Helper()
+	var chunks []*gatewayv1.ChatCompletionChunk
+	for {
+		c, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	client := dial(t, sseHandler(
+		`data: {"id":"c1","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`+"\n\n",
+		// A chunk split across writes must still be parsed whole.
+		`data: {"id":"c1","model":"gpt-4o-mini","choices":[{"index":0,"delta":{"content":"lo"},`,
+		`"finish_reason":"stop"}]}`+"\n\n",
+		"event: aegis.usage\n"+`data: {"provider":"openai","model":"gpt-4o-mini","prompt_tokens":5,"completion_tokens":2,"total_tokens":7,"estimated_cost_usd":0.001}`+"\n\n",
+		"data: [DONE]\n\n",
+	))
+
+	stream, err := client.StreamChatCompletion(withKey(context.Background()), &gatewayv1.ChatCompletionRequest{Model: "aegis-fast"})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion: %v", err)
+	}
+	chunks, err := recvAll(t, stream)
+	if err != nil {
+		t.Fatalf("recv: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3: %v", len(chunks), chunks)
+	}
+	if chunks[0].GetRole() != "assistant" || chunks[0].GetDelta()+chunks[1].GetDelta() != "Hello" {
+		t.Errorf("unexpected content chunks: %v %v", chunks[0], chunks[1])
+	}
+	if chunks[1].GetFinishReason() != "stop" {
+		t.Errorf("finish_reason = %q, want stop", chunks[1].GetFinishReason())
+	}
+	last := chunks[2]
+	if last.GetProvider() != "openai" || last.GetUsage().GetTotalTokens() != 7 || last.GetCostUsd() != 0.001 {
+		t.Errorf("unexpected usage chunk: %v", last)
+	}
+
+	md, _ := stream.Header()
+	if got := md.Get("x-request-id"); len(got) != 1 || got[0] != "req_stream" {
+		t.Errorf("x-request-id header = %v", got)
+	}
+}
+
+// errorInfo returns the ErrorInfo detail of a gRPC error.
+func errorInfo(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, d := range status.Convert(err).Details() {
+		if i, ok := d.(*errdetails.ErrorInfo); ok {
+			return i
+		}
+	}
+	t.Fatal("expected ErrorInfo detail")
+	return nil
+}
+
+func TestStreamChatCompletion_InBandTimeout(t *testing.T) {
+	client := dial(t, sseHandler(
+		`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"a"}}]}`+"\n\n",
+		"event: error\n",
+		`data: {"error":{"message":"Provider stalled","type":"server_error","code":"chunk_timeout","aegis_request_id":"req_stream"}}`+"\n\n",
+	))
+
+	stream, err := client.StreamChatCompletion(context.Background(), &gatewayv1.ChatCompletionRequest{Model: "aegis-fast"})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion: %v", err)
+	}
+	chunks, err := recvAll(t, stream)
+	if len(chunks) != 1 {
+		t.Errorf("got %d chunks before error, want 1", len(chunks))
+	}
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("code = %s, want DeadlineExceeded (err=%v)", status.Code(err), err)
+	}
+	if info := errorInfo(t, err); info.GetReason() != "chunk_timeout" || info.GetMetadata()["request_id"] != "req_stream" {
+		t.Errorf("unexpected ErrorInfo %v", info)
+	}
+}
+
+func TestStreamChatCompletion_RejectedBeforeStream(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httputil.WriteAuthError(w, "req_auth", "missing API key")
+	})
+	stream, err := dial(t, handler).StreamChatCompletion(context.Background(), &gatewayv1.ChatCompletionRequest{Model: "aegis-fast"})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion: %v", err)
+	}
+	_, err = recvAll(t, stream)
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("code = %s, want Unauthenticated", status.Code(err))
+	}
+}
+
+func TestStreamChatCompletion_TruncatedStream(t *testing.T) {
+	client := dial(t, sseHandler(`data: {"id":"c1","choices":[{"index":0,"delta":{"content":"a"}}]}`+"\n\n"))
+	stream, err := client.StreamChatCompletion(context.Background(), &gatewayv1.ChatCompletionRequest{Model: "aegis-fast"})
+	if err != nil {
+		t.Fatalf("StreamChatCompletion: %v", err)
+	}
+	if _, err := recvAll(t, stream); status.Code(err) != codes.Unavailable {
+		t.Errorf("code = %s, want Unavailable", status.Code(err))
+	}
+}
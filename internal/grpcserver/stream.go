@@ -0,0 +1,207 @@
+package grpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	gatewayv1 "github.com/af-corp/aegis-gateway/gen/gateway/v1"
+)
+
+// usageEventName matches the SSE event the gateway sends before [DONE].
+const usageEventName = "aegis.usage"
+
+// streamWriter is an http.ResponseWriter and http.Flusher that parses the
+// gateway's SSE output line by line and forwards it as gRPC messages.
+type streamWriter struct {
+	stream      gatewayv1.GatewayService_StreamChatCompletionServer
+	header      http.Header
+	status      int
+	wroteHeader bool
+
+	pending []byte // partial line awaiting its newline
+	errBody bytes.Buffer
+	event   string // current SSE event name, reset on blank line
+	done    bool
+	err     error // first fatal error; stops further processing
+}
+
+func newStreamWriter(stream gatewayv1.GatewayService_StreamChatCompletionServer) *streamWriter {
+	return &streamWriter{stream: stream, header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *streamWriter) Header() http.Header { return w.header }
+
+func (w *streamWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	if code == http.StatusOK {
+		_ = w.stream.SendHeader(metadata.Pairs("x-request-id", w.header.Get("X-Request-ID")))
+	}
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status != http.StatusOK {
+		return w.errBody.Write(p)
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.pending[:i]), "\r")
+		w.pending = w.pending[i+1:]
+		if err := w.handleLine(line); err != nil {
+			w.err = err
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush is a no-op: each parsed event is sent as soon as its line completes.
+func (w *streamWriter) Flush() {}
+
+func (w *streamWriter) handleLine(line string) error {
+	switch {
+	case line == "":
+		w.event = ""
+		return nil
+	case strings.HasPrefix(line, "event: "):
+		w.event = strings.TrimPrefix(line, "event: ")
+		return nil
+	case !strings.HasPrefix(line, "data: "):
+		return nil
+	}
+
+	data := strings.TrimPrefix(line, "data: ")
+	if data == "[DONE]" {
+		w.done = true
+		return nil
+	}
+	if w.event == usageEventName {
+		return w.sendUsage([]byte(data))
+	}
+	if errMsg, ok := streamError([]byte(data)); ok {
+		return statusFromStreamError([]byte(data), errMsg)
+	}
+	return w.sendChunk([]byte(data))
+}
+
+// sseChunk is the subset of an OpenAI chat.completion.chunk the bridge reads.
+type sseChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func (w *streamWriter) sendChunk(data []byte) error {
+	var chunk sseChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return status.Errorf(codes.Internal, "malformed stream chunk: %v", err)
+	}
+	for _, c := range chunk.Choices {
+		msg := &gatewayv1.ChatCompletionChunk{
+			Id:    chunk.ID,
+			Model: chunk.Model,
+			Index: int32(c.Index),
+			Role:  c.Delta.Role,
+			Delta: c.Delta.Content,
+		}
+		if c.FinishReason != nil {
+			msg.FinishReason = *c.FinishReason
+		}
+		if err := w.stream.Send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usagePayload mirrors the aegis.usage SSE event.
+type usagePayload struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	PromptTokens     int32   `json:"prompt_tokens"`
+	CompletionTokens int32   `json:"completion_tokens"`
+	TotalTokens      int32   `json:"total_tokens"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd"`
+}
+
+func (w *streamWriter) sendUsage(data []byte) error {
+	var u usagePayload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return status.Errorf(codes.Internal, "malformed usage event: %v", err)
+	}
+	return w.stream.Send(&gatewayv1.ChatCompletionChunk{
+		Model:    u.Model,
+		Provider: u.Provider,
+		CostUsd:  u.EstimatedCostUSD,
+		Usage: &gatewayv1.Usage{
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			TotalTokens:      u.TotalTokens,
+		},
+	})
+}
+
+// streamError reports whether data is an in-band error event such as
+// {"error": "timeout"} or {"error": {"message": "..."}}.
+func streamError(data []byte) (string, bool) {
+	var probe struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || len(probe.Error) == 0 {
+		return "", false
+	}
+	var msg string
+	if err := json.Unmarshal(probe.Error, &msg); err == nil {
+		return msg, true
+	}
+	var obj struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(probe.Error, &obj); err == nil && obj.Message != "" {
+		return obj.Message, true
+	}
+	return string(probe.Error), true
+}
+
+// finish returns the RPC result once the handler has returned.
+func (w *streamWriter) finish() error {
+	if w.status != http.StatusOK {
+		return statusFromHTTP(w.status, w.errBody.Bytes(), w.header.Get("X-Request-ID"))
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if !w.done {
+		if ctxErr := w.stream.Context().Err(); ctxErr != nil {
+			return status.FromContextError(ctxErr).Err()
+		}
+		return status.Error(codes.Unavailable, "stream ended before completion")
+	}
+	return nil
+}
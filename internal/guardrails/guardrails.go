@@ -0,0 +1,126 @@
+package guardrails
+
+import (
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// DefaultBannerTemplate is used by classification banner rules without a
+// banner_template.
+const DefaultBannerTemplate = "[Classification: {classification}]"
+
+// markdownLink matches inline markdown links and images: [text](url) and
+// ![alt](url "title").
+var markdownLink = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+["'(][^)]*)?\)`)
+
+// Guard selects the response guardrail rules that apply to a request.
+// Rules are read from config on every call so they follow hot reload.
+type Guard struct {
+	cfg func() config.GuardrailsConfig
+}
+
+// New creates a guard driven by the given config.
+func New(cfg func() config.GuardrailsConfig) *Guard {
+	return &Guard{cfg: cfg}
+}
+
+// For returns the rewriter for an org's responses, or nil when no rule
+// applies to it.
+func (g *Guard) For(orgID string, classification types.Classification) *Rewriter {
+	var rules []config.GuardrailRule
+	for _, rule := range g.cfg().Response {
+		if len(rule.Organizations) > 0 && !slices.Contains(rule.Organizations, orgID) {
+			continue
+		}
+		if rule.ClassificationBanner || rule.StripExternalLinks {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &Rewriter{rules: rules, classification: classification}
+}
+
+// Rewriter applies a fixed set of rules to assistant output.
+type Rewriter struct {
+	rules          []config.GuardrailRule
+	classification types.Classification
+}
+
+// Apply rewrites one piece of assistant text. Empty text, such as a reply
+// made only of tool calls, is left alone.
+func (rw *Rewriter) Apply(text string) string {
+	if text == "" {
+		return text
+	}
+	for _, rule := range rw.rules {
+		if rule.StripExternalLinks {
+			text = stripExternalLinks(text, rule.InternalDomains)
+		}
+		if rule.ClassificationBanner {
+			text = addBanner(text, rule, rw.classification)
+		}
+	}
+	return text
+}
+
+// ApplyResponse rewrites every choice of a non-streaming response.
+func (rw *Rewriter) ApplyResponse(resp *types.AegisResponse) {
+	for i := range resp.Choices {
+		resp.Choices[i].Message.Content = rw.Apply(resp.Choices[i].Message.Content)
+	}
+}
+
+func addBanner(text string, rule config.GuardrailRule, classification types.Classification) string {
+	tmpl := rule.BannerTemplate
+	if tmpl == "" {
+		tmpl = DefaultBannerTemplate
+	}
+	label := string(classification)
+	if label == "" {
+		label = "UNSPECIFIED"
+	}
+	banner := strings.ReplaceAll(tmpl, "{classification}", label)
+	if rule.BannerPosition == "top" {
+		return banner + "\n\n" + text
+	}
+	return text + "\n\n" + banner
+}
+
+// stripExternalLinks replaces links and images whose target is an absolute
+// URL outside the internal domains with their text. Relative links stay;
+// hostless schemes such as data: or javascript: are stripped.
+func stripExternalLinks(text string, internal []string) string {
+	return markdownLink.ReplaceAllStringFunc(text, func(m string) string {
+		sub := markdownLink.FindStringSubmatch(m)
+		if !isExternal(sub[3], internal) {
+			return m
+		}
+		return sub[2]
+	})
+}
+
+func isExternal(target string, internal []string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		// Unparseable targets are not trusted.
+		return true
+	}
+	if u.Host == "" {
+		return u.Scheme != ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, d := range internal {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package guardrails
+
+import (
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestGuard_For(t *testing.T) {
+	cfg := config.GuardrailsConfig{Response: []config.GuardrailRule{
+		{Name: "legal", Organizations: []string{"org-legal"}, ClassificationBanner: true},
+		{Name: "noop"},
+	}}
+	g := New(func() config.GuardrailsConfig { return cfg })
+
+	if g.For("org-other", types.ClassInternal) != nil {
+		t.Error("no rule should apply to org-other")
+	}
+	rw := g.For("org-legal", types.ClassConfidential)
+	if rw == nil {
+		t.Fatal("expected the legal rule to apply")
+	}
+	if got := rw.Apply("Hello"); got != "Hello\n\n[Classification: CONFIDENTIAL]" {
+		t.Errorf("Apply() = %q", got)
+	}
+	if got := rw.Apply(""); got != "" {
+		t.Errorf("empty content should stay empty, got %q", got)
+	}
+}
+
+func TestRewriter_Apply(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.GuardrailRule
+		in   string
+		want string
+	}{
+		{
+			name: "top banner with template",
+			rule: config.GuardrailRule{ClassificationBanner: true, BannerPosition: "top", BannerTemplate: "** {classification} **"},
+			in:   "text",
+			want: "** INTERNAL **\n\ntext",
+		},
+		{
+			name: "external links stripped",
+			rule: config.GuardrailRule{StripExternalLinks: true, InternalDomains: []string{"corp.example"}},
+			in:   "See [docs](https://wiki.corp.example/x), [this](https://evil.example/p \"t\") and ![img](http://cdn.other.example/a.png).",
+			want: "See [docs](https://wiki.corp.example/x), this and img.",
+		},
+		{
+			name: "relative links kept, data URLs stripped",
+			rule: config.GuardrailRule{StripExternalLinks: true},
+			in:   "[home](/index.html) [x](data:text/html,hi)",
+			want: "[home](/index.html) x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := &Rewriter{rules: []config.GuardrailRule{tt.rule}, classification: types.ClassInternal}
+			if got := rw.Apply(tt.in); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// Hook is a named transformation registered with a Chain. A hook implements
+// RequestHook, ResponseHook, or both.
+type Hook interface {
+	Name() string
+}
+
+// RequestHook mutates the canonical request after it is validated and
+// filtered, before it is routed. Hooks see content the filter chain has
+// already redacted.
+type RequestHook interface {
+	Hook
+	TransformRequest(ctx context.Context, req *types.AegisRequest) error
+}
+
+// ResponseHook mutates a completed response before it is returned to the
+// client. Streams are held back until they finish so the hook sees, and
+// rewrites, the complete text of each choice.
+type ResponseHook interface {
+	Hook
+	TransformResponse(ctx context.Context, req *types.AegisRequest, resp *types.AegisResponse) error
+}
+
+// Chain runs the hooks listed in config, in order, for the orgs they are
+// enabled for. Go hooks must be registered under the name config uses for
+// them; http hooks are built from their config entry. Config is read on
+// every call so reordering or enabling hooks follows hot reload.
+type Chain struct {
+	cfg    func() []config.HookConfig
+	client *http.Client
+
+	mu         sync.RWMutex
+	registered map[string]Hook
+}
+
+// NewChain creates a hook chain driven by the given config.
+func NewChain(cfg func() []config.HookConfig) *Chain {
+	return &Chain{
+		cfg:        cfg,
+		client:     &http.Client{},
+		registered: make(map[string]Hook),
+	}
+}
+
+// Register makes a compiled-in hook available to config entries of type go.
+func (c *Chain) Register(h Hook) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.registered[h.Name()] = h
+}
+
+// RunRequest applies the request hooks that apply to req's org. Hooks may
+// rewrite content but not the caller's identity, classification or stream
+// mode, which are restored after each hook.
+func (c *Chain) RunRequest(ctx context.Context, req *types.AegisRequest) error {
+	for _, hc := range c.active(config.HookStageRequest, req.OrganizationID) {
+		h, err := c.resolve(hc)
+		if err != nil {
+			if !hc.FailOpen {
+				return err
+			}
+			continue
+		}
+		sh, ok := h.(RequestHook)
+		if !ok {
+			continue
+		}
+		before := *req
+		before.Messages = slices.Clone(req.Messages)
+		err = sh.TransformRequest(ctx, req)
+		req.RequestID = before.RequestID
+		req.OrganizationID = before.OrganizationID
+		req.TeamID = before.TeamID
+		req.UserID = before.UserID
+		req.APIKeyID = before.APIKeyID
+		req.Classification = before.Classification
+		req.Stream = before.Stream
+		req.ReceivedAt = before.ReceivedAt
+		if err != nil {
+			if !hc.FailOpen {
+				return fmt.Errorf("hook %s: %w", hc.Name, err)
+			}
+			*req = before
+			slog.Warn("request hook failed, continuing unchanged",
+				"hook", hc.Name,
+				"request_id", req.RequestID,
+				"error", err,
+			)
+		}
+	}
+	return nil
+}
+
+// RunResponse applies the response hooks that apply to req's org. Usage and
+// cost are restored after each hook so billing reflects what the provider
+// served.
+func (c *Chain) RunResponse(ctx context.Context, req *types.AegisRequest, resp *types.AegisResponse) error {
+	for _, hc := range c.active(config.HookStageResponse, req.OrganizationID) {
+		h, err := c.resolve(hc)
+		if err != nil {
+			if !hc.FailOpen {
+				return err
+			}
+			continue
+		}
+		sh, ok := h.(ResponseHook)
+		if !ok {
+			continue
+		}
+		before := *resp
+		before.Choices = slices.Clone(resp.Choices)
+		err = sh.TransformResponse(ctx, req, resp)
+		resp.RequestID = before.RequestID
+		resp.Usage = before.Usage
+		resp.EstimatedCostUSD = before.EstimatedCostUSD
+		if err != nil {
+			if !hc.FailOpen {
+				return fmt.Errorf("hook %s: %w", hc.Name, err)
+			}
+			*resp = before
+			slog.Warn("response hook failed, continuing unchanged",
+				"hook", hc.Name,
+				"request_id", req.RequestID,
+				"error", err,
+			)
+		}
+	}
+	return nil
+}
+
+// HasResponseHooks reports whether any response hook applies to orgID, so
+// streams that no hook transforms are not held back.
+func (c *Chain) HasResponseHooks(orgID string) bool {
+	return len(c.active(config.HookStageResponse, orgID)) > 0
+}
+
+// active returns the enabled hooks for the stage and org in run order.
+func (c *Chain) active(stage, orgID string) []config.HookConfig {
+	var out []config.HookConfig
+	for _, hc := range c.cfg() {
+		if !hc.Enabled || !hc.RunsAt(stage) {
+			continue
+		}
+		if len(hc.Organizations) > 0 && !slices.Contains(hc.Organizations, orgID) {
+			continue
+		}
+		out = append(out, hc)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Order < out[j].Order })
+	return out
+}
+
+// resolve returns the hook for a config entry. A go hook that was never
+// registered is an error, so a typo cannot silently disable a hook.
+func (c *Chain) resolve(hc config.HookConfig) (Hook, error) {
+	if hc.Type == config.HookTypeHTTP {
+		return newHTTPHook(hc, c.client), nil
+	}
+	c.mu.RLock()
+	h, ok := c.registered[hc.Name]
+	c.mu.RUnlock()
+	if !ok {
+		slog.Warn("hook is configured but not registered", "hook", hc.Name)
+		return nil, fmt.Errorf("hook %s: not registered", hc.Name)
+	}
+	return h, nil
+}
@@ -0,0 +1,180 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// suffixHook appends its suffix to the last message and to every choice.
+type suffixHook struct {
+	name, suffix string
+	err          error
+}
+
+func (h suffixHook) Name() string { return h.name }
+
+func (h suffixHook) TransformRequest(_ context.Context, req *types.AegisRequest) error {
+	req.Messages[len(req.Messages)-1].Content += h.suffix
+	req.OrganizationID = "spoofed"
+	return h.err
+}
+
+func (h suffixHook) TransformResponse(_ context.Context, _ *types.AegisRequest, resp *types.AegisResponse) error {
+	for i := range resp.Choices {
+		resp.Choices[i].Message.Content += h.suffix
+	}
+	resp.Usage.TotalTokens = 0
+	return h.err
+}
+
+func newTestRequest(org string) *types.AegisRequest {
+	return &types.AegisRequest{
+		RequestID:      "req-1",
+		OrganizationID: org,
+		Model:          "aegis-gpt4",
+		Messages:       []types.Message{{Role: "user", Content: "hi"}},
+	}
+}
+
+func TestChain_OrderAndOrgs(t *testing.T) {
+	cfg := []config.HookConfig{
+		{Name: "b", Type: config.HookTypeGo, Enabled: true, Order: 20},
+		{Name: "a", Type: config.HookTypeGo, Enabled: true, Order: 10},
+		{Name: "org2", Type: config.HookTypeGo, Enabled: true, Order: 30, Organizations: []string{"org-2"}},
+		{Name: "off", Type: config.HookTypeGo, Order: 40},
+		{Name: "resp", Type: config.HookTypeGo, Enabled: true, Stages: []string{config.HookStageResponse}},
+	}
+	c := NewChain(func() []config.HookConfig { return cfg })
+	for _, name := range []string{"a", "b", "org2", "off", "resp"} {
+		c.Register(suffixHook{name: name, suffix: "+" + name})
+	}
+
+	req := newTestRequest("org-1")
+	if err := c.RunRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Messages[0].Content; got != "hi+a+b" {
+		t.Errorf("content = %q, want hooks a then b only", got)
+	}
+	if req.OrganizationID != "org-1" {
+		t.Errorf("hooks must not change identity, org = %q", req.OrganizationID)
+	}
+
+	req = newTestRequest("org-2")
+	_ = c.RunRequest(context.Background(), req)
+	if got := req.Messages[0].Content; got != "hi+a+b+org2" {
+		t.Errorf("content = %q, want the org-2 hook to run", got)
+	}
+
+	resp := &types.AegisResponse{
+		Choices: []types.Choice{{Message: types.Message{Role: "assistant", Content: "answer"}}},
+		Usage:   types.Usage{TotalTokens: 7},
+	}
+	if err := c.RunResponse(context.Background(), req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "answer+resp+a+b+org2" {
+		t.Errorf("response content = %q", got)
+	}
+	if resp.Usage.TotalTokens != 7 {
+		t.Error("hooks must not change usage")
+	}
+}
+
+func TestChain_HasResponseHooks(t *testing.T) {
+	cfg := []config.HookConfig{
+		{Name: "req", Type: config.HookTypeGo, Enabled: true, Stages: []string{config.HookStageRequest}},
+		{Name: "resp", Type: config.HookTypeGo, Enabled: true, Stages: []string{config.HookStageResponse}, Organizations: []string{"org-2"}},
+	}
+	c := NewChain(func() []config.HookConfig { return cfg })
+	if c.HasResponseHooks("org-1") {
+		t.Error("org-1 has only a request hook")
+	}
+	if !c.HasResponseHooks("org-2") {
+		t.Error("org-2 has a response hook")
+	}
+}
+
+func TestChain_Failures(t *testing.T) {
+	cfg := []config.HookConfig{
+		{Name: "flaky", Type: config.HookTypeGo, Enabled: true, FailOpen: true},
+		{Name: "strict", Type: config.HookTypeGo, Enabled: true},
+	}
+	c := NewChain(func() []config.HookConfig { return cfg })
+	c.Register(suffixHook{name: "flaky", suffix: "+flaky", err: errors.New("boom")})
+
+	// A fail-open hook is undone; an unregistered strict hook fails the request.
+	req := newTestRequest("org-1")
+	if err := c.RunRequest(context.Background(), req); err == nil {
+		t.Fatal("expected the unregistered hook to fail the request")
+	}
+	if got := req.Messages[0].Content; got != "hi" {
+		t.Errorf("failed fail-open hook left content %q", got)
+	}
+
+	c.Register(suffixHook{name: "strict", suffix: "+strict", err: errors.New("boom")})
+	if err := c.RunRequest(context.Background(), newTestRequest("org-1")); err == nil {
+		t.Fatal("expected the strict hook error to fail the request")
+	}
+}
+
+func TestHTTPHook(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		var p httpPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		if r.Header.Get("X-Request-ID") != "req-1" {
+			t.Errorf("X-Request-ID = %q", r.Header.Get("X-Request-ID"))
+		}
+		switch p.Stage {
+		case config.HookStageRequest:
+			// Leave requests alone.
+			w.WriteHeader(http.StatusNoContent)
+		case config.HookStageResponse:
+			p.Response.Provider = ""
+			p.Response.Choices[0].Message.Content += "\n\nAI-generated content."
+			_ = json.NewEncoder(w).Encode(map[string]any{"response": p.Response})
+		}
+	}))
+	defer srv.Close()
+
+	cfg := []config.HookConfig{{Name: "disclaimer", Type: config.HookTypeHTTP, Enabled: true, URL: srv.URL}}
+	c := NewChain(func() []config.HookConfig { return cfg })
+
+	req := newTestRequest("org-1")
+	if err := c.RunRequest(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Messages[0].Content != "hi" {
+		t.Errorf("204 reply changed the request: %q", req.Messages[0].Content)
+	}
+
+	resp := &types.AegisResponse{
+		RequestID: "req-1",
+		Provider:  "openai",
+		Choices:   []types.Choice{{Message: types.Message{Role: "assistant", Content: "answer"}}},
+	}
+	if err := c.RunResponse(context.Background(), req, resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "" || resp.Choices[0].Message.Content != "answer\n\nAI-generated content." {
+		t.Errorf("unexpected response after hook: %+v", resp)
+	}
+
+	cfg[0].URL = srv.URL + "/missing"
+	if err := c.RunResponse(context.Background(), req, resp); err == nil {
+		t.Error("expected a non-2xx hook reply to fail")
+	}
+}
@@ -0,0 +1,114 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+const (
+	defaultHTTPHookTimeout = 2 * time.Second
+	maxHTTPHookReplyBytes  = 16 << 20
+)
+
+// httpPayload is posted to an external hook. The hook replies 204 to leave
+// things unchanged, or 200 with the request (request stage) or response
+// (response stage) replaced in full.
+type httpPayload struct {
+	Stage    string               `json:"stage"`
+	Request  *types.AegisRequest  `json:"request"`
+	Response *types.AegisResponse `json:"response,omitempty"`
+}
+
+// httpHook calls an external service configured as a hook of type http.
+type httpHook struct {
+	name    string
+	url     string
+	timeout time.Duration
+	client  *http.Client
+}
+
+func newHTTPHook(hc config.HookConfig, client *http.Client) *httpHook {
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPHookTimeout
+	}
+	return &httpHook{name: hc.Name, url: hc.URL, timeout: timeout, client: client}
+}
+
+func (h *httpHook) Name() string { return h.name }
+
+func (h *httpHook) TransformRequest(ctx context.Context, req *types.AegisRequest) error {
+	var reply struct {
+		Request *types.AegisRequest `json:"request"`
+	}
+	changed, err := h.call(ctx, httpPayload{Stage: config.HookStageRequest, Request: req}, &reply)
+	if err != nil || !changed || reply.Request == nil {
+		return err
+	}
+	// Fields that never leave the gateway are kept from the original.
+	reply.Request.ProviderType = req.ProviderType
+	reply.Request.ReceivedAt = req.ReceivedAt
+	reply.Request.EstimatedTokens = req.EstimatedTokens
+	reply.Request.RequestHash = req.RequestHash
+	*req = *reply.Request
+	return nil
+}
+
+func (h *httpHook) TransformResponse(ctx context.Context, req *types.AegisRequest, resp *types.AegisResponse) error {
+	var reply struct {
+		Response *types.AegisResponse `json:"response"`
+	}
+	changed, err := h.call(ctx, httpPayload{Stage: config.HookStageResponse, Request: req, Response: resp}, &reply)
+	if err != nil || !changed || reply.Response == nil {
+		return err
+	}
+	*resp = *reply.Response
+	return nil
+}
+
+// call posts the payload and decodes a 200 reply into out. It reports
+// whether the hook returned a replacement.
+func (h *httpHook) call(ctx context.Context, payload httpPayload, out any) (bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("marshal hook payload: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create hook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-Request-ID", payload.Request.RequestID)
+
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return false, fmt.Errorf("call hook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("hook returned %d", resp.StatusCode)
+	}
+	reply, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPHookReplyBytes))
+	if err != nil {
+		return false, fmt.Errorf("read hook reply: %w", err)
+	}
+	if err := json.Unmarshal(reply, out); err != nil {
+		return false, fmt.Errorf("decode hook reply: %w", err)
+	}
+	return true, nil
+}
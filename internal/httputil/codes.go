@@ -0,0 +1,166 @@
+package httputil
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Error types, the "type" field of an error body. They follow OpenAI's,
+// plus the gateway's own content filter and budget types.
+const (
+	TypeInvalidRequest = "invalid_request_error"
+	TypeAuthentication = "authentication_error"
+	TypePermission     = "permission_error"
+	TypeRateLimit      = "rate_limit_error"
+	TypeContentFilter  = "content_filter_error"
+	TypeBudget         = "budget_error"
+	TypeServer         = "server_error"
+)
+
+// Error codes returned by the chat completion path. Clients should branch
+// on the code; messages are for people and may change.
+const (
+	CodeInvalidRequest         = "invalid_request"
+	CodeRequestTooLarge        = "request_too_large"
+	CodeInvalidAPIKey          = "invalid_api_key"
+	CodeForbidden              = "forbidden"
+	CodeModelNotAllowed        = "model_not_allowed"
+	CodeModelNotFound          = "model_not_found"
+	CodeClassificationExceeded = "classification_exceeded"
+	CodeContextLengthExceeded  = "context_length_exceeded"
+	CodeUnsupportedFeature     = "unsupported_feature"
+	CodeContentBlocked         = "content_blocked"
+	CodePolicyDenied           = "policy_denied"
+	CodeFilterUnavailable      = "filter_unavailable"
+	CodeRateLimitExceeded      = "rate_limit_exceeded"
+	CodeBudgetExceeded         = "budget_exceeded"
+	CodeNoProviderAvailable    = "no_provider_available"
+	CodeProviderTimeout        = "provider_timeout"
+	CodeProviderUnavailable    = "provider_unavailable"
+	CodeProviderError          = "provider_error"
+	CodeProviderAuthError      = "provider_auth_error"
+	CodeProviderRateLimited    = "provider_rate_limited"
+	CodeServiceUnavailable     = "service_unavailable"
+	CodeOverloaded             = "overloaded"
+	CodeMaintenanceWindow      = "maintenance_window"
+	CodeOutsideOfficeHours     = "outside_office_hours"
+	CodeHookFailed             = "hook_failed"
+	CodeInternalError          = "internal_error"
+
+	// Sent in the SSE error event of a stream that fails after it started.
+	CodeFirstChunkTimeout   = "first_chunk_timeout"
+	CodeChunkTimeout        = "chunk_timeout"
+	CodeStreamTimeout       = "stream_timeout"
+	CodeProviderDisconnect  = "provider_disconnected"
+	CodeProviderStreamError = "provider_stream_error"
+	CodeInvalidChunk        = "invalid_provider_chunk"
+	CodeGatewayShutdown     = "gateway_shutdown"
+)
+
+// ErrorCode is one entry of the error catalog.
+type ErrorCode struct {
+	Code        string `json:"code"`
+	Type        string `json:"type"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+var catalog = map[string]ErrorCode{}
+
+func register(code, errType string, status int, description string) {
+	catalog[code] = ErrorCode{Code: code, Type: errType, Status: status, Description: description}
+}
+
+func init() {
+	register(CodeInvalidRequest, TypeInvalidRequest, http.StatusBadRequest, "The request is malformed or fails validation; details.fields names the offending fields.")
+	register(CodeRequestTooLarge, TypeInvalidRequest, http.StatusRequestEntityTooLarge, "The request body exceeds the configured size limit.")
+	register(CodeInvalidAPIKey, TypeAuthentication, http.StatusUnauthorized, "The API key is missing, unknown, expired, or revoked.")
+	register(CodeForbidden, TypePermission, http.StatusForbidden, "The API key may not use this endpoint.")
+	register(CodeModelNotAllowed, TypePermission, http.StatusForbidden, "The API key's allowed_models does not include the requested model.")
+	register(CodeModelNotFound, TypeInvalidRequest, http.StatusNotFound, "The model is not configured on the gateway.")
+	register(CodeClassificationExceeded, TypePermission, http.StatusForbidden, "The request's data classification exceeds every route's ceiling for the model.")
+	register(CodeContextLengthExceeded, TypeInvalidRequest, http.StatusBadRequest, "The prompt plus requested completion does not fit the model's context window.")
+	register(CodeUnsupportedFeature, TypeInvalidRequest, http.StatusBadRequest, "No route for the model supports a feature the request uses (streaming, tools, JSON mode, or its max output tokens); details.missing names them.")
+	register(CodeContentBlocked, TypeContentFilter, 451, "A content filter blocked the request; details.filter names it and, unless disabled, details.findings locates the matches.")
+	register(CodePolicyDenied, TypeContentFilter, 451, "An OPA policy denied the request.")
+	register(CodeFilterUnavailable, TypeServer, http.StatusServiceUnavailable, "A fail-closed content filter could not evaluate the request; details.filter names it.")
+	register(CodeRateLimitExceeded, TypeRateLimit, http.StatusTooManyRequests, "The API key or organization exceeded its rate limit; Retry-After and reset_at give when the window frees up.")
+	register(CodeBudgetExceeded, TypeBudget, http.StatusPaymentRequired, "The organization, team, or conversation budget is used up; daily budgets set Retry-After and reset_at to the next UTC midnight.")
+	register(CodeNoProviderAvailable, TypeServer, http.StatusServiceUnavailable, "Every provider route for the model is unhealthy or quarantined; Retry-After and reset_at give the next circuit probe, when known.")
+	register(CodeProviderTimeout, TypeServer, http.StatusGatewayTimeout, "The provider did not respond within the configured timeout.")
+	register(CodeProviderUnavailable, TypeServer, http.StatusServiceUnavailable, "The provider could not be reached or failed after retries.")
+	register(CodeProviderError, TypeServer, http.StatusBadGateway, "The provider returned a server error or a response the gateway could not read.")
+	register(CodeProviderAuthError, TypeServer, http.StatusBadGateway, "The provider rejected the gateway's credentials.")
+	register(CodeProviderRateLimited, TypeRateLimit, http.StatusTooManyRequests, "The provider's rate limit, or the gateway's outbound limit for it, was reached.")
+	register(CodeServiceUnavailable, TypeServer, http.StatusServiceUnavailable, "A gateway dependency is temporarily unavailable.")
+	register(CodeOverloaded, TypeServer, http.StatusServiceUnavailable, "The gateway is at its in-flight request limit; see Retry-After.")
+	register(CodeMaintenanceWindow, TypeServer, http.StatusServiceUnavailable, "A maintenance window has closed the model; Retry-After and reset_at give when it reopens.")
+	register(CodeOutsideOfficeHours, TypeServer, http.StatusServiceUnavailable, "The model is only available during office hours; Retry-After and reset_at give the next opening.")
+	register(CodeHookFailed, TypeServer, http.StatusBadGateway, "A transformation hook without fail_open errored; streams end with this code as an error event.")
+	register(CodeInternalError, TypeServer, http.StatusInternalServerError, "An unexpected gateway error.")
+
+	register(CodeFirstChunkTimeout, TypeServer, http.StatusOK, "Stream event: the provider sent no data before the first chunk timeout.")
+	register(CodeChunkTimeout, TypeServer, http.StatusOK, "Stream event: the provider stalled between chunks.")
+	register(CodeStreamTimeout, TypeServer, http.StatusOK, "Stream event: the stream exceeded the request timeout.")
+	register(CodeProviderDisconnect, TypeServer, http.StatusOK, "Stream event: the provider closed the stream before it completed.")
+	register(CodeProviderStreamError, TypeServer, http.StatusOK, "Stream event: reading the provider stream failed.")
+	register(CodeInvalidChunk, TypeServer, http.StatusOK, "Stream event: the provider sent a chunk the gateway could not parse.")
+	register(CodeGatewayShutdown, TypeServer, http.StatusOK, "Stream event: the gateway shut down before the stream finished.")
+}
+
+// Codes returns the error catalog sorted by code.
+func Codes() []ErrorCode {
+	out := make([]ErrorCode, 0, len(catalog))
+	for _, c := range catalog {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// LookupCode returns the catalog entry for code.
+func LookupCode(code string) (ErrorCode, bool) {
+	c, ok := catalog[code]
+	return c, ok
+}
+
+// WriteCode writes an error whose status and type come from the catalog.
+// details, if non-nil, is sent as the body's details object. An unknown
+// code is written as a 500.
+func WriteCode(w http.ResponseWriter, requestID, code, message string, details map[string]any) {
+	writeCode(w, requestID, code, message, details, nil)
+}
+
+// WriteRetryableCode is WriteCode for a rejection that clears at resetAt,
+// such as a rate limit window or an open circuit. It sets Retry-After and
+// the body's reset_at; a zero resetAt sets neither.
+func WriteRetryableCode(w http.ResponseWriter, requestID, code, message string, details map[string]any, resetAt time.Time) {
+	if resetAt.IsZero() {
+		writeCode(w, requestID, code, message, details, nil)
+		return
+	}
+	SetRetryAfter(w, resetAt)
+	resetAt = resetAt.UTC().Truncate(time.Second)
+	writeCode(w, requestID, code, message, details, &resetAt)
+}
+
+func writeCode(w http.ResponseWriter, requestID, code, message string, details map[string]any, resetAt *time.Time) {
+	c, ok := catalog[code]
+	if !ok {
+		c = ErrorCode{Type: TypeServer, Status: http.StatusInternalServerError}
+	}
+	writeError(w, requestID, c.Status, APIErrorBody{
+		Message:    message,
+		Type:       c.Type,
+		Code:       code,
+		AegisReqID: requestID,
+		Details:    details,
+		ResetAt:    resetAt,
+	})
+}
+
+// WriteHTTPError writes e under its catalog code.
+func WriteHTTPError(w http.ResponseWriter, requestID string, e *HTTPError) {
+	WriteRetryableCode(w, requestID, e.Code, e.Message, e.Details, e.ResetAt)
+}
@@ -0,0 +1,125 @@
+package httputil
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// corsMethods are the methods the gateway's API exposes to browsers.
+const corsMethods = "GET, POST, PATCH, DELETE, OPTIONS"
+
+// CORS returns middleware that answers preflight requests and adds CORS
+// headers for allowed origins. It must run before auth so browsers can
+// complete the unauthenticated OPTIONS preflight that precedes a POST with an
+// Authorization header, including fetch-based SSE streams. Config is read per
+// request so hot reloads apply.
+func CORS(cfg func() config.CORSConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := cfg()
+			origin := r.Header.Get("Origin")
+			if !c.Enabled || origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if !originAllowed(c.AllowedOrigins, origin) {
+				if preflight {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var allowHeaders string
+			if preflight {
+				var ok bool
+				if allowHeaders, ok = allowedRequestHeaders(c.AllowedHeaders, r.Header.Get("Access-Control-Request-Headers")); !ok {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+			}
+
+			// Echo the origin rather than "*" so credentialed requests work.
+			h.Set("Access-Control-Allow-Origin", origin)
+			if c.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(c.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h.Set("Access-Control-Allow-Methods", corsMethods)
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+			if c.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// originAllowed matches origin against exact entries, "*", and subdomain
+// wildcards of the form "https://*.example.com".
+func originAllowed(allowed []string, origin string) bool {
+	for _, a := range allowed {
+		switch {
+		case a == "*", strings.EqualFold(a, origin):
+			return true
+		case strings.Contains(a, "://*."):
+			scheme, suffix, _ := strings.Cut(a, "://*")
+			if strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) &&
+				len(origin) > len(scheme)+3+len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowedRequestHeaders checks the comma-separated headers a preflight asks
+// for. It returns the value for Access-Control-Allow-Headers and false if any
+// requested header is not allowed.
+func allowedRequestHeaders(allowed []string, requested string) (string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return "", true
+	}
+	set := make(map[string]bool, len(allowed))
+	for _, a := range allowed {
+		if a == "*" {
+			return requested, true
+		}
+		set[http.CanonicalHeaderKey(a)] = true
+	}
+	var out []string
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if !set[http.CanonicalHeaderKey(h)] {
+			return "", false
+		}
+		out = append(out, h)
+	}
+	return strings.Join(out, ", "), true
+}
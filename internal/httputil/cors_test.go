@@ -0,0 +1,158 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func corsHandler(c config.CORSConfig) (http.Handler, *bool) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+	})
+	return CORS(func() config.CORSConfig { return c })(next), &called
+}
+
+func testCORSConfig() config.CORSConfig {
+	c := config.DefaultConfig().CORS
+	c.Enabled = true
+	c.AllowedOrigins = []string{"https://chat.internal", "https://*.corp.example"}
+	return c
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	h, called := corsHandler(testCORSConfig())
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://ui.corp.example")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "authorization, content-type, last-event-id")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if *called {
+		t.Error("preflight should not reach the next handler (auth would reject it)")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://ui.corp.example" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "authorization, content-type, last-event-id" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q, want 600", got)
+	}
+}
+
+func TestCORS_PreflightRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		headers string
+	}{
+		{"unknown origin", "https://evil.example", "authorization"},
+		{"bare wildcard domain", "https://corp.example", "authorization"},
+		{"disallowed header", "https://chat.internal", "x-custom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := corsHandler(testCORSConfig())
+			req := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("expected 403, got %d", w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("unexpected Allow-Origin %q", got)
+			}
+		})
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	c := testCORSConfig()
+	c.AllowCredentials = true
+	h, called := corsHandler(c)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://chat.internal")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if !*called {
+		t.Fatal("expected next handler to run")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://chat.internal" {
+		t.Errorf("Allow-Origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Allow-Credentials = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got == "" {
+		t.Error("expected exposed headers for usage and request ID")
+	}
+}
+
+func TestCORS_DisabledOrSameOrigin(t *testing.T) {
+	disabled := testCORSConfig()
+	disabled.Enabled = false
+
+	for name, tc := range map[string]struct {
+		cfg    config.CORSConfig
+		origin string
+	}{
+		"disabled":       {disabled, "https://chat.internal"},
+		"no origin":      {testCORSConfig(), ""},
+		"unknown origin": {testCORSConfig(), "https://evil.example"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			h, called := corsHandler(tc.cfg)
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tc.origin != "" {
+				req.Header.Set("Origin", tc.origin)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if !*called {
+				t.Error("expected next handler to run")
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+				t.Errorf("unexpected Allow-Origin %q", got)
+			}
+		})
+	}
+}
+
+func TestCORS_WildcardAllowsAnyHeader(t *testing.T) {
+	c := config.CORSConfig{Enabled: true, AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}, MaxAge: time.Minute}
+	h, _ := corsHandler(c)
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/models", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "x-anything")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Headers"); got != "x-anything" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+}
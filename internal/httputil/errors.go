@@ -4,12 +4,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // HTTPError represents an HTTP error with status code and message.
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	// Code, when set, is the catalog code written to the client, with
+	// Details as the body's details object.
+	Code    string
+	Details map[string]any
+	// ResetAt, when set, is when a retry may succeed; it is sent as
+	// Retry-After and the body's reset_at.
+	ResetAt time.Time
 }
 
 // Error implements the error interface.
@@ -25,6 +33,16 @@ func NewHTTPError(statusCode int, message string) *HTTPError {
 	}
 }
 
+// NewCodedError creates an HTTP error for a catalog code, taking its status
+// from the catalog.
+func NewCodedError(code, message string, details map[string]any) *HTTPError {
+	status := http.StatusInternalServerError
+	if c, ok := catalog[code]; ok {
+		status = c.Status
+	}
+	return &HTTPError{StatusCode: status, Message: message, Code: code, Details: details}
+}
+
 // APIError matches the OpenAI error response format.
 type APIError struct {
 	Error APIErrorBody `json:"error"`
@@ -35,46 +53,64 @@ type APIErrorBody struct {
 	Type       string `json:"type"`
 	Code       string `json:"code"`
 	AegisReqID string `json:"aegis_request_id,omitempty"`
+	// Details carries machine-readable specifics of the failure, such as
+	// the filter that blocked a request or the fields that failed
+	// validation. Its keys depend on the code.
+	Details map[string]any `json:"details,omitempty"`
+	// ResetAt is when the limit, budget, or outage that rejected the
+	// request is expected to clear. Retry-After carries the same time,
+	// rounded up and jittered.
+	ResetAt *time.Time `json:"reset_at,omitempty"`
 }
 
 func WriteError(w http.ResponseWriter, requestID string, statusCode int, errType, code, message string) {
+	writeError(w, requestID, statusCode, APIErrorBody{
+		Message:    message,
+		Type:       errType,
+		Code:       code,
+		AegisReqID: requestID,
+	})
+}
+
+func writeError(w http.ResponseWriter, requestID string, statusCode int, body APIErrorBody) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Request-ID", requestID)
 	w.WriteHeader(statusCode)
-	_ = json.NewEncoder(w).Encode(APIError{
-		Error: APIErrorBody{
-			Message:    message,
-			Type:       errType,
-			Code:       code,
-			AegisReqID: requestID,
-		},
-	})
+	_ = json.NewEncoder(w).Encode(APIError{Error: body})
 }
 
 func WriteAuthError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusUnauthorized, "authentication_error", "invalid_api_key", message)
+	WriteCode(w, requestID, CodeInvalidAPIKey, message, nil)
+}
+
+func WriteForbiddenError(w http.ResponseWriter, requestID, message string) {
+	WriteCode(w, requestID, CodeForbidden, message, nil)
 }
 
 func WriteRateLimitError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded", message)
+	WriteCode(w, requestID, CodeRateLimitExceeded, message, nil)
 }
 
 func WriteBadRequestError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusBadRequest, "invalid_request_error", "invalid_request", message)
+	WriteCode(w, requestID, CodeInvalidRequest, message, nil)
+}
+
+func WritePayloadTooLargeError(w http.ResponseWriter, requestID, message string) {
+	WriteCode(w, requestID, CodeRequestTooLarge, message, nil)
 }
 
 func WriteInternalError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusInternalServerError, "server_error", "internal_error", message)
+	WriteCode(w, requestID, CodeInternalError, message, nil)
 }
 
 func WriteServiceUnavailableError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusServiceUnavailable, "server_error", "service_unavailable", message)
+	WriteCode(w, requestID, CodeServiceUnavailable, message, nil)
 }
 
 func WriteContentBlockedError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, 451, "content_filter_error", "content_blocked", message)
+	WriteCode(w, requestID, CodeContentBlocked, message, nil)
 }
 
 func WriteBudgetExceededError(w http.ResponseWriter, requestID, message string) {
-	WriteError(w, requestID, http.StatusPaymentRequired, "budget_error", "budget_exceeded", message)
+	WriteCode(w, requestID, CodeBudgetExceeded, message, nil)
 }
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestWriteError(t *testing.T) {
@@ -62,3 +64,79 @@ func TestWriteContentBlockedError(t *testing.T) {
 		t.Errorf("expected status 451, got %d", w.Code)
 	}
 }
+
+func TestWriteCode(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteCode(w, "req_1", CodeModelNotFound, "Model not found: gpt-9", map[string]any{"model": "gpt-9"})
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	var resp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error.Code != CodeModelNotFound || resp.Error.Type != TypeInvalidRequest {
+		t.Errorf("unexpected error body %+v", resp.Error)
+	}
+	if resp.Error.Details["model"] != "gpt-9" {
+		t.Errorf("expected details.model gpt-9, got %v", resp.Error.Details)
+	}
+
+	w = httptest.NewRecorder()
+	WriteCode(w, "req_2", "no_such_code", "oops", nil)
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500 for unknown code, got %d", w.Code)
+	}
+}
+
+func TestCodes(t *testing.T) {
+	codes := Codes()
+	if len(codes) == 0 {
+		t.Fatal("expected a non-empty catalog")
+	}
+	for i, c := range codes {
+		if c.Type == "" || c.Status == 0 || c.Description == "" {
+			t.Errorf("incomplete catalog entry %+v", c)
+		}
+		if i > 0 && codes[i-1].Code >= c.Code {
+			t.Errorf("catalog not sorted at %s", c.Code)
+		}
+	}
+}
+
+func TestWriteRetryableCode(t *testing.T) {
+	resetAt := time.Now().Add(100 * time.Second)
+	w := httptest.NewRecorder()
+	WriteRetryableCode(w, "req_1", CodeRateLimitExceeded, "slow down", nil, resetAt)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", w.Code)
+	}
+	// Up to a tenth of jitter past the reset.
+	if secs, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || secs < 100 || secs > 110 {
+		t.Errorf("expected Retry-After of 100-110 seconds, got %q", w.Header().Get("Retry-After"))
+	}
+	var resp APIError
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Error.ResetAt == nil || !resp.Error.ResetAt.Equal(resetAt.UTC().Truncate(time.Second)) {
+		t.Errorf("expected reset_at %v, got %v", resetAt, resp.Error.ResetAt)
+	}
+
+	w = httptest.NewRecorder()
+	WriteRetryableCode(w, "req_2", CodeNoProviderAvailable, "down", nil, time.Time{})
+	if w.Header().Get("Retry-After") != "" {
+		t.Errorf("expected no Retry-After without a reset time, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	if got := RetryAfterSeconds(-time.Second); got != 1 {
+		t.Errorf("expected a minimum of 1 second, got %d", got)
+	}
+	if got := RetryAfterSeconds(2500 * time.Millisecond); got != 3 {
+		t.Errorf("expected short waits to round up without jitter, got %d", got)
+	}
+}
@@ -0,0 +1,22 @@
+package httputil
+
+import (
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// SetRetryAfter sets the Retry-After header to the time left until resetAt.
+func SetRetryAfter(w http.ResponseWriter, resetAt time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(RetryAfterSeconds(time.Until(resetAt))))
+}
+
+// RetryAfterSeconds rounds d up to whole seconds, at least one, and adds up
+// to a tenth more at random so clients rejected together do not all retry
+// in the same second. The jitter only ever delays a retry past the reset.
+func RetryAfterSeconds(d time.Duration) int {
+	secs := max(int(math.Ceil(d.Seconds())), 1)
+	return secs + rand.IntN(secs/10+1)
+}
@@ -0,0 +1,168 @@
+// Package janitor runs scheduled cleanup so Postgres and Redis do not grow
+// without bound: old usage and audit rows, long-dead API keys, and rate limit
+// buckets whose expiry was lost.
+package janitor
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+// Metric targets for Redis bucket pruning and key expiry.
+const (
+	TargetRedisBuckets = "redis_buckets"
+	TargetKeyExpiry    = "api_keys_expired"
+)
+
+// RowStore deletes database rows past retention. It is satisfied by
+// *storage.RetentionStore.
+type RowStore interface {
+	Prune(ctx context.Context, table string, before time.Time, batchSize int) (int64, error)
+	ExpireKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+// BucketPruner deletes stale Redis buckets. It is satisfied by
+// *ratelimit.Limiter and *ratelimit.BudgetTracker.
+type BucketPruner interface {
+	PruneStaleBuckets(ctx context.Context) (int64, error)
+}
+
+// Metrics receives janitor metrics. It is satisfied by *telemetry.Metrics.
+type Metrics interface {
+	RecordJanitorDeleted(target string, n int64)
+	RecordJanitorError(target string)
+	ObserveJanitorRun(d time.Duration)
+}
+
+// tablePlan maps each pruned table to the retention period that governs it.
+var tablePlan = []struct {
+	table     string
+	retention func(config.RetentionConfig) time.Duration
+}{
+	{storage.TableRequestUsage, func(c config.RetentionConfig) time.Duration { return c.UsageRetention }},
+	{storage.TableUsageRecords, func(c config.RetentionConfig) time.Duration { return c.UsageRetention }},
+	{storage.TableUsageDaily, func(c config.RetentionConfig) time.Duration { return c.UsageRetention }},
+	{storage.TableAuditEvents, func(c config.RetentionConfig) time.Duration { return c.AuditRetention }},
+	// audit_logs before api_keys: keys are only deleted once unreferenced.
+	{storage.TableAuditLogs, func(c config.RetentionConfig) time.Duration { return c.AuditRetention }},
+	{storage.TableAPIKeys, func(c config.RetentionConfig) time.Duration { return c.ExpiredKeyRetention }},
+	{storage.TableBatches, func(c config.RetentionConfig) time.Duration { return c.BatchRetention }},
+	{storage.TableConversations, func(c config.RetentionConfig) time.Duration { return c.ConversationRetention }},
+}
+
+// Result summarises one janitor run.
+type Result struct {
+	Deleted map[string]int64 // rows or keys removed, by target
+	Errors  map[string]error // failures, by target
+}
+
+// Janitor prunes data on a schedule. Configuration is read on every run so
+// retention changes apply on hot reload.
+type Janitor struct {
+	rows    RowStore
+	buckets []BucketPruner
+	cfg     func() config.RetentionConfig
+	metrics Metrics
+	now     func() time.Time
+}
+
+// New creates a janitor. rows may be nil when no database is configured.
+func New(rows RowStore, cfg func() config.RetentionConfig, buckets ...BucketPruner) *Janitor {
+	return &Janitor{
+		rows:    rows,
+		buckets: buckets,
+		cfg:     cfg,
+		now:     time.Now,
+	}
+}
+
+// SetMetrics enables janitor metrics.
+func (j *Janitor) SetMetrics(m Metrics) {
+	j.metrics = m
+}
+
+// Start runs the janitor every Interval until ctx is cancelled. Runs are
+// skipped while retention is disabled.
+func (j *Janitor) Start(ctx context.Context) {
+	go func() {
+		for {
+			interval := j.cfg().Interval
+			if interval <= 0 {
+				interval = config.DefaultConfig().Retention.Interval
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			if !j.cfg().Enabled {
+				continue
+			}
+			res := j.RunOnce(ctx)
+			for target, err := range res.Errors {
+				slog.Error("retention cleanup failed", "target", target, "error", err)
+			}
+			slog.Info("retention cleanup complete", "deleted", res.Deleted)
+		}
+	}()
+}
+
+// RunOnce performs a single cleanup pass. A failure on one target is
+// recorded and the remaining targets still run.
+func (j *Janitor) RunOnce(ctx context.Context) Result {
+	start := j.now()
+	cfg := j.cfg()
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = config.DefaultConfig().Retention.BatchSize
+	}
+	res := Result{Deleted: map[string]int64{}, Errors: map[string]error{}}
+
+	if j.rows != nil {
+		n, err := j.rows.ExpireKeys(ctx, start)
+		j.record(&res, TargetKeyExpiry, n, err)
+
+		for _, p := range tablePlan {
+			retention := p.retention(cfg)
+			if retention <= 0 {
+				continue
+			}
+			n, err := j.rows.Prune(ctx, p.table, start.Add(-retention), batchSize)
+			j.record(&res, p.table, n, err)
+		}
+	}
+
+	var bucketsDeleted int64
+	for _, b := range j.buckets {
+		n, err := b.PruneStaleBuckets(ctx)
+		bucketsDeleted += n
+		if err != nil {
+			j.record(&res, TargetRedisBuckets, 0, err)
+		}
+	}
+	j.record(&res, TargetRedisBuckets, bucketsDeleted, nil)
+
+	if j.metrics != nil {
+		j.metrics.ObserveJanitorRun(j.now().Sub(start))
+	}
+	return res
+}
+
+func (j *Janitor) record(res *Result, target string, n int64, err error) {
+	if n > 0 {
+		res.Deleted[target] += n
+		if j.metrics != nil {
+			j.metrics.RecordJanitorDeleted(target, n)
+		}
+	}
+	if err != nil {
+		res.Errors[target] = err
+		if j.metrics != nil {
+			j.metrics.RecordJanitorError(target)
+		}
+	}
+}
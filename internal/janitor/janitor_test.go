@@ -0,0 +1,146 @@
+package janitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/storage"
+)
+
+type pruneCall struct {
+	table     string
+	before    time.Time
+	batchSize int
+}
+
+type fakeRows struct {
+	calls   []pruneCall
+	deleted map[string]int64
+	fail    map[string]error
+	expired int64
+}
+
+func (f *fakeRows) Prune(_ context.Context, table string, before time.Time, batchSize int) (int64, error) {
+	f.calls = append(f.calls, pruneCall{table, before, batchSize})
+	return f.deleted[table], f.fail[table]
+}
+
+func (f *fakeRows) ExpireKeys(context.Context, time.Time) (int64, error) {
+	return f.expired, nil
+}
+
+type fakeBuckets struct {
+	n   int64
+	err error
+}
+
+func (f fakeBuckets) PruneStaleBuckets(context.Context) (int64, error) { return f.n, f.err }
+
+type fakeMetrics struct {
+	mu      sync.Mutex
+	deleted map[string]int64
+	errors  map[string]int
+	runs    int
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{deleted: map[string]int64{}, errors: map[string]int{}}
+}
+
+func (m *fakeMetrics) RecordJanitorDeleted(target string, n int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted[target] += n
+}
+
+func (m *fakeMetrics) RecordJanitorError(target string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[target]++
+}
+
+func (m *fakeMetrics) ObserveJanitorRun(time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runs++
+}
+
+func TestRunOnce_PrunesEachTableWithItsRetention(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	rows := &fakeRows{
+		deleted: map[string]int64{storage.TableRequestUsage: 120, storage.TableAuditEvents: 7},
+		expired: 3,
+	}
+	cfg := config.RetentionConfig{
+		UsageRetention:      400 * 24 * time.Hour,
+		AuditRetention:      90 * 24 * time.Hour,
+		ExpiredKeyRetention: 0, // keep keys forever
+		BatchSize:           100,
+	}
+	m := newFakeMetrics()
+	j := New(rows, func() config.RetentionConfig { return cfg }, fakeBuckets{n: 4}, fakeBuckets{n: 1})
+	j.SetMetrics(m)
+	j.now = func() time.Time { return now }
+
+	res := j.RunOnce(context.Background())
+
+	if len(res.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", res.Errors)
+	}
+	wantBefore := map[string]time.Time{
+		storage.TableRequestUsage: now.Add(-cfg.UsageRetention),
+		storage.TableUsageRecords: now.Add(-cfg.UsageRetention),
+		storage.TableUsageDaily:   now.Add(-cfg.UsageRetention),
+		storage.TableAuditEvents:  now.Add(-cfg.AuditRetention),
+		storage.TableAuditLogs:    now.Add(-cfg.AuditRetention),
+	}
+	if len(rows.calls) != len(wantBefore) {
+		t.Fatalf("expected %d prune calls, got %+v", len(wantBefore), rows.calls)
+	}
+	for _, c := range rows.calls {
+		if want, ok := wantBefore[c.table]; !ok || !c.before.Equal(want) || c.batchSize != 100 {
+			t.Errorf("unexpected prune call %+v", c)
+		}
+	}
+	if res.Deleted[storage.TableRequestUsage] != 120 || res.Deleted[TargetRedisBuckets] != 5 || res.Deleted[TargetKeyExpiry] != 3 {
+		t.Errorf("unexpected deleted counts: %v", res.Deleted)
+	}
+	if m.deleted[storage.TableAuditEvents] != 7 || m.runs != 1 {
+		t.Errorf("unexpected metrics: deleted=%v runs=%d", m.deleted, m.runs)
+	}
+}
+
+func TestRunOnce_ContinuesPastFailures(t *testing.T) {
+	rows := &fakeRows{fail: map[string]error{storage.TableRequestUsage: errors.New("lock timeout")}}
+	m := newFakeMetrics()
+	j := New(rows, func() config.RetentionConfig { return config.DefaultConfig().Retention },
+		fakeBuckets{err: errors.New("redis down")}, fakeBuckets{n: 2})
+	j.SetMetrics(m)
+
+	res := j.RunOnce(context.Background())
+
+	if res.Errors[storage.TableRequestUsage] == nil || res.Errors[TargetRedisBuckets] == nil {
+		t.Fatalf("expected failures recorded, got %v", res.Errors)
+	}
+	if len(rows.calls) != len(tablePlan) {
+		t.Errorf("expected every table attempted, got %d calls", len(rows.calls))
+	}
+	if res.Deleted[TargetRedisBuckets] != 2 {
+		t.Errorf("expected surviving bucket pruner counted, got %v", res.Deleted)
+	}
+	if m.errors[storage.TableRequestUsage] != 1 || m.errors[TargetRedisBuckets] != 1 {
+		t.Errorf("unexpected error metrics: %v", m.errors)
+	}
+}
+
+func TestRunOnce_NoDatabase(t *testing.T) {
+	j := New(nil, func() config.RetentionConfig { return config.DefaultConfig().Retention }, fakeBuckets{n: 1})
+	res := j.RunOnce(context.Background())
+	if len(res.Errors) != 0 || res.Deleted[TargetRedisBuckets] != 1 {
+		t.Errorf("unexpected result without a database: %+v", res)
+	}
+}
@@ -3,9 +3,13 @@ package ratelimit
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"math"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
 )
 
 // BudgetResult is the outcome of a budget check.
@@ -13,12 +17,28 @@ type BudgetResult struct {
 	Allowed    bool
 	SpentCents int64
 	LimitCents int64
+	// ResetAt is when the daily budget resets (the next UTC midnight), or,
+	// when Redis is unavailable, when the check is worth retrying.
+	ResetAt time.Time
+}
+
+// SpendSource reports durable spend, used to rebuild a missing Redis counter
+// (e.g. after a Redis restart or eviction). It is satisfied by
+// *storage.UsageRecorder.
+type SpendSource interface {
+	TeamSpendSince(ctx context.Context, teamID string, since time.Time) (float64, error)
 }
 
+// budgetSeedTTL bounds how long a counter rebuilt from the ledger is trusted
+// before it is re-read, so spend recorded meanwhile is picked up.
+const budgetSeedTTL = time.Minute
+
 // BudgetTracker tracks daily spend per team via Redis.
 type BudgetTracker struct {
 	rdb            *redis.Client
 	circuitBreaker *RedisCircuitBreaker
+	spendSource    SpendSource
+	strictTenancy  bool
 }
 
 // NewBudgetTracker creates a budget tracker with circuit breaker protection.
@@ -35,21 +55,32 @@ func NewBudgetTracker(rdb *redis.Client) *BudgetTracker {
 	}
 }
 
-func dailyBudgetKey(teamID string) string {
+// SetSpendSource enables rebuilding missing daily counters from durable spend.
+func (b *BudgetTracker) SetSpendSource(src SpendSource) {
+	b.spendSource = src
+}
+
+// SetStrictTenancy keeps each organization's counters under its own key
+// prefix. Call before serving traffic.
+func (b *BudgetTracker) SetStrictTenancy(strict bool) {
+	b.strictTenancy = strict
+}
+
+func (b *BudgetTracker) dailyBudgetKey(orgID, teamID string) string {
 	day := time.Now().UTC().Format("2006-01-02")
-	return fmt.Sprintf("aegis:budget:daily:%s:%s", teamID, day)
+	return fmt.Sprintf("%sbudget:daily:%s:%s", tenant.RedisPrefix(b.strictTenancy, orgID), teamID, day)
 }
 
 // CheckDailySpend checks if the team is under their daily spend limit.
 //
 // Security: FAILS CLOSED when Redis is unavailable (circuit breaker open).
-func (b *BudgetTracker) CheckDailySpend(ctx context.Context, teamID string, limitCents int64) (BudgetResult, error) {
+func (b *BudgetTracker) CheckDailySpend(ctx context.Context, orgID, teamID string, limitCents int64) (BudgetResult, error) {
 	// If Redis is not configured at all, allow (not a security risk, just no budget tracking)
 	if b.rdb == nil {
-		return BudgetResult{Allowed: true, LimitCents: limitCents}, nil
+		return BudgetResult{Allowed: true, LimitCents: limitCents, ResetAt: nextUTCMidnight(time.Now())}, nil
 	}
 
-	key := dailyBudgetKey(teamID)
+	key := b.dailyBudgetKey(orgID, teamID)
 	var spent int64
 	var getErr error
 
@@ -70,6 +101,7 @@ func (b *BudgetTracker) CheckDailySpend(ctx context.Context, teamID string, limi
 			Allowed:    false,
 			SpentCents: 0,
 			LimitCents: limitCents,
+			ResetAt:    time.Now().Add(b.circuitBreaker.RetryAfter()),
 		}, ErrRedisUnavailable
 	}
 
@@ -79,30 +111,62 @@ func (b *BudgetTracker) CheckDailySpend(ctx context.Context, teamID string, limi
 			Allowed:    false,
 			SpentCents: 0,
 			LimitCents: limitCents,
+			ResetAt:    time.Now().Add(redisRetryAfter),
 		}, ErrRedisUnavailable
 	}
 
+	if getErr == redis.Nil && b.spendSource != nil {
+		spent = b.seedFromLedger(ctx, key, teamID)
+	}
+
 	return BudgetResult{
 		Allowed:    spent < limitCents,
 		SpentCents: spent,
 		LimitCents: limitCents,
+		ResetAt:    nextUTCMidnight(time.Now()),
 	}, nil
 }
 
+// nextUTCMidnight returns the start of the UTC day after now, when daily
+// budget counters roll over.
+func nextUTCMidnight(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
+
 // RecordSpend adds cost to the team's daily spend counter.
-func (b *BudgetTracker) RecordSpend(ctx context.Context, teamID string, costCents int64) error {
+func (b *BudgetTracker) RecordSpend(ctx context.Context, orgID, teamID string, costCents int64) error {
 	if b.rdb == nil || costCents <= 0 {
 		return nil
 	}
 
-	key := dailyBudgetKey(teamID)
+	key := b.dailyBudgetKey(orgID, teamID)
 	pipe := b.rdb.Pipeline()
 	pipe.IncrBy(ctx, key, costCents)
 	// Expire at end of day UTC + 1 hour buffer
-	now := time.Now().UTC()
-	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
-	ttl := endOfDay.Sub(now) + time.Hour
+	now := time.Now()
+	ttl := nextUTCMidnight(now).Sub(now) + time.Hour
 	pipe.Expire(ctx, key, ttl)
 	_, err := pipe.Exec(ctx)
 	return err
 }
+
+// seedFromLedger loads today's spend from the spend source and caches it in
+// Redis briefly. Errors are logged and treated as zero spend, matching the
+// behaviour before a durable source existed.
+func (b *BudgetTracker) seedFromLedger(ctx context.Context, key, teamID string) int64 {
+	now := time.Now().UTC()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	usd, err := b.spendSource.TeamSpendSince(ctx, teamID, startOfDay)
+	if err != nil {
+		slog.Warn("failed to load team spend from usage ledger", "team_id", teamID, "error", err)
+		return 0
+	}
+	cents := int64(math.Round(usd * 100))
+	if cents > 0 {
+		if err := b.rdb.SetNX(ctx, key, cents, budgetSeedTTL).Err(); err != nil {
+			slog.Warn("failed to cache team spend", "team_id", teamID, "error", err)
+		}
+	}
+	return cents
+}
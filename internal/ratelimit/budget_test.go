@@ -7,7 +7,7 @@ import (
 
 func TestBudgetTracker_NilRedis_FailOpen(t *testing.T) {
 	b := NewBudgetTracker(nil)
-	result, err := b.CheckDailySpend(context.Background(), "team-1", 10000)
+	result, err := b.CheckDailySpend(context.Background(), "org-1", "team-1", 10000)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -22,7 +22,7 @@ func TestBudgetTracker_NilRedis_FailOpen(t *testing.T) {
 func TestBudgetTracker_NilRedis_RecordSpend(t *testing.T) {
 	b := NewBudgetTracker(nil)
 	// RecordSpend should be a no-op with nil Redis
-	err := b.RecordSpend(context.Background(), "team-1", 500)
+	err := b.RecordSpend(context.Background(), "org-1", "team-1", 500)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -30,7 +30,7 @@ func TestBudgetTracker_NilRedis_RecordSpend(t *testing.T) {
 
 func TestBudgetTracker_NilRedis_ZeroCost(t *testing.T) {
 	b := NewBudgetTracker(nil)
-	err := b.RecordSpend(context.Background(), "team-1", 0)
+	err := b.RecordSpend(context.Background(), "org-1", "team-1", 0)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -120,6 +120,18 @@ func (cb *RedisCircuitBreaker) GetState() CircuitState {
 	return cb.state
 }
 
+// RetryAfter estimates how long until an open circuit lets calls through
+// again: the next health probe or the end of the open timeout, whichever
+// comes first. It is zero when the circuit is not open.
+func (cb *RedisCircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if cb.state != StateOpen {
+		return 0
+	}
+	return max(min(cb.timeout-time.Since(cb.lastFailureTime), cb.healthCheckInterval), 0)
+}
+
 // recordResult updates circuit breaker state based on operation result.
 func (cb *RedisCircuitBreaker) recordResult(err error) {
 	cb.mu.Lock()
@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+)
+
+// pruneScanCount is the SCAN page size used when looking for stale buckets.
+const pruneScanCount = 1000
+
+// PruneStaleBuckets deletes rate limit buckets that have no expiry. Check
+// sets a TTL on every write, so a bucket without one was left behind by an
+// interrupted write or an older release and would otherwise live forever.
+// It returns the number of keys deleted.
+func (l *Limiter) PruneStaleBuckets(ctx context.Context) (int64, error) {
+	if l.rdb == nil {
+		return 0, nil
+	}
+	return scanAndDelete(ctx, l.rdb, tenant.RedisPattern(l.strictTenancy, "rl:"), func(keys []string) ([]string, error) {
+		pipe := l.rdb.Pipeline()
+		ttls := make([]*redis.DurationCmd, len(keys))
+		for i, k := range keys {
+			ttls[i] = pipe.TTL(ctx, k)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+			return nil, err
+		}
+		var stale []string
+		for i, cmd := range ttls {
+			// -1 means the key exists without an expiry; -2 means it is gone.
+			if cmd.Val() == -1 {
+				stale = append(stale, keys[i])
+			}
+		}
+		return stale, nil
+	})
+}
+
+// PruneStaleBuckets deletes daily budget counters for days before today
+// (UTC). They normally expire an hour after midnight; this catches counters
+// whose expiry was lost. It returns the number of keys deleted.
+func (b *BudgetTracker) PruneStaleBuckets(ctx context.Context) (int64, error) {
+	if b.rdb == nil {
+		return 0, nil
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	return scanAndDelete(ctx, b.rdb, tenant.RedisPattern(b.strictTenancy, "budget:daily:"), func(keys []string) ([]string, error) {
+		var stale []string
+		for _, k := range keys {
+			if isPastBudgetDay(k, today) {
+				stale = append(stale, k)
+			}
+		}
+		return stale, nil
+	})
+}
+
+// isPastBudgetDay reports whether a daily budget key is for a day before
+// today. Keys end in ":YYYY-MM-DD", which sorts lexically by date.
+func isPastBudgetDay(key, today string) bool {
+	i := strings.LastIndexByte(key, ':')
+	if i < 0 {
+		return false
+	}
+	day := key[i+1:]
+	if _, err := time.Parse("2006-01-02", day); err != nil {
+		return false
+	}
+	return day < today
+}
+
+// scanAndDelete walks keys matching pattern and deletes those selected by
+// stale, one SCAN page at a time.
+func scanAndDelete(ctx context.Context, rdb *redis.Client, pattern string, stale func([]string) ([]string, error)) (int64, error) {
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := rdb.Scan(ctx, cursor, pattern, pruneScanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+		if len(keys) > 0 {
+			victims, err := stale(keys)
+			if err != nil {
+				return deleted, err
+			}
+			if len(victims) > 0 {
+				n, err := rdb.Del(ctx, victims...).Result()
+				deleted += n
+				if err != nil {
+					return deleted, err
+				}
+			}
+		}
+		if next == 0 {
+			return deleted, nil
+		}
+		cursor = next
+	}
+}
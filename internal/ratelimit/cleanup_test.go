@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsPastBudgetDay(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"aegis:budget:daily:team-1:2026-10-14", true},
+		{"aegis:budget:daily:team-1:2025-12-31", true},
+		{"aegis:budget:daily:team-1:2026-10-15", false},
+		{"aegis:budget:daily:team-1:2026-10-16", false},
+		{"aegis:budget:daily:team-1", false},
+		{"aegis:budget:daily:team:not-a-date", false},
+	}
+	for _, tt := range tests {
+		if got := isPastBudgetDay(tt.key, "2026-10-15"); got != tt.want {
+			t.Errorf("isPastBudgetDay(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestPruneStaleBuckets_NilRedis(t *testing.T) {
+	if n, err := NewLimiter(nil).PruneStaleBuckets(context.Background()); n != 0 || err != nil {
+		t.Errorf("Limiter.PruneStaleBuckets = %d, %v; want 0, nil", n, err)
+	}
+	if n, err := NewBudgetTracker(nil).PruneStaleBuckets(context.Background()); n != 0 || err != nil {
+		t.Errorf("BudgetTracker.PruneStaleBuckets = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -2,17 +2,24 @@ package ratelimit
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
 )
 
+// redisRetryAfter is the retry guidance after a single failed Redis call,
+// before the circuit breaker has opened.
+const redisRetryAfter = 10 * time.Second
+
 // LimitResult is the outcome of a rate limit check.
 type LimitResult struct {
 	Allowed   bool
 	Remaining int64
-	ResetAt   time.Time
+	// ResetAt is when the oldest request in the window expires, freeing a
+	// slot.
+	ResetAt    time.Time
 	RetryAfter time.Duration
 }
 
@@ -20,6 +27,9 @@ type LimitResult struct {
 type Limiter struct {
 	rdb            *redis.Client
 	circuitBreaker *RedisCircuitBreaker
+	strictTenancy  bool
+	tokenEstimator TokenEstimator
+	maxBody        func() int64
 }
 
 // NewLimiter creates a new rate limiter with circuit breaker protection.
@@ -36,13 +46,28 @@ func NewLimiter(rdb *redis.Client) *Limiter {
 	}
 }
 
+// SetStrictTenancy keeps each organization's buckets under its own key
+// prefix. Call before serving traffic.
+func (l *Limiter) SetStrictTenancy(strict bool) {
+	l.strictTenancy = strict
+}
+
+// SetTokenEstimator enables the TPM limits of keys that set tpm_limit,
+// checked alongside RPM with each request's tokens as estimated by estimate
+// from at most maxBody bytes of its body, the configured request body limit.
+// Call before serving traffic.
+func (l *Limiter) SetTokenEstimator(estimate TokenEstimator, maxBody func() int64) {
+	l.tokenEstimator = estimate
+	l.maxBody = maxBody
+}
+
 // slidingWindowScript atomically: removes expired entries, adds current, counts.
 // KEYS[1] = sorted set key
 // ARGV[1] = window start (unix micro)
 // ARGV[2] = now (unix micro) — used as both score and member uniqueness
 // ARGV[3] = limit
 // ARGV[4] = TTL seconds for the key
-// Returns: [current_count, 1=allowed/0=denied]
+// Returns: [current_count, 1=allowed/0=denied, oldest entry's score]
 var slidingWindowScript = redis.NewScript(`
 local key = KEYS[1]
 local window_start = tonumber(ARGV[1])
@@ -56,20 +81,21 @@ local count = redis.call('ZCARD', key)
 if count < limit then
     redis.call('ZADD', key, now, now .. ':' .. math.random(1000000))
     redis.call('EXPIRE', key, ttl)
-    return {count + 1, 1}
+    return {count + 1, 1, tonumber(redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')[2])}
 end
 
 redis.call('EXPIRE', key, ttl)
-return {count, 0}
+return {count, 0, tonumber(redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')[2])}
 `)
 
 // Check performs a sliding-window rate limit check.
+// org: the organization that owns the bucket
 // key: the rate limit bucket identifier
 // limit: maximum allowed requests in the window
 // window: the sliding window duration
 //
 // Security: FAILS CLOSED when Redis is unavailable (circuit breaker open).
-func (l *Limiter) Check(ctx context.Context, key string, limit int64, window time.Duration) (LimitResult, error) {
+func (l *Limiter) Check(ctx context.Context, org, key string, limit int64, window time.Duration) (LimitResult, error) {
 	// If Redis is not configured at all, allow (not a security risk, just no rate limiting)
 	if l.rdb == nil {
 		return LimitResult{Allowed: true, Remaining: limit - 1, ResetAt: time.Now().Add(window)}, nil
@@ -80,7 +106,7 @@ func (l *Limiter) Check(ctx context.Context, key string, limit int64, window tim
 	nowMicro := now.UnixMicro()
 	ttlSecs := int64(window.Seconds()) + 1
 
-	redisKey := fmt.Sprintf("aegis:rl:%s", key)
+	redisKey := tenant.RedisPrefix(l.strictTenancy, org) + "rl:" + key
 
 	// Use circuit breaker to wrap Redis call
 	var result []int64
@@ -101,7 +127,7 @@ func (l *Limiter) Check(ctx context.Context, key string, limit int64, window tim
 			Allowed:    false,
 			Remaining:  0,
 			ResetAt:    now.Add(window),
-			RetryAfter: 30 * time.Second, // Circuit breaker timeout
+			RetryAfter: l.circuitBreaker.RetryAfter(),
 		}, ErrRedisUnavailable
 	}
 
@@ -111,7 +137,7 @@ func (l *Limiter) Check(ctx context.Context, key string, limit int64, window tim
 			Allowed:    false,
 			Remaining:  0,
 			ResetAt:    now.Add(window),
-			RetryAfter: 10 * time.Second,
+			RetryAfter: redisRetryAfter,
 		}, ErrRedisUnavailable
 	}
 
@@ -122,10 +148,14 @@ func (l *Limiter) Check(ctx context.Context, key string, limit int64, window tim
 		remaining = 0
 	}
 
+	// The window frees a slot when its oldest entry expires.
 	resetAt := now.Add(window)
+	if len(result) > 2 && result[2] > 0 {
+		resetAt = time.UnixMicro(result[2]).Add(window)
+	}
 	var retryAfter time.Duration
 	if !allowed {
-		retryAfter = window / 2 // conservative estimate
+		retryAfter = max(resetAt.Sub(now), 0)
 	}
 
 	return LimitResult{
@@ -8,7 +8,7 @@ import (
 
 func TestLimiter_NilRedis_FailOpen(t *testing.T) {
 	l := NewLimiter(nil)
-	result, err := l.Check(context.Background(), "test:key", 60, time.Minute)
+	result, err := l.Check(context.Background(), "org-1", "test:key", 60, time.Minute)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -24,9 +24,24 @@ func TestLimiter_NilRedis_MultipleChecks(t *testing.T) {
 	l := NewLimiter(nil)
 	// Without Redis, every check passes (fail open)
 	for i := 0; i < 100; i++ {
-		result, _ := l.Check(context.Background(), "test:key", 10, time.Minute)
+		result, _ := l.Check(context.Background(), "org-1", "test:key", 10, time.Minute)
 		if !result.Allowed {
 			t.Fatalf("expected allowed on check %d", i)
 		}
 	}
 }
+
+func TestLimiter_NilRedis_Usage(t *testing.T) {
+	usage, err := NewLimiter(nil).Usage(context.Background(), "org-1", "key-1")
+	if err != nil || usage != (KeyUsage{}) {
+		t.Errorf("expected no usage without Redis, got %+v, %v", usage, err)
+	}
+}
+
+func TestEntryTokens(t *testing.T) {
+	for member, want := range map[string]int64{"1700000000000000:42:1500": 1500, "1700000000000000:42": 42, "bad": 0} {
+		if got := entryTokens(member); got != want {
+			t.Errorf("entryTokens(%q) = %d, want %d", member, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/telemetry"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// HeaderPriority lets a client lower a request's priority below its key's.
+const HeaderPriority = "X-Aegis-Priority"
+
+// LoadShedder enforces a server-wide cap on concurrent requests. Unlike the
+// per-key limits it needs no Redis round trip, so an overloaded gateway can
+// reject excess work immediately instead of queueing it.
+//
+// Batch-priority work may only fill a share of the cap (see SetBatchRatio);
+// the remainder is held for interactive traffic, which is therefore the last
+// to be shed.
+type LoadShedder struct {
+	inFlight   atomic.Int64
+	limit      func() int
+	retryAfter func() time.Duration
+	batchRatio func() float64
+	metrics    *telemetry.Metrics
+}
+
+// NewLoadShedder creates a shedder. limit and retryAfter are read per request
+// so config reloads apply; a limit of zero or less disables shedding.
+func NewLoadShedder(limit func() int, retryAfter func() time.Duration, metrics *telemetry.Metrics) *LoadShedder {
+	return &LoadShedder{limit: limit, retryAfter: retryAfter, metrics: metrics}
+}
+
+// SetBatchRatio sets the share of the limit, in (0, 1], that batch-priority
+// work may fill. Without it batch work is shed only at the full limit.
+func (s *LoadShedder) SetBatchRatio(ratio func() float64) {
+	s.batchRatio = ratio
+}
+
+// InFlight returns the number of requests currently admitted.
+func (s *LoadShedder) InFlight() int {
+	return int(s.inFlight.Load())
+}
+
+// capacity returns the in-flight level beyond which work of priority p is
+// shed, or zero for no cap.
+func (s *LoadShedder) capacity(p types.Priority) int64 {
+	limit := int64(s.limit())
+	if limit <= 0 || p != types.PriorityBatch || s.batchRatio == nil {
+		return max(limit, 0)
+	}
+	ratio := s.batchRatio()
+	if ratio <= 0 || ratio > 1 {
+		return limit
+	}
+	return max(int64(float64(limit)*ratio), 1)
+}
+
+// Middleware admits requests up to the limit and answers the rest with 503
+// and Retry-After. Requests for which exempt returns true (health, admin) are
+// never counted or shed, so operators can still observe and fix an
+// overloaded gateway. Only the X-Aegis-Priority header is known here; a
+// key's own priority is applied by Prioritize after authentication.
+func (s *LoadShedder) Middleware(exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt != nil && exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			n := s.inFlight.Add(1)
+			defer func() { s.setInFlight(s.inFlight.Add(-1)) }()
+
+			priority, ok := types.ParsePriority(r.Header.Get(HeaderPriority))
+			if !ok {
+				priority = types.PriorityInteractive
+			}
+			if c := s.capacity(priority); c > 0 && n > c {
+				s.shed(w, r, priority, n-1, c)
+				return
+			}
+
+			s.setInFlight(n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Prioritize resolves the priority of authenticated requests: the key's
+// priority, lowered by X-Aegis-Priority if the client asks (a header can never
+// raise it). The result is stored on the request's AuthInfo, and batch
+// requests are shed once in-flight work exceeds the batch share. It must run
+// after auth and inside Middleware, with the same exempt function.
+func (s *LoadShedder) Prioritize(exempt func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authInfo, ok := auth.AuthFromContext(r.Context())
+			if !ok || (exempt != nil && exempt(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			reqID := w.Header().Get("X-Request-ID")
+
+			priority := authInfo.Priority
+			if priority == "" {
+				priority = types.PriorityInteractive
+			}
+			if v := r.Header.Get(HeaderPriority); v != "" {
+				requested, ok := types.ParsePriority(v)
+				if !ok {
+					httputil.WriteError(w, reqID, http.StatusBadRequest, "invalid_request_error", "invalid_priority",
+						fmt.Sprintf("%s must be interactive or batch", HeaderPriority))
+					return
+				}
+				priority = priority.Lower(requested)
+			}
+
+			if c := s.capacity(priority); c > 0 && int64(s.InFlight()) > c {
+				s.shed(w, r, priority, int64(s.InFlight())-1, c)
+				return
+			}
+
+			if priority != authInfo.Priority {
+				info := *authInfo
+				info.Priority = priority
+				r = r.WithContext(auth.ContextWithAuth(r.Context(), &info))
+			}
+
+			s.trackPriority(priority, 1)
+			defer s.trackPriority(priority, -1)
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			if s.metrics != nil {
+				s.metrics.RecordPriorityRequest(string(priority), time.Since(start))
+			}
+		})
+	}
+}
+
+// Admit counts internally dispatched work, such as batch items, against the
+// limit. It reports false without counting anything when work of priority p
+// would be shed; otherwise release must be called once the work is done.
+func (s *LoadShedder) Admit(p types.Priority) (release func(), ok bool) {
+	n := s.inFlight.Add(1)
+	if c := s.capacity(p); c > 0 && n > c {
+		s.setInFlight(s.inFlight.Add(-1))
+		return nil, false
+	}
+	s.setInFlight(n)
+	s.trackPriority(p, 1)
+	return func() {
+		s.trackPriority(p, -1)
+		s.setInFlight(s.inFlight.Add(-1))
+	}, true
+}
+
+func (s *LoadShedder) shed(w http.ResponseWriter, r *http.Request, priority types.Priority, inFlight, capacity int64) {
+	reqID := w.Header().Get("X-Request-ID")
+	slog.Warn("load shed: gateway at capacity",
+		"request_id", reqID,
+		"priority", priority,
+		"in_flight", inFlight,
+		"capacity", capacity,
+		"max_in_flight", s.limit(),
+		"path", r.URL.Path,
+	)
+	if s.metrics != nil {
+		s.metrics.RecordLoadShed(string(priority))
+	}
+	httputil.WriteRetryableCode(w, reqID, httputil.CodeOverloaded, "Gateway is at capacity, retry shortly", nil,
+		time.Now().Add(s.retryAfter()))
+}
+
+func (s *LoadShedder) setInFlight(n int64) {
+	if s.metrics != nil {
+		s.metrics.SetInFlightRequests(int(n))
+	}
+}
+
+func (s *LoadShedder) trackPriority(p types.Priority, delta int) {
+	if s.metrics != nil {
+		s.metrics.AddPriorityInFlight(string(p), delta)
+	}
+}
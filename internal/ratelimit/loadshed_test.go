@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestLoadShedder_ShedsOverLimit(t *testing.T) {
+	shedder := NewLoadShedder(func() int { return 2 }, func() time.Duration { return 1500 * time.Millisecond }, nil)
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	isAdmin := func(r *http.Request) bool { return strings.HasPrefix(r.URL.Path, "/aegis/") }
+	h := shedder.Middleware(isAdmin)(slow)
+
+	// Fill both slots.
+	var done sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		}()
+	}
+	started.Wait()
+	if got := shedder.InFlight(); got != 2 {
+		t.Fatalf("InFlight() = %d, want 2", got)
+	}
+
+	// A third API request is shed immediately.
+	w := httptest.NewRecorder()
+	w.Header().Set("X-Request-ID", "req-shed")
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want 2", got)
+	}
+	if !strings.Contains(w.Body.String(), "overloaded") {
+		t.Errorf("expected overloaded error code, got %s", w.Body.String())
+	}
+
+	// Health and admin endpoints are still served.
+	started.Add(1)
+	adminDone := make(chan int, 1)
+	go func() {
+		aw := httptest.NewRecorder()
+		h.ServeHTTP(aw, httptest.NewRequest(http.MethodGet, "/aegis/v1/health", nil))
+		adminDone <- aw.Code
+	}()
+	started.Wait()
+
+	close(release)
+	done.Wait()
+	if code := <-adminDone; code != http.StatusOK {
+		t.Errorf("admin request got %d, want 200", code)
+	}
+	if got := shedder.InFlight(); got != 0 {
+		t.Errorf("InFlight() = %d after completion, want 0", got)
+	}
+}
+
+func TestLoadShedder_ZeroLimitDisabled(t *testing.T) {
+	shedder := NewLoadShedder(func() int { return 0 }, func() time.Duration { return time.Second }, nil)
+	h := shedder.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200 with shedding disabled, got %d", w.Code)
+	}
+}
+
+func TestLoadShedder_BatchYieldsToInteractive(t *testing.T) {
+	shedder := NewLoadShedder(func() int { return 4 }, func() time.Duration { return time.Second }, nil)
+	shedder.SetBatchRatio(func() float64 { return 0.5 })
+
+	// Two batch items fill the batch share; a third is refused.
+	r1, ok1 := shedder.Admit(types.PriorityBatch)
+	r2, ok2 := shedder.Admit(types.PriorityBatch)
+	if !ok1 || !ok2 {
+		t.Fatal("batch work under the batch share should be admitted")
+	}
+	if _, ok := shedder.Admit(types.PriorityBatch); ok {
+		t.Error("batch work beyond the batch share should be refused")
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := shedder.Middleware(nil)(ok)
+
+	// Interactive traffic still has the remaining headroom.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("interactive request got %d, want 200", w.Code)
+	}
+
+	// A request that marks itself batch is shed before authentication.
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set(HeaderPriority, "batch")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("batch request got %d, want 503", w.Code)
+	}
+
+	r1()
+	r2()
+	if got := shedder.InFlight(); got != 0 {
+		t.Errorf("InFlight() = %d after release, want 0", got)
+	}
+}
+
+func TestLoadShedder_PrioritizeResolvesKeyAndHeader(t *testing.T) {
+	shedder := NewLoadShedder(func() int { return 0 }, func() time.Duration { return time.Second }, nil)
+	var got types.Priority
+	h := shedder.Prioritize(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := auth.AuthFromContext(r.Context())
+		got = info.Priority
+	}))
+
+	tests := []struct {
+		name   string
+		key    types.Priority
+		header string
+		want   types.Priority
+		status int
+	}{
+		{"default", "", "", types.PriorityInteractive, http.StatusOK},
+		{"header lowers", types.PriorityInteractive, "batch", types.PriorityBatch, http.StatusOK},
+		{"header cannot raise", types.PriorityBatch, "interactive", types.PriorityBatch, http.StatusOK},
+		{"invalid header", "", "urgent", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tt.header != "" {
+				req.Header.Set(HeaderPriority, tt.header)
+			}
+			req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{KeyID: "k", Priority: tt.key}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.status || got != tt.want {
+				t.Errorf("status %d priority %q, want %d %q", w.Code, got, tt.status, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadShedder_PrioritizeShedsBatchKeys(t *testing.T) {
+	shedder := NewLoadShedder(func() int { return 2 }, func() time.Duration { return time.Second }, nil)
+	shedder.SetBatchRatio(func() float64 { return 0.5 })
+	release, _ := shedder.Admit(types.PriorityInteractive)
+	defer release()
+
+	h := shedder.Middleware(nil)(shedder.Prioritize(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	serve := func(p types.Priority) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{KeyID: "k", Priority: p}))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := serve(types.PriorityBatch); code != http.StatusServiceUnavailable {
+		t.Errorf("batch key got %d, want 503", code)
+	}
+	if code := serve(types.PriorityInteractive); code != http.StatusOK {
+		t.Errorf("interactive key got %d, want 200", code)
+	}
+}
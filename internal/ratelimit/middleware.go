@@ -1,7 +1,9 @@
 package ratelimit
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -12,14 +14,17 @@ import (
 	"github.com/af-corp/aegis-gateway/internal/telemetry"
 )
 
-const (
-	defaultRPM = 60
-	defaultTPM = 200_000
+// DefaultRPM is the limit applied to keys that set no rpm_limit. Keys that
+// set no tpm_limit have no token limit.
+const DefaultRPM = 60
 
+const (
 	headerRateLimitRequests          = "X-RateLimit-Limit-Requests"
 	headerRateLimitRemainingRequests = "X-RateLimit-Remaining-Requests"
 	headerRateLimitReset             = "X-RateLimit-Reset-Requests"
-	headerRetryAfter                 = "Retry-After"
+	headerRateLimitTokens            = "X-RateLimit-Limit-Tokens"
+	headerRateLimitRemainingTokens   = "X-RateLimit-Remaining-Tokens"
+	headerRateLimitResetTokens       = "X-RateLimit-Reset-Tokens"
 )
 
 // AuditLogger defines the interface for audit logging (to avoid circular dependency).
@@ -29,6 +34,33 @@ type AuditLogger interface {
 	LogRedisFailure(requestID, orgID, teamID, keyID, operation string, err error, ip string)
 }
 
+// TokenEstimator returns the tokens a request will use against its key's
+// TPM limit, or 0 for requests that call no model. body is the start of the
+// request body, read ahead of the handler.
+type TokenEstimator func(r *http.Request, body []byte) int64
+
+// estimateTokens reads up to limit bytes of r's body, all of it when limit
+// is 0, for estimate and puts them back so the handler sees the whole body.
+// A body over the limit is left for the handler to reject; its estimate is
+// whatever the truncated start yields.
+func estimateTokens(estimate TokenEstimator, r *http.Request, limit int64) int64 {
+	if r.Body == nil || r.Body == http.NoBody {
+		return estimate(r, nil)
+	}
+	var src io.Reader = r.Body
+	if limit > 0 {
+		src = io.LimitReader(r.Body, limit)
+	}
+	body, _ := io.ReadAll(src)
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	return estimate(r, body)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // Middleware returns chi middleware that enforces per-key rate limits and budget.
 func Middleware(limiter *Limiter, budget *BudgetTracker, metrics *telemetry.Metrics, auditLogger AuditLogger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -42,15 +74,22 @@ func Middleware(limiter *Limiter, budget *BudgetTracker, metrics *telemetry.Metr
 				return
 			}
 
-			// Determine RPM limit
-			rpm := defaultRPM
+			// Determine RPM and TPM limits. TPM applies only to keys that
+			// set tpm_limit, and only to requests the limiter's estimator
+			// says will use tokens; other bodies are not read ahead.
+			rpm := DefaultRPM
 			if authInfo.RPMLimit != nil {
 				rpm = *authInfo.RPMLimit
 			}
+			tpm, tokens := 0, int64(0)
+			if limiter.tokenEstimator != nil && authInfo.TPMLimit != nil && *authInfo.TPMLimit > 0 {
+				if tokens = estimateTokens(limiter.tokenEstimator, r, limiter.maxBody()); tokens > 0 {
+					tpm = *authInfo.TPMLimit
+				}
+			}
 
-			// Check RPM
-			rpmKey := fmt.Sprintf("rpm:%s", authInfo.KeyID)
-			result, err := limiter.Check(r.Context(), rpmKey, int64(rpm), time.Minute)
+			// Check RPM and TPM in one round trip
+			result, err := limiter.CheckRequest(r.Context(), authInfo.OrganizationID, authInfo.KeyID, int64(rpm), int64(tpm), tokens)
 
 			// Handle Redis unavailability (fail closed for security)
 			if err == ErrRedisUnavailable {
@@ -63,41 +102,65 @@ func Middleware(limiter *Limiter, budget *BudgetTracker, metrics *telemetry.Metr
 					auditLogger.LogRedisFailure(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, "rate_limit_check", err, r.RemoteAddr)
 				}
 				if metrics != nil {
-					metrics.RecordRateLimitHit("redis_unavailable", authInfo.OrganizationID)
+					metrics.RecordRateLimitHit("redis_unavailable", authInfo.OrganizationID, authInfo.TeamID)
 				}
-				httputil.WriteServiceUnavailableError(w, reqID,
-					"Rate limiting service temporarily unavailable. Please try again in 30 seconds.")
+				httputil.WriteRetryableCode(w, reqID, httputil.CodeServiceUnavailable,
+					"Rate limiting service temporarily unavailable", nil, time.Now().Add(result.Requests.RetryAfter))
 				return
 			}
 
 			// Always set rate limit headers
 			w.Header().Set(headerRateLimitRequests, strconv.Itoa(rpm))
-			w.Header().Set(headerRateLimitRemainingRequests, strconv.FormatInt(result.Remaining, 10))
-			w.Header().Set(headerRateLimitReset, result.ResetAt.Format(time.RFC3339))
+			w.Header().Set(headerRateLimitRemainingRequests, strconv.FormatInt(result.Requests.Remaining, 10))
+			w.Header().Set(headerRateLimitReset, result.Requests.ResetAt.Format(time.RFC3339))
+			if metrics != nil {
+				metrics.RecordRateLimitRemaining("rpm", authInfo.OrganizationID, authInfo.TeamID, float64(result.Requests.Remaining))
+			}
+			if tpm > 0 {
+				w.Header().Set(headerRateLimitTokens, strconv.Itoa(tpm))
+				w.Header().Set(headerRateLimitRemainingTokens, strconv.FormatInt(result.Tokens.Remaining, 10))
+				w.Header().Set(headerRateLimitResetTokens, result.Tokens.ResetAt.Format(time.RFC3339))
+				if metrics != nil {
+					metrics.RecordRateLimitRemaining("tpm", authInfo.OrganizationID, authInfo.TeamID, float64(result.Tokens.Remaining))
+				}
+			}
 
-			if !result.Allowed {
+			if !result.Allowed() {
+				dimension, limit, denied := "rpm", rpm, result.Requests
+				message := fmt.Sprintf("Rate limit exceeded: %d requests per minute. Retry after %s", rpm, denied.ResetAt.Format(time.RFC3339))
+				if result.Requests.Allowed {
+					dimension, limit, denied = "tpm", tpm, result.Tokens
+					message = fmt.Sprintf("Rate limit exceeded: %d tokens per minute, request needs about %d. Retry after %s", tpm, tokens, denied.ResetAt.Format(time.RFC3339))
+				}
 				slog.Warn("rate limit exceeded",
 					"request_id", reqID,
 					"key_id", authInfo.KeyID,
 					"org_id", authInfo.OrganizationID,
-					"dimension", "rpm",
-					"limit", rpm,
+					"dimension", dimension,
+					"limit", limit,
 				)
 				if auditLogger != nil {
-					auditLogger.LogRateLimitViolation(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, "rpm", int64(rpm), r.RemoteAddr)
+					auditLogger.LogRateLimitViolation(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, dimension, int64(limit), r.RemoteAddr)
 				}
 				if metrics != nil {
-					metrics.RecordRateLimitHit("rpm", authInfo.OrganizationID)
+					metrics.RecordRateLimitHit(dimension, authInfo.OrganizationID, authInfo.TeamID)
 				}
-				w.Header().Set(headerRetryAfter, strconv.Itoa(int(result.RetryAfter.Seconds())))
-				httputil.WriteRateLimitError(w, reqID,
-					fmt.Sprintf("Rate limit exceeded: %d requests per minute. Retry after %s", rpm, result.ResetAt.Format(time.RFC3339)))
+				httputil.WriteRetryableCode(w, reqID, httputil.CodeRateLimitExceeded, message, nil, denied.ResetAt)
 				return
 			}
 
+			// The handler settles the tokens a request used once the
+			// provider reports them. A request that ends any other way, on
+			// a provider error, a rejection, or a budget check below, used
+			// none, so its estimate is released.
+			if result.reservation != nil {
+				r = r.WithContext(contextWithReservation(r.Context(), result.reservation))
+			}
+			defer SettleTokensInBackground(r.Context(), reqID, 0)
+
 			// Check daily budget
 			if authInfo.DailySpendLimitCents != nil {
-				budgetResult, budgetErr := budget.CheckDailySpend(r.Context(), authInfo.TeamID, int64(*authInfo.DailySpendLimitCents))
+				budgetResult, budgetErr := budget.CheckDailySpend(r.Context(), authInfo.OrganizationID, authInfo.TeamID, int64(*authInfo.DailySpendLimitCents))
 
 				// Handle Redis unavailability (fail closed for security)
 				if budgetErr == ErrRedisUnavailable {
@@ -111,13 +174,17 @@ func Middleware(limiter *Limiter, budget *BudgetTracker, metrics *telemetry.Metr
 						auditLogger.LogRedisFailure(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, "budget_check", budgetErr, r.RemoteAddr)
 					}
 					if metrics != nil {
-						metrics.RecordRateLimitHit("redis_unavailable", authInfo.TeamID)
+						metrics.RecordRateLimitHit("redis_unavailable", authInfo.OrganizationID, authInfo.TeamID)
 					}
-					httputil.WriteServiceUnavailableError(w, reqID,
-						"Budget tracking service temporarily unavailable. Please try again in 30 seconds.")
+					httputil.WriteRetryableCode(w, reqID, httputil.CodeServiceUnavailable,
+						"Budget tracking service temporarily unavailable", nil, budgetResult.ResetAt)
 					return
 				}
 
+				if metrics != nil {
+					metrics.RecordRateLimitRemaining("budget", authInfo.OrganizationID, authInfo.TeamID, float64(max(budgetResult.LimitCents-budgetResult.SpentCents, 0)))
+				}
+
 				if !budgetResult.Allowed {
 					slog.Warn("daily budget exceeded",
 						"request_id", reqID,
@@ -130,10 +197,11 @@ func Middleware(limiter *Limiter, budget *BudgetTracker, metrics *telemetry.Metr
 						auditLogger.LogBudgetViolation(reqID, authInfo.OrganizationID, authInfo.TeamID, authInfo.KeyID, budgetResult.SpentCents, budgetResult.LimitCents, r.RemoteAddr)
 					}
 					if metrics != nil {
-						metrics.RecordRateLimitHit("budget", authInfo.TeamID)
+						metrics.RecordRateLimitHit("budget", authInfo.OrganizationID, authInfo.TeamID)
 					}
-					httputil.WriteBudgetExceededError(w, reqID,
-						fmt.Sprintf("Daily budget exceeded: spent %d of %d cents", budgetResult.SpentCents, budgetResult.LimitCents))
+					httputil.WriteRetryableCode(w, reqID, httputil.CodeBudgetExceeded,
+						fmt.Sprintf("Daily budget exceeded: spent %d of %d cents", budgetResult.SpentCents, budgetResult.LimitCents),
+						nil, budgetResult.ResetAt)
 					return
 				}
 			}
@@ -2,12 +2,18 @@ package ratelimit
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/af-corp/aegis-gateway/internal/auth"
 	"github.com/af-corp/aegis-gateway/internal/httputil"
+	"github.com/af-corp/aegis-gateway/internal/telemetry"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func intPtr(v int) *int { return &v }
@@ -149,3 +155,116 @@ func TestMiddleware_RateLimitHeaders_Present(t *testing.T) {
 		}
 	}
 }
+
+func TestMiddleware_TokenLimitHeaders(t *testing.T) {
+	limiter := NewLimiter(nil)
+	var estimated []string
+	limiter.SetTokenEstimator(func(r *http.Request, body []byte) int64 {
+		estimated = append(estimated, string(body))
+		return int64(len(body))
+	}, func() int64 { return 1 << 20 })
+	mw := Middleware(limiter, NewBudgetTracker(nil), nil, nil)
+
+	var seen string
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	body := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{
+		KeyID: "key-1", OrganizationID: "org-1", TPMLimit: intPtr(1000),
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if seen != body {
+		t.Errorf("handler saw body %q, want %q", seen, body)
+	}
+	if got := rec.Header().Get(headerRateLimitTokens); got != "1000" {
+		t.Errorf("expected X-RateLimit-Limit-Tokens=1000, got %q", got)
+	}
+	if got := rec.Header().Get(headerRateLimitRemainingTokens); got != strconv.Itoa(1000-len(body)) {
+		t.Errorf("expected remaining tokens %d, got %q", 1000-len(body), got)
+	}
+
+	// Requests that use no tokens are not checked against TPM.
+	req = httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{KeyID: "key-1", OrganizationID: "org-1"}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get(headerRateLimitTokens) != "" {
+		t.Errorf("expected no token headers without tokens, got %q", rec.Header().Get(headerRateLimitTokens))
+	}
+
+	// Keys without tpm_limit have no token limit, and their bodies are not
+	// read ahead to estimate one.
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{KeyID: "key-2", OrganizationID: "org-1"}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get(headerRateLimitTokens) != "" || len(estimated) != 1 || seen != body {
+		t.Errorf("a key without tpm_limit should not be token limited, got %q after %d estimates",
+			rec.Header().Get(headerRateLimitTokens), len(estimated))
+	}
+}
+
+func TestMiddleware_TokenEstimateBoundedByBodyLimit(t *testing.T) {
+	limiter := NewLimiter(nil)
+	var estimated []byte
+	limiter.SetTokenEstimator(func(r *http.Request, body []byte) int64 {
+		estimated = body
+		return 1
+	}, func() int64 { return 16 })
+
+	var seen string
+	handler := Middleware(limiter, NewBudgetTracker(nil), nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	}))
+	body := strings.Repeat("x", 100)
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), &auth.AuthInfo{
+		KeyID: "key-1", OrganizationID: "org-1", TPMLimit: intPtr(1000),
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(estimated) != 16 {
+		t.Errorf("estimator should see at most the body limit, got %d bytes", len(estimated))
+	}
+	if seen != body {
+		t.Errorf("handler should still see the whole body to reject it, got %d bytes", len(seen))
+	}
+}
+
+func TestMiddleware_RecordsRemainingQuota(t *testing.T) {
+	metrics := &telemetry.Metrics{
+		RateLimitRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_rate_limit_remaining", Help: "Test",
+		}, []string{"dimension", "org", "team"}),
+	}
+	mw := Middleware(NewLimiter(nil), NewBudgetTracker(nil), metrics, nil)
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	authInfo := &auth.AuthInfo{
+		KeyID:          "key-1",
+		OrganizationID: "org-1",
+		TeamID:         "team-1",
+		RPMLimit:       intPtr(100),
+	}
+	req = req.WithContext(auth.ContextWithAuth(req.Context(), authInfo))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	gauge, _ := metrics.RateLimitRemaining.GetMetricWithLabelValues("rpm", "org-1", "team-1")
+	var metric dto.Metric
+	_ = gauge.Write(&metric)
+	if *metric.Gauge.Value != 99 {
+		t.Errorf("expected remaining rpm 99, got %v", *metric.Gauge.Value)
+	}
+}
@@ -0,0 +1,220 @@
+package ratelimit
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/af-corp/aegis-gateway/internal/tenant"
+)
+
+// requestWindow is the sliding window of per-key RPM and TPM limits.
+const requestWindow = time.Minute
+
+// RequestLimitResult is the outcome of CheckRequest: one LimitResult per
+// dimension. Tokens is the zero value when no TPM limit was checked.
+type RequestLimitResult struct {
+	Requests LimitResult
+	Tokens   LimitResult
+
+	// reservation is the TPM window entry recording the request's
+	// estimated tokens, nil when none was recorded.
+	reservation *tokenReservation
+}
+
+// Allowed reports whether both dimensions admitted the request.
+func (r RequestLimitResult) Allowed() bool {
+	return r.Requests.Allowed && r.Tokens.Allowed
+}
+
+// requestLimitScript checks a key's RPM and TPM windows in one round trip
+// and, if both admit the request, records it in each.
+// KEYS[1] = requests sorted set, KEYS[2] = tokens sorted set
+// ARGV[1] = window start (unix micro)
+// ARGV[2] = now (unix micro)
+// ARGV[3] = RPM limit
+// ARGV[4] = TPM limit, 0 to skip the tokens window
+// ARGV[5] = tokens the request will use
+// ARGV[6] = TTL seconds for the keys
+// ARGV[7] = member recording the request
+// Token entries are "<member>:<tokens>". A request larger than the whole TPM
+// limit is let through an empty window rather than refused forever.
+// Returns: [request count, requests allowed, requests reset, tokens used,
+// tokens allowed, tokens reset], where each reset is the score of the entry
+// whose expiry frees room.
+var requestLimitScript = redis.NewScript(`
+local rpm_key, tpm_key = KEYS[1], KEYS[2]
+local window_start = tonumber(ARGV[1])
+local now = tonumber(ARGV[2])
+local rpm_limit = tonumber(ARGV[3])
+local tpm_limit = tonumber(ARGV[4])
+local tokens = tonumber(ARGV[5])
+local ttl = tonumber(ARGV[6])
+local member = ARGV[7]
+
+local function entry_tokens(member)
+    return tonumber(string.match(member, ':(%d+)$')) or 0
+end
+
+redis.call('ZREMRANGEBYSCORE', rpm_key, '-inf', window_start)
+local count = redis.call('ZCARD', rpm_key)
+
+local used = 0
+local entries = {}
+if tpm_limit > 0 then
+    redis.call('ZREMRANGEBYSCORE', tpm_key, '-inf', window_start)
+    entries = redis.call('ZRANGE', tpm_key, 0, -1, 'WITHSCORES')
+    for i = 1, #entries, 2 do
+        used = used + entry_tokens(entries[i])
+    end
+end
+
+local rpm_ok = count < rpm_limit
+local tpm_ok = tpm_limit <= 0 or used == 0 or used + tokens <= tpm_limit
+if rpm_ok and tpm_ok then
+    redis.call('ZADD', rpm_key, now, member)
+    count = count + 1
+    if tpm_limit > 0 and tokens > 0 then
+        redis.call('ZADD', tpm_key, now, member .. ':' .. tokens)
+        used = used + tokens
+    end
+end
+redis.call('EXPIRE', rpm_key, ttl)
+
+local rpm_reset = tonumber(redis.call('ZRANGE', rpm_key, 0, 0, 'WITHSCORES')[2]) or now
+local tpm_reset = now
+if tpm_limit > 0 then
+    redis.call('EXPIRE', tpm_key, ttl)
+    if tpm_ok then
+        tpm_reset = tonumber(redis.call('ZRANGE', tpm_key, 0, 0, 'WITHSCORES')[2]) or now
+    else
+        local need, freed = used + tokens - tpm_limit, 0
+        for i = 1, #entries, 2 do
+            freed = freed + entry_tokens(entries[i])
+            tpm_reset = tonumber(entries[i + 1])
+            if freed >= need then
+                break
+            end
+        end
+    end
+end
+
+return {count, rpm_ok and 1 or 0, rpm_reset, used, tpm_ok and 1 or 0, tpm_reset}
+`)
+
+// CheckRequest checks a key's per-minute request and token limits in a
+// single Redis round trip, recording the request against both only if both
+// admit it. A tpm of 0 checks requests alone.
+//
+// Security: FAILS CLOSED when Redis is unavailable (circuit breaker open).
+func (l *Limiter) CheckRequest(ctx context.Context, org, keyID string, rpm, tpm, tokens int64) (RequestLimitResult, error) {
+	now := time.Now()
+	if l.rdb == nil {
+		res := RequestLimitResult{
+			Requests: LimitResult{Allowed: true, Remaining: rpm - 1, ResetAt: now.Add(requestWindow)},
+			Tokens:   LimitResult{Allowed: true},
+		}
+		if tpm > 0 {
+			res.Tokens = LimitResult{Allowed: true, Remaining: max(tpm-tokens, 0), ResetAt: now.Add(requestWindow)}
+		}
+		return res, nil
+	}
+
+	prefix := tenant.RedisPrefix(l.strictTenancy, org) + "rl:"
+	member := strconv.FormatInt(now.UnixMicro(), 10) + ":" + strconv.Itoa(rand.IntN(1000000)+1)
+	var result []int64
+	var scriptErr error
+	err := l.circuitBreaker.Call(ctx, func() error {
+		result, scriptErr = requestLimitScript.Run(ctx, l.rdb, []string{prefix + "rpm:" + keyID, prefix + "tpm:" + keyID},
+			now.Add(-requestWindow).UnixMicro(), now.UnixMicro(), rpm, tpm, tokens, int64(requestWindow.Seconds())+1, member,
+		).Int64Slice()
+		return scriptErr
+	})
+
+	// If circuit breaker is open or the script failed, FAIL CLOSED
+	if err == ErrCircuitOpen || scriptErr != nil || len(result) < 6 {
+		retryAfter := redisRetryAfter
+		if err == ErrCircuitOpen {
+			retryAfter = l.circuitBreaker.RetryAfter()
+		}
+		denied := LimitResult{ResetAt: now.Add(requestWindow), RetryAfter: retryAfter}
+		return RequestLimitResult{Requests: denied, Tokens: denied}, ErrRedisUnavailable
+	}
+
+	res := RequestLimitResult{
+		Requests: windowResult(now, result[1] == 1, rpm-result[0], result[2]),
+		Tokens:   LimitResult{Allowed: true},
+	}
+	if tpm > 0 {
+		res.Tokens = windowResult(now, result[4] == 1, tpm-result[3], result[5])
+		if res.Allowed() && tokens > 0 {
+			res.reservation = &tokenReservation{
+				limiter: l,
+				key:     prefix + "tpm:" + keyID,
+				entry:   member + ":" + strconv.FormatInt(tokens, 10),
+			}
+		}
+	}
+	return res, nil
+}
+
+// KeyUsage is what a key has used of its per-minute limits.
+type KeyUsage struct {
+	Requests int64
+	Tokens   int64
+}
+
+// Usage reports the requests and tokens a key has used in the current
+// window, recording nothing. Without Redis nothing is tracked and it
+// reports none.
+func (l *Limiter) Usage(ctx context.Context, org, keyID string) (KeyUsage, error) {
+	if l.rdb == nil {
+		return KeyUsage{}, nil
+	}
+	prefix := tenant.RedisPrefix(l.strictTenancy, org) + "rl:"
+	// Entries scored at or before the window start are expired.
+	start := "(" + strconv.FormatInt(time.Now().Add(-requestWindow).UnixMicro(), 10)
+
+	var usage KeyUsage
+	err := l.circuitBreaker.Call(ctx, func() error {
+		pipe := l.rdb.Pipeline()
+		requests := pipe.ZCount(ctx, prefix+"rpm:"+keyID, start, "+inf")
+		tokens := pipe.ZRangeByScore(ctx, prefix+"tpm:"+keyID, &redis.ZRangeBy{Min: start, Max: "+inf"})
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		usage.Requests = requests.Val()
+		for _, member := range tokens.Val() {
+			usage.Tokens += entryTokens(member)
+		}
+		return nil
+	})
+	if err != nil {
+		return KeyUsage{}, ErrRedisUnavailable
+	}
+	return usage, nil
+}
+
+// entryTokens parses the tokens of a TPM window entry, "<member>:<tokens>".
+func entryTokens(member string) int64 {
+	n, _ := strconv.ParseInt(member[strings.LastIndexByte(member, ':')+1:], 10, 64)
+	return n
+}
+
+// windowResult builds a LimitResult from a window whose room next frees up
+// when the entry scored resetMicro expires.
+func windowResult(now time.Time, allowed bool, remaining, resetMicro int64) LimitResult {
+	r := LimitResult{
+		Allowed:   allowed,
+		Remaining: max(remaining, 0),
+		ResetAt:   time.UnixMicro(resetMicro).Add(requestWindow),
+	}
+	if !allowed {
+		r.RetryAfter = max(r.ResetAt.Sub(now), 0)
+	}
+	return r
+}
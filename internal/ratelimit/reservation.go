@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// settleTokensScript replaces a request's estimated TPM window entry with
+// one carrying the tokens it actually used, keeping the entry's score so it
+// expires when the estimate would have. An entry already pruned from the
+// window is left gone.
+// KEYS[1] = tokens sorted set
+// ARGV[1] = estimated entry
+// ARGV[2] = actual entry, empty when the request used no tokens
+// Returns: 1 if the entry was replaced, 0 if it had expired.
+var settleTokensScript = redis.NewScript(`
+local key = KEYS[1]
+local score = redis.call('ZSCORE', key, ARGV[1])
+if not score then
+    return 0
+end
+redis.call('ZREM', key, ARGV[1])
+if ARGV[2] ~= '' then
+    redis.call('ZADD', key, score, ARGV[2])
+end
+return 1
+`)
+
+// tokenReservation is the TPM window entry a request was admitted with,
+// held in its context until the provider reports what it used.
+type tokenReservation struct {
+	limiter *Limiter
+	key     string
+	entry   string
+	settled atomic.Bool
+}
+
+type reservationContextKey struct{}
+
+func contextWithReservation(ctx context.Context, res *tokenReservation) context.Context {
+	return context.WithValue(ctx, reservationContextKey{}, res)
+}
+
+// SettleTokens corrects the TPM window for the request in ctx once the
+// provider has reported the tokens it used: the tokens estimated when the
+// request was admitted are taken back out and actual put in, so estimation
+// errors don't accumulate over a busy minute. Only the first call for a
+// request has any effect, and requests admitted without a token estimate
+// are left alone. ctx must derive from the request's context.
+func SettleTokens(ctx context.Context, actual int64) error {
+	res := claimReservation(ctx)
+	if res == nil {
+		return nil
+	}
+	return res.settle(ctx, actual)
+}
+
+// tokenSettleTimeout bounds correcting a TPM window entry in the background.
+const tokenSettleTimeout = 2 * time.Second
+
+// SettleTokensInBackground is SettleTokens without waiting on Redis: the
+// request's reservation is claimed at once, so no later call for the request
+// has any effect, and the window is corrected after the request has ended.
+func SettleTokensInBackground(ctx context.Context, reqID string, actual int64) {
+	res := claimReservation(ctx)
+	if res == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenSettleTimeout)
+		defer cancel()
+		if err := res.settle(ctx, actual); err != nil {
+			slog.Warn("failed to settle rate limit tokens", "error", err, "request_id", reqID)
+		}
+	}()
+}
+
+// claimReservation returns the reservation in ctx if it has not been
+// settled yet, marking it settled.
+func claimReservation(ctx context.Context) *tokenReservation {
+	res, ok := ctx.Value(reservationContextKey{}).(*tokenReservation)
+	if !ok || !res.settled.CompareAndSwap(false, true) {
+		return nil
+	}
+	return res
+}
+
+func (res *tokenReservation) settle(ctx context.Context, actual int64) error {
+	member := res.entry[:strings.LastIndexByte(res.entry, ':')]
+	var entry string
+	if actual > 0 {
+		entry = member + ":" + strconv.FormatInt(actual, 10)
+	}
+	if entry == res.entry {
+		return nil
+	}
+
+	l := res.limiter
+	return l.circuitBreaker.Call(ctx, func() error {
+		return settleTokensScript.Run(ctx, l.rdb, []string{res.key}, res.entry, entry).Err()
+	})
+}
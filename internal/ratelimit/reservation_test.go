@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/auth"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSettleTokens_NoReservation(t *testing.T) {
+	if err := SettleTokens(context.Background(), 500); err != nil {
+		t.Errorf("expected no-op without a reservation, got %v", err)
+	}
+}
+
+func TestLimiter_NilRedis_NoReservation(t *testing.T) {
+	res, err := NewLimiter(nil).CheckRequest(context.Background(), "org-1", "key-1", 60, 1000, 200)
+	if err != nil || res.reservation != nil {
+		t.Errorf("expected no reservation without Redis, got %+v, %v", res.reservation, err)
+	}
+}
+
+func TestSettleTokens_SettlesOnce(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	res := &tokenReservation{limiter: NewLimiter(rdb), key: "rl:tpm:key-1", entry: "1700000000000000:42:200"}
+	ctx := contextWithReservation(context.Background(), res)
+
+	if err := SettleTokens(ctx, 350); err == nil {
+		t.Fatal("expected an error settling against unreachable Redis")
+	}
+	if err := SettleTokens(ctx, 350); err != nil {
+		t.Errorf("second settle should be a no-op, got %v", err)
+	}
+}
+
+func TestSettleTokens_ExactEstimate(t *testing.T) {
+	// A nil limiter would panic if Redis were called.
+	res := &tokenReservation{key: "rl:tpm:key-1", entry: "1700000000000000:42:200"}
+	if err := SettleTokens(contextWithReservation(context.Background(), res), 200); err != nil {
+		t.Errorf("expected no-op when the estimate was exact, got %v", err)
+	}
+}
+
+func TestSettleTokensInBackground_ClaimsAtOnce(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	res := &tokenReservation{limiter: NewLimiter(rdb), key: "rl:tpm:key-1", entry: "1700000000000000:42:200"}
+	ctx := contextWithReservation(context.Background(), res)
+
+	SettleTokensInBackground(ctx, "req-1", 350)
+	// The background settle owns the reservation, so a later settle, such
+	// as the release when the request ends, must not reach Redis.
+	if err := SettleTokens(ctx, 0); err != nil {
+		t.Errorf("a settle after the background one should be a no-op, got %v", err)
+	}
+}
+
+func TestMiddleware_ReleasesUnsettledReservation(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	res := &tokenReservation{limiter: NewLimiter(rdb), key: "rl:tpm:key-1", entry: "1700000000000000:42:200"}
+
+	// The handler fails on a provider error without settling.
+	handler := Middleware(NewLimiter(nil), NewBudgetTracker(nil), nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	ctx := auth.ContextWithAuth(contextWithReservation(req.Context(), res), &auth.AuthInfo{KeyID: "key-1", OrganizationID: "org-1"})
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+
+	if !res.settled.Load() {
+		t.Error("a request that ends without settling should release its reservation")
+	}
+}
@@ -2,7 +2,11 @@ package adapters
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
@@ -18,3 +22,129 @@ type ProviderAdapter interface {
 	// SendRequest sends an HTTP request using the provider's configured client.
 	SendRequest(req *http.Request) (*http.Response, error)
 }
+
+// StreamUsageReader is implemented by adapters whose native stream events
+// carry token usage that their OpenAI-format chunks do not.
+type StreamUsageReader interface {
+	// StreamUsage reports the usage and served model in one raw stream event.
+	// Fields the event does not carry are left zero; ok is false when it
+	// carries none.
+	StreamUsage(chunk []byte) (usage types.Usage, model string, ok bool)
+}
+
+// TokenCounter is implemented by adapters whose provider can count a
+// request's prompt tokens without running it. It is satisfied by
+// *AnthropicAdapter.
+type TokenCounter interface {
+	CountTokens(ctx context.Context, req *types.AegisRequest) (int, error)
+}
+
+// StreamTransformer converts the chunks of a single provider stream, keeping
+// whatever state that needs between chunks.
+type StreamTransformer interface {
+	TransformStreamChunk(chunk []byte) ([]byte, error)
+}
+
+// StreamTransformerFactory is implemented by adapters whose stream conversion
+// is stateful. The gateway asks for a fresh transformer per stream and uses
+// it in place of the adapter's own TransformStreamChunk.
+type StreamTransformerFactory interface {
+	NewStreamTransformer() StreamTransformer
+}
+
+// StreamPassThrough is implemented by adapters whose provider already streams
+// OpenAI-format chunks. When PassThroughStream reports true the gateway
+// forwards each chunk as received and never calls TransformStreamChunk. It is
+// satisfied by *OpenAIAdapter.
+type StreamPassThrough interface {
+	PassThroughStream() bool
+}
+
+// providerRequestIDHeaders lists response headers in which providers return
+// their own request IDs (OpenAI, Anthropic, Azure APIM).
+var providerRequestIDHeaders = []string{"X-Request-Id", "Request-Id", "Apim-Request-Id"}
+
+// ProviderRequestID returns the provider's own request ID from response
+// headers, or "" if none is present. Quote it in provider support tickets.
+func ProviderRequestID(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+	for _, h := range providerRequestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// setCorrelationHeaders forwards the AEGIS request ID, and the W3C traceparent
+// when enabled for the provider, on an outbound provider request.
+func setCorrelationHeaders(httpReq *http.Request, req *types.AegisRequest, forwardTraceContext bool) {
+	if req.RequestID != "" {
+		httpReq.Header.Set("X-Request-ID", req.RequestID)
+	}
+	if forwardTraceContext && req.TraceContext != "" {
+		httpReq.Header.Set("traceparent", req.TraceContext)
+	}
+}
+
+// ProviderError is returned by TransformResponse when a provider answers with
+// a non-200 status. Type, Code, and Message are taken from the provider's
+// error body when it has the OpenAI or Anthropic shape.
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Type       string // e.g. "invalid_request_error"
+	Code       string // e.g. "context_length_exceeded"; OpenAI only
+	Message    string
+	Body       string
+	// RetryAt is when the provider said a retry may succeed, from its
+	// Retry-After or rate limit reset headers; zero if it did not say.
+	RetryAt time.Time
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+// NewProviderError builds a ProviderError from an error response body.
+// Both OpenAI ({"error":{"message","type","code"}}) and Anthropic
+// ({"type":"error","error":{"type","message"}}) nest details under "error".
+func NewProviderError(provider string, statusCode int, body []byte) *ProviderError {
+	pe := &ProviderError{Provider: provider, StatusCode: statusCode, Body: string(body)}
+	var parsed struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    any    `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		pe.Message = parsed.Error.Message
+		pe.Type = parsed.Error.Type
+		// OpenAI codes are usually strings but may be null or numeric.
+		switch c := parsed.Error.Code.(type) {
+		case string:
+			pe.Code = c
+		case float64:
+			pe.Code = fmt.Sprintf("%d", int(c))
+		}
+	}
+	return pe
+}
+
+// ResponseProviderError builds a ProviderError from resp and its already
+// read body, taking RetryAt from the response headers.
+func ResponseProviderError(provider string, resp *http.Response, body []byte) *ProviderError {
+	pe := NewProviderError(provider, resp.StatusCode, body)
+	pe.RetryAt = ParseRetryAt(resp.Header, time.Now())
+	return pe
+}
+
+// ReadProviderError drains resp (up to 64 KiB) into a ProviderError. The
+// caller still owns closing the body.
+func ReadProviderError(provider string, resp *http.Response) *ProviderError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return ResponseProviderError(provider, resp, body)
+}
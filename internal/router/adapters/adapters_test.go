@@ -3,6 +3,7 @@ package adapters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -90,6 +91,114 @@ func TestOpenAIAdapter_TransformRequest(t *testing.T) {
 	}
 }
 
+func TestOpenAIAdapter_TransformRequest_StreamOptions(t *testing.T) {
+	a := NewOpenAIAdapter(newOpenAICfg(), http.DefaultClient)
+
+	for _, stream := range []bool{true, false} {
+		httpReq, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+			Model:    "gpt-4o",
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+			Stream:   stream,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(httpReq.Body)
+		var parsed openAIRequestBody
+		_ = json.Unmarshal(body, &parsed)
+		if stream && (parsed.StreamOptions == nil || !parsed.StreamOptions.IncludeUsage) {
+			t.Error("streaming requests should always ask for usage")
+		}
+		if !stream && parsed.StreamOptions != nil {
+			t.Error("stream_options must not be sent on non-streaming requests")
+		}
+	}
+}
+
+func TestOpenAIAdapter_TransformRequest_AccountHeaders(t *testing.T) {
+	cfg := newOpenAICfg()
+	cfg.Organization, cfg.Project = "org-default", "proj_default"
+	cfg.Teams = map[string]config.ProviderTeamConfig{"team-ml": {Project: "proj_ml"}}
+	a := NewOpenAIAdapter(cfg, http.DefaultClient)
+
+	for team, want := range map[string][2]string{
+		"team-web": {"org-default", "proj_default"},
+		"team-ml":  {"org-default", "proj_ml"},
+	} {
+		httpReq, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+			TeamID: team, Model: "gpt-4o", Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := [2]string{httpReq.Header.Get("OpenAI-Organization"), httpReq.Header.Get("OpenAI-Project")}; got != want {
+			t.Errorf("team %s: organization and project = %v, want %v", team, got, want)
+		}
+	}
+}
+
+func TestOpenAIAdapter_TransformRequest_NamesAndToolCallIDs(t *testing.T) {
+	a := NewOpenAIAdapter(newOpenAICfg(), http.DefaultClient)
+
+	httpReq, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+		Model: "gpt-4o",
+		Messages: []types.Message{
+			{Role: "user", Name: "planner", Content: "Save it."},
+			{Role: "assistant", ToolCalls: []types.ToolCall{
+				{ID: "call_1", Type: "function", Function: types.ToolCallFunction{Name: "save", Arguments: `{}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: "saved"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(httpReq.Body)
+	var parsed openAIRequestBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+	if parsed.Messages[0].Name != "planner" || parsed.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("expected name and tool_call_id to pass through, got %s", body)
+	}
+}
+
+func TestOpenAIAdapter_TransformRequest_TokenLimitNames(t *testing.T) {
+	a := NewOpenAIAdapter(newOpenAICfg(), http.DefaultClient)
+	limit := 500
+
+	tests := []struct {
+		name                string
+		req                 types.AegisRequest
+		wantMaxTokens       bool
+		wantMaxCompletion   bool
+		wantReasoningEffort string
+	}{
+		{name: "max_tokens passes through", req: types.AegisRequest{Model: "gpt-4o", MaxTokens: &limit}, wantMaxTokens: true},
+		{name: "max_completion_tokens passes through", req: types.AegisRequest{Model: "gpt-4o", MaxCompletionTokens: &limit}, wantMaxCompletion: true},
+		{name: "reasoning model renames max_tokens", req: types.AegisRequest{Model: "o3-mini", MaxTokens: &limit, ReasoningEffort: "low"}, wantMaxCompletion: true, wantReasoningEffort: "low"},
+		{name: "reasoning_effort dropped for other models", req: types.AegisRequest{Model: "gpt-4o", ReasoningEffort: "low"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.Messages = []types.Message{{Role: "user", Content: "Hi"}}
+			httpReq, err := a.TransformRequest(context.Background(), &tt.req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			body, _ := io.ReadAll(httpReq.Body)
+			var parsed openAIRequestBody
+			_ = json.Unmarshal(body, &parsed)
+			if (parsed.MaxTokens != nil) != tt.wantMaxTokens || (parsed.MaxCompletionTokens != nil) != tt.wantMaxCompletion {
+				t.Errorf("unexpected limits in %s", body)
+			}
+			if parsed.ReasoningEffort != tt.wantReasoningEffort {
+				t.Errorf("reasoning_effort = %q, want %q", parsed.ReasoningEffort, tt.wantReasoningEffort)
+			}
+		})
+	}
+}
+
 func TestOpenAIAdapter_TransformResponse_Success(t *testing.T) {
 	a := NewOpenAIAdapter(newOpenAICfg(), http.DefaultClient)
 
@@ -221,6 +330,26 @@ func TestAnthropicAdapter_SupportsStreaming(t *testing.T) {
 	}
 }
 
+func TestAnthropicAdapter_TransformRequest_APIVersion(t *testing.T) {
+	cfg := newAnthropicCfg()
+	cfg.APIVersion = "2023-06-01"
+	cfg.Headers = nil
+	cfg.Teams = map[string]config.ProviderTeamConfig{"team-beta": {APIVersion: "2024-01-01"}}
+	a := NewAnthropicAdapter(cfg, http.DefaultClient)
+
+	for team, want := range map[string]string{"team-web": "2023-06-01", "team-beta": "2024-01-01"} {
+		httpReq, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+			TeamID: team, Model: "claude-sonnet-4-5-20250929", Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := httpReq.Header.Get("anthropic-version"); got != want {
+			t.Errorf("team %s: anthropic-version = %q, want %q", team, got, want)
+		}
+	}
+}
+
 func TestAnthropicAdapter_TransformRequest_SystemMessage(t *testing.T) {
 	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
 
@@ -433,6 +562,22 @@ func TestAnthropicAdapter_TransformStreamChunk_MessageDelta(t *testing.T) {
 	}
 }
 
+func TestAnthropicAdapter_StreamUsage(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+
+	usage, model, ok := a.StreamUsage([]byte(`{"type":"message_start","message":{"model":"claude-sonnet-4-20250514","usage":{"input_tokens":25,"output_tokens":1}}}`))
+	if !ok || usage.PromptTokens != 25 || model != "claude-sonnet-4-20250514" {
+		t.Errorf("message_start: got %+v %q %v", usage, model, ok)
+	}
+	usage, _, ok = a.StreamUsage([]byte(`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":42}}`))
+	if !ok || usage.CompletionTokens != 42 {
+		t.Errorf("message_delta: got %+v %v", usage, ok)
+	}
+	if _, _, ok := a.StreamUsage([]byte(`{"type":"content_block_delta","delta":{"text":"hi"}}`)); ok {
+		t.Error("content_block_delta should carry no usage")
+	}
+}
+
 func TestAnthropicAdapter_TransformStreamChunk_MessageStop(t *testing.T) {
 	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
 
@@ -450,7 +595,6 @@ func TestAnthropicAdapter_TransformStreamChunk_SkippedEvents(t *testing.T) {
 	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
 
 	skippable := []string{
-		`{"type":"message_start"}`,
 		`{"type":"content_block_start"}`,
 		`{"type":"content_block_stop"}`,
 		`{"type":"ping"}`,
@@ -497,3 +641,112 @@ func TestMapStopReason(t *testing.T) {
 		}
 	}
 }
+
+// --- Correlation Header Tests ---
+
+func TestTransformRequest_ForwardsRequestID(t *testing.T) {
+	req := &types.AegisRequest{
+		RequestID:    "req_123",
+		TraceContext: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		Model:        "gpt-4o",
+		Messages:     []types.Message{{Role: "user", Content: "Hi"}},
+	}
+
+	httpReq, err := NewOpenAIAdapter(newOpenAICfg(), http.DefaultClient).TransformRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := httpReq.Header.Get("X-Request-ID"); got != "req_123" {
+		t.Errorf("expected X-Request-ID req_123, got %q", got)
+	}
+	if got := httpReq.Header.Get("traceparent"); got != "" {
+		t.Errorf("expected no traceparent when forwarding is disabled, got %q", got)
+	}
+
+	cfg := newOpenAICfg()
+	cfg.ForwardTraceContext = true
+	httpReq, err = NewAnthropicAdapter(cfg, http.DefaultClient).TransformRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := httpReq.Header.Get("traceparent"); got != req.TraceContext {
+		t.Errorf("expected traceparent %q, got %q", req.TraceContext, got)
+	}
+}
+
+func TestProviderRequestID(t *testing.T) {
+	tests := []struct {
+		header string
+		value  string
+	}{
+		{"x-request-id", "req_openai"},
+		{"request-id", "req_anthropic"},
+		{"apim-request-id", "azure-id"},
+	}
+	for _, tt := range tests {
+		resp := &http.Response{Header: make(http.Header)}
+		resp.Header.Set(tt.header, tt.value)
+		if got := ProviderRequestID(resp); got != tt.value {
+			t.Errorf("ProviderRequestID with %s = %q, want %q", tt.header, got, tt.value)
+		}
+	}
+	if got := ProviderRequestID(&http.Response{Header: make(http.Header)}); got != "" {
+		t.Errorf("expected empty ID without headers, got %q", got)
+	}
+	if got := ProviderRequestID(nil); got != "" {
+		t.Errorf("expected empty ID for nil response, got %q", got)
+	}
+}
+
+func TestNewProviderError(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		wantType, wantCode string
+		wantMessage        string
+	}{
+		{
+			name:        "openai",
+			body:        `{"error":{"message":"This model's maximum context length is 8192 tokens.","type":"invalid_request_error","param":"messages","code":"context_length_exceeded"}}`,
+			wantType:    "invalid_request_error",
+			wantCode:    "context_length_exceeded",
+			wantMessage: "This model's maximum context length is 8192 tokens.",
+		},
+		{
+			name:        "anthropic",
+			body:        `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			wantType:    "invalid_request_error",
+			wantMessage: "prompt is too long: 210000 tokens > 200000 maximum",
+		},
+		{
+			name:     "null code",
+			body:     `{"error":{"message":"bad","type":"invalid_request_error","code":null}}`,
+			wantType: "invalid_request_error", wantMessage: "bad",
+		},
+		{name: "not json", body: `<html>Bad Request</html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe := NewProviderError("openai", 400, []byte(tt.body))
+			if pe.Type != tt.wantType || pe.Code != tt.wantCode || pe.Message != tt.wantMessage {
+				t.Errorf("got type=%q code=%q message=%q", pe.Type, pe.Code, pe.Message)
+			}
+			if pe.StatusCode != 400 || pe.Body != tt.body {
+				t.Errorf("status/body not preserved: %+v", pe)
+			}
+		})
+	}
+}
+
+func TestTransformResponse_ReturnsProviderError(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	resp := &http.Response{
+		StatusCode: 400,
+		Body:       io.NopCloser(strings.NewReader(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: must be positive"}}`)),
+	}
+	_, err := a.TransformResponse(context.Background(), resp)
+	var pe *ProviderError
+	if !errors.As(err, &pe) || pe.Provider != "anthropic" || pe.Message != "max_tokens: must be positive" {
+		t.Fatalf("expected *ProviderError, got %v", err)
+	}
+}
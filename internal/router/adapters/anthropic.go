@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/types"
@@ -28,23 +29,19 @@ func (a *AnthropicAdapter) SupportsStreaming() bool { return true }
 
 func (a *AnthropicAdapter) TransformRequest(ctx context.Context, req *types.AegisRequest) (*http.Request, error) {
 	// Convert OpenAI-format messages to Anthropic format
-	var system string
-	var messages []anthropicMessage
-	for _, m := range req.Messages {
-		if m.Role == "system" {
-			system = m.Content
-			continue
-		}
-		messages = append(messages, anthropicMessage{
-			Role:    m.Role,
-			Content: m.Content,
-		})
+	system, messages, err := toAnthropicMessages(req.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("convert messages for anthropic: %w", err)
+	}
+	toolChoice, err := toAnthropicToolChoice(req.ToolChoice)
+	if err != nil {
+		return nil, fmt.Errorf("convert tool_choice for anthropic: %w", err)
 	}
 
 	// Anthropic requires max_tokens
 	maxTokens := 4096
-	if req.MaxTokens != nil {
-		maxTokens = *req.MaxTokens
+	if limit := req.CompletionTokenLimit(); limit != nil {
+		maxTokens = *limit
 	}
 
 	body := anthropicRequestBody{
@@ -56,6 +53,16 @@ func (a *AnthropicAdapter) TransformRequest(ctx context.Context, req *types.Aegi
 		Temperature: req.Temperature,
 		TopP:        req.TopP,
 		Stop:        req.Stop,
+		Tools:       toAnthropicTools(req.Tools),
+		ToolChoice:  toolChoice,
+	}
+	// reasoning_effort becomes extended thinking, which counts against
+	// max_tokens like OpenAI reasoning tokens do and rejects sampling
+	// parameters the way reasoning models ignore them.
+	if budget := thinkingBudget(req.ReasoningEffort, maxTokens); budget > 0 {
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+		body.Temperature = nil
+		body.TopP = nil
 	}
 
 	data, err := json.Marshal(body)
@@ -69,15 +76,65 @@ func (a *AnthropicAdapter) TransformRequest(ctx context.Context, req *types.Aegi
 		return nil, fmt.Errorf("create http request: %w", err)
 	}
 
+	a.setHeaders(httpReq, req)
+	return httpReq, nil
+}
+
+// setHeaders sets authentication, correlation, and configured headers on a
+// request to the Messages API.
+func (a *AnthropicAdapter) setHeaders(httpReq *http.Request, req *types.AegisRequest) {
 	httpReq.Header.Set("Content-Type", "application/json")
 	httpReq.Header.Set("x-api-key", a.cfg.APIKey)
+	setCorrelationHeaders(httpReq, req, a.cfg.ForwardTraceContext)
 	for k, v := range a.cfg.Headers {
 		if v != "" {
 			httpReq.Header.Set(k, v)
 		}
 	}
+	if _, _, version := a.cfg.Account(req.TeamID); version != "" {
+		httpReq.Header.Set("anthropic-version", version)
+	}
+}
 
-	return httpReq, nil
+// CountTokens implements TokenCounter with the Messages count_tokens API,
+// which counts system prompt, messages, and tool definitions exactly as a
+// real request would.
+func (a *AnthropicAdapter) CountTokens(ctx context.Context, req *types.AegisRequest) (int, error) {
+	system, messages, err := toAnthropicMessages(req.Messages)
+	if err != nil {
+		return 0, fmt.Errorf("convert messages for anthropic: %w", err)
+	}
+	data, err := json.Marshal(anthropicCountTokensBody{
+		Model:    req.Model,
+		Messages: messages,
+		System:   system,
+		Tools:    toAnthropicTools(req.Tools),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("marshal anthropic count_tokens request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, a.cfg.BaseURL+"/messages/count_tokens", bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("create http request: %w", err)
+	}
+	a.setHeaders(httpReq, req)
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return 0, ReadProviderError("anthropic", resp)
+	}
+	var out struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, fmt.Errorf("decode anthropic count_tokens response: %w", err)
+	}
+	return out.InputTokens, nil
 }
 
 func (a *AnthropicAdapter) TransformResponse(ctx context.Context, resp *http.Response) (*types.AegisResponse, error) {
@@ -89,7 +146,7 @@ func (a *AnthropicAdapter) TransformResponse(ctx context.Context, resp *http.Res
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("anthropic returned status %d: %s", resp.StatusCode, string(body))
+		return nil, ResponseProviderError("anthropic", resp, body)
 	}
 
 	var antResp anthropicResponseBody
@@ -98,24 +155,13 @@ func (a *AnthropicAdapter) TransformResponse(ctx context.Context, resp *http.Res
 	}
 
 	// Convert Anthropic response to AEGIS canonical format
-	var content string
-	for _, block := range antResp.Content {
-		if block.Type == "text" {
-			content = block.Text
-			break
-		}
-	}
-
 	return &types.AegisResponse{
 		Model:    antResp.Model,
 		Provider: "anthropic",
 		Choices: []types.Choice{
 			{
-				Index: 0,
-				Message: types.Message{
-					Role:    "assistant",
-					Content: content,
-				},
+				Index:        0,
+				Message:      fromAnthropicContent(antResp.Content),
 				FinishReason: mapStopReason(antResp.StopReason),
 			},
 		},
@@ -127,68 +173,156 @@ func (a *AnthropicAdapter) TransformResponse(ctx context.Context, resp *http.Res
 	}, nil
 }
 
-// TransformStreamChunk converts an Anthropic SSE data payload to OpenAI streaming format.
-// Anthropic events: message_start, content_block_start, content_block_delta, message_delta, message_stop
-// We convert content_block_delta (text) → OpenAI delta chunk, and message_stop → [DONE].
+// TransformStreamChunk converts one Anthropic SSE data payload without
+// per-stream state, so tool calls are indexed by content block and only the
+// role chunk carries the message ID and model. The gateway uses
+// NewStreamTransformer instead.
 func (a *AnthropicAdapter) TransformStreamChunk(chunk []byte) ([]byte, error) {
+	return (&anthropicStream{}).TransformStreamChunk(chunk)
+}
+
+// NewStreamTransformer implements StreamTransformerFactory.
+func (a *AnthropicAdapter) NewStreamTransformer() StreamTransformer {
+	return &anthropicStream{toolIndex: make(map[int]int)}
+}
+
+// anthropicStream converts one Anthropic event stream to OpenAI chunks.
+// Anthropic numbers content blocks (thinking, text, tool_use) together while
+// OpenAI numbers tool calls on their own; toolIndex maps one to the other.
+// The message ID and model only arrive in message_start, so they are kept
+// for the envelope of every later chunk.
+type anthropicStream struct {
+	toolIndex map[int]int
+	id        string
+	model     string
+	created   int64
+}
+
+func (s *anthropicStream) toolCallIndex(block int, start bool) int {
+	if s.toolIndex == nil {
+		return block
+	}
+	if start {
+		s.toolIndex[block] = len(s.toolIndex)
+	}
+	return s.toolIndex[block]
+}
+
+// TransformStreamChunk converts an Anthropic SSE data payload to OpenAI
+// streaming format: message_start becomes the initial assistant role delta;
+// text, thinking, and tool input deltas become content, reasoning_content,
+// and tool_calls deltas; message_delta carries the finish reason and
+// message_stop becomes [DONE]. Other events are skipped.
+func (s *anthropicStream) TransformStreamChunk(chunk []byte) ([]byte, error) {
 	var event struct {
-		Type  string `json:"type"`
-		Index int    `json:"index"`
-		Delta struct {
-			Type       string `json:"type"`
-			Text       string `json:"text"`
-			StopReason string `json:"stop_reason"`
+		Type    string `json:"type"`
+		Index   int    `json:"index"`
+		Message struct {
+			ID    string `json:"id"`
+			Model string `json:"model"`
+		} `json:"message"`
+		ContentBlock anthropicContentBlock `json:"content_block"`
+		Delta        struct {
+			Type        string `json:"type"`
+			Text        string `json:"text"`
+			Thinking    string `json:"thinking"`
+			PartialJSON string `json:"partial_json"`
+			StopReason  string `json:"stop_reason"`
 		} `json:"delta"`
 	}
 	if err := json.Unmarshal(chunk, &event); err != nil {
 		return nil, nil // skip unparseable chunks
 	}
 
+	var delta openAIDelta
+	var finishReason *string
 	switch event.Type {
+	case "message_start":
+		s.id = event.Message.ID
+		s.model = event.Message.Model
+		s.created = time.Now().Unix()
+		delta.Role = "assistant"
+
+	case "content_block_start":
+		if event.ContentBlock.Type != "tool_use" {
+			return nil, nil
+		}
+		tc := openAIToolCallDelta{
+			Index: s.toolCallIndex(event.Index, true),
+			ID:    event.ContentBlock.ID,
+			Type:  "function",
+		}
+		tc.Function.Name = event.ContentBlock.Name
+		delta.ToolCalls = []openAIToolCallDelta{tc}
+
 	case "content_block_delta":
-		if event.Delta.Type == "text_delta" {
-			oaiChunk := openAIStreamChunk{
-				Choices: []openAIStreamChoice{
-					{
-						Index: event.Index,
-						Delta: openAIDelta{Content: event.Delta.Text},
-					},
-				},
-			}
-			data, err := json.Marshal(oaiChunk)
-			if err != nil {
-				return nil, fmt.Errorf("marshal openai chunk: %w", err)
-			}
-			return data, nil
+		switch event.Delta.Type {
+		case "text_delta":
+			delta.Content = event.Delta.Text
+		case "thinking_delta":
+			delta.ReasoningContent = event.Delta.Thinking
+		case "input_json_delta":
+			tc := openAIToolCallDelta{Index: s.toolCallIndex(event.Index, false)}
+			tc.Function.Arguments = event.Delta.PartialJSON
+			delta.ToolCalls = []openAIToolCallDelta{tc}
+		default:
+			// signature_delta and anything newer
+			return nil, nil
 		}
-		return nil, nil
 
 	case "message_delta":
 		// Final chunk with stop reason and usage
-		finishReason := mapStopReason(event.Delta.StopReason)
-		oaiChunk := openAIStreamChunk{
-			Choices: []openAIStreamChoice{
-				{
-					Index:        0,
-					Delta:        openAIDelta{},
-					FinishReason: &finishReason,
-				},
-			},
-		}
-		data, err := json.Marshal(oaiChunk)
-		if err != nil {
-			return nil, fmt.Errorf("marshal openai finish chunk: %w", err)
-		}
-		return data, nil
+		reason := mapStopReason(event.Delta.StopReason)
+		finishReason = &reason
 
 	case "message_stop":
 		// Signal end of stream — caller should send [DONE]
 		return []byte("[DONE]"), nil
 
 	default:
-		// message_start, content_block_start, content_block_stop, ping — skip
+		// content_block_stop, ping — skip
 		return nil, nil
 	}
+
+	created := s.created
+	if created == 0 {
+		created = time.Now().Unix()
+	}
+	data, err := json.Marshal(openAIStreamChunk{
+		ID:      s.id,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   s.model,
+		Choices: []openAIStreamChoice{{Index: 0, Delta: delta, FinishReason: finishReason}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal openai chunk: %w", err)
+	}
+	return data, nil
+}
+
+// StreamUsage implements StreamUsageReader. Anthropic reports input tokens
+// in message_start and the cumulative output tokens in message_delta.
+func (a *AnthropicAdapter) StreamUsage(chunk []byte) (types.Usage, string, bool) {
+	var event struct {
+		Type    string `json:"type"`
+		Message struct {
+			Model string         `json:"model"`
+			Usage anthropicUsage `json:"usage"`
+		} `json:"message"`
+		Usage anthropicUsage `json:"usage"`
+	}
+	if err := json.Unmarshal(chunk, &event); err != nil {
+		return types.Usage{}, "", false
+	}
+	switch event.Type {
+	case "message_start":
+		return types.Usage{PromptTokens: event.Message.Usage.InputTokens}, event.Message.Model, true
+	case "message_delta":
+		return types.Usage{CompletionTokens: event.Usage.OutputTokens}, "", event.Usage.OutputTokens > 0
+	default:
+		return types.Usage{}, "", false
+	}
 }
 
 func (a *AnthropicAdapter) SendRequest(req *http.Request) (*http.Response, error) {
@@ -197,18 +331,36 @@ func (a *AnthropicAdapter) SendRequest(req *http.Request) (*http.Response, error
 
 // OpenAI streaming format types
 type openAIStreamChunk struct {
+	ID      string               `json:"id"`
+	Object  string               `json:"object"`
+	Created int64                `json:"created"`
+	Model   string               `json:"model"`
 	Choices []openAIStreamChoice `json:"choices"`
 }
 
 type openAIStreamChoice struct {
-	Index        int        `json:"index"`
+	Index        int         `json:"index"`
 	Delta        openAIDelta `json:"delta"`
-	FinishReason *string    `json:"finish_reason"`
+	FinishReason *string     `json:"finish_reason"`
 }
 
 type openAIDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role             string                `json:"role,omitempty"`
+	Content          string                `json:"content,omitempty"`
+	ReasoningContent string                `json:"reasoning_content,omitempty"`
+	ToolCalls        []openAIToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// openAIToolCallDelta is one fragment of a streamed tool call: the first
+// carries the ID and name, later ones pieces of the arguments.
+type openAIToolCallDelta struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 func mapStopReason(reason string) string {
@@ -219,14 +371,18 @@ func mapStopReason(reason string) string {
 		return "length"
 	case "stop_sequence":
 		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "refusal":
+		return "content_filter"
 	default:
 		return reason
 	}
 }
 
 type anthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
 }
 
 type anthropicRequestBody struct {
@@ -238,20 +394,60 @@ type anthropicRequestBody struct {
 	Temperature *float64           `json:"temperature,omitempty"`
 	TopP        *float64           `json:"top_p,omitempty"`
 	Stop        []string           `json:"stop_sequences,omitempty"`
+
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+	Thinking   *anthropicThinking   `json:"thinking,omitempty"`
+}
+
+type anthropicCountTokensBody struct {
+	Model    string             `json:"model"`
+	Messages []anthropicMessage `json:"messages"`
+	System   string             `json:"system,omitempty"`
+	Tools    []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// minThinkingBudget is the smallest thinking budget Anthropic accepts.
+const minThinkingBudget = 1024
+
+// thinkingBudget maps an OpenAI reasoning_effort to a thinking budget that
+// leaves room for the answer within maxTokens, or 0 for no thinking.
+func thinkingBudget(effort string, maxTokens int) int {
+	var budget int
+	switch effort {
+	case "low":
+		budget = minThinkingBudget
+	case "medium":
+		budget = 4096
+	case "high":
+		budget = 16384
+	default:
+		// "minimal" and unset
+		return 0
+	}
+	budget = min(budget, maxTokens/2)
+	if budget < minThinkingBudget {
+		return 0
+	}
+	return budget
 }
 
 type anthropicResponseBody struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	Role       string `json:"role"`
-	Model      string `json:"model"`
-	Content    []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
-	StopReason string `json:"stop_reason"`
-	Usage      struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Model      string                  `json:"model"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      anthropicUsage          `json:"usage"`
+}
+
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
@@ -0,0 +1,208 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// anthropicContentBlock is one block of an Anthropic message. Only the fields
+// of its Type are set.
+type anthropicContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result; Content is a string going out but may be an array of
+	// blocks in server tool results coming back.
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   any    `json:"content,omitempty"`
+
+	// thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+// emptyInputSchema is sent for functions declared without parameters, since
+// Anthropic requires a schema.
+var emptyInputSchema = json.RawMessage(`{"type":"object","properties":{}}`)
+
+// toAnthropicMessages converts OpenAI-format messages. All system messages
+// are joined into the system prompt; assistant tool calls become tool_use
+// blocks and "tool" messages become tool_result blocks. Consecutive messages
+// with the same Anthropic role are merged, since the API expects alternating
+// turns with every result for one assistant turn in a single user message.
+// Anthropic has no per-message name, so a named message's text is prefixed
+// with it to keep the speakers of a multi-agent conversation apart.
+func toAnthropicMessages(msgs []types.Message) (string, []anthropicMessage, error) {
+	var system []string
+	var out []anthropicMessage
+	for _, m := range msgs {
+		role := m.Role
+		var blocks []anthropicContentBlock
+		switch m.Role {
+		case "system", "developer":
+			if m.Content != "" {
+				system = append(system, m.Content)
+			}
+			continue
+		case "tool":
+			role = "user"
+			if m.ToolCallID == "" {
+				return "", nil, fmt.Errorf("tool message has no tool_call_id")
+			}
+			block := anthropicContentBlock{Type: "tool_result", ToolUseID: m.ToolCallID}
+			if m.Content != "" {
+				block.Content = m.Content
+			}
+			blocks = append(blocks, block)
+		case "assistant":
+			// Thinking can only be sent back with the signature Anthropic
+			// issued for it; thinking from elsewhere is dropped.
+			if m.ReasoningContent != "" && m.ReasoningSignature != "" {
+				blocks = append(blocks, anthropicContentBlock{
+					Type:      "thinking",
+					Thinking:  m.ReasoningContent,
+					Signature: m.ReasoningSignature,
+				})
+			}
+			if m.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: speakerText(m)})
+			}
+			for _, tc := range m.ToolCalls {
+				input := json.RawMessage(tc.Function.Arguments)
+				if len(input) == 0 {
+					input = json.RawMessage(`{}`)
+				}
+				if !json.Valid(input) {
+					return "", nil, fmt.Errorf("tool call %s: arguments are not valid JSON", tc.ID)
+				}
+				blocks = append(blocks, anthropicContentBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: input,
+				})
+			}
+		default:
+			if m.Content != "" {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: speakerText(m)})
+			}
+		}
+
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			continue
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+	return strings.Join(system, "\n\n"), out, nil
+}
+
+func speakerText(m types.Message) string {
+	if m.Name == "" {
+		return m.Content
+	}
+	return m.Name + ": " + m.Content
+}
+
+// toAnthropicTools converts OpenAI function tools.
+func toAnthropicTools(tools []types.Tool) []anthropicTool {
+	if len(tools) == 0 {
+		return nil
+	}
+	out := make([]anthropicTool, 0, len(tools))
+	for _, t := range tools {
+		schema := t.Function.Parameters
+		if len(schema) == 0 {
+			schema = emptyInputSchema
+		}
+		out = append(out, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: schema,
+		})
+	}
+	return out
+}
+
+// toAnthropicToolChoice converts an OpenAI tool_choice: "auto", "none",
+// "required", or {"type":"function","function":{"name":...}}.
+func toAnthropicToolChoice(raw json.RawMessage) (*anthropicToolChoice, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		switch mode {
+		case "auto", "none":
+			return &anthropicToolChoice{Type: mode}, nil
+		case "required":
+			return &anthropicToolChoice{Type: "any"}, nil
+		}
+		return nil, fmt.Errorf("unsupported tool_choice %q", mode)
+	}
+	var named struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil || named.Function.Name == "" {
+		return nil, fmt.Errorf("unsupported tool_choice %s", raw)
+	}
+	return &anthropicToolChoice{Type: "tool", Name: named.Function.Name}, nil
+}
+
+// fromAnthropicContent converts response content blocks to an assistant
+// message. Text blocks are concatenated (citations split one answer into
+// many), tool_use blocks become tool calls, and thinking becomes reasoning
+// content. The thinking signature is kept only for a single thinking block,
+// the one case in which the joined text can be sent back verbatim.
+func fromAnthropicContent(blocks []anthropicContentBlock) types.Message {
+	msg := types.Message{Role: "assistant"}
+	var text, thinking strings.Builder
+	thinkingBlocks := 0
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			text.WriteString(b.Text)
+		case "thinking":
+			thinking.WriteString(b.Thinking)
+			msg.ReasoningSignature = b.Signature
+			thinkingBlocks++
+		case "tool_use":
+			args := string(b.Input)
+			if args == "" {
+				args = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, types.ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: types.ToolCallFunction{Name: b.Name, Arguments: args},
+			})
+		}
+	}
+	msg.Content = text.String()
+	msg.ReasoningContent = thinking.String()
+	if thinkingBlocks != 1 {
+		msg.ReasoningSignature = ""
+	}
+	return msg
+}
@@ -0,0 +1,313 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestAnthropicAdapter_TransformRequest_ToolRoundTrip(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+
+	req := &types.AegisRequest{
+		Model: "claude-sonnet-4-5-20250929",
+		Messages: []types.Message{
+			{Role: "system", Content: "You are helpful."},
+			{Role: "system", Content: "Answer in French."},
+			{Role: "user", Content: "Weather in Paris and Lyon?"},
+			{
+				Role:               "assistant",
+				Content:            "Checking both.",
+				ReasoningContent:   "Two lookups needed.",
+				ReasoningSignature: "sig-1",
+				ToolCalls: []types.ToolCall{
+					{ID: "toolu_1", Type: "function", Function: types.ToolCallFunction{Name: "weather", Arguments: `{"city":"Paris"}`}},
+					{ID: "toolu_2", Type: "function", Function: types.ToolCallFunction{Name: "weather", Arguments: `{"city":"Lyon"}`}},
+				},
+			},
+			{Role: "tool", ToolCallID: "toolu_1", Content: "18C"},
+			{Role: "tool", ToolCallID: "toolu_2", Content: "21C"},
+		},
+		Tools: []types.Tool{{Type: "function", Function: types.ToolFunction{
+			Name: "weather", Description: "Current weather", Parameters: json.RawMessage(`{"type":"object","properties":{"city":{"type":"string"}}}`),
+		}}},
+		ToolChoice: json.RawMessage(`"required"`),
+	}
+
+	httpReq, err := a.TransformRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(httpReq.Body)
+	var parsed anthropicRequestBody
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatalf("body is not valid JSON: %v", err)
+	}
+
+	if parsed.System != "You are helpful.\n\nAnswer in French." {
+		t.Errorf("system messages should be joined, got %q", parsed.System)
+	}
+	if len(parsed.Messages) != 3 {
+		t.Fatalf("expected user, assistant, user(tool results), got %d messages", len(parsed.Messages))
+	}
+	assistant := parsed.Messages[1].Content
+	if len(assistant) != 4 || assistant[0].Type != "thinking" || assistant[0].Signature != "sig-1" ||
+		assistant[1].Type != "text" || assistant[2].Type != "tool_use" || string(assistant[3].Input) != `{"city":"Lyon"}` {
+		t.Errorf("unexpected assistant blocks: %+v", assistant)
+	}
+	results := parsed.Messages[2]
+	if results.Role != "user" || len(results.Content) != 2 ||
+		results.Content[0].ToolUseID != "toolu_1" || results.Content[1].Content != "21C" {
+		t.Errorf("tool results should be merged into one user turn: %+v", results)
+	}
+	if len(parsed.Tools) != 1 || parsed.Tools[0].Name != "weather" || !strings.Contains(string(parsed.Tools[0].InputSchema), "city") {
+		t.Errorf("unexpected tools: %+v", parsed.Tools)
+	}
+	if parsed.ToolChoice == nil || parsed.ToolChoice.Type != "any" {
+		t.Errorf("required should map to any, got %+v", parsed.ToolChoice)
+	}
+}
+
+func TestAnthropicAdapter_TransformRequest_InvalidToolArguments(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	_, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+		Model: "claude-sonnet-4-5-20250929",
+		Messages: []types.Message{{Role: "assistant", ToolCalls: []types.ToolCall{
+			{ID: "toolu_1", Function: types.ToolCallFunction{Name: "f", Arguments: `{"city":`}},
+		}}},
+	})
+	if err == nil {
+		t.Error("expected an error for truncated tool arguments")
+	}
+}
+
+func TestToAnthropicMessages_NamesAndToolCallIDs(t *testing.T) {
+	_, msgs, err := toAnthropicMessages([]types.Message{
+		{Role: "user", Name: "planner", Content: "Split the task."},
+		{Role: "user", Name: "critic", Content: "Keep it short."},
+		{Role: "assistant", Name: "writer", Content: "Done.", ToolCalls: []types.ToolCall{
+			{ID: "call_1", Type: "function", Function: types.ToolCallFunction{Name: "save", Arguments: `{}`}},
+		}},
+		{Role: "tool", Name: "save", ToolCallID: "call_1", Content: "saved"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msgs) != 3 || len(msgs[0].Content) != 2 {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if msgs[0].Content[0].Text != "planner: Split the task." || msgs[0].Content[1].Text != "critic: Keep it short." {
+		t.Errorf("expected speaker names on user text, got %+v", msgs[0].Content)
+	}
+	if msgs[1].Content[0].Text != "writer: Done." {
+		t.Errorf("expected speaker name on assistant text, got %q", msgs[1].Content[0].Text)
+	}
+	if r := msgs[2].Content[0]; r.Type != "tool_result" || r.ToolUseID != "call_1" || r.Content != "saved" {
+		t.Errorf("expected tool_result for call_1, got %+v", r)
+	}
+
+	if _, _, err := toAnthropicMessages([]types.Message{{Role: "tool", Content: "orphan"}}); err == nil {
+		t.Error("expected an error for a tool message without tool_call_id")
+	}
+}
+
+func TestToAnthropicToolChoice(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    *anthropicToolChoice
+		wantErr bool
+	}{
+		{raw: ``},
+		{raw: `"auto"`, want: &anthropicToolChoice{Type: "auto"}},
+		{raw: `"none"`, want: &anthropicToolChoice{Type: "none"}},
+		{raw: `"required"`, want: &anthropicToolChoice{Type: "any"}},
+		{raw: `{"type":"function","function":{"name":"weather"}}`, want: &anthropicToolChoice{Type: "tool", Name: "weather"}},
+		{raw: `"sometimes"`, wantErr: true},
+		{raw: `{"type":"function"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := toAnthropicToolChoice(json.RawMessage(tt.raw))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v", tt.raw, err)
+			continue
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestAnthropicAdapter_TransformResponse_MultipleBlocks(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	respBody := `{
+		"model": "claude-sonnet-4-5-20250929",
+		"content": [
+			{"type": "thinking", "thinking": "User wants weather.", "signature": "sig-9"},
+			{"type": "text", "text": "Paris is "},
+			{"type": "text", "text": "sunny. Let me check Lyon."},
+			{"type": "tool_use", "id": "toolu_7", "name": "weather", "input": {"city": "Lyon"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 50, "output_tokens": 30}
+	}`
+	aegisResp, err := a.TransformResponse(context.Background(), &http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(strings.NewReader(respBody)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	choice := aegisResp.Choices[0]
+	if choice.Message.Content != "Paris is sunny. Let me check Lyon." {
+		t.Errorf("text blocks should be concatenated, got %q", choice.Message.Content)
+	}
+	if choice.Message.ReasoningContent != "User wants weather." || choice.Message.ReasoningSignature != "sig-9" {
+		t.Errorf("unexpected reasoning: %+v", choice.Message)
+	}
+	if len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].ID != "toolu_7" ||
+		choice.Message.ToolCalls[0].Function.Arguments != `{"city": "Lyon"}` {
+		t.Errorf("unexpected tool calls: %+v", choice.Message.ToolCalls)
+	}
+	if choice.FinishReason != "tool_calls" {
+		t.Errorf("tool_use should map to tool_calls, got %s", choice.FinishReason)
+	}
+}
+
+func TestAnthropicStream_ToolCallsAndThinking(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	s := a.NewStreamTransformer()
+
+	events := []string{
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Hmm."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"abc"}}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Checking."}}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+		`{"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+	}
+	var chunks []openAIStreamChunk
+	for _, e := range events {
+		out, err := s.TransformStreamChunk([]byte(e))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if out == nil {
+			continue
+		}
+		var c openAIStreamChunk
+		if err := json.Unmarshal(out, &c); err != nil {
+			t.Fatalf("invalid chunk %s: %v", out, err)
+		}
+		if c.Choices[0].Index != 0 {
+			t.Errorf("choice index must stay 0, got %d", c.Choices[0].Index)
+		}
+		chunks = append(chunks, c)
+	}
+
+	if len(chunks) != 6 {
+		t.Fatalf("expected 6 chunks, got %d", len(chunks))
+	}
+	if chunks[0].Choices[0].Delta.ReasoningContent != "Hmm." || chunks[1].Choices[0].Delta.Content != "Checking." {
+		t.Errorf("unexpected thinking/text chunks: %+v %+v", chunks[0], chunks[1])
+	}
+	start := chunks[2].Choices[0].Delta.ToolCalls[0]
+	if start.Index != 0 || start.ID != "toolu_1" || start.Function.Name != "weather" {
+		t.Errorf("first tool call should be index 0 whatever its block index: %+v", start)
+	}
+	args := chunks[3].Choices[0].Delta.ToolCalls[0].Function.Arguments + chunks[4].Choices[0].Delta.ToolCalls[0].Function.Arguments
+	if args != `{"city":"Paris"}` || chunks[4].Choices[0].Delta.ToolCalls[0].Index != 0 {
+		t.Errorf("unexpected argument deltas: %q", args)
+	}
+	if fr := chunks[5].Choices[0].FinishReason; fr == nil || *fr != "tool_calls" {
+		t.Errorf("expected finish_reason tool_calls, got %v", fr)
+	}
+}
+
+func TestAnthropicStream_ChunkEnvelope(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	s := a.NewStreamTransformer()
+
+	first, err := s.TransformStreamChunk([]byte(`{"type":"message_start","message":{"id":"msg_01","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":5}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, _ := s.TransformStreamChunk([]byte(`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`))
+
+	var role, text openAIStreamChunk
+	if err := json.Unmarshal(first, &role); err != nil {
+		t.Fatalf("message_start should yield a role chunk, got %s", first)
+	}
+	if err := json.Unmarshal(next, &text); err != nil {
+		t.Fatalf("invalid chunk %s: %v", next, err)
+	}
+	if role.Choices[0].Delta.Role != "assistant" || role.Choices[0].Delta.Content != "" {
+		t.Errorf("unexpected role delta: %+v", role.Choices[0].Delta)
+	}
+	for _, c := range []openAIStreamChunk{role, text} {
+		if c.ID != "msg_01" || c.Object != "chat.completion.chunk" || c.Model != "claude-sonnet-4-5-20250929" || c.Created == 0 {
+			t.Errorf("incomplete envelope: %+v", c)
+		}
+	}
+	if text.Created != role.Created {
+		t.Errorf("created should stay fixed for the stream: %d vs %d", role.Created, text.Created)
+	}
+}
+
+func TestAnthropicAdapter_TransformRequest_ReasoningEffort(t *testing.T) {
+	a := NewAnthropicAdapter(newAnthropicCfg(), http.DefaultClient)
+	limit := 8000
+	temp := 0.2
+
+	httpReq, err := a.TransformRequest(context.Background(), &types.AegisRequest{
+		Model:               "claude-sonnet-4-5-20250929",
+		Messages:            []types.Message{{Role: "user", Content: "Hi"}},
+		MaxCompletionTokens: &limit,
+		ReasoningEffort:     "high",
+		Temperature:         &temp,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := io.ReadAll(httpReq.Body)
+	var parsed anthropicRequestBody
+	_ = json.Unmarshal(body, &parsed)
+
+	if parsed.MaxTokens != 8000 {
+		t.Errorf("max_completion_tokens should become max_tokens, got %d", parsed.MaxTokens)
+	}
+	if parsed.Thinking == nil || parsed.Thinking.BudgetTokens != 4000 {
+		t.Errorf("thinking budget should leave room for the answer: %+v", parsed.Thinking)
+	}
+	if parsed.Temperature != nil {
+		t.Error("temperature must not be sent with thinking enabled")
+	}
+}
+
+func TestThinkingBudget(t *testing.T) {
+	tests := []struct {
+		effort    string
+		maxTokens int
+		want      int
+	}{
+		{"", 4096, 0},
+		{"minimal", 4096, 0},
+		{"low", 4096, 1024},
+		{"medium", 32000, 4096},
+		{"high", 64000, 16384},
+		{"medium", 1500, 0},
+	}
+	for _, tt := range tests {
+		if got := thinkingBudget(tt.effort, tt.maxTokens); got != tt.want {
+			t.Errorf("thinkingBudget(%q, %d) = %d, want %d", tt.effort, tt.maxTokens, got, tt.want)
+		}
+	}
+}
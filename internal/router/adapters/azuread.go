@@ -0,0 +1,177 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+const (
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com"
+	defaultAzureScope         = "https://cognitiveservices.azure.com/.default"
+
+	// azureTokenRefreshMargin renews tokens this long before they expire, so
+	// a request never leaves with a token that lapses in flight.
+	azureTokenRefreshMargin = 5 * time.Minute
+	// azureTokenRequestTimeout bounds one call to the token endpoint.
+	azureTokenRequestTimeout = 10 * time.Second
+
+	jwtBearerAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// TokenSource supplies bearer tokens for provider requests.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// AzureADTokenSource obtains Azure AD (Entra ID) access tokens with the OAuth
+// client credentials flow, authenticating either with a client secret or
+// with a federated workload identity token read from disk. Tokens are cached
+// and renewed shortly before they expire; concurrent callers share one
+// refresh.
+type AzureADTokenSource struct {
+	tokenURL  string
+	clientID  string
+	secret    string
+	tokenFile string
+	scope     string
+	client    *http.Client
+	now       func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAzureADTokenSource creates a token source from provider auth config,
+// filling unset fields from the AZURE_* variables the workload identity
+// webhook injects.
+func NewAzureADTokenSource(cfg config.ProviderAuthConfig, client *http.Client) (*AzureADTokenSource, error) {
+	tenantID := firstNonEmpty(cfg.TenantID, os.Getenv("AZURE_TENANT_ID"))
+	clientID := firstNonEmpty(cfg.ClientID, os.Getenv("AZURE_CLIENT_ID"))
+	authority := firstNonEmpty(cfg.AuthorityHost, os.Getenv("AZURE_AUTHORITY_HOST"), defaultAzureAuthorityHost)
+	if tenantID == "" || clientID == "" {
+		return nil, fmt.Errorf("azure ad auth: tenant_id and client_id are required")
+	}
+
+	ts := &AzureADTokenSource{
+		tokenURL: strings.TrimRight(authority, "/") + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+		clientID: clientID,
+		scope:    firstNonEmpty(cfg.Scope, defaultAzureScope),
+		client:   client,
+		now:      time.Now,
+	}
+	switch cfg.Type {
+	case config.ProviderAuthAzureClientCredentials:
+		if cfg.ClientSecret == "" {
+			return nil, fmt.Errorf("azure ad auth: client_secret is required")
+		}
+		ts.secret = cfg.ClientSecret
+	case config.ProviderAuthAzureWorkloadIdentity:
+		ts.tokenFile = firstNonEmpty(cfg.FederatedTokenFile, os.Getenv("AZURE_FEDERATED_TOKEN_FILE"))
+		if ts.tokenFile == "" {
+			return nil, fmt.Errorf("azure ad auth: federated_token_file is required")
+		}
+	default:
+		return nil, fmt.Errorf("azure ad auth: unknown type %q", cfg.Type)
+	}
+	if ts.client == nil {
+		ts.client = &http.Client{Timeout: azureTokenRequestTimeout}
+	}
+	return ts, nil
+}
+
+// Token returns a cached access token, fetching a new one when it is missing
+// or about to expire.
+func (ts *AzureADTokenSource) Token(ctx context.Context) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && ts.now().Add(azureTokenRefreshMargin).Before(ts.expires) {
+		return ts.token, nil
+	}
+
+	token, expiresIn, err := ts.fetch(ctx)
+	if err != nil {
+		// A token that is merely inside the refresh margin still works;
+		// riding out a brief identity outage beats failing requests.
+		if ts.token != "" && ts.now().Before(ts.expires) {
+			return ts.token, nil
+		}
+		return "", err
+	}
+	ts.token = token
+	ts.expires = ts.now().Add(expiresIn)
+	return ts.token, nil
+}
+
+func (ts *AzureADTokenSource) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {ts.clientID},
+		"scope":      {ts.scope},
+	}
+	if ts.tokenFile != "" {
+		// Kubernetes rotates the projected token, so read it every time.
+		assertion, err := os.ReadFile(ts.tokenFile)
+		if err != nil {
+			return "", 0, fmt.Errorf("azure ad auth: read federated token: %w", err)
+		}
+		form.Set("client_assertion_type", jwtBearerAssertionType)
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	} else {
+		form.Set("client_secret", ts.secret)
+	}
+
+	// Detached from the caller: a refresh shared by concurrent requests must
+	// not fail because the first of them was cancelled.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), azureTokenRequestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ts.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("azure ad auth: create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("azure ad auth: token request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", 0, fmt.Errorf("azure ad auth: read token response: %w", err)
+	}
+
+	var parsed struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(body, &parsed)
+	if resp.StatusCode != http.StatusOK || parsed.AccessToken == "" {
+		if parsed.Error != "" {
+			return "", 0, fmt.Errorf("azure ad auth: token endpoint returned %d: %s: %s", resp.StatusCode, parsed.Error, parsed.ErrorDescription)
+		}
+		return "", 0, fmt.Errorf("azure ad auth: token endpoint returned %d", resp.StatusCode)
+	}
+	return parsed.AccessToken, time.Duration(parsed.ExpiresIn) * time.Second, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+package adapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// newTokenServer fakes the Azure AD token endpoint, checking the grant and
+// handing out numbered tokens valid for an hour.
+func newTokenServer(t *testing.T, check func(r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant-1/oauth2/v2.0/token" {
+			t.Errorf("unexpected token path %s", r.URL.Path)
+		}
+		_ = r.ParseForm()
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("client_id") != "client-1" ||
+			r.PostForm.Get("scope") != defaultAzureScope {
+			t.Errorf("unexpected token form %v", r.PostForm)
+		}
+		check(r)
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token_type":"Bearer","expires_in":3600,"access_token":"token-` + string(rune('0'+n)) + `"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestAzureADTokenSource_ClientCredentialsCachesAndRefreshes(t *testing.T) {
+	srv, calls := newTokenServer(t, func(r *http.Request) {
+		if r.PostForm.Get("client_secret") != "s3cret" {
+			t.Errorf("client_secret = %q", r.PostForm.Get("client_secret"))
+		}
+	})
+	ts, err := NewAzureADTokenSource(config.ProviderAuthConfig{
+		Type:          config.ProviderAuthAzureClientCredentials,
+		TenantID:      "tenant-1",
+		ClientID:      "client-1",
+		ClientSecret:  "s3cret",
+		AuthorityHost: srv.URL,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	ts.now = func() time.Time { return now }
+
+	for range 3 {
+		if tok, err := ts.Token(context.Background()); err != nil || tok != "token-1" {
+			t.Fatalf("Token() = %q, %v", tok, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls.Load())
+	}
+
+	// Inside the refresh margin a new token is fetched.
+	now = now.Add(time.Hour - azureTokenRefreshMargin + time.Second)
+	if tok, _ := ts.Token(context.Background()); tok != "token-2" {
+		t.Errorf("expected a refreshed token, got %q", tok)
+	}
+}
+
+func TestAzureADTokenSource_WorkloadIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "azure-identity-token")
+	if err := os.WriteFile(tokenFile, []byte("federated-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv, _ := newTokenServer(t, func(r *http.Request) {
+		if r.PostForm.Get("client_assertion_type") != jwtBearerAssertionType || r.PostForm.Get("client_assertion") != "federated-jwt" {
+			t.Errorf("unexpected assertion %v", r.PostForm)
+		}
+		if r.PostForm.Has("client_secret") {
+			t.Error("workload identity must not send a client secret")
+		}
+	})
+	t.Setenv("AZURE_TENANT_ID", "tenant-1")
+	t.Setenv("AZURE_CLIENT_ID", "client-1")
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", tokenFile)
+	t.Setenv("AZURE_AUTHORITY_HOST", srv.URL)
+
+	ts, err := NewAzureADTokenSource(config.ProviderAuthConfig{Type: config.ProviderAuthAzureWorkloadIdentity}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewOpenAIAdapter(config.ProviderConfig{BaseURL: "https://example.openai.azure.com", APIKey: "unused"}, nil)
+	a.SetTokenSource(ts)
+	req, err := a.TransformRequest(context.Background(), &types.AegisRequest{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token-1" {
+		t.Errorf("Authorization = %q, want the Azure AD token", got)
+	}
+}
+
+func TestAzureADTokenSource_TokenEndpointError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"AADSTS7000215: Invalid client secret provided."}`))
+	}))
+	defer srv.Close()
+	ts, err := NewAzureADTokenSource(config.ProviderAuthConfig{
+		Type: config.ProviderAuthAzureClientCredentials, TenantID: "t", ClientID: "c", ClientSecret: "wrong", AuthorityHost: srv.URL,
+	}, srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewOpenAIAdapter(config.ProviderConfig{BaseURL: "https://example.openai.azure.com"}, nil)
+	a.SetTokenSource(ts)
+	if _, err := a.TransformRequest(context.Background(), &types.AegisRequest{Model: "gpt-4o"}); err == nil {
+		t.Fatal("expected the token error to fail the request")
+	}
+}
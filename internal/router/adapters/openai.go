@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/types"
@@ -17,12 +18,19 @@ import (
 type OpenAIAdapter struct {
 	cfg    config.ProviderConfig
 	client *http.Client
+	tokens TokenSource
 }
 
 func NewOpenAIAdapter(cfg config.ProviderConfig, client *http.Client) *OpenAIAdapter {
 	return &OpenAIAdapter{cfg: cfg, client: client}
 }
 
+// SetTokenSource authenticates requests with tokens from ts (e.g. Azure AD)
+// instead of the static API key.
+func (a *OpenAIAdapter) SetTokenSource(ts TokenSource) {
+	a.tokens = ts
+}
+
 func (a *OpenAIAdapter) Name() string { return "openai" }
 
 func (a *OpenAIAdapter) SupportsStreaming() bool { return true }
@@ -36,6 +44,26 @@ func (a *OpenAIAdapter) TransformRequest(ctx context.Context, req *types.AegisRe
 		MaxTokens:   req.MaxTokens,
 		TopP:        req.TopP,
 		Stop:        req.Stop,
+		Tools:       req.Tools,
+		ToolChoice:  req.ToolChoice,
+
+		MaxCompletionTokens: req.MaxCompletionTokens,
+		ResponseFormat:      req.ResponseFormat,
+	}
+	// Reasoning models reject max_tokens and are the only ones that take
+	// reasoning_effort; older OpenAI-compatible servers may know neither
+	// newer field, so other models get the limit under the name sent.
+	if isReasoningModel(req.Model) {
+		body.MaxTokens = nil
+		body.MaxCompletionTokens = req.CompletionTokenLimit()
+		body.ReasoningEffort = req.ReasoningEffort
+	} else if body.MaxCompletionTokens != nil {
+		body.MaxTokens = nil
+	}
+	// Always ask for the usage chunk so streams can be costed; the gateway
+	// drops it again unless the client asked for it too.
+	if req.Stream {
+		body.StreamOptions = &types.StreamOptions{IncludeUsage: true}
 	}
 
 	data, err := json.Marshal(body)
@@ -49,13 +77,28 @@ func (a *OpenAIAdapter) TransformRequest(ctx context.Context, req *types.AegisRe
 		return nil, fmt.Errorf("create http request: %w", err)
 	}
 
+	credential := a.cfg.APIKey
+	if a.tokens != nil {
+		if credential, err = a.tokens.Token(ctx); err != nil {
+			return nil, fmt.Errorf("get provider token: %w", err)
+		}
+	}
+
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+a.cfg.APIKey)
+	httpReq.Header.Set("Authorization", "Bearer "+credential)
+	setCorrelationHeaders(httpReq, req, a.cfg.ForwardTraceContext)
 	for k, v := range a.cfg.Headers {
 		if v != "" {
 			httpReq.Header.Set(k, v)
 		}
 	}
+	org, project, _ := a.cfg.Account(req.TeamID)
+	if org != "" {
+		httpReq.Header.Set("OpenAI-Organization", org)
+	}
+	if project != "" {
+		httpReq.Header.Set("OpenAI-Project", project)
+	}
 
 	return httpReq, nil
 }
@@ -69,7 +112,7 @@ func (a *OpenAIAdapter) TransformResponse(ctx context.Context, resp *http.Respon
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("openai returned status %d: %s", resp.StatusCode, string(body))
+		return nil, ResponseProviderError("openai", resp, body)
 	}
 
 	var oaiResp openAIResponseBody
@@ -89,11 +132,8 @@ func (a *OpenAIAdapter) TransformResponse(ctx context.Context, resp *http.Respon
 
 	for _, c := range oaiResp.Choices {
 		aegisResp.Choices = append(aegisResp.Choices, types.Choice{
-			Index: c.Index,
-			Message: types.Message{
-				Role:    c.Message.Role,
-				Content: c.Message.Content,
-			},
+			Index:        c.Index,
+			Message:      c.Message,
 			FinishReason: c.FinishReason,
 		})
 	}
@@ -106,6 +146,9 @@ func (a *OpenAIAdapter) TransformStreamChunk(chunk []byte) ([]byte, error) {
 	return chunk, nil
 }
 
+// PassThroughStream reports that OpenAI chunks need no conversion.
+func (a *OpenAIAdapter) PassThroughStream() bool { return true }
+
 func (a *OpenAIAdapter) SendRequest(req *http.Request) (*http.Response, error) {
 	return a.client.Do(req)
 }
@@ -118,6 +161,15 @@ type openAIRequestBody struct {
 	MaxTokens   *int            `json:"max_tokens,omitempty"`
 	TopP        *float64        `json:"top_p,omitempty"`
 	Stop        []string        `json:"stop,omitempty"`
+
+	MaxCompletionTokens *int   `json:"max_completion_tokens,omitempty"`
+	ReasoningEffort     string `json:"reasoning_effort,omitempty"`
+
+	Tools      []types.Tool    `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"`
+
+	StreamOptions  *types.StreamOptions `json:"stream_options,omitempty"`
+	ResponseFormat json.RawMessage      `json:"response_format,omitempty"`
 }
 
 type openAIResponseBody struct {
@@ -126,9 +178,9 @@ type openAIResponseBody struct {
 	Created int64  `json:"created"`
 	Model   string `json:"model"`
 	Choices []struct {
-		Index        int    `json:"index"`
+		Index        int           `json:"index"`
 		Message      types.Message `json:"message"`
-		FinishReason string `json:"finish_reason"`
+		FinishReason string        `json:"finish_reason"`
 	} `json:"choices"`
 	Usage struct {
 		PromptTokens     int `json:"prompt_tokens"`
@@ -136,3 +188,15 @@ type openAIResponseBody struct {
 		TotalTokens      int `json:"total_tokens"`
 	} `json:"usage"`
 }
+
+// isReasoningModel reports whether an OpenAI model is one of the reasoning
+// models (o-series and gpt-5) that use max_completion_tokens and accept
+// reasoning_effort.
+func isReasoningModel(model string) bool {
+	for _, prefix := range []string{"o1", "o3", "o4", "gpt-5"} {
+		if model == prefix || strings.HasPrefix(model, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
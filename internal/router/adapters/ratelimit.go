@@ -0,0 +1,86 @@
+package adapters
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateLimitWindow is one dimension (requests or tokens) of a provider's
+// quota as reported on a response. Limit is 0 when the provider reports only
+// what remains (Azure OpenAI). Reset is zero when not reported.
+type RateLimitWindow struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitStatus is the provider's quota as of one response. A dimension
+// the provider did not report is nil.
+type RateLimitStatus struct {
+	Requests *RateLimitWindow
+	Tokens   *RateLimitWindow
+}
+
+// ParseRateLimitHeaders reads the OpenAI (x-ratelimit-*) or Anthropic
+// (anthropic-ratelimit-*) rate limit headers from a response. ok is false
+// when the response carries neither. OpenAI reports resets as durations
+// ("6m0s") and Anthropic as RFC 3339 times; both become absolute times
+// relative to now.
+func ParseRateLimitHeaders(h http.Header, now time.Time) (status RateLimitStatus, ok bool) {
+	status.Requests = parseRateLimitWindow(h, now,
+		"X-Ratelimit-Limit-Requests", "X-Ratelimit-Remaining-Requests", "X-Ratelimit-Reset-Requests")
+	status.Tokens = parseRateLimitWindow(h, now,
+		"X-Ratelimit-Limit-Tokens", "X-Ratelimit-Remaining-Tokens", "X-Ratelimit-Reset-Tokens")
+	if status.Requests == nil {
+		status.Requests = parseRateLimitWindow(h, now,
+			"Anthropic-Ratelimit-Requests-Limit", "Anthropic-Ratelimit-Requests-Remaining", "Anthropic-Ratelimit-Requests-Reset")
+	}
+	if status.Tokens == nil {
+		status.Tokens = parseRateLimitWindow(h, now,
+			"Anthropic-Ratelimit-Tokens-Limit", "Anthropic-Ratelimit-Tokens-Remaining", "Anthropic-Ratelimit-Tokens-Reset")
+	}
+	return status, status.Requests != nil || status.Tokens != nil
+}
+
+// parseRateLimitWindow returns nil unless the remaining header is present
+// and numeric.
+func parseRateLimitWindow(h http.Header, now time.Time, limitHeader, remainingHeader, resetHeader string) *RateLimitWindow {
+	remaining, err := strconv.Atoi(h.Get(remainingHeader))
+	if err != nil {
+		return nil
+	}
+	w := &RateLimitWindow{Remaining: remaining}
+	w.Limit, _ = strconv.Atoi(h.Get(limitHeader))
+	if v := h.Get(resetHeader); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			w.Reset = t
+		} else if d, err := time.ParseDuration(v); err == nil {
+			w.Reset = now.Add(d)
+		}
+	}
+	return w
+}
+
+// ParseRetryAt returns when a rejected request may be retried: the
+// Retry-After header, in seconds or as an HTTP date, or failing that the
+// latest reset of any rate limit window reported as exhausted. It is zero
+// when the response says neither.
+func ParseRetryAt(h http.Header, now time.Time) time.Time {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return now.Add(time.Duration(secs) * time.Second)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t
+		}
+	}
+	var at time.Time
+	status, _ := ParseRateLimitHeaders(h, now)
+	for _, w := range []*RateLimitWindow{status.Requests, status.Tokens} {
+		if w != nil && w.Remaining == 0 && w.Reset.After(at) {
+			at = w.Reset
+		}
+	}
+	return at
+}
@@ -0,0 +1,85 @@
+package adapters
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders_OpenAI(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	h := http.Header{}
+	h.Set("x-ratelimit-limit-requests", "5000")
+	h.Set("x-ratelimit-remaining-requests", "4999")
+	h.Set("x-ratelimit-reset-requests", "12ms")
+	h.Set("x-ratelimit-limit-tokens", "800000")
+	h.Set("x-ratelimit-remaining-tokens", "12000")
+	h.Set("x-ratelimit-reset-tokens", "6m0s")
+
+	s, ok := ParseRateLimitHeaders(h, now)
+	if !ok || s.Requests == nil || s.Tokens == nil {
+		t.Fatalf("expected both windows, got %+v", s)
+	}
+	if s.Requests.Limit != 5000 || s.Requests.Remaining != 4999 || !s.Requests.Reset.Equal(now.Add(12*time.Millisecond)) {
+		t.Errorf("requests = %+v", *s.Requests)
+	}
+	if s.Tokens.Limit != 800000 || s.Tokens.Remaining != 12000 || !s.Tokens.Reset.Equal(now.Add(6*time.Minute)) {
+		t.Errorf("tokens = %+v", *s.Tokens)
+	}
+}
+
+func TestParseRateLimitHeaders_Anthropic(t *testing.T) {
+	h := http.Header{}
+	h.Set("anthropic-ratelimit-requests-limit", "50")
+	h.Set("anthropic-ratelimit-requests-remaining", "3")
+	h.Set("anthropic-ratelimit-requests-reset", "2026-01-01T12:00:30Z")
+
+	s, ok := ParseRateLimitHeaders(h, time.Now())
+	if !ok || s.Requests == nil || s.Tokens != nil {
+		t.Fatalf("expected only a requests window, got %+v", s)
+	}
+	if s.Requests.Limit != 50 || s.Requests.Remaining != 3 ||
+		!s.Requests.Reset.Equal(time.Date(2026, 1, 1, 12, 0, 30, 0, time.UTC)) {
+		t.Errorf("requests = %+v", *s.Requests)
+	}
+}
+
+func TestParseRateLimitHeaders_Absent(t *testing.T) {
+	h := http.Header{}
+	h.Set("x-ratelimit-remaining-requests", "unknown")
+	if _, ok := ParseRateLimitHeaders(h, time.Now()); ok {
+		t.Error("expected no status without numeric remaining headers")
+	}
+}
+
+func TestParseRetryAt(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Time
+	}{
+		{"seconds", map[string]string{"Retry-After": "20"}, now.Add(20 * time.Second)},
+		{"http date", map[string]string{"Retry-After": "Thu, 01 Jan 2026 12:01:00 GMT"}, now.Add(time.Minute)},
+		{"exhausted windows", map[string]string{
+			"x-ratelimit-remaining-requests": "0",
+			"x-ratelimit-reset-requests":     "2s",
+			"x-ratelimit-remaining-tokens":   "0",
+			"x-ratelimit-reset-tokens":       "30s",
+		}, now.Add(30 * time.Second)},
+		{"window not exhausted", map[string]string{
+			"x-ratelimit-remaining-requests": "5",
+			"x-ratelimit-reset-requests":     "2s",
+		}, time.Time{}},
+		{"absent", nil, time.Time{}},
+	}
+	for _, tt := range tests {
+		h := http.Header{}
+		for k, v := range tt.headers {
+			h.Set(k, v)
+		}
+		if got := ParseRetryAt(h, now); !got.Equal(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
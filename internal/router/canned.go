@@ -0,0 +1,236 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// defaultMockResponse is what a mock provider answers when its config sets
+// no response.
+const defaultMockResponse = "This is a mock response from AEGIS."
+
+// cannedTransport answers every provider call with a canned response in the
+// provider's wire format, so the adapter parses it exactly as it would a
+// real one and usage is metered as usual. Nothing leaves the gateway. It
+// backs dry-run mode and the mock provider type.
+type cannedTransport struct {
+	anthropic bool
+	response  string
+	latency   time.Duration
+	// chunkSize is the runes of content per stream chunk; zero sends the
+	// content in one chunk. chunkDelay is waited between stream events.
+	chunkSize  int
+	chunkDelay time.Duration
+}
+
+func newDryRunTransport(providerType string, cfg config.DryRunConfig) *cannedTransport {
+	return &cannedTransport{anthropic: providerType == "anthropic", response: cfg.Response, latency: cfg.Latency}
+}
+
+// newMockTransport serves a mock provider, which speaks the OpenAI format.
+func newMockTransport(cfg *config.ProviderMockConfig) *cannedTransport {
+	t := &cannedTransport{response: defaultMockResponse}
+	if cfg != nil {
+		if cfg.Response != "" {
+			t.response = cfg.Response
+		}
+		t.latency, t.chunkSize, t.chunkDelay = cfg.Latency, cfg.ChunkSize, cfg.ChunkDelay
+	}
+	return t
+}
+
+func (t *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := sleepCtx(req.Context(), t.latency); err != nil {
+		return nil, err
+	}
+
+	var in struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	_ = json.Unmarshal(body, &in)
+	prompt, completion := estimateCannedTokens(len(body)), estimateCannedTokens(len(t.response))
+
+	var out io.ReadCloser
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/count_tokens"):
+		out = stringBody(fmt.Sprintf(`{"input_tokens":%d}`, prompt))
+	case t.anthropic && in.Stream:
+		out = pacedBody(req.Context(), t.anthropicStream(in.Model, prompt, completion), t.chunkDelay)
+	case t.anthropic:
+		out = stringBody(mustJSON(map[string]any{
+			"id": "msg_canned", "type": "message", "role": "assistant", "model": in.Model,
+			"content":     []map[string]string{{"type": "text", "text": t.response}},
+			"stop_reason": "end_turn",
+			"usage":       map[string]int{"input_tokens": prompt, "output_tokens": completion},
+		}))
+	case in.Stream:
+		out = pacedBody(req.Context(), t.openAIStream(in.Model, prompt, completion), t.chunkDelay)
+	default:
+		out = stringBody(mustJSON(map[string]any{
+			"id": "chatcmpl-canned", "object": "chat.completion", "created": time.Now().Unix(), "model": in.Model,
+			"choices": []map[string]any{{
+				"index": 0, "finish_reason": "stop",
+				"message": map[string]string{"role": "assistant", "content": t.response},
+			}},
+			"usage": openAIUsage(prompt, completion),
+		}))
+	}
+
+	contentType := "application/json"
+	if in.Stream {
+		contentType = "text/event-stream"
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Status:        "200 OK",
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          out,
+		ContentLength: -1,
+		Request:       req,
+	}, nil
+}
+
+// chunks splits the response into chunkSize-rune pieces.
+func (t *cannedTransport) chunks() []string {
+	runes := []rune(t.response)
+	if t.chunkSize <= 0 || len(runes) <= t.chunkSize {
+		return []string{t.response}
+	}
+	var out []string
+	for len(runes) > 0 {
+		n := min(t.chunkSize, len(runes))
+		out = append(out, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return out
+}
+
+func (t *cannedTransport) openAIStream(model string, prompt, completion int) []string {
+	created := time.Now().Unix()
+	chunk := func(delta map[string]string, finish any) string {
+		return sseData(map[string]any{
+			"id": "chatcmpl-canned", "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+	}
+	var events []string
+	for i, c := range t.chunks() {
+		delta := map[string]string{"content": c}
+		if i == 0 {
+			delta["role"] = "assistant"
+		}
+		events = append(events, chunk(delta, nil))
+	}
+	return append(events,
+		chunk(map[string]string{}, "stop"),
+		sseData(map[string]any{
+			"id": "chatcmpl-canned", "object": "chat.completion.chunk", "created": created, "model": model,
+			"choices": []any{}, "usage": openAIUsage(prompt, completion),
+		}),
+		"data: [DONE]\n\n",
+	)
+}
+
+func (t *cannedTransport) anthropicStream(model string, prompt, completion int) []string {
+	event := func(v map[string]any) string {
+		return "event: " + v["type"].(string) + "\n" + sseData(v)
+	}
+	events := []string{
+		event(map[string]any{"type": "message_start", "message": map[string]any{
+			"id": "msg_canned", "type": "message", "role": "assistant", "model": model, "content": []any{},
+			"usage": map[string]int{"input_tokens": prompt, "output_tokens": 0},
+		}}),
+		event(map[string]any{"type": "content_block_start", "index": 0, "content_block": map[string]string{"type": "text", "text": ""}}),
+	}
+	for _, c := range t.chunks() {
+		events = append(events, event(map[string]any{"type": "content_block_delta", "index": 0, "delta": map[string]string{"type": "text_delta", "text": c}}))
+	}
+	return append(events,
+		event(map[string]any{"type": "content_block_stop", "index": 0}),
+		event(map[string]any{"type": "message_delta", "delta": map[string]string{"stop_reason": "end_turn"},
+			"usage": map[string]int{"output_tokens": completion}}),
+		event(map[string]any{"type": "message_stop"}),
+	)
+}
+
+// pacedBody returns events as a response body, waiting delay between them.
+// The writer stops when ctx ends or the reader closes the body.
+func pacedBody(ctx context.Context, events []string, delay time.Duration) io.ReadCloser {
+	if delay <= 0 {
+		return stringBody(strings.Join(events, ""))
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		for i, e := range events {
+			if i > 0 {
+				if err := sleepCtx(ctx, delay); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+			}
+			if _, err := io.WriteString(pw, e); err != nil {
+				return
+			}
+		}
+		_ = pw.Close()
+	}()
+	return pr
+}
+
+func stringBody(s string) io.ReadCloser {
+	return io.NopCloser(strings.NewReader(s))
+}
+
+// estimateCannedTokens approximates tokens at four bytes each. The request
+// body includes its JSON framing, so prompts count somewhat high.
+func estimateCannedTokens(n int) int {
+	return max(1, n/4)
+}
+
+func openAIUsage(prompt, completion int) map[string]int {
+	return map[string]int{"prompt_tokens": prompt, "completion_tokens": completion, "total_tokens": prompt + completion}
+}
+
+func sseData(v any) string {
+	return "data: " + mustJSON(v) + "\n\n"
+}
+
+// mustJSON marshals maps of strings and numbers, which cannot fail.
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,168 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func dryRunRegistry(t *testing.T, dryRun config.DryRunConfig) *Registry {
+	t.Helper()
+	// Unresolvable hosts: any real connection attempt would fail.
+	return BuildFromConfig(&config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"openai":    {Type: "openai", BaseURL: "https://openai.invalid/v1"},
+		"anthropic": {Type: "anthropic", BaseURL: "https://anthropic.invalid/v1"},
+	}}, BuildOptions{DryRun: &dryRun})
+}
+
+func TestDryRun_CannedResponses(t *testing.T) {
+	registry := dryRunRegistry(t, config.DryRunConfig{Enabled: true, Response: "canned reply"})
+	req := &types.AegisRequest{Model: "m", Messages: []types.Message{{Role: "user", Content: "hello there"}}}
+
+	for _, name := range []string{"openai", "anthropic"} {
+		adapter, _ := registry.Get(name)
+		httpReq, err := adapter.TransformRequest(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: TransformRequest: %v", name, err)
+		}
+		resp, err := adapter.SendRequest(httpReq)
+		if err != nil {
+			t.Fatalf("%s: a dry run must not reach the provider: %v", name, err)
+		}
+		out, err := adapter.TransformResponse(context.Background(), resp)
+		if err != nil {
+			t.Fatalf("%s: TransformResponse: %v", name, err)
+		}
+		if out.Model != "m" || len(out.Choices) != 1 || out.Choices[0].Message.Content != "canned reply" {
+			t.Errorf("%s: unexpected response %+v", name, out)
+		}
+		if out.Usage.PromptTokens <= 0 || out.Usage.CompletionTokens != len("canned reply")/4 {
+			t.Errorf("%s: expected estimated usage, got %+v", name, out.Usage)
+		}
+	}
+}
+
+func TestDryRun_AnthropicStream(t *testing.T) {
+	registry := dryRunRegistry(t, config.DryRunConfig{Enabled: true, Response: "streamed"})
+	adapter, _ := registry.Get("anthropic")
+	httpReq, _ := adapter.TransformRequest(context.Background(), &types.AegisRequest{
+		Model: "m", Stream: true, Messages: []types.Message{{Role: "user", Content: "hi"}},
+	})
+	resp, err := adapter.SendRequest(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	stream := adapter.(adapters.StreamTransformerFactory).NewStreamTransformer()
+	var content strings.Builder
+	var done bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data: "))
+		if !ok {
+			continue
+		}
+		chunk, err := stream.TransformStreamChunk(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(chunk) == "[DONE]" {
+			done = true
+			continue
+		}
+		if bytes.Contains(chunk, []byte(`"content":"streamed"`)) {
+			content.WriteString("streamed")
+		}
+	}
+	if !done || content.String() != "streamed" {
+		t.Errorf("expected the canned content then [DONE], got %q (done %v)", content.String(), done)
+	}
+}
+
+func TestDryRun_LatencyHonorsCancellation(t *testing.T) {
+	registry := dryRunRegistry(t, config.DryRunConfig{Enabled: true, Latency: time.Minute})
+	adapter, _ := registry.Get("openai")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	httpReq, _ := adapter.TransformRequest(ctx, &types.AegisRequest{Model: "m", Messages: []types.Message{{Role: "user", Content: "hi"}}})
+	if _, err := adapter.SendRequest(httpReq); err == nil {
+		t.Error("a cancelled request should not wait out the simulated latency")
+	}
+}
+
+func TestMockProvider_ChunkedStream(t *testing.T) {
+	registry := BuildFromConfig(&config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"mock": {Type: config.ProviderTypeMock, Mock: &config.ProviderMockConfig{
+			Response: "hello world", ChunkSize: 4, ChunkDelay: time.Millisecond,
+		}},
+	}}, BuildOptions{})
+	adapter, ok := registry.Get("mock")
+	if !ok {
+		t.Fatal("mock provider needs no base_url")
+	}
+	httpReq, _ := adapter.TransformRequest(context.Background(), &types.AegisRequest{
+		Model: "m", Stream: true, Messages: []types.Message{{Role: "user", Content: "hi"}},
+	})
+	resp, err := adapter.SendRequest(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var pieces []string
+	var done bool
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := bytes.CutPrefix(scanner.Bytes(), []byte("data: "))
+		switch {
+		case !ok:
+		case string(data) == "[DONE]":
+			done = true
+		default:
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+			}
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				t.Fatal(err)
+			}
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+				pieces = append(pieces, chunk.Choices[0].Delta.Content)
+			}
+		}
+	}
+	if want := []string{"hell", "o wo", "rld"}; !done || strings.Join(pieces, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks = %q (done %v), want %q then [DONE]", pieces, done, want)
+	}
+}
+
+func TestMockProvider_DefaultResponse(t *testing.T) {
+	registry := BuildFromConfig(&config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"mock": {Type: config.ProviderTypeMock},
+	}}, BuildOptions{})
+	adapter, _ := registry.Get("mock")
+	httpReq, _ := adapter.TransformRequest(context.Background(), &types.AegisRequest{Model: "m", Messages: []types.Message{{Role: "user", Content: "hi"}}})
+	resp, err := adapter.SendRequest(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := adapter.TransformResponse(context.Background(), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Choices[0].Message.Content != defaultMockResponse {
+		t.Errorf("content = %q, want the default mock response", out.Choices[0].Message.Content)
+	}
+}
@@ -0,0 +1,77 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// Features a request may need from its route, as named in CapabilityError.
+const (
+	FeatureStreaming       = "streaming"
+	FeatureTools           = "tools"
+	FeatureJSONMode        = "json_mode"
+	FeatureMaxOutputTokens = "max_output_tokens"
+)
+
+// Requirements are the features a request needs from the route serving it.
+// The zero value needs nothing.
+type Requirements struct {
+	Streaming bool
+	Tools     bool
+	JSONMode  bool
+	// OutputTokens is the completion limit the request asks for; zero if it
+	// sets none.
+	OutputTokens int
+}
+
+// RequirementsOf returns what req needs from its route.
+func RequirementsOf(req *types.AegisRequest) Requirements {
+	needs := Requirements{
+		Streaming: req.Stream,
+		Tools:     len(req.Tools) > 0,
+		JSONMode:  req.JSONMode(),
+	}
+	if limit := req.CompletionTokenLimit(); limit != nil {
+		needs.OutputTokens = *limit
+	}
+	return needs
+}
+
+// CapabilityError is returned by ResolveRoute when routes may carry the
+// request's classification but none of them supports every feature it needs.
+type CapabilityError struct {
+	Model string
+	// Missing names the features the model's first eligible route lacks.
+	Missing []string
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("no route for model %s supports %s", e.Model, strings.Join(e.Missing, ", "))
+}
+
+// missingFeatures returns the features needs asks for that route's provider
+// model does not declare, or nil if it supports them all.
+func missingFeatures(modelsCfg *config.ModelsConfig, route config.ProviderRoute, needs Requirements) []string {
+	caps, ok := modelsCfg.Capabilities[route.Provider][route.Model]
+	if !ok {
+		return nil
+	}
+	var missing []string
+	lacks := func(feature *bool) bool { return feature != nil && !*feature }
+	if needs.Streaming && lacks(caps.Streaming) {
+		missing = append(missing, FeatureStreaming)
+	}
+	if needs.Tools && lacks(caps.Tools) {
+		missing = append(missing, FeatureTools)
+	}
+	if needs.JSONMode && lacks(caps.JSONMode) {
+		missing = append(missing, FeatureJSONMode)
+	}
+	if caps.MaxOutputTokens > 0 && needs.OutputTokens > caps.MaxOutputTokens {
+		missing = append(missing, FeatureMaxOutputTokens)
+	}
+	return missing
+}
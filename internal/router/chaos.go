@@ -0,0 +1,102 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// chaosTransport injects the faults configured for one provider into its
+// calls: random added latency, synthetic error responses, and malformed
+// stream chunks. The faults go through the adapter and the gateway's usual
+// error handling, so they exercise retries, circuit breakers, and failover
+// the way a misbehaving provider would.
+type chaosTransport struct {
+	provider string
+	base     http.RoundTripper
+	faults   config.ChaosFaultConfig
+	// random returns a float in [0, 1); replaced in tests.
+	random func() float64
+}
+
+func newChaosTransport(provider string, base http.RoundTripper, faults config.ChaosFaultConfig) *chaosTransport {
+	return &chaosTransport{provider: provider, base: base, faults: faults, random: rand.Float64}
+}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.faults.Latency > 0 {
+		delay := time.Duration(t.random() * float64(t.faults.Latency))
+		if err := sleepCtx(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+	if t.faults.ErrorRate > 0 && t.random() < t.faults.ErrorRate {
+		status := t.faults.ErrorStatus
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		slog.Debug("chaos: injected provider error", "provider", t.provider, "status", status)
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		body := `{"error":{"message":"chaos: injected provider error","type":"server_error"}}`
+		return &http.Response{
+			StatusCode:    status,
+			Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{"Content-Type": {"application/json"}},
+			Body:          stringBody(body),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || t.faults.MalformedChunkRate <= 0 || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return resp, err
+	}
+	pr, pw := io.Pipe()
+	go t.corruptStream(resp.Body, pw)
+	resp.Body = pr
+	return resp, nil
+}
+
+// corruptStream copies an SSE body, cutting short the JSON of a share of
+// its data lines. Closing the reader stops the copy and closes src.
+func (t *chaosTransport) corruptStream(src io.ReadCloser, dst *io.PipeWriter) {
+	defer func() { _ = src.Close() }()
+	r := bufio.NewReader(src)
+	for {
+		line, err := r.ReadBytes('\n')
+		if data, ok := bytes.CutPrefix(line, sseDataPrefix); ok && !bytes.HasPrefix(data, []byte("[DONE]")) &&
+			len(data) > 2 && t.random() < t.faults.MalformedChunkRate {
+			slog.Debug("chaos: injected malformed chunk", "provider", t.provider)
+			line = append(append([]byte(nil), sseDataPrefix...), data[:len(data)/2]...)
+			line = append(line, '\n')
+		}
+		if len(line) > 0 {
+			if _, werr := dst.Write(line); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			_ = dst.CloseWithError(err)
+			return
+		}
+	}
+}
+
+var sseDataPrefix = []byte("data: ")
@@ -0,0 +1,103 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func chaosAdapter(faults config.ChaosFaultConfig, random float64) adapters.ProviderAdapter {
+	t := newChaosTransport("mock", newMockTransport(&config.ProviderMockConfig{Response: "abcdefgh", ChunkSize: 2}), faults)
+	t.random = func() float64 { return random }
+	return adapters.NewOpenAIAdapter(config.ProviderConfig{Type: config.ProviderTypeMock}, &http.Client{Transport: t})
+}
+
+func sendChaos(t *testing.T, a adapters.ProviderAdapter, ctx context.Context, stream bool) (*http.Response, error) {
+	t.Helper()
+	httpReq, err := a.TransformRequest(ctx, &types.AegisRequest{Model: "m", Stream: stream, Messages: []types.Message{{Role: "user", Content: "hi"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a.SendRequest(httpReq)
+}
+
+func TestChaos_InjectsErrors(t *testing.T) {
+	faults := config.ChaosFaultConfig{ErrorRate: 0.5, ErrorStatus: http.StatusTooManyRequests}
+
+	resp, err := sendChaos(t, chaosAdapter(faults, 0.2), context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want the injected 429", resp.StatusCode)
+	}
+	if _, err := chaosAdapter(faults, 0.2).TransformResponse(context.Background(), resp); err == nil {
+		t.Error("an injected error should surface as a provider error")
+	}
+
+	resp, err = sendChaos(t, chaosAdapter(faults, 0.9), context.Background(), false)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("a draw above error_rate should reach the provider, got %v, %v", resp, err)
+	}
+}
+
+func TestChaos_MalformedChunks(t *testing.T) {
+	resp, err := sendChaos(t, chaosAdapter(config.ChaosFaultConfig{MalformedChunkRate: 1}, 0), context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	var malformed int
+	for _, line := range strings.Split(string(body), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		if !json.Valid([]byte(data)) {
+			malformed++
+		}
+	}
+	if malformed == 0 || !strings.Contains(string(body), "data: [DONE]") {
+		t.Errorf("expected malformed chunks and an intact [DONE], got:\n%s", body)
+	}
+}
+
+func TestChaos_LatencyHonorsCancellation(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := sendChaos(t, chaosAdapter(config.ChaosFaultConfig{Latency: time.Minute}, 0.99), ctx, false); err == nil {
+		t.Error("a request cancelled during injected latency should fail")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("injected latency should end with the request context")
+	}
+}
+
+func TestBuildFromConfig_ChaosOnlyForNamedProviders(t *testing.T) {
+	providers := &config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"flaky":  {Type: config.ProviderTypeMock},
+		"steady": {Type: config.ProviderTypeMock},
+	}}
+	registry := BuildFromConfig(providers, BuildOptions{Chaos: map[string]config.ChaosFaultConfig{"flaky": {ErrorRate: 1}}})
+	for name, want := range map[string]int{"flaky": http.StatusServiceUnavailable, "steady": http.StatusOK} {
+		a, _ := registry.Get(name)
+		resp, err := sendChaos(t, a, context.Background(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("%s: status = %d, want %d", name, resp.StatusCode, want)
+		}
+	}
+}
@@ -40,6 +40,15 @@ type CircuitBreaker struct {
 	// Config
 	failureThreshold      int
 	recoveryProbeInterval time.Duration
+
+	// Recent outcomes for error-rate reporting
+	recent *errorWindow
+
+	// onTransition, if set, is called on every state change with mu held.
+	// It must not call back into the breaker.
+	onTransition func(from, to CircuitState)
+	// remote is set, with mu held, while applying another replica's state.
+	remote bool
 }
 
 // NewCircuitBreaker creates a circuit breaker with the given thresholds.
@@ -48,6 +57,7 @@ func NewCircuitBreaker(failureThreshold int, recoveryProbeInterval time.Duration
 		state:                 StateClosed,
 		failureThreshold:      failureThreshold,
 		recoveryProbeInterval: recoveryProbeInterval,
+		recent:                newErrorWindow(defaultErrorRateWindow),
 	}
 }
 
@@ -58,11 +68,21 @@ func (cb *CircuitBreaker) State() CircuitState {
 	return cb.currentState()
 }
 
+// setState moves the breaker to the given state, notifying onTransition on change.
+// Must be called with mu held.
+func (cb *CircuitBreaker) setState(to CircuitState) {
+	from := cb.state
+	cb.state = to
+	if from != to && cb.onTransition != nil {
+		cb.onTransition(from, to)
+	}
+}
+
 // currentState returns state, transitioning OPEN→HALF_OPEN if probe interval elapsed.
 // Must be called with mu held.
 func (cb *CircuitBreaker) currentState() CircuitState {
 	if cb.state == StateOpen && time.Since(cb.openedAt) >= cb.recoveryProbeInterval {
-		cb.state = StateHalfOpen
+		cb.setState(StateHalfOpen)
 	}
 	return cb.state
 }
@@ -84,15 +104,28 @@ func (cb *CircuitBreaker) Allow() bool {
 	return false
 }
 
+// ProbeAt returns when an open circuit will let a probe request through,
+// or the zero time if the circuit is not open.
+func (cb *CircuitBreaker) ProbeAt() time.Time {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.currentState() != StateOpen {
+		return time.Time{}
+	}
+	return cb.openedAt.Add(cb.recoveryProbeInterval)
+}
+
 // RecordSuccess records a successful request.
 func (cb *CircuitBreaker) RecordSuccess() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
+	cb.recent.record(time.Now(), false)
+
 	switch cb.state {
 	case StateHalfOpen:
 		// Probe succeeded — close the circuit
-		cb.state = StateClosed
+		cb.setState(StateClosed)
 		cb.failures = 0
 		cb.successes = 0
 	case StateClosed:
@@ -107,25 +140,57 @@ func (cb *CircuitBreaker) RecordFailure() {
 
 	cb.failures++
 	cb.lastFailure = time.Now()
+	cb.recent.record(cb.lastFailure, true)
 
 	switch cb.state {
 	case StateClosed:
 		if cb.failures >= cb.failureThreshold {
-			cb.state = StateOpen
+			cb.setState(StateOpen)
 			cb.openedAt = time.Now()
 		}
 	case StateHalfOpen:
 		// Probe failed — reopen
-		cb.state = StateOpen
+		cb.setState(StateOpen)
 		cb.openedAt = time.Now()
 	}
 }
 
+// CircuitStats is a point-in-time snapshot of a circuit breaker.
+type CircuitStats struct {
+	State          CircuitState
+	Failures       int
+	LastFailure    time.Time
+	RecentRequests int
+	RecentFailures int
+	// ErrorRate is RecentFailures/RecentRequests over the error-rate window,
+	// or zero when there were no recent requests.
+	ErrorRate float64
+}
+
+// Stats returns the current state and recent error rate.
+func (cb *CircuitBreaker) Stats() CircuitStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	total, failures := cb.recent.counts(time.Now())
+	stats := CircuitStats{
+		State:          cb.currentState(),
+		Failures:       cb.failures,
+		LastFailure:    cb.lastFailure,
+		RecentRequests: total,
+		RecentFailures: failures,
+	}
+	if total > 0 {
+		stats.ErrorRate = float64(failures) / float64(total)
+	}
+	return stats
+}
+
 // Reset resets the circuit breaker to closed state.
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
-	cb.state = StateClosed
+	cb.setState(StateClosed)
 	cb.failures = 0
 	cb.successes = 0
 }
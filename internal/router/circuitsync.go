@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// circuitChannel carries circuit state changes between gateway replicas.
+const circuitChannel = "aegis:circuit"
+
+// circuitEvent is one replica's breaker opening or closing.
+type circuitEvent struct {
+	Provider string    `json:"provider"`
+	State    string    `json:"state"` // "open" or "closed"
+	At       time.Time `json:"at"`
+	Origin   string    `json:"origin"`
+}
+
+// ShareCircuits publishes this replica's breaker trips and recoveries over
+// Redis pub/sub, so that once ListenForCircuitEvents runs on every replica a
+// provider failing on one opens its breaker on all of them, with the same
+// probe time, and a successful probe closes it everywhere. Failure counts
+// stay local. Call before serving traffic; a nil client leaves breakers
+// independent.
+func (ht *HealthTracker) ShareCircuits(rdb *redis.Client) {
+	ht.shared = rdb
+}
+
+// newInstanceID returns a random ID for a replica's events.
+func newInstanceID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// ListenForCircuitEvents applies other replicas' circuit changes until ctx is
+// cancelled. It returns at once when circuits are not shared.
+func (ht *HealthTracker) ListenForCircuitEvents(ctx context.Context) {
+	if ht.shared == nil {
+		return
+	}
+	sub := ht.shared.Subscribe(ctx, circuitChannel)
+	defer func() { _ = sub.Close() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			var ev circuitEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &ev); err != nil {
+				slog.Warn("invalid circuit event", "error", err)
+				continue
+			}
+			if ev.Origin == ht.instanceID || ev.Provider == "" {
+				continue
+			}
+			ht.GetBreaker(ev.Provider).applyRemote(ev.State, ev.At)
+		}
+	}
+}
+
+// publishCircuit tells the other replicas that provider's breaker opened or
+// closed here. Failures are logged; replicas then trip on their own failures.
+func (ht *HealthTracker) publishCircuit(provider string, to CircuitState, at time.Time) {
+	payload, err := json.Marshal(circuitEvent{Provider: provider, State: to.String(), At: at, Origin: ht.instanceID})
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ht.shared.Publish(ctx, circuitChannel, payload).Err(); err != nil {
+		slog.Warn("failed to publish circuit event", "provider", provider, "state", to.String(), "error", err)
+	}
+}
+
+// applyRemote moves the breaker to a state another replica reached: open
+// with that replica's opening time, so every replica probes at once, or
+// closed after that replica's probe succeeded.
+func (cb *CircuitBreaker) applyRemote(state string, at time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.remote = true
+	defer func() { cb.remote = false }()
+
+	switch state {
+	case StateOpen.String():
+		if cb.state == StateOpen && !at.After(cb.openedAt) {
+			return
+		}
+		cb.openedAt = at
+		cb.setState(StateOpen)
+	case StateClosed.String():
+		if cb.state == StateClosed {
+			return
+		}
+		cb.setState(StateClosed)
+		cb.failures = 0
+		cb.successes = 0
+	}
+}
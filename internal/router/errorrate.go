@@ -0,0 +1,59 @@
+package router
+
+import "time"
+
+// defaultErrorRateWindow is used when no error-rate window is configured.
+const defaultErrorRateWindow = 30 * time.Second
+
+// errorRateBuckets is the number of buckets the window is divided into.
+const errorRateBuckets = 10
+
+type errorBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// errorWindow tracks request outcomes over a sliding time window using
+// fixed-size buckets, so memory stays constant regardless of traffic.
+// Not safe for concurrent use; callers hold the circuit breaker lock.
+type errorWindow struct {
+	window     time.Duration
+	bucketSize time.Duration
+	buckets    [errorRateBuckets]errorBucket
+}
+
+func newErrorWindow(window time.Duration) *errorWindow {
+	if window <= 0 {
+		window = defaultErrorRateWindow
+	}
+	return &errorWindow{
+		window:     window,
+		bucketSize: window / errorRateBuckets,
+	}
+}
+
+// record adds an outcome at the given time.
+func (ew *errorWindow) record(now time.Time, failed bool) {
+	start := now.Truncate(ew.bucketSize)
+	b := &ew.buckets[(start.UnixNano()/int64(ew.bucketSize))%errorRateBuckets]
+	if !b.start.Equal(start) {
+		*b = errorBucket{start: start}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+// counts returns the total and failed requests within the window ending at now.
+func (ew *errorWindow) counts(now time.Time) (total, failures int) {
+	cutoff := now.Add(-ew.window)
+	for _, b := range ew.buckets {
+		if b.start.After(cutoff) {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
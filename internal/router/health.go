@@ -1,28 +1,68 @@
 package router
 
 import (
+	"context"
+	"log/slog"
 	"sync"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
+// CircuitMetrics is an optional interface for recording circuit breaker transitions.
+type CircuitMetrics interface {
+	RecordCircuitTransition(provider, from, to string)
+}
+
 // HealthTracker manages circuit breakers for all providers.
 type HealthTracker struct {
 	mu       sync.RWMutex
 	breakers map[string]*CircuitBreaker
+	metrics  CircuitMetrics
+	// quarantined providers are taken out of routing by an operator,
+	// regardless of circuit state, until released.
+	quarantined map[string]Quarantine
+	// quotas holds each provider's last reported upstream rate limit status.
+	quotas        map[string]upstreamQuota
+	quotaHeadroom float64
 
 	failureThreshold      int
 	recoveryProbeInterval time.Duration
+	errorRateWindow       time.Duration
+	// shared, when set, carries circuit changes to and from other replicas.
+	shared *redis.Client
+	// quarantineStore, when set, holds quarantines for every replica.
+	quarantineStore *redis.Client
+	// instanceID tells this replica's own events apart from other replicas'.
+	instanceID string
 }
 
 // NewHealthTracker creates a health tracker with the given circuit breaker config.
 func NewHealthTracker(failureThreshold int, recoveryProbeInterval time.Duration) *HealthTracker {
 	return &HealthTracker{
 		breakers:              make(map[string]*CircuitBreaker),
+		quarantined:           make(map[string]Quarantine),
+		quotas:                make(map[string]upstreamQuota),
 		failureThreshold:      failureThreshold,
 		recoveryProbeInterval: recoveryProbeInterval,
+		instanceID:            newInstanceID(),
 	}
 }
 
+// SetMetrics attaches a metrics recorder for circuit state transitions.
+// Call before serving traffic.
+func (ht *HealthTracker) SetMetrics(m CircuitMetrics) {
+	ht.metrics = m
+}
+
+// SetErrorRateWindow sets the window over which recent error rates are
+// reported. Applies to breakers created after the call.
+func (ht *HealthTracker) SetErrorRateWindow(window time.Duration) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.errorRateWindow = window
+}
+
 // GetBreaker returns (or lazily creates) the circuit breaker for a provider.
 func (ht *HealthTracker) GetBreaker(provider string) *CircuitBreaker {
 	ht.mu.RLock()
@@ -39,15 +79,125 @@ func (ht *HealthTracker) GetBreaker(provider string) *CircuitBreaker {
 		return cb
 	}
 	cb = NewCircuitBreaker(ht.failureThreshold, ht.recoveryProbeInterval)
+	cb.recent = newErrorWindow(ht.errorRateWindow)
+	cb.onTransition = func(from, to CircuitState) {
+		ht.onTransition(provider, from, to, cb.remote)
+		// Only trips and probe results are shared; half-open is each
+		// replica's own timer running out.
+		if ht.shared != nil && !cb.remote && (to == StateOpen || (from == StateHalfOpen && to == StateClosed)) {
+			go ht.publishCircuit(provider, to, time.Now())
+		}
+	}
 	ht.breakers[provider] = cb
 	return cb
 }
 
-// IsAvailable returns true if the provider's circuit breaker allows requests.
+// onTransition logs and records a provider's circuit state change. remote
+// marks a change learned from another replica.
+func (ht *HealthTracker) onTransition(provider string, from, to CircuitState, remote bool) {
+	level := slog.LevelInfo
+	if to == StateOpen {
+		level = slog.LevelWarn
+	}
+	slog.Log(context.Background(), level, "circuit breaker state change",
+		"provider", provider,
+		"from", from.String(),
+		"to", to.String(),
+		"remote", remote,
+	)
+	if ht.metrics != nil {
+		ht.metrics.RecordCircuitTransition(provider, from.String(), to.String())
+	}
+}
+
+// Quarantine records why and when an operator pulled a provider from routing.
+type Quarantine struct {
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// IsAvailable returns true if the provider is not quarantined and its
+// circuit breaker allows requests.
 func (ht *HealthTracker) IsAvailable(provider string) bool {
+	if ht.IsQuarantined(provider) {
+		return false
+	}
 	return ht.GetBreaker(provider).Allow()
 }
 
+// ProbeAt returns when an unavailable provider's circuit will next let a
+// probe through. It is zero when the provider is available, or quarantined
+// and so out of routing until an operator releases it.
+func (ht *HealthTracker) ProbeAt(provider string) time.Time {
+	if ht.IsQuarantined(provider) {
+		return time.Time{}
+	}
+	return ht.GetBreaker(provider).ProbeAt()
+}
+
+// Quarantine takes a provider out of routing until Release is called. When
+// quarantines are shared, it fails without changing anything if Redis
+// cannot record it.
+func (ht *HealthTracker) Quarantine(provider, reason string) error {
+	q := Quarantine{Reason: reason, Since: time.Now().UTC()}
+	if ht.quarantineStore != nil {
+		if err := ht.storeQuarantine(provider, &q); err != nil {
+			return err
+		}
+	}
+	ht.setQuarantine(provider, &q)
+	slog.Warn("provider quarantined", "provider", provider, "reason", reason)
+	return nil
+}
+
+// Release returns a quarantined provider to routing. It reports whether the
+// provider was quarantined.
+func (ht *HealthTracker) Release(provider string) (bool, error) {
+	ok := ht.IsQuarantined(provider)
+	if ht.quarantineStore != nil {
+		stored, err := ht.deleteQuarantine(provider)
+		if err != nil {
+			return false, err
+		}
+		ok = ok || stored
+	}
+	ht.setQuarantine(provider, nil)
+	if ok {
+		slog.Info("provider released from quarantine", "provider", provider)
+	}
+	return ok, nil
+}
+
+// setQuarantine records q for provider locally, or clears it when q is nil.
+func (ht *HealthTracker) setQuarantine(provider string, q *Quarantine) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	if q == nil {
+		delete(ht.quarantined, provider)
+		return
+	}
+	ht.quarantined[provider] = *q
+}
+
+// IsQuarantined reports whether an operator has quarantined the provider.
+func (ht *HealthTracker) IsQuarantined(provider string) bool {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+	_, ok := ht.quarantined[provider]
+	return ok
+}
+
+// Quarantined returns the currently quarantined providers.
+func (ht *HealthTracker) Quarantined() map[string]Quarantine {
+	ht.mu.RLock()
+	defer ht.mu.RUnlock()
+	out := make(map[string]Quarantine, len(ht.quarantined))
+	for name, q := range ht.quarantined {
+		out[name] = q
+	}
+	return out
+}
+
 // RecordSuccess records a successful request for the provider.
 func (ht *HealthTracker) RecordSuccess(provider string) {
 	ht.GetBreaker(provider).RecordSuccess()
@@ -69,13 +219,30 @@ func (ht *HealthTracker) ListProviders() []string {
 	return names
 }
 
-// GetState returns the circuit breaker state for a provider.
+// GetState returns the circuit breaker state for a provider, or
+// "quarantined" while an operator has it out of routing.
 func (ht *HealthTracker) GetState(provider string) string {
 	ht.mu.RLock()
 	cb, ok := ht.breakers[provider]
+	_, quarantined := ht.quarantined[provider]
 	ht.mu.RUnlock()
+	if quarantined {
+		return "quarantined"
+	}
 	if !ok {
 		return "unknown"
 	}
 	return cb.State().String()
 }
+
+// GetStats returns the circuit breaker snapshot for a provider.
+// The second return value is false if no requests have been tracked for it.
+func (ht *HealthTracker) GetStats(provider string) (CircuitStats, bool) {
+	ht.mu.RLock()
+	cb, ok := ht.breakers[provider]
+	ht.mu.RUnlock()
+	if !ok {
+		return CircuitStats{}, false
+	}
+	return cb.Stats(), true
+}
@@ -4,6 +4,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+
 	"github.com/af-corp/aegis-gateway/internal/config"
 )
 
@@ -124,3 +126,152 @@ func TestResolveRoute_AllUnhealthy_ReturnsError(t *testing.T) {
 		t.Fatal("expected error when all providers are unhealthy")
 	}
 }
+
+// recordingCircuitMetrics captures circuit transitions for assertions.
+type recordingCircuitMetrics struct {
+	transitions []string
+}
+
+func (r *recordingCircuitMetrics) RecordCircuitTransition(provider, from, to string) {
+	r.transitions = append(r.transitions, provider+":"+from+"->"+to)
+}
+
+func TestHealthTracker_RecordsTransitions(t *testing.T) {
+	ht := NewHealthTracker(1, 10*time.Millisecond)
+	rec := &recordingCircuitMetrics{}
+	ht.SetMetrics(rec)
+
+	ht.RecordFailure("openai")
+	time.Sleep(15 * time.Millisecond)
+	ht.IsAvailable("openai")
+	ht.RecordSuccess("openai")
+
+	want := []string{
+		"openai:closed->open",
+		"openai:open->half_open",
+		"openai:half_open->closed",
+	}
+	if len(rec.transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, rec.transitions)
+	}
+	for i := range want {
+		if rec.transitions[i] != want[i] {
+			t.Errorf("transition %d = %q, want %q", i, rec.transitions[i], want[i])
+		}
+	}
+}
+
+func TestHealthTracker_GetStatsErrorRate(t *testing.T) {
+	ht := NewHealthTracker(10, 5*time.Second)
+	ht.SetErrorRateWindow(time.Minute)
+
+	if _, ok := ht.GetStats("openai"); ok {
+		t.Error("expected no stats for untracked provider")
+	}
+
+	ht.RecordSuccess("openai")
+	ht.RecordSuccess("openai")
+	ht.RecordSuccess("openai")
+	ht.RecordFailure("openai")
+
+	stats, ok := ht.GetStats("openai")
+	if !ok {
+		t.Fatal("expected stats for openai")
+	}
+	if stats.RecentRequests != 4 || stats.RecentFailures != 1 {
+		t.Errorf("expected 4 requests / 1 failure, got %d / %d", stats.RecentRequests, stats.RecentFailures)
+	}
+	if stats.ErrorRate != 0.25 {
+		t.Errorf("expected error rate 0.25, got %v", stats.ErrorRate)
+	}
+	if stats.State != StateClosed {
+		t.Errorf("expected closed, got %s", stats.State)
+	}
+}
+
+func TestHealthTracker_Quarantine(t *testing.T) {
+	ht := NewHealthTracker(3, time.Minute)
+	ht.RecordSuccess("openai")
+
+	ht.Quarantine("openai", "elevated 5xx from upstream")
+	if ht.IsAvailable("openai") {
+		t.Error("expected quarantined provider to be unavailable")
+	}
+	if got := ht.GetState("openai"); got != "quarantined" {
+		t.Errorf("GetState = %q, want quarantined", got)
+	}
+	if q := ht.Quarantined()["openai"]; q.Reason != "elevated 5xx from upstream" || q.Since.IsZero() {
+		t.Errorf("unexpected quarantine record %+v", q)
+	}
+
+	if ok, err := ht.Release("openai"); !ok || err != nil {
+		t.Errorf("expected Release to report the provider was quarantined, got %v, %v", ok, err)
+	}
+	if ok, _ := ht.Release("openai"); ok {
+		t.Error("expected second Release to report nothing to release")
+	}
+	if !ht.IsAvailable("openai") || ht.GetState("openai") != "closed" {
+		t.Errorf("expected provider back in routing, state %q", ht.GetState("openai"))
+	}
+}
+
+func TestCircuitBreaker_ApplyRemote(t *testing.T) {
+	ht := NewHealthTracker(3, time.Minute)
+	cb := ht.GetBreaker("openai")
+
+	openedAt := time.Now().Add(-10 * time.Second)
+	cb.applyRemote("open", openedAt)
+	if cb.State() != StateOpen {
+		t.Fatalf("expected remote trip to open the breaker, got %s", cb.State())
+	}
+	if want := openedAt.Add(time.Minute); !cb.ProbeAt().Equal(want) {
+		t.Errorf("expected probe at the origin's schedule %v, got %v", want, cb.ProbeAt())
+	}
+
+	// An older trip must not push the probe time back.
+	cb.applyRemote("open", openedAt.Add(-time.Hour))
+	if want := openedAt.Add(time.Minute); !cb.ProbeAt().Equal(want) {
+		t.Errorf("expected probe time unchanged by an older trip, got %v", cb.ProbeAt())
+	}
+
+	cb.applyRemote("closed", time.Now())
+	if cb.State() != StateClosed || !ht.IsAvailable("openai") {
+		t.Errorf("expected remote recovery to close the breaker, got %s", cb.State())
+	}
+	if cb.remote {
+		t.Error("expected remote flag cleared after applying")
+	}
+}
+
+func TestHealthTracker_SharedQuarantineNeedsRedis(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	defer rdb.Close()
+	ht := NewHealthTracker(3, time.Minute)
+	ht.ShareQuarantine(rdb)
+
+	if err := ht.Quarantine("openai", "maintenance"); err == nil {
+		t.Fatal("expected an error when Redis cannot record the quarantine")
+	}
+	if ht.IsQuarantined("openai") {
+		t.Error("a quarantine Redis did not record must not apply locally either")
+	}
+}
+
+func TestHealthTracker_AppliesRemoteQuarantines(t *testing.T) {
+	ht := NewHealthTracker(3, time.Minute)
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ht.applyQuarantineEvent(`{"provider":"openai","quarantine":{"reason":"upstream incident","since":"2026-01-02T03:04:05Z"},"origin":"other"}`)
+	if q, ok := ht.Quarantined()["openai"]; !ok || q.Reason != "upstream incident" || !q.Since.Equal(since) {
+		t.Fatalf("remote quarantine not applied: %+v", ht.Quarantined())
+	}
+	// A replica ignores its own events.
+	ht.applyQuarantineEvent(`{"provider":"openai","origin":"` + ht.instanceID + `"}`)
+	if !ht.IsQuarantined("openai") {
+		t.Error("own release event should be ignored")
+	}
+	ht.applyQuarantineEvent(`{"provider":"openai","origin":"other"}`)
+	if ht.IsQuarantined("openai") {
+		t.Error("remote release not applied")
+	}
+}
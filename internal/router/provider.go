@@ -1,25 +1,72 @@
 package router
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/egress"
+	"github.com/af-corp/aegis-gateway/internal/fixture"
 	"github.com/af-corp/aegis-gateway/internal/router/adapters"
 	"github.com/af-corp/aegis-gateway/internal/types"
 )
 
+// ErrClassificationNotPermitted is returned (wrapped) by ResolveRoute when no
+// route for the model accepts the request's data classification, regardless
+// of provider health.
+var ErrClassificationNotPermitted = errors.New("classification exceeds every route's ceiling")
+
+// ErrUnknownModel is returned (wrapped) by ResolveRoute for a model with no
+// mapping in models.yaml.
+var ErrUnknownModel = errors.New("unknown model")
+
+// NoProviderError is returned by ResolveRoute when every route the request
+// may take leads to an unhealthy, quarantined, or unregistered provider.
+type NoProviderError struct {
+	Model          string
+	Classification string
+	// ProbeAt is the soonest an open circuit on one of those routes lets a
+	// probe through; zero if none will on its own.
+	ProbeAt time.Time
+}
+
+func (e *NoProviderError) Error() string {
+	return fmt.Sprintf("no eligible provider for model %s at classification %s", e.Model, e.Classification)
+}
+
 // Registry manages provider adapters.
 type Registry struct {
-	mu       sync.RWMutex
-	adapters map[string]adapters.ProviderAdapter
+	mu        sync.RWMutex
+	adapters  map[string]adapters.ProviderAdapter
+	throttles map[string]*Throttle
+	// specs are what BuildFromConfig built each adapter from, so a reload
+	// can tell which providers changed.
+	specs map[string]providerSpec
+	// strategy is the routing.strategy routes are ordered by.
+	strategy string
+}
+
+// providerSpec is everything an adapter built by BuildFromConfig depends on.
+type providerSpec struct {
+	cfg      config.ProviderConfig
+	allow    *egress.Allowlist
+	dryRun   *config.DryRunConfig
+	fixtures *config.FixturesConfig
+	chaos    *config.ChaosFaultConfig
 }
 
 func NewRegistry() *Registry {
 	return &Registry{
-		adapters: make(map[string]adapters.ProviderAdapter),
+		adapters:  make(map[string]adapters.ProviderAdapter),
+		throttles: make(map[string]*Throttle),
+		specs:     make(map[string]providerSpec),
 	}
 }
 
@@ -36,6 +83,62 @@ func (r *Registry) Get(name string) (adapters.ProviderAdapter, bool) {
 	return a, ok
 }
 
+// SetThrottle attaches an outbound rate limit to a provider; nil removes it.
+func (r *Registry) SetThrottle(name string, t *Throttle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t == nil {
+		delete(r.throttles, name)
+		return
+	}
+	r.throttles[name] = t
+}
+
+// Throttle returns the provider's outbound rate limit, or nil if it has none.
+// A nil *Throttle never waits.
+func (r *Registry) Throttle(name string) *Throttle {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.throttles[name]
+}
+
+// SetStrategy sets how ResolveRoute orders a model's routes, one of the
+// config.RoutingStrategy values. It is kept across ReplaceFrom; set it again
+// when the config reloads.
+func (r *Registry) SetStrategy(strategy string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.strategy = strategy
+}
+
+// routes returns mapping's primary and fallbacks in the order the routing
+// strategy tries them.
+func (r *Registry) routes(modelsCfg *config.ModelsConfig, mapping config.ModelMapping) []config.ProviderRoute {
+	routes := append([]config.ProviderRoute{mapping.Primary}, mapping.Fallback...)
+	if r == nil {
+		return routes
+	}
+	r.mu.RLock()
+	strategy := r.strategy
+	r.mu.RUnlock()
+	if strategy != config.RoutingStrategyCheapest {
+		return routes
+	}
+	price := func(route config.ProviderRoute) (float64, bool) {
+		p, ok := modelsCfg.Pricing[route.Provider][route.Model]
+		return p.Input + p.Output, ok
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		pi, iok := price(routes[i])
+		pj, jok := price(routes[j])
+		if iok != jok {
+			return iok
+		}
+		return pi < pj
+	})
+	return routes
+}
+
 // ListProviders returns a list of all registered provider names.
 func (r *Registry) ListProviders() []string {
 	r.mu.RLock()
@@ -47,18 +150,68 @@ func (r *Registry) ListProviders() []string {
 	return names
 }
 
-// ReplaceFrom replaces this registry's adapters with those from another registry.
-func (r *Registry) ReplaceFrom(other *Registry) {
+// ReplaceFrom replaces this registry's adapters and throttles with those from
+// another registry. A throttle whose limits did not change is kept, so a
+// reload does not hand out a fresh burst, and so is an adapter whose
+// provider config did not change, with its connection pool and cached
+// tokens. It returns the providers whose adapters were replaced or added.
+// Circuit breaker state lives in the HealthTracker and is unaffected.
+func (r *Registry) ReplaceFrom(other *Registry) []string {
 	other.mu.RLock()
 	newAdapters := make(map[string]adapters.ProviderAdapter, len(other.adapters))
 	for k, v := range other.adapters {
 		newAdapters[k] = v
 	}
+	newThrottles := make(map[string]*Throttle, len(other.throttles))
+	for k, v := range other.throttles {
+		newThrottles[k] = v
+	}
+	newSpecs := make(map[string]providerSpec, len(other.specs))
+	for k, v := range other.specs {
+		newSpecs[k] = v
+	}
 	other.mu.RUnlock()
 
+	var rebuilt []string
 	r.mu.Lock()
+	for k, v := range newThrottles {
+		if old := r.throttles[k]; old.sameLimits(&v.limits) {
+			newThrottles[k] = old
+		}
+	}
+	for k := range newAdapters {
+		old, hadSpec := r.specs[k]
+		spec, hasSpec := newSpecs[k]
+		if hadSpec && hasSpec && r.adapters[k] != nil && reflect.DeepEqual(old, spec) {
+			newAdapters[k] = r.adapters[k]
+			continue
+		}
+		rebuilt = append(rebuilt, k)
+		if hadSpec && hasSpec && credentialsOnlyChange(old.cfg, spec.cfg) {
+			slog.Info("provider credentials rotated", "provider", k)
+		}
+	}
 	r.adapters = newAdapters
+	r.throttles = newThrottles
+	r.specs = newSpecs
 	r.mu.Unlock()
+	sort.Strings(rebuilt)
+	return rebuilt
+}
+
+// credentialsOnlyChange reports whether a and b differ only in their API
+// key, headers, or Azure AD client secret.
+func credentialsOnlyChange(a, b config.ProviderConfig) bool {
+	if a.APIKey == b.APIKey && reflect.DeepEqual(a.Headers, b.Headers) && reflect.DeepEqual(a.Auth, b.Auth) {
+		return false
+	}
+	b.APIKey, b.Headers = a.APIKey, a.Headers
+	if a.Auth != nil && b.Auth != nil {
+		auth := *b.Auth
+		auth.ClientSecret = a.Auth.ClientSecret
+		b.Auth = &auth
+	}
+	return reflect.DeepEqual(a, b)
 }
 
 // GetProvider returns a provider adapter by name (for health checks).
@@ -68,31 +221,100 @@ func (r *Registry) GetProvider(name string) adapters.ProviderAdapter {
 	return r.adapters[name]
 }
 
-// BuildFromConfig builds provider adapters from the providers config.
-func BuildFromConfig(provCfg *config.ProvidersConfig) *Registry {
+// BuildOptions are the gateway-wide settings adapters are built with. The
+// zero value builds adapters that call their providers directly.
+type BuildOptions struct {
+	// Allow, if non-nil, leaves out providers whose base_url is off the
+	// egress allowlist and checks every connection against it.
+	Allow *egress.Allowlist
+	// DryRun, if non-nil, answers every call with a canned response; no
+	// provider is called.
+	DryRun *config.DryRunConfig
+	// Fixtures, if non-nil, records provider traffic to or replays it from
+	// fixture files.
+	Fixtures *config.FixturesConfig
+	// Chaos injects its faults into the calls of the providers it names, on
+	// top of any of the above.
+	Chaos map[string]config.ChaosFaultConfig
+	// Metrics, if non-nil, is told about each provider's connection pool.
+	Metrics ConnMetrics
+}
+
+// BuildFromConfig builds provider adapters from the providers config with
+// opts.
+func BuildFromConfig(provCfg *config.ProvidersConfig, opts BuildOptions) *Registry {
 	registry := NewRegistry()
 	for name, cfg := range provCfg.Providers {
-		client := &http.Client{
-			Timeout: cfg.Timeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        cfg.MaxConcurrent,
-				MaxIdleConnsPerHost: cfg.MaxConcurrent,
-				IdleConnTimeout:     90 * time.Second,
-				ForceAttemptHTTP2:   true,
-			},
+		var faults *config.ChaosFaultConfig
+		if f, ok := opts.Chaos[name]; ok {
+			faults = &f
 		}
+		if cfg.Type == config.ProviderTypeMock {
+			// Answered in-process, so there is no connection to restrict.
+			var transport http.RoundTripper = newMockTransport(cfg.Mock)
+			if opts.DryRun != nil {
+				transport = newDryRunTransport(cfg.Type, *opts.DryRun)
+			}
+			if faults != nil {
+				transport = newChaosTransport(name, transport, *faults)
+			}
+			registry.Register(name, adapters.NewOpenAIAdapter(cfg, &http.Client{Timeout: cfg.Timeout, Transport: transport}))
+			registry.SetThrottle(name, NewThrottle(name, cfg.RateLimit))
+			registry.specs[name] = providerSpec{cfg: cfg, allow: opts.Allow, dryRun: opts.DryRun, fixtures: opts.Fixtures, chaos: faults}
+			continue
+		}
+		if u, err := url.Parse(cfg.BaseURL); err != nil || !opts.Allow.MayAllow(u.Hostname()) {
+			slog.Error("provider base_url not on egress allowlist, provider disabled", "provider", name, "base_url", cfg.BaseURL)
+			continue
+		}
+		pool, err := newProviderTransport(cfg)
+		if err != nil {
+			slog.Error("provider transport misconfigured, provider disabled", "provider", name, "error", err)
+			continue
+		}
+		restrictEgress(pool, opts.Allow)
+		var transport http.RoundTripper = pool
+		if opts.Metrics != nil {
+			transport = instrumentTransport(name, pool, opts.Metrics)
+		}
+		switch {
+		case opts.DryRun != nil:
+			transport = newDryRunTransport(cfg.Type, *opts.DryRun)
+		case opts.Fixtures != nil && opts.Fixtures.Mode == config.FixturesReplay:
+			transport = fixture.NewReplayer(opts.Fixtures.Dir, name)
+		case opts.Fixtures != nil && opts.Fixtures.Mode == config.FixturesRecord:
+			transport = fixture.NewRecorder(opts.Fixtures.Dir, name, transport, func(err error) {
+				slog.Warn("failed to write provider fixture", "provider", name, "error", err)
+			})
+		}
+		if faults != nil {
+			transport = newChaosTransport(name, transport, *faults)
+		}
+		client := &http.Client{Timeout: cfg.Timeout, Transport: transport}
 
 		var adapter adapters.ProviderAdapter
 		switch cfg.Type {
-		case "openai":
-			adapter = adapters.NewOpenAIAdapter(cfg, client)
 		case "anthropic":
 			adapter = adapters.NewAnthropicAdapter(cfg, client)
 		default:
-			// Fall back to OpenAI-compatible for unknown types
-			adapter = adapters.NewOpenAIAdapter(cfg, client)
+			// "openai", and OpenAI-compatible for unknown types
+			oa := adapters.NewOpenAIAdapter(cfg, client)
+			if cfg.Auth != nil && opts.DryRun == nil && (opts.Fixtures == nil || opts.Fixtures.Mode != config.FixturesReplay) {
+				// Token requests leave through the provider's egress path.
+				ts, err := adapters.NewAzureADTokenSource(*cfg.Auth, &http.Client{Timeout: 10 * time.Second, Transport: transport})
+				if err != nil {
+					// Without credentials every call would fail; leave the
+					// provider out so routes fall back past it.
+					slog.Error("provider auth misconfigured, provider disabled", "provider", name, "error", err)
+					continue
+				}
+				oa.SetTokenSource(ts)
+			}
+			adapter = oa
 		}
 		registry.Register(name, adapter)
+		registry.SetThrottle(name, NewThrottle(name, cfg.RateLimit))
+		registry.specs[name] = providerSpec{cfg: cfg, allow: opts.Allow, dryRun: opts.DryRun, fixtures: opts.Fixtures, chaos: faults}
 	}
 	return registry
 }
@@ -118,29 +340,136 @@ func routeEligible(route config.ProviderRoute, classification string) bool {
 // It checks classification ceilings to ensure the request's data classification
 // does not exceed what the provider route is allowed to handle.
 // If healthTracker is non-nil, providers with open circuit breakers are skipped.
+// Routes whose provider model lacks a feature the request needs, per the
+// capabilities in models.yaml, are skipped too (see ResolvePreferredRoute).
+// Providers at their outbound rate limit, or whose upstream quota is nearly
+// used up, are passed over for a later route with room; if every route is
+// short of room the first is returned and the request queues on its throttle.
+// Routes are tried in the order the registry's strategy sets.
 func ResolveRoute(modelsCfg *config.ModelsConfig, registry *Registry, healthTracker *HealthTracker, modelName string, classification string) (adapters.ProviderAdapter, string, error) {
+	return ResolvePreferredRoute(modelsCfg, registry, healthTracker, modelName, classification, Requirements{}, "")
+}
+
+// ResolvePreferredRoute is ResolveRoute for a request with needs, which
+// takes the model's route to preferProvider, if it has one, ahead of the
+// primary whenever it is eligible, capable, healthy, and has room. Otherwise
+// routing proceeds as usual.
+func ResolvePreferredRoute(modelsCfg *config.ModelsConfig, registry *Registry, healthTracker *HealthTracker, modelName, classification string, needs Requirements, preferProvider string) (adapters.ProviderAdapter, string, error) {
 	mapping, ok := modelsCfg.Models[modelName]
 	if !ok {
-		return nil, "", fmt.Errorf("unknown model: %s", modelName)
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownModel, modelName)
+	}
+	usable := func(route config.ProviderRoute) bool {
+		return routeEligible(route, classification) && missingFeatures(modelsCfg, route, needs) == nil
 	}
 
-	// Try primary provider (must be registered, classification-eligible, and healthy)
-	if routeEligible(mapping.Primary, classification) && providerHealthy(healthTracker, mapping.Primary.Provider) {
-		if adapter, ok := registry.Get(mapping.Primary.Provider); ok {
-			return adapter, mapping.Primary.Model, nil
+	if preferProvider != "" {
+		for _, route := range append([]config.ProviderRoute{mapping.Primary}, mapping.Fallback...) {
+			if route.Provider != preferProvider {
+				continue
+			}
+			if !usable(route) || !providerHealthy(healthTracker, route.Provider) {
+				break
+			}
+			if registry.Throttle(route.Provider).Saturated() || quotaLow(healthTracker, route.Provider) {
+				break
+			}
+			if adapter, ok := registry.Get(route.Provider); ok {
+				return adapter, route.Model, nil
+			}
+			break
 		}
 	}
 
-	// Try fallbacks in order
-	for _, fb := range mapping.Fallback {
-		if routeEligible(fb, classification) && providerHealthy(healthTracker, fb.Provider) {
-			if adapter, ok := registry.Get(fb.Provider); ok {
-				return adapter, fb.Model, nil
+	// Try the routes in strategy order (must be registered,
+	// classification-eligible, and healthy)
+	var saturated adapters.ProviderAdapter
+	var saturatedModel string
+	for _, route := range registry.routes(modelsCfg, mapping) {
+		if !usable(route) || !providerHealthy(healthTracker, route.Provider) {
+			continue
+		}
+		adapter, ok := registry.Get(route.Provider)
+		if !ok {
+			continue
+		}
+		if registry.Throttle(route.Provider).Saturated() || quotaLow(healthTracker, route.Provider) {
+			if saturated == nil {
+				saturated, saturatedModel = adapter, route.Model
 			}
+			continue
+		}
+		return adapter, route.Model, nil
+	}
+	if saturated != nil {
+		return saturated, saturatedModel, nil
+	}
+
+	if !anyRouteEligible(mapping, classification) {
+		return nil, "", fmt.Errorf("no eligible provider for model %s at classification %s: %w", modelName, classification, ErrClassificationNotPermitted)
+	}
+	if missing := missingEverywhere(modelsCfg, mapping, classification, needs); missing != nil {
+		return nil, "", &CapabilityError{Model: modelName, Missing: missing}
+	}
+	return nil, "", &NoProviderError{
+		Model:          modelName,
+		Classification: classification,
+		ProbeAt:        nextProbe(mapping, usable, registry, healthTracker),
+	}
+}
+
+// missingEverywhere returns the features the first classification-eligible
+// route lacks when every such route lacks one, or nil if some route has them
+// all.
+func missingEverywhere(modelsCfg *config.ModelsConfig, mapping config.ModelMapping, classification string, needs Requirements) []string {
+	var first []string
+	for _, route := range append([]config.ProviderRoute{mapping.Primary}, mapping.Fallback...) {
+		if !routeEligible(route, classification) {
+			continue
+		}
+		missing := missingFeatures(modelsCfg, route, needs)
+		if missing == nil {
+			return nil
+		}
+		if first == nil {
+			first = missing
 		}
 	}
+	return first
+}
 
-	return nil, "", fmt.Errorf("no eligible provider for model %s at classification %s", modelName, classification)
+// nextProbe returns the soonest time an open circuit on one of mapping's
+// usable, registered routes lets a probe through, or zero if none is open.
+func nextProbe(mapping config.ModelMapping, usable func(config.ProviderRoute) bool, registry *Registry, ht *HealthTracker) time.Time {
+	var next time.Time
+	if ht == nil {
+		return next
+	}
+	for _, route := range append([]config.ProviderRoute{mapping.Primary}, mapping.Fallback...) {
+		if !usable(route) {
+			continue
+		}
+		if _, ok := registry.Get(route.Provider); !ok {
+			continue
+		}
+		if at := ht.ProbeAt(route.Provider); !at.IsZero() && (next.IsZero() || at.Before(next)) {
+			next = at
+		}
+	}
+	return next
+}
+
+// anyRouteEligible reports whether any primary or fallback route accepts the classification.
+func anyRouteEligible(mapping config.ModelMapping, classification string) bool {
+	if routeEligible(mapping.Primary, classification) {
+		return true
+	}
+	for _, fb := range mapping.Fallback {
+		if routeEligible(fb, classification) {
+			return true
+		}
+	}
+	return false
 }
 
 // providerHealthy returns true if the provider is healthy or if no health tracker is configured.
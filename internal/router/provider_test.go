@@ -2,8 +2,11 @@ package router
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/af-corp/aegis-gateway/internal/config"
 	"github.com/af-corp/aegis-gateway/internal/types"
@@ -129,6 +132,56 @@ func TestResolveRoute_ClassificationGating_BlocksAll(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error when all providers are below classification ceiling")
 	}
+	if !errors.Is(err, ErrClassificationNotPermitted) {
+		t.Errorf("expected ErrClassificationNotPermitted, got %v", err)
+	}
+}
+
+func TestResolveRoute_UnhealthyIsNotClassificationViolation(t *testing.T) {
+	registry := newTestRegistry("openai")
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"gpt-4o": {
+			Primary: config.ProviderRoute{
+				Provider:              "openai",
+				Model:                 "gpt-4o",
+				ClassificationCeiling: "CONFIDENTIAL",
+			},
+		},
+	})
+	ht := NewHealthTracker(1, time.Minute)
+	ht.RecordFailure("openai")
+
+	_, _, err := ResolveRoute(cfg, registry, ht, "gpt-4o", "INTERNAL")
+	if err == nil {
+		t.Fatal("expected error when the only provider is unhealthy")
+	}
+	if errors.Is(err, ErrClassificationNotPermitted) {
+		t.Errorf("expected health failure not to be reported as classification violation: %v", err)
+	}
+}
+
+func TestResolveRoute_NoProviderProbeAt(t *testing.T) {
+	registry := newTestRegistry("openai")
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"gpt-4o": {Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"}},
+	})
+	ht := NewHealthTracker(1, time.Minute)
+	ht.RecordFailure("openai")
+
+	_, _, err := ResolveRoute(cfg, registry, ht, "gpt-4o", "INTERNAL")
+	var noProvider *NoProviderError
+	if !errors.As(err, &noProvider) {
+		t.Fatalf("expected NoProviderError, got %v", err)
+	}
+	if d := time.Until(noProvider.ProbeAt); d <= 55*time.Second || d > time.Minute {
+		t.Errorf("expected probe in about a minute, got %s", d)
+	}
+
+	ht.Quarantine("openai", "maintenance")
+	_, _, err = ResolveRoute(cfg, registry, ht, "gpt-4o", "INTERNAL")
+	if !errors.As(err, &noProvider) || !noProvider.ProbeAt.IsZero() {
+		t.Errorf("expected no probe time while quarantined, got %v", err)
+	}
 }
 
 func TestResolveRoute_ClassificationGating_AllowsEqualLevel(t *testing.T) {
@@ -208,3 +261,104 @@ func TestResolveRoute_FallbackOrder(t *testing.T) {
 		t.Errorf("expected model-c, got %s", model)
 	}
 }
+
+func TestResolveRoute_CheapestStrategy(t *testing.T) {
+	registry := newTestRegistry("openai", "anthropic", "local")
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"chat": {
+			Primary: config.ProviderRoute{Provider: "openai", Model: "gpt-4o"},
+			Fallback: []config.ProviderRoute{
+				{Provider: "local", Model: "llama"},
+				{Provider: "anthropic", Model: "claude-haiku"},
+			},
+		},
+	})
+	cfg.Pricing = map[string]map[string]config.PriceEntry{
+		"openai":    {"gpt-4o": {Input: 0.0025, Output: 0.01}},
+		"anthropic": {"claude-haiku": {Input: 0.0008, Output: 0.004}},
+	}
+
+	resolve := func() string {
+		adapter, _, err := ResolveRoute(cfg, registry, nil, "chat", "INTERNAL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return adapter.Name()
+	}
+	if got := resolve(); got != "openai" {
+		t.Errorf("priority strategy: got %s, want the primary", got)
+	}
+	registry.SetStrategy(config.RoutingStrategyCheapest)
+	if got := resolve(); got != "anthropic" {
+		t.Errorf("cheapest strategy: got %s, want the lowest priced route", got)
+	}
+	if got := registry.routes(cfg, cfg.Models["chat"]); got[2].Provider != "local" {
+		t.Errorf("unpriced route should come last, got %v", got)
+	}
+}
+
+func TestResolvePreferredRoute(t *testing.T) {
+	registry := newTestRegistry("openai", "anthropic")
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"chat": {
+			Primary:  config.ProviderRoute{Provider: "openai", Model: "gpt-4o"},
+			Fallback: []config.ProviderRoute{{Provider: "anthropic", Model: "claude-sonnet"}},
+		},
+	})
+	ht := NewHealthTracker(1, time.Minute)
+
+	adapter, model, err := ResolvePreferredRoute(cfg, registry, ht, "chat", "INTERNAL", Requirements{}, "anthropic")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if adapter.Name() != "anthropic" || model != "claude-sonnet" {
+		t.Errorf("expected the preferred fallback, got %s/%s", adapter.Name(), model)
+	}
+
+	ht.RecordFailure("anthropic")
+	adapter, _, err = ResolvePreferredRoute(cfg, registry, ht, "chat", "INTERNAL", Requirements{}, "anthropic")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if adapter.Name() != "openai" {
+		t.Errorf("expected the primary once the preferred provider is unhealthy, got %s", adapter.Name())
+	}
+
+	adapter, _, err = ResolvePreferredRoute(cfg, registry, ht, "chat", "INTERNAL", Requirements{}, "unrouted")
+	if err != nil || adapter.Name() != "openai" {
+		t.Errorf("expected normal routing for a provider not on the model's routes, got %v, %v", adapter, err)
+	}
+}
+
+func TestResolvePreferredRoute_Capabilities(t *testing.T) {
+	registry := newTestRegistry("openai", "anthropic")
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"chat": {
+			Primary:  config.ProviderRoute{Provider: "anthropic", Model: "claude-sonnet"},
+			Fallback: []config.ProviderRoute{{Provider: "openai", Model: "gpt-4o"}},
+		},
+	})
+	no, yes := false, true
+	cfg.Capabilities = map[string]map[string]config.ModelCapabilities{
+		"anthropic": {"claude-sonnet": {JSONMode: &no, Tools: &yes, MaxOutputTokens: 64000}},
+		"openai":    {"gpt-4o": {JSONMode: &yes, MaxOutputTokens: 16384}},
+	}
+
+	adapter, _, err := ResolvePreferredRoute(cfg, registry, nil, "chat", "INTERNAL", Requirements{Tools: true}, "")
+	if err != nil || adapter.Name() != "anthropic" {
+		t.Fatalf("expected the capable primary, got %v, %v", adapter, err)
+	}
+	adapter, model, err := ResolvePreferredRoute(cfg, registry, nil, "chat", "INTERNAL", Requirements{JSONMode: true}, "")
+	if err != nil || adapter.Name() != "openai" || model != "gpt-4o" {
+		t.Fatalf("expected failover to the JSON-capable fallback, got %v, %v", adapter, err)
+	}
+
+	_, _, err = ResolvePreferredRoute(cfg, registry, nil, "chat", "INTERNAL", Requirements{JSONMode: true, OutputTokens: 32000}, "")
+	var capErr *CapabilityError
+	if !errors.As(err, &capErr) {
+		t.Fatalf("expected CapabilityError, got %v", err)
+	}
+	if !slices.Equal(capErr.Missing, []string{FeatureJSONMode}) {
+		t.Errorf("expected the primary's missing features, got %v", capErr.Missing)
+	}
+}
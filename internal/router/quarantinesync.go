@@ -0,0 +1,137 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// quarantineKey is the Redis hash of quarantined providers, each field a
+// provider name and its value the JSON Quarantine. Replicas that start
+// later load it, so a quarantine outlives the replica that set it.
+const quarantineKey = "aegis:quarantine"
+
+// quarantineChannel carries quarantine changes between gateway replicas.
+const quarantineChannel = "aegis:quarantine:events"
+
+// quarantineEvent is a provider quarantined, or released when Quarantine is
+// nil, on one replica.
+type quarantineEvent struct {
+	Provider   string      `json:"provider"`
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+	Origin     string      `json:"origin"`
+}
+
+// ShareQuarantine keeps quarantines in Redis, so that once
+// ListenForQuarantines runs on every replica, a provider an operator
+// quarantines through any replica leaves routing on all of them until it is
+// released through any replica. Call before serving traffic; a nil client
+// keeps quarantines per replica.
+func (ht *HealthTracker) ShareQuarantine(rdb *redis.Client) {
+	ht.quarantineStore = rdb
+}
+
+// ListenForQuarantines loads the quarantines already in Redis, then applies
+// other replicas' changes until ctx is cancelled. It returns at once when
+// quarantines are not shared.
+func (ht *HealthTracker) ListenForQuarantines(ctx context.Context) {
+	if ht.quarantineStore == nil {
+		return
+	}
+	// Subscribe before loading so no change made in between is missed.
+	sub := ht.quarantineStore.Subscribe(ctx, quarantineChannel)
+	defer func() { _ = sub.Close() }()
+	if err := ht.loadQuarantines(ctx); err != nil {
+		slog.Error("failed to load shared quarantines", "error", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return
+			}
+			ht.applyQuarantineEvent(msg.Payload)
+		}
+	}
+}
+
+// applyQuarantineEvent applies a change another replica published.
+func (ht *HealthTracker) applyQuarantineEvent(payload string) {
+	var ev quarantineEvent
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		slog.Warn("invalid quarantine event", "error", err)
+		return
+	}
+	if ev.Origin == ht.instanceID || ev.Provider == "" {
+		return
+	}
+	ht.setQuarantine(ev.Provider, ev.Quarantine)
+	slog.Info("provider quarantine changed on another replica", "provider", ev.Provider, "quarantined", ev.Quarantine != nil)
+}
+
+// loadQuarantines replaces the local quarantines with those in Redis.
+func (ht *HealthTracker) loadQuarantines(ctx context.Context) error {
+	stored, err := ht.quarantineStore.HGetAll(ctx, quarantineKey).Result()
+	if err != nil {
+		return err
+	}
+	quarantined := make(map[string]Quarantine, len(stored))
+	for provider, raw := range stored {
+		var q Quarantine
+		if err := json.Unmarshal([]byte(raw), &q); err != nil {
+			slog.Warn("invalid shared quarantine", "provider", provider, "error", err)
+			continue
+		}
+		quarantined[provider] = q
+	}
+	ht.mu.Lock()
+	ht.quarantined = quarantined
+	ht.mu.Unlock()
+	return nil
+}
+
+// storeQuarantine records q in Redis and tells the other replicas.
+func (ht *HealthTracker) storeQuarantine(provider string, q *Quarantine) error {
+	raw, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ht.quarantineStore.HSet(ctx, quarantineKey, provider, raw).Err(); err != nil {
+		return fmt.Errorf("store quarantine: %w", err)
+	}
+	ht.publishQuarantine(ctx, provider, q)
+	return nil
+}
+
+// deleteQuarantine removes provider's quarantine from Redis and tells the
+// other replicas. It reports whether one was stored.
+func (ht *HealthTracker) deleteQuarantine(provider string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	n, err := ht.quarantineStore.HDel(ctx, quarantineKey, provider).Result()
+	if err != nil {
+		return false, fmt.Errorf("delete quarantine: %w", err)
+	}
+	ht.publishQuarantine(ctx, provider, nil)
+	return n > 0, nil
+}
+
+// publishQuarantine announces a change already stored in Redis. Failures
+// are logged; replicas that miss it pick the change up when they restart.
+func (ht *HealthTracker) publishQuarantine(ctx context.Context, provider string, q *Quarantine) {
+	payload, err := json.Marshal(quarantineEvent{Provider: provider, Quarantine: q, Origin: ht.instanceID})
+	if err != nil {
+		return
+	}
+	if err := ht.quarantineStore.Publish(ctx, quarantineChannel, payload).Err(); err != nil {
+		slog.Warn("failed to publish quarantine event", "provider", provider, "error", err)
+	}
+}
@@ -0,0 +1,70 @@
+package router
+
+import (
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+// quotaStaleAfter is how long a rate limit observation without a reset time
+// is trusted. OpenAI and Anthropic quotas are per-minute windows.
+const quotaStaleAfter = time.Minute
+
+// upstreamQuota is the last rate limit status a provider reported.
+type upstreamQuota struct {
+	status     adapters.RateLimitStatus
+	observedAt time.Time
+}
+
+// SetQuotaHeadroom sets the fraction of a provider's upstream requests or
+// tokens below which it is routed to only after providers with more room.
+// Zero disables quota-aware routing. Call before serving traffic.
+func (ht *HealthTracker) SetQuotaHeadroom(fraction float64) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.quotaHeadroom = fraction
+}
+
+// ObserveRateLimit records the rate limit status from a provider response.
+func (ht *HealthTracker) ObserveRateLimit(provider string, status adapters.RateLimitStatus) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+	ht.quotas[provider] = upstreamQuota{status: status, observedAt: time.Now()}
+}
+
+// QuotaLow reports whether the provider's last reported requests or tokens
+// remaining are under the headroom fraction of its limit (or zero, when it
+// reports no limit) and that window has not reset since.
+func (ht *HealthTracker) QuotaLow(provider string) bool {
+	ht.mu.RLock()
+	q, ok := ht.quotas[provider]
+	headroom := ht.quotaHeadroom
+	ht.mu.RUnlock()
+	if !ok || headroom <= 0 {
+		return false
+	}
+	now := time.Now()
+	return q.windowLow(q.status.Requests, headroom, now) || q.windowLow(q.status.Tokens, headroom, now)
+}
+
+func (q upstreamQuota) windowLow(w *adapters.RateLimitWindow, headroom float64, now time.Time) bool {
+	if w == nil {
+		return false
+	}
+	if !w.Reset.IsZero() {
+		if !now.Before(w.Reset) {
+			return false
+		}
+	} else if now.Sub(q.observedAt) > quotaStaleAfter {
+		return false
+	}
+	if w.Limit <= 0 {
+		return w.Remaining <= 0
+	}
+	return float64(w.Remaining) < headroom*float64(w.Limit)
+}
+
+// quotaLow is QuotaLow that tolerates a nil tracker.
+func quotaLow(ht *HealthTracker, provider string) bool {
+	return ht != nil && ht.QuotaLow(provider)
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/router/adapters"
+)
+
+func TestHealthTracker_QuotaLow(t *testing.T) {
+	ht := NewHealthTracker(5, time.Second)
+	ht.SetQuotaHeadroom(0.05)
+	future := time.Now().Add(time.Minute)
+
+	ht.ObserveRateLimit("openai", adapters.RateLimitStatus{
+		Requests: &adapters.RateLimitWindow{Limit: 5000, Remaining: 4000, Reset: future},
+		Tokens:   &adapters.RateLimitWindow{Limit: 800000, Remaining: 30000, Reset: future},
+	})
+	if !ht.QuotaLow("openai") {
+		t.Error("tokens under 5% remaining should count as low")
+	}
+
+	ht.ObserveRateLimit("openai", adapters.RateLimitStatus{
+		Tokens: &adapters.RateLimitWindow{Limit: 800000, Remaining: 30000, Reset: time.Now().Add(-time.Second)},
+	})
+	if ht.QuotaLow("openai") {
+		t.Error("a window that has reset should not count as low")
+	}
+
+	ht.ObserveRateLimit("azure", adapters.RateLimitStatus{Requests: &adapters.RateLimitWindow{Remaining: 0}})
+	if !ht.QuotaLow("azure") {
+		t.Error("zero remaining without a limit should count as low")
+	}
+
+	ht.SetQuotaHeadroom(0)
+	if ht.QuotaLow("azure") {
+		t.Error("zero headroom disables quota-aware routing")
+	}
+}
+
+func TestResolveRoute_LowQuotaDeprioritized(t *testing.T) {
+	registry := newTestRegistry("openai", "azure")
+	ht := NewHealthTracker(5, time.Second)
+	ht.SetQuotaHeadroom(0.05)
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"gpt-4o": {
+			Primary:  config.ProviderRoute{Provider: "openai", Model: "gpt-4o"},
+			Fallback: []config.ProviderRoute{{Provider: "azure", Model: "gpt-4o"}},
+		},
+	})
+	ht.ObserveRateLimit("openai", adapters.RateLimitStatus{
+		Requests: &adapters.RateLimitWindow{Limit: 100, Remaining: 1, Reset: time.Now().Add(time.Minute)},
+	})
+
+	adapter, _, err := ResolveRoute(cfg, registry, ht, "gpt-4o", "")
+	if err != nil || adapter.Name() != "azure" {
+		t.Fatalf("expected azure ahead of nearly exhausted openai, got %v, %v", adapter, err)
+	}
+
+	ht.Quarantine("azure", "test")
+	adapter, _, err = ResolveRoute(cfg, registry, ht, "gpt-4o", "")
+	if err != nil || adapter.Name() != "openai" {
+		t.Errorf("expected openai when it is the only route left, got %v, %v", adapter, err)
+	}
+}
@@ -0,0 +1,129 @@
+package router
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// AccessWindowError is returned by CheckAccessWindows when a maintenance
+// window or office hours close a model to an organization.
+type AccessWindowError struct {
+	Window string
+	Type   string // config.AccessWindowMaintenance or config.AccessWindowOfficeHours
+	// Until is when the model reopens; zero if no opening is scheduled
+	// within a week.
+	Until   time.Time
+	Message string
+}
+
+func (e *AccessWindowError) Error() string { return e.Message }
+
+// CheckAccessWindows returns an *AccessWindowError if, at now, a maintenance
+// window covering org and model is open, or office hours cover them and none
+// is open. It returns nil otherwise.
+func CheckAccessWindows(windows []config.AccessWindowConfig, org, model string, now time.Time) error {
+	var officeHours []config.AccessWindowConfig
+	for _, w := range windows {
+		if !w.Applies(org, model) {
+			continue
+		}
+		switch w.Type {
+		case config.AccessWindowMaintenance:
+			if open, end := AccessWindowState(w, now); open {
+				return &AccessWindowError{
+					Window:  w.Name,
+					Type:    w.Type,
+					Until:   end,
+					Message: windowMessage(w, fmt.Sprintf("Model %s is unavailable for scheduled maintenance (%s) until %s", model, w.Name, end.Format(time.RFC3339))),
+				}
+			}
+		case config.AccessWindowOfficeHours:
+			officeHours = append(officeHours, w)
+		}
+	}
+	if len(officeHours) == 0 {
+		return nil
+	}
+
+	var next time.Time
+	closedBy := officeHours[0]
+	for _, w := range officeHours {
+		open, change := AccessWindowState(w, now)
+		if open {
+			return nil
+		}
+		if !change.IsZero() && (next.IsZero() || change.Before(next)) {
+			next, closedBy = change, w
+		}
+	}
+	msg := fmt.Sprintf("Model %s is only available during office hours (%s)", model, closedBy.Name)
+	if !next.IsZero() {
+		msg += "; next available " + next.Format(time.RFC3339)
+	}
+	return &AccessWindowError{
+		Window:  closedBy.Name,
+		Type:    closedBy.Type,
+		Until:   next,
+		Message: windowMessage(closedBy, msg),
+	}
+}
+
+func windowMessage(w config.AccessWindowConfig, fallback string) string {
+	if w.Message != "" {
+		return w.Message
+	}
+	return fallback
+}
+
+// AccessWindowState reports whether w is open at now, and when that next
+// changes: the end of the current occurrence if open, else the start of the
+// next one. The change is zero when none is scheduled within a week.
+func AccessWindowState(w config.AccessWindowConfig, now time.Time) (open bool, change time.Time) {
+	for _, occ := range occurrences(w, now) {
+		if !now.Before(occ[0]) && now.Before(occ[1]) {
+			return true, occ[1]
+		}
+		if occ[0].After(now) && (change.IsZero() || occ[0].Before(change)) {
+			change = occ[0]
+		}
+	}
+	return false, change
+}
+
+// occurrences returns w's open intervals that can contain now or start
+// within the following week: the one-off interval, or each recurrence from
+// the day before now, which may run past midnight into today.
+func occurrences(w config.AccessWindowConfig, now time.Time) [][2]time.Time {
+	if !w.StartsAt.IsZero() {
+		return [][2]time.Time{{w.StartsAt, w.EndsAt}}
+	}
+	start, err := config.ParseClock(w.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := config.ParseClock(w.End)
+	if err != nil {
+		return nil
+	}
+	endDay := 0
+	if end <= start {
+		endDay = 1
+	}
+
+	loc := w.Location()
+	local := now.In(loc)
+	var out [][2]time.Time
+	for d := -1; d <= 7; d++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+d, 0, 0, 0, 0, loc)
+		if !w.OnDay(day.Weekday()) {
+			continue
+		}
+		out = append(out, [2]time.Time{
+			time.Date(day.Year(), day.Month(), day.Day(), start/60, start%60, 0, 0, loc),
+			time.Date(day.Year(), day.Month(), day.Day()+endDay, end/60, end%60, 0, 0, loc),
+		})
+	}
+	return out
+}
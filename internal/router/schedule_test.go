@@ -0,0 +1,80 @@
+package router
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func TestCheckAccessWindows(t *testing.T) {
+	windows := []config.AccessWindowConfig{
+		{
+			Name: "berlin-office", Type: config.AccessWindowOfficeHours,
+			Organizations: []string{"org-de"}, Timezone: "Europe/Berlin",
+			Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "09:00", End: "17:00",
+		},
+		{
+			Name: "weekly-patch", Type: config.AccessWindowMaintenance,
+			Models: []string{"aegis-gpt4"}, Days: []string{"sunday"}, Start: "23:00", End: "01:00",
+		},
+		{
+			Name: "db-upgrade", Type: config.AccessWindowMaintenance, Organizations: []string{"org-us"},
+			StartsAt: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+			EndsAt:   time.Date(2026, 10, 15, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	utc := func(day, hour, min int) time.Time { return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		org, model string
+		now        time.Time
+		wantWindow string
+		wantUntil  time.Time
+	}{
+		{"inside office hours", "org-de", "aegis-fast", utc(14, 8, 0), "", time.Time{}},
+		{"friday evening", "org-de", "aegis-fast", utc(16, 16, 0), "berlin-office", utc(19, 7, 0)},
+		{"office hours scoped to org", "org-us", "aegis-fast", utc(16, 16, 0), "", time.Time{}},
+		{"maintenance past midnight", "org-us", "aegis-gpt4", utc(19, 0, 30), "weekly-patch", utc(19, 1, 0)},
+		{"maintenance scoped to model", "org-us", "aegis-fast", utc(19, 0, 30), "", time.Time{}},
+		{"after maintenance", "org-us", "aegis-gpt4", utc(19, 1, 0), "", time.Time{}},
+		{"one-off maintenance", "org-us", "aegis-fast", utc(15, 13, 0), "db-upgrade", utc(15, 14, 0)},
+		{"after one-off maintenance", "org-us", "aegis-fast", utc(15, 14, 0), "", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAccessWindows(windows, tt.org, tt.model, tt.now)
+			if tt.wantWindow == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var closed *AccessWindowError
+			if !errors.As(err, &closed) {
+				t.Fatalf("expected AccessWindowError, got %v", err)
+			}
+			if closed.Window != tt.wantWindow || !closed.Until.Equal(tt.wantUntil) {
+				t.Errorf("got window %s until %s, want %s until %s", closed.Window, closed.Until, tt.wantWindow, tt.wantUntil)
+			}
+			if closed.Message == "" {
+				t.Error("expected an error message")
+			}
+		})
+	}
+}
+
+func TestAccessWindowState(t *testing.T) {
+	w := config.AccessWindowConfig{Type: config.AccessWindowOfficeHours, Start: "09:00", End: "17:00", Days: []string{"Mon"}}
+
+	open, change := AccessWindowState(w, time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC))
+	if !open || !change.Equal(time.Date(2026, 10, 12, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("Monday 10:00: open=%v change=%s", open, change)
+	}
+	open, change = AccessWindowState(w, time.Date(2026, 10, 13, 10, 0, 0, 0, time.UTC))
+	if open || !change.Equal(time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Tuesday 10:00: open=%v change=%s", open, change)
+	}
+}
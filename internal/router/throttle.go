@@ -0,0 +1,165 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// defaultThrottleMaxWait is how long a request queues for provider capacity
+// when rate_limit.max_wait is unset.
+const defaultThrottleMaxWait = 5 * time.Second
+
+// throttleBurstWindow is how much of a minute's quota may be sent at once.
+// Anything beyond it is spread out at the configured rate, so a burst cannot
+// use up a provider's quota in the first seconds of a minute.
+const throttleBurstWindow = 10 * time.Second
+
+// ThrottledError is returned by Throttle.Wait when a request would have to
+// queue longer than the provider's max_wait.
+type ThrottledError struct {
+	Provider   string
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("provider %s is at its outbound rate limit, retry after %s", e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+// Throttle paces requests to one provider under its configured requests and
+// tokens per minute. Requests reserve capacity in arrival order; a
+// reservation may leave a bucket negative, which later requests wait out.
+type Throttle struct {
+	provider string
+	limits   config.ProviderRateLimitConfig
+	maxWait  time.Duration
+
+	mu       sync.Mutex
+	requests *bucket // nil when rpm is unlimited
+	tokens   *bucket // nil when tpm is unlimited
+}
+
+// NewThrottle returns a throttle for the provider, or nil when cfg sets no
+// limits.
+func NewThrottle(provider string, cfg *config.ProviderRateLimitConfig) *Throttle {
+	if cfg == nil || (cfg.RPM <= 0 && cfg.TPM <= 0) {
+		return nil
+	}
+	t := &Throttle{provider: provider, limits: *cfg, maxWait: cfg.MaxWait}
+	if t.maxWait <= 0 {
+		t.maxWait = defaultThrottleMaxWait
+	}
+	now := time.Now()
+	if cfg.RPM > 0 {
+		t.requests = newBucket(cfg.RPM, now)
+	}
+	if cfg.TPM > 0 {
+		t.tokens = newBucket(cfg.TPM, now)
+	}
+	return t
+}
+
+// Saturated reports whether a request would have to wait for capacity now.
+// Routing prefers a fallback with room over a saturated provider.
+func (t *Throttle) Saturated() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.delayLocked(time.Now(), 1) > 0
+}
+
+// Wait reserves one request and tokens tokens, blocking until the provider
+// has room. It returns a *ThrottledError without reserving anything when the
+// wait would exceed max_wait, and ctx's error if ctx ends first. The
+// returned duration is how long the request queued.
+func (t *Throttle) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	if t == nil {
+		return 0, nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	delay := t.delayLocked(now, tokens)
+	if delay > t.maxWait {
+		t.mu.Unlock()
+		return 0, &ThrottledError{Provider: t.provider, RetryAfter: delay}
+	}
+	t.takeLocked(1, tokens)
+	t.mu.Unlock()
+
+	if delay <= 0 {
+		return 0, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return delay, nil
+	case <-ctx.Done():
+		// Give the reservation back so requests behind this one move up.
+		t.mu.Lock()
+		t.takeLocked(-1, -tokens)
+		t.mu.Unlock()
+		return 0, ctx.Err()
+	}
+}
+
+// sameLimits reports whether t was built from cfg, so a config reload can
+// keep its state.
+func (t *Throttle) sameLimits(cfg *config.ProviderRateLimitConfig) bool {
+	return t != nil && cfg != nil && t.limits == *cfg
+}
+
+func (t *Throttle) delayLocked(now time.Time, tokens int) time.Duration {
+	var d time.Duration
+	if t.requests != nil {
+		d = max(d, t.requests.delay(now, 1))
+	}
+	if t.tokens != nil {
+		d = max(d, t.tokens.delay(now, float64(tokens)))
+	}
+	return d
+}
+
+func (t *Throttle) takeLocked(requests, tokens int) {
+	if t.requests != nil {
+		t.requests.level -= float64(requests)
+	}
+	if t.tokens != nil {
+		t.tokens.level -= float64(tokens)
+	}
+}
+
+// bucket is a token bucket refilled continuously at perMinute/60 per second
+// up to throttleBurstWindow's worth.
+type bucket struct {
+	rate  float64 // units per second
+	burst float64
+	level float64
+	last  time.Time
+}
+
+func newBucket(perMinute int, now time.Time) *bucket {
+	rate := float64(perMinute) / 60
+	burst := max(rate*throttleBurstWindow.Seconds(), 1)
+	return &bucket{rate: rate, burst: burst, level: burst, last: now}
+}
+
+// delay refills the bucket to now and returns how long until n units are
+// available. Requests larger than the burst only wait for a full bucket, so
+// they are slowed rather than refused forever.
+func (b *bucket) delay(now time.Time, n float64) time.Duration {
+	if now.After(b.last) {
+		b.level = min(b.burst, b.level+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+	n = min(n, b.burst)
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.rate * float64(time.Second))
+}
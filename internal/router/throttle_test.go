@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func TestNewThrottle_NoLimits(t *testing.T) {
+	if NewThrottle("openai", nil) != nil || NewThrottle("openai", &config.ProviderRateLimitConfig{MaxWait: time.Second}) != nil {
+		t.Fatal("expected no throttle without rpm or tpm")
+	}
+	var th *Throttle
+	if th.Saturated() {
+		t.Error("nil throttle should never be saturated")
+	}
+	if _, err := th.Wait(context.Background(), 1000); err != nil {
+		t.Errorf("nil throttle Wait: %v", err)
+	}
+}
+
+func TestThrottle_RequestsPerMinute(t *testing.T) {
+	// 60 RPM allows a 10-request burst, then one request per second.
+	th := NewThrottle("openai", &config.ProviderRateLimitConfig{RPM: 60, MaxWait: time.Millisecond})
+	for i := 0; i < 10; i++ {
+		if _, err := th.Wait(context.Background(), 0); err != nil {
+			t.Fatalf("request %d within burst: %v", i, err)
+		}
+	}
+	if !th.Saturated() {
+		t.Fatal("expected throttle to be saturated after the burst")
+	}
+	_, err := th.Wait(context.Background(), 0)
+	var throttled *ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("expected ThrottledError, got %v", err)
+	}
+	if throttled.RetryAfter <= 0 || throttled.RetryAfter > time.Second {
+		t.Errorf("RetryAfter = %v, want (0, 1s]", throttled.RetryAfter)
+	}
+}
+
+func TestThrottle_TokensQueueWithinMaxWait(t *testing.T) {
+	// 6000 TPM refills 100 tokens/s with a 1000-token burst.
+	th := NewThrottle("openai", &config.ProviderRateLimitConfig{TPM: 6000, MaxWait: time.Second})
+	if _, err := th.Wait(context.Background(), 1000); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	waited, err := th.Wait(context.Background(), 20)
+	if err != nil {
+		t.Fatalf("second request should queue: %v", err)
+	}
+	if waited < 150*time.Millisecond || waited > 250*time.Millisecond {
+		t.Errorf("waited %v, want about 200ms", waited)
+	}
+	if _, err := th.Wait(context.Background(), 500); err == nil {
+		t.Error("expected rejection when the wait exceeds max_wait")
+	}
+}
+
+func TestThrottle_CancelledWaitReturnsReservation(t *testing.T) {
+	th := NewThrottle("openai", &config.ProviderRateLimitConfig{TPM: 6000, MaxWait: 5 * time.Second})
+	if _, err := th.Wait(context.Background(), 1000); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := th.Wait(ctx, 400); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	th.mu.Lock()
+	level := th.tokens.level
+	th.mu.Unlock()
+	if level < -1 {
+		t.Errorf("cancelled reservation not returned, level = %v", level)
+	}
+}
+
+func TestResolveRoute_SaturatedProviderFailsOver(t *testing.T) {
+	registry := newTestRegistry("openai", "azure")
+	registry.SetThrottle("openai", NewThrottle("openai", &config.ProviderRateLimitConfig{RPM: 6}))
+	cfg := modelsCfgWith(map[string]config.ModelMapping{
+		"gpt-4o": {
+			Primary:  config.ProviderRoute{Provider: "openai", Model: "gpt-4o"},
+			Fallback: []config.ProviderRoute{{Provider: "azure", Model: "gpt-4o-azure"}},
+		},
+	})
+
+	adapter, _, _ := ResolveRoute(cfg, registry, nil, "gpt-4o", "")
+	if adapter.Name() != "openai" {
+		t.Fatalf("expected primary while it has room, got %s", adapter.Name())
+	}
+	if _, err := registry.Throttle("openai").Wait(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	adapter, model, _ := ResolveRoute(cfg, registry, nil, "gpt-4o", "")
+	if adapter.Name() != "azure" || model != "gpt-4o-azure" {
+		t.Errorf("expected failover to azure, got %s/%s", adapter.Name(), model)
+	}
+
+	// With every route saturated the primary is returned to queue on.
+	registry.SetThrottle("azure", NewThrottle("azure", &config.ProviderRateLimitConfig{RPM: 6}))
+	_, _ = registry.Throttle("azure").Wait(context.Background(), 0)
+	adapter, _, _ = ResolveRoute(cfg, registry, nil, "gpt-4o", "")
+	if adapter.Name() != "openai" {
+		t.Errorf("expected primary when all routes are saturated, got %s", adapter.Name())
+	}
+}
+
+func TestRegistry_ReplaceFromKeepsUnchangedThrottle(t *testing.T) {
+	limits := &config.ProviderRateLimitConfig{RPM: 60}
+	current := newTestRegistry("openai", "anthropic")
+	kept := NewThrottle("openai", limits)
+	current.SetThrottle("openai", kept)
+	current.SetThrottle("anthropic", NewThrottle("anthropic", limits))
+
+	reloaded := newTestRegistry("openai", "anthropic")
+	reloaded.SetThrottle("openai", NewThrottle("openai", limits))
+	changed := NewThrottle("anthropic", &config.ProviderRateLimitConfig{RPM: 120})
+	reloaded.SetThrottle("anthropic", changed)
+
+	current.ReplaceFrom(reloaded)
+	if current.Throttle("openai") != kept {
+		t.Error("unchanged throttle should survive a reload")
+	}
+	if current.Throttle("anthropic") != changed {
+		t.Error("changed throttle should be replaced")
+	}
+}
@@ -0,0 +1,241 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/egress"
+)
+
+// ConnMetrics is an optional interface for recording provider connection
+// pool activity. It is satisfied by *telemetry.Metrics.
+type ConnMetrics interface {
+	RecordProviderConnOpened(provider string)
+	RecordProviderConnClosed(provider string)
+	// RecordProviderConnAcquired records a request getting a connection,
+	// reused from the idle pool or newly dialed.
+	RecordProviderConnAcquired(provider string, reused bool)
+	// RecordProviderConnSetup records how long one phase of setting up a
+	// connection took: "dns", "connect", or "tls".
+	RecordProviderConnSetup(provider, phase string, d time.Duration)
+	// RecordProviderProtocol records the protocol a response came over,
+	// such as "HTTP/1.1" or "HTTP/2.0".
+	RecordProviderProtocol(provider, proto string)
+}
+
+// newProviderTransport builds the outbound transport for one provider,
+// applying its proxy, TLS, and connection pool settings. External providers
+// may need to go through a corporate forward proxy while in-house backends
+// connect directly, so nothing here is shared between providers.
+func newProviderTransport(cfg config.ProviderConfig) (*http.Transport, error) {
+	var conns config.ProviderConnectionsConfig
+	if cfg.Connections != nil {
+		conns = *cfg.Connections
+	}
+	idle := cfg.MaxConcurrent
+	if conns.MaxIdleConnsPerHost > 0 {
+		idle = conns.MaxIdleConnsPerHost
+	}
+	dialer := &net.Dialer{
+		Timeout:   durationOr(conns.DialTimeout, 30*time.Second),
+		KeepAlive: durationOr(conns.KeepAlive, 30*time.Second),
+	}
+	t := &http.Transport{
+		DialContext:         dialer.DialContext,
+		MaxIdleConns:        idle,
+		MaxIdleConnsPerHost: idle,
+		MaxConnsPerHost:     conns.MaxConnsPerHost,
+		IdleConnTimeout:     durationOr(conns.IdleConnTimeout, 90*time.Second),
+		TLSHandshakeTimeout: durationOr(conns.TLSHandshakeTimeout, 10*time.Second),
+		ForceAttemptHTTP2:   true,
+	}
+
+	switch cfg.Proxy {
+	case "":
+	case config.ProviderProxyFromEnv:
+		t.Proxy = http.ProxyFromEnvironment
+	default:
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", cfg.Proxy)
+		}
+		t.Proxy = http.ProxyURL(u)
+	}
+
+	if cfg.TLS != nil {
+		tlsCfg, err := providerTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = tlsCfg
+	}
+	return t, nil
+}
+
+// restrictEgress makes t refuse destinations allow does not permit. Through
+// a proxy, the proxy is dialed and so checked as a destination, while the
+// provider host it is asked to reach must be allowed by name.
+func restrictEgress(t *http.Transport, allow *egress.Allowlist) {
+	if allow == nil {
+		return
+	}
+	t.DialContext = allow.DialContext(t.DialContext)
+	if proxy := t.Proxy; proxy != nil {
+		t.Proxy = func(req *http.Request) (*url.URL, error) {
+			if host := req.URL.Hostname(); !allow.AllowsHost(host) {
+				return nil, fmt.Errorf("%w: %s is not on the egress allowlist", egress.ErrDenied, host)
+			}
+			return proxy(req)
+		}
+	}
+}
+
+func providerTLSConfig(c *config.ProviderTLSConfig) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	switch c.MinVersion {
+	case "", "1.2":
+		tlsCfg.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsCfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported tls min_version %q", c.MinVersion)
+	}
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca_file %s: no PEM certificates found", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+func durationOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// instrumentTransport reports t's connections to metrics under provider:
+// connections opened and closed, whether each request reused one, how long
+// DNS, connect, and TLS took for new ones, and the protocol negotiated.
+func instrumentTransport(provider string, t *http.Transport, metrics ConnMetrics) http.RoundTripper {
+	dial := t.DialContext
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		c, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		metrics.RecordProviderConnOpened(provider)
+		return &trackedConn{Conn: c, onClose: func() { metrics.RecordProviderConnClosed(provider) }}, nil
+	}
+	return &tracedTransport{base: t, provider: provider, metrics: metrics}
+}
+
+// trackedConn calls onClose the first time it is closed.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
+
+type tracedTransport struct {
+	base     http.RoundTripper
+	provider string
+	metrics  ConnMetrics
+}
+
+func (t *tracedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), t.trace())))
+	if err == nil {
+		t.metrics.RecordProviderProtocol(t.provider, resp.Proto)
+	}
+	return resp, err
+}
+
+// trace builds the hooks for one request. A dial may outlive the request
+// that started it and race other address attempts, so the start times are
+// guarded.
+func (t *tracedTransport) trace() *httptrace.ClientTrace {
+	var mu sync.Mutex
+	var dnsStart, tlsStart time.Time
+	connectStart := map[string]time.Time{}
+	since := func(start *time.Time) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Since(*start)
+	}
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.metrics.RecordProviderConnAcquired(t.provider, info.Reused)
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			mu.Lock()
+			dnsStart = time.Now()
+			mu.Unlock()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			if info.Err == nil {
+				t.metrics.RecordProviderConnSetup(t.provider, "dns", since(&dnsStart))
+			}
+		},
+		ConnectStart: func(network, addr string) {
+			mu.Lock()
+			connectStart[network+addr] = time.Now()
+			mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			mu.Lock()
+			start := connectStart[network+addr]
+			mu.Unlock()
+			if err == nil {
+				t.metrics.RecordProviderConnSetup(t.provider, "connect", time.Since(start))
+			}
+		},
+		TLSHandshakeStart: func() {
+			mu.Lock()
+			tlsStart = time.Now()
+			mu.Unlock()
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.metrics.RecordProviderConnSetup(t.provider, "tls", since(&tlsStart))
+			}
+		},
+	}
+}
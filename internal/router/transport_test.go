@@ -0,0 +1,265 @@
+package router
+
+import (
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+	"github.com/af-corp/aegis-gateway/internal/egress"
+)
+
+func TestNewProviderTransport_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A forward proxy receives the absolute target URL.
+		proxied = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	transport, err := newProviderTransport(config.ProviderConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get("http://api.openai.example/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if proxied != "http://api.openai.example/v1/models" {
+		t.Errorf("proxy saw %q", proxied)
+	}
+
+	// Without a proxy the transport never consults the environment.
+	t.Setenv("HTTP_PROXY", proxy.URL)
+	direct, err := newProviderTransport(config.ProviderConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if direct.Proxy != nil {
+		t.Error("providers without proxy should connect directly")
+	}
+}
+
+func TestNewProviderTransport_CAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	plain, _ := newProviderTransport(config.ProviderConfig{})
+	if _, err := (&http.Client{Transport: plain}).Get(srv.URL); err == nil {
+		t.Fatal("expected an unknown authority error without ca_file")
+	}
+
+	trusted, err := newProviderTransport(config.ProviderConfig{TLS: &config.ProviderTLSConfig{CAFile: caFile, MinVersion: "1.3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: trusted}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with ca_file failed: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestBuildFromConfig_SkipsMisconfiguredTransport(t *testing.T) {
+	registry := BuildFromConfig(&config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"openai":   {Type: "openai", BaseURL: "https://api.openai.com/v1"},
+		"external": {Type: "openai", BaseURL: "https://example.com", TLS: &config.ProviderTLSConfig{CAFile: "/nonexistent/ca.pem"}},
+	}}, BuildOptions{})
+	if _, ok := registry.Get("openai"); !ok {
+		t.Error("valid provider should be registered")
+	}
+	if _, ok := registry.Get("external"); ok {
+		t.Error("provider with an unreadable ca_file should be left out")
+	}
+}
+
+func TestBuildFromConfig_EgressAllowlist(t *testing.T) {
+	allow, err := egress.Parse([]string{"api.openai.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := BuildFromConfig(&config.ProvidersConfig{Providers: map[string]config.ProviderConfig{
+		"openai":   {Type: "openai", BaseURL: "https://api.openai.com/v1"},
+		"attacker": {Type: "openai", BaseURL: "https://collector.example.net/v1"},
+	}}, BuildOptions{Allow: allow})
+	if _, ok := registry.Get("openai"); !ok {
+		t.Error("allowed provider should be registered")
+	}
+	if _, ok := registry.Get("attacker"); ok {
+		t.Error("provider off the egress allowlist should be left out")
+	}
+}
+
+func TestRegistry_ReplaceFromRebuildsOnlyChangedProviders(t *testing.T) {
+	providers := map[string]config.ProviderConfig{
+		"openai":    {Type: "openai", BaseURL: "https://api.openai.com/v1", APIKey: "sk-old"},
+		"anthropic": {Type: "anthropic", BaseURL: "https://api.anthropic.com/v1", APIKey: "sk-ant"},
+	}
+	current := BuildFromConfig(&config.ProvidersConfig{Providers: providers}, BuildOptions{})
+	openai, _ := current.Get("openai")
+	anthropic, _ := current.Get("anthropic")
+
+	rotated := providers["openai"]
+	rotated.APIKey = "sk-new"
+	providers["openai"] = rotated
+	rebuilt := current.ReplaceFrom(BuildFromConfig(&config.ProvidersConfig{Providers: providers}, BuildOptions{}))
+
+	if len(rebuilt) != 1 || rebuilt[0] != "openai" {
+		t.Errorf("rebuilt = %v, want [openai]", rebuilt)
+	}
+	if a, _ := current.Get("openai"); a == openai {
+		t.Error("provider with a rotated key should get a new adapter")
+	}
+	if a, _ := current.Get("anthropic"); a != anthropic {
+		t.Error("unchanged provider should keep its adapter")
+	}
+
+	// A new egress allowlist changes every provider's transport.
+	allow, _ := egress.Parse([]string{"api.openai.com", "api.anthropic.com"})
+	if rebuilt := current.ReplaceFrom(BuildFromConfig(&config.ProvidersConfig{Providers: providers}, BuildOptions{Allow: allow})); len(rebuilt) != 2 {
+		t.Errorf("rebuilt = %v, want both providers", rebuilt)
+	}
+}
+
+func TestCredentialsOnlyChange(t *testing.T) {
+	base := config.ProviderConfig{Type: "openai", BaseURL: "https://api.openai.com/v1", APIKey: "a", Headers: map[string]string{"OpenAI-Organization": "org-1"}}
+	key, header, url := base, base, base
+	key.APIKey = "b"
+	header.Headers = map[string]string{"OpenAI-Organization": "org-2"}
+	url.BaseURL, url.APIKey = "https://proxy.internal/v1", "b"
+	if !credentialsOnlyChange(base, key) || !credentialsOnlyChange(base, header) {
+		t.Error("key and header changes are credential rotations")
+	}
+	if credentialsOnlyChange(base, url) || credentialsOnlyChange(base, base) {
+		t.Error("a base_url change, or no change, is not a credential rotation")
+	}
+}
+
+func TestRestrictEgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	denied, _ := newProviderTransport(config.ProviderConfig{})
+	allow, _ := egress.Parse([]string{"10.0.0.0/8"})
+	restrictEgress(denied, allow)
+	if _, err := (&http.Client{Transport: denied}).Get(srv.URL); !errors.Is(err, egress.ErrDenied) {
+		t.Errorf("dial off the allowlist: got %v, want ErrDenied", err)
+	}
+
+	// An allowed address is dialed as usual.
+	allowed, _ := newProviderTransport(config.ProviderConfig{})
+	allow, _ = egress.Parse([]string{"127.0.0.1"})
+	restrictEgress(allowed, allow)
+	resp, err := (&http.Client{Transport: allowed}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("dial on the allowlist: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	// Through a proxy, the provider host must be allowed by name.
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer proxy.Close()
+	proxied, _ := newProviderTransport(config.ProviderConfig{Proxy: proxy.URL})
+	restrictEgress(proxied, allow)
+	if _, err := (&http.Client{Transport: proxied}).Get("http://collector.example.net/v1"); !errors.Is(err, egress.ErrDenied) {
+		t.Errorf("proxied request off the allowlist: got %v, want ErrDenied", err)
+	}
+}
+
+func TestNewProviderTransport_Connections(t *testing.T) {
+	legacy, _ := newProviderTransport(config.ProviderConfig{MaxConcurrent: 50})
+	if legacy.MaxIdleConnsPerHost != 50 || legacy.MaxConnsPerHost != 0 || legacy.TLSHandshakeTimeout != 10*time.Second {
+		t.Errorf("unexpected defaults: idle=%d max=%d tls=%s", legacy.MaxIdleConnsPerHost, legacy.MaxConnsPerHost, legacy.TLSHandshakeTimeout)
+	}
+
+	tuned, _ := newProviderTransport(config.ProviderConfig{MaxConcurrent: 50, Connections: &config.ProviderConnectionsConfig{
+		MaxConnsPerHost: 100, MaxIdleConnsPerHost: 80, TLSHandshakeTimeout: 3 * time.Second,
+	}})
+	if tuned.MaxIdleConnsPerHost != 80 || tuned.MaxConnsPerHost != 100 || tuned.TLSHandshakeTimeout != 3*time.Second {
+		t.Errorf("connection settings not applied: idle=%d max=%d tls=%s", tuned.MaxIdleConnsPerHost, tuned.MaxConnsPerHost, tuned.TLSHandshakeTimeout)
+	}
+}
+
+type fakeConnMetrics struct {
+	mu             sync.Mutex
+	opened, closed int
+	reused, dialed int
+	phases         map[string]int
+	protocols      map[string]int
+}
+
+func (f *fakeConnMetrics) RecordProviderConnOpened(string) { f.mu.Lock(); f.opened++; f.mu.Unlock() }
+func (f *fakeConnMetrics) RecordProviderConnClosed(string) { f.mu.Lock(); f.closed++; f.mu.Unlock() }
+func (f *fakeConnMetrics) RecordProviderConnAcquired(_ string, reused bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if reused {
+		f.reused++
+	} else {
+		f.dialed++
+	}
+}
+
+func (f *fakeConnMetrics) RecordProviderConnSetup(_, phase string, _ time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.phases[phase]++
+}
+
+func (f *fakeConnMetrics) RecordProviderProtocol(_, proto string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.protocols[proto]++
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	pool, _ := newProviderTransport(config.ProviderConfig{MaxConcurrent: 4})
+	metrics := &fakeConnMetrics{phases: map[string]int{}, protocols: map[string]int{}}
+	client := &http.Client{Transport: instrumentTransport("local", pool, metrics)}
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	pool.CloseIdleConnections()
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if metrics.opened != 1 || metrics.closed != 1 {
+		t.Errorf("expected one connection opened and closed, got %d/%d", metrics.opened, metrics.closed)
+	}
+	if metrics.dialed != 1 || metrics.reused != 2 {
+		t.Errorf("expected 1 new and 2 reused acquires, got %d/%d", metrics.dialed, metrics.reused)
+	}
+	if metrics.phases["connect"] != 1 || metrics.phases["tls"] != 0 {
+		t.Errorf("expected one plain connect, got %v", metrics.phases)
+	}
+	if metrics.protocols["HTTP/1.1"] != 3 {
+		t.Errorf("expected 3 HTTP/1.1 responses, got %v", metrics.protocols)
+	}
+}
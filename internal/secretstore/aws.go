@@ -0,0 +1,150 @@
+package secretstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/awssig"
+)
+
+// AWSConfig configures the AWS Secrets Manager resolver.
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Endpoint overrides https://secretsmanager.<region>.amazonaws.com,
+	// e.g. for VPC endpoints or LocalStack.
+	Endpoint string
+	Timeout  time.Duration
+}
+
+// AWSConfigFromEnv reads the standard AWS_REGION (or AWS_DEFAULT_REGION),
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, and
+// AWS_ENDPOINT_URL_SECRETS_MANAGER variables.
+func AWSConfigFromEnv() AWSConfig {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	return AWSConfig{
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER"),
+		Timeout:         10 * time.Second,
+	}
+}
+
+// AWSSecretsManagerResolver resolves "secretsmanager://<secret-id>[#<json-key>]"
+// references. <secret-id> is a secret name or full ARN; an ARN's region takes
+// precedence over the configured one. With #<json-key> the secret string is
+// parsed as a JSON object and that key is returned.
+type AWSSecretsManagerResolver struct {
+	cfg    AWSConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewAWSSecretsManagerResolver creates an AWS Secrets Manager resolver. A nil
+// client uses a default client with cfg.Timeout.
+func NewAWSSecretsManagerResolver(cfg AWSConfig, client *http.Client) *AWSSecretsManagerResolver {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &AWSSecretsManagerResolver{cfg: cfg, client: client, now: time.Now}
+}
+
+// Scheme implements config.SecretResolver.
+func (a *AWSSecretsManagerResolver) Scheme() string { return "secretsmanager" }
+
+// Resolve implements config.SecretResolver.
+func (a *AWSSecretsManagerResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	id, field, _ := strings.Cut(strings.TrimPrefix(ref, "secretsmanager://"), "#")
+	if id == "" {
+		return "", fmt.Errorf("invalid secrets manager reference %q: want secretsmanager://<secret-id>[#<key>]", ref)
+	}
+	if a.cfg.AccessKeyID == "" || a.cfg.SecretAccessKey == "" {
+		return "", fmt.Errorf("resolve %s: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set", ref)
+	}
+
+	region := a.cfg.Region
+	if arn := strings.Split(id, ":"); len(arn) > 3 && arn[0] == "arn" {
+		region = arn[3]
+	}
+	if region == "" {
+		return "", fmt.Errorf("resolve %s: no AWS region configured", ref)
+	}
+	endpoint := a.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(endpoint, "/")+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("create secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	creds := awssig.Credentials{AccessKeyID: a.cfg.AccessKeyID, SecretAccessKey: a.cfg.SecretAccessKey, SessionToken: a.cfg.SessionToken}
+	awssig.Sign(req, creds, region, "secretsmanager", "/", body, a.now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager get %s: %w", id, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("secrets manager get %s returned status %d: %s", id, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode secrets manager response: %w", err)
+	}
+	secret := out.SecretString
+	if secret == "" && out.SecretBinary != "" {
+		raw, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("decode secret binary for %s: %w", id, err)
+		}
+		secret = string(raw)
+	}
+	return extractField(id, secret, field)
+}
+
+// extractField returns secret as-is when field is empty, otherwise parses it
+// as a JSON object and returns the string value under field.
+func extractField(name, secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(secret), &obj); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, cannot select %q", name, field)
+	}
+	val, ok := obj[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %q", name, field)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s field %q is not a string", name, field)
+	}
+	return s, nil
+}
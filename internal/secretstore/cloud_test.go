@@ -0,0 +1,109 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAWSSecretsManagerResolver_Resolve(t *testing.T) {
+	var lastAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" {
+			t.Errorf("unexpected target %q", r.Header.Get("X-Amz-Target"))
+		}
+		lastAuth = r.Header.Get("Authorization")
+		var in struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		switch in.SecretId {
+		case "aegis/openai", "arn:aws:secretsmanager:eu-west-1:123456789012:secret:aegis/openai-AbCdEf":
+			_, _ = w.Write([]byte(`{"SecretString":"{\"api_key\":\"sk-aws\"}"}`))
+		case "aegis/plain":
+			_, _ = w.Write([]byte(`{"SecretBinary":"` + base64.StdEncoding.EncodeToString([]byte("raw-bytes")) + `"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		}
+	}))
+	defer srv.Close()
+
+	a := NewAWSSecretsManagerResolver(AWSConfig{
+		Region: "us-east-1", AccessKeyID: "AKIDTEST", SecretAccessKey: "secret", Endpoint: srv.URL,
+	}, nil)
+	a.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	got, err := a.Resolve(context.Background(), "secretsmanager://aegis/openai#api_key")
+	if err != nil || got != "sk-aws" {
+		t.Fatalf("expected sk-aws, got %q err=%v", got, err)
+	}
+	if !strings.HasPrefix(lastAuth, "AWS4-HMAC-SHA256 Credential=AKIDTEST/20260102/us-east-1/secretsmanager/aws4_request") {
+		t.Errorf("unexpected Authorization header %q", lastAuth)
+	}
+
+	if _, err := a.Resolve(context.Background(), "secretsmanager://arn:aws:secretsmanager:eu-west-1:123456789012:secret:aegis/openai-AbCdEf#api_key"); err != nil {
+		t.Fatalf("resolve by ARN: %v", err)
+	}
+	if !strings.Contains(lastAuth, "/eu-west-1/secretsmanager/") {
+		t.Errorf("expected ARN region in signing scope, got %q", lastAuth)
+	}
+
+	if got, err := a.Resolve(context.Background(), "secretsmanager://aegis/plain"); err != nil || got != "raw-bytes" {
+		t.Errorf("expected binary secret, got %q err=%v", got, err)
+	}
+	if _, err := a.Resolve(context.Background(), "secretsmanager://aegis/missing"); err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Errorf("expected status error for missing secret, got %v", err)
+	}
+}
+
+func TestAWSSecretsManagerResolver_RequiresCredentials(t *testing.T) {
+	a := NewAWSSecretsManagerResolver(AWSConfig{Region: "us-east-1"}, nil)
+	if _, err := a.Resolve(context.Background(), "secretsmanager://aegis/openai"); err == nil {
+		t.Error("expected error without AWS credentials")
+	}
+}
+
+func TestGCPSecretManagerResolver_Resolve(t *testing.T) {
+	tokenFetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			tokenFetches++
+			_, _ = w.Write([]byte(`{"access_token":"ya29.test","expires_in":3600}`))
+		case r.URL.Path == "/v1/projects/p1/secrets/aegis/versions/latest:access":
+			if r.Header.Get("Authorization") != "Bearer ya29.test" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			payload := base64.StdEncoding.EncodeToString([]byte(`{"db":"pw-gcp"}`))
+			_, _ = w.Write([]byte(`{"payload":{"data":"` + payload + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	g := NewGCPSecretManagerResolver(GCPConfig{Endpoint: srv.URL, MetadataURL: srv.URL}, nil)
+
+	for i := 0; i < 2; i++ {
+		got, err := g.Resolve(context.Background(), "gcpsm://projects/p1/secrets/aegis#db")
+		if err != nil || got != "pw-gcp" {
+			t.Fatalf("expected pw-gcp, got %q err=%v", got, err)
+		}
+	}
+	if tokenFetches != 1 {
+		t.Errorf("expected metadata token to be cached, fetched %d times", tokenFetches)
+	}
+
+	if _, err := g.Resolve(context.Background(), "gcpsm://aegis"); err == nil {
+		t.Error("expected error for malformed reference")
+	}
+}
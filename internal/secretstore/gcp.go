@@ -0,0 +1,150 @@
+package secretstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GCPConfig configures the GCP Secret Manager resolver.
+type GCPConfig struct {
+	// AccessToken is a static OAuth2 token. When empty, tokens are fetched
+	// from the GCE/GKE metadata server for the default service account.
+	AccessToken string
+	// Endpoint overrides https://secretmanager.googleapis.com.
+	Endpoint string
+	// MetadataURL overrides http://metadata.google.internal.
+	MetadataURL string
+	Timeout     time.Duration
+}
+
+// GCPConfigFromEnv reads GOOGLE_OAUTH_ACCESS_TOKEN; everything else defaults.
+func GCPConfigFromEnv() GCPConfig {
+	return GCPConfig{
+		AccessToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		Endpoint:    "https://secretmanager.googleapis.com",
+		MetadataURL: "http://metadata.google.internal",
+		Timeout:     10 * time.Second,
+	}
+}
+
+// GCPSecretManagerResolver resolves
+// "gcpsm://projects/<project>/secrets/<name>[/versions/<version>][#<json-key>]"
+// references. The version defaults to "latest".
+type GCPSecretManagerResolver struct {
+	cfg    GCPConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewGCPSecretManagerResolver creates a GCP Secret Manager resolver. A nil
+// client uses a default client with cfg.Timeout.
+func NewGCPSecretManagerResolver(cfg GCPConfig, client *http.Client) *GCPSecretManagerResolver {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &GCPSecretManagerResolver{cfg: cfg, client: client, now: time.Now}
+}
+
+// Scheme implements config.SecretResolver.
+func (g *GCPSecretManagerResolver) Scheme() string { return "gcpsm" }
+
+// Resolve implements config.SecretResolver.
+func (g *GCPSecretManagerResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	name, field, _ := strings.Cut(strings.TrimPrefix(ref, "gcpsm://"), "#")
+	if !strings.HasPrefix(name, "projects/") || !strings.Contains(name, "/secrets/") {
+		return "", fmt.Errorf("invalid gcp secret reference %q: want gcpsm://projects/<project>/secrets/<name>[/versions/<v>][#<key>]", ref)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	url := strings.TrimRight(g.cfg.Endpoint, "/") + "/v1/" + name + ":access"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("create secret manager request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secret manager access %s: %w", name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("secret manager access %s returned status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("decode secret manager response: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(out.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("decode secret payload for %s: %w", name, err)
+	}
+	return extractField(name, string(raw), field)
+}
+
+// accessToken returns the static token, or a cached metadata-server token
+// that is refreshed a minute before it expires.
+func (g *GCPSecretManagerResolver) accessToken(ctx context.Context) (string, error) {
+	if g.cfg.AccessToken != "" {
+		return g.cfg.AccessToken, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && g.now().Before(g.tokenExpiry.Add(-time.Minute)) {
+		return g.token, nil
+	}
+
+	url := strings.TrimRight(g.cfg.MetadataURL, "/") + "/computeMetadata/v1/instance/service-accounts/default/token"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("create metadata token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fetch gcp access token from metadata server: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server token request returned status %d", resp.StatusCode)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("decode metadata token response: %w", err)
+	}
+	g.token = tok.AccessToken
+	g.tokenExpiry = g.now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return g.token, nil
+}
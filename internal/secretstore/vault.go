@@ -0,0 +1,194 @@
+// Package secretstore provides config.SecretResolver backends that fetch
+// credentials from external secret stores at load time, so long-lived keys
+// never have to live in config files or the process environment.
+package secretstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultConfig configures the Vault resolver.
+type VaultConfig struct {
+	// Address is the Vault server URL, e.g. "https://vault.internal:8200".
+	Address string
+	// Token authenticates requests. Ignored when TokenFile is set.
+	Token string
+	// TokenFile is re-read on every request, for tokens written by Vault Agent.
+	TokenFile string
+	// Namespace is sent as X-Vault-Namespace (Vault Enterprise).
+	Namespace string
+	Timeout   time.Duration
+}
+
+// VaultConfigFromEnv reads the standard VAULT_ADDR, VAULT_TOKEN, and
+// VAULT_NAMESPACE variables, plus VAULT_TOKEN_FILE for Vault Agent sinks.
+func VaultConfigFromEnv() VaultConfig {
+	return VaultConfig{
+		Address:   os.Getenv("VAULT_ADDR"),
+		Token:     os.Getenv("VAULT_TOKEN"),
+		TokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+		Timeout:   10 * time.Second,
+	}
+}
+
+// VaultResolver resolves "vault://<path>#<field>" references against Vault's
+// HTTP API. <path> is the API path without the /v1/ prefix, so KV v2 secrets
+// look like "vault://secret/data/aegis/providers#openai_api_key". Both KV v1
+// and v2 responses are understood.
+type VaultResolver struct {
+	cfg    VaultConfig
+	client *http.Client
+
+	mu    sync.RWMutex
+	token string
+}
+
+// NewVaultResolver creates a Vault resolver. A nil client uses a default
+// client with cfg.Timeout.
+func NewVaultResolver(cfg VaultConfig, client *http.Client) *VaultResolver {
+	if client == nil {
+		client = &http.Client{Timeout: cfg.Timeout}
+	}
+	return &VaultResolver{cfg: cfg, client: client, token: cfg.Token}
+}
+
+// Scheme implements config.SecretResolver.
+func (v *VaultResolver) Scheme() string { return "vault" }
+
+// Resolve implements config.SecretResolver.
+func (v *VaultResolver) Resolve(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(strings.TrimPrefix(ref, "vault://"), "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("invalid vault reference %q: want vault://<path>#<field>", ref)
+	}
+
+	var body struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, path, &body); err != nil {
+		return "", err
+	}
+
+	data := body.Data
+	// KV v2 nests the secret under data.data alongside data.metadata.
+	if inner, ok := data["data"].(map[string]any); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+
+	val, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s field %q is not a string", path, field)
+	}
+	return s, nil
+}
+
+// RenewToken renews the resolver's token and returns its new TTL. Tokens read
+// from TokenFile are renewed by Vault Agent and are left alone.
+func (v *VaultResolver) RenewToken(ctx context.Context) (time.Duration, error) {
+	if v.cfg.TokenFile != "" {
+		return 0, nil
+	}
+	var body struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+			Renewable     bool   `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := v.do(ctx, http.MethodPost, "auth/token/renew-self", &body); err != nil {
+		return 0, err
+	}
+	if body.Auth.ClientToken != "" {
+		v.mu.Lock()
+		v.token = body.Auth.ClientToken
+		v.mu.Unlock()
+	}
+	if !body.Auth.Renewable {
+		return 0, nil
+	}
+	return time.Duration(body.Auth.LeaseDuration) * time.Second, nil
+}
+
+// StartRenewal renews the token at half its TTL until ctx is done. It stops
+// when the token is not renewable (root tokens, or a zero TTL).
+func (v *VaultResolver) StartRenewal(ctx context.Context, logger *slog.Logger) {
+	go func() {
+		for {
+			ttl, err := v.RenewToken(ctx)
+			wait := ttl / 2
+			if err != nil {
+				logger.Warn("vault token renewal failed", "error", err)
+				wait = time.Minute
+			} else if ttl <= 0 {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+}
+
+func (v *VaultResolver) currentToken() (string, error) {
+	if v.cfg.TokenFile != "" {
+		data, err := os.ReadFile(v.cfg.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("read vault token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.token, nil
+}
+
+// do calls the Vault API at /v1/<path> and decodes the JSON response into dest.
+func (v *VaultResolver) do(ctx context.Context, method, path string, dest any) error {
+	token, err := v.currentToken()
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimRight(v.cfg.Address, "/") + "/v1/" + strings.TrimLeft(path, "/")
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return fmt.Errorf("create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault %s %s returned status %d: %s", method, path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("decode vault response: %w", err)
+	}
+	return nil
+}
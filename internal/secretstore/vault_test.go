@@ -0,0 +1,102 @@
+package secretstore
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestVault(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/aegis/providers":
+			_, _ = w.Write([]byte(`{"data":{"data":{"openai":"sk-v2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/aegis":
+			_, _ = w.Write([]byte(`{"data":{"db_password":"pw-v1","port":5432}}`))
+		case "/v1/auth/token/renew-self":
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"` + token + `","lease_duration":3600,"renewable":true}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+}
+
+func TestVaultResolver_Resolve(t *testing.T) {
+	srv := newTestVault(t, "s.test")
+	defer srv.Close()
+	v := NewVaultResolver(VaultConfig{Address: srv.URL, Token: "s.test"}, nil)
+
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr string
+	}{
+		{ref: "vault://secret/data/aegis/providers#openai", want: "sk-v2"},
+		{ref: "vault://kv/aegis#db_password", want: "pw-v1"},
+		{ref: "vault://kv/aegis#missing", wantErr: `no field "missing"`},
+		{ref: "vault://kv/aegis#port", wantErr: "not a string"},
+		{ref: "vault://kv/nope#x", wantErr: "status 404"},
+		{ref: "vault://kv/aegis", wantErr: "invalid vault reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := v.Resolve(context.Background(), tt.ref)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestVaultResolver_TokenFile(t *testing.T) {
+	srv := newTestVault(t, "s.agent")
+	defer srv.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("s.agent\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v := NewVaultResolver(VaultConfig{Address: srv.URL, Token: "ignored", TokenFile: tokenFile}, nil)
+
+	if _, err := v.Resolve(context.Background(), "vault://kv/aegis#db_password"); err != nil {
+		t.Fatalf("expected token file to be used, got %v", err)
+	}
+	if ttl, err := v.RenewToken(context.Background()); err != nil || ttl != 0 {
+		t.Errorf("expected agent-managed token to skip renewal, got ttl=%v err=%v", ttl, err)
+	}
+}
+
+func TestVaultResolver_RenewToken(t *testing.T) {
+	srv := newTestVault(t, "s.test")
+	defer srv.Close()
+	v := NewVaultResolver(VaultConfig{Address: srv.URL, Token: "s.test"}, nil)
+
+	ttl, err := v.RenewToken(context.Background())
+	if err != nil {
+		t.Fatalf("RenewToken: %v", err)
+	}
+	if ttl != time.Hour {
+		t.Errorf("expected 1h TTL, got %v", ttl)
+	}
+}
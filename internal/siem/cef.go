@@ -0,0 +1,71 @@
+package siem
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	cefVendor  = "AEGIS"
+	cefProduct = "ai-gateway"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// formatCEF renders an event in ArcSight Common Event Format:
+// CEF:Version|Vendor|Product|DeviceVersion|SignatureID|Name|Severity|Extension
+func formatCEF(ev Event, version string) string {
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	b.WriteString(cefHeaderEscaper.Replace(cefVendor))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(cefProduct))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(version))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(ev.EventType))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(ev.Message))
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(ev.SeverityScore))
+	b.WriteByte('|')
+
+	ext := []struct{ key, value string }{
+		{"rt", strconv.FormatInt(ev.Timestamp.UnixMilli(), 10)},
+		{"src", ev.SourceIP},
+		{"requestClientApplication", ev.UserAgent},
+		{"outcome", strconv.Itoa(ev.StatusCode)},
+		{"cs1Label", "orgId"},
+		{"cs1", ev.OrganizationID},
+		{"cs2Label", "teamId"},
+		{"cs2", ev.TeamID},
+		{"cs3Label", "requestId"},
+		{"cs3", ev.RequestID},
+		{"cs4Label", "apiKeyId"},
+		{"cs4", ev.APIKeyID},
+	}
+	if len(ev.Details) > 0 {
+		if details, err := json.Marshal(ev.Details); err == nil {
+			ext = append(ext, struct{ key, value string }{"cs5Label", "details"}, struct{ key, value string }{"cs5", string(details)})
+		}
+	}
+
+	first := true
+	for _, kv := range ext {
+		if kv.value == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(kv.key)
+		b.WriteByte('=')
+		b.WriteString(cefExtensionEscaper.Replace(kv.value))
+	}
+	return b.String()
+}
@@ -0,0 +1,33 @@
+package siem
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// nopCloser wraps stdout/stderr so closing the output doesn't close the process streams.
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// OpenOutput opens the destination configured for the security event stream:
+// "stdout", "stderr", "syslog", or a file path (appended to).
+func OpenOutput(cfg config.SIEMConfig) (io.WriteCloser, error) {
+	switch cfg.Output {
+	case "", "stdout":
+		return nopCloser{os.Stdout}, nil
+	case "stderr":
+		return nopCloser{os.Stderr}, nil
+	case "syslog":
+		return openSyslog(cfg.SyslogNetwork, cfg.SyslogAddress, cfg.SyslogTag)
+	default:
+		f, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("open security log %s: %w", cfg.Output, err)
+		}
+		return f, nil
+	}
+}
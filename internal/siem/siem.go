@@ -0,0 +1,131 @@
+// Package siem emits security-relevant gateway events as a dedicated stream,
+// separate from request logs, in a schema suited to SIEM detection rules.
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/audit"
+)
+
+// Severity ranks security events. Values follow the CEF 0-10 scale.
+type Severity int
+
+const (
+	SeverityLow      Severity = 3
+	SeverityMedium   Severity = 5
+	SeverityHigh     Severity = 7
+	SeverityCritical Severity = 9
+)
+
+func (s Severity) String() string {
+	switch {
+	case s >= SeverityCritical:
+		return "critical"
+	case s >= SeverityHigh:
+		return "high"
+	case s >= SeverityMedium:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// severities maps forwarded audit event types to severity. Event types not
+// listed here (e.g. request_complete, redis_failure) are not security events
+// and are dropped.
+var severities = map[audit.EventType]Severity{
+	audit.EventAuthFailure:             SeverityMedium,
+	audit.EventFilterBlock:             SeverityHigh,
+	audit.EventFilterBypass:            SeverityHigh,
+	audit.EventPolicyDenial:            SeverityMedium,
+	audit.EventClassificationViolation: SeverityHigh,
+	audit.EventConfigChange:            SeverityMedium,
+	audit.EventRateLimitViolation:      SeverityLow,
+	audit.EventBudgetViolation:         SeverityLow,
+}
+
+// Event is the security event schema written to the stream.
+type Event struct {
+	Timestamp      time.Time              `json:"timestamp"`
+	EventType      string                 `json:"event_type"`
+	Severity       string                 `json:"severity"`
+	SeverityScore  int                    `json:"severity_score"`
+	RequestID      string                 `json:"request_id"`
+	OrganizationID string                 `json:"org_id,omitempty"`
+	TeamID         string                 `json:"team_id,omitempty"`
+	APIKeyID       string                 `json:"api_key_id,omitempty"`
+	SourceIP       string                 `json:"src_ip,omitempty"`
+	UserAgent      string                 `json:"user_agent,omitempty"`
+	StatusCode     int                    `json:"status_code"`
+	Message        string                 `json:"message"`
+	Details        map[string]interface{} `json:"details,omitempty"`
+}
+
+// Logger writes security events to an output in JSON lines or CEF.
+// It implements audit.SecuritySink.
+type Logger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	format  string
+	version string
+}
+
+// NewLogger creates a security event logger. Format is "json" or "cef";
+// version is reported as the CEF device version.
+func NewLogger(out io.Writer, format, version string) *Logger {
+	return &Logger{out: out, format: format, version: version}
+}
+
+// LogSecurityEvent converts an audit event to the security schema and writes it.
+// Non-security audit events are ignored.
+func (l *Logger) LogSecurityEvent(ae audit.Event) {
+	severity, ok := severities[ae.EventType]
+	if !ok {
+		return
+	}
+	ev := Event{
+		Timestamp:      ae.Timestamp.UTC(),
+		EventType:      string(ae.EventType),
+		Severity:       severity.String(),
+		SeverityScore:  int(severity),
+		RequestID:      ae.RequestID,
+		OrganizationID: ae.OrganizationID,
+		TeamID:         ae.TeamID,
+		SourceIP:       ae.IPAddress,
+		UserAgent:      ae.UserAgent,
+		StatusCode:     ae.StatusCode,
+		Message:        ae.ErrorMessage,
+		Details:        ae.Metadata,
+	}
+	if ae.APIKeyID != nil {
+		ev.APIKeyID = *ae.APIKeyID
+	}
+	if err := l.Write(ev); err != nil {
+		slog.Error("failed to write security event", "error", err, "request_id", ev.RequestID, "event_type", ev.EventType)
+	}
+}
+
+// Write formats and writes a single event.
+func (l *Logger) Write(ev Event) error {
+	var line []byte
+	if l.format == "cef" {
+		line = []byte(formatCEF(ev, l.version) + "\n")
+	} else {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return fmt.Errorf("marshal security event: %w", err)
+		}
+		line = append(data, '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err := l.out.Write(line)
+	return err
+}
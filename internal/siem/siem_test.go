@@ -0,0 +1,100 @@
+package siem
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/audit"
+)
+
+func testAuditEvent(eventType audit.EventType) audit.Event {
+	keyID := "key-1"
+	return audit.Event{
+		RequestID:      "req_123",
+		Timestamp:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		EventType:      eventType,
+		OrganizationID: "org-1",
+		TeamID:         "team-1",
+		APIKeyID:       &keyID,
+		IPAddress:      "10.0.0.1",
+		StatusCode:     451,
+		ErrorMessage:   "Content blocked by secrets filter",
+		Metadata:       map[string]interface{}{"filter_type": "secrets"},
+	}
+}
+
+func TestLogSecurityEvent_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "json", "1.0.0")
+	l.LogSecurityEvent(testAuditEvent(audit.EventFilterBlock))
+
+	var ev Event
+	if err := json.Unmarshal(buf.Bytes(), &ev); err != nil {
+		t.Fatalf("expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if ev.EventType != "filter_block" || ev.Severity != "high" || ev.SeverityScore != int(SeverityHigh) {
+		t.Errorf("unexpected type/severity: %+v", ev)
+	}
+	if ev.APIKeyID != "key-1" || ev.SourceIP != "10.0.0.1" || ev.RequestID != "req_123" {
+		t.Errorf("unexpected identity fields: %+v", ev)
+	}
+}
+
+func TestLogSecurityEvent_IgnoresNonSecurityEvents(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "json", "1.0.0")
+	l.LogSecurityEvent(testAuditEvent(audit.EventRequestComplete))
+	l.LogSecurityEvent(testAuditEvent(audit.EventRedisFailure))
+	if buf.Len() != 0 {
+		t.Errorf("expected no output for non-security events, got %q", buf.String())
+	}
+}
+
+func TestLogSecurityEvent_CEF(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf, "cef", "1.0.0")
+	l.LogSecurityEvent(testAuditEvent(audit.EventClassificationViolation))
+
+	line := strings.TrimSuffix(buf.String(), "\n")
+	prefix := "CEF:0|AEGIS|ai-gateway|1.0.0|classification_violation|Content blocked by secrets filter|7|"
+	if !strings.HasPrefix(line, prefix) {
+		t.Fatalf("unexpected CEF header: %q", line)
+	}
+	for _, want := range []string{"rt=1767323045000", "src=10.0.0.1", "cs1=org-1", "cs3=req_123", "cs4=key-1", `cs5={"filter_type":"secrets"}`} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected %q in %q", want, line)
+		}
+	}
+}
+
+func TestFormatCEF_Escaping(t *testing.T) {
+	line := formatCEF(Event{
+		EventType:      "policy_denial",
+		Message:        `denied | reason`,
+		SeverityScore:  5,
+		OrganizationID: "a=b\\c",
+	}, "1.0")
+	if !strings.Contains(line, `|denied \| reason|`) {
+		t.Errorf("expected escaped pipe in header, got %q", line)
+	}
+	if !strings.Contains(line, `cs1=a\=b\\c`) {
+		t.Errorf("expected escaped extension value, got %q", line)
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	tests := map[Severity]string{
+		SeverityLow:      "low",
+		SeverityMedium:   "medium",
+		SeverityHigh:     "high",
+		SeverityCritical: "critical",
+	}
+	for sev, want := range tests {
+		if got := sev.String(); got != want {
+			t.Errorf("Severity(%d).String() = %q, want %q", sev, got, want)
+		}
+	}
+}
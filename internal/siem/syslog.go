@@ -0,0 +1,18 @@
+//go:build !windows && !plan9
+
+package siem
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+)
+
+// openSyslog dials syslog. An empty network uses the local syslog daemon.
+func openSyslog(network, address, tag string) (io.WriteCloser, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_WARNING|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, fmt.Errorf("dial syslog: %w", err)
+	}
+	return w, nil
+}
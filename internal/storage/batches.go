@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Batch job and item states.
+const (
+	BatchQueued     = "queued"
+	BatchInProgress = "in_progress"
+	BatchCompleted  = "completed"
+	BatchCancelled  = "cancelled"
+	BatchExpired    = "expired"
+
+	BatchItemPending   = "pending"
+	BatchItemRunning   = "running"
+	BatchItemSucceeded = "succeeded"
+	BatchItemFailed    = "failed"
+)
+
+// ErrBatchNotFound is returned for an unknown batch ID.
+var ErrBatchNotFound = errors.New("batch not found")
+
+// BatchJob is an asynchronous batch of chat completion requests. Principal is
+// the submitting key's identity and limits, stored opaquely for the worker.
+type BatchJob struct {
+	ID                string          `json:"id"`
+	OrganizationID    string          `json:"organization_id"`
+	TeamID            string          `json:"team_id"`
+	APIKeyID          string          `json:"api_key_id"`
+	Principal         json.RawMessage `json:"-"`
+	Status            string          `json:"status"`
+	TotalRequests     int             `json:"total_requests"`
+	CompletedRequests int             `json:"completed_requests"`
+	FailedRequests    int             `json:"failed_requests"`
+	CostUSD           float64         `json:"cost_usd"`
+	CreatedAt         time.Time       `json:"created_at"`
+	ExpiresAt         time.Time       `json:"expires_at"`
+	CompletedAt       *time.Time      `json:"completed_at,omitempty"`
+}
+
+// BatchItem is one request of a batch. Response holds the completion or the
+// error body once the item has finished.
+type BatchItem struct {
+	BatchID    string          `json:"-"`
+	Line       int             `json:"-"`
+	CustomID   string          `json:"custom_id"`
+	Body       json.RawMessage `json:"-"`
+	Status     string          `json:"status"`
+	StatusCode int             `json:"status_code"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	CostUSD    float64         `json:"cost_usd"`
+}
+
+// BatchStore persists batch jobs in Postgres. Items are claimed with
+// FOR UPDATE SKIP LOCKED, so workers on several replicas share the queue.
+type BatchStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewBatchStore creates a batch store.
+func NewBatchStore(pool *pgxpool.Pool) *BatchStore {
+	return &BatchStore{pool: pool}
+}
+
+// CreateBatch inserts a job and its items atomically.
+func (s *BatchStore) CreateBatch(ctx context.Context, job BatchJob, items []BatchItem) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin batch insert: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO batches (id, organization_id, team_id, api_key_id, principal, status, total_requests, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		job.ID, job.OrganizationID, job.TeamID, job.APIKeyID, job.Principal, BatchQueued, len(items), job.CreatedAt, job.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("insert batch: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"batch_items"}, []string{"batch_id", "line", "custom_id", "body"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			return []any{job.ID, items[i].Line, items[i].CustomID, items[i].Body}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("insert batch items: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+const batchColumns = `id, organization_id, team_id, api_key_id, principal, status, total_requests,
+	completed_requests, failed_requests, cost_usd, created_at, expires_at, completed_at`
+
+func scanBatch(row pgx.Row) (*BatchJob, error) {
+	var b BatchJob
+	err := row.Scan(&b.ID, &b.OrganizationID, &b.TeamID, &b.APIKeyID, &b.Principal, &b.Status, &b.TotalRequests,
+		&b.CompletedRequests, &b.FailedRequests, &b.CostUSD, &b.CreatedAt, &b.ExpiresAt, &b.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrBatchNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// GetBatch returns a job by ID.
+func (s *BatchStore) GetBatch(ctx context.Context, id string) (*BatchJob, error) {
+	return scanBatch(s.pool.QueryRow(ctx, `SELECT `+batchColumns+` FROM batches WHERE id = $1`, id))
+}
+
+// BatchResults returns the finished items of a job in submission order.
+func (s *BatchStore) BatchResults(ctx context.Context, id string) ([]BatchItem, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT line, custom_id, status, COALESCE(status_code, 0), response, cost_usd
+		FROM batch_items
+		WHERE batch_id = $1 AND status IN ($2, $3)
+		ORDER BY line`, id, BatchItemSucceeded, BatchItemFailed)
+	if err != nil {
+		return nil, fmt.Errorf("query batch results: %w", err)
+	}
+	defer rows.Close()
+
+	var items []BatchItem
+	for rows.Next() {
+		it := BatchItem{BatchID: id}
+		if err := rows.Scan(&it.Line, &it.CustomID, &it.Status, &it.StatusCode, &it.Response, &it.CostUSD); err != nil {
+			return nil, fmt.Errorf("scan batch result: %w", err)
+		}
+		items = append(items, it)
+	}
+	return items, rows.Err()
+}
+
+// CancelBatch stops a job that has not finished; items already running
+// complete, the rest are never claimed.
+func (s *BatchStore) CancelBatch(ctx context.Context, id string) (*BatchJob, error) {
+	return scanBatch(s.pool.QueryRow(ctx, `
+		UPDATE batches SET status = $2, completed_at = NOW()
+		WHERE id = $1 AND status IN ($3, $4)
+		RETURNING `+batchColumns, id, BatchCancelled, BatchQueued, BatchInProgress))
+}
+
+// ClaimItem marks the oldest pending item of a live job as running and
+// returns it, skipping jobs of the given organizations. It returns nil when
+// there is nothing to do.
+func (s *BatchStore) ClaimItem(ctx context.Context, skipOrgs []string) (*BatchItem, error) {
+	if skipOrgs == nil {
+		skipOrgs = []string{}
+	}
+	it := BatchItem{Status: BatchItemRunning}
+	err := s.pool.QueryRow(ctx, `
+		UPDATE batch_items SET status = $1, updated_at = NOW()
+		WHERE (batch_id, line) = (
+			SELECT i.batch_id, i.line
+			FROM batch_items i JOIN batches b ON b.id = i.batch_id
+			WHERE i.status = $2
+			  AND b.status IN ($3, $4)
+			  AND b.expires_at > NOW()
+			  AND NOT b.organization_id = ANY($5)
+			ORDER BY b.created_at, i.line
+			LIMIT 1
+			FOR UPDATE OF i SKIP LOCKED)
+		RETURNING batch_id, line, custom_id, body`,
+		BatchItemRunning, BatchItemPending, BatchQueued, BatchInProgress, skipOrgs,
+	).Scan(&it.BatchID, &it.Line, &it.CustomID, &it.Body)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim batch item: %w", err)
+	}
+	_, err = s.pool.Exec(ctx, `UPDATE batches SET status = $2 WHERE id = $1 AND status = $3`,
+		it.BatchID, BatchInProgress, BatchQueued)
+	if err != nil {
+		return nil, fmt.Errorf("start batch: %w", err)
+	}
+	return &it, nil
+}
+
+// ReleaseItem returns a claimed item to the queue, e.g. while its team is
+// over budget.
+func (s *BatchStore) ReleaseItem(ctx context.Context, batchID string, line int) error {
+	_, err := s.pool.Exec(ctx, `
+		UPDATE batch_items SET status = $3, updated_at = NOW()
+		WHERE batch_id = $1 AND line = $2 AND status = $4`,
+		batchID, line, BatchItemPending, BatchItemRunning)
+	if err != nil {
+		return fmt.Errorf("release batch item: %w", err)
+	}
+	return nil
+}
+
+// CompleteItem records an item's outcome, updates the job's counters and
+// cost, and completes the job once no item is left to run.
+func (s *BatchStore) CompleteItem(ctx context.Context, item BatchItem) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin batch item update: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE batch_items SET status = $3, status_code = $4, response = $5, cost_usd = $6, updated_at = NOW()
+		WHERE batch_id = $1 AND line = $2 AND status = $7`,
+		item.BatchID, item.Line, item.Status, item.StatusCode, item.Response, item.CostUSD, BatchItemRunning)
+	if err != nil {
+		return fmt.Errorf("update batch item: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		// Reset as stale and finished elsewhere; counting it again would
+		// skew the totals.
+		return nil
+	}
+
+	completed, failed := 1, 0
+	if item.Status != BatchItemSucceeded {
+		completed, failed = 0, 1
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE batches SET
+			completed_requests = completed_requests + $2,
+			failed_requests = failed_requests + $3,
+			cost_usd = cost_usd + $4,
+			status = CASE WHEN status = $5 AND completed_requests + $2 + failed_requests + $3 >= total_requests THEN $6 ELSE status END,
+			completed_at = CASE WHEN status = $5 AND completed_requests + $2 + failed_requests + $3 >= total_requests THEN NOW() ELSE completed_at END
+		WHERE id = $1`,
+		item.BatchID, completed, failed, item.CostUSD, BatchInProgress, BatchCompleted)
+	if err != nil {
+		return fmt.Errorf("update batch totals: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// ResetStaleItems returns items claimed before cutoff by a worker that never
+// finished them (a replica that died mid-request) to the queue.
+func (s *BatchStore) ResetStaleItems(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx, `
+		UPDATE batch_items SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND updated_at < $3`,
+		BatchItemPending, BatchItemRunning, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("reset stale batch items: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExpireBatches ends jobs past their completion window, failing the items
+// that never ran.
+func (s *BatchStore) ExpireBatches(ctx context.Context, now time.Time) (int64, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin batch expiry: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	rows, err := tx.Query(ctx, `
+		UPDATE batches SET status = $1, completed_at = $2
+		WHERE status IN ($3, $4) AND expires_at <= $2
+		RETURNING id`, BatchExpired, now, BatchQueued, BatchInProgress)
+	if err != nil {
+		return 0, fmt.Errorf("expire batches: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return 0, fmt.Errorf("expire batches: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		WITH expired AS (
+			UPDATE batch_items SET status = $2, response = $3, updated_at = NOW()
+			WHERE batch_id = ANY($1) AND status = $4
+			RETURNING batch_id)
+		UPDATE batches b SET failed_requests = failed_requests + n.count
+		FROM (SELECT batch_id, COUNT(*) AS count FROM expired GROUP BY batch_id) n
+		WHERE b.id = n.batch_id`,
+		ids, BatchItemFailed, json.RawMessage(`{"message":"Batch expired before this request ran","type":"batch_error","code":"batch_expired"}`), BatchItemPending)
+	if err != nil {
+		return 0, fmt.Errorf("fail expired batch items: %w", err)
+	}
+	return int64(len(ids)), tx.Commit(ctx)
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrConversationNotFound is returned for an unknown conversation ID.
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// Conversation aggregates the turns sent with one X-Aegis-Conversation-ID.
+// TeamID and APIKeyID are those of the first turn.
+type Conversation struct {
+	ID               string    `json:"id"`
+	OrganizationID   string    `json:"organization_id"`
+	TeamID           string    `json:"team_id"`
+	APIKeyID         string    `json:"api_key_id"`
+	Turns            int       `json:"turns"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	Models           []string  `json:"models"`
+	FirstSeenAt      time.Time `json:"first_seen_at"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+}
+
+// TotalTokens returns prompt plus completion tokens.
+func (c *Conversation) TotalTokens() int64 {
+	return c.PromptTokens + c.CompletionTokens
+}
+
+// ConversationTurn is one completed request of a conversation.
+type ConversationTurn struct {
+	ConversationID   string
+	OrganizationID   string
+	TeamID           string
+	APIKeyID         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+}
+
+// ConversationStore persists per-conversation metadata in Postgres.
+type ConversationStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewConversationStore creates a conversation store.
+func NewConversationStore(pool *pgxpool.Pool) *ConversationStore {
+	return &ConversationStore{pool: pool}
+}
+
+// RecordTurn adds a turn to its conversation, creating the conversation on
+// its first turn.
+func (s *ConversationStore) RecordTurn(ctx context.Context, t ConversationTurn) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO conversations AS c (organization_id, id, team_id, api_key_id, turns,
+			prompt_tokens, completion_tokens, cost_usd, models)
+		VALUES ($1, $2, $3, $4, 1, $5, $6, $7, ARRAY[$8]::TEXT[])
+		ON CONFLICT (organization_id, id) DO UPDATE SET
+			turns = c.turns + 1,
+			prompt_tokens = c.prompt_tokens + EXCLUDED.prompt_tokens,
+			completion_tokens = c.completion_tokens + EXCLUDED.completion_tokens,
+			cost_usd = c.cost_usd + EXCLUDED.cost_usd,
+			models = CASE WHEN $8 = ANY(c.models) THEN c.models ELSE array_append(c.models, $8) END,
+			last_seen_at = NOW()`,
+		t.OrganizationID, t.ConversationID, t.TeamID, t.APIKeyID,
+		t.PromptTokens, t.CompletionTokens, t.CostUSD, t.Model)
+	if err != nil {
+		return fmt.Errorf("record conversation turn: %w", err)
+	}
+	return nil
+}
+
+const conversationColumns = `id, organization_id, team_id, api_key_id, turns, prompt_tokens,
+	completion_tokens, cost_usd, models, first_seen_at, last_seen_at`
+
+func scanConversation(row pgx.Row) (*Conversation, error) {
+	var c Conversation
+	err := row.Scan(&c.ID, &c.OrganizationID, &c.TeamID, &c.APIKeyID, &c.Turns, &c.PromptTokens,
+		&c.CompletionTokens, &c.CostUSD, &c.Models, &c.FirstSeenAt, &c.LastSeenAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// GetConversation returns an organization's conversation by ID.
+func (s *ConversationStore) GetConversation(ctx context.Context, orgID, id string) (*Conversation, error) {
+	return scanConversation(s.pool.QueryRow(ctx,
+		`SELECT `+conversationColumns+` FROM conversations WHERE organization_id = $1 AND id = $2`, orgID, id))
+}
+
+// ListConversations returns an organization's most recently active
+// conversations, newest first.
+func (s *ConversationStore) ListConversations(ctx context.Context, orgID string, limit int) ([]Conversation, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+conversationColumns+` FROM conversations
+		WHERE organization_id = $1
+		ORDER BY last_seen_at DESC
+		LIMIT $2`, orgID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Conversation
+	for rows.Next() {
+		c, err := scanConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan conversation: %w", err)
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Dashboard time buckets for the trend queries.
+const (
+	BucketHour = "hour"
+	BucketDay  = "day"
+)
+
+// DashboardQuery selects the rows a dashboard aggregate covers. Empty
+// OrganizationID covers every organization. Limit and Offset page through
+// the aggregated rows, not the underlying requests.
+type DashboardQuery struct {
+	OrganizationID string
+	From, To       time.Time
+	Limit, Offset  int
+}
+
+// ModelSpend is one model's share of spend.
+type ModelSpend struct {
+	Model        string  `json:"model"`
+	Requests     int64   `json:"requests"`
+	TotalTokens  int64   `json:"total_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+// FilterBlocks counts the requests one filter blocked.
+type FilterBlocks struct {
+	Filter        string `json:"filter"`
+	Blocks        int64  `json:"blocks"`
+	Organizations int64  `json:"organizations"`
+}
+
+// ProviderHealthPoint is one provider's outcomes during one bucket.
+type ProviderHealthPoint struct {
+	Provider     string    `json:"provider"`
+	BucketStart  time.Time `json:"bucket_start"`
+	Requests     int64     `json:"requests"`
+	Errors       int64     `json:"errors"`
+	ErrorRate    float64   `json:"error_rate"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	P95LatencyMs float64   `json:"p95_latency_ms"`
+}
+
+// TeamUsagePoint is one team's usage during one bucket.
+type TeamUsagePoint struct {
+	OrganizationID string    `json:"organization_id"`
+	TeamID         string    `json:"team_id"`
+	BucketStart    time.Time `json:"bucket_start"`
+	Requests       int64     `json:"requests"`
+	TotalTokens    int64     `json:"total_tokens"`
+	CostUSD        float64   `json:"cost_usd"`
+}
+
+// ValidBucket reports whether bucket is BucketHour or BucketDay.
+func ValidBucket(bucket string) bool {
+	return bucket == BucketHour || bucket == BucketDay
+}
+
+// TopModelsBySpend returns requested models ordered by cost, highest first.
+func (r *UsageRecorder) TopModelsBySpend(ctx context.Context, q DashboardQuery) ([]ModelSpend, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT model_requested, COUNT(*), COALESCE(SUM(total_tokens), 0),
+			COALESCE(SUM(cost_usd), 0)::float8, COALESCE(AVG(duration_ms), 0)::float8
+		FROM request_usage
+		WHERE ($1 = '' OR organization_id = $1) AND completed_at >= $2 AND completed_at < $3
+		GROUP BY model_requested
+		ORDER BY 4 DESC, model_requested
+		LIMIT $4 OFFSET $5`, q.OrganizationID, q.From, q.To, q.Limit, q.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query model spend: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ModelSpend, error) {
+		var m ModelSpend
+		err := row.Scan(&m.Model, &m.Requests, &m.TotalTokens, &m.CostUSD, &m.AvgLatencyMs)
+		return m, err
+	})
+}
+
+// BlocksByFilter counts blocked requests per filter, most first. Blocked
+// requests never reach the usage ledger, so they are counted from the
+// filter_block and policy_denial audit events; policy denials count as the
+// "policy" filter.
+func (r *UsageRecorder) BlocksByFilter(ctx context.Context, q DashboardQuery) ([]FilterBlocks, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(metadata->>'filter_type', 'policy') AS filter, COUNT(*), COUNT(DISTINCT organization_id)
+		FROM audit_events
+		WHERE event_type IN ('filter_block', 'policy_denial')
+			AND ($1 = '' OR organization_id = $1) AND timestamp >= $2 AND timestamp < $3
+		GROUP BY filter
+		ORDER BY 2 DESC, filter
+		LIMIT $4 OFFSET $5`, q.OrganizationID, q.From, q.To, q.Limit, q.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query filter blocks: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (FilterBlocks, error) {
+		var f FilterBlocks
+		err := row.Scan(&f.Filter, &f.Blocks, &f.Organizations)
+		return f, err
+	})
+}
+
+// ProviderHealthHistory returns each provider's request outcomes per bucket,
+// oldest first. Responses with status 500 or above count as errors. An empty
+// provider covers every provider.
+func (r *UsageRecorder) ProviderHealthHistory(ctx context.Context, q DashboardQuery, bucket, provider string) ([]ProviderHealthPoint, error) {
+	if !ValidBucket(bucket) {
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT provider, date_trunc($6, completed_at) AS bucket, COUNT(*),
+			COUNT(*) FILTER (WHERE status_code >= 500),
+			COALESCE(AVG(duration_ms), 0)::float8,
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms), 0)::float8
+		FROM request_usage
+		WHERE ($1 = '' OR organization_id = $1) AND completed_at >= $2 AND completed_at < $3
+			AND ($7 = '' OR provider = $7)
+		GROUP BY provider, bucket
+		ORDER BY bucket, provider
+		LIMIT $4 OFFSET $5`, q.OrganizationID, q.From, q.To, q.Limit, q.Offset, bucket, provider)
+	if err != nil {
+		return nil, fmt.Errorf("query provider health: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (ProviderHealthPoint, error) {
+		var p ProviderHealthPoint
+		err := row.Scan(&p.Provider, &p.BucketStart, &p.Requests, &p.Errors, &p.AvgLatencyMs, &p.P95LatencyMs)
+		if p.Requests > 0 {
+			p.ErrorRate = float64(p.Errors) / float64(p.Requests)
+		}
+		return p, err
+	})
+}
+
+// TeamUsageTrend returns each team's usage per bucket, oldest first. An
+// empty team covers every team.
+func (r *UsageRecorder) TeamUsageTrend(ctx context.Context, q DashboardQuery, bucket, team string) ([]TeamUsagePoint, error) {
+	if !ValidBucket(bucket) {
+		return nil, fmt.Errorf("unknown bucket %q", bucket)
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT organization_id, team_id, date_trunc($6, completed_at) AS bucket, COUNT(*),
+			COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost_usd), 0)::float8
+		FROM request_usage
+		WHERE ($1 = '' OR organization_id = $1) AND completed_at >= $2 AND completed_at < $3
+			AND ($7 = '' OR team_id = $7)
+		GROUP BY organization_id, team_id, bucket
+		ORDER BY bucket, organization_id, team_id
+		LIMIT $4 OFFSET $5`, q.OrganizationID, q.From, q.To, q.Limit, q.Offset, bucket, team)
+	if err != nil {
+		return nil, fmt.Errorf("query team usage: %w", err)
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (TeamUsagePoint, error) {
+		var t TeamUsagePoint
+		err := row.Scan(&t.OrganizationID, &t.TeamID, &t.BucketStart, &t.Requests, &t.TotalTokens, &t.CostUSD)
+		return t, err
+	})
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Filter feedback review states.
+const (
+	FeedbackOpen     = "open"
+	FeedbackAccepted = "accepted"
+	FeedbackRejected = "rejected"
+)
+
+var (
+	// ErrBlockNotFound is returned when feedback names a request the
+	// organization had no filter block for.
+	ErrBlockNotFound = errors.New("no filter block for request")
+	// ErrFeedbackExists is returned when the block already has feedback.
+	ErrFeedbackExists = errors.New("feedback already submitted")
+	// ErrFeedbackNotFound is returned for an unknown feedback ID.
+	ErrFeedbackNotFound = errors.New("feedback not found")
+)
+
+// FilterFeedback is a user's report that a filter block was a false
+// positive. Detection is the block's audit metadata: filter_type, reason,
+// detections, and score.
+type FilterFeedback struct {
+	ID             string         `json:"id"`
+	RequestID      string         `json:"request_id"`
+	OrganizationID string         `json:"organization_id"`
+	TeamID         string         `json:"team_id"`
+	APIKeyID       string         `json:"api_key_id"`
+	Filter         string         `json:"filter"`
+	Detection      map[string]any `json:"detection"`
+	BlockedAt      time.Time      `json:"blocked_at"`
+	Comment        string         `json:"comment,omitempty"`
+	Status         string         `json:"status"`
+	ReviewNote     string         `json:"review_note,omitempty"`
+	ReviewedBy     string         `json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time     `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// FeedbackSubmission is a new report, from the caller's identity.
+type FeedbackSubmission struct {
+	ID             string
+	RequestID      string
+	OrganizationID string
+	TeamID         string
+	APIKeyID       string
+	Comment        string
+}
+
+// FeedbackFilter selects feedback for review. Empty fields match all.
+type FeedbackFilter struct {
+	OrganizationID string
+	Filter         string
+	Status         string
+	Limit          int
+}
+
+// FeedbackStore persists filter block feedback in Postgres.
+type FeedbackStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewFeedbackStore creates a feedback store.
+func NewFeedbackStore(pool *pgxpool.Pool) *FeedbackStore {
+	return &FeedbackStore{pool: pool}
+}
+
+const feedbackColumns = `id, request_id, organization_id, team_id, api_key_id, filter, detection,
+	blocked_at, COALESCE(comment, ''), status, COALESCE(review_note, ''), COALESCE(reviewed_by, ''),
+	reviewed_at, created_at`
+
+func scanFeedback(row pgx.Row) (*FilterFeedback, error) {
+	var f FilterFeedback
+	var detection []byte
+	err := row.Scan(&f.ID, &f.RequestID, &f.OrganizationID, &f.TeamID, &f.APIKeyID, &f.Filter, &detection,
+		&f.BlockedAt, &f.Comment, &f.Status, &f.ReviewNote, &f.ReviewedBy, &f.ReviewedAt, &f.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	_ = json.Unmarshal(detection, &f.Detection)
+	return &f, nil
+}
+
+// Submit records feedback on the organization's block of s.RequestID,
+// copying the block's filter and detection metadata from the audit trail.
+// It returns ErrBlockNotFound if the organization has no such block and
+// ErrFeedbackExists if the block already has feedback.
+func (s *FeedbackStore) Submit(ctx context.Context, sub FeedbackSubmission) (*FilterFeedback, error) {
+	f, err := scanFeedback(s.pool.QueryRow(ctx, `
+		INSERT INTO filter_feedback (id, request_id, organization_id, team_id, api_key_id,
+			filter, detection, blocked_at, comment)
+		SELECT $1, e.request_id, e.organization_id, $4, $5,
+			COALESCE(e.metadata->>'filter_type', 'policy'), e.metadata, e.timestamp, NULLIF($6, '')
+		FROM audit_events e
+		WHERE e.request_id = $2 AND e.organization_id = $3
+			AND e.event_type IN ('filter_block', 'policy_denial')
+		ORDER BY e.timestamp DESC
+		LIMIT 1
+		ON CONFLICT (organization_id, request_id) DO NOTHING
+		RETURNING `+feedbackColumns,
+		sub.ID, sub.RequestID, sub.OrganizationID, sub.TeamID, sub.APIKeyID, sub.Comment))
+	if err == nil {
+		return f, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("insert filter feedback: %w", err)
+	}
+	var exists bool
+	if err := s.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM filter_feedback WHERE organization_id = $1 AND request_id = $2)`,
+		sub.OrganizationID, sub.RequestID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check filter feedback: %w", err)
+	}
+	if exists {
+		return nil, ErrFeedbackExists
+	}
+	return nil, ErrBlockNotFound
+}
+
+// ListFeedback returns feedback matching f, newest first.
+func (s *FeedbackStore) ListFeedback(ctx context.Context, f FeedbackFilter) ([]FilterFeedback, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT `+feedbackColumns+` FROM filter_feedback
+		WHERE ($1 = '' OR organization_id = $1) AND ($2 = '' OR filter = $2) AND ($3 = '' OR status = $3)
+		ORDER BY created_at DESC
+		LIMIT $4`, f.OrganizationID, f.Filter, f.Status, f.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("query filter feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var out []FilterFeedback
+	for rows.Next() {
+		fb, err := scanFeedback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan filter feedback: %w", err)
+		}
+		out = append(out, *fb)
+	}
+	return out, rows.Err()
+}
+
+// ReviewFeedback records the security team's verdict on feedback.
+func (s *FeedbackStore) ReviewFeedback(ctx context.Context, id, status, note, reviewedBy string) (*FilterFeedback, error) {
+	f, err := scanFeedback(s.pool.QueryRow(ctx, `
+		UPDATE filter_feedback SET status = $2, review_note = NULLIF($3, ''), reviewed_by = NULLIF($4, ''), reviewed_at = NOW()
+		WHERE id = $1
+		RETURNING `+feedbackColumns, id, status, note, reviewedBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFeedbackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("review filter feedback: %w", err)
+	}
+	return f, nil
+}
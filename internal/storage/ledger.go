@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// Ledger record results reported to LedgerMetrics.
+const (
+	ledgerWritten = "written"
+	ledgerDropped = "dropped"
+	ledgerFailed  = "failed"
+)
+
+// ledgerWriteAttempts bounds retries of a failed batch before it is dropped.
+const ledgerWriteAttempts = 3
+
+var requestUsageColumns = []string{
+	"request_id", "api_key_id", "organization_id", "team_id", "user_id", "project",
+	"model_requested", "model_served", "provider", "classification", "stream",
+	"prompt_tokens", "completion_tokens", "total_tokens", "cost_usd",
+	"status_code", "duration_ms", "started_at", "completed_at", "request_hash",
+}
+
+// LedgerMetrics receives usage ledger writer metrics. It is satisfied by
+// *telemetry.Metrics.
+type LedgerMetrics interface {
+	SetUsageLedgerQueueDepth(n int)
+	RecordUsageLedgerRecords(result string, n int)
+	ObserveUsageLedgerFlush(d time.Duration)
+}
+
+// batchWriter persists a batch of usage records atomically, skipping records
+// whose request ID is already stored.
+type batchWriter interface {
+	WriteBatch(ctx context.Context, records []UsageRecord) error
+}
+
+// UsageLedger buffers usage records and writes them to request_usage in
+// batches from a single goroutine. When the buffer is full, Record applies
+// backpressure by waiting up to the enqueue timeout, then drops the record
+// rather than stalling the request path indefinitely.
+type UsageLedger struct {
+	writer  batchWriter
+	cfg     config.UsageConfig
+	records chan UsageRecord
+	metrics LedgerMetrics
+
+	mu     sync.RWMutex // guards closed against sends on a closed channel
+	closed bool
+	done   chan struct{}
+}
+
+// NewUsageLedger creates a ledger writing to pool and starts its flush loop.
+// Call Close on shutdown to flush buffered records.
+func NewUsageLedger(pool *pgxpool.Pool, cfg config.UsageConfig) *UsageLedger {
+	return newUsageLedger(&pgBatchWriter{pool: pool}, cfg)
+}
+
+func newUsageLedger(writer batchWriter, cfg config.UsageConfig) *UsageLedger {
+	defaults := config.DefaultConfig().Usage
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = defaults.BufferSize
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaults.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = defaults.FlushInterval
+	}
+	l := &UsageLedger{
+		writer:  writer,
+		cfg:     cfg,
+		records: make(chan UsageRecord, cfg.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// SetMetrics enables writer metrics.
+func (l *UsageLedger) SetMetrics(m LedgerMetrics) {
+	l.metrics = m
+}
+
+// Record enqueues a usage record. It reports false if the record was dropped
+// because the buffer stayed full for the enqueue timeout or the ledger is
+// closed. CompletedAt defaults to now and StartedAt to CompletedAt minus
+// DurationMs.
+func (l *UsageLedger) Record(record UsageRecord) bool {
+	if record.CompletedAt.IsZero() {
+		record.CompletedAt = time.Now().UTC()
+	}
+	if record.StartedAt.IsZero() {
+		record.StartedAt = record.CompletedAt.Add(-time.Duration(record.DurationMs) * time.Millisecond)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.drop(record, "ledger closed")
+		return false
+	}
+
+	select {
+	case l.records <- record:
+		l.setQueueDepth()
+		return true
+	default:
+	}
+
+	// Buffer full: wait briefly for the writer to catch up.
+	timer := time.NewTimer(l.cfg.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.records <- record:
+		l.setQueueDepth()
+		return true
+	case <-timer.C:
+		l.drop(record, "buffer full")
+		return false
+	}
+}
+
+// Close stops accepting records and flushes everything buffered. It returns
+// ctx.Err() if ctx ends before the flush completes.
+func (l *UsageLedger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.records)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *UsageLedger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]UsageRecord, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case record, ok := <-l.records:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= l.cfg.BatchSize {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch, retrying with a short backoff. A batch that still
+// fails is logged with its request IDs and counted as failed.
+func (l *UsageLedger) flush(batch []UsageRecord) {
+	l.setQueueDepth()
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	var err error
+	for attempt := 1; attempt <= ledgerWriteAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = l.writer.WriteBatch(ctx, batch)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt < ledgerWriteAttempts {
+			time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+		}
+	}
+	if l.metrics != nil {
+		l.metrics.ObserveUsageLedgerFlush(time.Since(start))
+	}
+
+	if err != nil {
+		ids := make([]string, len(batch))
+		for i, r := range batch {
+			ids[i] = r.RequestID
+		}
+		slog.Error("failed to write usage batch",
+			"error", err,
+			"records", len(batch),
+			"request_ids", ids,
+		)
+		if l.metrics != nil {
+			l.metrics.RecordUsageLedgerRecords(ledgerFailed, len(batch))
+		}
+		return
+	}
+
+	slog.Debug("usage batch saved", "records", len(batch))
+	if l.metrics != nil {
+		l.metrics.RecordUsageLedgerRecords(ledgerWritten, len(batch))
+	}
+}
+
+func (l *UsageLedger) drop(record UsageRecord, reason string) {
+	slog.Warn("usage record dropped",
+		"reason", reason,
+		"request_id", record.RequestID,
+		"org_id", record.OrganizationID,
+		"cost_usd", record.EstimatedCostUSD,
+	)
+	if l.metrics != nil {
+		l.metrics.RecordUsageLedgerRecords(ledgerDropped, 1)
+	}
+}
+
+func (l *UsageLedger) setQueueDepth() {
+	if l.metrics != nil {
+		l.metrics.SetUsageLedgerQueueDepth(len(l.records))
+	}
+}
+
+// pgBatchWriter COPYs each batch into a temporary staging table and moves
+// it into request_usage with ON CONFLICT (request_id) DO NOTHING, all in one
+// transaction. A batch is retried when its first attempt may still have
+// committed, for example when the commit timed out on the client side, so
+// rows already present are skipped rather than counted twice.
+type pgBatchWriter struct {
+	pool *pgxpool.Pool
+}
+
+func (w *pgBatchWriter) WriteBatch(ctx context.Context, records []UsageRecord) error {
+	tx, err := w.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	columns := strings.Join(requestUsageColumns, ", ")
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE request_usage_staging ON COMMIT DROP AS
+		SELECT `+columns+` FROM request_usage WITH NO DATA`); err != nil {
+		return fmt.Errorf("create staging table: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"request_usage_staging"}, requestUsageColumns,
+		pgx.CopyFromSlice(len(records), func(i int) ([]any, error) {
+			r := records[i]
+			return []any{
+				r.RequestID, r.APIKeyID, r.OrganizationID, r.TeamID, r.UserID, r.Project,
+				r.ModelRequested, r.ModelServed, r.Provider, r.Classification, r.Stream,
+				r.PromptTokens, r.CompletionTokens, r.TotalTokens, r.EstimatedCostUSD,
+				r.StatusCode, r.DurationMs, r.StartedAt, r.CompletedAt, r.RequestHash,
+			}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("copy usage batch: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO request_usage (`+columns+`)
+		SELECT `+columns+` FROM request_usage_staging
+		ON CONFLICT (request_id) DO NOTHING`); err != nil {
+		return fmt.Errorf("insert usage batch: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+type fakeBatchWriter struct {
+	mu      sync.Mutex
+	batches [][]UsageRecord
+	fails   int           // number of calls to fail before succeeding
+	block   chan struct{} // if set, WriteBatch waits on it
+}
+
+func (f *fakeBatchWriter) WriteBatch(ctx context.Context, records []UsageRecord) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fails > 0 {
+		f.fails--
+		return errors.New("connection reset")
+	}
+	f.batches = append(f.batches, append([]UsageRecord(nil), records...))
+	return nil
+}
+
+func (f *fakeBatchWriter) written() []UsageRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var all []UsageRecord
+	for _, b := range f.batches {
+		all = append(all, b...)
+	}
+	return all
+}
+
+type fakeLedgerMetrics struct {
+	mu      sync.Mutex
+	results map[string]int
+}
+
+func (m *fakeLedgerMetrics) SetUsageLedgerQueueDepth(int)          {}
+func (m *fakeLedgerMetrics) ObserveUsageLedgerFlush(time.Duration) {}
+func (m *fakeLedgerMetrics) RecordUsageLedgerRecords(result string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[result] += n
+}
+
+func (m *fakeLedgerMetrics) count(result string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.results[result]
+}
+
+func TestUsageLedger_FlushesFullBatches(t *testing.T) {
+	w := &fakeBatchWriter{}
+	l := newUsageLedger(w, config.UsageConfig{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour})
+
+	for _, id := range []string{"req-1", "req-2", "req-3"} {
+		if !l.Record(UsageRecord{RequestID: id, DurationMs: 250}) {
+			t.Fatalf("Record(%s) dropped", id)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(w.written()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := len(w.written()); got != 2 {
+		t.Fatalf("expected one full batch of 2 before close, got %d records", got)
+	}
+
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got := w.written()
+	if len(got) != 3 || got[2].RequestID != "req-3" {
+		t.Fatalf("expected remaining record flushed on close, got %+v", got)
+	}
+	if got[0].CompletedAt.IsZero() || got[0].CompletedAt.Sub(got[0].StartedAt) != 250*time.Millisecond {
+		t.Errorf("expected timestamps derived from duration, got started=%v completed=%v", got[0].StartedAt, got[0].CompletedAt)
+	}
+	if l.Record(UsageRecord{RequestID: "late"}) {
+		t.Error("Record after Close should report a drop")
+	}
+}
+
+func TestUsageLedger_FlushesOnInterval(t *testing.T) {
+	w := &fakeBatchWriter{}
+	l := newUsageLedger(w, config.UsageConfig{BufferSize: 10, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer func() { _ = l.Close(context.Background()) }()
+
+	l.Record(UsageRecord{RequestID: "req-1"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(w.written()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(w.written()) != 1 {
+		t.Fatal("expected partial batch to flush on interval")
+	}
+}
+
+func TestUsageLedger_BackpressureDropsWhenFull(t *testing.T) {
+	w := &fakeBatchWriter{block: make(chan struct{})}
+	m := &fakeLedgerMetrics{results: map[string]int{}}
+	l := newUsageLedger(w, config.UsageConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, EnqueueTimeout: 20 * time.Millisecond})
+	l.SetMetrics(m)
+
+	// The first record is taken by the writer, which then blocks; the second
+	// fills the buffer; the third waits out the enqueue timeout and drops.
+	l.Record(UsageRecord{RequestID: "req-1"})
+	deadline := time.Now().Add(2 * time.Second)
+	for len(l.records) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !l.Record(UsageRecord{RequestID: "req-2"}) {
+		t.Fatal("second record should fit in the buffer")
+	}
+	start := time.Now()
+	if l.Record(UsageRecord{RequestID: "req-3"}) {
+		t.Fatal("third record should be dropped while the buffer is full")
+	}
+	if waited := time.Since(start); waited < 20*time.Millisecond {
+		t.Errorf("expected Record to wait for the enqueue timeout, waited %v", waited)
+	}
+	if m.count(ledgerDropped) != 1 {
+		t.Errorf("dropped = %d, want 1", m.count(ledgerDropped))
+	}
+
+	close(w.block)
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := len(w.written()); got != 2 {
+		t.Errorf("expected 2 records written, got %d", got)
+	}
+	if m.count(ledgerWritten) != 2 {
+		t.Errorf("written = %d, want 2", m.count(ledgerWritten))
+	}
+}
+
+func TestUsageLedger_RetriesFailedBatch(t *testing.T) {
+	w := &fakeBatchWriter{fails: 1}
+	m := &fakeLedgerMetrics{results: map[string]int{}}
+	l := newUsageLedger(w, config.UsageConfig{BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+	l.SetMetrics(m)
+
+	l.Record(UsageRecord{RequestID: "req-1"})
+	if err := l.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(w.written()) != 1 {
+		t.Fatal("expected batch to be written after a retry")
+	}
+	if m.count(ledgerFailed) != 0 {
+		t.Errorf("failed = %d, want 0", m.count(ledgerFailed))
+	}
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+
+	"github.com/af-corp/aegis-gateway/migrations"
+)
+
+// MigrationStatus describes the schema version of a database relative to the
+// migrations available to the migrator.
+type MigrationStatus struct {
+	Current uint   // applied version, 0 when none
+	Dirty   bool   // a migration failed part-way and needs manual repair
+	Latest  uint   // highest available version
+	Pending []uint // available versions above Current, in order
+}
+
+// Migrator applies schema migrations. The postgres driver holds an advisory
+// lock while migrating, so several gateway replicas starting with
+// --auto-migrate at once is safe.
+type Migrator struct {
+	m        *migrate.Migrate
+	versions []uint
+}
+
+// NewMigrator creates a migrator for dsn. An empty dir uses the migrations
+// embedded in the binary; otherwise migrations are read from dir on disk.
+func NewMigrator(dsn, dir string) (*Migrator, error) {
+	var fsys fs.FS = migrations.FS
+	if dir != "" {
+		fsys = os.DirFS(dir)
+	}
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	versions, err := sourceVersions(src)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+	m, err := migrate.NewWithSourceInstance("iofs", src, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("connect for migrations: %w", err)
+	}
+	return &Migrator{m: m, versions: versions}, nil
+}
+
+// Up applies all pending migrations. It reports whether anything changed.
+func (mg *Migrator) Up() (bool, error) {
+	err := mg.m.Up()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Steps applies n migrations, or rolls back -n when n is negative.
+func (mg *Migrator) Steps(n int) error {
+	err := mg.m.Steps(n)
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Down rolls back all applied migrations.
+func (mg *Migrator) Down() error {
+	err := mg.m.Down()
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// Version returns the applied version, 0 if no migration has been applied.
+func (mg *Migrator) Version() (uint, bool, error) {
+	v, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return v, dirty, err
+}
+
+// Status compares the applied version with the available migrations.
+func (mg *Migrator) Status() (MigrationStatus, error) {
+	v, dirty, err := mg.Version()
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	return migrationStatus(mg.versions, v, dirty), nil
+}
+
+// Close releases the migrator's database connection.
+func (mg *Migrator) Close() error {
+	srcErr, dbErr := mg.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+// EmbeddedVersions lists the migration versions compiled into the binary.
+func EmbeddedVersions() ([]uint, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = src.Close() }()
+	return sourceVersions(src)
+}
+
+func sourceVersions(src source.Driver) ([]uint, error) {
+	v, err := src.First()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	versions := []uint{v}
+	for {
+		v, err = src.Next(v)
+		if errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+}
+
+func migrationStatus(versions []uint, current uint, dirty bool) MigrationStatus {
+	sorted := append([]uint(nil), versions...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	status := MigrationStatus{Current: current, Dirty: dirty}
+	if len(sorted) > 0 {
+		status.Latest = sorted[len(sorted)-1]
+	}
+	for _, v := range sorted {
+		if v > current {
+			status.Pending = append(status.Pending, v)
+		}
+	}
+	return status
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/migrations"
+)
+
+func TestEmbeddedVersions(t *testing.T) {
+	versions, err := EmbeddedVersions()
+	if err != nil {
+		t.Fatalf("EmbeddedVersions: %v", err)
+	}
+	if len(versions) == 0 {
+		t.Fatal("expected migrations to be embedded")
+	}
+	for i, v := range versions {
+		if v != uint(i+1) {
+			t.Fatalf("versions = %v, want a contiguous sequence from 1", versions)
+		}
+	}
+
+	entries, err := migrations.FS.ReadDir(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ups, downs := 0, 0
+	for _, e := range entries {
+		switch {
+		case strings.HasSuffix(e.Name(), ".up.sql"):
+			ups++
+		case strings.HasSuffix(e.Name(), ".down.sql"):
+			downs++
+		}
+	}
+	if ups != len(versions) || downs != len(versions) {
+		t.Errorf("expected an up and down file per version, got %d up / %d down for %d versions", ups, downs, len(versions))
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		current uint
+		want    MigrationStatus
+	}{
+		{"empty database", 0, MigrationStatus{Current: 0, Latest: 5, Pending: []uint{1, 2, 3, 5}}},
+		{"behind", 2, MigrationStatus{Current: 2, Latest: 5, Pending: []uint{3, 5}}},
+		{"up to date", 5, MigrationStatus{Current: 5, Latest: 5}},
+		{"ahead of binary", 7, MigrationStatus{Current: 7, Latest: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := migrationStatus([]uint{3, 1, 5, 2}, tt.current, false)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("migrationStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+// NewPoolConfig builds a pgxpool config from the database settings, including
+// sslmode/TLS options and pool sizing. Zero values keep pgxpool defaults.
+func NewPoolConfig(db config.DatabaseConfig) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(db.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("parse database DSN: %w", err)
+	}
+	if db.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(db.MaxOpenConns)
+	}
+	if db.MaxIdleConns > 0 {
+		poolConfig.MinConns = min(int32(db.MaxIdleConns), poolConfig.MaxConns)
+	}
+	if db.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = db.ConnMaxLifetime
+	}
+	if db.ConnMaxIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = db.ConnMaxIdleTime
+	}
+	if db.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = db.HealthCheckPeriod
+	}
+	return poolConfig, nil
+}
+
+// RegisterPoolMetrics exports pgxpool's cumulative counters (acquires, waits,
+// connection churn), read from pool.Stat() at scrape time. Point-in-time
+// connection counts are covered by aegis_db_pool_conns.
+func RegisterPoolMetrics(reg prometheus.Registerer, pool *pgxpool.Pool) error {
+	counters := []struct {
+		name, help string
+		labels     prometheus.Labels
+		value      func(*pgxpool.Stat) float64
+	}{
+		{"aegis_db_pool_acquire_total", "Total successful connection acquires from the database pool.", nil,
+			func(s *pgxpool.Stat) float64 { return float64(s.AcquireCount()) }},
+		{"aegis_db_pool_empty_acquire_total", "Total acquires that had to wait because no idle connection was available.", nil,
+			func(s *pgxpool.Stat) float64 { return float64(s.EmptyAcquireCount()) }},
+		{"aegis_db_pool_canceled_acquire_total", "Total acquires canceled by their context while waiting.", nil,
+			func(s *pgxpool.Stat) float64 { return float64(s.CanceledAcquireCount()) }},
+		{"aegis_db_pool_acquire_duration_seconds_total", "Cumulative time spent acquiring connections from the database pool.", nil,
+			func(s *pgxpool.Stat) float64 { return s.AcquireDuration().Seconds() }},
+		{"aegis_db_pool_new_conns_total", "Total new database connections opened by the pool.", nil,
+			func(s *pgxpool.Stat) float64 { return float64(s.NewConnsCount()) }},
+		{"aegis_db_pool_destroyed_conns_total", "Total database connections closed by the pool.", prometheus.Labels{"reason": "max_lifetime"},
+			func(s *pgxpool.Stat) float64 { return float64(s.MaxLifetimeDestroyCount()) }},
+		{"aegis_db_pool_destroyed_conns_total", "Total database connections closed by the pool.", prometheus.Labels{"reason": "max_idle_time"},
+			func(s *pgxpool.Stat) float64 { return float64(s.MaxIdleDestroyCount()) }},
+	}
+	for _, c := range counters {
+		value := c.value
+		err := reg.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        c.name,
+			Help:        c.help,
+			ConstLabels: c.labels,
+		}, func() float64 { return value(pool.Stat()) }))
+		if err != nil {
+			return fmt.Errorf("register %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/af-corp/aegis-gateway/internal/config"
+)
+
+func TestNewPoolConfig_AppliesSettings(t *testing.T) {
+	db := config.DefaultConfig().Database
+	db.Password = "p@ss/word"
+	db.MaxOpenConns = 8
+	db.MaxIdleConns = 3
+	db.ConnMaxLifetime = 7 * time.Minute
+	db.ConnMaxIdleTime = 2 * time.Minute
+	db.HealthCheckPeriod = 15 * time.Second
+
+	pc, err := NewPoolConfig(db)
+	if err != nil {
+		t.Fatalf("NewPoolConfig: %v", err)
+	}
+	if pc.MaxConns != 8 || pc.MinConns != 3 {
+		t.Errorf("MaxConns/MinConns = %d/%d, want 8/3", pc.MaxConns, pc.MinConns)
+	}
+	if pc.MaxConnLifetime != 7*time.Minute || pc.MaxConnIdleTime != 2*time.Minute || pc.HealthCheckPeriod != 15*time.Second {
+		t.Errorf("unexpected durations: lifetime=%s idle=%s health=%s", pc.MaxConnLifetime, pc.MaxConnIdleTime, pc.HealthCheckPeriod)
+	}
+	if pc.ConnConfig.Password != "p@ss/word" {
+		t.Errorf("password with special characters not preserved: %q", pc.ConnConfig.Password)
+	}
+	if pc.ConnConfig.TLSConfig != nil {
+		t.Error("expected no TLS with sslmode=disable")
+	}
+}
+
+func TestNewPoolConfig_TLS(t *testing.T) {
+	db := config.DefaultConfig().Database
+	db.SSLMode = "require"
+
+	pc, err := NewPoolConfig(db)
+	if err != nil {
+		t.Fatalf("NewPoolConfig: %v", err)
+	}
+	if pc.ConnConfig.TLSConfig == nil {
+		t.Error("expected TLS config with sslmode=require")
+	}
+
+	db.SSLMode = "verify-full"
+	db.SSLRootCert = "/nonexistent/ca.pem"
+	if _, err := NewPoolConfig(db); err == nil {
+		t.Error("expected error for unreadable sslrootcert")
+	}
+}
+
+func TestNewPoolConfig_MinConnsCappedAtMax(t *testing.T) {
+	db := config.DefaultConfig().Database
+	db.MaxOpenConns = 2
+	db.MaxIdleConns = 10
+
+	pc, err := NewPoolConfig(db)
+	if err != nil {
+		t.Fatalf("NewPoolConfig: %v", err)
+	}
+	if pc.MinConns != 2 {
+		t.Errorf("MinConns = %d, want capped at 2", pc.MinConns)
+	}
+}
+
+func TestRegisterPoolMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := RegisterPoolMetrics(reg, nil); err != nil {
+		t.Fatalf("RegisterPoolMetrics: %v", err)
+	}
+	if err := RegisterPoolMetrics(reg, nil); err == nil {
+		t.Error("expected duplicate registration to fail")
+	}
+}
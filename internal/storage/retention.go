@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tables pruned by the retention janitor.
+const (
+	TableRequestUsage  = "request_usage"
+	TableUsageRecords  = "usage_records"
+	TableUsageDaily    = "usage_daily"
+	TableAuditLogs     = "audit_logs"
+	TableAuditEvents   = "audit_events"
+	TableAPIKeys       = "api_keys"
+	TableBatches       = "batches"
+	TableConversations = "conversations"
+)
+
+// pruneQueries delete up to $2 rows older than $1 from each table. Deleting
+// by primary key in bounded batches keeps each statement short so pruning
+// does not block the request path's inserts.
+var pruneQueries = map[string]string{
+	TableRequestUsage: `DELETE FROM request_usage WHERE id IN (
+		SELECT id FROM request_usage WHERE completed_at < $1 LIMIT $2)`,
+	TableUsageRecords: `DELETE FROM usage_records WHERE id IN (
+		SELECT id FROM usage_records WHERE created_at < $1 LIMIT $2)`,
+	TableUsageDaily: `DELETE FROM usage_daily WHERE id IN (
+		SELECT id FROM usage_daily WHERE date < $1::date LIMIT $2)`,
+	TableAuditLogs: `DELETE FROM audit_logs WHERE id IN (
+		SELECT id FROM audit_logs WHERE timestamp < $1 LIMIT $2)`,
+	TableAuditEvents: `DELETE FROM audit_events WHERE id IN (
+		SELECT id FROM audit_events WHERE timestamp < $1 LIMIT $2)`,
+	// Keys are removed once expired or revoked before the cutoff. audit_logs
+	// references api_keys without cascading, so keys with surviving audit
+	// rows are kept until those rows are pruned.
+	TableAPIKeys: `DELETE FROM api_keys WHERE id IN (
+		SELECT k.id FROM api_keys k
+		WHERE (k.expires_at < $1 OR (k.status = 'revoked' AND k.revoked_at < $1))
+		  AND NOT EXISTS (SELECT 1 FROM audit_logs a WHERE a.api_key_id = k.id)
+		LIMIT $2)`,
+	// Finished batch jobs; their items go with them by cascade.
+	TableBatches: `DELETE FROM batches WHERE id IN (
+		SELECT id FROM batches WHERE completed_at < $1 LIMIT $2)`,
+	// Conversations idle since before the cutoff.
+	TableConversations: `DELETE FROM conversations WHERE (organization_id, id) IN (
+		SELECT organization_id, id FROM conversations WHERE last_seen_at < $1 LIMIT $2)`,
+}
+
+// RetentionStore deletes rows past their retention period.
+type RetentionStore struct {
+	pool *pgxpool.Pool
+}
+
+// NewRetentionStore creates a retention store.
+func NewRetentionStore(pool *pgxpool.Pool) *RetentionStore {
+	return &RetentionStore{pool: pool}
+}
+
+// Prune deletes rows from table older than before, batchSize rows per
+// statement, until none remain or ctx ends. It returns the rows deleted.
+func (s *RetentionStore) Prune(ctx context.Context, table string, before time.Time, batchSize int) (int64, error) {
+	query, ok := pruneQueries[table]
+	if !ok {
+		return 0, fmt.Errorf("no retention query for table %q", table)
+	}
+	var total int64
+	for {
+		tag, err := s.pool.Exec(ctx, query, before, batchSize)
+		if err != nil {
+			return total, fmt.Errorf("prune %s: %w", table, err)
+		}
+		n := tag.RowsAffected()
+		total += n
+		if n < int64(batchSize) {
+			return total, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+	}
+}
+
+// ExpireKeys marks active keys past their expiry as expired, so key listings
+// and the auth cache agree with expires_at. It returns the keys updated.
+func (s *RetentionStore) ExpireKeys(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.pool.Exec(ctx,
+		`UPDATE api_keys SET status = 'expired' WHERE status = 'active' AND expires_at < $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("expire keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -27,11 +27,18 @@ type UsageRecord struct {
 	StatusCode       int
 	Project          string
 	Stream           bool
+	StartedAt        time.Time
+	CompletedAt      time.Time
+	// RequestHash is the request's content hash, identical for every
+	// request carrying the same prompt and parameters.
+	RequestHash string
 }
 
-// UsageRecorder handles writing usage records to the database.
+// UsageRecorder handles writing usage records to the request_usage ledger
+// and querying it for billing and the usage API.
 type UsageRecorder struct {
-	pool *pgxpool.Pool
+	pool   *pgxpool.Pool
+	ledger *UsageLedger
 }
 
 // NewUsageRecorder creates a new usage recorder.
@@ -41,9 +48,24 @@ func NewUsageRecorder(pool *pgxpool.Pool) *UsageRecorder {
 	}
 }
 
+// SetLedger routes RecordUsage through the batching ledger writer.
+func (r *UsageRecorder) SetLedger(ledger *UsageLedger) {
+	r.ledger = ledger
+}
+
 // RecordUsage asynchronously writes a usage record to the database.
-// It does not block the request response.
+// It does not block the request response beyond the ledger's enqueue timeout.
 func (r *UsageRecorder) RecordUsage(record UsageRecord) {
+	if r.ledger != nil {
+		r.ledger.Record(record)
+		return
+	}
+	if record.CompletedAt.IsZero() {
+		record.CompletedAt = time.Now().UTC()
+	}
+	if record.StartedAt.IsZero() {
+		record.StartedAt = record.CompletedAt.Add(-time.Duration(record.DurationMs) * time.Millisecond)
+	}
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -61,19 +83,20 @@ func (r *UsageRecorder) RecordUsage(record UsageRecord) {
 // recordSync performs the actual database write.
 func (r *UsageRecorder) recordSync(ctx context.Context, record UsageRecord) error {
 	query := `
-		INSERT INTO usage_records (
+		INSERT INTO request_usage (
 			request_id, organization_id, team_id, user_id, api_key_id,
 			model_requested, model_served, provider, classification,
 			prompt_tokens, completion_tokens, total_tokens,
-			estimated_cost_usd, duration_ms, status_code,
-			project, stream
+			cost_usd, duration_ms, status_code,
+			project, stream, started_at, completed_at, request_hash
 		) VALUES (
 			$1, $2, $3, $4, $5,
 			$6, $7, $8, $9,
 			$10, $11, $12,
 			$13, $14, $15,
-			$16, $17
+			$16, $17, $18, $19, $20
 		)
+		ON CONFLICT (request_id) DO NOTHING
 	`
 
 	_, err := r.pool.Exec(ctx, query,
@@ -81,7 +104,7 @@ func (r *UsageRecorder) recordSync(ctx context.Context, record UsageRecord) erro
 		record.ModelRequested, record.ModelServed, record.Provider, record.Classification,
 		record.PromptTokens, record.CompletionTokens, record.TotalTokens,
 		record.EstimatedCostUSD, record.DurationMs, record.StatusCode,
-		record.Project, record.Stream,
+		record.Project, record.Stream, record.StartedAt, record.CompletedAt, record.RequestHash,
 	)
 
 	if err != nil {
@@ -105,13 +128,13 @@ func (r *UsageRecorder) GetUsageByOrg(ctx context.Context, orgID string, startTi
 			request_id, organization_id, team_id, user_id, api_key_id,
 			model_requested, model_served, provider, classification,
 			prompt_tokens, completion_tokens, total_tokens,
-			estimated_cost_usd, duration_ms, status_code,
-			project, stream
-		FROM usage_records
+			cost_usd, duration_ms, status_code,
+			project, stream, started_at, completed_at
+		FROM request_usage
 		WHERE organization_id = $1
-		  AND created_at >= $2
-		  AND created_at < $3
-		ORDER BY created_at DESC
+		  AND completed_at >= $2
+		  AND completed_at < $3
+		ORDER BY completed_at DESC
 		LIMIT $4
 	`
 
@@ -129,7 +152,7 @@ func (r *UsageRecorder) GetUsageByOrg(ctx context.Context, orgID string, startTi
 			&rec.ModelRequested, &rec.ModelServed, &rec.Provider, &rec.Classification,
 			&rec.PromptTokens, &rec.CompletionTokens, &rec.TotalTokens,
 			&rec.EstimatedCostUSD, &rec.DurationMs, &rec.StatusCode,
-			&rec.Project, &rec.Stream,
+			&rec.Project, &rec.Stream, &rec.StartedAt, &rec.CompletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -147,13 +170,13 @@ func (r *UsageRecorder) GetUsageByTeam(ctx context.Context, teamID string, start
 			request_id, organization_id, team_id, user_id, api_key_id,
 			model_requested, model_served, provider, classification,
 			prompt_tokens, completion_tokens, total_tokens,
-			estimated_cost_usd, duration_ms, status_code,
-			project, stream
-		FROM usage_records
+			cost_usd, duration_ms, status_code,
+			project, stream, started_at, completed_at
+		FROM request_usage
 		WHERE team_id = $1
-		  AND created_at >= $2
-		  AND created_at < $3
-		ORDER BY created_at DESC
+		  AND completed_at >= $2
+		  AND completed_at < $3
+		ORDER BY completed_at DESC
 		LIMIT $4
 	`
 
@@ -171,7 +194,7 @@ func (r *UsageRecorder) GetUsageByTeam(ctx context.Context, teamID string, start
 			&rec.ModelRequested, &rec.ModelServed, &rec.Provider, &rec.Classification,
 			&rec.PromptTokens, &rec.CompletionTokens, &rec.TotalTokens,
 			&rec.EstimatedCostUSD, &rec.DurationMs, &rec.StatusCode,
-			&rec.Project, &rec.Stream,
+			&rec.Project, &rec.Stream, &rec.StartedAt, &rec.CompletedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -184,27 +207,27 @@ func (r *UsageRecorder) GetUsageByTeam(ctx context.Context, teamID string, start
 
 // GetUsageSummary returns aggregated usage statistics for an organization.
 type UsageSummary struct {
-	TotalRequests     int
-	TotalCostUSD      float64
-	TotalTokens       int64
-	PromptTokens      int64
-	CompletionTokens  int64
-	AverageDurationMs float64
+	TotalRequests     int     `json:"total_requests"`
+	TotalCostUSD      float64 `json:"total_cost_usd"`
+	TotalTokens       int64   `json:"total_tokens"`
+	PromptTokens      int64   `json:"prompt_tokens"`
+	CompletionTokens  int64   `json:"completion_tokens"`
+	AverageDurationMs float64 `json:"average_duration_ms"`
 }
 
 func (r *UsageRecorder) GetUsageSummary(ctx context.Context, orgID string, startTime, endTime time.Time) (*UsageSummary, error) {
 	query := `
 		SELECT 
 			COUNT(*) as total_requests,
-			COALESCE(SUM(estimated_cost_usd), 0) as total_cost,
+			COALESCE(SUM(cost_usd), 0) as total_cost,
 			COALESCE(SUM(total_tokens), 0) as total_tokens,
 			COALESCE(SUM(prompt_tokens), 0) as prompt_tokens,
 			COALESCE(SUM(completion_tokens), 0) as completion_tokens,
 			COALESCE(AVG(duration_ms), 0) as avg_duration
-		FROM usage_records
+		FROM request_usage
 		WHERE organization_id = $1
-		  AND created_at >= $2
-		  AND created_at < $3
+		  AND completed_at >= $2
+		  AND completed_at < $3
 	`
 
 	var summary UsageSummary
@@ -223,3 +246,44 @@ func (r *UsageRecorder) GetUsageSummary(ctx context.Context, orgID string, start
 
 	return &summary, nil
 }
+
+// TeamSpendSince returns a team's total cost in USD for requests completed at
+// or after since. It lets the budget tracker rebuild its Redis counter from
+// the durable ledger.
+func (r *UsageRecorder) TeamSpendSince(ctx context.Context, teamID string, since time.Time) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(cost_usd), 0)
+		FROM request_usage
+		WHERE team_id = $1
+		  AND completed_at >= $2
+	`
+
+	var spent float64
+	if err := r.pool.QueryRow(ctx, query, teamID, since).Scan(&spent); err != nil {
+		return 0, err
+	}
+	return spent, nil
+}
+
+// KeyUsage totals one API key's requests over a period.
+type KeyUsage struct {
+	Requests    int64   `json:"requests"`
+	TotalTokens int64   `json:"total_tokens"`
+	CostUSD     float64 `json:"cost_usd"`
+}
+
+// KeyUsageSince totals a key's requests completed at or after since.
+func (r *UsageRecorder) KeyUsageSince(ctx context.Context, keyID string, since time.Time) (KeyUsage, error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost_usd), 0)::float8
+		FROM request_usage
+		WHERE api_key_id = $1
+		  AND completed_at >= $2
+	`
+
+	var u KeyUsage
+	if err := r.pool.QueryRow(ctx, query, keyID, since).Scan(&u.Requests, &u.TotalTokens, &u.CostUSD); err != nil {
+		return KeyUsage{}, err
+	}
+	return u, nil
+}
@@ -0,0 +1,97 @@
+package telemetry
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+)
+
+// Label modes for LabelLimit.
+const (
+	LabelKeep = "keep" // record values as they are
+	LabelDrop = "drop" // record every value as ""
+	LabelHash = "hash" // record one of Buckets hash buckets, "bucket-<n>"
+	LabelTopN = "top_n"
+)
+
+// LabelOther is recorded for the values a top_n label no longer admits.
+const LabelOther = "other"
+
+// LabelLimit bounds the distinct values one metric label takes.
+type LabelLimit struct {
+	// Mode is LabelKeep (also ""), LabelDrop, LabelHash, or LabelTopN.
+	Mode string
+	// Buckets is the number of hash buckets in LabelHash mode.
+	Buckets int
+	// TopN is how many distinct values LabelTopN mode records as
+	// themselves: the first seen since startup, which for a label like org
+	// are the busiest ones; later values are recorded as LabelOther.
+	TopN int
+}
+
+// labelLimiter maps a label's values onto the bounded set its limit allows.
+// A nil limiter keeps every value.
+type labelLimiter struct {
+	limit LabelLimit
+
+	mu       sync.RWMutex
+	admitted map[string]struct{}
+}
+
+func newLabelLimiter(limit LabelLimit) *labelLimiter {
+	switch limit.Mode {
+	case LabelDrop, LabelHash, LabelTopN:
+		return &labelLimiter{limit: limit, admitted: map[string]struct{}{}}
+	}
+	return nil
+}
+
+func (l *labelLimiter) value(v string) string {
+	if l == nil || v == "" {
+		return v
+	}
+	switch l.limit.Mode {
+	case LabelDrop:
+		return ""
+	case LabelHash:
+		if l.limit.Buckets <= 0 {
+			return v
+		}
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(v))
+		return "bucket-" + strconv.Itoa(int(h.Sum32()%uint32(l.limit.Buckets)))
+	case LabelTopN:
+		l.mu.RLock()
+		_, ok := l.admitted[v]
+		full := len(l.admitted) >= l.limit.TopN
+		l.mu.RUnlock()
+		if ok {
+			return v
+		}
+		if full {
+			return LabelOther
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if len(l.admitted) >= l.limit.TopN {
+			return LabelOther
+		}
+		l.admitted[v] = struct{}{}
+		return v
+	}
+	return v
+}
+
+// SetLabelLimits bounds the org, team, and model labels of every metric that
+// carries them, keyed by label name, so that many tenants or models do not
+// explode the series count. Labels without a limit are kept. Call before
+// serving traffic.
+func (m *Metrics) SetLabelLimits(limits map[string]LabelLimit) {
+	m.orgLimiter = newLabelLimiter(limits["org"])
+	m.teamLimiter = newLabelLimiter(limits["team"])
+	m.modelLimiter = newLabelLimiter(limits["model"])
+}
+
+func (m *Metrics) orgLabel(org string) string     { return m.orgLimiter.value(org) }
+func (m *Metrics) teamLabel(team string) string   { return m.teamLimiter.value(team) }
+func (m *Metrics) modelLabel(model string) string { return m.modelLimiter.value(model) }
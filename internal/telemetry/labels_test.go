@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelLimiter(t *testing.T) {
+	t.Run("keep", func(t *testing.T) {
+		l := newLabelLimiter(LabelLimit{Mode: LabelKeep})
+		if got := l.value("team-a"); got != "team-a" {
+			t.Errorf("expected team-a, got %q", got)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		l := newLabelLimiter(LabelLimit{Mode: LabelDrop})
+		if got := l.value("team-a"); got != "" {
+			t.Errorf("expected empty label, got %q", got)
+		}
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		l := newLabelLimiter(LabelLimit{Mode: LabelHash, Buckets: 4})
+		seen := map[string]bool{}
+		for i := range 100 {
+			v := l.value("team-" + itoa(i))
+			if !strings.HasPrefix(v, "bucket-") {
+				t.Fatalf("expected a bucket label, got %q", v)
+			}
+			seen[v] = true
+		}
+		if len(seen) > 4 {
+			t.Errorf("expected at most 4 buckets, got %d", len(seen))
+		}
+		if l.value("team-7") != l.value("team-7") {
+			t.Error("expected a value to hash to the same bucket every time")
+		}
+	})
+
+	t.Run("top_n", func(t *testing.T) {
+		l := newLabelLimiter(LabelLimit{Mode: LabelTopN, TopN: 2})
+		for _, v := range []string{"a", "b", "a"} {
+			if got := l.value(v); got != v {
+				t.Errorf("expected admitted value %q, got %q", v, got)
+			}
+		}
+		if got := l.value("c"); got != LabelOther {
+			t.Errorf("expected %q beyond top_n, got %q", LabelOther, got)
+		}
+		if got := l.value(""); got != "" {
+			t.Errorf("expected empty value kept empty, got %q", got)
+		}
+	})
+}
+
+func TestSetLabelLimits(t *testing.T) {
+	m := &Metrics{}
+	m.SetLabelLimits(map[string]LabelLimit{"team": {Mode: LabelDrop}})
+	if got := m.teamLabel("team-a"); got != "" {
+		t.Errorf("expected team label dropped, got %q", got)
+	}
+	if got := m.orgLabel("org-1"); got != "org-1" {
+		t.Errorf("expected org label kept, got %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+package telemetry
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// ParseLogLevel converts a config log level ("debug", "info", "warn", "error")
+// to a slog.Level. Unknown values fall back to info.
+func ParseLogLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewLogger builds a structured logger writing to w. Format "text" produces
+// human-readable key=value output for local development; anything else is JSON.
+// The level is read from the given LevelVar so it can be changed at runtime.
+func NewLogger(w io.Writer, format string, level *slog.LevelVar) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(strings.TrimSpace(format), "text") {
+		return slog.New(slog.NewTextHandler(w, opts))
+	}
+	return slog.New(slog.NewJSONHandler(w, opts))
+}
@@ -0,0 +1,58 @@
+package telemetry
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warn":    slog.LevelWarn,
+		"warning": slog.LevelWarn,
+		"error":   slog.LevelError,
+		"":        slog.LevelInfo,
+		"verbose": slog.LevelInfo,
+	}
+	for in, want := range tests {
+		if got := ParseLogLevel(in); got != want {
+			t.Errorf("ParseLogLevel(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestNewLogger_Format(t *testing.T) {
+	level := new(slog.LevelVar)
+
+	var buf bytes.Buffer
+	NewLogger(&buf, "text", level).Info("hello", "k", "v")
+	if !strings.Contains(buf.String(), "msg=hello") {
+		t.Errorf("expected text output, got %q", buf.String())
+	}
+
+	buf.Reset()
+	NewLogger(&buf, "json", level).Info("hello")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("expected JSON output, got %q", buf.String())
+	}
+}
+
+func TestNewLogger_LevelChangesAtRuntime(t *testing.T) {
+	level := new(slog.LevelVar)
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, "json", level)
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be suppressed at info level, got %q", buf.String())
+	}
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("visible")
+	if !strings.Contains(buf.String(), "visible") {
+		t.Errorf("expected debug output after level change, got %q", buf.String())
+	}
+}
@@ -1,6 +1,9 @@
 package telemetry
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -10,10 +13,17 @@ type Metrics struct {
 	RequestTotal      *prometheus.CounterVec
 	RequestDurationMs *prometheus.HistogramVec
 	GatewayOverheadMs *prometheus.HistogramVec
+	ProviderLatencyMs *prometheus.HistogramVec
 	TokensTotal       *prometheus.CounterVec
 	CostUSDTotal      *prometheus.CounterVec
 	FilterActionTotal *prometheus.CounterVec
+	FilterEvalTotal   *prometheus.CounterVec
+	FilterDurationMs  *prometheus.HistogramVec
+	// FilterDegradedTotal counts filter evaluations that failed and were
+	// passed under fail_open.
+	FilterDegradedTotal *prometheus.CounterVec
 	RateLimitHitTotal *prometheus.CounterVec
+	RateLimitRemaining *prometheus.GaugeVec
 	DBPoolConns       *prometheus.GaugeVec
 	DBPoolWaitDuration *prometheus.HistogramVec
 	
@@ -30,6 +40,55 @@ type Metrics struct {
 	
 	// Policy reload metrics
 	PolicyReloadTotal *prometheus.CounterVec
+	// PolicyDecisionCacheTotal counts policy decision cache lookups by
+	// result (hit, miss).
+	PolicyDecisionCacheTotal *prometheus.CounterVec
+
+	// Classification detection metrics
+	ClassificationMismatchTotal *prometheus.CounterVec
+
+	// DeprecatedModelRequestsTotal counts requests for models marked
+	// deprecated in models.yaml.
+	DeprecatedModelRequestsTotal *prometheus.CounterVec
+
+	// Config hot-reload metrics
+	ConfigReloadTotal       *prometheus.CounterVec
+	ConfigLastReloadSuccess prometheus.Gauge
+
+	// Load shedding metrics
+	InFlightRequests prometheus.Gauge
+	LoadShedTotal    *prometheus.CounterVec
+
+	// Priority class metrics
+	PriorityInFlight          *prometheus.GaugeVec
+	PriorityRequestDurationMs *prometheus.HistogramVec
+
+	// Usage ledger writer metrics
+	UsageLedgerQueueDepth    prometheus.Gauge
+	UsageLedgerRecordsTotal  *prometheus.CounterVec
+	UsageLedgerFlushDuration prometheus.Histogram
+
+	// Retention janitor metrics
+	JanitorDeletedTotal *prometheus.CounterVec
+	JanitorErrorsTotal  *prometheus.CounterVec
+	JanitorRunDuration  prometheus.Histogram
+
+	// Provider circuit breaker metrics
+	CircuitState           *prometheus.GaugeVec
+	CircuitTransitionTotal *prometheus.CounterVec
+
+	// Provider outbound rate limit metrics
+	ProviderThrottleTotal       *prometheus.CounterVec
+	ProviderThrottleWaitSeconds *prometheus.HistogramVec
+	ProviderRateLimitRemaining  *prometheus.GaugeVec
+	ProviderRateLimitLimit      *prometheus.GaugeVec
+
+	// Provider connection pool metrics
+	ProviderConnectionsOpen   *prometheus.GaugeVec
+	ProviderConnectionsTotal  *prometheus.CounterVec
+	ProviderConnAcquiresTotal *prometheus.CounterVec
+	ProviderConnSetupSeconds  *prometheus.HistogramVec
+	ProviderProtocolTotal     *prometheus.CounterVec
 
 	// Streaming metrics
 	StreamingChunkTotal     *prometheus.CounterVec
@@ -37,6 +96,14 @@ type Metrics struct {
 	StreamingTokensPerSecond  *prometheus.HistogramVec
 	StreamingDurationMs       *prometheus.HistogramVec
 	StreamingErrorTotal       *prometheus.CounterVec
+	StreamingInterChunkMs     *prometheus.HistogramVec
+	StreamingChunksPerStream  *prometheus.HistogramVec
+
+	// strictTenancy fills the org label on every request series.
+	strictTenancy bool
+	// Limiters bounding the org, team, and model label values; nil keeps
+	// them as they are.
+	orgLimiter, teamLimiter, modelLimiter *labelLimiter
 }
 
 // NewMetrics creates and registers all Prometheus metrics.
@@ -51,7 +118,7 @@ func NewMetrics() *Metrics {
 			Name:    "aegis_request_duration_ms",
 			Help:    "Total request duration in milliseconds (including provider latency).",
 			Buckets: []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000},
-		}, []string{"model", "provider"}),
+		}, []string{"org", "model", "provider"}),
 
 		GatewayOverheadMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "aegis_gateway_overhead_ms",
@@ -59,6 +126,12 @@ func NewMetrics() *Metrics {
 			Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250},
 		}, []string{"org"}),
 
+		ProviderLatencyMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_provider_latency_ms",
+			Help:    "Provider round-trip time in milliseconds, including retries.",
+			Buckets: []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000},
+		}, []string{"org", "model", "provider"}),
+
 		TokensTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "aegis_tokens_total",
 			Help: "Total tokens processed.",
@@ -72,12 +145,33 @@ func NewMetrics() *Metrics {
 		FilterActionTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "aegis_filter_action_total",
 			Help: "Total filter actions taken.",
-		}, []string{"filter", "action"}),
+		}, []string{"filter", "org", "action"}),
+
+		FilterEvalTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_filter_evaluation_total",
+			Help: "Total filter evaluations by outcome (pass, flag, redact, block).",
+		}, []string{"filter", "org", "action"}),
+
+		FilterDurationMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_filter_duration_ms",
+			Help:    "Filter evaluation time in milliseconds.",
+			Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 25, 50, 100, 250, 1000},
+		}, []string{"filter"}),
+
+		FilterDegradedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_filter_degraded_total",
+			Help: "Filter evaluations that failed and were passed under fail_open.",
+		}, []string{"filter", "org"}),
 
 		RateLimitHitTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "aegis_rate_limit_hit_total",
 			Help: "Total rate limit hits.",
-		}, []string{"dimension", "id"}),
+		}, []string{"dimension", "org", "team"}),
+
+		RateLimitRemaining: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_rate_limit_remaining",
+			Help: "Remaining quota from the most recent limiter check (requests for rpm, cents for budget).",
+		}, []string{"dimension", "org", "team"}),
 
 		DBPoolConns: promauto.NewGaugeVec(prometheus.GaugeOpts{
 			Name: "aegis_db_pool_conns",
@@ -120,78 +214,276 @@ func NewMetrics() *Metrics {
 			Help: "Total number of policy reload attempts.",
 		}, []string{"status"}),
 
+		PolicyDecisionCacheTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_policy_decision_cache_total",
+			Help: "Policy decision cache lookups by result (hit, miss).",
+		}, []string{"result"}),
+
+		ConfigReloadTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_config_reload_total",
+			Help: "Total number of config hot-reload attempts.",
+		}, []string{"status"}),
+
+		ConfigLastReloadSuccess: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "aegis_config_last_reload_success_timestamp_seconds",
+			Help: "Unix time of the last config reload that was applied.",
+		}),
+
+		InFlightRequests: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "aegis_in_flight_requests",
+			Help: "Requests currently being processed, excluding health and admin endpoints.",
+		}),
+
+		LoadShedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_load_shed_total",
+			Help: "Total number of requests rejected with 503 because the gateway was at capacity for their priority class.",
+		}, []string{"priority"}),
+
+		PriorityInFlight: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_priority_in_flight_requests",
+			Help: "Authenticated requests and batch items currently being processed, by priority class.",
+		}, []string{"priority"}),
+
+		PriorityRequestDurationMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_priority_request_duration_ms",
+			Help:    "Request duration in milliseconds by priority class, for requests admitted past load shedding.",
+			Buckets: []float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000},
+		}, []string{"priority"}),
+
+		UsageLedgerQueueDepth: promauto.NewGauge(prometheus.GaugeOpts{
+			Name: "aegis_usage_ledger_queue_depth",
+			Help: "Usage records buffered and waiting to be written to request_usage.",
+		}),
+
+		UsageLedgerRecordsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_usage_ledger_records_total",
+			Help: "Usage records handled by the ledger writer, by result (written, dropped, failed).",
+		}, []string{"result"}),
+
+		UsageLedgerFlushDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "aegis_usage_ledger_flush_duration_seconds",
+			Help:    "Time to write one batch of usage records to request_usage.",
+			Buckets: prometheus.DefBuckets,
+		}),
+
+		ClassificationMismatchTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_classification_mismatch_total",
+			Help: "Prompts whose estimated classification exceeds the key's declared classification.",
+		}, []string{"org", "declared", "detected", "action"}),
+
+		DeprecatedModelRequestsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_deprecated_model_requests_total",
+			Help: "Requests for models marked deprecated in models.yaml, by org and model.",
+		}, []string{"org", "model"}),
+
+		JanitorDeletedTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_janitor_deleted_total",
+			Help: "Rows or Redis keys removed by the retention janitor, by target table or keyspace.",
+		}, []string{"target"}),
+
+		JanitorErrorsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_janitor_errors_total",
+			Help: "Retention janitor failures, by target table or keyspace.",
+		}, []string{"target"}),
+
+		JanitorRunDuration: promauto.NewHistogram(prometheus.HistogramOpts{
+			Name:    "aegis_janitor_run_duration_seconds",
+			Help:    "Time taken by one retention janitor pass.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 15, 60, 300, 900},
+		}),
+
+		CircuitState: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_circuit_state",
+			Help: "Provider circuit breaker state (0=closed, 1=open, 2=half_open).",
+		}, []string{"provider"}),
+
+		CircuitTransitionTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_circuit_transition_total",
+			Help: "Total provider circuit breaker state transitions.",
+		}, []string{"provider", "from", "to"}),
+
+		ProviderThrottleTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_provider_throttle_total",
+			Help: "Requests held by a provider's outbound rate limit, by outcome (queued, rejected).",
+		}, []string{"provider", "outcome"}),
+
+		ProviderThrottleWaitSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_provider_throttle_wait_seconds",
+			Help:    "Time requests queued for a provider's outbound rate limit.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		}, []string{"provider"}),
+
+		ProviderRateLimitRemaining: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_provider_ratelimit_remaining",
+			Help: "Upstream requests or tokens remaining in the provider's rate limit window, from its response headers.",
+		}, []string{"provider", "kind"}),
+
+		ProviderRateLimitLimit: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_provider_ratelimit_limit",
+			Help: "Upstream requests or tokens allowed per rate limit window, from the provider's response headers.",
+		}, []string{"provider", "kind"}),
+
+		ProviderConnectionsOpen: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "aegis_provider_connections_open",
+			Help: "Open connections to each provider, active and idle.",
+		}, []string{"provider"}),
+
+		ProviderConnectionsTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_provider_connections_total",
+			Help: "Connections to each provider by event (opened, closed); a high rate means connection churn.",
+		}, []string{"provider", "event"}),
+
+		ProviderConnAcquiresTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_provider_conn_acquires_total",
+			Help: "Provider requests by whether their connection was reused from the idle pool.",
+		}, []string{"provider", "reused"}),
+
+		ProviderConnSetupSeconds: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_provider_conn_setup_seconds",
+			Help:    "Time spent setting up new provider connections, by phase (dns, connect, tls).",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, []string{"provider", "phase"}),
+
+		ProviderProtocolTotal: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: "aegis_provider_protocol_total",
+			Help: "Provider responses by HTTP protocol version.",
+		}, []string{"provider", "protocol"}),
+
 		StreamingChunkTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "aegis_streaming_chunk_total",
 			Help: "Total number of streaming chunks sent.",
-		}, []string{"provider", "model"}),
+		}, []string{"org", "provider", "model"}),
 		
 		StreamingTimeToFirstToken: promauto.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "aegis_streaming_time_to_first_token_ms",
 			Help:    "Time to first token in milliseconds for streaming requests.",
 			Buckets: []float64{50, 100, 250, 500, 1000, 2000, 5000, 10000},
-		}, []string{"provider", "model"}),
+		}, []string{"org", "provider", "model"}),
 		
 		StreamingTokensPerSecond: promauto.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "aegis_streaming_tokens_per_second",
 			Help:    "Tokens per second during streaming.",
 			Buckets: []float64{1, 5, 10, 20, 50, 100, 200, 500, 1000},
-		}, []string{"provider", "model"}),
+		}, []string{"org", "provider", "model"}),
 		
 		StreamingDurationMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
 			Name:    "aegis_streaming_duration_ms",
 			Help:    "Total duration of streaming requests in milliseconds.",
 			Buckets: []float64{1000, 5000, 10000, 30000, 60000, 120000, 300000},
-		}, []string{"provider", "model"}),
+		}, []string{"org", "provider", "model"}),
 		
 		StreamingErrorTotal: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: "aegis_streaming_error_total",
 			Help: "Total number of streaming errors.",
-		}, []string{"provider", "error_type"}),
+		}, []string{"org", "provider", "error_type"}),
+
+		StreamingInterChunkMs: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_streaming_inter_chunk_latency_ms",
+			Help:    "Latency between consecutive streaming chunks in milliseconds.",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 5000},
+		}, []string{"org", "provider", "model"}),
+
+		StreamingChunksPerStream: promauto.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aegis_streaming_chunks_per_stream",
+			Help:    "Number of chunks sent per streaming request.",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500},
+		}, []string{"org", "provider", "model"}),
+	}
+}
+
+// SetStrictTenancy fills the org label on request metrics that otherwise
+// leave it empty. Histograms per org are costly, so it is off unless
+// tenancy.strict asks for every request series to be attributable to its
+// org. Call before serving traffic.
+func (m *Metrics) SetStrictTenancy(strict bool) {
+	m.strictTenancy = strict
+}
+
+// tenantLabel is the org label value for series that carry the org only
+// under strict tenancy.
+func (m *Metrics) tenantLabel(org string) string {
+	if m.strictTenancy {
+		return m.orgLabel(org)
 	}
+	return ""
 }
 
 // RecordRequest records metrics for a completed request.
 func (m *Metrics) RecordRequest(labels RequestLabels) {
+	org, team, model := m.orgLabel(labels.Org), m.teamLabel(labels.Team), m.modelLabel(labels.Model)
 	m.RequestTotal.WithLabelValues(
-		labels.Org, labels.Team, labels.Model, labels.Provider,
+		org, team, model, labels.Provider,
 		labels.Status, labels.Classification,
 	).Inc()
 
 	m.RequestDurationMs.WithLabelValues(
-		labels.Model, labels.Provider,
+		m.tenantLabel(labels.Org), model, labels.Provider,
 	).Observe(labels.DurationMs)
 
 	m.GatewayOverheadMs.WithLabelValues(
-		labels.Org,
+		org,
 	).Observe(labels.OverheadMs)
 
+	if m.ProviderLatencyMs != nil && labels.ProviderLatencyMs > 0 {
+		m.ProviderLatencyMs.WithLabelValues(
+			m.tenantLabel(labels.Org), model, labels.Provider,
+		).Observe(labels.ProviderLatencyMs)
+	}
+
 	if labels.PromptTokens > 0 {
 		m.TokensTotal.WithLabelValues(
-			labels.Org, labels.Team, labels.Model, "prompt",
+			org, team, model, "prompt",
 		).Add(float64(labels.PromptTokens))
 	}
 
 	if labels.CompletionTokens > 0 {
 		m.TokensTotal.WithLabelValues(
-			labels.Org, labels.Team, labels.Model, "completion",
+			org, team, model, "completion",
 		).Add(float64(labels.CompletionTokens))
 	}
 
 	if labels.CostUSD > 0 {
 		m.CostUSDTotal.WithLabelValues(
-			labels.Org, labels.Team, labels.Model, labels.Provider,
+			org, team, model, labels.Provider,
 		).Add(labels.CostUSD)
 	}
 }
 
 // RecordRateLimitHit records a rate limit hit.
-func (m *Metrics) RecordRateLimitHit(dimension, id string) {
-	m.RateLimitHitTotal.WithLabelValues(dimension, id).Inc()
+func (m *Metrics) RecordRateLimitHit(dimension, org, team string) {
+	if m.RateLimitHitTotal != nil {
+		m.RateLimitHitTotal.WithLabelValues(dimension, m.orgLabel(org), m.teamLabel(team)).Inc()
+	}
+}
+
+// RecordRateLimitRemaining samples the remaining quota reported by a limiter check.
+func (m *Metrics) RecordRateLimitRemaining(dimension, org, team string, remaining float64) {
+	if m.RateLimitRemaining != nil {
+		m.RateLimitRemaining.WithLabelValues(dimension, m.orgLabel(org), m.teamLabel(team)).Set(remaining)
+	}
 }
 
 // RecordFilterAction records a filter action metric.
-func (m *Metrics) RecordFilterAction(filter, action string) {
-	m.FilterActionTotal.WithLabelValues(filter, action).Inc()
+func (m *Metrics) RecordFilterAction(filter, org, action string) {
+	m.FilterActionTotal.WithLabelValues(filter, m.tenantLabel(org), action).Inc()
+}
+
+// RecordFilterEvaluation records the latency and outcome of a single filter evaluation.
+func (m *Metrics) RecordFilterEvaluation(filter, org, action string, duration time.Duration) {
+	if m.FilterEvalTotal != nil {
+		m.FilterEvalTotal.WithLabelValues(filter, m.orgLabel(org), action).Inc()
+	}
+	if m.FilterDurationMs != nil {
+		m.FilterDurationMs.WithLabelValues(filter).Observe(float64(duration.Microseconds()) / 1000)
+	}
+}
+
+// RecordFilterDegraded counts a filter evaluation that failed and was
+// passed under fail_open.
+func (m *Metrics) RecordFilterDegraded(filter, org string) {
+	if m.FilterDegradedTotal != nil {
+		m.FilterDegradedTotal.WithLabelValues(filter, m.orgLabel(org)).Inc()
+	}
 }
 
 // RecordDBPoolStats records database pool statistics.
@@ -260,35 +552,58 @@ type RequestLabels struct {
 	PromptTokens     int
 	CompletionTokens int
 	CostUSD          float64
+	// ProviderLatencyMs is the provider round-trip time. OverheadMs should be
+	// DurationMs minus this value.
+	ProviderLatencyMs float64
 }
 
 // StreamingLabels holds the label values for recording streaming metrics.
 type StreamingLabels struct {
+	Org                string
 	Provider           string
 	Model              string
 	ChunkCount         int
 	TimeToFirstTokenMs float64
 	TokensPerSecond    float64
 	StreamDurationMs   float64
+	// InterChunkLatenciesMs holds the gap between each pair of consecutive chunks.
+	InterChunkLatenciesMs []float64
 }
 
 // RecordStreamingMetrics records metrics for a completed streaming request.
 func (m *Metrics) RecordStreamingMetrics(labels StreamingLabels) {
+	org, model := m.tenantLabel(labels.Org), m.modelLabel(labels.Model)
 	m.StreamingChunkTotal.WithLabelValues(
-		labels.Provider, labels.Model,
+		org, labels.Provider, model,
 	).Add(float64(labels.ChunkCount))
-	
-	m.StreamingTimeToFirstToken.WithLabelValues(
-		labels.Provider, labels.Model,
-	).Observe(labels.TimeToFirstTokenMs)
-	
-	m.StreamingTokensPerSecond.WithLabelValues(
-		labels.Provider, labels.Model,
-	).Observe(labels.TokensPerSecond)
-	
+
+	if m.StreamingChunksPerStream != nil {
+		m.StreamingChunksPerStream.WithLabelValues(
+			org, labels.Provider, model,
+		).Observe(float64(labels.ChunkCount))
+	}
+
+	// TTFT and throughput are meaningless for streams that never produced a chunk.
+	if labels.ChunkCount > 0 {
+		m.StreamingTimeToFirstToken.WithLabelValues(
+			org, labels.Provider, model,
+		).Observe(labels.TimeToFirstTokenMs)
+
+		m.StreamingTokensPerSecond.WithLabelValues(
+			org, labels.Provider, model,
+		).Observe(labels.TokensPerSecond)
+	}
+
 	m.StreamingDurationMs.WithLabelValues(
-		labels.Provider, labels.Model,
+		org, labels.Provider, model,
 	).Observe(labels.StreamDurationMs)
+
+	if m.StreamingInterChunkMs != nil && len(labels.InterChunkLatenciesMs) > 0 {
+		interChunk := m.StreamingInterChunkMs.WithLabelValues(org, labels.Provider, model)
+		for _, gap := range labels.InterChunkLatenciesMs {
+			interChunk.Observe(gap)
+		}
+	}
 }
 
 // RecordPolicyReload records a policy reload attempt.
@@ -300,7 +615,213 @@ func (m *Metrics) RecordPolicyReload(success bool) {
 	}
 }
 
+// RecordPolicyCacheLookup records a policy decision cache hit or miss.
+func (m *Metrics) RecordPolicyCacheLookup(hit bool) {
+	if m.PolicyDecisionCacheTotal == nil {
+		return
+	}
+	if hit {
+		m.PolicyDecisionCacheTotal.WithLabelValues("hit").Inc()
+	} else {
+		m.PolicyDecisionCacheTotal.WithLabelValues("miss").Inc()
+	}
+}
+
+// RecordConfigReload records a config hot-reload attempt. A rejected reload
+// leaves the previous config in effect.
+func (m *Metrics) RecordConfigReload(success bool) {
+	if m.ConfigReloadTotal == nil {
+		return
+	}
+	if success {
+		m.ConfigReloadTotal.WithLabelValues("success").Inc()
+		if m.ConfigLastReloadSuccess != nil {
+			m.ConfigLastReloadSuccess.SetToCurrentTime()
+		}
+	} else {
+		m.ConfigReloadTotal.WithLabelValues("error").Inc()
+	}
+}
+
+// SetInFlightRequests updates the in-flight request gauge.
+func (m *Metrics) SetInFlightRequests(n int) {
+	if m.InFlightRequests == nil {
+		return
+	}
+	m.InFlightRequests.Set(float64(n))
+}
+
+// SetUsageLedgerQueueDepth updates the buffered usage record gauge.
+func (m *Metrics) SetUsageLedgerQueueDepth(n int) {
+	if m.UsageLedgerQueueDepth == nil {
+		return
+	}
+	m.UsageLedgerQueueDepth.Set(float64(n))
+}
+
+// RecordUsageLedgerRecords counts n usage records with the given result.
+func (m *Metrics) RecordUsageLedgerRecords(result string, n int) {
+	if m.UsageLedgerRecordsTotal == nil {
+		return
+	}
+	m.UsageLedgerRecordsTotal.WithLabelValues(result).Add(float64(n))
+}
+
+// ObserveUsageLedgerFlush records how long a batch write took.
+func (m *Metrics) ObserveUsageLedgerFlush(d time.Duration) {
+	if m.UsageLedgerFlushDuration == nil {
+		return
+	}
+	m.UsageLedgerFlushDuration.Observe(d.Seconds())
+}
+
+// RecordClassificationMismatch counts a prompt estimated above its key's
+// declared classification; action is "flag" or "block".
+func (m *Metrics) RecordClassificationMismatch(org, declared, detected, action string) {
+	if m.ClassificationMismatchTotal == nil {
+		return
+	}
+	m.ClassificationMismatchTotal.WithLabelValues(m.tenantLabel(org), declared, detected, action).Inc()
+}
+
+// RecordDeprecatedModel counts a request by org for a deprecated model.
+func (m *Metrics) RecordDeprecatedModel(org, model string) {
+	if m.DeprecatedModelRequestsTotal == nil {
+		return
+	}
+	m.DeprecatedModelRequestsTotal.WithLabelValues(m.orgLabel(org), m.modelLabel(model)).Inc()
+}
+
+// RecordJanitorDeleted counts rows or keys removed from target.
+func (m *Metrics) RecordJanitorDeleted(target string, n int64) {
+	if m.JanitorDeletedTotal == nil {
+		return
+	}
+	m.JanitorDeletedTotal.WithLabelValues(target).Add(float64(n))
+}
+
+// RecordJanitorError counts a failed cleanup of target.
+func (m *Metrics) RecordJanitorError(target string) {
+	if m.JanitorErrorsTotal == nil {
+		return
+	}
+	m.JanitorErrorsTotal.WithLabelValues(target).Inc()
+}
+
+// ObserveJanitorRun records how long a janitor pass took.
+func (m *Metrics) ObserveJanitorRun(d time.Duration) {
+	if m.JanitorRunDuration == nil {
+		return
+	}
+	m.JanitorRunDuration.Observe(d.Seconds())
+}
+
+// RecordLoadShed counts a request of the given priority rejected by the
+// global concurrency limit.
+func (m *Metrics) RecordLoadShed(priority string) {
+	if m.LoadShedTotal == nil {
+		return
+	}
+	m.LoadShedTotal.WithLabelValues(priority).Inc()
+}
+
+// AddPriorityInFlight adjusts the in-flight gauge of a priority class.
+func (m *Metrics) AddPriorityInFlight(priority string, delta int) {
+	if m.PriorityInFlight == nil {
+		return
+	}
+	m.PriorityInFlight.WithLabelValues(priority).Add(float64(delta))
+}
+
+// RecordPriorityRequest records the duration of an admitted request by
+// priority class.
+func (m *Metrics) RecordPriorityRequest(priority string, d time.Duration) {
+	if m.PriorityRequestDurationMs == nil {
+		return
+	}
+	m.PriorityRequestDurationMs.WithLabelValues(priority).Observe(float64(d.Milliseconds()))
+}
+
+// circuitStateValues maps circuit state names to aegis_circuit_state gauge values.
+var circuitStateValues = map[string]float64{
+	"closed":    0,
+	"open":      1,
+	"half_open": 2,
+}
+
+// RecordCircuitTransition records a provider circuit breaker state change.
+func (m *Metrics) RecordCircuitTransition(provider, from, to string) {
+	if m.CircuitTransitionTotal != nil {
+		m.CircuitTransitionTotal.WithLabelValues(provider, from, to).Inc()
+	}
+	if v, ok := circuitStateValues[to]; ok && m.CircuitState != nil {
+		m.CircuitState.WithLabelValues(provider).Set(v)
+	}
+}
+
+// RecordProviderThrottle records a request held by a provider's outbound
+// rate limit: "queued" with how long it waited, or "rejected".
+func (m *Metrics) RecordProviderThrottle(provider, outcome string, wait time.Duration) {
+	if m.ProviderThrottleTotal != nil {
+		m.ProviderThrottleTotal.WithLabelValues(provider, outcome).Inc()
+	}
+	if outcome == "queued" && m.ProviderThrottleWaitSeconds != nil {
+		m.ProviderThrottleWaitSeconds.WithLabelValues(provider).Observe(wait.Seconds())
+	}
+}
+
+// RecordProviderRateLimit records one dimension ("requests" or "tokens") of
+// a provider's reported upstream quota. A zero limit is not reported.
+func (m *Metrics) RecordProviderRateLimit(provider, kind string, limit, remaining int) {
+	if m.ProviderRateLimitRemaining != nil {
+		m.ProviderRateLimitRemaining.WithLabelValues(provider, kind).Set(float64(remaining))
+	}
+	if limit > 0 && m.ProviderRateLimitLimit != nil {
+		m.ProviderRateLimitLimit.WithLabelValues(provider, kind).Set(float64(limit))
+	}
+}
+
+// RecordProviderConnOpened records a new connection to a provider.
+func (m *Metrics) RecordProviderConnOpened(provider string) {
+	if m.ProviderConnectionsOpen != nil {
+		m.ProviderConnectionsOpen.WithLabelValues(provider).Inc()
+		m.ProviderConnectionsTotal.WithLabelValues(provider, "opened").Inc()
+	}
+}
+
+// RecordProviderConnClosed records a provider connection being closed.
+func (m *Metrics) RecordProviderConnClosed(provider string) {
+	if m.ProviderConnectionsOpen != nil {
+		m.ProviderConnectionsOpen.WithLabelValues(provider).Dec()
+		m.ProviderConnectionsTotal.WithLabelValues(provider, "closed").Inc()
+	}
+}
+
+// RecordProviderConnAcquired records whether a provider request reused an
+// idle connection.
+func (m *Metrics) RecordProviderConnAcquired(provider string, reused bool) {
+	if m.ProviderConnAcquiresTotal != nil {
+		m.ProviderConnAcquiresTotal.WithLabelValues(provider, strconv.FormatBool(reused)).Inc()
+	}
+}
+
+// RecordProviderConnSetup records one phase ("dns", "connect", or "tls") of
+// setting up a provider connection.
+func (m *Metrics) RecordProviderConnSetup(provider, phase string, d time.Duration) {
+	if m.ProviderConnSetupSeconds != nil {
+		m.ProviderConnSetupSeconds.WithLabelValues(provider, phase).Observe(d.Seconds())
+	}
+}
+
+// RecordProviderProtocol records the HTTP protocol version of a provider
+// response.
+func (m *Metrics) RecordProviderProtocol(provider, proto string) {
+	if m.ProviderProtocolTotal != nil {
+		m.ProviderProtocolTotal.WithLabelValues(provider, proto).Inc()
+	}
+}
+
 // RecordStreamingError records a streaming error.
-func (m *Metrics) RecordStreamingError(provider, errorType string) {
-	m.StreamingErrorTotal.WithLabelValues(provider, errorType).Inc()
+func (m *Metrics) RecordStreamingError(provider, org, errorType string) {
+	m.StreamingErrorTotal.WithLabelValues(m.tenantLabel(org), provider, errorType).Inc()
 }
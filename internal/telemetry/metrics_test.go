@@ -2,6 +2,7 @@ package telemetry
 
 import (
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
@@ -48,7 +49,7 @@ func TestRecordRequest(t *testing.T) {
 		Name:    "test_aegis_request_duration_ms",
 		Help:    "Test histogram",
 		Buckets: []float64{100, 500, 1000},
-	}, []string{"model", "provider"})
+	}, []string{"org", "model", "provider"})
 
 	overheadMs := prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "test_aegis_gateway_overhead_ms",
@@ -64,7 +65,7 @@ func TestRecordRequest(t *testing.T) {
 	filterTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "test_aegis_filter_action_total",
 		Help: "Test counter",
-	}, []string{"filter", "action"})
+	}, []string{"filter", "org", "action"})
 
 	reg.MustRegister(requestTotal, tokensTotal, durationMs, overheadMs, costTotal, filterTotal)
 
@@ -114,15 +115,230 @@ func TestRecordFilterAction(t *testing.T) {
 	filterTotal := prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "test_filter_action",
 		Help: "Test",
-	}, []string{"filter", "action"})
+	}, []string{"filter", "org", "action"})
 
 	m := &Metrics{FilterActionTotal: filterTotal}
-	m.RecordFilterAction("secrets", "block")
+	m.RecordFilterAction("secrets", "org-1", "block")
 
-	counter, _ := filterTotal.GetMetricWithLabelValues("secrets", "block")
+	// Outside strict tenancy the org label is left empty to bound cardinality.
+	counter, _ := filterTotal.GetMetricWithLabelValues("secrets", "", "block")
 	var metric dto.Metric
 	_ = counter.Write(&metric)
 	if *metric.Counter.Value != 1 {
 		t.Errorf("expected filter action count 1, got %v", *metric.Counter.Value)
 	}
+
+	m.SetStrictTenancy(true)
+	m.RecordFilterAction("secrets", "org-1", "block")
+	counter, _ = filterTotal.GetMetricWithLabelValues("secrets", "org-1", "block")
+	metric = dto.Metric{}
+	_ = counter.Write(&metric)
+	if *metric.Counter.Value != 1 {
+		t.Errorf("expected org-labelled filter action count 1, got %v", *metric.Counter.Value)
+	}
+}
+
+func TestRecordFilterEvaluation(t *testing.T) {
+	m := &Metrics{
+		FilterEvalTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_filter_evaluation_total", Help: "Test",
+		}, []string{"filter", "org", "action"}),
+		FilterDurationMs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_filter_duration_ms", Help: "Test",
+		}, []string{"filter"}),
+	}
+	m.RecordFilterEvaluation("pii", "org-1", "flag", 3*time.Millisecond)
+
+	counter, _ := m.FilterEvalTotal.GetMetricWithLabelValues("pii", "org-1", "flag")
+	var metric dto.Metric
+	_ = counter.Write(&metric)
+	if *metric.Counter.Value != 1 {
+		t.Errorf("expected evaluation count 1, got %v", *metric.Counter.Value)
+	}
+	if got := histogramCount(t, m.FilterDurationMs, "pii"); got != 1 {
+		t.Errorf("expected 1 duration observation, got %d", got)
+	}
+}
+
+func TestRecordCircuitTransition(t *testing.T) {
+	m := &Metrics{
+		CircuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_circuit_state", Help: "Test",
+		}, []string{"provider"}),
+		CircuitTransitionTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_circuit_transition_total", Help: "Test",
+		}, []string{"provider", "from", "to"}),
+	}
+	m.RecordCircuitTransition("openai", "closed", "open")
+
+	var metric dto.Metric
+	gauge, _ := m.CircuitState.GetMetricWithLabelValues("openai")
+	_ = gauge.Write(&metric)
+	if *metric.Gauge.Value != 1 {
+		t.Errorf("expected circuit state 1 (open), got %v", *metric.Gauge.Value)
+	}
+	counter, _ := m.CircuitTransitionTotal.GetMetricWithLabelValues("openai", "closed", "open")
+	_ = counter.Write(&metric)
+	if *metric.Counter.Value != 1 {
+		t.Errorf("expected 1 transition, got %v", *metric.Counter.Value)
+	}
+}
+
+func TestRecordConfigReload(t *testing.T) {
+	m := &Metrics{
+		ConfigReloadTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_config_reload_total", Help: "Test",
+		}, []string{"status"}),
+		ConfigLastReloadSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "test_config_last_reload_success", Help: "Test",
+		}),
+	}
+	m.RecordConfigReload(true)
+	m.RecordConfigReload(false)
+	m.RecordConfigReload(false)
+
+	var metric dto.Metric
+	counter, _ := m.ConfigReloadTotal.GetMetricWithLabelValues("error")
+	_ = counter.Write(&metric)
+	if *metric.Counter.Value != 2 {
+		t.Errorf("expected 2 failed reloads, got %v", *metric.Counter.Value)
+	}
+	_ = m.ConfigLastReloadSuccess.Write(&metric)
+	if *metric.Gauge.Value == 0 {
+		t.Error("expected last successful reload timestamp to be set")
+	}
+}
+
+func TestRecordRateLimit(t *testing.T) {
+	m := &Metrics{
+		RateLimitHitTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_rate_limit_hit_total", Help: "Test",
+		}, []string{"dimension", "org", "team"}),
+		RateLimitRemaining: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "test_rate_limit_remaining", Help: "Test",
+		}, []string{"dimension", "org", "team"}),
+	}
+	m.RecordRateLimitHit("rpm", "org-1", "team-1")
+	m.RecordRateLimitRemaining("budget", "org-1", "team-1", 250)
+
+	var metric dto.Metric
+	counter, _ := m.RateLimitHitTotal.GetMetricWithLabelValues("rpm", "org-1", "team-1")
+	_ = counter.Write(&metric)
+	if *metric.Counter.Value != 1 {
+		t.Errorf("expected 1 rate limit hit, got %v", *metric.Counter.Value)
+	}
+	gauge, _ := m.RateLimitRemaining.GetMetricWithLabelValues("budget", "org-1", "team-1")
+	_ = gauge.Write(&metric)
+	if *metric.Gauge.Value != 250 {
+		t.Errorf("expected remaining 250, got %v", *metric.Gauge.Value)
+	}
+}
+
+func newTestStreamingMetrics(suffix string) *Metrics {
+	hist := func(name string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "test_" + name + "_" + suffix,
+			Help:    "Test histogram",
+			Buckets: []float64{1, 10, 100},
+		}, []string{"org", "provider", "model"})
+	}
+	return &Metrics{
+		StreamingChunkTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_streaming_chunk_total_" + suffix,
+			Help: "Test counter",
+		}, []string{"org", "provider", "model"}),
+		StreamingTimeToFirstToken: hist("ttft"),
+		StreamingTokensPerSecond:  hist("tps"),
+		StreamingDurationMs:       hist("duration"),
+		StreamingInterChunkMs:     hist("inter_chunk"),
+		StreamingChunksPerStream:  hist("chunks_per_stream"),
+	}
+}
+
+func histogramCount(t *testing.T, vec *prometheus.HistogramVec, labels ...string) uint64 {
+	t.Helper()
+	obs, err := vec.GetMetricWithLabelValues(labels...)
+	if err != nil {
+		t.Fatalf("get histogram: %v", err)
+	}
+	var metric dto.Metric
+	_ = obs.(prometheus.Histogram).Write(&metric)
+	return metric.Histogram.GetSampleCount()
+}
+
+func TestRecordStreamingMetrics(t *testing.T) {
+	m := newTestStreamingMetrics("full")
+	m.RecordStreamingMetrics(StreamingLabels{
+		Provider:              "openai",
+		Model:                 "gpt-4o",
+		ChunkCount:            3,
+		TimeToFirstTokenMs:    120,
+		TokensPerSecond:       40,
+		StreamDurationMs:      900,
+		InterChunkLatenciesMs: []float64{5, 7},
+	})
+
+	if got := histogramCount(t, m.StreamingInterChunkMs, "", "openai", "gpt-4o"); got != 2 {
+		t.Errorf("expected 2 inter-chunk observations, got %d", got)
+	}
+	if got := histogramCount(t, m.StreamingChunksPerStream, "", "openai", "gpt-4o"); got != 1 {
+		t.Errorf("expected 1 chunks-per-stream observation, got %d", got)
+	}
+	if got := histogramCount(t, m.StreamingTimeToFirstToken, "", "openai", "gpt-4o"); got != 1 {
+		t.Errorf("expected 1 TTFT observation, got %d", got)
+	}
+}
+
+func TestRecordStreamingMetrics_NoChunksSkipsTTFT(t *testing.T) {
+	m := newTestStreamingMetrics("empty")
+	m.RecordStreamingMetrics(StreamingLabels{
+		Provider:         "openai",
+		Model:            "gpt-4o",
+		StreamDurationMs: 50,
+	})
+
+	if got := histogramCount(t, m.StreamingTimeToFirstToken, "", "openai", "gpt-4o"); got != 0 {
+		t.Errorf("expected no TTFT observation for empty stream, got %d", got)
+	}
+	if got := histogramCount(t, m.StreamingDurationMs, "", "openai", "gpt-4o"); got != 1 {
+		t.Errorf("expected stream duration to be recorded, got %d", got)
+	}
+}
+
+func TestRecordRequest_ProviderLatency(t *testing.T) {
+	m := &Metrics{
+		RequestTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "test_latency_request_total", Help: "Test counter",
+		}, []string{"org", "team", "model", "provider", "status", "classification"}),
+		RequestDurationMs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_latency_duration_ms", Help: "Test histogram",
+		}, []string{"org", "model", "provider"}),
+		GatewayOverheadMs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_latency_overhead_ms", Help: "Test histogram",
+		}, []string{"org"}),
+		ProviderLatencyMs: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "test_latency_provider_ms", Help: "Test histogram",
+		}, []string{"org", "model", "provider"}),
+	}
+
+	m.RecordRequest(RequestLabels{
+		Org:               "org-1",
+		Model:             "gpt-4o",
+		Provider:          "openai",
+		Status:            "200",
+		DurationMs:        500,
+		OverheadMs:        4.5,
+		ProviderLatencyMs: 495.5,
+	})
+
+	var metric dto.Metric
+	obs, _ := m.GatewayOverheadMs.GetMetricWithLabelValues("org-1")
+	_ = obs.(prometheus.Histogram).Write(&metric)
+	if metric.Histogram.GetSampleSum() != 4.5 {
+		t.Errorf("expected overhead sum 4.5, got %v", metric.Histogram.GetSampleSum())
+	}
+
+	if got := histogramCount(t, m.ProviderLatencyMs, "", "gpt-4o", "openai"); got != 1 {
+		t.Errorf("expected 1 provider latency observation, got %d", got)
+	}
 }
@@ -0,0 +1,22 @@
+// Package tenant lays out per-organization state for strict tenancy.
+package tenant
+
+// sharedRedisPrefix prefixes Redis keys when tenants share one keyspace.
+const sharedRedisPrefix = "aegis:"
+
+// RedisPrefix returns the prefix for an organization's Redis keys. Under
+// strict tenancy it is "aegis:org:<org>:", so one org's keys can be listed,
+// exported, or purged with a single pattern and fenced off with Redis ACL
+// key patterns; otherwise it is the shared "aegis:".
+func RedisPrefix(strict bool, org string) string {
+	if !strict {
+		return sharedRedisPrefix
+	}
+	return sharedRedisPrefix + "org:" + org + ":"
+}
+
+// RedisPattern returns a SCAN pattern matching every org's keys whose
+// RedisPrefix is followed by family (e.g. "rl:").
+func RedisPattern(strict bool, family string) string {
+	return RedisPrefix(strict, "*") + family + "*"
+}
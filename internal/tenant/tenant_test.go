@@ -0,0 +1,18 @@
+package tenant
+
+import "testing"
+
+func TestRedisPrefix(t *testing.T) {
+	if got := RedisPrefix(false, "acme"); got != "aegis:" {
+		t.Errorf("shared prefix = %q", got)
+	}
+	if got := RedisPrefix(true, "acme"); got != "aegis:org:acme:" {
+		t.Errorf("strict prefix = %q", got)
+	}
+	if got := RedisPattern(true, "rl:"); got != "aegis:org:*:rl:*" {
+		t.Errorf("strict pattern = %q", got)
+	}
+	if got := RedisPattern(false, "budget:daily:"); got != "aegis:budget:daily:*" {
+		t.Errorf("shared pattern = %q", got)
+	}
+}
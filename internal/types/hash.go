@@ -0,0 +1,76 @@
+package types
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// hashedRequest is the part of a request ContentHash covers: what the model
+// is asked, not who asked or how the answer is delivered.
+type hashedRequest struct {
+	Model               string          `json:"model"`
+	Messages            []Message       `json:"messages"`
+	Temperature         *float64        `json:"temperature,omitempty"`
+	MaxTokens           *int            `json:"max_tokens,omitempty"`
+	MaxCompletionTokens *int            `json:"max_completion_tokens,omitempty"`
+	TopP                *float64        `json:"top_p,omitempty"`
+	Stop                []string        `json:"stop,omitempty"`
+	ReasoningEffort     string          `json:"reasoning_effort,omitempty"`
+	Tools               []Tool          `json:"tools,omitempty"`
+	ToolChoice          json.RawMessage `json:"tool_choice,omitempty"`
+	ResponseFormat      json.RawMessage `json:"response_format,omitempty"`
+}
+
+// ContentHash returns the hex SHA-256 of r's content: the model, messages,
+// sampling parameters, tools, and response format. Identity, metadata, and
+// streaming options are left out, so the same prompt hashes the same for
+// every caller whether or not it is streamed. Raw JSON fields are hashed in
+// a canonical form, so key order and whitespace in tool schemas and
+// response formats do not change the hash.
+func (r *AegisRequest) ContentHash() string {
+	h := hashedRequest{
+		Model:               r.Model,
+		Messages:            r.Messages,
+		Temperature:         r.Temperature,
+		MaxTokens:           r.MaxTokens,
+		MaxCompletionTokens: r.MaxCompletionTokens,
+		TopP:                r.TopP,
+		Stop:                r.Stop,
+		ReasoningEffort:     r.ReasoningEffort,
+		ToolChoice:          canonicalJSON(r.ToolChoice),
+		ResponseFormat:      canonicalJSON(r.ResponseFormat),
+	}
+	if len(r.Tools) > 0 {
+		h.Tools = make([]Tool, len(r.Tools))
+		for i, t := range r.Tools {
+			t.Function.Parameters = canonicalJSON(t.Function.Parameters)
+			h.Tools[i] = t
+		}
+	}
+	// Marshaling plain structs, strings, and canonical raw JSON cannot fail.
+	data, _ := json.Marshal(h)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalJSON re-encodes raw with sorted object keys and no insignificant
+// whitespace. Numbers keep their original text. Invalid JSON, which the
+// request decoder has already rejected, is returned as is.
+func canonicalJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return out
+}
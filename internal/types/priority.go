@@ -0,0 +1,40 @@
+package types
+
+// Priority is a request's scheduling class. Under load, batch traffic is shed
+// and deferred before interactive traffic.
+type Priority string
+
+const (
+	PriorityInteractive Priority = "interactive"
+	PriorityBatch       Priority = "batch"
+)
+
+// Level returns a numeric level for comparison. Higher values are served
+// first.
+func (p Priority) Level() int {
+	switch p {
+	case PriorityBatch:
+		return 0
+	case PriorityInteractive:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func ParsePriority(s string) (Priority, bool) {
+	switch Priority(s) {
+	case PriorityInteractive, PriorityBatch:
+		return Priority(s), true
+	default:
+		return "", false
+	}
+}
+
+// Lower returns the lower of two priorities; an empty priority is ignored.
+func (p Priority) Lower(other Priority) Priority {
+	if p == "" || (other != "" && other.Level() < p.Level()) {
+		return other
+	}
+	return p
+}
@@ -1,6 +1,13 @@
 package types
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
 
 // AegisRequest is the canonical internal representation of an incoming AI request.
 // All provider-specific formats are converted to/from this type.
@@ -22,11 +29,35 @@ type AegisRequest struct {
 	TopP        *float64  `json:"top_p,omitempty"`
 	Stop        []string  `json:"stop,omitempty"`
 
+	// MaxCompletionTokens is OpenAI's newer name for MaxTokens, which
+	// reasoning models require; use CompletionTokenLimit to read either.
+	MaxCompletionTokens *int `json:"max_completion_tokens,omitempty"`
+	// ReasoningEffort is "minimal", "low", "medium", or "high".
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+
+	// Tools and ToolChoice follow OpenAI function calling. ToolChoice is kept
+	// raw because it is either a string or an object naming one function.
+	Tools      []Tool          `json:"tools,omitempty"`
+	ToolChoice json.RawMessage `json:"tool_choice,omitempty"`
+
+	// StreamOptions follows OpenAI's stream_options; only meaningful with Stream.
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+
+	// ResponseFormat follows OpenAI's response_format and is kept raw for
+	// the providers that take it; see JSONMode.
+	ResponseFormat json.RawMessage `json:"response_format,omitempty"`
+
 	// Metadata
 	Project        string `json:"project,omitempty"`
 	PreferProvider string `json:"prefer_provider,omitempty"`
 	TraceContext   string `json:"trace_context,omitempty"`
 	SkipCache      bool   `json:"skip_cache,omitempty"`
+	// ConversationID groups the turns of a multi-turn session, from the
+	// X-Aegis-Conversation-ID header.
+	ConversationID string `json:"conversation_id,omitempty"`
+	// AffinityKey, from the X-Aegis-Affinity-Key header, keeps requests
+	// sharing it on the provider that served the first of them.
+	AffinityKey string `json:"-"`
 
 	// Resolved at routing time
 	ProviderType string `json:"-"`
@@ -34,10 +65,186 @@ type AegisRequest struct {
 	// Internal tracking
 	ReceivedAt      time.Time `json:"-"`
 	EstimatedTokens int       `json:"-"`
+	// RequestHash is the ContentHash of the request as the client sent it,
+	// before model rules and hooks rewrite it.
+	RequestHash string `json:"-"`
+}
+
+// CompletionTokenLimit returns the requested output token limit, preferring
+// max_completion_tokens when a client sends both, or nil if neither is set.
+func (r *AegisRequest) CompletionTokenLimit() *int {
+	if r.MaxCompletionTokens != nil {
+		return r.MaxCompletionTokens
+	}
+	return r.MaxTokens
+}
+
+// JSONMode reports whether the request asks for JSON output, through a
+// response_format of type json_object or json_schema.
+func (r *AegisRequest) JSONMode() bool {
+	if len(r.ResponseFormat) == 0 {
+		return false
+	}
+	var format struct {
+		Type string `json:"type"`
+	}
+	if json.Unmarshal(r.ResponseFormat, &format) != nil {
+		return false
+	}
+	return format.Type == "json_object" || format.Type == "json_schema"
+}
+
+// StreamOptions are the OpenAI stream_options a client may send.
+type StreamOptions struct {
+	// IncludeUsage asks for a final chunk with empty choices and the usage
+	// for the whole request, sent just before [DONE].
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 	Name    string `json:"name,omitempty"`
+
+	// ToolCalls are the function calls made by an assistant message.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID links a "tool" message to the call whose result it carries.
+	ToolCallID string `json:"tool_call_id,omitempty"`
+
+	// ReasoningContent is the model's thinking, for providers that expose
+	// it. ReasoningSignature is the provider's proof of that thinking, which
+	// must accompany it when an assistant turn is sent back.
+	ReasoningContent   string `json:"reasoning_content,omitempty"`
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
+}
+
+// UnmarshalJSON accepts content as a string, null, or an array of text parts,
+// which are concatenated. Other part types are rejected rather than dropped.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	var raw struct {
+		plain
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.plain)
+
+	content := strings.TrimSpace(string(raw.Content))
+	switch {
+	case content == "" || content == "null":
+		return nil
+	case content[0] != '[':
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw.Content, &parts); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return fmt.Errorf("unsupported content part type %q", p.Type)
+		}
+		b.WriteString(p.Text)
+	}
+	m.Content = b.String()
+	return nil
+}
+
+// FilterText returns the text of m the content filters inspect: its content
+// and the arguments of any tool calls it carries. Tool results and tool call
+// arguments that are JSON contribute their decoded keys and values, so
+// escape sequences cannot hide a secret or an address from the filters.
+func (m Message) FilterText() string {
+	toolPayload := m.Role == "tool" || m.Role == "function"
+	if len(m.ToolCalls) == 0 && !toolPayload {
+		return m.Content
+	}
+	var b strings.Builder
+	add := func(s string, structured bool) {
+		if s == "" {
+			return
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		if structured {
+			s = jsonText(s)
+		}
+		b.WriteString(s)
+	}
+	add(m.Content, toolPayload)
+	for _, tc := range m.ToolCalls {
+		add(tc.Function.Name, false)
+		add(tc.Function.Arguments, true)
+	}
+	return b.String()
+}
+
+// jsonText returns the keys and scalar values of the JSON document s, one
+// per line, or s itself if it is not JSON.
+func jsonText(s string) string {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return s
+	}
+	if str, ok := v.(string); ok {
+		return str
+	}
+	var lines []string
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for _, k := range slices.Sorted(maps.Keys(v)) {
+				lines = append(lines, k)
+				walk(v[k])
+			}
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case string:
+			lines = append(lines, v)
+		case json.Number:
+			lines = append(lines, v.String())
+		}
+	}
+	walk(v)
+	return strings.Join(lines, "\n")
+}
+
+// Tool is a function the model may call.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function; Parameters is a JSON Schema.
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is one function call made by the model.
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction holds the called function's name and its arguments as a
+// JSON-encoded string.
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
 }
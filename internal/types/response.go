@@ -8,6 +8,17 @@ type AegisResponse struct {
 	Usage            Usage         `json:"usage"`
 	EstimatedCostUSD float64       `json:"estimated_cost_usd"`
 	FilterActions    FilterSummary `json:"filter_actions"`
+
+	// Deprecation is set when the requested model is being retired.
+	Deprecation *ModelDeprecation `json:"deprecation,omitempty"`
+}
+
+// ModelDeprecation tells a client that the model it used is being retired.
+type ModelDeprecation struct {
+	Model string `json:"model"`
+	// SunsetDate is the day the model stops being served, as YYYY-MM-DD.
+	SunsetDate  string `json:"sunset_date"`
+	Replacement string `json:"replacement,omitempty"`
 }
 
 type Choice struct {
@@ -204,3 +204,104 @@ func TestMessage_OmitsEmptyName(t *testing.T) {
 		t.Error("expected name to be omitted when empty")
 	}
 }
+
+func TestMessage_UnmarshalContentParts(t *testing.T) {
+	var m Message
+	if err := json.Unmarshal([]byte(`{"role":"user","content":[{"type":"text","text":"Hello, "},{"type":"text","text":"world"}]}`), &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Role != "user" || m.Content != "Hello, world" {
+		t.Errorf("unexpected message: %+v", m)
+	}
+
+	if err := json.Unmarshal([]byte(`{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"f","arguments":"{}"}}]}`), &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Content != "" || len(m.ToolCalls) != 1 || m.ToolCalls[0].Function.Name != "f" {
+		t.Errorf("unexpected tool call message: %+v", m)
+	}
+
+	err := json.Unmarshal([]byte(`{"role":"user","content":[{"type":"image_url","image_url":{"url":"https://x"}}]}`), &m)
+	if err == nil {
+		t.Error("non-text parts should be rejected, not silently dropped")
+	}
+}
+
+func TestMessage_FilterText(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string
+	}{
+		{"plain content", Message{Role: "user", Content: `{"a":"b"}`}, `{"a":"b"}`},
+		{"tool result json", Message{Role: "tool", Content: `{"user":{"email":"jane\u0040example.com"},"ids":[1,2]}`}, "ids\n1\n2\nuser\nemail\njane@example.com"},
+		{"tool result text", Message{Role: "tool", Content: "42 rows"}, "42 rows"},
+		{"tool call arguments", Message{Role: "assistant", Content: "Looking it up.", ToolCalls: []ToolCall{
+			{Function: ToolCallFunction{Name: "lookup", Arguments: `{"ssn":"078-05-1120"}`}},
+		}}, "Looking it up.\nlookup\nssn\n078-05-1120"},
+	}
+	for _, tt := range tests {
+		if got := tt.msg.FilterText(); got != tt.want {
+			t.Errorf("%s: FilterText() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAegisRequest_JSONMode(t *testing.T) {
+	tests := map[string]bool{
+		``:                       false,
+		`{"type":"text"}`:        false,
+		`{"type":"json_object"}`: true,
+		`{"type":"json_schema"}`: true,
+		`"json"`:                 false,
+	}
+	for format, want := range tests {
+		req := AegisRequest{ResponseFormat: json.RawMessage(format)}
+		if got := req.JSONMode(); got != want {
+			t.Errorf("JSONMode(%s) = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestAegisRequest_ContentHash(t *testing.T) {
+	temp := 0.2
+	base := func() AegisRequest {
+		return AegisRequest{
+			Model:       "gpt-4o",
+			Messages:    []Message{{Role: "user", Content: "Hello"}},
+			Temperature: &temp,
+			Tools: []Tool{{Type: "function", Function: ToolFunction{
+				Name:       "lookup",
+				Parameters: json.RawMessage(`{"type":"object","properties":{"q":{"type":"string"}}}`),
+			}}},
+			ResponseFormat: json.RawMessage(`{"type":"json_object"}`),
+		}
+	}
+	a := base()
+	want := a.ContentHash()
+	if len(want) != 64 {
+		t.Fatalf("expected a hex SHA-256, got %q", want)
+	}
+
+	same := base()
+	same.RequestID, same.OrganizationID, same.APIKeyID = "req-2", "org-2", "key-2"
+	same.Stream, same.Project = true, "other"
+	same.Tools[0].Function.Parameters = json.RawMessage(`{ "properties": {"q": {"type": "string"}}, "type": "object" }`)
+	same.ResponseFormat = json.RawMessage(`{ "type" : "json_object" }`)
+	if got := same.ContentHash(); got != want {
+		t.Error("identity, streaming, and JSON formatting should not change the hash")
+	}
+
+	for name, mutate := range map[string]func(*AegisRequest){
+		"model":       func(r *AegisRequest) { r.Model = "gpt-4o-mini" },
+		"message":     func(r *AegisRequest) { r.Messages[0].Content = "Hello!" },
+		"temperature": func(r *AegisRequest) { r.Temperature = nil },
+		"tools":       func(r *AegisRequest) { r.Tools = nil },
+	} {
+		r := base()
+		mutate(&r)
+		if r.ContentHash() == want {
+			t.Errorf("changing %s should change the hash", name)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DecodeOptions control how strictly DecodeJSON treats fields the target
+// type does not declare. The zero value ignores them, as json.Unmarshal does.
+type DecodeOptions struct {
+	// Strict rejects unknown top-level fields, naming every one of them.
+	Strict bool
+	// DisallowUnknownFields rejects the first unknown field at any depth
+	// the standard decoder walks, as json.Decoder.DisallowUnknownFields does.
+	DisallowUnknownFields bool
+}
+
+// UnknownFieldsError reports top-level fields rejected in strict mode.
+// Handlers respond 400 with the fields in details.
+type UnknownFieldsError struct {
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	quoted := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		quoted[i] = fmt.Sprintf("%q", f)
+	}
+	noun := "field"
+	if len(e.Fields) > 1 {
+		noun = "fields"
+	}
+	return fmt.Sprintf("unknown top-level %s %s; remove them or check their spelling", noun, strings.Join(quoted, ", "))
+}
+
+// DecodeJSON decodes a single JSON value from body into v, which must point
+// to a struct when opts.Strict is set. Trailing data after the value is an
+// error, as with json.Unmarshal.
+func DecodeJSON(body []byte, v any, opts DecodeOptions) error {
+	if opts.Strict {
+		if unknown := unknownTopLevelFields(body, v); len(unknown) > 0 {
+			return &UnknownFieldsError{Fields: unknown}
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// knownFields caches, per struct type, the lower-cased JSON names it
+// decodes. Matching is case-insensitive, as encoding/json's is.
+var knownFields sync.Map // reflect.Type -> map[string]bool
+
+func fieldsOf(t reflect.Type) map[string]bool {
+	if f, ok := knownFields.Load(t); ok {
+		return f.(map[string]bool)
+	}
+	fields := map[string]bool{}
+	addFields(t, fields)
+	knownFields.Store(t, fields)
+	return fields
+}
+
+func addFields(t reflect.Type, fields map[string]bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addFields(ft, fields)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = true
+	}
+}
+
+// unknownTopLevelFields returns the keys of body's top-level object that v's
+// struct type does not declare, each once, in the order they appear. It
+// returns nil when body is not an object or v is not a struct pointer and
+// leaves reporting malformed JSON to the decoder.
+func unknownTopLevelFields(body []byte, v any) []string {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	known := fieldsOf(t.Elem())
+
+	var unknown []string
+	depth := 0
+	inString, escaped := false, false
+	keyStart := -1 // start of the current depth-1 string, when it may be a key
+	expectKey := false
+	for i, c := range body {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if keyStart >= 0 {
+					name := topLevelKey(body[keyStart : i+1])
+					if !known[strings.ToLower(name)] && !slices.Contains(unknown, name) {
+						unknown = append(unknown, name)
+					}
+					keyStart = -1
+				}
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+			if depth == 1 && expectKey {
+				keyStart = i
+				expectKey = false
+			}
+		case '{', '[':
+			depth++
+			expectKey = depth == 1 && c == '{'
+		case '}', ']':
+			depth--
+		case ',':
+			expectKey = depth == 1
+		}
+	}
+	return unknown
+}
+
+// topLevelKey returns the value of a quoted JSON key, unescaping it only
+// when it has escapes.
+func topLevelKey(quoted []byte) string {
+	if bytes.IndexByte(quoted, '\\') < 0 {
+		return string(quoted[1 : len(quoted)-1])
+	}
+	var s string
+	if json.Unmarshal(quoted, &s) != nil {
+		return string(quoted[1 : len(quoted)-1])
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		opts        DecodeOptions
+		wantUnknown []string
+		wantErr     bool
+	}{
+		{"unknown fields ignored by default", `{"model":"gpt-4o","temprature":1}`, DecodeOptions{}, nil, false},
+		{"known fields pass strict mode", `{"Model":"gpt-4o","messages":[],"stream_options":{"include_usage":true}}`, DecodeOptions{Strict: true}, nil, false},
+		{
+			"strict mode lists every unknown top-level field",
+			`{"model":"gpt-4o","temprature":1,"messages":[{"role":"user","content":"hi","extra":1}],"top_k":5,"temprature":2}`,
+			DecodeOptions{Strict: true}, []string{"temprature", "top_k"}, true,
+		},
+		{"escaped keys are unescaped", `{"top\u005fk":5}`, DecodeOptions{Strict: true}, []string{"top_k"}, true},
+		{"keys inside string values are not fields", `{"model":"{\"x\":1, \"y\":2}"}`, DecodeOptions{Strict: true}, nil, false},
+		{"nested unknown field disallowed", `{"stream_options":{"include_usage":true,"x":1}}`, DecodeOptions{DisallowUnknownFields: true}, nil, true},
+		{"trailing data rejected", `{"model":"gpt-4o"} {}`, DecodeOptions{}, nil, true},
+		{"malformed JSON rejected", `{"model":`, DecodeOptions{Strict: true}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req types.AegisRequest
+			err := DecodeJSON([]byte(tt.body), &req, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DecodeJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var unknown *UnknownFieldsError
+			if errors.As(err, &unknown) {
+				if !reflect.DeepEqual(unknown.Fields, tt.wantUnknown) {
+					t.Errorf("unknown fields = %v, want %v", unknown.Fields, tt.wantUnknown)
+				}
+			} else if tt.wantUnknown != nil {
+				t.Errorf("expected UnknownFieldsError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+// SizeLimits bounds how much a single request may make the gateway read and
+// parse. They are checked before the filter chain runs. Zero disables a limit.
+type SizeLimits struct {
+	MaxBodyBytes     int64
+	MaxMessages      int
+	MaxMessageLength int // characters per message
+	MaxJSONDepth     int
+}
+
+// TooLargeError reports a request that exceeds a SizeLimits bound.
+// Handlers respond 413 Payload Too Large.
+type TooLargeError struct {
+	Message string
+}
+
+func (e *TooLargeError) Error() string {
+	return e.Message
+}
+
+// ReadBody reads the request body, refusing to buffer more than
+// limits.MaxBodyBytes, and rejects JSON nested deeper than limits.MaxJSONDepth.
+// A declared Content-Length over the limit is rejected without reading. w may
+// be nil when no response is tied to the read.
+func ReadBody(w http.ResponseWriter, r *http.Request, limits SizeLimits) ([]byte, error) {
+	if max := limits.MaxBodyBytes; max > 0 {
+		if r.ContentLength > max {
+			return nil, bodyTooLarge(max)
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, max)
+	}
+
+	body, err := readAll(r.Body, r.ContentLength)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, bodyTooLarge(maxErr.Limit)
+		}
+		return nil, err
+	}
+
+	if err := CheckJSONDepth(body, limits.MaxJSONDepth); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// bodyBuffers recycles the buffers bodies of unknown length are read into,
+// so a large body grows one pooled buffer instead of a fresh slice each time.
+var bodyBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+const (
+	// maxPooledBuffer keeps outsized buffers from being pinned by the pool.
+	maxPooledBuffer = 1 << 20
+	// maxPreallocBody caps what a declared Content-Length alone may make
+	// the gateway allocate when no body limit is set.
+	maxPreallocBody = 16 << 20
+)
+
+// readAll reads r to EOF. A body with a declared length is read straight
+// into a slice of that size; otherwise it is read through a pooled buffer
+// and copied out once.
+func readAll(r io.Reader, contentLength int64) ([]byte, error) {
+	if contentLength > 0 && contentLength <= maxPreallocBody {
+		// One spare byte lets ReadFull see a body longer than declared.
+		body := make([]byte, contentLength+1)
+		n, err := io.ReadFull(r, body)
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			return body[:n], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return append(body, rest...), nil
+	}
+
+	buf := bodyBuffers.Get().(*bytes.Buffer)
+	defer func() {
+		if buf.Cap() <= maxPooledBuffer {
+			buf.Reset()
+			bodyBuffers.Put(buf)
+		}
+	}()
+	buf.Reset()
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	return bytes.Clone(buf.Bytes()), nil
+}
+
+func bodyTooLarge(max int64) *TooLargeError {
+	return &TooLargeError{Message: fmt.Sprintf("request body too large (max %d bytes)", max)}
+}
+
+// CheckJSONDepth returns a TooLargeError if data nests objects or arrays more
+// than maxDepth levels deep. It only tracks brackets outside strings and does
+// not otherwise validate the JSON.
+func CheckJSONDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+	depth := 0
+	inString, escaped := false, false
+	for _, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return &TooLargeError{Message: fmt.Sprintf("request JSON nested too deeply (max depth %d)", maxDepth)}
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// CheckMessageSizes enforces the message count and per-message length limits.
+func CheckMessageSizes(messages []types.Message, limits SizeLimits) error {
+	if limits.MaxMessages > 0 && len(messages) > limits.MaxMessages {
+		return &TooLargeError{Message: fmt.Sprintf("too many messages: %d (max %d)", len(messages), limits.MaxMessages)}
+	}
+	if limits.MaxMessageLength > 0 {
+		for i, msg := range messages {
+			if n := utf8.RuneCountInString(msg.Content); n > limits.MaxMessageLength {
+				return &TooLargeError{Message: fmt.Sprintf("messages[%d].content too long: %d characters (max %d)", i, n, limits.MaxMessageLength)}
+			}
+		}
+	}
+	return nil
+}
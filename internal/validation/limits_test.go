@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/af-corp/aegis-gateway/internal/types"
+)
+
+func TestReadBody(t *testing.T) {
+	limits := SizeLimits{MaxBodyBytes: 16, MaxJSONDepth: 3}
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64 // -1 hides the length so the streaming limit applies
+		wantTooLarge  bool
+	}{
+		{"within limits", `{"a":[1,2]}`, 0, false},
+		{"declared length over limit", strings.Repeat("a", 32), 0, true},
+		{"undeclared length over limit", strings.Repeat("a", 32), -1, true},
+		{"too deep", `{"a":[[{}]]}`, 0, true},
+		{"brackets inside strings ignored", `{"a":"[[[[\""}`, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", bytes.NewBufferString(tt.body))
+			if tt.contentLength < 0 {
+				req.ContentLength = -1
+			}
+			body, err := ReadBody(httptest.NewRecorder(), req, limits)
+
+			var tooLarge *TooLargeError
+			if got := errors.As(err, &tooLarge); got != tt.wantTooLarge {
+				t.Fatalf("ReadBody() error = %v, wantTooLarge %v", err, tt.wantTooLarge)
+			}
+			if !tt.wantTooLarge && string(body) != tt.body {
+				t.Errorf("ReadBody() = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestReadBody_ZeroLimitsDisabled(t *testing.T) {
+	payload := strings.Repeat("[", 100) + strings.Repeat("]", 100)
+	req := httptest.NewRequest("POST", "/", bytes.NewBufferString(payload))
+	if _, err := ReadBody(nil, req, SizeLimits{}); err != nil {
+		t.Errorf("expected no error with zero limits, got %v", err)
+	}
+}
+
+func TestCheckMessageSizes(t *testing.T) {
+	limits := SizeLimits{MaxMessages: 2, MaxMessageLength: 5}
+
+	tests := []struct {
+		name     string
+		messages []types.Message
+		wantErr  bool
+	}{
+		{"ok", []types.Message{{Role: "user", Content: "hello"}}, false},
+		{"multibyte counted as characters", []types.Message{{Role: "user", Content: "héllo"}}, false},
+		{"too many", []types.Message{{Content: "a"}, {Content: "b"}, {Content: "c"}}, true},
+		{"too long", []types.Message{{Role: "user", Content: "hello!"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckMessageSizes(tt.messages, limits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckMessageSizes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
@@ -30,6 +31,17 @@ func (e ValidationErrors) Error() string {
 	return strings.Join(msgs, "; ")
 }
 
+// Fields returns the names of the invalid fields, each once, in order.
+func (e ValidationErrors) Fields() []string {
+	var fields []string
+	for _, err := range e {
+		if !slices.Contains(fields, err.Field) {
+			fields = append(fields, err.Field)
+		}
+	}
+	return fields
+}
+
 // Limits holds validation limits for request fields
 type Limits struct {
 	MaxModelNameLength    int
@@ -100,13 +112,28 @@ func (v *Validator) Validate(req *types.AegisRequest) error {
 		}
 	}
 
-	// Validate max_tokens
+	// Validate max_tokens and its newer name, max_completion_tokens
 	if req.MaxTokens != nil {
-		if err := v.validateMaxTokens(*req.MaxTokens); err != nil {
+		if err := v.validateMaxTokens("max_tokens", *req.MaxTokens); err != nil {
 			errs = append(errs, *err)
 			v.recordInvalidField("max_tokens")
 		}
 	}
+	if req.MaxCompletionTokens != nil {
+		if err := v.validateMaxTokens("max_completion_tokens", *req.MaxCompletionTokens); err != nil {
+			errs = append(errs, *err)
+			v.recordInvalidField("max_completion_tokens")
+		}
+	}
+
+	// Validate reasoning_effort
+	if req.ReasoningEffort != "" && !isValidReasoningEffort(req.ReasoningEffort) {
+		errs = append(errs, ValidationError{
+			Field:   "reasoning_effort",
+			Message: "reasoning_effort must be one of: minimal, low, medium, high",
+		})
+		v.recordInvalidField("reasoning_effort")
+	}
 
 	// Validate top_p
 	if req.TopP != nil {
@@ -191,7 +218,7 @@ func (v *Validator) validateMessages(messages []types.Message) ValidationErrors
 		} else if !isValidRole(msg.Role) {
 			errs = append(errs, ValidationError{
 				Field:   fmt.Sprintf("messages[%d].role", i),
-				Message: fmt.Sprintf("invalid role '%s' (allowed: system, user, assistant, function)", msg.Role),
+				Message: fmt.Sprintf("invalid role '%s' (allowed: system, developer, user, assistant, tool, function)", msg.Role),
 			})
 		}
 
@@ -237,19 +264,19 @@ func (v *Validator) validateTemperature(temp float64) *ValidationError {
 	return nil
 }
 
-// validateMaxTokens validates the max_tokens parameter
-func (v *Validator) validateMaxTokens(maxTokens int) *ValidationError {
+// validateMaxTokens validates max_tokens or max_completion_tokens
+func (v *Validator) validateMaxTokens(field string, maxTokens int) *ValidationError {
 	if maxTokens <= 0 {
 		return &ValidationError{
-			Field:   "max_tokens",
-			Message: "max_tokens must be positive",
+			Field:   field,
+			Message: field + " must be positive",
 		}
 	}
 
 	if maxTokens > v.limits.MaxTokens {
 		return &ValidationError{
-			Field:   "max_tokens",
-			Message: fmt.Sprintf("max_tokens too large (max %d)", v.limits.MaxTokens),
+			Field:   field,
+			Message: fmt.Sprintf("%s too large (max %d)", field, v.limits.MaxTokens),
 		}
 	}
 
@@ -306,7 +333,17 @@ func isValidModelChar(r rune) bool {
 // isValidRole checks if a role is valid
 func isValidRole(role string) bool {
 	switch role {
-	case "system", "user", "assistant", "function":
+	case "system", "developer", "user", "assistant", "tool", "function":
+		return true
+	default:
+		return false
+	}
+}
+
+// isValidReasoningEffort checks if reasoning_effort is supported
+func isValidReasoningEffort(effort string) bool {
+	switch effort {
+	case "minimal", "low", "medium", "high":
 		return true
 	default:
 		return false
@@ -145,7 +145,7 @@ func TestValidator_ValidateMaxTokens(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validator.validateMaxTokens(tt.maxTokens)
+			err := validator.validateMaxTokens("max_tokens", tt.maxTokens)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateMaxTokens() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -276,6 +276,40 @@ func TestValidator_Validate_FullRequest(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid max_completion_tokens and reasoning_effort",
+			req: &types.AegisRequest{
+				Model: "o3-mini",
+				Messages: []types.Message{
+					{Role: "user", Content: "Hello"},
+				},
+				MaxCompletionTokens: intPtr(2000),
+				ReasoningEffort:     "high",
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid max_completion_tokens",
+			req: &types.AegisRequest{
+				Model: "o3-mini",
+				Messages: []types.Message{
+					{Role: "user", Content: "Hello"},
+				},
+				MaxCompletionTokens: intPtr(0),
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid reasoning_effort",
+			req: &types.AegisRequest{
+				Model: "o3-mini",
+				Messages: []types.Message{
+					{Role: "user", Content: "Hello"},
+				},
+				ReasoningEffort: "extreme",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -344,6 +378,8 @@ func TestIsValidRole(t *testing.T) {
 		{"user", true},
 		{"assistant", true},
 		{"function", true},
+		{"tool", true},
+		{"developer", true},
 		{"admin", false},
 		{"", false},
 		{"SYSTEM", false}, // Case sensitive
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_request_usage_request_id;
+DROP INDEX IF EXISTS idx_request_usage_completed;
+DROP INDEX IF EXISTS idx_request_usage_key_completed;
+DROP INDEX IF EXISTS idx_request_usage_team_completed;
+DROP INDEX IF EXISTS idx_request_usage_org_completed;
+DROP TABLE IF EXISTS request_usage;
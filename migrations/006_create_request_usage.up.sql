@@ -0,0 +1,41 @@
+-- request_usage is the durable per-request usage ledger used for billing,
+-- the usage API, and budget reconciliation. Rows are appended in batches by
+-- the gateway's async writer. api_key_id is not a foreign key so billing
+-- history survives key deletion.
+CREATE TABLE request_usage (
+    id                  BIGSERIAL PRIMARY KEY,
+    request_id          VARCHAR(100) NOT NULL,
+
+    -- Identity
+    api_key_id          VARCHAR(100) NOT NULL,
+    organization_id     VARCHAR(100) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    user_id             VARCHAR(100),
+    project             VARCHAR(100),
+
+    -- Request details
+    model_requested     VARCHAR(100) NOT NULL,
+    model_served        VARCHAR(100) NOT NULL,
+    provider            VARCHAR(50) NOT NULL,
+    classification      VARCHAR(20) NOT NULL,
+    stream              BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- Usage and cost
+    prompt_tokens       INT NOT NULL DEFAULT 0,
+    completion_tokens   INT NOT NULL DEFAULT 0,
+    total_tokens        INT NOT NULL DEFAULT 0,
+    cost_usd            DECIMAL(14, 8) NOT NULL DEFAULT 0,
+
+    -- Outcome and timing
+    status_code         INT NOT NULL,
+    duration_ms         INT NOT NULL,
+    started_at          TIMESTAMPTZ NOT NULL,
+    completed_at        TIMESTAMPTZ NOT NULL,
+    recorded_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_request_usage_org_completed ON request_usage(organization_id, completed_at DESC);
+CREATE INDEX idx_request_usage_team_completed ON request_usage(team_id, completed_at DESC);
+CREATE INDEX idx_request_usage_key_completed ON request_usage(api_key_id, completed_at DESC);
+CREATE INDEX idx_request_usage_completed ON request_usage(completed_at DESC);
+CREATE INDEX idx_request_usage_request_id ON request_usage(request_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_batch_items_running;
+DROP INDEX IF EXISTS idx_batch_items_pending;
+DROP TABLE IF EXISTS batch_items;
+DROP INDEX IF EXISTS idx_batches_created;
+DROP INDEX IF EXISTS idx_batches_org_created;
+DROP TABLE IF EXISTS batches;
@@ -0,0 +1,45 @@
+-- batches holds asynchronous batch completion jobs submitted as JSONL. The
+-- submitting key's identity and limits are snapshotted in principal so items
+-- run with the permissions the key had at submission, even after rotation.
+CREATE TABLE batches (
+    id                  VARCHAR(64) PRIMARY KEY,
+    organization_id     VARCHAR(100) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    api_key_id          VARCHAR(100) NOT NULL,
+    principal           JSONB NOT NULL,
+
+    -- queued, in_progress, completed, cancelled, expired
+    status              VARCHAR(20) NOT NULL DEFAULT 'queued',
+    total_requests      INT NOT NULL,
+    completed_requests  INT NOT NULL DEFAULT 0,
+    failed_requests     INT NOT NULL DEFAULT 0,
+    cost_usd            DECIMAL(14, 8) NOT NULL DEFAULT 0,
+
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMPTZ NOT NULL,
+    completed_at        TIMESTAMPTZ
+);
+
+CREATE INDEX idx_batches_org_created ON batches(organization_id, created_at DESC);
+CREATE INDEX idx_batches_created ON batches(created_at);
+
+-- batch_items are the individual chat completion requests of a batch, one per
+-- JSONL line.
+CREATE TABLE batch_items (
+    batch_id            VARCHAR(64) NOT NULL REFERENCES batches(id) ON DELETE CASCADE,
+    line                INT NOT NULL,
+    custom_id           VARCHAR(255) NOT NULL,
+    body                JSONB NOT NULL,
+
+    -- pending, running, succeeded, failed
+    status              VARCHAR(20) NOT NULL DEFAULT 'pending',
+    status_code         INT,
+    response            JSONB,
+    cost_usd            DECIMAL(14, 8) NOT NULL DEFAULT 0,
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (batch_id, line)
+);
+
+CREATE INDEX idx_batch_items_pending ON batch_items(batch_id, line) WHERE status = 'pending';
+CREATE INDEX idx_batch_items_running ON batch_items(updated_at) WHERE status = 'running';
@@ -0,0 +1,5 @@
+CREATE TYPE request_priority AS ENUM ('interactive', 'batch');
+
+-- Scheduling class for the key's traffic; batch keys are shed and deferred
+-- first under load. An X-Aegis-Priority header may lower it, never raise it.
+ALTER TABLE api_keys ADD COLUMN priority request_priority NOT NULL DEFAULT 'interactive';
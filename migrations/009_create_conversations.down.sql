@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_conversations_last_seen;
+DROP INDEX IF EXISTS idx_conversations_org_last_seen;
+DROP TABLE IF EXISTS conversations;
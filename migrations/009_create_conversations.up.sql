@@ -0,0 +1,24 @@
+-- conversations aggregates multi-turn sessions identified by the client's
+-- X-Aegis-Conversation-ID header, scoped to the organization. It backs the
+-- conversations API and per-conversation budgets.
+CREATE TABLE conversations (
+    organization_id     VARCHAR(100) NOT NULL,
+    id                  VARCHAR(128) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    api_key_id          VARCHAR(100) NOT NULL,
+
+    turns               INT NOT NULL DEFAULT 0,
+    prompt_tokens       BIGINT NOT NULL DEFAULT 0,
+    completion_tokens   BIGINT NOT NULL DEFAULT 0,
+    cost_usd            DECIMAL(14, 8) NOT NULL DEFAULT 0,
+    -- models served, in order of first use
+    models              TEXT[] NOT NULL DEFAULT '{}',
+
+    first_seen_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (organization_id, id)
+);
+
+CREATE INDEX idx_conversations_org_last_seen ON conversations(organization_id, last_seen_at DESC);
+CREATE INDEX idx_conversations_last_seen ON conversations(last_seen_at);
@@ -0,0 +1,9 @@
+-- organization_suspensions lists organizations whose API keys are rejected
+-- outright, typically during an incident. Deleting the row resumes the
+-- organization; its keys are left untouched either way.
+CREATE TABLE organization_suspensions (
+    organization_id     VARCHAR(100) PRIMARY KEY,
+    reason              TEXT,
+    suspended_by        VARCHAR(100),
+    suspended_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,11 @@
+-- team_suspensions lists teams whose API keys are rejected while the rest of
+-- the organization keeps working. Deleting the row resumes the team.
+CREATE TABLE team_suspensions (
+    organization_id     VARCHAR(100) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    reason              TEXT,
+    suspended_by        VARCHAR(100),
+    suspended_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (organization_id, team_id)
+);
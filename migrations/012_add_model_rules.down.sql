@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS team_model_rules;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS model_rewrites;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS default_model;
@@ -0,0 +1,17 @@
+-- Model rules let admins steer traffic between models without client
+-- changes: a default for requests that name no model, and rewrites such as
+-- gpt-4 -> gpt-4o-mini. Team rules apply to every key of the team; a key's
+-- own rules take precedence, rewrite by rewrite.
+ALTER TABLE api_keys ADD COLUMN default_model VARCHAR(100);
+ALTER TABLE api_keys ADD COLUMN model_rewrites JSONB NOT NULL DEFAULT '{}';
+
+CREATE TABLE team_model_rules (
+    organization_id     VARCHAR(100) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    default_model       VARCHAR(100),
+    model_rewrites      JSONB NOT NULL DEFAULT '{}',
+    updated_by          VARCHAR(100),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (organization_id, team_id)
+);
@@ -0,0 +1,28 @@
+-- filter_feedback holds users' reports that a content filter block was a
+-- false positive. Each report copies the block's audit metadata (filter,
+-- reason, detections, score) so the security team can review it and tune
+-- thresholds after the audit trail is pruned. One report per blocked request.
+CREATE TABLE filter_feedback (
+    id                  VARCHAR(64) PRIMARY KEY,
+    request_id          VARCHAR(100) NOT NULL,
+    organization_id     VARCHAR(100) NOT NULL,
+    team_id             VARCHAR(100) NOT NULL,
+    api_key_id          VARCHAR(100) NOT NULL,
+
+    filter              VARCHAR(50) NOT NULL,
+    detection           JSONB NOT NULL DEFAULT '{}',
+    blocked_at          TIMESTAMPTZ NOT NULL,
+    comment             TEXT,
+
+    -- open, accepted (a false positive), or rejected (the block stands)
+    status              VARCHAR(20) NOT NULL DEFAULT 'open',
+    review_note         TEXT,
+    reviewed_by         VARCHAR(100),
+    reviewed_at         TIMESTAMPTZ,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (organization_id, request_id)
+);
+
+CREATE INDEX idx_filter_feedback_status_created ON filter_feedback(status, created_at DESC);
+CREATE INDEX idx_filter_feedback_filter_created ON filter_feedback(filter, created_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_request_usage_org_hash;
+ALTER TABLE request_usage DROP COLUMN IF EXISTS request_hash;
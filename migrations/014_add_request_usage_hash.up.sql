@@ -0,0 +1,6 @@
+-- request_hash is the content hash of the request (model, messages, and
+-- sampling parameters, but not who sent it), also returned to clients in
+-- X-Aegis-Request-Hash. It answers "who else sent this exact prompt".
+ALTER TABLE request_usage ADD COLUMN request_hash VARCHAR(64);
+
+CREATE INDEX idx_request_usage_org_hash ON request_usage(organization_id, request_hash, completed_at DESC);
@@ -0,0 +1,4 @@
+-- Rows copied from usage_records stay in request_usage; usage_records still
+-- holds them too.
+ALTER TABLE request_usage DROP CONSTRAINT IF EXISTS request_usage_request_id_key;
+CREATE INDEX IF NOT EXISTS idx_request_usage_request_id ON request_usage(request_id);
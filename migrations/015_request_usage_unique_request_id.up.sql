@@ -0,0 +1,33 @@
+-- A retried ledger batch whose first attempt committed but timed out on the
+-- client side used to append its rows a second time. Keep the first copy of
+-- each request and make request_id unique so writers can skip duplicates
+-- with ON CONFLICT.
+DELETE FROM request_usage r
+USING request_usage earlier
+WHERE earlier.request_id = r.request_id
+  AND earlier.id < r.id;
+
+DROP INDEX IF EXISTS idx_request_usage_request_id;
+ALTER TABLE request_usage
+    ADD CONSTRAINT request_usage_request_id_key UNIQUE (request_id);
+
+-- Carry history over from usage_records, which the gateway wrote before
+-- request_usage existed, so usage queries and budget rebuilds that now read
+-- only request_usage still see it. usage_records.created_at was written with
+-- NOW() as the request completed.
+INSERT INTO request_usage (
+    request_id, api_key_id, organization_id, team_id, user_id, project,
+    model_requested, model_served, provider, classification, stream,
+    prompt_tokens, completion_tokens, total_tokens, cost_usd,
+    status_code, duration_ms, started_at, completed_at
+)
+SELECT DISTINCT ON (u.request_id)
+    u.request_id, u.api_key_id::text, u.organization_id, u.team_id, u.user_id, u.project,
+    u.model_requested, u.model_served, u.provider, u.classification, u.stream,
+    u.prompt_tokens, u.completion_tokens, u.total_tokens, u.estimated_cost_usd,
+    u.status_code, u.duration_ms,
+    u.created_at::timestamptz - make_interval(secs => u.duration_ms / 1000.0),
+    u.created_at::timestamptz
+FROM usage_records u
+ORDER BY u.request_id, u.id
+ON CONFLICT (request_id) DO NOTHING;
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so the gateway binary
+// can apply them without a migrations directory on disk.
+package migrations
+
+import "embed"
+
+// FS holds the *.up.sql and *.down.sql files in this directory.
+//
+//go:embed *.sql
+var FS embed.FS